@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/backup"
+	"github.com/Rehtt/hamster-bin/internal/config"
+	"github.com/Rehtt/hamster-bin/internal/database"
+)
+
+// runBackup 将整库备份写入文件
+func runBackup(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	output := fs.String("o", "hamster-bin-backup-"+time.Now().Format("20060102-150405")+".zip", "备份文件路径")
+	_ = fs.Parse(args)
+
+	file, err := os.Create(*output)
+	if err != nil {
+		log.Fatalf("创建备份文件失败: %v", err)
+	}
+	manifest, err := backup.Write(context.Background(), database.GetDB(), cfg.ImageDir, file)
+	if err == nil {
+		err = file.Close()
+	} else {
+		file.Close()
+	}
+	if err != nil {
+		os.Remove(*output)
+		log.Fatalf("备份失败: %v", err)
+	}
+	fmt.Printf("✅ 备份完成: %s（%d 张表，%d 张图片）\n", *output, len(manifest.Tables), manifest.Images)
+}
+
+// runRestore 从备份文件恢复，-mode 必填
+func runRestore(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	mode := fs.String("mode", "", "恢复方式：replace（清空后恢复）或 merge（合并）")
+	dryRun := fs.Bool("dry-run", false, "只校验备份文件，不写入")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: hamster-bin restore -mode replace|merge [-dry-run] <备份文件>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 || !backup.IsValidRestoreMode(*mode) {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("打开备份文件失败: %v", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		log.Fatalf("读取备份文件失败: %v", err)
+	}
+
+	report, err := backup.Restore(context.Background(), database.GetDB(), cfg.ImageDir, file, info.Size(), *mode, *dryRun)
+	if err != nil {
+		log.Fatalf("恢复失败: %v", err)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(report)
+}
+
+func newBackupScheduler(cfg *config.Config) (*backup.Scheduler, error) {
+	schedulerCfg := backup.SchedulerConfig{
+		Schedule:            cfg.BackupSchedule,
+		MaintenanceSchedule: cfg.DBMaintenanceSchedule,
+		Dir:                 cfg.BackupDir,
+		ImagesDir:           cfg.ImageDir,
+		Retention: backup.Retention{
+			Daily:   cfg.BackupKeepDaily,
+			Weekly:  cfg.BackupKeepWeekly,
+			Monthly: cfg.BackupKeepMonthly,
+		},
+	}
+	if cfg.IsBackupS3Enabled() {
+		schedulerCfg.S3 = &backup.S3Config{
+			Endpoint:  cfg.BackupS3Endpoint,
+			Region:    cfg.BackupS3Region,
+			Bucket:    cfg.BackupS3Bucket,
+			Prefix:    cfg.BackupS3Prefix,
+			AccessKey: cfg.BackupS3AccessKey,
+			SecretKey: cfg.BackupS3SecretKey,
+			PathStyle: cfg.BackupS3PathStyle,
+		}
+	}
+	return backup.NewScheduler(database.GetDB(), schedulerCfg)
+}
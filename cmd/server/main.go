@@ -1,14 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	// 内置时区数据，运行镜像（alpine）未安装 tzdata 时统计接口的 tz 参数仍可用
+	_ "time/tzdata"
 
+	"github.com/Rehtt/hamster-bin/internal/abc"
 	"github.com/Rehtt/hamster-bin/internal/config"
 	"github.com/Rehtt/hamster-bin/internal/database"
+	"github.com/Rehtt/hamster-bin/internal/forecast"
+	"github.com/Rehtt/hamster-bin/internal/label"
 	"github.com/Rehtt/hamster-bin/internal/llm"
 	"github.com/Rehtt/hamster-bin/internal/parser"
+	"github.com/Rehtt/hamster-bin/internal/repository"
 	"github.com/Rehtt/hamster-bin/internal/router"
 	"github.com/Rehtt/hamster-bin/internal/version"
 )
@@ -22,14 +29,42 @@ func main() {
 	// 加载配置
 	cfg := config.Load()
 
+	// 表结构迁移命令在初始化数据库前执行，避免启动时自动迁移
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(cfg, os.Args[2:])
+		return
+	}
+
 	// 初始化数据库
 	if err := database.Init(database.Config{
-		Driver: cfg.DBDriver,
-		DSN:    cfg.DBDSN,
-		Path:   cfg.DBPath,
+		Driver:      cfg.DBDriver,
+		DSN:         cfg.DBDSN,
+		Path:        cfg.DBPath,
+		AutoMigrate: cfg.DBAutoMigrate,
 	}); err != nil {
 		log.Fatalf("数据库初始化失败: %v", err)
 	}
+	if err := repository.NewWorkspaceRepository(database.GetDB()).EnsureDefault(); err != nil {
+		log.Fatalf("创建默认工作区失败: %v", err)
+	}
+	if err := repository.RegisterSuggestionCacheInvalidation(database.GetDB()); err != nil {
+		log.Fatalf("注册缓存回调失败: %v", err)
+	}
+
+	// 命令行子命令：backup / restore / migrate-db
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backup":
+			runBackup(cfg, os.Args[2:])
+			return
+		case "restore":
+			runRestore(cfg, os.Args[2:])
+			return
+		case "migrate-db":
+			runMigrateDB(cfg, os.Args[2:])
+			return
+		}
+	}
 
 	// 初始化解析器管理器
 	parserManager := parser.NewParserManager()
@@ -37,8 +72,35 @@ func main() {
 	parserManager.Register(parser.NewLCSCParser(llmClient)) // 注册立创商城解析器
 	parserManager.Register(parser.NewTaobaoParser())        // 注册淘宝解析器（示例）
 
+	// 定时备份与数据库维护
+	scheduler, err := newBackupScheduler(cfg)
+	if err != nil {
+		log.Fatalf("定时备份配置错误: %v", err)
+	}
+	scheduler.Start(context.Background())
+
+	// 消耗预测（启动时计算一次，之后按 FORECAST_SCHEDULE 定时计算）
+	forecastJob, err := forecast.NewJob(database.GetDB(), cfg)
+	if err != nil {
+		log.Fatalf("消耗预测配置错误: %v", err)
+	}
+	forecastJob.Start(context.Background())
+
+	// ABC 分类（启动时计算一次，之后按 ABC_SCHEDULE 定时计算）
+	abcJob, err := abc.NewJob(database.GetDB(), cfg)
+	if err != nil {
+		log.Fatalf("ABC 分类配置错误: %v", err)
+	}
+	abcJob.Start(context.Background())
+
+	// 标签模板（内置模板及 LABEL_TEMPLATES_FILE）
+	labels, err := label.NewService(cfg)
+	if err != nil {
+		log.Fatalf("标签模板配置错误: %v", err)
+	}
+
 	// 设置路由
-	r := router.Setup(database.GetDB(), parserManager, cfg)
+	r := router.Setup(database.GetDB(), parserManager, cfg, scheduler, forecastJob, abcJob, labels)
 
 	// 启动服务器
 	addr := ":" + cfg.Port
@@ -47,7 +109,11 @@ func main() {
 	fmt.Printf("📡 API地址: http://localhost%s/api/v1\n", addr)
 	fmt.Printf("🌐 Web地址: http://localhost%s\n", addr)
 	fmt.Printf("💾 数据库: %s (%s)\n", cfg.DBDriver, cfg.DatabaseDisplay())
-	fmt.Printf("🔌 支持平台: %v\n\n", parserManager.GetAvailableParsers())
+	fmt.Printf("🔌 支持平台: %v\n", parserManager.GetAvailableParsers())
+	if cfg.BackupSchedule != "" {
+		fmt.Printf("🗄️  定时备份: %s → %s\n", cfg.BackupSchedule, cfg.BackupDir)
+	}
+	fmt.Println()
 
 	if cfg.SSLCert != "" && cfg.SSLKey != "" {
 		fmt.Printf("🔒 启用HTTPS服务\n")
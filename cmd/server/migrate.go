@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Rehtt/hamster-bin/internal/config"
+	"github.com/Rehtt/hamster-bin/internal/database"
+)
+
+// runMigrate 查看或执行表结构迁移：migrate status | migrate up
+func runMigrate(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: hamster-bin migrate status|up")
+		fmt.Fprintln(fs.Output(), "  status  列出全部迁移及执行时间")
+		fmt.Fprintln(fs.Output(), "  up      执行全部待执行的迁移（SQLite 会先在数据库同目录生成备份）")
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 || (fs.Arg(0) != "status" && fs.Arg(0) != "up") {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	dbCfg := database.Config{Driver: cfg.DBDriver, DSN: cfg.DBDSN, Path: cfg.DBPath}
+	db, err := database.Connect(dbCfg)
+	if err != nil {
+		log.Fatalf("数据库连接失败: %v", err)
+	}
+
+	if fs.Arg(0) == "up" {
+		result, err := database.Migrate(db, database.SQLiteFilePath(dbCfg))
+		if err != nil {
+			log.Fatalf("迁移失败: %v", err)
+		}
+		if len(result.Applied) == 0 {
+			fmt.Printf("✅ 数据库已是最新版本 v%d\n", result.To)
+			return
+		}
+		if result.BackupPath != "" {
+			fmt.Printf("💾 迁移前备份: %s\n", result.BackupPath)
+		}
+		for _, name := range result.Applied {
+			fmt.Printf("  %s\n", name)
+		}
+		fmt.Printf("✅ 迁移完成: v%d → v%d\n", result.From, result.To)
+		return
+	}
+
+	current, pending, err := database.CheckVersion(db)
+	states, statusErr := database.MigrationStatus(db)
+	if statusErr != nil {
+		log.Fatalf("读取迁移记录失败: %v", statusErr)
+	}
+	fmt.Printf("数据库版本: v%d，程序版本: v%d\n", current, database.SchemaVersion)
+	for _, state := range states {
+		appliedAt := "待执行"
+		if state.AppliedAt != nil {
+			appliedAt = state.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("  v%-4d %-30s %s\n", state.Version, state.Name, appliedAt)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if len(pending) > 0 {
+		fmt.Printf("%d 个迁移待执行，请执行 hamster-bin migrate up\n", len(pending))
+	}
+}
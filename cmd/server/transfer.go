@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/Rehtt/hamster-bin/internal/backup"
+	"github.com/Rehtt/hamster-bin/internal/config"
+	"github.com/Rehtt/hamster-bin/internal/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// runMigrateDB 将当前配置的数据库（源库）复制到另一个数据库（目标库）
+func runMigrateDB(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("migrate-db", flag.ExitOnError)
+	toDriver := fs.String("to-driver", "", "目标数据库类型：sqlite、mysql 或 postgres")
+	toDSN := fs.String("to-dsn", "", "目标数据库连接串；SQLite 可改用 -to-path")
+	toPath := fs.String("to-path", "", "目标 SQLite 数据库路径")
+	resume := fs.Bool("resume", false, "从上次中断处继续迁移")
+	abort := fs.Bool("abort", false, "放弃上次中断的迁移，清空目标库全部数据")
+	batch := fs.Int("batch", 0, "每批复制的行数")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: hamster-bin migrate-db -to-driver sqlite|mysql|postgres (-to-dsn <连接串> | -to-path <路径>) [-resume | -abort]")
+		fmt.Fprintln(fs.Output(), "源库取自 DB_DRIVER / DB_DSN / DB_PATH，迁移前请停止服务。")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 0 || *toDriver == "" || (*toDSN == "" && *toPath == "") || (*resume && *abort) {
+		fs.Usage()
+		os.Exit(2)
+	}
+	targetLocation := *toDSN
+	if targetLocation == "" {
+		targetLocation = *toPath
+	}
+	sourceLocation := cfg.DBDSN
+	if cfg.DBDriver == "sqlite" {
+		sourceLocation = cfg.DatabaseDisplay()
+	}
+	if strings.EqualFold(*toDriver, cfg.DBDriver) && targetLocation == sourceLocation {
+		log.Fatalf("目标库与源库相同")
+	}
+
+	target, err := database.Open(database.Config{Driver: *toDriver, DSN: *toDSN, Path: *toPath})
+	if err != nil {
+		log.Fatalf("打开目标库失败: %v", err)
+	}
+	// 逐批写入时不输出每条 SQL
+	quiet := &gorm.Session{Logger: logger.Default.LogMode(logger.Warn)}
+	src := database.GetDB().Session(quiet)
+	dst := target.Session(quiet)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *abort {
+		if err := backup.ClearTransferTarget(ctx, dst); err != nil {
+			log.Fatalf("清空目标库失败: %v", err)
+		}
+		fmt.Println("✅ 已清空目标库，可重新迁移")
+		return
+	}
+
+	report, err := backup.Transfer(ctx, src, dst, backup.TransferOptions{
+		Resume:    *resume,
+		BatchSize: *batch,
+		Progress: func(table string, copied, total int64) {
+			fmt.Fprintf(os.Stderr, "\r%s: %d/%d", table, copied, total)
+			if copied == total {
+				fmt.Fprintln(os.Stderr)
+			}
+		},
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr)
+		if errors.Is(err, backup.ErrTargetNotEmpty) {
+			log.Fatalf("迁移失败: %v", err)
+		}
+		log.Fatalf("迁移中断: %v\n已提交的数据保留在目标库中：加 -resume 重新执行可继续迁移，加 -abort 执行可清空目标库", err)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(report)
+	fmt.Printf("✅ 迁移完成，各表行数一致。请将 DB_DRIVER / DB_DSN 改为目标库后启动服务，图片目录 IMAGE_DIR 不受影响\n")
+}
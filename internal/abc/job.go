@@ -0,0 +1,73 @@
+// Package abc 在服务进程内按 cron 重新计算各工作区元件的 ABC 分类
+package abc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/config"
+	"github.com/Rehtt/hamster-bin/internal/cron"
+	"github.com/Rehtt/hamster-bin/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Job 在服务进程内按 cron 重新计算全部工作区的 ABC 分类
+type Job struct {
+	repo   *repository.ABCRepository
+	params repository.ABCParams
+	loop   cron.WorkspaceJob
+
+	running sync.Mutex
+}
+
+// NewJob 按配置创建 ABC 分类任务；ABC_SCHEDULE 为空或 off 时只在启动时与手动触发时计算
+func NewJob(db *gorm.DB, cfg *config.Config) (*Job, error) {
+	j := &Job{
+		repo: repository.NewABCRepository(db),
+		params: repository.ABCParams{
+			Metric:     strings.ToLower(strings.TrimSpace(cfg.ABCMetric)),
+			WindowDays: cfg.ABCWindowDays,
+			ThresholdA: cfg.ABCThresholdA,
+			ThresholdB: cfg.ABCThresholdB,
+		},
+	}
+	if j.params.WindowDays <= 0 {
+		j.params.WindowDays = repository.DefaultABCWindowDays
+	}
+	if err := j.params.Validate(); err != nil {
+		return nil, fmt.Errorf("ABC 分类配置: %w", err)
+	}
+	if cron.Enabled(cfg.ABCSchedule) {
+		var err error
+		if j.loop.Schedule, err = cron.Parse(cfg.ABCSchedule); err != nil {
+			return nil, fmt.Errorf("ABC_SCHEDULE: %w", err)
+		}
+	}
+	j.loop.Name = "ABC 分类计算"
+	j.loop.Workspaces = repository.NewWorkspaceRepository(db).IDsWithComponents
+	j.loop.Run = func(workspaceID uint, now time.Time) error {
+		_, err := j.ClassifyWorkspace(workspaceID, now)
+		return err
+	}
+	return j, nil
+}
+
+// Params 返回分类使用的参数
+func (j *Job) Params() repository.ABCParams {
+	return j.params
+}
+
+// Start 先计算一次，再按 cron 定时计算，ctx 取消时退出
+func (j *Job) Start(ctx context.Context) {
+	j.loop.Start(ctx)
+}
+
+// ClassifyWorkspace 重新计算单个工作区，返回各分类的元件数
+func (j *Job) ClassifyWorkspace(workspaceID uint, now time.Time) (map[string]int, error) {
+	j.running.Lock()
+	defer j.running.Unlock()
+	return j.repo.ForWorkspace(workspaceID).Classify(j.params, now)
+}
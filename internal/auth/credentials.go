@@ -1,6 +1,13 @@
 package auth
 
-import "crypto/subtle"
+import (
+	"crypto/subtle"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength 账号密码最短长度
+const MinPasswordLength = 8
 
 // CheckCredentials 使用恒定时间比较校验管理员凭据
 func CheckCredentials(username, password, expectedUsername, expectedPassword string) bool {
@@ -8,3 +15,17 @@ func CheckCredentials(username, password, expectedUsername, expectedPassword str
 	passwordMatch := subtle.ConstantTimeCompare([]byte(password), []byte(expectedPassword)) == 1
 	return usernameMatch && passwordMatch
 }
+
+// HashPassword 生成账号密码的 bcrypt 哈希
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword 校验密码与 bcrypt 哈希是否匹配
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
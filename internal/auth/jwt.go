@@ -10,23 +10,49 @@ import (
 
 const CookieName = "hamster_token"
 
+// PendingCookieName 密码校验通过、等待二次验证时使用的短期 Cookie
+const PendingCookieName = "hamster_2fa_token"
+
+// PendingTokenMinutes 二次验证等待 token 的有效期（分钟）
+const PendingTokenMinutes = 5
+
+const purposeTwoFactor = "2fa"
+
 var (
 	ErrInvalidToken = errors.New("invalid token")
 )
 
 type Claims struct {
 	Username string `json:"username"`
+	Purpose  string `json:"purpose,omitempty"` // 非空表示受限用途 token，不可访问业务接口
+	Verified bool   `json:"tfa,omitempty"`     // 登录时已通过二次验证
 	jwt.RegisteredClaims
 }
 
 // IssueToken 签发 JWT
 func IssueToken(username, secret string, expireHours int) (string, error) {
+	return issueToken(username, "", false, secret, time.Duration(expireHours)*time.Hour)
+}
+
+// IssueVerifiedToken 签发通过二次验证后的 JWT；账号启用二次验证后只接受此类 token
+func IssueVerifiedToken(username, secret string, expireHours int) (string, error) {
+	return issueToken(username, "", true, secret, time.Duration(expireHours)*time.Hour)
+}
+
+// IssuePendingToken 签发二次验证等待 token，仅可用于 /auth/2fa 相关接口
+func IssuePendingToken(username, secret string) (string, error) {
+	return issueToken(username, purposeTwoFactor, false, secret, PendingTokenMinutes*time.Minute)
+}
+
+func issueToken(username, purpose string, verified bool, secret string, ttl time.Duration) (string, error) {
 	now := time.Now()
 	claims := Claims{
 		Username: username,
+		Purpose:  purpose,
+		Verified: verified,
 		RegisteredClaims: jwt.RegisteredClaims{
 			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(expireHours) * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 		},
 	}
 
@@ -38,8 +64,31 @@ func IssueToken(username, secret string, expireHours int) (string, error) {
 	return signed, nil
 }
 
-// ParseToken 解析并校验 JWT
+// ParseToken 解析并校验 JWT；受限用途 token 视为无效
 func ParseToken(tokenString, secret string) (*Claims, error) {
+	claims, err := parseToken(tokenString, secret)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// ParsePendingToken 解析二次验证等待 token
+func ParsePendingToken(tokenString, secret string) (*Claims, error) {
+	claims, err := parseToken(tokenString, secret)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != purposeTwoFactor {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func parseToken(tokenString, secret string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (any, error) {
 		if token.Method != jwt.SigningMethodHS256 {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -58,3 +58,29 @@ func TestCheckCredentials(t *testing.T) {
 		t.Fatal("expected invalid username")
 	}
 }
+
+func TestPendingTokenIsNotAccessToken(t *testing.T) {
+	secret := "test-secret-key"
+	pending, err := IssuePendingToken("admin", secret)
+	if err != nil {
+		t.Fatalf("IssuePendingToken() error = %v", err)
+	}
+	if _, err := ParseToken(pending, secret); err == nil {
+		t.Fatal("expected pending token to be rejected as access token")
+	}
+	claims, err := ParsePendingToken(pending, secret)
+	if err != nil {
+		t.Fatalf("ParsePendingToken() error = %v", err)
+	}
+	if claims.Username != "admin" {
+		t.Fatalf("username = %q, want admin", claims.Username)
+	}
+
+	access, err := IssueToken("admin", secret, 1)
+	if err != nil {
+		t.Fatalf("IssueToken() error = %v", err)
+	}
+	if _, err := ParsePendingToken(access, secret); err == nil {
+		t.Fatal("expected access token to be rejected as pending token")
+	}
+}
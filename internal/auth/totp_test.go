@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 的 SHA1 测试向量（取后 6 位）
+var rfc6238Secret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	tests := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range tests {
+		got, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d) error = %v", unix, err)
+		}
+		if got != want {
+			t.Fatalf("TOTPCode(%d) = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestValidateTOTPSkewAndReplay(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret() error = %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	prevCode, _ := TOTPCode(secret, TOTPStep(now)-1)
+
+	step, ok := ValidateTOTP(secret, prevCode, now, 0)
+	if !ok || step != TOTPStep(now)-1 {
+		t.Fatalf("ValidateTOTP previous step = (%d, %v), want accepted", step, ok)
+	}
+	if _, ok := ValidateTOTP(secret, prevCode, now, step); ok {
+		t.Fatal("expected replayed code to be rejected")
+	}
+
+	oldCode, _ := TOTPCode(secret, TOTPStep(now)-3)
+	if _, ok := ValidateTOTP(secret, oldCode, now, 0); ok {
+		t.Fatal("expected code outside skew window to be rejected")
+	}
+	if _, ok := ValidateTOTP(secret, "12345", now, 0); ok {
+		t.Fatal("expected short code to be rejected")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI(TOTPIssuer, "admin", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/Hamster%20Bin:admin?") {
+		t.Fatalf("unexpected uri prefix: %s", uri)
+	}
+	for _, part := range []string{"secret=JBSWY3DPEHPK3PXP", "issuer=Hamster+Bin", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Fatalf("uri %s missing %s", uri, part)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes() error = %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("len(codes) = %d, want %d", len(codes), recoveryCodeCount)
+	}
+	seen := make(map[string]struct{}, len(codes))
+	for _, code := range codes {
+		if len(code) != 14 || code[4] != '-' || code[9] != '-' {
+			t.Fatalf("unexpected recovery code format: %q", code)
+		}
+		seen[code] = struct{}{}
+	}
+	if len(seen) != len(codes) {
+		t.Fatal("expected recovery codes to be unique")
+	}
+	if HashRecoveryCode(codes[0]) != HashRecoveryCode(" "+strings.ToUpper(codes[0])+" ") {
+		t.Fatal("expected hash to ignore case and surrounding spaces")
+	}
+}
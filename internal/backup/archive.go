@@ -0,0 +1,291 @@
+// Package backup 负责整库备份与恢复：备份文件为 zip，包含清单、按表导出的 JSON Lines 与图片目录。
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/database"
+	"github.com/Rehtt/hamster-bin/internal/version"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// ArchiveFormat 备份清单中的格式标识
+	ArchiveFormat = "hamster-bin-backup"
+	// ArchiveFormatVersion 备份文件结构版本
+	ArchiveFormatVersion = 1
+
+	manifestName = "manifest.json"
+	tableDir     = "db/"
+	imageDir     = "images/"
+)
+
+var ErrInvalidArchive = errors.New("备份文件无效")
+
+// Manifest 备份清单
+type Manifest struct {
+	Format        string          `json:"format"`
+	FormatVersion int             `json:"format_version"`
+	SchemaVersion int             `json:"schema_version"`
+	AppVersion    string          `json:"app_version"`
+	DBDriver      string          `json:"db_driver"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Tables        []TableManifest `json:"tables"`
+	Images        int             `json:"images"`
+	ImageBytes    int64           `json:"image_bytes"`
+}
+
+// TableManifest 单表行数
+type TableManifest struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+}
+
+// tableCodec 按 gorm 模型的数据库列（而非 JSON 标签）编解码行，保证敏感字段与关联以外的所有列都被备份
+type tableCodec struct {
+	name   string
+	schema *schema.Schema
+	fields []*schema.Field
+}
+
+func newTableCodecs(db *gorm.DB) ([]*tableCodec, error) {
+	codecs := make([]*tableCodec, 0, len(database.Models()))
+	for _, model := range database.Models() {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		codec := &tableCodec{name: stmt.Schema.Table, schema: stmt.Schema}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" {
+				codec.fields = append(codec.fields, field)
+			}
+		}
+		codecs = append(codecs, codec)
+	}
+	return codecs, nil
+}
+
+func (t *tableCodec) entryName() string {
+	return tableDir + t.name + ".jsonl"
+}
+
+// newRow 返回指向新模型实例的指针
+func (t *tableCodec) newRow() reflect.Value {
+	return reflect.New(t.schema.ModelType)
+}
+
+// encode 将一行编码为以列名为键、按模型字段顺序排列的 JSON 对象
+func (t *tableCodec) encode(ctx context.Context, row reflect.Value, buf *bytes.Buffer) error {
+	buf.WriteByte('{')
+	for i, field := range t.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.DBName)
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(field.ReflectValueOf(ctx, row.Elem()).Interface())
+		if err != nil {
+			return fmt.Errorf("编码 %s.%s 失败: %w", t.name, field.DBName, err)
+		}
+		buf.Write(value)
+	}
+	buf.WriteString("}\n")
+	return nil
+}
+
+// decode 将 JSON 对象解码为模型实例指针；未知列视为备份文件损坏
+func (t *tableCodec) decode(ctx context.Context, line []byte) (reflect.Value, error) {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(line, &values); err != nil {
+		return reflect.Value{}, err
+	}
+	row := t.newRow()
+	for _, field := range t.fields {
+		raw, ok := values[field.DBName]
+		if !ok {
+			continue
+		}
+		delete(values, field.DBName)
+		if err := json.Unmarshal(raw, field.ReflectValueOf(ctx, row.Elem()).Addr().Interface()); err != nil {
+			return reflect.Value{}, fmt.Errorf("列 %s: %w", field.DBName, err)
+		}
+	}
+	for key := range values {
+		return reflect.Value{}, fmt.Errorf("未知列 %s", key)
+	}
+	return row, nil
+}
+
+type imageFile struct {
+	rel  string
+	size int64
+}
+
+// listImages 列出图片目录下的全部文件（含子目录），目录不存在时返回空
+func listImages(dir string) ([]imageFile, error) {
+	var files []imageFile
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && p == dir {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, imageFile{rel: filepath.ToSlash(rel), size: info.Size()})
+		return nil
+	})
+	return files, err
+}
+
+// Write 将数据库与图片目录写为 zip 备份。所有表在同一只读事务中读取，得到一致快照；
+// 行与图片逐个流式写出，不在内存中缓存整表。
+func Write(ctx context.Context, db *gorm.DB, imagesDir string, w io.Writer) (*Manifest, error) {
+	codecs, err := newTableCodecs(db)
+	if err != nil {
+		return nil, err
+	}
+	images, err := listImages(imagesDir)
+	if err != nil {
+		return nil, fmt.Errorf("读取图片目录失败: %w", err)
+	}
+
+	manifest := &Manifest{
+		Format:        ArchiveFormat,
+		FormatVersion: ArchiveFormatVersion,
+		SchemaVersion: database.SchemaVersion,
+		AppVersion:    version.Version,
+		DBDriver:      db.Dialector.Name(),
+		CreatedAt:     time.Now(),
+		Images:        len(images),
+	}
+	for _, image := range images {
+		manifest.ImageBytes += image.size
+	}
+
+	zw := zip.NewWriter(w)
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, codec := range codecs {
+			var count int64
+			if err := tx.Table(codec.name).Count(&count).Error; err != nil {
+				return err
+			}
+			manifest.Tables = append(manifest.Tables, TableManifest{Name: codec.name, Rows: count})
+		}
+
+		entry, err := zw.Create(manifestName)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(manifest); err != nil {
+			return err
+		}
+
+		for _, codec := range codecs {
+			if err := writeTable(ctx, tx, codec, zw); err != nil {
+				return fmt.Errorf("导出表 %s 失败: %w", codec.name, err)
+			}
+		}
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, image := range images {
+		if err := writeImage(zw, imagesDir, image); err != nil {
+			return nil, fmt.Errorf("写入图片 %s 失败: %w", image.rel, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func writeTable(ctx context.Context, tx *gorm.DB, codec *tableCodec, zw *zip.Writer) error {
+	entry, err := zw.Create(codec.entryName())
+	if err != nil {
+		return err
+	}
+	rows, err := tx.Table(codec.name).Order(primaryKeyColumn(codec)).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var buf bytes.Buffer
+	for rows.Next() {
+		row := codec.newRow()
+		if err := tx.ScanRows(rows, row.Interface()); err != nil {
+			return err
+		}
+		buf.Reset()
+		if err := codec.encode(ctx, row, &buf); err != nil {
+			return err
+		}
+		if _, err := entry.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func primaryKeyColumn(codec *tableCodec) string {
+	if codec.schema.PrioritizedPrimaryField != nil {
+		return codec.schema.PrioritizedPrimaryField.DBName
+	}
+	return ""
+}
+
+// writeImage 图片（AVIF 等）本身已压缩，按原样存储
+func writeImage(zw *zip.Writer, dir string, image imageFile) error {
+	file, err := os.Open(filepath.Join(dir, filepath.FromSlash(image.rel)))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	entry, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     path.Join(imageDir, image.rel),
+		Method:   zip.Store,
+		Modified: info.ModTime(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, file)
+	return err
+}
@@ -0,0 +1,427 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/Rehtt/hamster-bin/internal/database"
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/Rehtt/hamster-bin/internal/repository"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupBackupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	// 单连接：内存库每个连接相互独立，同时可暴露事务外的查询
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("db: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(database.Models()...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := repository.NewWorkspaceRepository(db).EnsureDefault(); err != nil {
+		t.Fatalf("ensure default workspace: %v", err)
+	}
+	return db
+}
+
+func strPtr(s string) *string { return &s }
+
+// seedBackupFixtures 写入一组覆盖全部表的数据，返回元件 ID
+func seedBackupFixtures(t *testing.T, db *gorm.DB, imagesDir string) uint {
+	t.Helper()
+	parent := models.Category{Name: "被动元件"}
+	mustCreate(t, db, &parent)
+	child := models.Category{Name: "电阻", ParentID: &parent.ID}
+	mustCreate(t, db, &child)
+	supplier := models.Supplier{Name: "嘉立创", ProductURLTemplate: "https://item.szlcsc.com/{sku}.html"}
+	mustCreate(t, db, &supplier)
+	component := models.Component{
+		CategoryID:      child.ID,
+		SupplierID:      &supplier.ID,
+		ComponentNumber: strPtr("HB-000001"),
+		Name:            "贴片电阻",
+		StockQuantity:   90,
+		UnitPriceMicro:  12345,
+		Description:     "含 \"引号\" 与\n换行",
+	}
+	mustCreate(t, db, &component)
+	stockIn := models.StockLog{ComponentID: component.ID, ChangeAmount: 100, Reason: "入库"}
+	mustCreate(t, db, &stockIn)
+	stockOut := models.StockLog{ComponentID: component.ID, ChangeAmount: -10, Reason: "出库", ReversalOfID: &stockIn.ID}
+	mustCreate(t, db, &stockOut)
+	mustCreate(t, db, &models.ComponentTag{ComponentID: component.ID, Name: "常用"})
+	mustCreate(t, db, &models.PreStock{CategoryID: child.ID, ComponentNumber: strPtr("HB-000002"), Name: "电容", ExpectedQuantity: 5})
+	mustCreate(t, db, &models.WorkspaceMember{WorkspaceID: repository.DefaultWorkspaceID, Username: "alice", Role: "editor"})
+	mustCreate(t, db, &models.TwoFactorAuth{Username: "alice", Secret: "SECRET", Enabled: true})
+	mustCreate(t, db, &models.TwoFactorRecoveryCode{Username: "alice", CodeHash: "hash"})
+	mustCreate(t, db, &models.SavedSearch{Name: "低库存电阻", Owner: "alice", Shared: true, Params: models.SavedSearchParams{
+		CategoryID: &child.ID, Q: "stock:<100", Columns: []string{"name", "stock_quantity"},
+	}})
+
+	if imagesDir != "" {
+		writeTestFile(t, filepath.Join(imagesDir, "1.avif"), "image-1")
+		writeTestFile(t, filepath.Join(imagesDir, "extra", "note.txt"), "note")
+	}
+	return component.ID
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, value any) {
+	t.Helper()
+	if err := db.Create(value).Error; err != nil {
+		t.Fatalf("create %T: %v", value, err)
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+}
+
+func writeTestArchive(t *testing.T, db *gorm.DB, imagesDir string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := Write(context.Background(), db, imagesDir, &buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func restoreTestArchive(t *testing.T, db *gorm.DB, imagesDir string, data []byte, mode string) *RestoreReport {
+	t.Helper()
+	report, err := Restore(context.Background(), db, imagesDir, bytes.NewReader(data), int64(len(data)), mode, false)
+	if err != nil {
+		t.Fatalf("Restore %s: %v", mode, err)
+	}
+	return report
+}
+
+func tableReport(report *RestoreReport, name string) TableReport {
+	for _, table := range report.Tables {
+		if table.Name == name {
+			return table
+		}
+	}
+	return TableReport{Name: name}
+}
+
+func TestWriteManifest(t *testing.T) {
+	db := setupBackupTestDB(t)
+	imagesDir := t.TempDir()
+	seedBackupFixtures(t, db, imagesDir)
+
+	data := writeTestArchive(t, db, imagesDir)
+	manifest, err := Inspect(context.Background(), db, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if manifest.SchemaVersion != database.SchemaVersion || manifest.DBDriver != "sqlite" {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+	if manifest.Images != 2 || manifest.ImageBytes != int64(len("image-1")+len("note")) {
+		t.Fatalf("images = %d (%d bytes), want 2", manifest.Images, manifest.ImageBytes)
+	}
+	rows := map[string]int64{}
+	for _, table := range manifest.Tables {
+		rows[table.Name] = table.Rows
+	}
+	if rows["workspaces"] != 1 || rows["categories"] != 2 || rows["stock_logs"] != 2 || rows["two_factor_recovery_codes"] != 1 {
+		t.Fatalf("table rows = %v", rows)
+	}
+}
+
+func TestRestoreReplaceRoundTrip(t *testing.T) {
+	source := setupBackupTestDB(t)
+	sourceImages := t.TempDir()
+	componentID := seedBackupFixtures(t, source, sourceImages)
+	data := writeTestArchive(t, source, sourceImages)
+
+	target := setupBackupTestDB(t)
+	targetImages := t.TempDir()
+	// 覆盖恢复前的旧数据与旧图片应被清空
+	mustCreate(t, target, &models.Category{Name: "旧分类"})
+	writeTestFile(t, filepath.Join(targetImages, "99.avif"), "stale")
+
+	report := restoreTestArchive(t, target, targetImages, data, RestoreModeReplace)
+	if got := tableReport(report, "components").Inserted; got != 1 {
+		t.Fatalf("components inserted = %d, want 1", got)
+	}
+	if report.ImagesRestored != 2 {
+		t.Fatalf("images restored = %d, want 2", report.ImagesRestored)
+	}
+
+	var component models.Component
+	if err := target.First(&component, componentID).Error; err != nil {
+		t.Fatalf("load component: %v", err)
+	}
+	if component.Description != "含 \"引号\" 与\n换行" || component.UnitPriceMicro != 12345 || *component.ComponentNumber != "HB-000001" {
+		t.Fatalf("component = %+v", component)
+	}
+	var categories []models.Category
+	target.Order("id").Find(&categories)
+	if len(categories) != 2 || categories[1].ParentID == nil || *categories[1].ParentID != categories[0].ID {
+		t.Fatalf("categories = %+v", categories)
+	}
+	var secret models.TwoFactorAuth
+	if err := target.Where("username = ?", "alice").First(&secret).Error; err != nil || secret.Secret != "SECRET" || !secret.Enabled {
+		t.Fatalf("two factor = %+v, err %v", secret, err)
+	}
+	var saved models.SavedSearch
+	if err := target.First(&saved).Error; err != nil || saved.Params.Q != "stock:<100" || len(saved.Params.Columns) != 2 || *saved.Params.CategoryID != categories[1].ID {
+		t.Fatalf("saved search = %+v, err %v", saved, err)
+	}
+	if _, err := os.Stat(filepath.Join(targetImages, "99.avif")); !os.IsNotExist(err) {
+		t.Fatalf("stale image should be removed, stat err = %v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(targetImages, "extra", "note.txt")); err != nil || string(content) != "note" {
+		t.Fatalf("nested image = %q, err %v", content, err)
+	}
+
+	// 恢复后的自增 ID 继续递增
+	next := models.Category{Name: "新分类"}
+	mustCreate(t, target, &next)
+	if next.ID <= categories[1].ID {
+		t.Fatalf("new category id = %d, want > %d", next.ID, categories[1].ID)
+	}
+}
+
+func TestRestoreMergeRemapsIDs(t *testing.T) {
+	source := setupBackupTestDB(t)
+	sourceImages := t.TempDir()
+	componentID := seedBackupFixtures(t, source, sourceImages)
+	// 合并记录指向已删除的元件，冲销记录指向备份中不存在的记录，合并后都应置空
+	missing := uint(999)
+	mustCreate(t, source, &models.StockLog{ComponentID: componentID, Reason: "合并", MergedFromID: &missing, ReversalOfID: &missing})
+	data := writeTestArchive(t, source, sourceImages)
+
+	target := setupBackupTestDB(t)
+	targetImages := t.TempDir()
+	// 目标库已有同名分类与占用 ID 1 的其他元件，且 HB-000001 被预入库占用
+	existing := models.Category{Name: "被动元件"}
+	mustCreate(t, target, &existing)
+	other := models.Category{Name: "芯片"}
+	mustCreate(t, target, &other)
+	mustCreate(t, target, &models.Component{CategoryID: other.ID, ComponentNumber: strPtr("HB-000009"), Name: "MCU"})
+	mustCreate(t, target, &models.PreStock{CategoryID: other.ID, ComponentNumber: strPtr("HB-000001"), Name: "占位"})
+	writeTestFile(t, filepath.Join(targetImages, "1.avif"), "mcu-image")
+
+	report := restoreTestArchive(t, target, targetImages, data, RestoreModeMerge)
+	if got := tableReport(report, "workspaces"); got.Inserted != 0 || got.Skipped != 1 {
+		t.Fatalf("workspaces report = %+v", got)
+	}
+	if got := tableReport(report, "categories"); got.Inserted != 1 || got.Skipped != 1 {
+		t.Fatalf("categories report = %+v", got)
+	}
+
+	var merged models.Component
+	if err := target.Where("name = ?", "贴片电阻").First(&merged).Error; err != nil {
+		t.Fatalf("load merged component: %v", err)
+	}
+	if merged.ID == 1 || merged.ComponentNumber == nil || *merged.ComponentNumber == "HB-000001" {
+		t.Fatalf("merged component should get new id and number, got %+v", merged)
+	}
+	var resistor models.Category
+	target.First(&resistor, merged.CategoryID)
+	if resistor.Name != "电阻" || resistor.ParentID == nil || *resistor.ParentID != existing.ID {
+		t.Fatalf("category = %+v, want child of %d", resistor, existing.ID)
+	}
+
+	var logs []models.StockLog
+	target.Where("component_id = ?", merged.ID).Order("id").Find(&logs)
+	if len(logs) != 3 || logs[1].ReversalOfID == nil || *logs[1].ReversalOfID != logs[0].ID {
+		t.Fatalf("stock logs = %+v", logs)
+	}
+	if logs[2].ReversalOfID != nil || logs[2].MergedFromID != nil {
+		t.Fatalf("unmapped references should be cleared: %+v", logs[2])
+	}
+	var tags []models.ComponentTag
+	target.Find(&tags)
+	if len(tags) != 1 || tags[0].ComponentID != merged.ID || tags[0].Name != "常用" {
+		t.Fatalf("tags = %+v, want 常用 on %d", tags, merged.ID)
+	}
+	var saved models.SavedSearch
+	if err := target.Where("name = ?", "低库存电阻").First(&saved).Error; err != nil || saved.Params.CategoryID == nil || *saved.Params.CategoryID != resistor.ID {
+		t.Fatalf("saved search category should follow merged category %d: %+v, err %v", resistor.ID, saved, err)
+	}
+	// 预入库 HB-000002 不冲突，正常写入
+	var preStocks int64
+	target.Model(&models.PreStock{}).Where("component_number = ?", "HB-000002").Count(&preStocks)
+	if preStocks != 1 {
+		t.Fatalf("pre-stock HB-000002 count = %d, want 1", preStocks)
+	}
+
+	// 元件图片按新 ID 写入，不覆盖已有元件的图片
+	if content, _ := os.ReadFile(filepath.Join(targetImages, "1.avif")); string(content) != "mcu-image" {
+		t.Fatalf("existing image overwritten: %q", content)
+	}
+	name := filepath.Join(targetImages, strconv.FormatUint(uint64(merged.ID), 10)+".avif")
+	if content, err := os.ReadFile(name); err != nil || string(content) != "image-1" {
+		t.Fatalf("merged image = %q, err %v", content, err)
+	}
+}
+
+func TestRestoreMergeIntoSourceIsNoop(t *testing.T) {
+	db := setupBackupTestDB(t)
+	imagesDir := t.TempDir()
+	seedBackupFixtures(t, db, imagesDir)
+	data := writeTestArchive(t, db, imagesDir)
+
+	report := restoreTestArchive(t, db, imagesDir, data, RestoreModeMerge)
+	for _, table := range report.Tables {
+		if table.Inserted != 0 {
+			t.Fatalf("merge into source inserted into %s: %+v", table.Name, table)
+		}
+	}
+	// 非元件图片已存在时同样跳过
+	if report.ImagesRestored != 0 || report.ImagesSkipped != 2 {
+		t.Fatalf("images restored %d skipped %d, want 0/2", report.ImagesRestored, report.ImagesSkipped)
+	}
+}
+
+func TestRestoreDryRunDoesNotWrite(t *testing.T) {
+	source := setupBackupTestDB(t)
+	seedBackupFixtures(t, source, "")
+	data := writeTestArchive(t, source, "")
+
+	target := setupBackupTestDB(t)
+	report, err := Restore(context.Background(), target, t.TempDir(), bytes.NewReader(data), int64(len(data)), RestoreModeReplace, true)
+	if err != nil {
+		t.Fatalf("Restore dry run: %v", err)
+	}
+	if !report.DryRun || len(report.Tables) != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	var count int64
+	target.Model(&models.Component{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("dry run wrote %d components", count)
+	}
+}
+
+func TestRestoreRejectsInvalidArchive(t *testing.T) {
+	db := setupBackupTestDB(t)
+	seedBackupFixtures(t, db, "")
+	valid := writeTestArchive(t, db, "")
+
+	rewrite := func(edit func(name string, content []byte) (string, []byte)) []byte {
+		reader, err := zip.NewReader(bytes.NewReader(valid), int64(len(valid)))
+		if err != nil {
+			t.Fatalf("read zip: %v", err)
+		}
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, file := range reader.File {
+			rc, _ := file.Open()
+			var content bytes.Buffer
+			content.ReadFrom(rc)
+			rc.Close()
+			name, data := edit(file.Name, content.Bytes())
+			if name == "" {
+				continue
+			}
+			w, _ := zw.Create(name)
+			w.Write(data)
+		}
+		zw.Close()
+		return buf.Bytes()
+	}
+
+	cases := map[string][]byte{
+		"not zip": []byte("plain text"),
+		"missing manifest": rewrite(func(name string, content []byte) (string, []byte) {
+			if name == manifestName {
+				return "", nil
+			}
+			return name, content
+		}),
+		"future schema": rewrite(func(name string, content []byte) (string, []byte) {
+			if name == manifestName {
+				var manifest Manifest
+				json.Unmarshal(content, &manifest)
+				manifest.SchemaVersion = database.SchemaVersion + 1
+				content, _ = json.Marshal(manifest)
+			}
+			return name, content
+		}),
+		"row count mismatch": rewrite(func(name string, content []byte) (string, []byte) {
+			if name == "db/components.jsonl" {
+				return name, nil
+			}
+			return name, content
+		}),
+		"unknown column": rewrite(func(name string, content []byte) (string, []byte) {
+			if name == "db/suppliers.jsonl" {
+				return name, bytes.Replace(content, []byte(`"name"`), []byte(`"nickname"`), 1)
+			}
+			return name, content
+		}),
+		"path traversal": rewrite(func(name string, content []byte) (string, []byte) {
+			if name == manifestName {
+				var manifest Manifest
+				json.Unmarshal(content, &manifest)
+				manifest.Images = 1
+				content, _ = json.Marshal(manifest)
+			}
+			return name, content
+		}),
+	}
+	// path traversal：额外写入越界图片
+	cases["path traversal"] = appendZipEntry(t, cases["path traversal"], imageDir+"../../evil.avif")
+
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Restore(context.Background(), db, t.TempDir(), bytes.NewReader(data), int64(len(data)), RestoreModeReplace, false)
+			if !errors.Is(err, ErrInvalidArchive) {
+				t.Fatalf("err = %v, want ErrInvalidArchive", err)
+			}
+		})
+	}
+
+	var count int64
+	db.Model(&models.Component{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("invalid archive changed data: components = %d", count)
+	}
+
+	if _, err := Restore(context.Background(), db, t.TempDir(), bytes.NewReader(valid), int64(len(valid)), "overwrite", false); !errors.Is(err, ErrInvalidRestoreMode) {
+		t.Fatalf("err = %v, want ErrInvalidRestoreMode", err)
+	}
+}
+
+func appendZipEntry(t *testing.T, data []byte, name string) []byte {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("read zip: %v", err)
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range reader.File {
+		if err := zw.Copy(file); err != nil {
+			t.Fatalf("copy entry: %v", err)
+		}
+	}
+	w, _ := zw.Create(name)
+	w.Write([]byte("evil"))
+	zw.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,441 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/Rehtt/hamster-bin/internal/repository"
+	"gorm.io/gorm"
+)
+
+// componentImagePattern 元件图片按元件 ID 命名
+var componentImagePattern = regexp.MustCompile(`^(\d+)\.avif$`)
+
+// merger 把备份数据合并进现有数据：所有记录重新分配 ID，并按以下规则匹配已有记录（匹配到则跳过）：
+// 工作区按名称；账号按用户名；成员按工作区 + 用户名；分类按工作区 + 上级分类 + 名称；供应商按工作区 + 名称；
+// 元件与预入库按工作区 + 元件编号；保存搜索按工作区 + 创建人 + 名称。库存记录与标签只随新写入的元件一起导入；
+// 二次验证按用户名，已存在则跳过。
+type merger struct {
+	tx         *gorm.DB
+	workspaces map[uint]uint
+	categories map[uint]uint
+	suppliers  map[uint]uint
+	// components 备份元件 ID → 现有元件 ID（含匹配到的已有元件）
+	components map[uint]uint
+	// insertedComponents 新写入的元件，其库存记录与图片随之导入
+	insertedComponents map[uint]uint
+	stockLogs          map[uint]uint
+	twoFactorUsers     map[string]bool
+	reports            map[string]*TableReport
+}
+
+func newMerger(tx *gorm.DB) *merger {
+	return &merger{
+		tx:                 tx,
+		workspaces:         make(map[uint]uint),
+		categories:         make(map[uint]uint),
+		suppliers:          make(map[uint]uint),
+		components:         make(map[uint]uint),
+		insertedComponents: make(map[uint]uint),
+		stockLogs:          make(map[uint]uint),
+		twoFactorUsers:     make(map[string]bool),
+		reports:            make(map[string]*TableReport),
+	}
+}
+
+func (m *merger) run(ctx context.Context, a *archive) ([]TableReport, error) {
+	reports := make([]TableReport, 0, len(a.codecs))
+	for _, codec := range a.codecs {
+		report := &TableReport{Name: codec.name}
+		m.reports[codec.name] = report
+
+		var err error
+		if codec.schema.ModelType == reflect.TypeFor[models.Category]() {
+			err = m.mergeCategories(ctx, a, codec, report)
+		} else {
+			err = a.eachRow(ctx, codec, func(row reflect.Value) error {
+				inserted, err := m.mergeRow(row.Interface())
+				if err != nil {
+					return err
+				}
+				if inserted {
+					report.Inserted++
+				} else {
+					report.Skipped++
+				}
+				return nil
+			})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("合并表 %s 失败: %w", codec.name, err)
+		}
+		reports = append(reports, *report)
+	}
+	return reports, nil
+}
+
+// mergeRow 合并单行，返回是否新写入
+func (m *merger) mergeRow(row any) (bool, error) {
+	switch item := row.(type) {
+	case *models.Workspace:
+		return m.mergeWorkspace(item)
+	case *models.User:
+		return m.mergeUser(item)
+	case *models.WorkspaceMember:
+		return m.mergeMember(item)
+	case *models.Supplier:
+		return m.mergeSupplier(item)
+	case *models.Component:
+		return m.mergeComponent(item)
+	case *models.ComponentTag:
+		// 标签只随新写入的元件导入
+		componentID, ok := m.insertedComponents[item.ComponentID]
+		if !ok {
+			return false, nil
+		}
+		item.ID = 0
+		item.ComponentID = componentID
+		return true, m.tx.Create(item).Error
+	case *models.PreStock:
+		return m.mergePreStock(item)
+	case *models.StockLog:
+		return m.mergeStockLog(item)
+	case *models.SavedSearch:
+		return m.mergeSavedSearch(item)
+	case *models.TwoFactorAuth:
+		return m.mergeTwoFactor(item)
+	case *models.TwoFactorRecoveryCode:
+		if !m.twoFactorUsers[item.Username] {
+			return false, nil
+		}
+		item.ID = 0
+		return true, m.tx.Create(item).Error
+	default:
+		return false, fmt.Errorf("不支持合并 %T", row)
+	}
+}
+
+// findExisting 查询匹配的已有记录 ID，不存在时返回 0
+func (m *merger) findExisting(model any, query string, args ...any) (uint, error) {
+	var ids []uint
+	err := m.tx.Model(model).Where(query, args...).Order("id ASC").Limit(1).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+func (m *merger) workspaceID(oldID uint) (uint, error) {
+	id, ok := m.workspaces[oldID]
+	if !ok {
+		return 0, invalidArchive("引用了不存在的工作区 %d", oldID)
+	}
+	return id, nil
+}
+
+func (m *merger) mergeWorkspace(item *models.Workspace) (bool, error) {
+	existing, err := m.findExisting(&models.Workspace{}, "name = ?", item.Name)
+	if err != nil {
+		return false, err
+	}
+	if existing > 0 {
+		m.workspaces[item.ID] = existing
+		return false, nil
+	}
+	oldID := item.ID
+	item.ID = 0
+	if err := m.tx.Create(item).Error; err != nil {
+		return false, err
+	}
+	m.workspaces[oldID] = item.ID
+	return true, nil
+}
+
+// mergeUser 账号按用户名匹配，已存在时保留现有密码
+func (m *merger) mergeUser(item *models.User) (bool, error) {
+	existing, err := m.findExisting(&models.User{}, "username = ?", item.Username)
+	if err != nil || existing > 0 {
+		return false, err
+	}
+	item.ID = 0
+	return true, m.tx.Create(item).Error
+}
+
+func (m *merger) mergeMember(item *models.WorkspaceMember) (bool, error) {
+	workspaceID, err := m.workspaceID(item.WorkspaceID)
+	if err != nil {
+		return false, err
+	}
+	existing, err := m.findExisting(&models.WorkspaceMember{}, "workspace_id = ? AND username = ?", workspaceID, item.Username)
+	if err != nil || existing > 0 {
+		return false, err
+	}
+	item.ID = 0
+	item.WorkspaceID = workspaceID
+	return true, m.tx.Create(item).Error
+}
+
+// mergeCategories 分类需按层级自上而下处理，才能按上级分类匹配；无法解析的上级（环或缺失）挂到顶级
+func (m *merger) mergeCategories(ctx context.Context, a *archive, codec *tableCodec, report *TableReport) error {
+	var pending []*models.Category
+	if err := a.eachRow(ctx, codec, func(row reflect.Value) error {
+		pending = append(pending, row.Interface().(*models.Category))
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for len(pending) > 0 {
+		var next []*models.Category
+		for _, item := range pending {
+			if item.ParentID != nil {
+				if _, ok := m.categories[*item.ParentID]; !ok {
+					next = append(next, item)
+					continue
+				}
+			}
+			inserted, err := m.mergeCategory(item)
+			if err != nil {
+				return err
+			}
+			if inserted {
+				report.Inserted++
+			} else {
+				report.Skipped++
+			}
+		}
+		if len(next) == len(pending) {
+			for _, item := range next {
+				item.ParentID = nil
+			}
+		}
+		pending = next
+	}
+	return nil
+}
+
+func (m *merger) mergeCategory(item *models.Category) (bool, error) {
+	workspaceID, err := m.workspaceID(item.WorkspaceID)
+	if err != nil {
+		return false, err
+	}
+	var parentID *uint
+	if item.ParentID != nil {
+		id := m.categories[*item.ParentID]
+		parentID = &id
+	}
+
+	query := m.tx.Model(&models.Category{}).Where("workspace_id = ? AND name = ?", workspaceID, item.Name)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	var ids []uint
+	if err := query.Order("id ASC").Limit(1).Pluck("id", &ids).Error; err != nil {
+		return false, err
+	}
+	if len(ids) > 0 {
+		m.categories[item.ID] = ids[0]
+		return false, nil
+	}
+
+	oldID := item.ID
+	item.ID = 0
+	item.WorkspaceID = workspaceID
+	item.ParentID = parentID
+	if err := m.tx.Create(item).Error; err != nil {
+		return false, err
+	}
+	m.categories[oldID] = item.ID
+	return true, nil
+}
+
+func (m *merger) mergeSupplier(item *models.Supplier) (bool, error) {
+	workspaceID, err := m.workspaceID(item.WorkspaceID)
+	if err != nil {
+		return false, err
+	}
+	existing, err := m.findExisting(&models.Supplier{}, "workspace_id = ? AND name = ?", workspaceID, item.Name)
+	if err != nil {
+		return false, err
+	}
+	if existing > 0 {
+		m.suppliers[item.ID] = existing
+		return false, nil
+	}
+	oldID := item.ID
+	item.ID = 0
+	item.WorkspaceID = workspaceID
+	if err := m.tx.Create(item).Error; err != nil {
+		return false, err
+	}
+	m.suppliers[oldID] = item.ID
+	return true, nil
+}
+
+// mapSupplier 映射可选的供应商引用，备份中不存在的供应商置空
+func (m *merger) mapSupplier(id *uint) *uint {
+	return mapID(m.suppliers, id)
+}
+
+func (m *merger) mergeComponent(item *models.Component) (bool, error) {
+	workspaceID, err := m.workspaceID(item.WorkspaceID)
+	if err != nil {
+		return false, err
+	}
+	categoryID, ok := m.categories[item.CategoryID]
+	if !ok {
+		return false, invalidArchive("元件 %d 引用了不存在的分类 %d", item.ID, item.CategoryID)
+	}
+
+	if item.ComponentNumber != nil {
+		existing, err := m.findExisting(&models.Component{}, "workspace_id = ? AND component_number = ?", workspaceID, *item.ComponentNumber)
+		if err != nil {
+			return false, err
+		}
+		if existing > 0 {
+			m.components[item.ID] = existing
+			return false, nil
+		}
+	}
+
+	oldID := item.ID
+	item.ID = 0
+	item.CategoryID = categoryID
+	item.SupplierID = m.mapSupplier(item.SupplierID)
+	// 编号被现有预入库占用时重新编号
+	components := repository.NewComponentRepository(m.tx).ForWorkspace(workspaceID)
+	if err := components.AssignComponentNumberForCreate(item); errors.Is(err, repository.ErrComponentNumberDuplicate) {
+		item.ComponentNumber = nil
+		err = components.AssignComponentNumberForCreate(item)
+		if err != nil {
+			return false, err
+		}
+	} else if err != nil {
+		return false, err
+	}
+	if err := components.Create(item); err != nil {
+		return false, err
+	}
+	m.components[oldID] = item.ID
+	m.insertedComponents[oldID] = item.ID
+	return true, nil
+}
+
+func (m *merger) mergePreStock(item *models.PreStock) (bool, error) {
+	workspaceID, err := m.workspaceID(item.WorkspaceID)
+	if err != nil {
+		return false, err
+	}
+	categoryID, ok := m.categories[item.CategoryID]
+	if !ok {
+		return false, invalidArchive("预入库 %d 引用了不存在的分类 %d", item.ID, item.CategoryID)
+	}
+	if item.ComponentNumber != nil {
+		existing, err := m.findExisting(&models.PreStock{}, "workspace_id = ? AND component_number = ?", workspaceID, *item.ComponentNumber)
+		if err != nil || existing > 0 {
+			return false, err
+		}
+	}
+
+	item.ID = 0
+	item.WorkspaceID = workspaceID
+	item.CategoryID = categoryID
+	item.SupplierID = m.mapSupplier(item.SupplierID)
+	item.ComponentID = mapID(m.components, item.ComponentID)
+	return true, m.tx.Create(item).Error
+}
+
+func (m *merger) mergeStockLog(item *models.StockLog) (bool, error) {
+	componentID, ok := m.insertedComponents[item.ComponentID]
+	if !ok {
+		return false, nil
+	}
+	workspaceID, err := m.workspaceID(item.WorkspaceID)
+	if err != nil {
+		return false, err
+	}
+
+	oldID := item.ID
+	item.ID = 0
+	item.WorkspaceID = workspaceID
+	item.ComponentID = componentID
+	// 引用的记录或元件映射不到时置空，避免指向目标库中无关的行
+	item.ReversalOfID = mapID(m.stockLogs, item.ReversalOfID)
+	item.MergedFromID = mapID(m.components, item.MergedFromID)
+	if err := m.tx.Create(item).Error; err != nil {
+		return false, err
+	}
+	m.stockLogs[oldID] = item.ID
+	return true, nil
+}
+
+// mapID 按映射表换算可空 ID，映射不到时返回 nil
+func mapID(ids map[uint]uint, id *uint) *uint {
+	if id == nil {
+		return nil
+	}
+	mapped, ok := ids[*id]
+	if !ok {
+		return nil
+	}
+	return &mapped
+}
+
+// mergeSavedSearch 条件中的分类 ID 映射为合并后的分类，映射不到时去掉分类条件
+func (m *merger) mergeSavedSearch(item *models.SavedSearch) (bool, error) {
+	workspaceID, err := m.workspaceID(item.WorkspaceID)
+	if err != nil {
+		return false, err
+	}
+	existing, err := m.findExisting(&models.SavedSearch{}, "workspace_id = ? AND owner = ? AND name = ?", workspaceID, item.Owner, item.Name)
+	if err != nil || existing > 0 {
+		return false, err
+	}
+	if item.Params.CategoryID != nil {
+		if mapped, ok := m.categories[*item.Params.CategoryID]; ok {
+			item.Params.CategoryID = &mapped
+		} else {
+			item.Params.CategoryID = nil
+		}
+	}
+	item.ID = 0
+	item.WorkspaceID = workspaceID
+	return true, m.tx.Create(item).Error
+}
+
+func (m *merger) mergeTwoFactor(item *models.TwoFactorAuth) (bool, error) {
+	existing, err := m.findExisting(&models.TwoFactorAuth{}, "username = ?", item.Username)
+	if err != nil || existing > 0 {
+		return false, err
+	}
+	item.ID = 0
+	if err := m.tx.Create(item).Error; err != nil {
+		return false, err
+	}
+	m.twoFactorUsers[item.Username] = true
+	return true, nil
+}
+
+// imageName 元件图片改名为新元件 ID；匹配到已有元件或元件不存在时跳过，其余文件原样恢复
+func (m *merger) imageName(rel string) string {
+	match := componentImagePattern.FindStringSubmatch(rel)
+	if match == nil {
+		return rel
+	}
+	oldID, err := strconv.ParseUint(match[1], 10, 32)
+	if err != nil {
+		return ""
+	}
+	newID, ok := m.insertedComponents[uint(oldID)]
+	if !ok {
+		return ""
+	}
+	return strconv.FormatUint(uint64(newID), 10) + ".avif"
+}
@@ -0,0 +1,390 @@
+package backup
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/Rehtt/hamster-bin/internal/database"
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/Rehtt/hamster-bin/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 恢复方式
+const (
+	// RestoreModeReplace 清空现有数据与图片后按备份原样恢复（保留原 ID）
+	RestoreModeReplace = "replace"
+	// RestoreModeMerge 将备份数据追加到现有数据中，重新分配 ID，已存在的记录跳过
+	RestoreModeMerge = "merge"
+)
+
+// maxRowSize 单行 JSON 的最大长度
+const maxRowSize = 16 << 20
+
+// restoreBatchSize 覆盖恢复时每批插入的行数
+const restoreBatchSize = 200
+
+var ErrInvalidRestoreMode = errors.New("恢复方式仅支持 replace 或 merge")
+
+// TableReport 单表恢复结果
+type TableReport struct {
+	Name     string `json:"name"`
+	Inserted int64  `json:"inserted"`
+	Skipped  int64  `json:"skipped"`
+}
+
+// RestoreReport 恢复结果；DryRun 时只校验备份文件，Tables 为空
+type RestoreReport struct {
+	Mode           string        `json:"mode"`
+	DryRun         bool          `json:"dry_run"`
+	Manifest       *Manifest     `json:"manifest"`
+	Tables         []TableReport `json:"tables"`
+	ImagesRestored int           `json:"images_restored"`
+	ImagesSkipped  int           `json:"images_skipped"`
+}
+
+// IsValidRestoreMode 判断恢复方式是否合法
+func IsValidRestoreMode(mode string) bool {
+	return mode == RestoreModeReplace || mode == RestoreModeMerge
+}
+
+type archive struct {
+	manifest *Manifest
+	codecs   []*tableCodec
+	tables   map[string]*zip.File
+	rows     map[string]int64
+	images   []*zip.File
+}
+
+func invalidArchive(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidArchive, fmt.Sprintf(format, args...))
+}
+
+// openArchive 读取并校验备份文件结构与清单，不解析表数据
+func openArchive(db *gorm.DB, r io.ReaderAt, size int64) (*archive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, invalidArchive("不是有效的 zip 文件")
+	}
+	codecs, err := newTableCodecs(db)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(codecs))
+	for _, codec := range codecs {
+		known[codec.entryName()] = true
+	}
+
+	a := &archive{codecs: codecs, tables: make(map[string]*zip.File), rows: make(map[string]int64)}
+	var manifestFile *zip.File
+	for _, file := range zr.File {
+		switch {
+		case strings.HasSuffix(file.Name, "/"):
+			continue
+		case file.Name == manifestName:
+			manifestFile = file
+		case known[file.Name]:
+			a.tables[file.Name] = file
+		case strings.HasPrefix(file.Name, imageDir):
+			rel := strings.TrimPrefix(file.Name, imageDir)
+			if !filepath.IsLocal(rel) || strings.Contains(rel, `\`) {
+				return nil, invalidArchive("非法的图片路径 %s", file.Name)
+			}
+			a.images = append(a.images, file)
+		default:
+			return nil, invalidArchive("未知的文件 %s", file.Name)
+		}
+	}
+	if manifestFile == nil {
+		return nil, invalidArchive("缺少 %s", manifestName)
+	}
+
+	reader, err := manifestFile.Open()
+	if err != nil {
+		return nil, invalidArchive("读取清单失败: %v", err)
+	}
+	defer reader.Close()
+	if err := json.NewDecoder(reader).Decode(&a.manifest); err != nil {
+		return nil, invalidArchive("解析清单失败: %v", err)
+	}
+
+	m := a.manifest
+	if m.Format != ArchiveFormat || m.FormatVersion != ArchiveFormatVersion {
+		return nil, invalidArchive("不支持的备份格式 %s v%d", m.Format, m.FormatVersion)
+	}
+	if m.SchemaVersion < 1 || m.SchemaVersion > database.SchemaVersion {
+		return nil, invalidArchive("备份表结构版本 %d 高于当前程序支持的版本 %d，请先升级程序", m.SchemaVersion, database.SchemaVersion)
+	}
+	for _, table := range m.Tables {
+		name := tableDir + table.Name + ".jsonl"
+		if a.tables[name] == nil {
+			return nil, invalidArchive("清单中的表 %s 缺少数据文件", table.Name)
+		}
+		a.rows[name] = table.Rows
+	}
+	for name := range a.tables {
+		if _, ok := a.rows[name]; !ok {
+			return nil, invalidArchive("数据文件 %s 未登记在清单中", name)
+		}
+	}
+	if m.Images != len(a.images) {
+		return nil, invalidArchive("清单登记 %d 张图片，实际包含 %d 张", m.Images, len(a.images))
+	}
+	return a, nil
+}
+
+// eachRow 逐行解码指定表；备份中没有该表（旧版本备份）时视为空表
+func (a *archive) eachRow(ctx context.Context, codec *tableCodec, fn func(row reflect.Value) error) error {
+	file := a.tables[codec.entryName()]
+	if file == nil {
+		return nil
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return invalidArchive("读取 %s 失败: %v", file.Name, err)
+	}
+	defer reader.Close()
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxRowSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		row, err := codec.decode(ctx, scanner.Bytes())
+		if err != nil {
+			return invalidArchive("%s 第 %d 行: %v", file.Name, line, err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return invalidArchive("读取 %s 失败: %v", file.Name, err)
+	}
+	return nil
+}
+
+// validate 完整解码所有表并核对行数，确保写入前发现损坏
+func (a *archive) validate(ctx context.Context) error {
+	for _, codec := range a.codecs {
+		var count int64
+		if err := a.eachRow(ctx, codec, func(reflect.Value) error {
+			count++
+			return nil
+		}); err != nil {
+			return err
+		}
+		if expected := a.rows[codec.entryName()]; count != expected {
+			return invalidArchive("表 %s 清单登记 %d 行，实际 %d 行", codec.name, expected, count)
+		}
+	}
+	return nil
+}
+
+// Inspect 校验备份文件并返回清单，不写入任何数据
+func Inspect(ctx context.Context, db *gorm.DB, r io.ReaderAt, size int64) (*Manifest, error) {
+	a, err := openArchive(db, r, size)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.validate(ctx); err != nil {
+		return nil, err
+	}
+	return a.manifest, nil
+}
+
+// Restore 校验备份文件后恢复数据与图片。数据库在单事务中写入，失败时整体回滚；
+// 图片在数据库提交后写入。dryRun 时只校验不写入。
+func Restore(ctx context.Context, db *gorm.DB, imagesDir string, r io.ReaderAt, size int64, mode string, dryRun bool) (*RestoreReport, error) {
+	if !IsValidRestoreMode(mode) {
+		return nil, ErrInvalidRestoreMode
+	}
+	a, err := openArchive(db, r, size)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.validate(ctx); err != nil {
+		return nil, err
+	}
+
+	report := &RestoreReport{Mode: mode, DryRun: dryRun, Manifest: a.manifest, Tables: []TableReport{}}
+	if dryRun {
+		return report, nil
+	}
+
+	var images imageRenamer
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var tables []TableReport
+		var err error
+		if mode == RestoreModeReplace {
+			tables, err = a.replace(ctx, tx)
+		} else {
+			m := newMerger(tx)
+			tables, err = m.run(ctx, a)
+			images = m.imageName
+		}
+		report.Tables = tables
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == RestoreModeReplace {
+		if err := clearImages(imagesDir); err != nil {
+			return nil, fmt.Errorf("清理图片目录失败: %w", err)
+		}
+	}
+	report.ImagesRestored, report.ImagesSkipped, err = a.restoreImages(imagesDir, images)
+	if err != nil {
+		return report, fmt.Errorf("数据已恢复，但写入图片失败: %w", err)
+	}
+	return report, nil
+}
+
+// replace 清空全部表后按原 ID 写入备份数据
+func (a *archive) replace(ctx context.Context, tx *gorm.DB) ([]TableReport, error) {
+	for i := len(a.codecs) - 1; i >= 0; i-- {
+		if err := tx.Exec("DELETE FROM ?", clause.Table{Name: a.codecs[i].name}).Error; err != nil {
+			return nil, fmt.Errorf("清空表 %s 失败: %w", a.codecs[i].name, err)
+		}
+	}
+	// 消耗预测不在备份中，恢复后由定时任务重新计算，避免旧预测挂到同 ID 的新元件上
+	if tx.Migrator().HasTable(&models.ComponentForecast{}) {
+		if err := tx.Where("1 = 1").Delete(&models.ComponentForecast{}).Error; err != nil {
+			return nil, fmt.Errorf("清空消耗预测失败: %w", err)
+		}
+	}
+
+	reports := make([]TableReport, 0, len(a.codecs))
+	for _, codec := range a.codecs {
+		sliceType := reflect.SliceOf(codec.schema.ModelType)
+		batch := reflect.MakeSlice(sliceType, 0, restoreBatchSize)
+		report := TableReport{Name: codec.name}
+		flush := func() error {
+			if batch.Len() == 0 {
+				return nil
+			}
+			ptr := reflect.New(sliceType)
+			ptr.Elem().Set(batch)
+			if err := tx.Create(ptr.Interface()).Error; err != nil {
+				return fmt.Errorf("写入表 %s 失败: %w", codec.name, err)
+			}
+			report.Inserted += int64(batch.Len())
+			batch = reflect.MakeSlice(sliceType, 0, restoreBatchSize)
+			return nil
+		}
+
+		err := a.eachRow(ctx, codec, func(row reflect.Value) error {
+			batch = reflect.Append(batch, row.Elem())
+			if batch.Len() >= restoreBatchSize {
+				return flush()
+			}
+			return nil
+		})
+		if err == nil {
+			err = flush()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := resetSequence(tx, codec); err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	if err := repository.NewWorkspaceRepository(tx).EnsureDefault(); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// resetSequence 显式写入主键后，PostgreSQL 的自增序列不会前移，需要手动对齐到最大 ID
+func resetSequence(tx *gorm.DB, codec *tableCodec) error {
+	primary := codec.schema.PrioritizedPrimaryField
+	if tx.Dialector.Name() != "postgres" || primary == nil || !primary.AutoIncrement {
+		return nil
+	}
+	sql := fmt.Sprintf(
+		"SELECT setval(pg_get_serial_sequence('%s', '%s'), COALESCE((SELECT MAX(%s) FROM %s), 0) + 1, false)",
+		codec.name, primary.DBName, primary.DBName, codec.name,
+	)
+	return tx.Exec(sql).Error
+}
+
+// imageRenamer 返回图片在恢复目标中的相对路径，返回空字符串表示跳过；nil 表示原样恢复
+type imageRenamer func(rel string) string
+
+func (a *archive) restoreImages(dir string, rename imageRenamer) (restored, skipped int, err error) {
+	for _, file := range a.images {
+		rel := strings.TrimPrefix(file.Name, imageDir)
+		if rename != nil {
+			rel = rename(rel)
+		}
+		if rel == "" {
+			skipped++
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+		if rename != nil {
+			if _, err := os.Stat(target); err == nil {
+				skipped++
+				continue
+			}
+		}
+		if err := extractFile(file, target); err != nil {
+			return restored, skipped, fmt.Errorf("%s: %w", rel, err)
+		}
+		restored++
+	}
+	return restored, skipped, nil
+}
+
+func extractFile(file *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, reader); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// clearImages 删除图片目录下的全部文件，保留目录本身
+func clearImages(dir string) error {
+	images, err := listImages(dir)
+	if err != nil {
+		return err
+	}
+	for _, image := range images {
+		if err := os.Remove(filepath.Join(dir, filepath.FromSlash(image.rel))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package backup
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	backupFilePrefix = "hamster-bin-backup-"
+	backupFileSuffix = ".zip"
+	backupTimeLayout = "20060102-150405"
+)
+
+// FileName 返回指定时间的备份文件名
+func FileName(t time.Time) string {
+	return backupFilePrefix + t.Format(backupTimeLayout) + backupFileSuffix
+}
+
+// parseFileName 从备份文件名（可带目录或对象键前缀）解析备份时间
+func parseFileName(name string) (time.Time, bool) {
+	base := path.Base(name)
+	if !strings.HasPrefix(base, backupFilePrefix) || !strings.HasSuffix(base, backupFileSuffix) {
+		return time.Time{}, false
+	}
+	stamp := strings.TrimSuffix(strings.TrimPrefix(base, backupFilePrefix), backupFileSuffix)
+	t, err := time.ParseInLocation(backupTimeLayout, stamp, time.Local)
+	return t, err == nil
+}
+
+// Retention 备份保留策略：分别保留最近 Daily 天、Weekly 周、Monthly 月中每个周期最新的一份，三者取并集。
+// 全部为 0 时不清理。
+type Retention struct {
+	Daily   int `json:"daily"`
+	Weekly  int `json:"weekly"`
+	Monthly int `json:"monthly"`
+}
+
+func (r Retention) enabled() bool {
+	return r.Daily > 0 || r.Weekly > 0 || r.Monthly > 0
+}
+
+// Expired 返回按保留策略应删除的备份名称；无法解析时间的名称不会被删除，最新一份总是保留
+func (r Retention) Expired(names []string) []string {
+	if !r.enabled() {
+		return nil
+	}
+	type entry struct {
+		name string
+		at   time.Time
+	}
+	var entries []entry
+	for _, name := range names {
+		if at, ok := parseFileName(name); ok {
+			entries = append(entries, entry{name, at})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].at.After(entries[j].at) })
+
+	keep := make(map[string]bool)
+	if len(entries) > 0 {
+		keep[entries[0].name] = true
+	}
+	periods := []struct {
+		limit int
+		key   func(time.Time) string
+	}{
+		{r.Daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{r.Weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{r.Monthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	for _, period := range periods {
+		seen := make(map[string]bool)
+		for _, item := range entries {
+			if len(seen) >= period.limit {
+				break
+			}
+			key := period.key(item.at)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			keep[item.name] = true
+		}
+	}
+
+	var expired []string
+	for _, item := range entries {
+		if !keep[item.name] {
+			expired = append(expired, item.name)
+		}
+	}
+	return expired
+}
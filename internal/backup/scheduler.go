@@ -0,0 +1,296 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/cron"
+	"github.com/Rehtt/hamster-bin/internal/database"
+	"gorm.io/gorm"
+)
+
+// SchedulerConfig 定时备份配置
+type SchedulerConfig struct {
+	// Schedule 备份 cron 表达式，为空或 off 时不定时备份（仍可手动触发）
+	Schedule string
+	// MaintenanceSchedule SQLite 维护 cron 表达式，为 off 或非 SQLite 时不执行
+	MaintenanceSchedule string
+	// Dir 本地备份目录
+	Dir       string
+	ImagesDir string
+	Retention Retention
+	// S3 非空时备份完成后上传，并对远端同样执行保留策略
+	S3 *S3Config
+}
+
+// RunStatus 最近一次执行结果
+type RunStatus struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Error      string    `json:"error,omitempty"`
+	// 备份任务
+	File     string `json:"file,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	Uploaded bool   `json:"uploaded,omitempty"`
+	Pruned   int    `json:"pruned,omitempty"`
+	// 维护任务
+	Maintenance *database.MaintenanceResult `json:"maintenance,omitempty"`
+}
+
+// BackupFile 本地备份文件
+type BackupFile struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SchedulerStatus 定时任务状态
+type SchedulerStatus struct {
+	Schedule            string       `json:"schedule"`
+	NextRun             *time.Time   `json:"next_run"`
+	LastRun             *RunStatus   `json:"last_run"`
+	MaintenanceSchedule string       `json:"maintenance_schedule"`
+	NextMaintenance     *time.Time   `json:"next_maintenance"`
+	LastMaintenance     *RunStatus   `json:"last_maintenance"`
+	Retention           Retention    `json:"retention"`
+	Dir                 string       `json:"dir"`
+	S3Enabled           bool         `json:"s3_enabled"`
+	Files               []BackupFile `json:"files"`
+}
+
+var ErrBackupRunning = errors.New("已有备份任务正在执行")
+
+// Scheduler 在服务进程内按 cron 定时备份、清理过期备份并维护 SQLite
+type Scheduler struct {
+	db          *gorm.DB
+	cfg         SchedulerConfig
+	schedule    *cron.Schedule
+	maintenance *cron.Schedule
+	s3          *S3Client
+
+	running sync.Mutex
+	mu      sync.Mutex
+	status  SchedulerStatus
+}
+
+func NewScheduler(db *gorm.DB, cfg SchedulerConfig) (*Scheduler, error) {
+	s := &Scheduler{db: db, cfg: cfg}
+	var err error
+	if cron.Enabled(cfg.Schedule) {
+		if s.schedule, err = cron.Parse(cfg.Schedule); err != nil {
+			return nil, fmt.Errorf("BACKUP_SCHEDULE: %w", err)
+		}
+	}
+	if cron.Enabled(cfg.MaintenanceSchedule) && db.Dialector.Name() == "sqlite" {
+		if s.maintenance, err = cron.Parse(cfg.MaintenanceSchedule); err != nil {
+			return nil, fmt.Errorf("DB_MAINTENANCE_SCHEDULE: %w", err)
+		}
+	}
+	if cfg.S3 != nil {
+		if s.s3, err = NewS3Client(*cfg.S3); err != nil {
+			return nil, err
+		}
+	}
+
+	s.status = SchedulerStatus{Retention: cfg.Retention, Dir: cfg.Dir, S3Enabled: s.s3 != nil}
+	if s.schedule != nil {
+		s.status.Schedule = s.schedule.String()
+	}
+	if s.maintenance != nil {
+		s.status.MaintenanceSchedule = s.maintenance.String()
+	}
+	return s, nil
+}
+
+// Start 启动定时任务，ctx 取消时退出
+func (s *Scheduler) Start(ctx context.Context) {
+	if s.schedule != nil {
+		go s.loop(ctx, s.schedule, func(next *time.Time) { s.status.NextRun = next }, func() {
+			if _, err := s.RunBackup(ctx); err != nil {
+				log.Printf("定时备份失败: %v", err)
+			}
+		})
+	}
+	if s.maintenance != nil {
+		go s.loop(ctx, s.maintenance, func(next *time.Time) { s.status.NextMaintenance = next }, func() {
+			if _, err := s.RunMaintenance(); err != nil {
+				log.Printf("数据库维护失败: %v", err)
+			}
+		})
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, schedule *cron.Schedule, setNext func(*time.Time), run func()) {
+	cron.Loop(ctx, schedule, func(next time.Time) {
+		s.mu.Lock()
+		setNext(&next)
+		s.mu.Unlock()
+	}, run)
+}
+
+// RunBackup 立即执行一次备份：写入本地目录，按需上传 S3，再按保留策略清理本地与远端
+func (s *Scheduler) RunBackup(ctx context.Context) (*RunStatus, error) {
+	if !s.running.TryLock() {
+		return nil, ErrBackupRunning
+	}
+	defer s.running.Unlock()
+
+	status := &RunStatus{StartedAt: time.Now()}
+	err := s.runBackup(ctx, status)
+	status.FinishedAt = time.Now()
+	if err != nil {
+		status.Error = err.Error()
+	}
+	s.mu.Lock()
+	s.status.LastRun = status
+	s.mu.Unlock()
+	return status, err
+}
+
+func (s *Scheduler) runBackup(ctx context.Context, status *RunStatus) error {
+	if err := os.MkdirAll(s.cfg.Dir, 0o755); err != nil {
+		return fmt.Errorf("创建备份目录失败: %w", err)
+	}
+	name := FileName(status.StartedAt)
+	target := filepath.Join(s.cfg.Dir, name)
+	// 先写临时文件，完成后再改名，避免留下不完整的备份
+	tmp, err := os.CreateTemp(s.cfg.Dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = Write(ctx, s.db, s.cfg.ImagesDir, tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), target)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	status.File = name
+	if info, err := os.Stat(target); err == nil {
+		status.Size = info.Size()
+	}
+
+	if s.s3 != nil {
+		if err := s.s3.Upload(ctx, s.s3.Key(name), target); err != nil {
+			return fmt.Errorf("上传备份失败: %w", err)
+		}
+		status.Uploaded = true
+	}
+
+	pruned, err := s.prune(ctx)
+	status.Pruned = pruned
+	return err
+}
+
+// prune 按保留策略删除本地与远端的过期备份
+func (s *Scheduler) prune(ctx context.Context) (int, error) {
+	files, err := s.localFiles()
+	if err != nil {
+		return 0, err
+	}
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = file.Name
+	}
+	pruned := 0
+	for _, name := range s.cfg.Retention.Expired(names) {
+		if err := os.Remove(filepath.Join(s.cfg.Dir, name)); err != nil {
+			return pruned, fmt.Errorf("删除过期备份失败: %w", err)
+		}
+		pruned++
+	}
+
+	if s.s3 == nil {
+		return pruned, nil
+	}
+	objects, err := s.s3.List(ctx)
+	if err != nil {
+		return pruned, fmt.Errorf("列出远端备份失败: %w", err)
+	}
+	keys := make([]string, len(objects))
+	for i, object := range objects {
+		keys[i] = object.Key
+	}
+	for _, key := range s.cfg.Retention.Expired(keys) {
+		if err := s.s3.Delete(ctx, key); err != nil {
+			return pruned, fmt.Errorf("删除远端过期备份失败: %w", err)
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+// localFiles 列出本地备份目录中的备份文件，按时间倒序
+func (s *Scheduler) localFiles() ([]BackupFile, error) {
+	entries, err := os.ReadDir(s.cfg.Dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []BackupFile
+	for _, entry := range entries {
+		at, ok := parseFileName(entry.Name())
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, BackupFile{Name: entry.Name(), Size: info.Size(), CreatedAt: at})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].CreatedAt.After(files[j].CreatedAt) })
+	return files, nil
+}
+
+// OpenFile 打开本地备份文件，name 必须是备份文件名
+func (s *Scheduler) OpenFile(name string) (*os.File, error) {
+	if _, ok := parseFileName(name); !ok || filepath.Base(name) != name {
+		return nil, os.ErrNotExist
+	}
+	return os.Open(filepath.Join(s.cfg.Dir, name))
+}
+
+// RunMaintenance 立即执行一次数据库维护
+func (s *Scheduler) RunMaintenance() (*RunStatus, error) {
+	status := &RunStatus{StartedAt: time.Now()}
+	result, err := database.Maintain(s.db)
+	status.FinishedAt = time.Now()
+	status.Maintenance = result
+	if err != nil {
+		status.Error = err.Error()
+	}
+	s.mu.Lock()
+	s.status.LastMaintenance = status
+	s.mu.Unlock()
+	return status, err
+}
+
+// Status 返回定时任务状态与本地备份列表
+func (s *Scheduler) Status() (SchedulerStatus, error) {
+	files, err := s.localFiles()
+	if err != nil {
+		return SchedulerStatus{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.Files = files
+	if status.Files == nil {
+		status.Files = []BackupFile{}
+	}
+	return status, nil
+}
@@ -0,0 +1,124 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRetentionExpired(t *testing.T) {
+	var names []string
+	// 2026-01-01 起连续 60 天，每天 03:00 一份，1 月 15 日额外 12:00 一份
+	start := time.Date(2026, 1, 1, 3, 0, 0, 0, time.Local)
+	for i := 0; i < 60; i++ {
+		names = append(names, "backups/"+FileName(start.AddDate(0, 0, i)))
+	}
+	names = append(names, FileName(time.Date(2026, 1, 15, 12, 0, 0, 0, time.Local)), "notes.txt")
+
+	expired := Retention{Daily: 3, Weekly: 2, Monthly: 2}.Expired(names)
+	kept := map[string]bool{}
+	for _, name := range names {
+		kept[name] = true
+	}
+	for _, name := range expired {
+		delete(kept, name)
+	}
+	var got []string
+	for name := range kept {
+		got = append(got, name)
+	}
+	sort.Strings(got)
+
+	want := []string{
+		"backups/" + FileName(time.Date(2026, 2, 22, 3, 0, 0, 0, time.Local)), // 上一周（ISO 周日）
+		"backups/" + FileName(time.Date(2026, 2, 27, 3, 0, 0, 0, time.Local)),
+		"backups/" + FileName(time.Date(2026, 2, 28, 3, 0, 0, 0, time.Local)), // 2 月最新
+		"backups/" + FileName(time.Date(2026, 3, 1, 3, 0, 0, 0, time.Local)),  // 最新，同时为本周与本月
+		"notes.txt",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("kept = %v\nwant %v", got, want)
+	}
+
+	if expired := (Retention{}).Expired(names); expired != nil {
+		t.Fatalf("zero retention should keep all, expired %v", expired)
+	}
+}
+
+func TestSchedulerRunBackupPrunesLocalFiles(t *testing.T) {
+	db := setupBackupTestDB(t)
+	seedBackupFixtures(t, db, "")
+	dir := t.TempDir()
+	old := filepath.Join(dir, FileName(time.Now().AddDate(0, 0, -3)))
+	writeTestFile(t, old, "old")
+	writeTestFile(t, filepath.Join(dir, "keep.txt"), "unrelated")
+
+	scheduler, err := NewScheduler(db, SchedulerConfig{
+		Schedule:            "@daily",
+		MaintenanceSchedule: "off",
+		Dir:                 dir,
+		ImagesDir:           t.TempDir(),
+		Retention:           Retention{Daily: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewScheduler: %v", err)
+	}
+	status, err := scheduler.RunBackup(context.Background())
+	if err != nil {
+		t.Fatalf("RunBackup: %v", err)
+	}
+	if status.File == "" || status.Size == 0 || status.Pruned != 1 {
+		t.Fatalf("status = %+v", status)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Fatalf("old backup should be pruned, stat err = %v", err)
+	}
+
+	file, err := scheduler.OpenFile(status.File)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	info, _ := file.Stat()
+	if _, err := Inspect(context.Background(), db, file, info.Size()); err != nil {
+		t.Fatalf("scheduled backup is invalid: %v", err)
+	}
+	file.Close()
+	if _, err := scheduler.OpenFile("../" + status.File); err == nil {
+		t.Fatalf("OpenFile should reject paths")
+	}
+
+	current, err := scheduler.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if current.Schedule != "@daily" || current.MaintenanceSchedule != "" || len(current.Files) != 1 || current.LastRun == nil {
+		t.Fatalf("status = %+v", current)
+	}
+}
+
+func TestSchedulerRunMaintenance(t *testing.T) {
+	db := setupBackupTestDB(t)
+	scheduler, err := NewScheduler(db, SchedulerConfig{Dir: t.TempDir(), MaintenanceSchedule: "30 4 * * *"})
+	if err != nil {
+		t.Fatalf("NewScheduler: %v", err)
+	}
+	status, err := scheduler.RunMaintenance()
+	if err != nil {
+		t.Fatalf("RunMaintenance: %v", err)
+	}
+	if status.Maintenance == nil {
+		t.Fatalf("status = %+v", status)
+	}
+	// 首次维护后 auto_vacuum 已切换为增量模式，再次维护不再 VACUUM
+	again, err := scheduler.RunMaintenance()
+	if err != nil {
+		t.Fatalf("RunMaintenance again: %v", err)
+	}
+	if again.Maintenance.Vacuumed {
+		t.Fatalf("second maintenance should not vacuum: %+v", again.Maintenance)
+	}
+}
@@ -0,0 +1,204 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/Rehtt/hamster-bin/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrTargetNotEmpty 目标库已有数据，且未指定续传
+	ErrTargetNotEmpty = errors.New("目标数据库已有数据")
+	// ErrTransferMismatch 续传前或迁移后源库与目标库数据不一致
+	ErrTransferMismatch = errors.New("源库与目标库数据不一致")
+)
+
+// TransferOptions 跨数据库迁移选项
+type TransferOptions struct {
+	// Resume 从上次中断处继续：按主键跳过目标库中已写入的行
+	Resume bool
+	// BatchSize 每批复制的行数，每批单独提交；为 0 时使用默认值
+	BatchSize int
+	// Progress 每批提交后回调，可为空
+	Progress func(table string, copied, total int64)
+}
+
+// TransferTable 单表迁移结果
+type TransferTable struct {
+	Name string `json:"name"`
+	// SourceRows 源库行数
+	SourceRows int64 `json:"source_rows"`
+	// Existing 续传时目标库中已存在的行数
+	Existing int64 `json:"existing"`
+	// Copied 本次复制的行数
+	Copied int64 `json:"copied"`
+}
+
+// TransferReport 迁移结果
+type TransferReport struct {
+	SourceDriver string          `json:"source_driver"`
+	TargetDriver string          `json:"target_driver"`
+	Resumed      bool            `json:"resumed"`
+	Tables       []TransferTable `json:"tables"`
+}
+
+// Transfer 将源库全部数据表按原 ID 复制到目标库（表结构需已同步）。
+// 按外键依赖顺序逐表、按主键顺序分批复制，每批单独提交：中断后目标库中是每张表的一段主键前缀，
+// 可通过 Resume 继续，或调用 ClearTransferTarget 清空后重来。复制完成后重置 PostgreSQL 自增序列并核对各表行数。
+// 迁移期间源库不应有写入。
+func Transfer(ctx context.Context, src, dst *gorm.DB, opts TransferOptions) (*TransferReport, error) {
+	codecs, err := newTableCodecs(dst)
+	if err != nil {
+		return nil, err
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = restoreBatchSize
+	}
+	src = src.WithContext(ctx)
+	dst = dst.WithContext(ctx)
+
+	report := &TransferReport{
+		SourceDriver: src.Dialector.Name(),
+		TargetDriver: dst.Dialector.Name(),
+		Resumed:      opts.Resume,
+	}
+	if !opts.Resume {
+		if err := prepareTransferTarget(dst, codecs); err != nil {
+			return report, err
+		}
+	}
+
+	for _, codec := range codecs {
+		table, err := transferTable(ctx, src, dst, codec, opts)
+		report.Tables = append(report.Tables, table)
+		if err != nil {
+			return report, fmt.Errorf("迁移表 %s 失败: %w", codec.name, err)
+		}
+	}
+
+	// 核对行数
+	for _, codec := range codecs {
+		var srcCount, dstCount int64
+		if err := src.Table(codec.name).Count(&srcCount).Error; err != nil {
+			return report, err
+		}
+		if err := dst.Table(codec.name).Count(&dstCount).Error; err != nil {
+			return report, err
+		}
+		if srcCount != dstCount {
+			return report, fmt.Errorf("%w：表 %s 源库 %d 行，目标库 %d 行", ErrTransferMismatch, codec.name, srcCount, dstCount)
+		}
+	}
+	return report, nil
+}
+
+// prepareTransferTarget 要求目标库为空。目标库启动过服务时会自动创建默认工作区，
+// 只有这一条记录时视为空库并删除，以便按源库 ID 写入。
+func prepareTransferTarget(dst *gorm.DB, codecs []*tableCodec) error {
+	for _, codec := range codecs {
+		var count int64
+		if err := dst.Table(codec.name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			continue
+		}
+		if codec.name == "workspaces" && count == 1 {
+			var workspace struct {
+				ID   uint
+				Name string
+			}
+			if err := dst.Table(codec.name).Select("id", "name").Take(&workspace).Error; err != nil {
+				return err
+			}
+			if workspace.ID == repository.DefaultWorkspaceID && workspace.Name == repository.DefaultWorkspaceName {
+				continue
+			}
+		}
+		return fmt.Errorf("%w：表 %s 有 %d 行，请使用空库，或续传上次中断的迁移", ErrTargetNotEmpty, codec.name, count)
+	}
+	return dst.Exec("DELETE FROM ?", clause.Table{Name: "workspaces"}).Error
+}
+
+func transferTable(ctx context.Context, src, dst *gorm.DB, codec *tableCodec, opts TransferOptions) (TransferTable, error) {
+	table := TransferTable{Name: codec.name}
+	primary := codec.schema.PrioritizedPrimaryField
+	if primary == nil {
+		return table, errors.New("缺少主键")
+	}
+	if err := src.Table(codec.name).Count(&table.SourceRows).Error; err != nil {
+		return table, err
+	}
+
+	// 续传：目标库中应恰好是源库中主键不大于已写入最大主键的那些行
+	var last uint64
+	if opts.Resume {
+		var maxID *uint64
+		if err := dst.Table(codec.name).Select("MAX(" + primary.DBName + ")").Scan(&maxID).Error; err != nil {
+			return table, err
+		}
+		if maxID != nil {
+			last = *maxID
+			if err := dst.Table(codec.name).Count(&table.Existing).Error; err != nil {
+				return table, err
+			}
+			var expected int64
+			if err := src.Table(codec.name).Where(clause.Lte{Column: primary.DBName, Value: last}).Count(&expected).Error; err != nil {
+				return table, err
+			}
+			if expected != table.Existing {
+				return table, fmt.Errorf("%w：目标库已有 %d 行，源库对应 %d 行，无法续传", ErrTransferMismatch, table.Existing, expected)
+			}
+		}
+	}
+
+	sliceType := reflect.SliceOf(codec.schema.ModelType)
+	for {
+		if err := ctx.Err(); err != nil {
+			return table, err
+		}
+		batch := reflect.New(sliceType)
+		err := src.Unscoped().
+			Where(clause.Gt{Column: primary.DBName, Value: last}).
+			Order(primary.DBName).
+			Limit(opts.BatchSize).
+			Find(batch.Interface()).Error
+		if err != nil {
+			return table, err
+		}
+		rows := batch.Elem()
+		if rows.Len() == 0 {
+			break
+		}
+		if err := dst.Omit(clause.Associations).Create(batch.Interface()).Error; err != nil {
+			return table, err
+		}
+		table.Copied += int64(rows.Len())
+		last = uint64(reflect.Indirect(primary.ReflectValueOf(ctx, rows.Index(rows.Len()-1))).Uint())
+		if opts.Progress != nil {
+			opts.Progress(codec.name, table.Existing+table.Copied, table.SourceRows)
+		}
+	}
+	return table, resetSequence(dst, codec)
+}
+
+// ClearTransferTarget 放弃中断的迁移：按依赖逆序清空目标库全部数据表
+func ClearTransferTarget(ctx context.Context, dst *gorm.DB) error {
+	codecs, err := newTableCodecs(dst)
+	if err != nil {
+		return err
+	}
+	return dst.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := len(codecs) - 1; i >= 0; i-- {
+			if err := tx.Exec("DELETE FROM ?", clause.Table{Name: codecs[i].name}).Error; err != nil {
+				return fmt.Errorf("清空表 %s 失败: %w", codecs[i].name, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,151 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Rehtt/hamster-bin/internal/database"
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupEmptyTestDB 只同步表结构、不创建默认工作区的空库，模拟迁移目标
+func setupEmptyTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("db: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(database.Models()...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func assertSameRows(t *testing.T, src, dst *gorm.DB) {
+	t.Helper()
+	codecs, err := newTableCodecs(src)
+	if err != nil {
+		t.Fatalf("codecs: %v", err)
+	}
+	for _, codec := range codecs {
+		var srcCount, dstCount int64
+		src.Table(codec.name).Count(&srcCount)
+		dst.Table(codec.name).Count(&dstCount)
+		if srcCount != dstCount {
+			t.Fatalf("table %s: source %d rows, target %d rows", codec.name, srcCount, dstCount)
+		}
+	}
+	var srcComponent, dstComponent models.Component
+	src.Order("id").First(&srcComponent)
+	dst.Order("id").First(&dstComponent)
+	if srcComponent.ID != dstComponent.ID || srcComponent.Name != dstComponent.Name ||
+		srcComponent.Description != dstComponent.Description || srcComponent.UnitPriceMicro != dstComponent.UnitPriceMicro {
+		t.Fatalf("component mismatch: %+v vs %+v", srcComponent, dstComponent)
+	}
+	var totp models.TwoFactorAuth
+	if err := dst.First(&totp).Error; err != nil || totp.Secret == "" {
+		t.Fatalf("two factor secret not copied: %+v, %v", totp, err)
+	}
+}
+
+func TestTransferCopiesAllTables(t *testing.T) {
+	src := setupBackupTestDB(t)
+	seedBackupFixtures(t, src, "")
+	// 目标库启动过服务，只有自动创建的默认工作区，视为空库
+	dst := setupBackupTestDB(t)
+
+	report, err := Transfer(context.Background(), src, dst, TransferOptions{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+	if report.Resumed || len(report.Tables) != len(database.Models()) {
+		t.Fatalf("report = %+v", report)
+	}
+	assertSameRows(t, src, dst)
+
+	// 新写入的记录继续使用递增 ID
+	var maxID uint
+	dst.Model(&models.Category{}).Select("MAX(id)").Scan(&maxID)
+	category := models.Category{Name: "新分类"}
+	mustCreate(t, dst, &category)
+	if category.ID != maxID+1 {
+		t.Fatalf("new category id = %d, want %d", category.ID, maxID+1)
+	}
+
+	if _, err := Transfer(context.Background(), src, dst, TransferOptions{}); !errors.Is(err, ErrTargetNotEmpty) {
+		t.Fatalf("second Transfer err = %v, want ErrTargetNotEmpty", err)
+	}
+}
+
+func TestTransferResumeAndAbort(t *testing.T) {
+	src := setupBackupTestDB(t)
+	seedBackupFixtures(t, src, "")
+	dst := setupEmptyTestDB(t)
+
+	// 复制到元件表的第一批后中断
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := Transfer(ctx, src, dst, TransferOptions{BatchSize: 1, Progress: func(table string, copied, total int64) {
+		if table == "components" {
+			cancel()
+		}
+	}})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("interrupted Transfer err = %v, want context.Canceled", err)
+	}
+	var components int64
+	dst.Model(&models.Component{}).Count(&components)
+	if components != 1 {
+		t.Fatalf("components after interrupt = %d, want 1", components)
+	}
+
+	if _, err := Transfer(context.Background(), src, dst, TransferOptions{}); !errors.Is(err, ErrTargetNotEmpty) {
+		t.Fatalf("fresh Transfer on partial target err = %v, want ErrTargetNotEmpty", err)
+	}
+
+	report, err := Transfer(context.Background(), src, dst, TransferOptions{Resume: true})
+	if err != nil {
+		t.Fatalf("resume Transfer: %v", err)
+	}
+	for _, table := range report.Tables {
+		if table.Existing+table.Copied != table.SourceRows {
+			t.Fatalf("table %+v not complete", table)
+		}
+		if table.Name == "workspaces" && table.Copied != 0 {
+			t.Fatalf("resume should skip copied workspaces: %+v", table)
+		}
+	}
+	assertSameRows(t, src, dst)
+
+	// 放弃后目标库清空，可重新迁移
+	if err := ClearTransferTarget(context.Background(), dst); err != nil {
+		t.Fatalf("ClearTransferTarget: %v", err)
+	}
+	dst.Model(&models.Component{}).Count(&components)
+	if components != 0 {
+		t.Fatalf("components after clear = %d", components)
+	}
+	if _, err := Transfer(context.Background(), src, dst, TransferOptions{}); err != nil {
+		t.Fatalf("Transfer after clear: %v", err)
+	}
+	assertSameRows(t, src, dst)
+}
+
+func TestTransferResumeDetectsDivergedTarget(t *testing.T) {
+	src := setupBackupTestDB(t)
+	seedBackupFixtures(t, src, "")
+	dst := setupBackupTestDB(t)
+	mustCreate(t, dst, &models.Category{ID: 100, Name: "目标库独有"})
+
+	_, err := Transfer(context.Background(), src, dst, TransferOptions{Resume: true})
+	if !errors.Is(err, ErrTransferMismatch) {
+		t.Fatalf("err = %v, want ErrTransferMismatch", err)
+	}
+}
@@ -12,21 +12,56 @@ const defaultDBPath = "./data/inventory.db"
 
 // Config 应用配置
 type Config struct {
-	Port           string
-	DBDriver       string
-	DBDSN          string
-	DBPath         string
-	ImageDir       string
-	LogLevel       string
-	SSLCert        string
-	SSLKey         string
-	LLMBaseURL     string
-	LLMAPIKey      string
-	LLMModel       string
-	AdminUsername  string
-	AdminPassword  string
-	JWTSecret      string
-	JWTExpireHours int
+	Port              string
+	DBDriver          string
+	DBDSN             string
+	DBPath            string
+	DBAutoMigrate     bool
+	ImageDir          string
+	LogLevel          string
+	SSLCert           string
+	SSLKey            string
+	LLMBaseURL        string
+	LLMAPIKey         string
+	LLMModel          string
+	AdminUsername     string
+	AdminPassword     string
+	JWTSecret         string
+	JWTExpireHours    int
+	TwoFactorRequired bool
+
+	// 定时备份与数据库维护
+	BackupSchedule        string
+	BackupDir             string
+	BackupKeepDaily       int
+	BackupKeepWeekly      int
+	BackupKeepMonthly     int
+	DBMaintenanceSchedule string
+	BackupS3Endpoint      string
+	BackupS3Region        string
+	BackupS3Bucket        string
+	BackupS3Prefix        string
+	BackupS3AccessKey     string
+	BackupS3SecretKey     string
+	BackupS3PathStyle     bool
+
+	// 消耗预测
+	ForecastSchedule     string
+	ForecastWindowDays   int
+	ForecastLeadTimeDays int
+	ForecastTargetDays   int
+
+	// ABC 分类
+	ABCSchedule   string
+	ABCMetric     string
+	ABCWindowDays int
+	ABCThresholdA int
+	ABCThresholdB int
+
+	// 标签打印
+	LabelTemplatesFile string
+	LabelZPLFont       string
+	LabelTSPLFont      string
 }
 
 // Load 加载配置（支持环境变量）
@@ -39,21 +74,52 @@ func Load() *Config {
 	}
 
 	cfg := &Config{
-		Port:           getEnv("PORT", "8080"),
-		DBDriver:       normalizeDBDriver(getEnv("DB_DRIVER", "sqlite")),
-		DBDSN:          getEnv("DB_DSN", ""),
-		DBPath:         getEnv("DB_PATH", defaultDBPath),
-		LogLevel:       getEnv("LOG_LEVEL", "info"),
-		ImageDir:       getEnv("IMAGE_DIR", "./data/images"),
-		SSLCert:        getEnv("SSL_CERT", ""),
-		SSLKey:         getEnv("SSL_KEY", ""),
-		LLMBaseURL:     getEnv("LLM_BASE_URL", ""),
-		LLMAPIKey:      getEnv("LLM_API_KEY", ""),
-		LLMModel:       getEnv("LLM_MODEL", ""),
-		AdminUsername:  getEnv("ADMIN_USERNAME", ""),
-		AdminPassword:  getEnv("ADMIN_PASSWORD", ""),
-		JWTSecret:      getEnv("JWT_SECRET", ""),
-		JWTExpireHours: expireHours,
+		Port:              getEnv("PORT", "8080"),
+		DBDriver:          normalizeDBDriver(getEnv("DB_DRIVER", "sqlite")),
+		DBDSN:             getEnv("DB_DSN", ""),
+		DBPath:            getEnv("DB_PATH", defaultDBPath),
+		DBAutoMigrate:     getEnvBool("DB_AUTO_MIGRATE", true),
+		LogLevel:          getEnv("LOG_LEVEL", "info"),
+		ImageDir:          getEnv("IMAGE_DIR", "./data/images"),
+		SSLCert:           getEnv("SSL_CERT", ""),
+		SSLKey:            getEnv("SSL_KEY", ""),
+		LLMBaseURL:        getEnv("LLM_BASE_URL", ""),
+		LLMAPIKey:         getEnv("LLM_API_KEY", ""),
+		LLMModel:          getEnv("LLM_MODEL", ""),
+		AdminUsername:     getEnv("ADMIN_USERNAME", ""),
+		AdminPassword:     getEnv("ADMIN_PASSWORD", ""),
+		JWTSecret:         getEnv("JWT_SECRET", ""),
+		JWTExpireHours:    expireHours,
+		TwoFactorRequired: getEnvBool("TWO_FACTOR_REQUIRED", false),
+
+		BackupSchedule:        getEnv("BACKUP_SCHEDULE", ""),
+		BackupDir:             getEnv("BACKUP_DIR", "./data/backups"),
+		BackupKeepDaily:       getEnvInt("BACKUP_KEEP_DAILY", 7),
+		BackupKeepWeekly:      getEnvInt("BACKUP_KEEP_WEEKLY", 4),
+		BackupKeepMonthly:     getEnvInt("BACKUP_KEEP_MONTHLY", 6),
+		DBMaintenanceSchedule: getEnv("DB_MAINTENANCE_SCHEDULE", "30 4 * * *"),
+		BackupS3Endpoint:      getEnv("BACKUP_S3_ENDPOINT", ""),
+		BackupS3Region:        getEnv("BACKUP_S3_REGION", "us-east-1"),
+		BackupS3Bucket:        getEnv("BACKUP_S3_BUCKET", ""),
+		BackupS3Prefix:        getEnv("BACKUP_S3_PREFIX", "hamster-bin"),
+		BackupS3AccessKey:     getEnv("BACKUP_S3_ACCESS_KEY", ""),
+		BackupS3SecretKey:     getEnv("BACKUP_S3_SECRET_KEY", ""),
+		BackupS3PathStyle:     getEnvBool("BACKUP_S3_PATH_STYLE", true),
+
+		ForecastSchedule:     getEnv("FORECAST_SCHEDULE", "15 3 * * *"),
+		ForecastWindowDays:   getEnvInt("FORECAST_WINDOW_DAYS", 90),
+		ForecastLeadTimeDays: getEnvInt("FORECAST_LEAD_TIME_DAYS", 14),
+		ForecastTargetDays:   getEnvInt("FORECAST_TARGET_DAYS", 60),
+
+		ABCSchedule:   getEnv("ABC_SCHEDULE", "30 3 * * *"),
+		ABCMetric:     getEnv("ABC_METRIC", "value"),
+		ABCWindowDays: getEnvInt("ABC_WINDOW_DAYS", 365),
+		ABCThresholdA: getEnvInt("ABC_THRESHOLD_A", 80),
+		ABCThresholdB: getEnvInt("ABC_THRESHOLD_B", 95),
+
+		LabelTemplatesFile: getEnv("LABEL_TEMPLATES_FILE", ""),
+		LabelZPLFont:       getEnv("LABEL_ZPL_FONT", "0"),
+		LabelTSPLFont:      getEnv("LABEL_TSPL_FONT", "TSS24.BF2"),
 	}
 
 	if err := cfg.Validate(); err != nil {
@@ -84,11 +150,19 @@ func (c *Config) DatabaseDisplay() string {
 	return "external"
 }
 
+// IsBackupS3Enabled 配置了 BACKUP_S3_BUCKET 时将备份上传到 S3 兼容存储
+func (c *Config) IsBackupS3Enabled() bool {
+	return c.BackupS3Bucket != ""
+}
+
 // Validate 校验配置合法性
 func (c *Config) Validate() error {
 	if c.IsAuthEnabled() && c.JWTSecret == "" {
 		return fmt.Errorf("启用鉴权时必须设置 JWT_SECRET 环境变量")
 	}
+	if c.IsBackupS3Enabled() && (c.BackupS3Endpoint == "" || c.BackupS3AccessKey == "" || c.BackupS3SecretKey == "") {
+		return fmt.Errorf("启用 BACKUP_S3_BUCKET 时必须设置 BACKUP_S3_ENDPOINT、BACKUP_S3_ACCESS_KEY 和 BACKUP_S3_SECRET_KEY")
+	}
 	switch c.DBDriver {
 	case "sqlite":
 	case "mysql", "postgres":
@@ -114,6 +188,27 @@ func normalizeDBDriver(driver string) string {
 	}
 }
 
+func getEnvBool(key string, defaultValue bool) bool {
+	value := strings.ToLower(strings.TrimSpace(os.Getenv(key)))
+	switch value {
+	case "1", "true", "yes", "on":
+		return true
+	case "0", "false", "no", "off":
+		return false
+	default:
+		return defaultValue
+	}
+}
+
+// getEnvInt 读取非负整数，未设置或无效时返回默认值
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(strings.TrimSpace(os.Getenv(key)))
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
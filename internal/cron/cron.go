@@ -0,0 +1,251 @@
+// Package cron 解析标准 5 段 cron 表达式并按表达式循环执行任务，供定时备份、消耗预测与 ABC 分类共用
+package cron
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronField 单个字段允许的取值，bit i 表示值 i
+type cronField uint64
+
+// Schedule 标准 5 段 cron 表达式：分 时 日 月 周，按服务器本地时区计算
+type Schedule struct {
+	expr    string
+	minute  cronField
+	hour    cronField
+	dom     cronField
+	month   cronField
+	dow     cronField
+	domStar bool
+	dowStar bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronMonthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var cronDayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// Enabled 判断表达式是否启用，为空或 off 表示关闭
+func Enabled(expr string) bool {
+	expr = strings.TrimSpace(expr)
+	return expr != "" && !strings.EqualFold(expr, "off")
+}
+
+// Parse 解析 cron 表达式，支持 *、列表、范围、步长、月份/星期英文缩写及 @daily 等宏。
+// 日与周同时受限时按标准 cron 语义取并集。
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	spec := expr
+	if macro, ok := cronMacros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron 表达式 %q 应为 5 段（分 时 日 月 周）", expr)
+	}
+
+	s := &Schedule{expr: expr}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("cron 分钟字段: %w", err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("cron 小时字段: %w", err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("cron 日字段: %w", err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("cron 月字段: %w", err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7, cronDayNames); err != nil {
+		return nil, fmt.Errorf("cron 星期字段: %w", err)
+	}
+	// 7 与 0 均表示周日
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domStar = fields[2] == "*" || fields[2] == "?"
+	s.dowStar = fields[4] == "*" || fields[4] == "?"
+	return s, nil
+}
+
+func parseCronField(field string, min, max int, names map[string]int) (cronField, error) {
+	var result cronField
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("无效的步长 %q", part)
+			}
+			rangePart = part[:i]
+		}
+
+		low, high := min, max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = parseCronValue(bounds[0], names); err != nil {
+				return 0, err
+			}
+			if high, err = parseCronValue(bounds[1], names); err != nil {
+				return 0, err
+			}
+		default:
+			value, err := parseCronValue(rangePart, names)
+			if err != nil {
+				return 0, err
+			}
+			low = value
+			// a/n 表示从 a 起到最大值
+			if step == 1 {
+				high = value
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q 超出范围 %d-%d", part, min, max)
+		}
+		for v := low; v <= high; v += step {
+			result |= 1 << v
+		}
+	}
+	return result, nil
+}
+
+func parseCronValue(value string, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("无效的取值 %q", value)
+	}
+	return n, nil
+}
+
+func (f cronField) has(v int) bool {
+	return f&(1<<v) != 0
+}
+
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom, dow := s.dom.has(t.Day()), s.dow.has(int(t.Weekday()))
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next 返回严格晚于 t 的下一次触发时间；表达式永远无法触发（如 2 月 30 日）时返回零值
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !s.month.has(int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hour.has(t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.minute.has(t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// Loop 按 schedule 循环执行 run，直到 ctx 取消；每次等待前以下次触发时间调用 onNext（可为 nil）
+func Loop(ctx context.Context, schedule *Schedule, onNext func(time.Time), run func()) {
+	for {
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("cron 表达式 %s 不会触发", schedule)
+			return
+		}
+		if onNext != nil {
+			onNext(next)
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			run()
+		}
+	}
+}
+
+// WorkspaceJob 启动时与按 cron 定时逐个工作区重新计算，供消耗预测与 ABC 分类共用
+type WorkspaceJob struct {
+	Name       string                                      // 日志中的任务名，如「消耗预测计算」
+	Schedule   *Schedule                                   // 为 nil 时只在启动时计算
+	Workspaces func() ([]uint, error)                      // 需要计算的工作区
+	Run        func(workspaceID uint, now time.Time) error // 重新计算单个工作区
+}
+
+// Start 先计算一次，再按 cron 定时计算，ctx 取消时退出
+func (j *WorkspaceJob) Start(ctx context.Context) {
+	run := func() {
+		if err := j.RunAll(time.Now()); err != nil {
+			log.Printf("%s失败: %v", j.Name, err)
+		}
+	}
+	go func() {
+		run()
+		if j.Schedule != nil {
+			Loop(ctx, j.Schedule, nil, run)
+		}
+	}()
+}
+
+// RunAll 逐个工作区重新计算，单个工作区失败不影响其他工作区，返回最后一个错误
+func (j *WorkspaceJob) RunAll(now time.Time) error {
+	ids, err := j.Workspaces()
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	for _, id := range ids {
+		if err := j.Run(id, now); err != nil {
+			lastErr = fmt.Errorf("工作区 %d: %w", id, err)
+			log.Printf("%s失败: %v", j.Name, lastErr)
+		}
+	}
+	return lastErr
+}
@@ -0,0 +1,85 @@
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	base := time.Date(2026, 1, 30, 10, 15, 30, 0, time.Local) // 周五
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/20 * * * *", time.Date(2026, 1, 30, 10, 20, 0, 0, time.Local)},
+		{"0 3 * * *", time.Date(2026, 1, 31, 3, 0, 0, 0, time.Local)},
+		{"@daily", time.Date(2026, 1, 31, 0, 0, 0, 0, time.Local)},
+		{"30 2 * * sun", time.Date(2026, 2, 1, 2, 30, 0, 0, time.Local)},
+		{"0 0 * * 7", time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local)},
+		{"0 4 1 * *", time.Date(2026, 2, 1, 4, 0, 0, 0, time.Local)},
+		{"0 0 31 * *", time.Date(2026, 1, 31, 0, 0, 0, 0, time.Local)},
+		{"0 0 31 2-12 *", time.Date(2026, 3, 31, 0, 0, 0, 0, time.Local)},
+		{"0 9-17/4 * * mon-fri", time.Date(2026, 1, 30, 13, 0, 0, 0, time.Local)},
+		// 日与周同时受限时取并集：1 号或周一
+		{"0 0 1 * mon", time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local)},
+		{"0 12 29 feb *", time.Date(2028, 2, 29, 12, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		schedule, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expr, err)
+		}
+		if got := schedule.Next(base); !got.Equal(tt.want) {
+			t.Errorf("%q Next = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	never, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got := never.Next(base); !got.IsZero() {
+		t.Fatalf("Feb 30 Next = %v, want zero", got)
+	}
+}
+
+func TestParseRejectsInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) error = nil", expr)
+		}
+	}
+}
+
+func TestWorkspaceJobRunAll(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	var ran []uint
+	job := WorkspaceJob{
+		Name:       "测试计算",
+		Workspaces: func() ([]uint, error) { return []uint{1, 2, 3}, nil },
+		Run: func(workspaceID uint, at time.Time) error {
+			if !at.Equal(now) {
+				t.Fatalf("Run now = %v, want %v", at, now)
+			}
+			ran = append(ran, workspaceID)
+			if workspaceID == 2 {
+				return errors.New("boom")
+			}
+			return nil
+		},
+	}
+
+	err := job.RunAll(now)
+	if err == nil || err.Error() != "工作区 2: boom" {
+		t.Fatalf("RunAll error = %v, want 工作区 2: boom", err)
+	}
+	if len(ran) != 3 {
+		t.Fatalf("ran = %v, want all 3 workspaces despite failure", ran)
+	}
+
+	job.Workspaces = func() ([]uint, error) { return nil, errors.New("db down") }
+	if err := job.RunAll(now); err == nil || err.Error() != "db down" {
+		t.Fatalf("RunAll error = %v, want db down", err)
+	}
+}
@@ -23,35 +23,68 @@ type Config struct {
 	Driver string
 	DSN    string
 	Path   string
+	// AutoMigrate 启动时自动执行待执行的迁移；为 false 时存在待执行迁移则拒绝启动
+	AutoMigrate bool
 }
 
-// Init 初始化数据库连接
+// Init 初始化数据库连接：检查表结构版本并按需执行迁移
 func Init(cfg Config) error {
-	dialector, err := openDialector(cfg)
+	db, err := Connect(cfg)
 	if err != nil {
 		return err
 	}
 
-	DB, err = gorm.Open(dialector, &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-	})
+	current, pending, err := CheckVersion(db)
 	if err != nil {
-		return fmt.Errorf("连接数据库失败: %w", err)
+		return err
 	}
-
-	if normalizeDriver(cfg.Driver) == "sqlite" {
-		setSQLitePragmas(DB)
+	if len(pending) > 0 {
+		if !cfg.AutoMigrate {
+			return fmt.Errorf("%w：数据库为 v%d，程序需要 v%d，请执行 hamster-bin migrate up", ErrPendingMigrations, current, SchemaVersion)
+		}
+		if _, err := Migrate(db, SQLiteFilePath(cfg)); err != nil {
+			return fmt.Errorf("数据库迁移失败: %w", err)
+		}
 	}
 
-	// 自动迁移表结构
-	if err := autoMigrate(); err != nil {
-		return fmt.Errorf("数据库迁移失败: %w", err)
-	}
+	DB = db
 
 	log.Println("数据库初始化成功")
 	return nil
 }
 
+// Open 打开数据库连接并执行迁移，不修改全局实例；跨库迁移时用于打开目标库
+func Open(cfg Config) (*gorm.DB, error) {
+	db, err := Connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := Migrate(db, SQLiteFilePath(cfg)); err != nil {
+		return nil, fmt.Errorf("数据库迁移失败: %w", err)
+	}
+	return db, nil
+}
+
+// Connect 只打开数据库连接（SQLite 设置 pragma），不检查也不迁移表结构
+func Connect(cfg Config) (*gorm.DB, error) {
+	dialector, err := openDialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Info),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("连接数据库失败: %w", err)
+	}
+
+	if normalizeDriver(cfg.Driver) == "sqlite" {
+		setSQLitePragmas(db)
+	}
+	return db, nil
+}
+
 func openDialector(cfg Config) (gorm.Dialector, error) {
 	switch normalizeDriver(cfg.Driver) {
 	case "sqlite":
@@ -110,15 +143,25 @@ func setSQLitePragmas(db *gorm.DB) {
 	db.Exec("pragma wal_checkpoint(PASSIVE)")
 }
 
-// autoMigrate 自动创建/更新表结构
-func autoMigrate() error {
-	return DB.AutoMigrate(
+// SchemaVersion 当前表结构版本，即 migrations 中最后一项的版本，写入备份清单
+const SchemaVersion = 8
+
+// Models 返回全部数据表模型，按外键依赖顺序排列（被引用的表在前）
+func Models() []any {
+	return []any{
+		&models.Workspace{},
+		&models.User{},
+		&models.WorkspaceMember{},
 		&models.Category{},
 		&models.Supplier{},
 		&models.Component{},
+		&models.ComponentTag{},
 		&models.PreStock{},
 		&models.StockLog{},
-	)
+		&models.SavedSearch{},
+		&models.TwoFactorAuth{},
+		&models.TwoFactorRecoveryCode{},
+	}
 }
 
 // GetDB 获取数据库实例
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// sqliteAutoVacuumIncremental PRAGMA auto_vacuum 返回值：0 NONE、1 FULL、2 INCREMENTAL
+const sqliteAutoVacuumIncremental = 2
+
+// MaintenanceResult SQLite 维护结果
+type MaintenanceResult struct {
+	// Vacuumed 数据库创建时未启用增量回收，本次执行了一次 VACUUM 使 auto_vacuum = INCREMENTAL 生效
+	Vacuumed bool `json:"vacuumed"`
+	// FreedPages incremental_vacuum 回收的空闲页数
+	FreedPages int64 `json:"freed_pages"`
+	// WALPages 检查点写回主库的 WAL 页数
+	WALPages int64 `json:"wal_pages"`
+}
+
+// Maintain 执行 SQLite 例行维护：回收空闲页、截断 WAL 并更新查询统计。其他数据库直接返回 nil。
+// auto_vacuum 只对新建库或 VACUUM 之后生效，旧库首次维护时会执行一次完整 VACUUM（需要约一倍的临时磁盘空间）。
+func Maintain(db *gorm.DB) (*MaintenanceResult, error) {
+	if db.Dialector.Name() != "sqlite" {
+		return nil, nil
+	}
+	result := &MaintenanceResult{}
+
+	var mode int
+	if err := db.Raw("PRAGMA auto_vacuum").Scan(&mode).Error; err != nil {
+		return nil, fmt.Errorf("读取 auto_vacuum 失败: %w", err)
+	}
+	if mode != sqliteAutoVacuumIncremental {
+		if err := db.Exec("PRAGMA auto_vacuum = INCREMENTAL").Error; err != nil {
+			return nil, err
+		}
+		if err := db.Exec("VACUUM").Error; err != nil {
+			return nil, fmt.Errorf("VACUUM 失败: %w", err)
+		}
+		result.Vacuumed = true
+	} else {
+		var before, after int64
+		if err := db.Raw("PRAGMA freelist_count").Scan(&before).Error; err != nil {
+			return nil, err
+		}
+		if err := db.Exec("PRAGMA incremental_vacuum").Error; err != nil {
+			return nil, fmt.Errorf("incremental_vacuum 失败: %w", err)
+		}
+		if err := db.Raw("PRAGMA freelist_count").Scan(&after).Error; err != nil {
+			return nil, err
+		}
+		result.FreedPages = before - after
+	}
+
+	var checkpoint struct {
+		Busy         int64
+		Log          int64
+		Checkpointed int64
+	}
+	if err := db.Raw("PRAGMA wal_checkpoint(TRUNCATE)").Row().Scan(&checkpoint.Busy, &checkpoint.Log, &checkpoint.Checkpointed); err != nil {
+		return nil, fmt.Errorf("wal_checkpoint 失败: %w", err)
+	}
+	result.WALPages = checkpoint.Checkpointed
+	if err := db.Exec("PRAGMA optimize").Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,312 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/Rehtt/hamster-bin/internal/searchindex"
+	"github.com/Rehtt/hamster-bin/internal/searchkey"
+	"gorm.io/gorm"
+)
+
+// Migration 一个表结构版本。Up 在事务中执行（MySQL 的 DDL 会隐式提交），
+// 需要区分数据库时按 tx.Dialector.Name()（sqlite/mysql/postgres）分别处理。
+// 新库执行基线迁移时已按最新模型建表，因此后续步骤必须可重复执行（先判断列/索引是否存在）。
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+}
+
+// SchemaMigration 已执行的迁移记录
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	Name      string    `gorm:"size:200;not null" json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// MigrationState 迁移执行状态
+type MigrationState struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// MigrateResult 一次迁移的结果
+type MigrateResult struct {
+	From    int      `json:"from"`
+	To      int      `json:"to"`
+	Applied []string `json:"applied"`
+	// BackupPath 迁移前的 SQLite 备份文件，未备份时为空
+	BackupPath string `json:"backup_path,omitempty"`
+}
+
+var (
+	// ErrSchemaTooNew 数据库由更新版本的程序迁移过
+	ErrSchemaTooNew = errors.New("数据库表结构版本高于当前程序")
+	// ErrPendingMigrations 关闭自动迁移时存在未执行的迁移
+	ErrPendingMigrations = errors.New("存在未执行的数据库迁移")
+)
+
+// migrations 全部迁移，按版本递增；最后一项的版本即 SchemaVersion
+var migrations = []Migration{
+	{Version: 1, Name: "baseline", Up: migrateBaseline},
+	{Version: 2, Name: "component_search_index", Up: migrateComponentSearchIndex},
+	{Version: 3, Name: "component_search_keys", Up: migrateComponentSearchKeys},
+	{Version: 4, Name: "component_tags", Up: migrateComponentTags},
+	{Version: 5, Name: "saved_searches", Up: migrateSavedSearches},
+	{Version: 6, Name: "component_forecasts", Up: migrateComponentForecasts},
+	{Version: 7, Name: "component_abc_class", Up: migrateComponentABCClass},
+	{Version: 8, Name: "two_factor_lockout", Up: migrateTwoFactorLockout},
+}
+
+// migrateBaseline 按当前模型建表，并删除引入工作区前的全局唯一索引。
+// 引入版本化迁移前的数据库没有迁移记录，同样从此步开始，AutoMigrate 只补齐缺失的列与索引。
+func migrateBaseline(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(Models()...); err != nil {
+		return err
+	}
+	migrator := tx.Migrator()
+	for _, index := range legacyUniqueIndexes {
+		if migrator.HasIndex(index.model, index.name) {
+			if err := migrator.DropIndex(index.model, index.name); err != nil {
+				return fmt.Errorf("删除旧索引 %s 失败: %w", index.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// migrateComponentSearchIndex 创建元件全文索引。数据库不支持时（如 SQLite 未编译 FTS5、MySQL 未启用 ngram）
+// 回滚本步的改动并继续，关键词搜索退回逐列 LIKE
+func migrateComponentSearchIndex(tx *gorm.DB) error {
+	const savepoint = "component_search_index"
+	tx.SavePoint(savepoint)
+	if err := searchindex.Ensure(tx); err != nil {
+		tx.RollbackTo(savepoint)
+		log.Printf("创建元件全文索引失败，关键词搜索将使用 LIKE: %v", err)
+	}
+	return nil
+}
+
+// migrateComponentSearchKeys 新增元件搜索键列（拼音、型号三元组），为已有元件生成搜索键，
+// 并重建全文索引使其覆盖该列；重建失败时同 v2 退回 LIKE
+func migrateComponentSearchKeys(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn(&models.Component{}, "SearchKeys") {
+		if err := tx.Migrator().AddColumn(&models.Component{}, "SearchKeys"); err != nil {
+			return err
+		}
+	}
+	var batch []models.Component
+	err := tx.Model(&models.Component{}).
+		Select("id", "name", "model", "supplier_part_number", "description").
+		FindInBatches(&batch, 500, func(batchTx *gorm.DB, _ int) error {
+			for _, component := range batch {
+				keys := searchkey.Build(component.Name, component.Model, component.SupplierPartNumber, component.Description)
+				if err := tx.Model(&models.Component{}).Where("id = ?", component.ID).UpdateColumn("search_keys", keys).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return fmt.Errorf("生成元件搜索键失败: %w", err)
+	}
+
+	const savepoint = "component_search_keys"
+	tx.SavePoint(savepoint)
+	if err := searchindex.Rebuild(tx); err != nil {
+		tx.RollbackTo(savepoint)
+		log.Printf("重建元件全文索引失败，关键词搜索将使用 LIKE: %v", err)
+	}
+	return nil
+}
+
+// migrateComponentTags 创建元件标签表（新库的基线已建表）
+func migrateComponentTags(tx *gorm.DB) error {
+	return tx.AutoMigrate(&models.ComponentTag{})
+}
+
+// migrateSavedSearches 创建保存搜索表（新库的基线已建表）
+func migrateSavedSearches(tx *gorm.DB) error {
+	return tx.AutoMigrate(&models.SavedSearch{})
+}
+
+// migrateComponentForecasts 创建消耗预测表。预测为派生数据，不在 Models() 中（不写入备份），
+// 新库同样由本步建表
+func migrateComponentForecasts(tx *gorm.DB) error {
+	return tx.AutoMigrate(&models.ComponentForecast{})
+}
+
+// migrateComponentABCClass 新增元件 ABC 分类列及索引，分类由定时任务计算后填充
+func migrateComponentABCClass(tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	if !migrator.HasColumn(&models.Component{}, "ABCClass") {
+		if err := migrator.AddColumn(&models.Component{}, "ABCClass"); err != nil {
+			return err
+		}
+	}
+	if !migrator.HasIndex(&models.Component{}, "ABCClass") {
+		return migrator.CreateIndex(&models.Component{}, "ABCClass")
+	}
+	return nil
+}
+
+// migrateTwoFactorLockout 新增二次验证失败计数与锁定时间列
+func migrateTwoFactorLockout(tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	for _, field := range []string{"FailedAttempts", "LockedAt"} {
+		if !migrator.HasColumn(&models.TwoFactorAuth{}, field) {
+			if err := migrator.AddColumn(&models.TwoFactorAuth{}, field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// legacyUniqueIndexes 引入工作区前的全局唯一索引，现已改为工作区内唯一
+var legacyUniqueIndexes = []struct {
+	model any
+	name  string
+}{
+	{&models.Supplier{}, "idx_suppliers_name"},
+	{&models.Component{}, "idx_components_component_number"},
+	{&models.PreStock{}, "idx_pre_stocks_component_number"},
+}
+
+// CurrentVersion 返回数据库已执行的最高迁移版本，未执行过任何迁移时为 0
+func CurrentVersion(db *gorm.DB) (int, error) {
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return 0, nil
+	}
+	var version *int
+	if err := db.Model(&SchemaMigration{}).Select("MAX(version)").Scan(&version).Error; err != nil {
+		return 0, err
+	}
+	if version == nil {
+		return 0, nil
+	}
+	return *version, nil
+}
+
+// CheckVersion 返回当前版本与待执行的迁移；数据库版本高于程序时返回 ErrSchemaTooNew
+func CheckVersion(db *gorm.DB) (int, []Migration, error) {
+	current, err := CurrentVersion(db)
+	if err != nil {
+		return 0, nil, fmt.Errorf("读取迁移记录失败: %w", err)
+	}
+	if current > SchemaVersion {
+		return current, nil, fmt.Errorf("%w：数据库为 v%d，程序支持到 v%d，请升级程序", ErrSchemaTooNew, current, SchemaVersion)
+	}
+	var pending []Migration
+	for _, migration := range migrations {
+		if migration.Version > current {
+			pending = append(pending, migration)
+		}
+	}
+	return current, pending, nil
+}
+
+// MigrationStatus 列出全部迁移及执行时间；数据库中存在程序未知的版本时一并列出
+func MigrationStatus(db *gorm.DB) ([]MigrationState, error) {
+	var applied []SchemaMigration
+	if db.Migrator().HasTable(&SchemaMigration{}) {
+		if err := db.Order("version").Find(&applied).Error; err != nil {
+			return nil, err
+		}
+	}
+	appliedAt := make(map[int]SchemaMigration, len(applied))
+	for _, record := range applied {
+		appliedAt[record.Version] = record
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, migration := range migrations {
+		state := MigrationState{Version: migration.Version, Name: migration.Name}
+		if record, ok := appliedAt[migration.Version]; ok {
+			state.AppliedAt = &record.AppliedAt
+			delete(appliedAt, migration.Version)
+		}
+		states = append(states, state)
+	}
+	for _, record := range applied {
+		if _, unknown := appliedAt[record.Version]; unknown {
+			states = append(states, MigrationState{Version: record.Version, Name: record.Name, AppliedAt: &record.AppliedAt})
+		}
+	}
+	return states, nil
+}
+
+// Migrate 依次执行待执行的迁移，每步与其迁移记录在同一事务中提交。
+// sqlitePath 非空、数据库已有表且存在待执行迁移时，先用 VACUUM INTO 在同目录生成备份。
+func Migrate(db *gorm.DB, sqlitePath string) (*MigrateResult, error) {
+	current, pending, err := CheckVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	result := &MigrateResult{From: current, To: current, Applied: []string{}}
+	if len(pending) == 0 {
+		return result, nil
+	}
+
+	if sqlitePath != "" && hasExistingSchema(db) {
+		result.BackupPath = fmt.Sprintf("%s.pre-migrate-v%d-%s", sqlitePath, current, time.Now().Format("20060102-150405"))
+		if err := db.Exec("VACUUM INTO ?", result.BackupPath).Error; err != nil {
+			return result, fmt.Errorf("迁移前备份数据库失败: %w", err)
+		}
+		log.Printf("迁移前已备份数据库到 %s", result.BackupPath)
+	}
+
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return result, fmt.Errorf("创建迁移记录表失败: %w", err)
+	}
+	for _, migration := range pending {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return result, fmt.Errorf("执行迁移 v%d %s 失败: %w", migration.Version, migration.Name, err)
+		}
+		log.Printf("已执行数据库迁移 v%d %s", migration.Version, migration.Name)
+		result.To = migration.Version
+		result.Applied = append(result.Applied, fmt.Sprintf("v%d %s", migration.Version, migration.Name))
+	}
+	return result, nil
+}
+
+// hasExistingSchema 数据库中已有任意业务表（含引入迁移记录前的旧库）
+func hasExistingSchema(db *gorm.DB) bool {
+	migrator := db.Migrator()
+	if migrator.HasTable(&SchemaMigration{}) {
+		return true
+	}
+	for _, model := range Models() {
+		if migrator.HasTable(model) {
+			return true
+		}
+	}
+	return false
+}
+
+// SQLiteFilePath 返回迁移前需要备份的 SQLite 文件路径，内存库与 URI 形式返回空
+func SQLiteFilePath(cfg Config) string {
+	if normalizeDriver(cfg.Driver) != "sqlite" {
+		return ""
+	}
+	path := cfg.Path
+	if strings.TrimSpace(cfg.DSN) != "" {
+		path = cfg.DSN
+	}
+	if path == ":memory:" || strings.HasPrefix(path, "file:") {
+		return ""
+	}
+	return path
+}
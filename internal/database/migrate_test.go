@@ -0,0 +1,201 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/Rehtt/hamster-bin/internal/repository"
+	"github.com/Rehtt/hamster-bin/internal/searchindex"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T, path string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("db: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func TestSchemaVersionMatchesLatestMigration(t *testing.T) {
+	for i, migration := range migrations {
+		if migration.Version != i+1 || migration.Name == "" || migration.Up == nil {
+			t.Fatalf("migration %d invalid: %+v", i, migration)
+		}
+	}
+	if latest := migrations[len(migrations)-1].Version; latest != SchemaVersion {
+		t.Fatalf("SchemaVersion = %d, latest migration = %d", SchemaVersion, latest)
+	}
+}
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	db := openTestDB(t, ":memory:")
+
+	result, err := Migrate(db, "")
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if result.From != 0 || result.To != SchemaVersion || len(result.Applied) != len(migrations) || result.BackupPath != "" {
+		t.Fatalf("result = %+v", result)
+	}
+	for _, model := range Models() {
+		if !db.Migrator().HasTable(model) {
+			t.Fatalf("table for %T not created", model)
+		}
+	}
+	if !db.Migrator().HasTable(searchindex.Table) {
+		t.Fatalf("full-text index not created")
+	}
+
+	again, err := Migrate(db, "")
+	if err != nil {
+		t.Fatalf("Migrate again: %v", err)
+	}
+	if len(again.Applied) != 0 || again.From != SchemaVersion {
+		t.Fatalf("second Migrate = %+v", again)
+	}
+
+	states, err := MigrationStatus(db)
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	if len(states) != len(migrations) || states[0].AppliedAt == nil {
+		t.Fatalf("states = %+v", states)
+	}
+}
+
+// 引入迁移记录前的旧库：表已存在、仍有旧的全局唯一索引，迁移前应生成备份
+func TestMigrateLegacySQLiteCreatesBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.db")
+	db := openTestDB(t, path)
+	if err := db.AutoMigrate(Models()...); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	if err := db.Exec("CREATE UNIQUE INDEX idx_suppliers_name ON suppliers(name)").Error; err != nil {
+		t.Fatalf("create legacy index: %v", err)
+	}
+	if err := db.Create(&models.Workspace{Name: "旧数据"}).Error; err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	result, err := Migrate(db, path)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if result.BackupPath == "" {
+		t.Fatalf("legacy database should be backed up: %+v", result)
+	}
+	if db.Migrator().HasIndex(&models.Supplier{}, "idx_suppliers_name") {
+		t.Fatalf("legacy unique index should be dropped")
+	}
+
+	backup := openTestDB(t, result.BackupPath)
+	var count int64
+	backup.Model(&models.Workspace{}).Count(&count)
+	if count != 1 || backup.Migrator().HasTable(&SchemaMigration{}) {
+		t.Fatalf("backup should be the pre-migration database, workspaces = %d", count)
+	}
+}
+
+func TestMigrateFreshSQLiteSkipsBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "inventory.db")
+	result, err := Migrate(openTestDB(t, path), path)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if result.BackupPath != "" {
+		t.Fatalf("empty database should not be backed up: %s", result.BackupPath)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.pre-migrate-*")); len(matches) != 0 {
+		t.Fatalf("unexpected backup files %v", matches)
+	}
+}
+
+func TestCheckVersionRejectsNewerSchema(t *testing.T) {
+	db := openTestDB(t, ":memory:")
+	if _, err := Migrate(db, ""); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	future := SchemaMigration{Version: SchemaVersion + 1, Name: "future", AppliedAt: time.Now()}
+	if err := db.Create(&future).Error; err != nil {
+		t.Fatalf("insert future migration: %v", err)
+	}
+
+	if _, _, err := CheckVersion(db); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("CheckVersion err = %v, want ErrSchemaTooNew", err)
+	}
+	if _, err := Migrate(db, ""); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("Migrate err = %v, want ErrSchemaTooNew", err)
+	}
+	states, err := MigrationStatus(db)
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	if last := states[len(states)-1]; last.Version != future.Version || last.AppliedAt == nil {
+		t.Fatalf("unknown applied migration should be listed: %+v", states)
+	}
+}
+
+func TestInitRequiresMigrateWhenAutoMigrateDisabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.db")
+	err := Init(Config{Driver: "sqlite", Path: path})
+	if !errors.Is(err, ErrPendingMigrations) {
+		t.Fatalf("Init err = %v, want ErrPendingMigrations", err)
+	}
+	if err := Init(Config{Driver: "sqlite", Path: path, AutoMigrate: true}); err != nil {
+		t.Fatalf("Init with AutoMigrate: %v", err)
+	}
+	if version, err := CurrentVersion(GetDB()); err != nil || version != SchemaVersion {
+		t.Fatalf("CurrentVersion = %d, %v", version, err)
+	}
+	if sqlDB, err := GetDB().DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// v2 的库没有 search_keys 列，升级到 v3 时应补齐列、回填搜索键并重建索引
+func TestMigrateComponentSearchKeysBackfills(t *testing.T) {
+	db := openTestDB(t, ":memory:")
+	if _, err := Migrate(db, ""); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	for _, statement := range []string{
+		"DROP TRIGGER components_search_ai",
+		"DROP TRIGGER components_search_ad",
+		"DROP TRIGGER components_search_au",
+		"DROP TABLE " + searchindex.Table,
+		"ALTER TABLE components DROP COLUMN search_keys",
+		"INSERT INTO categories (id, workspace_id, name) VALUES (1, 1, '电阻')",
+		"INSERT INTO components (workspace_id, category_id, name, model) VALUES (1, 1, '贴片电阻', 'RC0603FR-0710KL')",
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+
+	if err := db.Transaction(migrateComponentSearchKeys); err != nil {
+		t.Fatalf("migrateComponentSearchKeys: %v", err)
+	}
+	repo := repository.NewComponentRepository(db)
+	for _, keyword := range []string{"tpdz", "RC0603FR"} {
+		items, total, err := repo.GetAll(repository.ComponentQuery{Keyword: keyword})
+		if err != nil || total != 1 || items[0].Name != "贴片电阻" {
+			t.Fatalf("GetAll(%q) = %+v, %d, %v", keyword, items, total, err)
+		}
+	}
+	if engine := repo.SearchEngine(); engine != repository.SearchEngineFTS5 {
+		t.Fatalf("engine = %s", engine)
+	}
+}
@@ -0,0 +1,64 @@
+package forecast
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/config"
+	"github.com/Rehtt/hamster-bin/internal/cron"
+	"github.com/Rehtt/hamster-bin/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Job 在服务进程内按 cron 重新计算全部工作区的消耗预测
+type Job struct {
+	repo   *repository.ForecastRepository
+	params repository.ForecastParams
+	loop   cron.WorkspaceJob
+
+	running sync.Mutex
+}
+
+// NewJob 按配置创建预测任务；FORECAST_SCHEDULE 为空或 off 时只在启动时与手动触发时计算
+func NewJob(db *gorm.DB, cfg *config.Config) (*Job, error) {
+	j := &Job{
+		repo: repository.NewForecastRepository(db),
+		params: repository.ForecastParams{
+			WindowDays:   cfg.ForecastWindowDays,
+			LeadTimeDays: cfg.ForecastLeadTimeDays,
+			TargetDays:   cfg.ForecastTargetDays,
+		},
+	}
+	if cron.Enabled(cfg.ForecastSchedule) {
+		var err error
+		if j.loop.Schedule, err = cron.Parse(cfg.ForecastSchedule); err != nil {
+			return nil, fmt.Errorf("FORECAST_SCHEDULE: %w", err)
+		}
+	}
+	j.loop.Name = "消耗预测计算"
+	j.loop.Workspaces = repository.NewWorkspaceRepository(db).IDsWithComponents
+	j.loop.Run = func(workspaceID uint, now time.Time) error {
+		_, err := j.RunWorkspace(workspaceID, now)
+		return err
+	}
+	return j, nil
+}
+
+// Params 返回计算使用的参数
+func (j *Job) Params() repository.ForecastParams {
+	return j.params
+}
+
+// Start 先计算一次，再按 cron 定时计算，ctx 取消时退出
+func (j *Job) Start(ctx context.Context) {
+	j.loop.Start(ctx)
+}
+
+// RunWorkspace 重新计算单个工作区，返回计算的元件数
+func (j *Job) RunWorkspace(workspaceID uint, now time.Time) (int, error) {
+	j.running.Lock()
+	defer j.running.Unlock()
+	return j.repo.ForWorkspace(workspaceID).Recompute(j.params, now)
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/abc"
+	"github.com/Rehtt/hamster-bin/internal/middleware"
+	"github.com/Rehtt/hamster-bin/internal/repository"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ABCHandler struct {
+	repo *repository.ABCRepository
+	job  *abc.Job
+}
+
+func NewABCHandler(db *gorm.DB, job *abc.Job) *ABCHandler {
+	return &ABCHandler{repo: repository.NewABCRepository(db), job: job}
+}
+
+// GetSummary 按元件当前的 ABC 分类汇总元件数、统计期内出库金额与次数、指标占比及库存价值
+// @route GET /api/v1/stats/abc
+// 分类由定时任务按 ABC_METRIC（value 出库金额 / movements 出库次数）与阈值计算，params 为计算使用的参数。
+func (h *ABCHandler) GetSummary(c *gin.Context) {
+	summary, err := h.repo.ForWorkspace(middleware.CurrentWorkspaceID(c)).Summary(h.job.Params(), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取 ABC 分类统计失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": summary})
+}
+
+// Recompute 立即重新计算当前工作区的 ABC 分类（编辑者及以上）
+// @route POST /api/v1/stats/abc/recompute
+func (h *ABCHandler) Recompute(c *gin.Context) {
+	counts, err := h.job.ClassifyWorkspace(middleware.CurrentWorkspaceID(c), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "计算 ABC 分类失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"counts": counts, "params": h.job.Params()}})
+}
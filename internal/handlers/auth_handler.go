@@ -1,19 +1,29 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/Rehtt/hamster-bin/internal/auth"
 	"github.com/Rehtt/hamster-bin/internal/config"
+	"github.com/Rehtt/hamster-bin/internal/middleware"
+	"github.com/Rehtt/hamster-bin/internal/repository"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type AuthHandler struct {
-	cfg *config.Config
+	cfg           *config.Config
+	userRepo      *repository.UserRepository
+	twoFactorRepo *repository.TwoFactorRepository
 }
 
-func NewAuthHandler(cfg *config.Config) *AuthHandler {
-	return &AuthHandler{cfg: cfg}
+func NewAuthHandler(cfg *config.Config, db *gorm.DB) *AuthHandler {
+	return &AuthHandler{
+		cfg:           cfg,
+		userRepo:      repository.NewUserRepository(db),
+		twoFactorRepo: repository.NewTwoFactorRepository(db),
+	}
 }
 
 type loginRequest struct {
@@ -21,7 +31,22 @@ type loginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// Login 管理员登录
+// checkPassword 校验管理员（ADMIN_USERNAME）或账号表中的用户凭据
+func (h *AuthHandler) checkPassword(username, password string) (bool, error) {
+	if auth.CheckCredentials(username, password, h.cfg.AdminUsername, h.cfg.AdminPassword) {
+		return true, nil
+	}
+	if username == h.cfg.AdminUsername {
+		return false, nil
+	}
+	_, err := h.userRepo.Authenticate(username, password)
+	if errors.Is(err, repository.ErrInvalidCredentials) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Login 管理员或账号登录
 // @route POST /api/v1/auth/login
 func (h *AuthHandler) Login(c *gin.Context) {
 	if !h.cfg.IsAuthEnabled() {
@@ -35,11 +60,41 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	if !auth.CheckCredentials(req.Username, req.Password, h.cfg.AdminUsername, h.cfg.AdminPassword) {
+	valid, err := h.checkPassword(req.Username, req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "登录失败"})
+		return
+	}
+	if !valid {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
 		return
 	}
 
+	twoFactorEnabled, err := h.twoFactorRepo.IsEnabled(req.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "登录失败"})
+		return
+	}
+
+	// 已绑定或策略强制要求二次验证时，只签发短期等待 token，完成 /auth/2fa 校验后再登录
+	if twoFactorEnabled || h.cfg.TwoFactorRequired {
+		pending, err := auth.IssuePendingToken(req.Username, h.cfg.JWTSecret)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "登录失败"})
+			return
+		}
+		setPendingCookie(c, h.cfg, pending)
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"auth_enabled":        true,
+				"username":            req.Username,
+				"two_factor_required": true,
+				"enrollment_required": !twoFactorEnabled,
+			},
+		})
+		return
+	}
+
 	token, err := auth.IssueToken(req.Username, h.cfg.JWTSecret, h.cfg.JWTExpireHours)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "登录失败"})
@@ -59,6 +114,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 // @route POST /api/v1/auth/logout
 func (h *AuthHandler) Logout(c *gin.Context) {
 	clearAuthCookie(c, h.cfg)
+	clearPendingCookie(c, h.cfg)
 	c.JSON(http.StatusOK, gin.H{"message": "已退出登录"})
 }
 
@@ -74,15 +130,13 @@ func (h *AuthHandler) Me(c *gin.Context) {
 		return
 	}
 
-	token, err := c.Cookie(auth.CookieName)
-	if err != nil || token == "" {
+	claims, err := middleware.ParseSession(c, h.cfg, h.twoFactorRepo)
+	if errors.Is(err, auth.ErrInvalidToken) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录或登录已过期"})
 		return
 	}
-
-	claims, err := auth.ParseToken(token, h.cfg.JWTSecret)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录或登录已过期"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取登录状态失败"})
 		return
 	}
 
@@ -90,6 +144,7 @@ func (h *AuthHandler) Me(c *gin.Context) {
 		"data": gin.H{
 			"auth_enabled": true,
 			"username":     claims.Username,
+			"is_admin":     claims.Username == h.cfg.AdminUsername,
 		},
 	})
 }
@@ -104,3 +159,13 @@ func clearAuthCookie(c *gin.Context, cfg *config.Config) {
 	c.SetSameSite(http.SameSiteLaxMode)
 	c.SetCookie(auth.CookieName, "", -1, "/", "", cfg.IsHTTPS(), true)
 }
+
+func setPendingCookie(c *gin.Context, cfg *config.Config, token string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(auth.PendingCookieName, token, auth.PendingTokenMinutes*60, "/", "", cfg.IsHTTPS(), true)
+}
+
+func clearPendingCookie(c *gin.Context, cfg *config.Config) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(auth.PendingCookieName, "", -1, "/", "", cfg.IsHTTPS(), true)
+}
@@ -6,10 +6,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/Rehtt/hamster-bin/internal/auth"
 	"github.com/Rehtt/hamster-bin/internal/config"
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/Rehtt/hamster-bin/internal/repository"
 	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
 )
 
 func init() {
@@ -30,8 +35,20 @@ func testAuthConfig(enabled bool) *config.Config {
 	return cfg
 }
 
+func setupAuthTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.TwoFactorAuth{}, &models.TwoFactorRecoveryCode{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
 func TestAuthHandlerMeAuthDisabled(t *testing.T) {
-	handler := NewAuthHandler(testAuthConfig(false))
+	handler := NewAuthHandler(testAuthConfig(false), setupAuthTestDB(t))
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
@@ -52,7 +69,7 @@ func TestAuthHandlerMeAuthDisabled(t *testing.T) {
 }
 
 func TestAuthHandlerLoginSuccess(t *testing.T) {
-	handler := NewAuthHandler(testAuthConfig(true))
+	handler := NewAuthHandler(testAuthConfig(true), setupAuthTestDB(t))
 	body, _ := json.Marshal(map[string]string{
 		"username": "admin",
 		"password": "secret",
@@ -85,7 +102,7 @@ func TestAuthHandlerLoginSuccess(t *testing.T) {
 }
 
 func TestAuthHandlerLoginInvalidCredentials(t *testing.T) {
-	handler := NewAuthHandler(testAuthConfig(true))
+	handler := NewAuthHandler(testAuthConfig(true), setupAuthTestDB(t))
 	body, _ := json.Marshal(map[string]string{
 		"username": "admin",
 		"password": "wrong",
@@ -102,9 +119,42 @@ func TestAuthHandlerLoginInvalidCredentials(t *testing.T) {
 	}
 }
 
+func TestAuthHandlerLoginUserAccount(t *testing.T) {
+	db := setupAuthTestDB(t)
+	if _, err := repository.NewUserRepository(db).Create("alice", "alice-password"); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	handler := NewAuthHandler(testAuthConfig(true), db)
+
+	tests := []struct {
+		username string
+		password string
+		want     int
+	}{
+		{"alice", "alice-password", http.StatusOK},
+		{"alice", "wrong-password", http.StatusUnauthorized},
+		{"bob", "alice-password", http.StatusUnauthorized},
+		// 账号表中的密码不能用于登录管理员
+		{"admin", "alice-password", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		body, _ := json.Marshal(map[string]string{"username": tt.username, "password": tt.password})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Login(c)
+
+		if w.Code != tt.want {
+			t.Fatalf("login %s/%s status = %d, want %d", tt.username, tt.password, w.Code, tt.want)
+		}
+	}
+}
+
 func TestAuthHandlerMeAuthenticated(t *testing.T) {
 	cfg := testAuthConfig(true)
-	handler := NewAuthHandler(cfg)
+	handler := NewAuthHandler(cfg, setupAuthTestDB(t))
 
 	token, err := auth.IssueToken("admin", cfg.JWTSecret, cfg.JWTExpireHours)
 	if err != nil {
@@ -135,7 +185,7 @@ func TestAuthHandlerMeAuthenticated(t *testing.T) {
 }
 
 func TestAuthHandlerLogoutClearsCookie(t *testing.T) {
-	handler := NewAuthHandler(testAuthConfig(true))
+	handler := NewAuthHandler(testAuthConfig(true), setupAuthTestDB(t))
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", nil)
@@ -153,3 +203,172 @@ func TestAuthHandlerLogoutClearsCookie(t *testing.T) {
 		}
 	}
 }
+
+func setupTwoFactorRouter(cfg *config.Config, db *gorm.DB) *gin.Engine {
+	handler := NewAuthHandler(cfg, db)
+	r := gin.New()
+	r.POST("/login", handler.Login)
+	r.POST("/2fa/setup", handler.SetupTwoFactor)
+	r.POST("/2fa/enable", handler.EnableTwoFactor)
+	r.POST("/2fa/verify", handler.VerifyTwoFactor)
+	r.POST("/2fa/disable", handler.DisableTwoFactor)
+	return r
+}
+
+func performJSON(r *gin.Engine, path string, body any, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func findCookie(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == name && cookie.Value != "" {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func currentTOTPCode(t *testing.T, secret string, offset time.Duration) string {
+	t.Helper()
+	code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now().Add(offset)))
+	if err != nil {
+		t.Fatalf("TOTPCode() error = %v", err)
+	}
+	return code
+}
+
+func TestTwoFactorLoginRequiresSecondStep(t *testing.T) {
+	cfg := testAuthConfig(true)
+	db := setupAuthTestDB(t)
+	repo := repository.NewTwoFactorRepository(db)
+	item, err := repo.BeginSetup("admin")
+	if err != nil {
+		t.Fatalf("BeginSetup() error = %v", err)
+	}
+	recoveryCodes, err := repo.Enable("admin", currentTOTPCode(t, item.Secret, -30*time.Second), time.Now())
+	if err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+	r := setupTwoFactorRouter(cfg, db)
+	credentials := map[string]string{"username": "admin", "password": "secret"}
+
+	w := performJSON(r, "/login", credentials)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if findCookie(w, auth.CookieName) != nil {
+		t.Fatal("expected no auth cookie before second step")
+	}
+	pending := findCookie(w, auth.PendingCookieName)
+	if pending == nil {
+		t.Fatal("expected pending cookie")
+	}
+
+	if w := performJSON(r, "/2fa/verify", map[string]string{"code": "000000"}, pending); w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong code status = %d, want 401", w.Code)
+	}
+
+	w = performJSON(r, "/2fa/verify", map[string]string{"code": currentTOTPCode(t, item.Secret, 0)}, pending)
+	if w.Code != http.StatusOK {
+		t.Fatalf("verify status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if findCookie(w, auth.CookieName) == nil {
+		t.Fatal("expected auth cookie after verification")
+	}
+
+	w = performJSON(r, "/login", credentials)
+	pending = findCookie(w, auth.PendingCookieName)
+	if w := performJSON(r, "/2fa/verify", map[string]string{"recovery_code": recoveryCodes[0]}, pending); w.Code != http.StatusOK {
+		t.Fatalf("recovery code status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w := performJSON(r, "/2fa/verify", map[string]string{"recovery_code": recoveryCodes[0]}, pending); w.Code != http.StatusUnauthorized {
+		t.Fatalf("reused recovery code status = %d, want 401", w.Code)
+	}
+}
+
+func TestTwoFactorLockoutInvalidatesPendingSession(t *testing.T) {
+	cfg := testAuthConfig(true)
+	db := setupAuthTestDB(t)
+	repo := repository.NewTwoFactorRepository(db)
+	item, err := repo.BeginSetup("admin")
+	if err != nil {
+		t.Fatalf("BeginSetup() error = %v", err)
+	}
+	if _, err := repo.Enable("admin", currentTOTPCode(t, item.Secret, -30*time.Second), time.Now()); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+	r := setupTwoFactorRouter(cfg, db)
+	credentials := map[string]string{"username": "admin", "password": "secret"}
+	pending := findCookie(performJSON(r, "/login", credentials), auth.PendingCookieName)
+
+	for i := 1; i < repository.TwoFactorMaxFailures; i++ {
+		if w := performJSON(r, "/2fa/verify", map[string]string{"recovery_code": "wrong-code"}, pending); w.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d status = %d, want 401", i, w.Code)
+		}
+	}
+	w := performJSON(r, "/2fa/verify", map[string]string{"recovery_code": "wrong-code"}, pending)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("final attempt status = %d, want 429", w.Code)
+	}
+
+	// 锁定前签发的等待 token 作废，即使动态码正确也不能登录
+	if w := performJSON(r, "/2fa/verify", map[string]string{"code": currentTOTPCode(t, item.Secret, 0)}, pending); w.Code != http.StatusUnauthorized {
+		t.Fatalf("old pending token status = %d, want 401", w.Code)
+	}
+	// 重新输入密码得到新的等待 token，锁定期内仍被拒绝
+	time.Sleep(time.Second)
+	pending = findCookie(performJSON(r, "/login", credentials), auth.PendingCookieName)
+	if w := performJSON(r, "/2fa/verify", map[string]string{"code": currentTOTPCode(t, item.Secret, 0)}, pending); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("locked verify status = %d, want 429", w.Code)
+	}
+}
+
+func TestTwoFactorRequiredPolicyEnrollsDuringLogin(t *testing.T) {
+	cfg := testAuthConfig(true)
+	cfg.TwoFactorRequired = true
+	r := setupTwoFactorRouter(cfg, setupAuthTestDB(t))
+
+	w := performJSON(r, "/login", map[string]string{"username": "admin", "password": "secret"})
+	var loginResp map[string]map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &loginResp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if loginResp["data"]["enrollment_required"] != true {
+		t.Fatalf("enrollment_required = %v, want true", loginResp["data"]["enrollment_required"])
+	}
+	pending := findCookie(w, auth.PendingCookieName)
+	if pending == nil {
+		t.Fatal("expected pending cookie")
+	}
+
+	w = performJSON(r, "/2fa/setup", nil, pending)
+	var setupResp map[string]map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &setupResp); err != nil {
+		t.Fatalf("unmarshal setup response: %v (%s)", err, w.Body.String())
+	}
+	secret := setupResp["data"]["secret"]
+	if secret == "" || setupResp["data"]["otpauth_uri"] == "" {
+		t.Fatalf("unexpected setup response: %s", w.Body.String())
+	}
+
+	w = performJSON(r, "/2fa/enable", map[string]string{"code": currentTOTPCode(t, secret, 0)}, pending)
+	if w.Code != http.StatusOK {
+		t.Fatalf("enable status = %d, body = %s", w.Code, w.Body.String())
+	}
+	authCookie := findCookie(w, auth.CookieName)
+	if authCookie == nil {
+		t.Fatal("expected auth cookie after enrollment")
+	}
+
+	if w := performJSON(r, "/2fa/disable", map[string]string{"code": "123456"}, authCookie); w.Code != http.StatusForbidden {
+		t.Fatalf("disable status = %d, want 403", w.Code)
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/backup"
+	"github.com/Rehtt/hamster-bin/internal/config"
+	"github.com/Rehtt/hamster-bin/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type BackupHandler struct {
+	cfg       *config.Config
+	db        *gorm.DB
+	scheduler *backup.Scheduler
+}
+
+func NewBackupHandler(cfg *config.Config, db *gorm.DB, scheduler *backup.Scheduler) *BackupHandler {
+	return &BackupHandler{cfg: cfg, db: db, scheduler: scheduler}
+}
+
+func (h *BackupHandler) requireAdmin(c *gin.Context, action string) bool {
+	if !middleware.IsInstanceAdmin(h.cfg, c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "仅管理员可" + action})
+		return false
+	}
+	return true
+}
+
+// Download 下载整库备份（全部工作区的数据与图片，含二次验证密钥，仅实例管理员）
+// @route GET /api/v1/backup
+func (h *BackupHandler) Download(c *gin.Context) {
+	if !h.requireAdmin(c, "下载备份") {
+		return
+	}
+
+	filename := backup.FileName(time.Now())
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+	if _, err := backup.Write(c.Request.Context(), h.db, h.cfg.ImageDir, c.Writer); err != nil {
+		if c.Writer.Written() {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成备份失败"})
+	}
+}
+
+// Restore 从备份文件恢复（仅实例管理员）
+// @route POST /api/v1/backup/restore  multipart: file=xxx.zip, mode=replace|merge, dry_run=true
+// replace 清空现有数据与图片后按原样恢复；merge 重新分配 ID 追加数据，已存在的记录跳过。
+// dry_run=true 时只校验备份文件并返回清单。
+func (h *BackupHandler) Restore(c *gin.Context) {
+	if !h.requireAdmin(c, "恢复备份") {
+		return
+	}
+
+	mode := c.PostForm("mode")
+	if !backup.IsValidRestoreMode(mode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": backup.ErrInvalidRestoreMode.Error()})
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请上传备份文件"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取文件失败"})
+		return
+	}
+	defer file.Close()
+
+	dryRun := c.PostForm("dry_run") == "true"
+	report, err := backup.Restore(c.Request.Context(), h.db, h.cfg.ImageDir, file, fileHeader.Size, mode, dryRun)
+	if err != nil {
+		if errors.Is(err, backup.ErrInvalidArchive) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复备份失败: " + err.Error(), "data": report})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// GetSchedule 定时备份状态：cron 表达式、下次执行时间、最近一次结果与本地备份列表
+// @route GET /api/v1/backup/schedule
+func (h *BackupHandler) GetSchedule(c *gin.Context) {
+	if !h.requireAdmin(c, "查看备份") {
+		return
+	}
+	status, err := h.scheduler.Status()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取备份目录失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": status})
+}
+
+// RunNow 立即执行一次定时备份流程（写入备份目录、上传、清理过期备份）
+// @route POST /api/v1/backup/run
+func (h *BackupHandler) RunNow(c *gin.Context) {
+	if !h.requireAdmin(c, "执行备份") {
+		return
+	}
+	status, err := h.scheduler.RunBackup(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, backup.ErrBackupRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "备份失败: " + err.Error(), "data": status})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": status})
+}
+
+// RunMaintenance 立即执行一次 SQLite 维护（其他数据库直接返回）
+// @route POST /api/v1/backup/maintenance
+func (h *BackupHandler) RunMaintenance(c *gin.Context) {
+	if !h.requireAdmin(c, "维护数据库") {
+		return
+	}
+	status, err := h.scheduler.RunMaintenance()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库维护失败: " + err.Error(), "data": status})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": status})
+}
+
+// DownloadFile 下载备份目录中的指定备份
+// @route GET /api/v1/backup/files/:name
+func (h *BackupHandler) DownloadFile(c *gin.Context) {
+	if !h.requireAdmin(c, "下载备份") {
+		return
+	}
+	file, err := h.scheduler.OpenFile(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "备份文件不存在"})
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取备份文件失败"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, info.Name()))
+	http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), file)
+}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/Rehtt/hamster-bin/internal/middleware"
 	"github.com/Rehtt/hamster-bin/internal/models"
 	"github.com/Rehtt/hamster-bin/internal/repository"
 	"github.com/gin-gonic/gin"
@@ -20,10 +22,15 @@ func NewCategoryHandler(db *gorm.DB) *CategoryHandler {
 	}
 }
 
+// repoFor 返回限定在当前请求工作区内的仓储
+func (h *CategoryHandler) repoFor(c *gin.Context) *repository.CategoryRepository {
+	return h.repo.ForWorkspace(middleware.CurrentWorkspaceID(c))
+}
+
 // GetAll 获取所有分类
 // @route GET /api/v1/categories
 func (h *CategoryHandler) GetAll(c *gin.Context) {
-	categories, err := h.repo.GetAll()
+	categories, err := h.repoFor(c).GetAll()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分类失败"})
 		return
@@ -31,6 +38,17 @@ func (h *CategoryHandler) GetAll(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": categories})
 }
 
+// GetTree 获取分类树，每个节点含元件数量与库存价值（含子孙分类汇总）
+// @route GET /api/v1/categories/tree
+func (h *CategoryHandler) GetTree(c *gin.Context) {
+	tree, err := h.repoFor(c).GetTree()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分类树失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tree})
+}
+
 // GetByID 获取单个分类
 // @route GET /api/v1/categories/:id
 func (h *CategoryHandler) GetByID(c *gin.Context) {
@@ -40,7 +58,7 @@ func (h *CategoryHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	category, err := h.repo.GetByID(uint(id))
+	category, err := h.repoFor(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "分类不存在"})
 		return
@@ -58,7 +76,11 @@ func (h *CategoryHandler) Create(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.Create(&category); err != nil {
+	if err := h.repoFor(c).Create(&category); err != nil {
+		if errors.Is(err, repository.ErrWorkspaceMismatch) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建分类失败"})
 		return
 	}
@@ -76,7 +98,7 @@ func (h *CategoryHandler) Update(c *gin.Context) {
 	}
 
 	// 1. 先获取现有分类
-	category, err := h.repo.GetByID(uint(id))
+	category, err := h.repoFor(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "分类不存在"})
 		return
@@ -92,7 +114,11 @@ func (h *CategoryHandler) Update(c *gin.Context) {
 	category.ID = uint(id)
 
 	// 4. 保存更新
-	if err := h.repo.Update(category); err != nil {
+	if err := h.repoFor(c).Update(category); err != nil {
+		if errors.Is(err, repository.ErrWorkspaceMismatch) || errors.Is(err, repository.ErrCategoryCycle) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新分类失败"})
 		return
 	}
@@ -100,8 +126,35 @@ func (h *CategoryHandler) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": category})
 }
 
-// Delete 删除分类
-// @route DELETE /api/v1/categories/:id
+// Move 移动分类（连同子树）到新的父分类下，不允许移动到自身或子孙分类下
+// @route PUT /api/v1/categories/:id/move
+// Body: {"parent_id": 3}，parent_id 为 null 表示移动到根级
+func (h *CategoryHandler) Move(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
+		return
+	}
+
+	var req struct {
+		ParentID *uint `json:"parent_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+
+	category, err := h.repoFor(c).Move(uint(id), req.ParentID)
+	if err != nil {
+		writeCategoryError(c, err, "移动分类失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": category})
+}
+
+// Delete 删除分类；仍被引用时需通过 reassign_to 指定转移分类，否则返回 400
+// @route DELETE /api/v1/categories/:id?reassign_to=2
 func (h *CategoryHandler) Delete(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -109,10 +162,36 @@ func (h *CategoryHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.Delete(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除分类失败"})
+	var reassignTo *uint
+	if raw := c.Query("reassign_to"); raw != "" {
+		target, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的转移分类 ID"})
+			return
+		}
+		uid := uint(target)
+		reassignTo = &uid
+	}
+
+	if err := h.repoFor(c).Delete(uint(id), reassignTo); err != nil {
+		writeCategoryError(c, err, "删除分类失败")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
 }
+
+func writeCategoryError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrCategoryCycle),
+		errors.Is(err, repository.ErrCategoryInUse),
+		errors.Is(err, repository.ErrCategoryHasChildren),
+		errors.Is(err, repository.ErrInvalidReassign),
+		errors.Is(err, repository.ErrWorkspaceMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "分类不存在"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
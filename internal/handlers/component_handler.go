@@ -1,13 +1,15 @@
 package handlers
 
 import (
-	"encoding/csv"
 	"errors"
 	"fmt"
 	"image"
+	"log"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -16,6 +18,7 @@ import (
 	_ "image/png"
 
 	"github.com/Rehtt/hamster-bin/internal/config"
+	"github.com/Rehtt/hamster-bin/internal/middleware"
 	"github.com/Rehtt/hamster-bin/internal/models"
 	"github.com/Rehtt/hamster-bin/internal/price"
 	"github.com/Rehtt/hamster-bin/internal/repository"
@@ -26,17 +29,31 @@ import (
 )
 
 type ComponentHandler struct {
-	componentRepo *repository.ComponentRepository
-	stockLogRepo  *repository.StockLogRepository
+	componentRepo   *repository.ComponentRepository
+	stockLogRepo    *repository.StockLogRepository
+	savedSearchRepo *repository.SavedSearchRepository
+	forecastRepo    *repository.ForecastRepository
 }
 
 func NewComponentHandler(db *gorm.DB) *ComponentHandler {
 	return &ComponentHandler{
-		componentRepo: repository.NewComponentRepository(db),
-		stockLogRepo:  repository.NewStockLogRepository(db),
+		componentRepo:   repository.NewComponentRepository(db),
+		stockLogRepo:    repository.NewStockLogRepository(db),
+		savedSearchRepo: repository.NewSavedSearchRepository(db),
+		forecastRepo:    repository.NewForecastRepository(db),
 	}
 }
 
+// componentRepoFor 返回当前请求工作区的元件仓储
+func (h *ComponentHandler) componentRepoFor(c *gin.Context) *repository.ComponentRepository {
+	return h.componentRepo.ForWorkspace(middleware.CurrentWorkspaceID(c))
+}
+
+// stockLogRepoFor 返回当前请求工作区的库存记录仓储
+func (h *ComponentHandler) stockLogRepoFor(c *gin.Context) *repository.StockLogRepository {
+	return h.stockLogRepo.ForWorkspace(middleware.CurrentWorkspaceID(c))
+}
+
 var componentExportColumnLabels = map[string]string{
 	"component_number":     "系统编号",
 	"name":                 "名称",
@@ -54,44 +71,136 @@ var componentExportColumnLabels = map[string]string{
 	"datasheet_url":        "数据手册",
 	"created_at":           "创建时间",
 	"updated_at":           "更新时间",
-}
+	"abc_class":            "ABC分类",
 
-func parseComponentQueryFromContext(c *gin.Context) repository.ComponentQuery {
-	var query repository.ComponentQuery
+	"avg_daily_consumption": "日均消耗",
+	"days_of_cover":         "可用天数",
+	"stockout_date":         "预计断货日期",
+	"reorder_quantity":      "建议补货数量",
+}
 
-	if page := c.Query("page"); page != "" {
-		query.Page, _ = strconv.Atoi(page)
-	} else {
-		query.Page = 1
+// isForecastColumn 判断是否为消耗预测列，导出包含这些列时才加载预测
+func isForecastColumn(column string) bool {
+	switch column {
+	case "avg_daily_consumption", "days_of_cover", "stockout_date", "reorder_quantity":
+		return true
 	}
+	return false
+}
 
-	if pageSize := c.Query("page_size"); pageSize != "" {
-		query.PageSize, _ = strconv.Atoi(pageSize)
-	} else {
-		query.PageSize = 20
+// componentParamsFromContext 读取元件列表的筛选与排序参数（与保存搜索的条件同名）
+func componentParamsFromContext(c *gin.Context) models.SavedSearchParams {
+	params := models.SavedSearchParams{
+		Keyword:              c.Query("keyword"),
+		Q:                    c.Query("q"),
+		ComponentNumber:      c.Query("component_number"),
+		Name:                 c.Query("name"),
+		Model:                c.Query("model"),
+		Manufacturer:         c.Query("manufacturer"),
+		Value:                c.Query("value"),
+		Supplier:             c.Query("supplier"),
+		SupplierPartNumber:   c.Query("supplier_part_number"),
+		ABCClass:             c.Query("abc_class"),
+		SortBy:               strings.TrimSpace(c.Query("sort_by")),
+		SortOrder:            strings.TrimSpace(c.Query("sort_order")),
+		IncludeSubcategories: c.Query("include_subcategories") == "true",
 	}
-
-	query.Keyword = c.Query("keyword")
-	query.ComponentNumber = c.Query("component_number")
-	query.Name = c.Query("name")
-	query.Model = c.Query("model")
-	query.Manufacturer = c.Query("manufacturer")
-	query.Value = c.Query("value")
-	query.SupplierName = c.Query("supplier")
-	query.SupplierPartNumber = c.Query("supplier_part_number")
-
 	if categoryID := c.Query("category_id"); categoryID != "" {
 		id, err := strconv.ParseUint(categoryID, 10, 32)
 		if err == nil {
 			uid := uint(id)
-			query.CategoryID = &uid
+			params.CategoryID = &uid
+		}
+	}
+	return params
+}
+
+// overlaySavedSearchParams 请求中的非空参数覆盖保存的条件；两者都有 q 时同时生效（AND）
+func overlaySavedSearchParams(c *gin.Context, saved, req models.SavedSearchParams) models.SavedSearchParams {
+	params := saved
+	for _, field := range []struct {
+		dst *string
+		src string
+	}{
+		{&params.Keyword, req.Keyword},
+		{&params.ComponentNumber, req.ComponentNumber},
+		{&params.Name, req.Name},
+		{&params.Model, req.Model},
+		{&params.Manufacturer, req.Manufacturer},
+		{&params.Value, req.Value},
+		{&params.Supplier, req.Supplier},
+		{&params.SupplierPartNumber, req.SupplierPartNumber},
+		{&params.ABCClass, req.ABCClass},
+		{&params.SortBy, req.SortBy},
+		{&params.SortOrder, req.SortOrder},
+	} {
+		if strings.TrimSpace(field.src) != "" {
+			*field.dst = field.src
+		}
+	}
+	if strings.TrimSpace(req.Q) != "" {
+		if strings.TrimSpace(saved.Q) != "" {
+			params.Q = "(" + saved.Q + ") (" + req.Q + ")"
+		} else {
+			params.Q = req.Q
 		}
 	}
+	if req.CategoryID != nil {
+		params.CategoryID = req.CategoryID
+	}
+	if _, ok := c.GetQuery("include_subcategories"); ok {
+		params.IncludeSubcategories = req.IncludeSubcategories
+	}
+	return params
+}
 
-	query.SortBy = strings.TrimSpace(c.Query("sort_by"))
-	query.SortOrder = strings.TrimSpace(c.Query("sort_order"))
+// resolveComponentQuery 解析元件列表/导出的查询条件与分页。saved_search_id 指定时以该保存搜索为基础，
+// 请求参数覆盖其中同名条件；出错时已写入响应并返回 false
+func (h *ComponentHandler) resolveComponentQuery(c *gin.Context) (repository.ComponentQuery, *models.SavedSearch, bool) {
+	params := componentParamsFromContext(c)
+	var saved *models.SavedSearch
+	if raw := c.Query("saved_search_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的保存搜索 ID"})
+			return repository.ComponentQuery{}, nil, false
+		}
+		saved, err = h.savedSearchRepo.ForWorkspace(middleware.CurrentWorkspaceID(c)).GetByID(uint(id), middleware.CurrentUsername(c))
+		if err != nil {
+			writeSavedSearchError(c, err, "获取保存搜索失败")
+			return repository.ComponentQuery{}, nil, false
+		}
+		params = overlaySavedSearchParams(c, saved.Params, params)
+	}
 
-	return query
+	query, err := repository.SavedSearchQuery(params)
+	if err != nil {
+		writeFilterSyntaxError(c, err)
+		return repository.ComponentQuery{}, nil, false
+	}
+	if msg := validateComponentSort(query); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return repository.ComponentQuery{}, nil, false
+	}
+
+	query.Page, query.PageSize = 1, 20
+	if page := c.Query("page"); page != "" {
+		query.Page, _ = strconv.Atoi(page)
+	}
+	if pageSize := c.Query("page_size"); pageSize != "" {
+		query.PageSize, _ = strconv.Atoi(pageSize)
+	}
+	return query, saved, true
+}
+
+// writeFilterSyntaxError 查询语言语法错误返回 400（含出错位置）
+func writeFilterSyntaxError(c *gin.Context, err error) {
+	var syntaxErr *repository.FilterSyntaxError
+	if errors.As(err, &syntaxErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": syntaxErr.Error(), "position": syntaxErr.Pos})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 }
 
 func validateComponentSort(query repository.ComponentQuery) string {
@@ -105,7 +214,8 @@ func validateComponentSort(query repository.ComponentQuery) string {
 	return ""
 }
 
-func componentExportValue(component *models.Component, column string) string {
+// componentExportValue 返回导出单元格值：数量为整数，单价为元（float64，0 为空），其余为字符串
+func componentExportValue(component *models.Component, column string) any {
 	switch column {
 	case "component_number":
 		if component.ComponentNumber != nil {
@@ -130,12 +240,9 @@ func componentExportValue(component *models.Component, column string) string {
 		}
 		return ""
 	case "stock_quantity":
-		return strconv.Itoa(component.StockQuantity)
+		return component.StockQuantity
 	case "unit_price":
-		if component.UnitPriceMicro > 0 {
-			return fmt.Sprintf("%.6f", float64(component.UnitPriceMicro)/1e6)
-		}
-		return ""
+		return exportYuan(component.UnitPriceMicro, 1e6)
 	case "location":
 		return component.Location
 	case "supplier":
@@ -148,45 +255,129 @@ func componentExportValue(component *models.Component, column string) string {
 	case "datasheet_url":
 		return component.DatasheetURL
 	case "created_at":
-		return component.CreatedAt.Format("2006-01-02 15:04:05")
+		return exportTime(&component.CreatedAt)
 	case "updated_at":
-		return component.UpdatedAt.Format("2006-01-02 15:04:05")
-	default:
-		return ""
+		return exportTime(&component.UpdatedAt)
+	case "abc_class":
+		return component.ABCClass
+	}
+	if isForecastColumn(column) {
+		return forecastExportValue(component.Forecast, column)
+	}
+	return ""
+}
+
+// forecastExportValue 返回消耗预测列的值：日均消耗保留两位小数、可用天数保留一位小数，尚未计算或无消耗时为空
+func forecastExportValue(forecast *models.ComponentForecast, column string) any {
+	if forecast == nil {
+		return nil
+	}
+	switch column {
+	case "avg_daily_consumption":
+		return math.Round(forecast.AvgDailyConsumption*100) / 100
+	case "days_of_cover":
+		if forecast.DaysOfCover == nil {
+			return nil
+		}
+		return math.Round(*forecast.DaysOfCover*10) / 10
+	case "stockout_date":
+		if forecast.StockoutDate == nil {
+			return nil
+		}
+		return forecast.StockoutDate.In(time.Local).Format(time.DateOnly)
+	case "reorder_quantity":
+		return forecast.ReorderQuantity
 	}
+	return nil
 }
 
 // GetAll 获取所有元件（支持分页和搜索）
-// @route GET /api/v1/components?page=1&page_size=20&manufacturer=YAGEO&value=10k&category_id=1
-// 分字段 query：component_number、name、model、manufacturer、value、supplier、supplier_part_number；各字段内空格拆词 AND，字段间 AND。keyword 仍兼容旧客户端。
+// @route GET /api/v1/components?page=1&page_size=20&manufacturer=YAGEO&value=10k&category_id=1&include_subcategories=true
+// 分字段 query：component_number、name、model、manufacturer、value、supplier、supplier_part_number；各字段内空格拆词 AND，字段间 AND。
+// keyword 为全文搜索：优先走全文索引，未指定 sort_by（或为 relevance）时按相关度排序，每项附带 highlights，search.engine 为实际使用的实现。
+// q 为查询语言（如 pkg:0603 stock:<100 -cat:电容 (mfr:TI OR mfr:ST)），与其他条件 AND；语法错误返回 400 与 position。
+// keyword 支持名称/描述的拼音全拼与首字母；精确无结果时对型号类词按编辑距离容错匹配，此时 search.fuzzy 为 true。
+// saved_search_id 以保存搜索的条件与排序为基础，请求中的非空参数覆盖同名条件，q 与保存的 q 同时生效。
+// 每项附带 forecast（消耗预测，尚未计算时省略）；sort_by 可按 avg_daily_consumption、days_of_cover、stockout_date、reorder_quantity 排序。
+// abc_class 按 ABC 分类筛选（逗号分隔为或，如 A,B），sort_by=abc_class 按分类排序；查询语言同样支持 abc:A。
 func (h *ComponentHandler) GetAll(c *gin.Context) {
-	query := parseComponentQueryFromContext(c)
-	if msg := validateComponentSort(query); msg != "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+	query, _, ok := h.resolveComponentQuery(c)
+	if !ok {
 		return
 	}
 
-	components, total, err := h.componentRepo.GetAll(query)
+	repo := h.componentRepoFor(c)
+	components, total, fuzzy, err := repo.Search(query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取元件列表失败"})
 		return
 	}
+	if err := h.attachForecasts(c, components); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取元件列表失败"})
+		return
+	}
+
+	pagination := gin.H{
+		"page":       query.Page,
+		"page_size":  query.PageSize,
+		"total":      total,
+		"total_page": (total + int64(query.PageSize) - 1) / int64(query.PageSize),
+	}
+	if strings.TrimSpace(query.Keyword) == "" {
+		c.JSON(http.StatusOK, gin.H{"data": components, "pagination": pagination})
+		return
+	}
 
+	results := make([]repository.ComponentSearchResult, len(components))
+	for i := range components {
+		results[i] = repository.ComponentSearchResult{
+			Component:  components[i],
+			Highlights: repository.HighlightComponent(&components[i], query.Keyword),
+		}
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"data": components,
-		"pagination": gin.H{
-			"page":       query.Page,
-			"page_size":  query.PageSize,
-			"total":      total,
-			"total_page": (total + int64(query.PageSize) - 1) / int64(query.PageSize),
-		},
+		"data":       results,
+		"pagination": pagination,
+		"search":     gin.H{"engine": repo.SearchEngine(), "fuzzy": fuzzy},
 	})
 }
 
-// ExportCSV 导出元件列表为 CSV（支持筛选与自定义列/表头）
-// @route GET /api/v1/components/export?columns=component_number,name&headers=系统编号,名称
-func (h *ComponentHandler) ExportCSV(c *gin.Context) {
+// attachForecasts 为当前页元件填充消耗预测
+func (h *ComponentHandler) attachForecasts(c *gin.Context, components []models.Component) error {
+	ids := make([]uint, len(components))
+	for i := range components {
+		ids[i] = components[i].ID
+	}
+	forecasts, err := h.forecastRepo.ForWorkspace(middleware.CurrentWorkspaceID(c)).ByComponentIDs(ids)
+	if err != nil {
+		return err
+	}
+	for i := range components {
+		components[i].Forecast = forecasts[components[i].ID]
+	}
+	return nil
+}
+
+// Export 导出元件列表为 CSV、XLSX 或 JSON Lines（支持筛选与自定义列/表头），逐行流式写出
+// @route GET /api/v1/components/export?format=xlsx&columns=component_number,name&headers=系统编号,名称
+// XLSX 数量与单价为数值单元格，冻结表头并开启自动筛选；JSON Lines 以列名为字段名，忽略 headers。
+// 筛选参数与列表相同（含 q、saved_search_id）；指定保存搜索且未传 columns 时使用其保存的列。
+func (h *ComponentHandler) Export(c *gin.Context) {
+	format, err := parseExportFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, saved, ok := h.resolveComponentQuery(c)
+	if !ok {
+		return
+	}
+
 	columnsParam := strings.TrimSpace(c.Query("columns"))
+	if columnsParam == "" && saved != nil {
+		columnsParam = strings.Join(saved.Params.Columns, ",")
+	}
 	if columnsParam == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定导出列 columns"})
 		return
@@ -230,69 +421,46 @@ func (h *ComponentHandler) ExportCSV(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "请至少选择一列导出"})
 		return
 	}
-
-	query := parseComponentQueryFromContext(c)
-	if msg := validateComponentSort(query); msg != "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
-		return
-	}
-	query.Page = 1
-	query.PageSize = -1
-
-	components, _, err := h.componentRepo.GetAll(query)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取元件列表失败"})
-		return
-	}
-
-	filename := fmt.Sprintf("components_%s.csv", time.Now().Format("20060102"))
-	c.Header("Content-Type", "text/csv; charset=utf-8")
-	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
-
-	writer := csv.NewWriter(c.Writer)
-	if _, err := c.Writer.Write([]byte{0xEF, 0xBB, 0xBF}); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成 CSV 失败"})
-		return
-	}
-	if err := writer.Write(validHeaders); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成 CSV 失败"})
-		return
-	}
-
-	for i := range components {
-		row := make([]string, len(validColumns))
-		for j, column := range validColumns {
-			row[j] = componentExportValue(&components[i], column)
-		}
-		if err := writer.Write(row); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成 CSV 失败"})
+	var forecasts map[uint]*models.ComponentForecast
+	if slices.ContainsFunc(validColumns, isForecastColumn) {
+		if forecasts, err = h.forecastRepo.ForWorkspace(middleware.CurrentWorkspaceID(c)).All(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取消耗预测失败"})
 			return
 		}
 	}
 
-	writer.Flush()
-	if err := writer.Error(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成 CSV 失败"})
+	exporter, err := newTableExporter(c, format, "components", validColumns, validHeaders)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成导出文件失败"})
 		return
 	}
+	row := make([]any, len(validColumns))
+	err = h.componentRepoFor(c).Each(query, func(component *models.Component) error {
+		component.Forecast = forecasts[component.ID]
+		for i, column := range validColumns {
+			row[i] = componentExportValue(component, column)
+		}
+		return exporter.WriteRow(row)
+	})
+	finishExport(c, exporter, err)
 }
 
 // GetOptions 获取元件录入表单的历史选项（封装、位置、制造商）
 // @route GET /api/v1/components/options
 func (h *ComponentHandler) GetOptions(c *gin.Context) {
-	packages, err := h.componentRepo.GetDistinctPackages()
+	packages, err := h.componentRepoFor(c).GetDistinctPackages()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取封装选项失败"})
 		return
 	}
 
-	locations, err := h.componentRepo.GetDistinctLocations()
+	locations, err := h.componentRepoFor(c).GetDistinctLocations()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取位置选项失败"})
 		return
 	}
 
-	manufacturers, err := h.componentRepo.GetDistinctManufacturers()
+	manufacturers, err := h.componentRepoFor(c).GetDistinctManufacturers()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取制造商选项失败"})
 		return
@@ -307,6 +475,45 @@ func (h *ComponentHandler) GetOptions(c *gin.Context) {
 	})
 }
 
+// GetTags 获取当前工作区使用中的标签及元件数
+// @route GET /api/v1/components/tags
+func (h *ComponentHandler) GetTags(c *gin.Context) {
+	tags, err := h.componentRepoFor(c).GetTags()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取标签失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tags})
+}
+
+// Suggest 按字段返回输入提示：前缀匹配的常用取值及使用次数，无前缀匹配时含容错匹配
+// @route GET /api/v1/components/suggest/:field
+// Query: prefix（可为空，返回最常用的取值）、limit（默认 10，最多 50）
+func (h *ComponentHandler) Suggest(c *gin.Context) {
+	limit := repository.DefaultSuggestionLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit 必须为正整数"})
+			return
+		}
+		limit = parsed
+	}
+	suggestions, err := h.componentRepoFor(c).Suggest(c.Param("field"), c.Query("prefix"), limit)
+	if err != nil {
+		if errors.Is(err, repository.ErrUnknownSuggestionField) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  err.Error() + "，可用字段：" + strings.Join(repository.SuggestionFields(), "、"),
+				"fields": repository.SuggestionFields(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取输入提示失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": suggestions})
+}
+
 // GetByID 获取单个元件详情
 // @route GET /api/v1/components/:id
 func (h *ComponentHandler) GetByID(c *gin.Context) {
@@ -316,7 +523,7 @@ func (h *ComponentHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	component, err := h.componentRepo.GetByID(uint(id))
+	component, err := h.componentRepoFor(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "元件不存在"})
 		return
@@ -338,11 +545,12 @@ func (h *ComponentHandler) Create(c *gin.Context) {
 	}
 
 	component := req.Component
+	component.ABCClass = "" // ABC 分类由定时任务计算
 	if req.TotalPriceCents != nil && *req.TotalPriceCents > 0 && component.StockQuantity > 0 {
 		component.UnitPriceMicro = price.UnitPriceMicro(*req.TotalPriceCents, component.StockQuantity)
 	}
 
-	if err := h.componentRepo.AssignComponentNumberForCreate(&component); err != nil {
+	if err := h.componentRepoFor(c).AssignComponentNumberForCreate(&component); err != nil {
 		if errors.Is(err, repository.ErrComponentNumberDuplicate) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "元件编号已存在"})
 			return
@@ -351,7 +559,11 @@ func (h *ComponentHandler) Create(c *gin.Context) {
 		return
 	}
 
-	if err := h.componentRepo.Create(&component); err != nil {
+	if err := h.componentRepoFor(c).Create(&component); err != nil {
+		if errors.Is(err, repository.ErrWorkspaceMismatch) || errors.Is(err, repository.ErrInvalidTag) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建元件失败"})
 		return
 	}
@@ -363,19 +575,91 @@ func (h *ComponentHandler) Create(c *gin.Context) {
 			UnitPriceMicro:  component.UnitPriceMicro,
 			TotalPriceCents: *req.TotalPriceCents,
 			Reason:          "初始入库",
+			Operator:        middleware.CurrentUsername(c),
 		}
-		if err := h.stockLogRepo.Create(&log); err != nil {
+		if err := h.stockLogRepoFor(c).Create(&log); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建初始库存记录失败"})
 			return
 		}
 	}
 
-	created, err := h.componentRepo.GetByID(component.ID)
+	created, err := h.componentRepoFor(c).GetByID(component.ID)
 	if err == nil {
 		component = *created
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"data": component})
+	// 疑似重复只做提示，不阻止创建
+	duplicates, err := h.componentRepoFor(c).FindLikelyDuplicates(&component)
+	if err != nil {
+		duplicates = []models.Component{}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": component, "likely_duplicates": duplicates})
+}
+
+// GetDuplicates 查找疑似重复元件
+// @route GET /api/v1/components/duplicates?by=supplier_part_number,model_manufacturer,value_package
+func (h *ComponentHandler) GetDuplicates(c *gin.Context) {
+	var by []string
+	for _, item := range strings.Split(c.Query("by"), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			by = append(by, item)
+		}
+	}
+
+	groups, err := h.componentRepoFor(c).FindDuplicates(by)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidDuplicateCriteria) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找重复元件失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": groups})
+}
+
+// Merge 合并重复元件到保留元件
+// @route POST /api/v1/components/merge
+// Body: {"target_id": 1, "source_ids": [2, 3]}
+func (h *ComponentHandler) Merge(c *gin.Context) {
+	var req struct {
+		TargetID  uint   `json:"target_id" binding:"required"`
+		SourceIDs []uint `json:"source_ids" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+
+	seen := make(map[uint]struct{}, len(req.SourceIDs))
+	for _, id := range req.SourceIDs {
+		if _, ok := seen[id]; ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "存在重复的元件 ID"})
+			return
+		}
+		seen[id] = struct{}{}
+	}
+
+	merged, err := h.componentRepoFor(c).MergeComponents(req.TargetID, req.SourceIDs, middleware.CurrentUsername(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrInvalidComponentMerge):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "元件不存在"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "合并元件失败"})
+		}
+		return
+	}
+	// 来源已删除，数据库提交后再整理图片文件；失败不影响合并结果
+	if err := mergeComponentImages(config.Load().ImageDir, req.TargetID, req.SourceIDs); err != nil {
+		log.Printf("整理合并元件的图片失败: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": merged, "message": "合并成功"})
 }
 
 // Update 更新元件
@@ -388,7 +672,7 @@ func (h *ComponentHandler) Update(c *gin.Context) {
 	}
 
 	// 1. 先获取现有元件信息
-	existing, err := h.componentRepo.GetByID(uint(id))
+	existing, err := h.componentRepoFor(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "元件不存在"})
 		return
@@ -412,8 +696,9 @@ func (h *ComponentHandler) Update(c *gin.Context) {
 	component.Category = nil
 	component.Supplier = nil
 	component.UnitPriceMicro = existing.UnitPriceMicro
+	component.ABCClass = existing.ABCClass
 
-	if err := h.componentRepo.ValidateComponentNumberForUpdate(&component, existing); err != nil {
+	if err := h.componentRepoFor(c).ValidateComponentNumberForUpdate(&component, existing); err != nil {
 		if errors.Is(err, repository.ErrComponentNumberDuplicate) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "元件编号已存在"})
 			return
@@ -423,12 +708,16 @@ func (h *ComponentHandler) Update(c *gin.Context) {
 	}
 
 	// 5. 保存更新
-	if err := h.componentRepo.Update(&component); err != nil {
+	if err := h.componentRepoFor(c).Update(&component); err != nil {
+		if errors.Is(err, repository.ErrWorkspaceMismatch) || errors.Is(err, repository.ErrInvalidTag) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新元件失败"})
 		return
 	}
 
-	componentPtr, err := h.componentRepo.GetByID(uint(id))
+	componentPtr, err := h.componentRepoFor(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取更新后元件失败"})
 		return
@@ -460,7 +749,7 @@ func (h *ComponentHandler) BackfillPrice(c *gin.Context) {
 		return
 	}
 
-	existing, err := h.componentRepo.GetByID(uint(id))
+	existing, err := h.componentRepoFor(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "元件不存在"})
 		return
@@ -484,7 +773,7 @@ func (h *ComponentHandler) BackfillPrice(c *gin.Context) {
 	component.Category = nil
 	component.Supplier = nil
 
-	if err := h.componentRepo.Update(&component); err != nil {
+	if err := h.componentRepoFor(c).Update(&component); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新参考单价失败"})
 		return
 	}
@@ -495,13 +784,14 @@ func (h *ComponentHandler) BackfillPrice(c *gin.Context) {
 		UnitPriceMicro:  batchUnitPrice,
 		TotalPriceCents: req.TotalPriceCents,
 		Reason:          fmt.Sprintf("补录价格（采购 %d 件）", req.Quantity),
+		Operator:        middleware.CurrentUsername(c),
 	}
-	if err := h.stockLogRepo.Create(&log); err != nil {
+	if err := h.stockLogRepoFor(c).Create(&log); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建补录价格记录失败"})
 		return
 	}
 
-	componentPtr, err := h.componentRepo.GetByID(uint(id))
+	componentPtr, err := h.componentRepoFor(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取更新后元件失败"})
 		return
@@ -524,7 +814,7 @@ func (h *ComponentHandler) BatchUpdateLocation(c *gin.Context) {
 		return
 	}
 
-	updated, err := h.componentRepo.BatchUpdateLocation(req.IDs, req.Location)
+	updated, err := h.componentRepoFor(c).BatchUpdateLocation(req.IDs, req.Location)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "批量更新位置失败"})
 		return
@@ -536,6 +826,71 @@ func (h *ComponentHandler) BatchUpdateLocation(c *gin.Context) {
 	})
 }
 
+// BatchUpdateCategory 批量移动元件到指定分类（如将呆滞料归入单独分类）
+// @route PATCH /api/v1/components/batch-category
+// Body: {"ids": [1, 2], "category_id": 5}
+func (h *ComponentHandler) BatchUpdateCategory(c *gin.Context) {
+	var req struct {
+		IDs        []uint `json:"ids" binding:"required,min=1"`
+		CategoryID uint   `json:"category_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+
+	updated, err := h.componentRepoFor(c).BatchUpdateCategory(req.IDs, req.CategoryID)
+	if err != nil {
+		if errors.Is(err, repository.ErrWorkspaceMismatch) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "批量更新分类失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "批量更新分类成功",
+		"updated": updated,
+	})
+}
+
+// BatchUpdateTags 批量添加与移除元件标签（如给呆滞料打上 obsolete 标签）
+// @route PATCH /api/v1/components/batch-tags
+// Body: {"ids": [1, 2], "add": ["obsolete"], "remove": ["常用"]}
+func (h *ComponentHandler) BatchUpdateTags(c *gin.Context) {
+	var req struct {
+		IDs    []uint   `json:"ids" binding:"required,min=1"`
+		Add    []string `json:"add"`
+		Remove []string `json:"remove"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+	if len(req.Add) == 0 && len(req.Remove) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定要添加或移除的标签"})
+		return
+	}
+
+	updated, err := h.componentRepoFor(c).BatchUpdateTags(req.IDs, req.Add, req.Remove)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidTag) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "批量更新标签失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "批量更新标签成功",
+		"updated": updated,
+	})
+}
+
 // BatchStockOut 批量出库
 // @route POST /api/v1/components/batch-stock-out
 // Body: {"reason": "项目A", "items": [{"component_id": 1, "quantity": 5}]}
@@ -571,7 +926,7 @@ func (h *ComponentHandler) BatchStockOut(c *gin.Context) {
 		})
 	}
 
-	updated, failures, err := h.componentRepo.BatchApplyStockOut(items, req.Reason)
+	updated, failures, err := h.componentRepoFor(c).BatchApplyStockOut(items, req.Reason, middleware.CurrentUsername(c))
 	if err != nil {
 		if errors.Is(err, repository.ErrBatchStockOutFailed) {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -606,7 +961,7 @@ func (h *ComponentHandler) BatchStockOut(c *gin.Context) {
 // GenerateMissingNumbers 为所有未编号元件自动生成编号
 // @route PATCH /api/v1/components/generate-numbers
 func (h *ComponentHandler) GenerateMissingNumbers(c *gin.Context) {
-	updated, err := h.componentRepo.GenerateMissingComponentNumbers()
+	updated, err := h.componentRepoFor(c).GenerateMissingComponentNumbers()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "自动编号失败"})
 		return
@@ -627,7 +982,7 @@ func (h *ComponentHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.componentRepo.Delete(uint(id)); err != nil {
+	if err := h.componentRepoFor(c).Delete(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除元件失败"})
 		return
 	}
@@ -660,6 +1015,7 @@ func (h *ComponentHandler) UpdateStock(c *gin.Context) {
 		ComponentID: uint(id),
 		Amount:      req.Amount,
 		Reason:      req.Reason,
+		Operator:    middleware.CurrentUsername(c),
 	}
 
 	if req.Amount > 0 && req.TotalPriceCents != nil && *req.TotalPriceCents > 0 {
@@ -667,7 +1023,7 @@ func (h *ComponentHandler) UpdateStock(c *gin.Context) {
 		params.UnitPriceMicro = price.UnitPriceMicro(*req.TotalPriceCents, req.Amount)
 	}
 
-	component, err := h.componentRepo.ApplyStockChange(params)
+	component, err := h.componentRepoFor(c).ApplyStockChange(params)
 	if err != nil {
 		if errors.Is(err, repository.ErrInsufficientStock) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "库存不足"})
@@ -698,7 +1054,7 @@ func (h *ComponentHandler) GetStockLogs(c *gin.Context) {
 		limit, _ = strconv.Atoi(l)
 	}
 
-	logs, err := h.stockLogRepo.GetByComponentID(uint(id), limit)
+	logs, err := h.stockLogRepoFor(c).GetByComponentID(uint(id), limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取记录失败"})
 		return
@@ -707,16 +1063,50 @@ func (h *ComponentHandler) GetStockLogs(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": logs})
 }
 
+// componentImagePath 元件的本地图片按元件 ID 存放为 IMAGE_DIR/{id}.avif
+func componentImagePath(dir string, id uint) string {
+	return filepath.Join(dir, strconv.FormatUint(uint64(id), 10)+".avif")
+}
+
+// mergeComponentImages 合并元件后整理本地图片：目标没有图片时改用第一张来源图片（按 ID 顺序），其余来源图片删除
+func mergeComponentImages(dir string, targetID uint, sourceIDs []uint) error {
+	sorted := slices.Clone(sourceIDs)
+	slices.Sort(sorted)
+	targetPath := componentImagePath(dir, targetID)
+	_, err := os.Stat(targetPath)
+	hasImage := err == nil
+
+	var errs []error
+	for _, id := range sorted {
+		path := componentImagePath(dir, id)
+		if hasImage {
+			err = os.Remove(path)
+		} else if err = os.Rename(path, targetPath); err == nil {
+			hasImage = true
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // UploadImage 上传并压缩图片
 // @route POST /api/v1/components/:id/image
 func (h *ComponentHandler) UploadImage(c *gin.Context) {
-	idStr := c.Param("id")
-	_, err := strconv.ParseUint(idStr, 10, 32)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
 		return
 	}
 
+	// 图片按元件 ID 存储，只允许写入当前工作区内的元件
+	if _, err := h.componentRepoFor(c).GetByID(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "元件不存在"})
+		return
+	}
+	idStr := strconv.FormatUint(id, 10)
+
 	// 获取上传的文件
 	file, err := c.FormFile("image")
 	if err != nil {
@@ -747,7 +1137,7 @@ func (h *ComponentHandler) UploadImage(c *gin.Context) {
 	}
 
 	// 创建目标文件
-	dstPath := filepath.Join(storageDir, idStr+".avif")
+	dstPath := componentImagePath(storageDir, uint(id))
 	dstFile, err := os.Create(dstPath)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建目标文件失败"})
@@ -771,24 +1161,29 @@ func (h *ComponentHandler) UploadImage(c *gin.Context) {
 // GetImage 获取元件图片
 // @route GET /api/v1/components/:id/image
 func (h *ComponentHandler) GetImage(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
 		return
 	}
 
+	// 先确认元件属于当前工作区，避免按 ID 读取其他工作区的图片
+	component, err := h.componentRepoFor(c).GetByID(uint(id))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
 	// 1. 检查本地 AVIF 文件
-	localPath := filepath.Join(config.Load().ImageDir, idStr+".avif")
+	localPath := componentImagePath(config.Load().ImageDir, uint(id))
 	if _, err := os.Stat(localPath); err == nil {
-		c.Header("Cache-Control", "public, max-age=86400") // 缓存一天
+		c.Header("Cache-Control", "private, max-age=86400") // 缓存一天，图片按工作区隔离，不允许共享缓存
 		c.File(localPath)
 		return
 	}
 
 	// 2. 如果本地没有，检查数据库中是否有 External URL
-	component, err := h.componentRepo.GetByID(uint(id))
-	if err == nil && component.ImageURL != "" {
+	if component.ImageURL != "" {
 		// 如果是外部链接，重定向
 		c.Redirect(http.StatusFound, component.ImageURL)
 		return
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMergeComponentImages(t *testing.T) {
+	writeImage := func(t *testing.T, dir string, id uint, content string) {
+		t.Helper()
+		if err := os.WriteFile(componentImagePath(dir, id), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readImage := func(t *testing.T, dir string, id uint) string {
+		t.Helper()
+		data, err := os.ReadFile(componentImagePath(dir, id))
+		if err != nil {
+			t.Fatalf("读取元件 %d 图片失败: %v", id, err)
+		}
+		return string(data)
+	}
+	assertNoImage := func(t *testing.T, dir string, id uint) {
+		t.Helper()
+		if _, err := os.Stat(componentImagePath(dir, id)); !os.IsNotExist(err) {
+			t.Fatalf("元件 %d 图片应已删除: %v", id, err)
+		}
+	}
+
+	t.Run("target without image takes first source", func(t *testing.T) {
+		dir := t.TempDir()
+		writeImage(t, dir, 3, "source-3")
+		writeImage(t, dir, 4, "source-4")
+
+		if err := mergeComponentImages(dir, 1, []uint{4, 2, 3}); err != nil {
+			t.Fatal(err)
+		}
+		if got := readImage(t, dir, 1); got != "source-3" {
+			t.Fatalf("目标图片 = %q, want source-3", got)
+		}
+		assertNoImage(t, dir, 3)
+		assertNoImage(t, dir, 4)
+	})
+
+	t.Run("target image is kept", func(t *testing.T) {
+		dir := t.TempDir()
+		writeImage(t, dir, 1, "target")
+		writeImage(t, dir, 2, "source-2")
+
+		if err := mergeComponentImages(dir, 1, []uint{2, 3}); err != nil {
+			t.Fatal(err)
+		}
+		if got := readImage(t, dir, 1); got != "target" {
+			t.Fatalf("目标图片 = %q, want target", got)
+		}
+		assertNoImage(t, dir, 2)
+	})
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Rehtt/hamster-bin/internal/middleware"
+	"github.com/Rehtt/hamster-bin/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// maxImportFileSize 导入文件大小上限
+const maxImportFileSize = 10 << 20
+
+var errImportEmptyFile = errors.New("文件中没有表头")
+
+// ImportComponents 从 CSV/XLSX 导入元件：按系统编号匹配已有元件则更新，否则创建
+// @route POST /api/v1/components/import  multipart: file=xxx.csv|xlsx, dry_run=true, mapping={"表头":"列名"}
+// 表头默认按导出列名或中文标签自动识别；mapping 可覆盖识别结果，映射为空字符串表示忽略该列。
+// dry_run=true 时只返回逐行校验报告不写入；正式导入任一行失败则整体不写入并返回 400 与报告。
+func (h *ComponentHandler) ImportComponents(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请上传导入文件"})
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "导入文件不能超过 10MB"})
+		return
+	}
+
+	var mapping map[string]string
+	if raw := strings.TrimSpace(c.PostForm("mapping")); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mapping 格式错误"})
+			return
+		}
+		for header, column := range mapping {
+			if column != "" && !slices.Contains(repository.ComponentImportColumns, column) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的导入列: " + column + "（表头 " + header + "）"})
+				return
+			}
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取文件失败"})
+		return
+	}
+	defer file.Close()
+
+	var records [][]string
+	switch strings.ToLower(filepath.Ext(fileHeader.Filename)) {
+	case ".csv":
+		records, err = readImportCSV(file)
+	case ".xlsx":
+		records, err = readImportXLSX(file)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "仅支持 CSV 或 XLSX 文件"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "解析文件失败: " + err.Error()})
+		return
+	}
+	if len(records) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errImportEmptyFile.Error()})
+		return
+	}
+
+	columns := mapImportHeaders(records[0], mapping)
+	if !slices.ContainsFunc(columns, func(column string) bool { return column != "" }) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未识别到任何可导入的列，请指定 mapping"})
+		return
+	}
+
+	rows := make([]repository.ComponentImportRow, 0, len(records)-1)
+	for i, record := range records[1:] {
+		values := make(map[string]string, len(columns))
+		blank := true
+		for j, column := range columns {
+			if column == "" || j >= len(record) {
+				continue
+			}
+			values[column] = record[j]
+			if strings.TrimSpace(record[j]) != "" {
+				blank = false
+			}
+		}
+		if blank {
+			continue
+		}
+		rows = append(rows, repository.ComponentImportRow{Line: i + 2, Values: values})
+	}
+
+	dryRun := c.PostForm("dry_run") == "true"
+	report, err := h.componentRepoFor(c).Import(rows, dryRun, middleware.CurrentUsername(c))
+	if errors.Is(err, repository.ErrImportValidationFailed) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "data": report, "columns": importColumnMapping(records[0], columns)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导入元件失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": report, "columns": importColumnMapping(records[0], columns)})
+}
+
+// mapImportHeaders 将表头映射为导入列名，无法识别或被忽略的列返回空字符串
+func mapImportHeaders(headers []string, mapping map[string]string) []string {
+	columns := make([]string, len(headers))
+	used := make(map[string]bool, len(headers))
+	for i, header := range headers {
+		header = strings.TrimSpace(header)
+		column, ok := mapping[header]
+		if !ok {
+			column = detectImportColumn(header)
+		}
+		// 同一列出现多次时只取第一次
+		if column == "" || used[column] {
+			continue
+		}
+		used[column] = true
+		columns[i] = column
+	}
+	return columns
+}
+
+// detectImportColumn 按导出列名或中文标签（不区分大小写）识别表头
+func detectImportColumn(header string) string {
+	for _, column := range repository.ComponentImportColumns {
+		if strings.EqualFold(header, column) || strings.EqualFold(header, componentExportColumnLabels[column]) {
+			return column
+		}
+	}
+	return ""
+}
+
+// importColumnMapping 返回表头到导入列名的实际映射，便于前端展示与调整
+func importColumnMapping(headers []string, columns []string) []gin.H {
+	result := make([]gin.H, len(headers))
+	for i, header := range headers {
+		result[i] = gin.H{"header": strings.TrimSpace(header), "column": columns[i]}
+	}
+	return result
+}
+
+func readImportCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	return reader.ReadAll()
+}
+
+// readImportXLSX 读取第一个工作表
+func readImportXLSX(r io.Reader) ([][]string, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errImportEmptyFile
+	}
+	return f.GetRows(sheets[0])
+}
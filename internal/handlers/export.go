@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// 导出格式
+const (
+	exportFormatCSV   = "csv"
+	exportFormatXLSX  = "xlsx"
+	exportFormatJSONL = "jsonl"
+)
+
+const exportSheetName = "Sheet1"
+
+var errUnsupportedExportFormat = errors.New("format 仅支持 csv、xlsx 或 jsonl")
+
+// tableExporter 逐行写出导出数据；单元格值为 nil、string、整数或 float64。
+// Close 写出剩余内容，Discard 在出错时释放资源且不再写出。
+type tableExporter interface {
+	WriteRow(values []any) error
+	Close() error
+	Discard()
+}
+
+// parseExportFormat 解析 query format，默认 csv
+func parseExportFormat(c *gin.Context) (string, error) {
+	format := strings.ToLower(strings.TrimSpace(c.DefaultQuery("format", exportFormatCSV)))
+	switch format {
+	case exportFormatCSV, exportFormatXLSX, exportFormatJSONL:
+		return format, nil
+	default:
+		return "", errUnsupportedExportFormat
+	}
+}
+
+// newTableExporter 设置下载响应头并返回对应格式的导出器。
+// keys 为 JSON Lines 的字段名，headers 为 CSV/XLSX 的表头；xlsx 在 Close 时才写出响应体。
+func newTableExporter(c *gin.Context, format, baseName string, keys, headers []string) (tableExporter, error) {
+	filename := fmt.Sprintf("%s_%s.%s", baseName, time.Now().Format("20060102"), format)
+	switch format {
+	case exportFormatXLSX:
+		c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	case exportFormatJSONL:
+		c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+	default:
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	switch format {
+	case exportFormatXLSX:
+		return newXLSXExporter(c.Writer, headers)
+	case exportFormatJSONL:
+		return &jsonlExporter{w: bufio.NewWriter(c.Writer), keys: keys}, nil
+	default:
+		return newCSVExporter(c.Writer, headers)
+	}
+}
+
+type csvExporter struct {
+	writer *csv.Writer
+	row    []string
+}
+
+func newCSVExporter(w io.Writer, headers []string) (*csvExporter, error) {
+	if _, err := w.Write([]byte{0xEF, 0xBB, 0xBF}); err != nil {
+		return nil, err
+	}
+	e := &csvExporter{writer: csv.NewWriter(w), row: make([]string, len(headers))}
+	return e, e.writer.Write(headers)
+}
+
+func (e *csvExporter) WriteRow(values []any) error {
+	for i, value := range values {
+		e.row[i] = formatExportCell(value)
+	}
+	return e.writer.Write(e.row)
+}
+
+func (e *csvExporter) Close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvExporter) Discard() {}
+
+// formatExportCell 将单元格值格式化为 CSV 文本
+func formatExportCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// xlsxExporter 使用 excelize 流式写入，数据量大时由 excelize 落盘临时文件；表头冻结并开启筛选
+type xlsxExporter struct {
+	w       io.Writer
+	file    *excelize.File
+	stream  *excelize.StreamWriter
+	columns int
+	rows    int
+}
+
+func newXLSXExporter(w io.Writer, headers []string) (*xlsxExporter, error) {
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter(exportSheetName)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := stream.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	headerStyle, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	cells := make([]any, len(headers))
+	for i, header := range headers {
+		cells[i] = excelize.Cell{StyleID: headerStyle, Value: header}
+	}
+	if err := stream.SetRow("A1", cells); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &xlsxExporter{w: w, file: file, stream: stream, columns: len(headers), rows: 1}, nil
+}
+
+func (e *xlsxExporter) WriteRow(values []any) error {
+	e.rows++
+	cell, err := excelize.CoordinatesToCellName(1, e.rows)
+	if err != nil {
+		return err
+	}
+	return e.stream.SetRow(cell, values)
+}
+
+func (e *xlsxExporter) Close() error {
+	defer e.file.Close()
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	lastCell, err := excelize.CoordinatesToCellName(e.columns, e.rows)
+	if err != nil {
+		return err
+	}
+	if err := e.file.AutoFilter(exportSheetName, "A1:"+lastCell, nil); err != nil {
+		return err
+	}
+	return e.file.Write(e.w)
+}
+
+func (e *xlsxExporter) Discard() {
+	e.file.Close()
+}
+
+// jsonlExporter 每行输出一个 JSON 对象，字段按导出列顺序排列
+type jsonlExporter struct {
+	w    *bufio.Writer
+	keys []string
+	buf  bytes.Buffer
+}
+
+func (e *jsonlExporter) WriteRow(values []any) error {
+	e.buf.Reset()
+	e.buf.WriteByte('{')
+	for i, key := range e.keys {
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		e.buf.Write(k)
+		e.buf.WriteByte(':')
+		v, err := json.Marshal(values[i])
+		if err != nil {
+			return err
+		}
+		e.buf.Write(v)
+	}
+	e.buf.WriteString("}\n")
+	_, err := e.w.Write(e.buf.Bytes())
+	return err
+}
+
+func (e *jsonlExporter) Close() error {
+	return e.w.Flush()
+}
+
+func (e *jsonlExporter) Discard() {}
+
+// finishExport 结束导出：err 为遍历过程中的错误。响应体已开始写出时无法再返回 JSON 错误，只能中断响应
+func finishExport(c *gin.Context, exporter tableExporter, err error) {
+	if err != nil {
+		exporter.Discard()
+	} else {
+		err = exporter.Close()
+	}
+	if err == nil {
+		return
+	}
+	if c.Writer.Written() {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "生成导出文件失败"})
+}
+
+// parseExportTimeRange 解析 query from/to（YYYY-MM-DD 或 RFC3339）；日期形式的 to 包含当天
+func parseExportTimeRange(c *gin.Context) (time.Time, time.Time, error) {
+	return parseTimeRangeIn(c, time.Local)
+}
+
+// parseTimeRangeIn 同 parseExportTimeRange，日期形式按 loc 时区的零点解析
+func parseTimeRangeIn(c *gin.Context, loc *time.Location) (time.Time, time.Time, error) {
+	from, _, err := parseExportTime(c.Query("from"), loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("from 格式错误，应为 YYYY-MM-DD 或 RFC3339")
+	}
+	to, dateOnly, err := parseExportTime(c.Query("to"), loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("to 格式错误，应为 YYYY-MM-DD 或 RFC3339")
+	}
+	if dateOnly {
+		to = to.AddDate(0, 0, 1)
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return time.Time{}, time.Time{}, errors.New("from 需早于 to")
+	}
+	return from, to, nil
+}
+
+func parseExportTime(raw string, loc *time.Location) (time.Time, bool, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, false, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, raw, loc); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	return t, false, err
+}
+
+// exportTime 格式化导出时间，nil 或零值导出为空
+func exportTime(t *time.Time) any {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return t.Format(time.DateTime)
+}
+
+// exportID 导出可选关联 ID，nil 导出为空
+func exportID(id *uint) any {
+	if id == nil {
+		return nil
+	}
+	return *id
+}
+
+// exportYuan 将微元/分金额转为元，0 导出为空
+func exportYuan(amount int64, unit float64) any {
+	if amount == 0 {
+		return nil
+	}
+	return float64(amount) / unit
+}
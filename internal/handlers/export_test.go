@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+func writeTestExport(t *testing.T, format string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	exporter, err := newTableExporter(c, format, "test", []string{"name", "qty", "price"}, []string{"名称", "数量", "单价"})
+	if err != nil {
+		t.Fatalf("newTableExporter: %v", err)
+	}
+	for _, row := range [][]any{{"电阻", 10, 0.05}, {"电容, 0603", 0, nil}} {
+		if err := exporter.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow: %v", err)
+		}
+	}
+	finishExport(c, exporter, nil)
+	return w
+}
+
+func TestCSVExport(t *testing.T) {
+	w := writeTestExport(t, exportFormatCSV)
+	want := "\ufeff名称,数量,单价\n电阻,10,0.05\n\"电容, 0603\",0,\n"
+	if got := w.Body.String(); got != want {
+		t.Fatalf("csv = %q, want %q", got, want)
+	}
+}
+
+func TestJSONLExportKeepsColumnOrder(t *testing.T) {
+	w := writeTestExport(t, exportFormatJSONL)
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 2 || lines[0] != `{"name":"电阻","qty":10,"price":0.05}` || lines[1] != `{"name":"电容, 0603","qty":0,"price":null}` {
+		t.Fatalf("jsonl = %q", lines)
+	}
+}
+
+func TestXLSXExportTypedCellsAndFrozenHeader(t *testing.T) {
+	w := writeTestExport(t, exportFormatXLSX)
+	if ct := w.Header().Get("Content-Type"); !strings.Contains(ct, "spreadsheetml") {
+		t.Fatalf("content type = %q", ct)
+	}
+
+	f, err := excelize.OpenReader(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("open xlsx: %v", err)
+	}
+	defer f.Close()
+
+	rows, err := f.GetRows(exportSheetName)
+	if err != nil {
+		t.Fatalf("GetRows: %v", err)
+	}
+	if len(rows) != 3 || rows[0][0] != "名称" || rows[1][1] != "10" {
+		t.Fatalf("rows = %v", rows)
+	}
+	cellType, err := f.GetCellType(exportSheetName, "C2")
+	if err != nil || cellType == excelize.CellTypeSharedString || cellType == excelize.CellTypeInlineString {
+		t.Fatalf("price cell type = %v, %v; want numeric", cellType, err)
+	}
+	panes, err := f.GetPanes(exportSheetName)
+	if err != nil || !panes.Freeze || panes.YSplit != 1 {
+		t.Fatalf("panes = %+v, %v", panes, err)
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/forecast"
+	"github.com/Rehtt/hamster-bin/internal/middleware"
+	"github.com/Rehtt/hamster-bin/internal/repository"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ForecastHandler struct {
+	repo *repository.ForecastRepository
+	job  *forecast.Job
+}
+
+func NewForecastHandler(db *gorm.DB, job *forecast.Job) *ForecastHandler {
+	return &ForecastHandler{repo: repository.NewForecastRepository(db), job: job}
+}
+
+// repoFor 返回限定在当前请求工作区内的仓储
+func (h *ForecastHandler) repoFor(c *gin.Context) *repository.ForecastRepository {
+	return h.repo.ForWorkspace(middleware.CurrentWorkspaceID(c))
+}
+
+// GetAll 获取消耗预测列表，默认按可用天数升序（最先断货的在前），无消耗的元件排在最后
+// @route GET /api/v1/forecasts?within_days=30&reorder_only=true&sort_by=days_of_cover&sort_order=asc&page=1&page_size=20
+// within_days 只返回预计在该天数内断货的元件；reorder_only=true 只返回建议补货量大于 0 的元件。
+// sort_by 可选 days_of_cover、stockout_date、avg_daily_consumption、reorder_quantity、stock_quantity、name。
+// params 为计算使用的统计窗口、到货天数与目标覆盖天数。
+func (h *ForecastHandler) GetAll(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+
+	query := repository.ForecastQuery{
+		ReorderOnly: c.Query("reorder_only") == "true",
+		SortBy:      strings.TrimSpace(c.Query("sort_by")),
+		SortOrder:   strings.ToLower(strings.TrimSpace(c.Query("sort_order"))),
+		Page:        page,
+		PageSize:    pageSize,
+	}
+	if query.SortBy != "" {
+		if _, ok := repository.ForecastSortColumns[query.SortBy]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 sort_by 参数"})
+			return
+		}
+	}
+	if raw := strings.TrimSpace(c.Query("within_days")); raw != "" {
+		days, err := strconv.ParseFloat(raw, 64)
+		if err != nil || days < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 within_days 参数"})
+			return
+		}
+		query.WithinDays = &days
+	}
+
+	items, total, err := h.repoFor(c).List(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取消耗预测失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   items,
+		"params": forecastParamsJSON(h.job.Params()),
+		"pagination": gin.H{
+			"page":       page,
+			"page_size":  pageSize,
+			"total":      total,
+			"total_page": (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// Recompute 立即重新计算当前工作区的消耗预测（编辑者及以上）
+// @route POST /api/v1/forecasts/recompute
+func (h *ForecastHandler) Recompute(c *gin.Context) {
+	count, err := h.job.RunWorkspace(middleware.CurrentWorkspaceID(c), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "计算消耗预测失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"count": count, "params": forecastParamsJSON(h.job.Params())}})
+}
+
+func forecastParamsJSON(params repository.ForecastParams) gin.H {
+	if params.WindowDays <= 0 {
+		params.WindowDays = repository.DefaultForecastWindowDays
+	}
+	return gin.H{
+		"window_days":    params.WindowDays,
+		"lead_time_days": params.LeadTimeDays,
+		"target_days":    params.TargetDays,
+	}
+}
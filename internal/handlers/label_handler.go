@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Rehtt/hamster-bin/internal/label"
+	"github.com/Rehtt/hamster-bin/internal/middleware"
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/Rehtt/hamster-bin/internal/parser"
+	"github.com/Rehtt/hamster-bin/internal/repository"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxLabelsPerRequest 单次打印的最大标签数（含份数）
+const maxLabelsPerRequest = 2000
+
+var errTooManyLabels = errors.New("标签数量超出上限")
+
+type LabelHandler struct {
+	components   *ComponentHandler
+	preStockRepo *repository.PreStockRepository
+	service      *label.Service
+}
+
+func NewLabelHandler(db *gorm.DB, service *label.Service) *LabelHandler {
+	return &LabelHandler{
+		components:   NewComponentHandler(db),
+		preStockRepo: repository.NewPreStockRepository(db),
+		service:      service,
+	}
+}
+
+// GetTemplates 获取标签模板（内置模板及 LABEL_TEMPLATES_FILE 中的自定义模板）
+// @route GET /api/v1/labels/templates
+func (h *LabelHandler) GetTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": h.service.Templates()})
+}
+
+// labelRequest 解析公共的打印参数：template、format（pdf/zpl/tspl，默认 pdf）、copies、dpi、skip
+func (h *LabelHandler) labelRequest(c *gin.Context) (label.Template, string, label.RenderOptions, bool) {
+	tpl, err := h.service.Template(c.Query("template"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return tpl, "", label.RenderOptions{}, false
+	}
+	format := strings.ToLower(c.DefaultQuery("format", label.FormatPDF))
+	if err := label.CheckFormat(tpl, format); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return tpl, "", label.RenderOptions{}, false
+	}
+
+	var opts label.RenderOptions
+	for _, param := range []struct {
+		name  string
+		value *int
+		max   int
+	}{
+		{"copies", &opts.Copies, label.MaxCopies},
+		{"dpi", &opts.DPI, 1200},
+		{"skip", &opts.Skip, 1000},
+	} {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n > param.max {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s 应为 0 到 %d 之间的整数", param.name, param.max)})
+			return tpl, "", label.RenderOptions{}, false
+		}
+		*param.value = n
+	}
+	return tpl, format, opts, true
+}
+
+// writeLabels 渲染标签并以附件返回，文件名为 labels-<模板>.<格式>
+func (h *LabelHandler) writeLabels(c *gin.Context, tpl label.Template, format string, labels []label.Label, opts label.RenderOptions) {
+	if len(labels) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要打印的标签"})
+		return
+	}
+	if len(labels)*max(opts.Copies, 1) > maxLabelsPerRequest {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("单次最多打印 %d 个标签，请缩小范围", maxLabelsPerRequest)})
+		return
+	}
+	var buf bytes.Buffer
+	if err := h.service.Render(&buf, format, tpl, labels, opts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成标签失败"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="labels-%s.%s"`, tpl.Name, format))
+	c.Data(http.StatusOK, label.ContentType(format), buf.Bytes())
+}
+
+// parseIDList 解析逗号分隔的 ID 列表
+func parseIDList(raw string) ([]uint, error) {
+	var ids []uint
+	for part := range strings.SplitSeq(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("无效的元件 ID: %s", part)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// PrintComponents 批量生成元件标签
+// @route GET /api/v1/labels/components?template=40x30&format=pdf&ids=1,2,3&copies=1
+// ids 为空时按元件列表的筛选条件（含 saved_search_id）打印全部匹配的元件，按列表排序输出。
+// 标签二维码为元件系统编号，存在没有编号的元件时返回 400 及这些元件的 ID。
+// format 为 zpl/tspl 时返回打印机指令（dpi 默认 203），整页模板只能输出 pdf；skip 为整页模板第一页跳过的位置数。
+func (h *LabelHandler) PrintComponents(c *gin.Context) {
+	tpl, format, opts, ok := h.labelRequest(c)
+	if !ok {
+		return
+	}
+	ids, err := parseIDList(c.Query("ids"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query := repository.ComponentQuery{IDs: ids}
+	if len(ids) == 0 {
+		if query, _, ok = h.components.resolveComponentQuery(c); !ok {
+			return
+		}
+	}
+
+	var labels []label.Label
+	var missing []uint
+	err = h.components.componentRepoFor(c).Each(query, func(component *models.Component) error {
+		if label.ComponentCode(component) == "" {
+			missing = append(missing, component.ID)
+			return nil
+		}
+		if len(labels) >= maxLabelsPerRequest {
+			return errTooManyLabels
+		}
+		labels = append(labels, label.ComponentLabel(component, tpl))
+		return nil
+	})
+	if errors.Is(err, errTooManyLabels) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("单次最多打印 %d 个标签，请缩小范围", maxLabelsPerRequest)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取元件失败"})
+		return
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         fmt.Sprintf("有 %d 个元件没有系统编号，请先生成编号", len(missing)),
+			"component_ids": missing,
+		})
+		return
+	}
+	h.writeLabels(c, tpl, format, labels, opts)
+}
+
+// PrintLocations 批量生成存放位置标签
+// @route GET /api/v1/labels/locations?template=50x25&locations=A1-01,A1-02
+// locations 为逗号分隔的位置（可以是还没有元件的新位置）；为空时按 prefix 打印以其开头的全部已用位置，两者都为空时打印全部位置。
+// 标签二维码为位置本身，并注明位置中的元件数；其余参数同 PrintComponents。
+func (h *LabelHandler) PrintLocations(c *gin.Context) {
+	tpl, format, opts, ok := h.labelRequest(c)
+	if !ok {
+		return
+	}
+	var locations []string
+	for part := range strings.SplitSeq(c.Query("locations"), ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			locations = append(locations, part)
+		}
+	}
+	prefix := ""
+	if len(locations) == 0 {
+		prefix = strings.TrimSpace(c.Query("prefix"))
+	}
+	counts, err := h.components.componentRepoFor(c).CountByLocation(prefix, locations)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取存放位置失败"})
+		return
+	}
+
+	var labels []label.Label
+	if len(locations) > 0 {
+		byLocation := make(map[string]int, len(counts))
+		for _, count := range counts {
+			byLocation[count.Location] = count.Count
+		}
+		for _, location := range locations {
+			labels = append(labels, label.LocationLabel(location, byLocation[location]))
+		}
+	} else {
+		for _, count := range counts {
+			labels = append(labels, label.LocationLabel(count.Location, count.Count))
+		}
+	}
+	h.writeLabels(c, tpl, format, labels, opts)
+}
+
+// writeCodeImage 按 query 生成条码图片：format 为 png（默认）或 svg，type 为 qr（默认）、datamatrix 或 code128，
+// size 为宽度像素（默认 256，最大 2048）；download=true 时以附件 <name>.<格式> 返回，否则可直接用于 <img>
+func writeCodeImage(c *gin.Context, content, name string) {
+	format := strings.ToLower(c.DefaultQuery("format", label.ImagePNG))
+	symbology := strings.ToLower(c.DefaultQuery("type", label.SymbologyQR))
+	size := label.DefaultImageSize
+	if raw := c.Query("size"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > label.MaxImageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("size 应为 1 到 %d 之间的整数", label.MaxImageSize)})
+			return
+		}
+		size = n
+	}
+	var buf bytes.Buffer
+	if err := label.WriteCodeImage(&buf, format, symbology, content, size); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	}
+	c.Data(http.StatusOK, label.ImageContentType(format), buf.Bytes())
+}
+
+// ComponentCodeImage 生成元件的二维码/条码图片，内容为以系统编号生成的自有二维码（parser.ComponentPayload）
+// @route GET /api/v1/components/:id/qrcode?format=png&type=qr&size=256
+// 元件没有系统编号时返回 400；其余参数见 writeCodeImage。
+func (h *LabelHandler) ComponentCodeImage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
+		return
+	}
+	component, err := h.components.componentRepoFor(c).GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "元件不存在"})
+		return
+	}
+	code := label.ComponentCode(component)
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "元件没有系统编号，请先生成编号"})
+		return
+	}
+	writeCodeImage(c, code, *component.ComponentNumber)
+}
+
+// PreStockCodeImage 生成预入库记录的二维码/条码图片（parser.PreStockPayload）
+// @route GET /api/v1/pre-stocks/:id/qrcode?format=svg
+func (h *LabelHandler) PreStockCodeImage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
+		return
+	}
+	preStock, err := h.preStockRepo.ForWorkspace(middleware.CurrentWorkspaceID(c)).GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "预入库记录不存在"})
+		return
+	}
+	writeCodeImage(c, parser.PreStockPayload(preStock.ID), fmt.Sprintf("pre-stock-%d", preStock.ID))
+}
+
+// LocationCodeImage 生成存放位置的二维码/条码图片（parser.LocationPayload），位置可以还没有元件
+// @route GET /api/v1/locations/qrcode?location=A1-03
+func (h *LabelHandler) LocationCodeImage(c *gin.Context) {
+	location := strings.TrimSpace(c.Query("location"))
+	if location == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请提供存放位置"})
+		return
+	}
+	writeCodeImage(c, label.LocationCode(location), "location")
+}
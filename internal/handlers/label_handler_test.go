@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWriteCodeImage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		query       string
+		wantStatus  int
+		contentType string
+		disposition string
+	}{
+		{query: "", wantStatus: http.StatusOK, contentType: "image/png"},
+		{query: "format=svg&type=datamatrix&download=true", wantStatus: http.StatusOK, contentType: "image/svg+xml", disposition: `attachment; filename="HB-000001.svg"`},
+		{query: "type=code128&size=512", wantStatus: http.StatusOK, contentType: "image/png"},
+		{query: "format=gif", wantStatus: http.StatusBadRequest},
+		{query: "type=ean13", wantStatus: http.StatusBadRequest},
+		{query: "size=4096", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			writeCodeImage(c, "HB1:C:HB-000001", "HB-000001")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+				t.Errorf("content type = %q, want %q", ct, tt.contentType)
+			}
+			if got := w.Header().Get("Content-Disposition"); got != tt.disposition {
+				t.Errorf("disposition = %q, want %q", got, tt.disposition)
+			}
+		})
+	}
+}
@@ -3,32 +3,43 @@ package handlers
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/Rehtt/hamster-bin/internal/llm"
+	"github.com/Rehtt/hamster-bin/internal/middleware"
 	"github.com/Rehtt/hamster-bin/internal/parser"
+	"github.com/Rehtt/hamster-bin/internal/repository"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type ParserHandler struct {
-	manager *parser.ParserManager
+	manager       *parser.ParserManager
+	supplierRepo  *repository.SupplierRepository
+	componentRepo *repository.ComponentRepository
+	preStockRepo  *repository.PreStockRepository
 }
 
-func NewParserHandler(manager *parser.ParserManager) *ParserHandler {
+func NewParserHandler(manager *parser.ParserManager, db *gorm.DB) *ParserHandler {
 	return &ParserHandler{
-		manager: manager,
+		manager:       manager,
+		supplierRepo:  repository.NewSupplierRepository(db),
+		componentRepo: repository.NewComponentRepository(db),
+		preStockRepo:  repository.NewPreStockRepository(db),
 	}
 }
 
-// ParseRequest 解析请求
+// ParseRequest 解析请求，POST 时为 JSON 请求体，GET 时为同名 query
 type ParseRequest struct {
-	Code   string `json:"code" binding:"required"` // 平台编码
-	UseLLM bool   `json:"use_llm"`                 // 是否使用 LLM 辅助解析
+	Code   string `json:"code" form:"code" binding:"required"` // 平台编码
+	UseLLM bool   `json:"use_llm" form:"use_llm"`              // 是否使用 LLM 辅助解析
 }
 
-// QRCodeParseRequest 二维码解析请求
+// QRCodeParseRequest 二维码解析请求，POST 时为 JSON 请求体，GET 时为同名 query
 type QRCodeParseRequest struct {
-	QRCodeData string `json:"qrcode_data" binding:"required"` // 二维码原始数据
-	UseLLM     bool   `json:"use_llm"`                        // 是否使用 LLM 辅助解析
+	QRCodeData string `json:"qrcode_data" form:"qrcode_data" binding:"required"` // 二维码原始数据
+	UseLLM     bool   `json:"use_llm" form:"use_llm"`                            // 是否使用 LLM 辅助解析
 }
 
 // parseErrorResponse 将解析错误映射为 HTTP 状态码和错误消息
@@ -49,17 +60,46 @@ func parseErrorResponse(err error) (int, string) {
 	}
 }
 
-// ParseComponent 解析平台编码，返回元件信息
+// parse 解析平台编码；编码是商品链接且匹配当前工作区某个供应商的链接模板时，
+// 先提取料号再交给解析器，没有解析器能处理该料号时仅返回供应商与料号
+func (h *ParserHandler) parse(c *gin.Context, code string, options parser.ParseOptions) (*parser.ComponentInfo, error) {
+	code = strings.TrimSpace(code)
+	if !strings.HasPrefix(code, "http://") && !strings.HasPrefix(code, "https://") {
+		return h.manager.ParseWithOptions(code, options)
+	}
+
+	supplier, sku, err := h.supplierRepo.ForWorkspace(middleware.CurrentWorkspaceID(c)).FindByProductURL(code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return h.manager.ParseWithOptions(code, options)
+		}
+		return nil, err
+	}
+
+	info, err := h.manager.ParseWithOptions(sku, options)
+	if errors.Is(err, parser.ErrNoParserMatched) || errors.Is(err, parser.ErrNoParsersAvailable) {
+		info, err = &parser.ComponentInfo{PlatformCode: sku}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	info.PlatformName = supplier.Name
+	info.PlatformURL = code
+	return info, nil
+}
+
+// ParseComponent 解析平台编码或供应商商品链接，返回元件信息。只读，GET 供只读成员使用
+// @route GET /api/v1/components/parse
 // @route POST /api/v1/components/parse
 func (h *ParserHandler) ParseComponent(c *gin.Context) {
 	var req ParseRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBind(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "请提供平台编码"})
 		return
 	}
 
 	// 调用解析器
-	info, err := h.manager.ParseWithOptions(req.Code, parser.ParseOptions{UseLLM: req.UseLLM})
+	info, err := h.parse(c, req.Code, parser.ParseOptions{UseLLM: req.UseLLM})
 	if err != nil {
 		status, message := parseErrorResponse(err)
 		c.JSON(status, gin.H{"error": message})
@@ -82,11 +122,13 @@ func (h *ParserHandler) GetSupportedPlatforms(c *gin.Context) {
 	})
 }
 
-// ParseQRCode 解析二维码，返回元件信息和数量
+// ParseQRCode 解析二维码，返回元件信息和数量。只读，GET 供只读成员使用
+// @route GET /api/v1/components/parse-qrcode
 // @route POST /api/v1/components/parse-qrcode
+// Hamster Bin 自有二维码不调用解析器，直接返回当前工作区中对应的对象，见 resolvePayload。
 func (h *ParserHandler) ParseQRCode(c *gin.Context) {
 	var req QRCodeParseRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBind(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "请提供二维码数据"})
 		return
 	}
@@ -97,9 +139,13 @@ func (h *ParserHandler) ParseQRCode(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "解析二维码失败: " + err.Error()})
 		return
 	}
+	if qrData.Platform == parser.HamsterBinPlatform {
+		h.resolvePayload(c, qrData)
+		return
+	}
 
 	// 使用提取的编码调用解析器获取元件信息
-	info, err := h.manager.ParseWithOptions(qrData.Code, parser.ParseOptions{UseLLM: req.UseLLM})
+	info, err := h.parse(c, qrData.Code, parser.ParseOptions{UseLLM: req.UseLLM})
 	if err != nil {
 		status, message := parseErrorResponse(err)
 		c.JSON(status, gin.H{
@@ -119,3 +165,47 @@ func (h *ParserHandler) ParseQRCode(c *gin.Context) {
 		"message": "解析成功",
 	})
 }
+
+// resolvePayload 按自有二维码的类型查找当前工作区中的对象，响应 data 含 kind 与 qrcode_info：
+// component 附元件，location 附位置及其中的元件数（位置上还没有元件时为 0），pre_stock 附预入库记录；元件或预入库不存在时返回 404
+func (h *ParserHandler) resolvePayload(c *gin.Context, qrData *parser.QRCodeData) {
+	workspaceID := middleware.CurrentWorkspaceID(c)
+	data := gin.H{"kind": qrData.Kind, "qrcode_info": qrData}
+	switch qrData.Kind {
+	case parser.PayloadComponent:
+		component, err := h.componentRepo.ForWorkspace(workspaceID).GetByNumber(qrData.Code)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "元件 " + qrData.Code + " 不存在", "qrcode_data": qrData})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取元件失败"})
+			return
+		}
+		data["component"] = component
+	case parser.PayloadLocation:
+		counts, err := h.componentRepo.ForWorkspace(workspaceID).CountByLocation("", []string{qrData.Code})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取存放位置失败"})
+			return
+		}
+		data["location"] = qrData.Code
+		data["component_count"] = 0
+		if len(counts) > 0 {
+			data["component_count"] = counts[0].Count
+		}
+	case parser.PayloadPreStock:
+		id, _ := strconv.ParseUint(qrData.Code, 10, 32)
+		preStock, err := h.preStockRepo.ForWorkspace(workspaceID).GetByID(uint(id))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "预入库记录不存在", "qrcode_data": qrData})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取预入库记录失败"})
+			return
+		}
+		data["pre_stock"] = preStock
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data, "message": "解析成功"})
+}
@@ -1,13 +1,21 @@
 package handlers
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/Rehtt/hamster-bin/internal/llm"
+	"github.com/Rehtt/hamster-bin/internal/models"
 	"github.com/Rehtt/hamster-bin/internal/parser"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
 )
 
 func TestParseErrorResponse(t *testing.T) {
@@ -72,3 +80,56 @@ func TestParseErrorResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestParseQRCodeAcceptsQueryAndBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Component{}, &models.ComponentTag{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := db.Create(&models.Component{CategoryID: 1, Name: "电阻", Location: "A1-01"}).Error; err != nil {
+		t.Fatalf("create component: %v", err)
+	}
+	h := NewParserHandler(parser.NewParserManager(), db)
+	r := gin.New()
+	r.GET("/parse-qrcode", h.ParseQRCode)
+	r.POST("/parse-qrcode", h.ParseQRCode)
+
+	payload := parser.LocationPayload("A1-01")
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"query", httptest.NewRequest(http.MethodGet, "/parse-qrcode?qrcode_data="+url.QueryEscape(payload), nil), http.StatusOK},
+		{"missing query", httptest.NewRequest(http.MethodGet, "/parse-qrcode", nil), http.StatusBadRequest},
+		{"json body", httptest.NewRequest(http.MethodPost, "/parse-qrcode", strings.NewReader(`{"qrcode_data":"`+payload+`"}`)), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.req.Method == http.MethodPost {
+				tt.req.Header.Set("Content-Type", "application/json")
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, tt.req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Data struct {
+					Kind           string `json:"kind"`
+					ComponentCount int64  `json:"component_count"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Data.Kind != parser.PayloadLocation || resp.Data.ComponentCount != 1 {
+				t.Fatalf("response = %s, err %v", w.Body.String(), err)
+			}
+		})
+	}
+}
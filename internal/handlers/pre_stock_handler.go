@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/Rehtt/hamster-bin/internal/middleware"
 	"github.com/Rehtt/hamster-bin/internal/models"
 	"github.com/Rehtt/hamster-bin/internal/repository"
 	"github.com/gin-gonic/gin"
@@ -21,6 +22,11 @@ func NewPreStockHandler(db *gorm.DB) *PreStockHandler {
 	}
 }
 
+// repoFor 返回限定在当前请求工作区内的仓储
+func (h *PreStockHandler) repoFor(c *gin.Context) *repository.PreStockRepository {
+	return h.repo.ForWorkspace(middleware.CurrentWorkspaceID(c))
+}
+
 // GetAll 获取预入库记录
 // @route GET /api/v1/pre-stocks
 func (h *PreStockHandler) GetAll(c *gin.Context) {
@@ -38,7 +44,7 @@ func (h *PreStockHandler) GetAll(c *gin.Context) {
 		Page:     page,
 		PageSize: pageSize,
 	}
-	items, total, err := h.repo.GetAll(query)
+	items, total, err := h.repoFor(c).GetAll(query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取预入库记录失败"})
 		return
@@ -55,6 +61,78 @@ func (h *PreStockHandler) GetAll(c *gin.Context) {
 	})
 }
 
+var preStockExportKeys = []string{
+	"id", "status", "component_number", "name", "model", "manufacturer", "value", "package", "category",
+	"supplier", "supplier_part_number", "expected_quantity", "total_price", "location", "component_id",
+	"created_at", "confirmed_at",
+}
+
+var preStockExportHeaders = []string{
+	"记录ID", "状态", "系统编号", "名称", "厂家型号", "制造商", "参数", "封装", "分类",
+	"供应商", "供应商料号", "预计数量", "采购总价", "存放位置", "入库元件ID",
+	"创建时间", "确认时间",
+}
+
+// Export 导出预入库记录为 CSV、XLSX 或 JSON Lines，按创建时间先后逐行流式写出
+// @route GET /api/v1/pre-stocks/export?format=xlsx&status=all&from=2024-01-01&to=2024-12-31
+// status 默认 all；from/to 按创建时间筛选，为 YYYY-MM-DD（含 to 当天）或 RFC3339；采购总价单位为元。
+func (h *PreStockHandler) Export(c *gin.Context) {
+	format, err := parseExportFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	status := c.DefaultQuery("status", "all")
+	if status != "all" && status != repository.PreStockStatusPending && status != repository.PreStockStatusConfirmed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status 仅支持 pending、confirmed 或 all"})
+		return
+	}
+	from, to, err := parseExportTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	exporter, err := newTableExporter(c, format, "pre_stocks", preStockExportKeys, preStockExportHeaders)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成导出文件失败"})
+		return
+	}
+	query := repository.PreStockExportQuery{Status: status, From: from, To: to}
+	err = h.repoFor(c).Each(query, func(item *models.PreStock) error {
+		var number, category, supplier string
+		if item.ComponentNumber != nil {
+			number = *item.ComponentNumber
+		}
+		if item.Category != nil {
+			category = item.Category.Name
+		}
+		if item.Supplier != nil {
+			supplier = item.Supplier.Name
+		}
+		return exporter.WriteRow([]any{
+			item.ID,
+			item.Status,
+			number,
+			item.Name,
+			item.Model,
+			item.Manufacturer,
+			item.Value,
+			item.Package,
+			category,
+			supplier,
+			item.SupplierPartNumber,
+			item.ExpectedQuantity,
+			exportYuan(item.TotalPriceCents, 100),
+			item.Location,
+			exportID(item.ComponentID),
+			exportTime(&item.CreatedAt),
+			exportTime(item.ConfirmedAt),
+		})
+	})
+	finishExport(c, exporter, err)
+}
+
 // GetByID 获取单个预入库记录
 // @route GET /api/v1/pre-stocks/:id
 func (h *PreStockHandler) GetByID(c *gin.Context) {
@@ -64,7 +142,7 @@ func (h *PreStockHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	item, err := h.repo.GetByID(uint(id))
+	item, err := h.repoFor(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "预入库记录不存在"})
 		return
@@ -82,12 +160,12 @@ func (h *PreStockHandler) Create(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.Create(&preStock); err != nil {
+	if err := h.repoFor(c).Create(&preStock); err != nil {
 		writePreStockError(c, err, "创建预入库记录失败")
 		return
 	}
 
-	created, err := h.repo.GetByID(preStock.ID)
+	created, err := h.repoFor(c).GetByID(preStock.ID)
 	if err == nil {
 		preStock = *created
 	}
@@ -115,12 +193,12 @@ func (h *PreStockHandler) Update(c *gin.Context) {
 	preStock.Supplier = nil
 	preStock.Component = nil
 
-	if err := h.repo.Update(&preStock); err != nil {
+	if err := h.repoFor(c).Update(&preStock); err != nil {
 		writePreStockError(c, err, "更新预入库记录失败")
 		return
 	}
 
-	updated, err := h.repo.GetByID(preStock.ID)
+	updated, err := h.repoFor(c).GetByID(preStock.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取更新后预入库记录失败"})
 		return
@@ -138,7 +216,7 @@ func (h *PreStockHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.Delete(uint(id)); err != nil {
+	if err := h.repoFor(c).Delete(uint(id)); err != nil {
 		writePreStockError(c, err, "删除预入库记录失败")
 		return
 	}
@@ -155,7 +233,7 @@ func (h *PreStockHandler) Confirm(c *gin.Context) {
 		return
 	}
 
-	item, err := h.repo.Confirm(uint(id))
+	item, err := h.repoFor(c).Confirm(uint(id), middleware.CurrentUsername(c))
 	if err != nil {
 		writePreStockError(c, err, "确认预入库失败")
 		return
@@ -170,6 +248,8 @@ func writePreStockError(c *gin.Context, err error, fallback string) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "元件编号已存在"})
 	case errors.Is(err, repository.ErrPreStockAlreadyConfirmed):
 		c.JSON(http.StatusBadRequest, gin.H{"error": "预入库记录已确认"})
+	case errors.Is(err, repository.ErrWorkspaceMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 	case errors.Is(err, repository.ErrInvalidPreStockStatus):
 		c.JSON(http.StatusBadRequest, gin.H{"error": "预入库状态无效"})
 	case errors.Is(err, gorm.ErrRecordNotFound):
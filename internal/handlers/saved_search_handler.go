@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Rehtt/hamster-bin/internal/middleware"
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/Rehtt/hamster-bin/internal/repository"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SavedSearchHandler struct {
+	repo         *repository.SavedSearchRepository
+	categoryRepo *repository.CategoryRepository
+}
+
+func NewSavedSearchHandler(db *gorm.DB) *SavedSearchHandler {
+	return &SavedSearchHandler{
+		repo:         repository.NewSavedSearchRepository(db),
+		categoryRepo: repository.NewCategoryRepository(db),
+	}
+}
+
+// repoFor 返回限定在当前请求工作区内的仓储
+func (h *SavedSearchHandler) repoFor(c *gin.Context) *repository.SavedSearchRepository {
+	return h.repo.ForWorkspace(middleware.CurrentWorkspaceID(c))
+}
+
+// isManager 工作区所有者可修改、删除他人共享的保存搜索
+func isManager(c *gin.Context) bool {
+	return middleware.CurrentWorkspaceRole(c) == repository.WorkspaceRoleOwner
+}
+
+// viewerMayWrite 只读成员只能创建、修改与删除自己的私有搜索；shared 为写入前后任一侧的共享状态。
+// 不允许时已写入 403 并返回 false
+func viewerMayWrite(c *gin.Context, shared bool) bool {
+	if !shared || repository.WorkspaceRoleAllows(middleware.CurrentWorkspaceRole(c), repository.WorkspaceRoleEditor) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "只读成员只能保存私有搜索"})
+	return false
+}
+
+// viewerMayChange 只读成员修改或删除前确认目标不是共享搜索；出错时已写入响应并返回 false
+func (h *SavedSearchHandler) viewerMayChange(c *gin.Context, id uint, shared bool) bool {
+	if repository.WorkspaceRoleAllows(middleware.CurrentWorkspaceRole(c), repository.WorkspaceRoleEditor) {
+		return true
+	}
+	search, err := h.repoFor(c).GetByID(id, middleware.CurrentUsername(c))
+	if err != nil {
+		writeSavedSearchError(c, err, "获取保存搜索失败")
+		return false
+	}
+	return viewerMayWrite(c, search.Shared || shared)
+}
+
+// GetAll 获取当前用户可见的保存搜索（自己的与共享的）
+// @route GET /api/v1/saved-searches
+func (h *SavedSearchHandler) GetAll(c *gin.Context) {
+	searches, err := h.repoFor(c).GetAll(middleware.CurrentUsername(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取保存搜索失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": searches})
+}
+
+// GetByID 获取单个保存搜索
+// @route GET /api/v1/saved-searches/:id
+func (h *SavedSearchHandler) GetByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
+		return
+	}
+	search, err := h.repoFor(c).GetByID(uint(id), middleware.CurrentUsername(c))
+	if err != nil {
+		writeSavedSearchError(c, err, "获取保存搜索失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": search})
+}
+
+// Create 以当前用户为创建人保存搜索；只读成员只能创建私有搜索
+// @route POST /api/v1/saved-searches
+// Body: {"name": "B 柜 0402 电容低库存", "shared": true, "params": {"q": "pkg:0402 cat:电容 stock:<100 location:B*", "sort_by": "stock_quantity", "sort_order": "asc", "columns": ["name", "stock_quantity"]}}
+func (h *SavedSearchHandler) Create(c *gin.Context) {
+	var search models.SavedSearch
+	if err := c.ShouldBindJSON(&search); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+	if !viewerMayWrite(c, search.Shared) || !h.validateParams(c, &search.Params) {
+		return
+	}
+	search.Owner = middleware.CurrentUsername(c)
+	if err := h.repoFor(c).Create(&search); err != nil {
+		writeSavedSearchError(c, err, "保存搜索失败")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": search})
+}
+
+// Update 修改保存搜索的名称、共享状态与条件；他人的共享搜索只有工作区所有者可修改，只读成员只能修改自己的私有搜索
+// @route PUT /api/v1/saved-searches/:id
+func (h *SavedSearchHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
+		return
+	}
+	var change models.SavedSearch
+	if err := c.ShouldBindJSON(&change); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+	if !h.viewerMayChange(c, uint(id), change.Shared) || !h.validateParams(c, &change.Params) {
+		return
+	}
+	search, err := h.repoFor(c).Update(uint(id), middleware.CurrentUsername(c), isManager(c), &change)
+	if err != nil {
+		writeSavedSearchError(c, err, "更新保存搜索失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": search})
+}
+
+// Delete 删除保存搜索，权限同 Update
+// @route DELETE /api/v1/saved-searches/:id
+func (h *SavedSearchHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
+		return
+	}
+	if !h.viewerMayChange(c, uint(id), false) {
+		return
+	}
+	if err := h.repoFor(c).Delete(uint(id), middleware.CurrentUsername(c), isManager(c)); err != nil {
+		writeSavedSearchError(c, err, "删除保存搜索失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// validateParams 校验并规范化保存的条件：q 语法、排序、列名与分类归属；出错时已写入响应并返回 false
+func (h *SavedSearchHandler) validateParams(c *gin.Context, params *models.SavedSearchParams) bool {
+	query, err := repository.SavedSearchQuery(*params)
+	if err != nil {
+		writeFilterSyntaxError(c, err)
+		return false
+	}
+	if msg := validateComponentSort(query); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return false
+	}
+	params.SortBy, params.SortOrder = query.SortBy, strings.ToLower(query.SortOrder)
+
+	columns := params.Columns[:0]
+	for _, column := range params.Columns {
+		column = strings.TrimSpace(column)
+		if column == "" {
+			continue
+		}
+		if _, ok := componentExportColumnLabels[column]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的列: " + column})
+			return false
+		}
+		columns = append(columns, column)
+	}
+	params.Columns = columns
+
+	if params.CategoryID != nil {
+		if _, err := h.categoryRepo.ForWorkspace(middleware.CurrentWorkspaceID(c)).GetByID(*params.CategoryID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "分类不存在"})
+			return false
+		}
+	}
+	return true
+}
+
+func writeSavedSearchError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrSavedSearchNameRequired),
+		errors.Is(err, repository.ErrSavedSearchNameDuplicate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrSavedSearchForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "保存搜索不存在"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
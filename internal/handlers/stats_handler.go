@@ -1,24 +1,39 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Rehtt/hamster-bin/internal/middleware"
 	"github.com/Rehtt/hamster-bin/internal/repository"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type StatsHandler struct {
-	repo *repository.StatsRepository
+	repo            *repository.StatsRepository
+	savedSearchRepo *repository.SavedSearchRepository
+	categoryRepo    *repository.CategoryRepository
 }
 
 func NewStatsHandler(db *gorm.DB) *StatsHandler {
 	return &StatsHandler{
-		repo: repository.NewStatsRepository(db),
+		repo:            repository.NewStatsRepository(db),
+		savedSearchRepo: repository.NewSavedSearchRepository(db),
+		categoryRepo:    repository.NewCategoryRepository(db),
 	}
 }
 
-// GetDashboard 获取仪表盘统计
+// repoFor 返回限定在当前请求工作区内的仓储
+func (h *StatsHandler) repoFor(c *gin.Context) *repository.StatsRepository {
+	return h.repo.ForWorkspace(middleware.CurrentWorkspaceID(c))
+}
+
+// GetDashboard 获取仪表盘统计，saved_searches 为当前用户可见的各保存搜索命中的元件数与库存合计
 // @route GET /api/v1/stats
 func (h *StatsHandler) GetDashboard(c *gin.Context) {
 	rangeKey := c.DefaultQuery("range", repository.StatsRangeMonth)
@@ -29,7 +44,7 @@ func (h *StatsHandler) GetDashboard(c *gin.Context) {
 		return
 	}
 
-	stats, err := h.repo.GetDashboardStats(rangeKey)
+	stats, err := h.repoFor(c).GetDashboardStats(rangeKey)
 	if err != nil {
 		if err == repository.ErrInvalidStatsRange {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -38,6 +53,333 @@ func (h *StatsHandler) GetDashboard(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取统计数据失败"})
 		return
 	}
+	stats.SavedSearches, err = h.savedSearchRepo.ForWorkspace(middleware.CurrentWorkspaceID(c)).Counts(middleware.CurrentUsername(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取统计数据失败"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"data": stats})
 }
+
+// defaultStatsSeriesDays 未指定 from 时统计最近的天数
+const defaultStatsSeriesDays = 30
+
+// GetSeries 按时间桶统计入库/出库数量与金额。from/to 为 YYYY-MM-DD（按 tz 时区解析，to 包含当天）或 RFC3339，
+// 默认最近 30 天；tz 为 IANA 时区名，默认服务器时区；bucket 可选 day、week（周一开始）、month；
+// group_by 可选 category、supplier、location、project（出入库原因）；top 为返回的消耗最多元件数
+// @route GET /api/v1/stats/series
+func (h *StatsHandler) GetSeries(c *gin.Context) {
+	query, ok := parseStatsSeriesQuery(c)
+	if !ok {
+		return
+	}
+	groupBy := c.Query("group_by")
+	if groupBy != "" && !repository.IsStatsGroup(groupBy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 group_by 参数，可选值：category、supplier、location、project"})
+		return
+	}
+	top, err := strconv.Atoi(c.DefaultQuery("top", strconv.Itoa(repository.DefaultStatsTopN)))
+	if err != nil || top < 1 || top > repository.MaxStatsTopN {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("top 需为 1 到 %d 的整数", repository.MaxStatsTopN)})
+		return
+	}
+
+	query.GroupBy, query.TopN = groupBy, top
+
+	series, err := h.repoFor(c).GetSeries(query)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidStatsRange) || errors.Is(err, repository.ErrTooManyStatsBuckets) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取统计数据失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": series})
+}
+
+// parseStatsLocation 解析 query tz（IANA 时区名），默认服务器时区；出错时已写入响应并返回 false
+func parseStatsLocation(c *gin.Context) (*time.Location, bool) {
+	tz := strings.TrimSpace(c.Query("tz"))
+	if tz == "" {
+		return time.Local, true
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 tz 参数，应为 IANA 时区名，如 Asia/Shanghai"})
+		return nil, false
+	}
+	return loc, true
+}
+
+// parseStatsSeriesQuery 解析时间序列的 tz、from/to（默认最近 30 天）与 bucket；出错时已写入响应并返回 false
+func parseStatsSeriesQuery(c *gin.Context) (repository.StatsSeriesQuery, bool) {
+	loc, ok := parseStatsLocation(c)
+	if !ok {
+		return repository.StatsSeriesQuery{}, false
+	}
+	from, to, err := parseTimeRangeIn(c, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return repository.StatsSeriesQuery{}, false
+	}
+	if to.IsZero() {
+		now := time.Now().In(loc)
+		to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+	}
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -defaultStatsSeriesDays)
+	}
+
+	bucket := c.DefaultQuery("bucket", repository.StatsBucketDay)
+	if !repository.IsStatsBucket(bucket) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 bucket 参数，可选值：day、week、month"})
+		return repository.StatsSeriesQuery{}, false
+	}
+	return repository.StatsSeriesQuery{From: from, To: to, Location: loc, Bucket: bucket}, true
+}
+
+// parseValuationQuery 解析 at（YYYY-MM-DD 按 tz 时区解析并包含当天，或 RFC3339；默认当前时间）、
+// category_id 与 include_subcategories；出错时已写入响应并返回 false
+func (h *StatsHandler) parseValuationQuery(c *gin.Context) (repository.ValuationQuery, bool) {
+	loc, ok := parseStatsLocation(c)
+	if !ok {
+		return repository.ValuationQuery{}, false
+	}
+	at, dateOnly, err := parseExportTime(c.Query("at"), loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at 格式错误，应为 YYYY-MM-DD 或 RFC3339"})
+		return repository.ValuationQuery{}, false
+	}
+	if at.IsZero() {
+		at = time.Now().In(loc)
+	} else if dateOnly {
+		at = at.AddDate(0, 0, 1)
+	}
+	categoryIDs, ok := h.parseCategoryIDs(c)
+	if !ok {
+		return repository.ValuationQuery{}, false
+	}
+	return repository.ValuationQuery{At: at, CategoryIDs: categoryIDs}, true
+}
+
+// parseCategoryIDs 解析 category_id 与 include_subcategories，未指定分类时返回 nil；出错时已写入响应并返回 false
+func (h *StatsHandler) parseCategoryIDs(c *gin.Context) ([]uint, bool) {
+	raw := c.Query("category_id")
+	if raw == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分类 ID"})
+		return nil, false
+	}
+	if c.Query("include_subcategories") != "true" {
+		return []uint{uint(id)}, true
+	}
+	ids, err := h.categoryRepo.ForWorkspace(middleware.CurrentWorkspaceID(c)).DescendantIDs(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分类失败"})
+		return nil, false
+	}
+	return ids, true
+}
+
+// GetValuation 按库存记录重放还原某一时刻的库存数量与价值，返回合计与按分类的合计。
+// 已撤销的记录及其冲销流水视为从未发生；无法还原单价的元件使用当前参考单价
+// @route GET /api/v1/stats/valuation?at=2025-12-31&tz=Asia/Shanghai&category_id=1&include_subcategories=true
+func (h *StatsHandler) GetValuation(c *gin.Context) {
+	query, ok := h.parseValuationQuery(c)
+	if !ok {
+		return
+	}
+	valuation, err := h.repoFor(c).GetValuation(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取库存估值失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": valuation})
+}
+
+var valuationExportKeys = []string{
+	"component_id", "component_number", "component_name", "category_id", "category_name",
+	"quantity", "unit_price", "value", "price_estimated",
+}
+
+var valuationExportHeaders = []string{
+	"元件ID", "系统编号", "元件名称", "分类ID", "分类",
+	"库存数量", "单价", "价值", "单价为估算",
+}
+
+// ExportValuation 导出某一时刻各元件的库存数量与价值（只含当时有库存的元件），参数同 GetValuation
+// @route GET /api/v1/stats/valuation/export?format=xlsx&at=2025-12-31
+func (h *StatsHandler) ExportValuation(c *gin.Context) {
+	format, err := parseExportFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query, ok := h.parseValuationQuery(c)
+	if !ok {
+		return
+	}
+
+	exporter, err := newTableExporter(c, format, "valuation_"+query.At.Format("20060102"), valuationExportKeys, valuationExportHeaders)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成导出文件失败"})
+		return
+	}
+	err = h.repoFor(c).EachValuation(query, func(item *repository.ComponentValuation) error {
+		if item.Quantity == 0 {
+			return nil
+		}
+		estimated := "否"
+		if item.PriceEstimated {
+			estimated = "是"
+		}
+		return exporter.WriteRow([]any{
+			item.ComponentID,
+			item.ComponentNumber,
+			item.Name,
+			item.CategoryID,
+			item.CategoryName,
+			item.Quantity,
+			exportYuan(item.UnitPriceMicro, 1e6),
+			exportYuan(item.ValueCents, 100),
+			estimated,
+		})
+	})
+	finishExport(c, exporter, err)
+}
+
+// parseDeadStockQuery 解析 days（默认 180）、max_outbound（默认 0）、category_id、include_subcategories 与 location；
+// 出错时已写入响应并返回 false
+func (h *StatsHandler) parseDeadStockQuery(c *gin.Context) (repository.DeadStockQuery, bool) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(repository.DefaultDeadStockDays)))
+	if err != nil || days < 1 || days > repository.MaxDeadStockDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days 应为 1 到 %d 之间的整数", repository.MaxDeadStockDays)})
+		return repository.DeadStockQuery{}, false
+	}
+	maxOutbound, err := strconv.Atoi(c.DefaultQuery("max_outbound", "0"))
+	if err != nil || maxOutbound < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_outbound 应为非负整数"})
+		return repository.DeadStockQuery{}, false
+	}
+	categoryIDs, ok := h.parseCategoryIDs(c)
+	if !ok {
+		return repository.DeadStockQuery{}, false
+	}
+	return repository.DeadStockQuery{
+		Days:        days,
+		MaxOutbound: maxOutbound,
+		Now:         time.Now(),
+		CategoryIDs: categoryIDs,
+		Location:    c.Query("location"),
+	}, true
+}
+
+// GetDeadStock 呆滞料报表：最近 days 天出库数量不超过 max_outbound 的有库存元件，附占用金额、最后出库与最后变动，
+// 并按分类、按存放位置合计。统计期起点之后创建的元件不计入
+// @route GET /api/v1/stats/dead-stock?days=180&max_outbound=0&category_id=1&include_subcategories=true&location=A1
+func (h *StatsHandler) GetDeadStock(c *gin.Context) {
+	query, ok := h.parseDeadStockQuery(c)
+	if !ok {
+		return
+	}
+	report, err := h.repoFor(c).GetDeadStock(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取呆滞料报表失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+var deadStockExportKeys = []string{
+	"component_id", "component_number", "component_name", "category_name", "location", "stock_quantity",
+	"unit_price", "value", "outbound_quantity", "last_outbound_at", "last_movement_at", "last_movement_change", "last_movement_reason",
+}
+
+var deadStockExportHeaders = []string{
+	"元件ID", "系统编号", "元件名称", "分类", "存放位置", "库存数量",
+	"参考单价", "占用金额", "统计期内出库", "最后出库时间", "最后变动时间", "最后变动数量", "最后变动原因",
+}
+
+// ExportDeadStock 导出呆滞料明细（按占用金额降序），参数同 GetDeadStock
+// @route GET /api/v1/stats/dead-stock/export?format=xlsx&days=180
+func (h *StatsHandler) ExportDeadStock(c *gin.Context) {
+	format, err := parseExportFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query, ok := h.parseDeadStockQuery(c)
+	if !ok {
+		return
+	}
+	items, err := h.repoFor(c).DeadStockItems(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取呆滞料报表失败"})
+		return
+	}
+
+	exporter, err := newTableExporter(c, format, fmt.Sprintf("dead_stock_%dd", query.Days), deadStockExportKeys, deadStockExportHeaders)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成导出文件失败"})
+		return
+	}
+	for _, item := range items {
+		var lastChange any
+		if item.LastMovementAt != nil {
+			lastChange = item.LastMovementChange
+		}
+		err = exporter.WriteRow([]any{
+			item.ComponentID,
+			item.ComponentNumber,
+			item.Name,
+			item.CategoryName,
+			item.Location,
+			item.StockQuantity,
+			exportYuan(item.UnitPriceMicro, 1e6),
+			exportYuan(item.ValueCents, 100),
+			item.OutboundQuantity,
+			exportTime(item.LastOutboundAt),
+			exportTime(item.LastMovementAt),
+			lastChange,
+			item.LastMovementReason,
+		})
+		if err != nil {
+			break
+		}
+	}
+	finishExport(c, exporter, err)
+}
+
+// GetComponentStockHistory 返回单个元件每个时间桶结束时的库存数量与价值，from/to/tz/bucket 同 GetSeries，重放口径同 GetValuation
+// @route GET /api/v1/components/:id/stock-history?from=2025-01-01&to=2025-12-31&bucket=month
+func (h *StatsHandler) GetComponentStockHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的元件 ID"})
+		return
+	}
+	query, ok := parseStatsSeriesQuery(c)
+	if !ok {
+		return
+	}
+
+	points, err := h.repoFor(c).GetComponentStockHistory(uint(id), query)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "元件不存在"})
+			return
+		}
+		if errors.Is(err, repository.ErrInvalidStatsRange) || errors.Is(err, repository.ErrTooManyStatsBuckets) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取库存历史失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": points})
+}
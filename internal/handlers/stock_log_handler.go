@@ -4,7 +4,9 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/Rehtt/hamster-bin/internal/middleware"
 	"github.com/Rehtt/hamster-bin/internal/repository"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -20,29 +22,197 @@ func NewStockLogHandler(db *gorm.DB) *StockLogHandler {
 	}
 }
 
-// GetAll 获取所有库存记录（分页）
-// @route GET /api/v1/stock-logs?page=1&page_size=20
+// repoFor 返回限定在当前请求工作区内的仓储
+func (h *StockLogHandler) repoFor(c *gin.Context) *repository.StockLogRepository {
+	return h.repo.ForWorkspace(middleware.CurrentWorkspaceID(c))
+}
+
+// GetAll 按条件查询库存记录，每条附带变动后结存 balance_after
+// @route GET /api/v1/stock-logs?page=1&page_size=20&operator=admin
+// 筛选 query：component_id、category_id（含子分类）、from/to（同导出）、direction（in|out|adjust）、reason（包含）、
+// status（normal|revoked|reversal|merged，逗号分隔为或）、min_amount/max_amount（变动数量绝对值）。
+// 传 cursor（首次为空字符串，之后为上次响应的 next_cursor）时按游标分页，忽略 page。
 func (h *StockLogHandler) GetAll(c *gin.Context) {
+	query, ok := parseStockLogQuery(c)
+	if !ok {
+		return
+	}
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	query.Page, query.PageSize = page, pageSize
+
+	rawCursor, cursorMode := c.GetQuery("cursor")
+	if cursorMode {
+		query.Cursor = &repository.StockLogCursor{}
+		if rawCursor != "" {
+			cursor, err := repository.ParseStockLogCursor(rawCursor)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			query.Cursor = &cursor
+		}
+	}
 
-	logs, total, err := h.repo.GetAll(page, pageSize)
+	list, err := h.repoFor(c).GetAll(query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取记录失败"})
 		return
 	}
 
+	if cursorMode {
+		c.JSON(http.StatusOK, gin.H{
+			"data": list.Items,
+			"pagination": gin.H{
+				"page_size":   pageSize,
+				"total":       list.Total,
+				"next_cursor": list.NextCursor,
+			},
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"data": logs,
+		"data": list.Items,
 		"pagination": gin.H{
 			"page":       page,
 			"page_size":  pageSize,
-			"total":      total,
-			"total_page": (total + int64(pageSize) - 1) / int64(pageSize),
+			"total":      list.Total,
+			"total_page": (list.Total + int64(pageSize) - 1) / int64(pageSize),
 		},
 	})
 }
 
+// parseStockLogQuery 解析列表与导出共用的筛选参数；出错时已写入 400 响应并返回 false
+func parseStockLogQuery(c *gin.Context) (repository.StockLogQuery, bool) {
+	fail := func(msg string) (repository.StockLogQuery, bool) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return repository.StockLogQuery{}, false
+	}
+
+	from, to, err := parseExportTimeRange(c)
+	if err != nil {
+		return fail(err.Error())
+	}
+	query := repository.StockLogQuery{
+		Operator:  strings.TrimSpace(c.Query("operator")),
+		From:      from,
+		To:        to,
+		Direction: strings.TrimSpace(c.Query("direction")),
+		Reason:    strings.TrimSpace(c.Query("reason")),
+	}
+	if raw := c.Query("component_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return fail("无效的元件 ID")
+		}
+		query.ComponentID = uint(id)
+	}
+	if raw := c.Query("category_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return fail("无效的分类 ID")
+		}
+		categoryID := uint(id)
+		query.CategoryID = &categoryID
+	}
+	if query.Direction != "" && !repository.IsStockLogDirection(query.Direction) {
+		return fail("direction 只能为 in、out 或 adjust")
+	}
+	for _, status := range strings.Split(c.Query("status"), ",") {
+		if status = strings.TrimSpace(status); status == "" {
+			continue
+		}
+		if !repository.IsStockLogStatus(status) {
+			return fail("不支持的状态: " + status + "，可选 normal、revoked、reversal、merged")
+		}
+		query.Statuses = append(query.Statuses, status)
+	}
+	for _, bound := range []struct {
+		key    string
+		target **int
+	}{{"min_amount", &query.MinAmount}, {"max_amount", &query.MaxAmount}} {
+		raw := strings.TrimSpace(c.Query(bound.key))
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return fail(bound.key + " 必须为非负整数")
+		}
+		*bound.target = &n
+	}
+	if query.MinAmount != nil && query.MaxAmount != nil && *query.MinAmount > *query.MaxAmount {
+		return fail("min_amount 不能大于 max_amount")
+	}
+	return query, true
+}
+
+// GetOperators 获取库存记录中出现过的操作人，供筛选下拉使用
+// @route GET /api/v1/stock-logs/operators
+func (h *StockLogHandler) GetOperators(c *gin.Context) {
+	operators, err := h.repoFor(c).GetDistinctOperators()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取操作人列表失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": operators})
+}
+
+var stockLogExportKeys = []string{
+	"id", "created_at", "component_id", "component_number", "component_name", "change_amount",
+	"unit_price", "total_price", "reason", "operator", "revoked_at", "reversal_of_id", "merged_from_id",
+}
+
+var stockLogExportHeaders = []string{
+	"记录ID", "时间", "元件ID", "系统编号", "元件名称", "变动数量",
+	"单价", "总价", "原因", "操作人", "撤销时间", "冲销记录ID", "合并来源元件ID",
+}
+
+// Export 导出库存记录为 CSV、XLSX 或 JSON Lines，按时间先后逐行流式写出
+// @route GET /api/v1/stock-logs/export?format=xlsx&from=2024-01-01&to=2024-12-31&operator=admin&component_id=1
+// from/to 为 YYYY-MM-DD（含 to 当天）或 RFC3339；其余筛选参数同 GetAll；单价、总价单位为元。
+func (h *StockLogHandler) Export(c *gin.Context) {
+	format, err := parseExportFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query, ok := parseStockLogQuery(c)
+	if !ok {
+		return
+	}
+
+	exporter, err := newTableExporter(c, format, "stock_logs", stockLogExportKeys, stockLogExportHeaders)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成导出文件失败"})
+		return
+	}
+	err = h.repoFor(c).Each(query, func(log *repository.StockLogExportRow) error {
+		return exporter.WriteRow([]any{
+			log.ID,
+			exportTime(&log.CreatedAt),
+			log.ComponentID,
+			log.ComponentNumber,
+			log.ComponentName,
+			log.ChangeAmount,
+			exportYuan(log.UnitPriceMicro, 1e6),
+			exportYuan(log.TotalPriceCents, 100),
+			log.Reason,
+			log.Operator,
+			exportTime(log.RevokedAt),
+			exportID(log.ReversalOfID),
+			exportID(log.MergedFromID),
+		})
+	})
+	finishExport(c, exporter, err)
+}
+
 // Revoke 撤销库存记录
 // @route POST /api/v1/stock-logs/:id/revoke
 func (h *StockLogHandler) Revoke(c *gin.Context) {
@@ -52,7 +222,7 @@ func (h *StockLogHandler) Revoke(c *gin.Context) {
 		return
 	}
 
-	original, reversal, err := h.repo.RevokeStockLog(uint(id))
+	original, reversal, err := h.repoFor(c).RevokeStockLog(uint(id), middleware.CurrentUsername(c))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "记录不存在"})
@@ -66,6 +236,10 @@ func (h *StockLogHandler) Revoke(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "冲销记录不可撤销"})
 			return
 		}
+		if errors.Is(err, repository.ErrCannotRevokeMerge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "合并记录不可撤销"})
+			return
+		}
 		if errors.Is(err, repository.ErrInsufficientStock) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "库存不足，无法撤销该入库记录"})
 			return
@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/Rehtt/hamster-bin/internal/middleware"
 	"github.com/Rehtt/hamster-bin/internal/models"
 	"github.com/Rehtt/hamster-bin/internal/repository"
 	"github.com/gin-gonic/gin"
@@ -21,10 +23,15 @@ func NewSupplierHandler(db *gorm.DB) *SupplierHandler {
 	}
 }
 
+// repoFor 返回限定在当前请求工作区内的仓储
+func (h *SupplierHandler) repoFor(c *gin.Context) *repository.SupplierRepository {
+	return h.repo.ForWorkspace(middleware.CurrentWorkspaceID(c))
+}
+
 // GetAll 获取所有供应商
 // @route GET /api/v1/suppliers
 func (h *SupplierHandler) GetAll(c *gin.Context) {
-	suppliers, err := h.repo.GetAll()
+	suppliers, err := h.repoFor(c).GetAll()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取供应商失败"})
 		return
@@ -41,7 +48,7 @@ func (h *SupplierHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	supplier, err := h.repo.GetByID(uint(id))
+	supplier, err := h.repoFor(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "供应商不存在"})
 		return
@@ -65,11 +72,149 @@ func (h *SupplierHandler) Create(c *gin.Context) {
 		return
 	}
 
-	saved, err := h.repo.FirstOrCreateByName(supplier.Name)
+	saved, err := h.repoFor(c).FirstOrCreate(&supplier)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建供应商失败"})
+		writeSupplierError(c, err, "创建供应商失败")
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{"data": saved})
 }
+
+// Update 更新供应商名称、联系方式、备注与商品链接模板
+// @route PUT /api/v1/suppliers/:id
+func (h *SupplierHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
+		return
+	}
+
+	supplier, err := h.repoFor(c).GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "供应商不存在"})
+		return
+	}
+
+	// 只接受可编辑字段，ID、工作区与时间戳以数据库为准；未提供的字段保留原值
+	var req struct {
+		Name               *string `json:"name"`
+		ContactName        *string `json:"contact_name"`
+		Phone              *string `json:"phone"`
+		Email              *string `json:"email"`
+		Website            *string `json:"website"`
+		Notes              *string `json:"notes"`
+		ProductURLTemplate *string `json:"product_url_template"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+	if req.Name != nil {
+		supplier.Name = *req.Name
+	}
+	if req.ContactName != nil {
+		supplier.ContactName = *req.ContactName
+	}
+	if req.Phone != nil {
+		supplier.Phone = *req.Phone
+	}
+	if req.Email != nil {
+		supplier.Email = *req.Email
+	}
+	if req.Website != nil {
+		supplier.Website = *req.Website
+	}
+	if req.Notes != nil {
+		supplier.Notes = *req.Notes
+	}
+	if req.ProductURLTemplate != nil {
+		supplier.ProductURLTemplate = *req.ProductURLTemplate
+	}
+	if strings.TrimSpace(supplier.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "供应商名称不能为空"})
+		return
+	}
+
+	if err := h.repoFor(c).Update(supplier); err != nil {
+		writeSupplierError(c, err, "更新供应商失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": supplier})
+}
+
+// Delete 删除供应商；仍被引用时需通过 reassign_to 指定转移供应商，否则返回 400
+// @route DELETE /api/v1/suppliers/:id?reassign_to=2
+func (h *SupplierHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
+		return
+	}
+
+	var reassignTo *uint
+	if raw := c.Query("reassign_to"); raw != "" {
+		target, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的转移供应商 ID"})
+			return
+		}
+		uid := uint(target)
+		reassignTo = &uid
+	}
+
+	if err := h.repoFor(c).Delete(uint(id), reassignTo); err != nil {
+		writeSupplierError(c, err, "删除供应商失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// Merge 合并重复供应商：来源供应商的元件与预入库转移到目标供应商后删除来源
+// @route POST /api/v1/suppliers/merge
+// Body: {"source_ids": [3, 4], "target_id": 1}
+func (h *SupplierHandler) Merge(c *gin.Context) {
+	var req struct {
+		SourceIDs []uint `json:"source_ids" binding:"required,min=1"`
+		TargetID  uint   `json:"target_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+
+	seen := make(map[uint]struct{}, len(req.SourceIDs))
+	for _, id := range req.SourceIDs {
+		if _, ok := seen[id]; ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "存在重复的供应商 ID"})
+			return
+		}
+		seen[id] = struct{}{}
+	}
+
+	merged, err := h.repoFor(c).Merge(req.SourceIDs, req.TargetID)
+	if err != nil {
+		writeSupplierError(c, err, "合并供应商失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": merged, "message": "合并成功"})
+}
+
+func writeSupplierError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrSupplierNameDuplicate),
+		errors.Is(err, repository.ErrSupplierInUse),
+		errors.Is(err, repository.ErrInvalidSupplierReassign),
+		errors.Is(err, repository.ErrInvalidSupplierMerge),
+		errors.Is(err, repository.ErrInvalidProductURLTemplate),
+		errors.Is(err, repository.ErrWorkspaceMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "供应商不存在"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
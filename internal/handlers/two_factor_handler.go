@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"errors"
+	"image/png"
+	"net/http"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/auth"
+	"github.com/Rehtt/hamster-bin/internal/middleware"
+	"github.com/Rehtt/hamster-bin/internal/repository"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const twoFactorQRCodeSize = 256
+
+type twoFactorCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// twoFactorSession 当前请求的身份：已登录会话或密码校验通过后的等待会话
+type twoFactorSession struct {
+	username string
+	pending  bool
+}
+
+func (h *AuthHandler) resolveTwoFactorSession(c *gin.Context) (twoFactorSession, bool) {
+	if !h.cfg.IsAuthEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "鉴权未启用"})
+		return twoFactorSession{}, false
+	}
+
+	claims, err := middleware.ParseSession(c, h.cfg, h.twoFactorRepo)
+	if err == nil {
+		return twoFactorSession{username: claims.Username}, true
+	}
+	if !errors.Is(err, auth.ErrInvalidToken) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取二次验证状态失败"})
+		return twoFactorSession{}, false
+	}
+	if token, err := c.Cookie(auth.PendingCookieName); err == nil && token != "" {
+		if claims, err := auth.ParsePendingToken(token, h.cfg.JWTSecret); err == nil {
+			valid, err := h.twoFactorRepo.PendingTokenValid(claims.Username, claims.IssuedAt.Time)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "获取二次验证状态失败"})
+				return twoFactorSession{}, false
+			}
+			if valid {
+				return twoFactorSession{username: claims.Username, pending: true}, true
+			}
+			clearPendingCookie(c, h.cfg)
+		}
+	}
+
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录或登录已过期"})
+	return twoFactorSession{}, false
+}
+
+// completeLogin 二次验证通过后签发正式 token 并清除等待 Cookie
+func (h *AuthHandler) completeLogin(c *gin.Context, username string) bool {
+	token, err := auth.IssueVerifiedToken(username, h.cfg.JWTSecret, h.cfg.JWTExpireHours)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "登录失败"})
+		return false
+	}
+	setAuthCookie(c, h.cfg, token)
+	clearPendingCookie(c, h.cfg)
+	return true
+}
+
+// TwoFactorStatus 获取当前账号二次验证状态
+// @route GET /api/v1/auth/2fa
+func (h *AuthHandler) TwoFactorStatus(c *gin.Context) {
+	session, ok := h.resolveTwoFactorSession(c)
+	if !ok {
+		return
+	}
+
+	enabled := false
+	setupPending := false
+	item, err := h.twoFactorRepo.GetByUsername(session.username)
+	switch {
+	case err == nil:
+		enabled = item.Enabled
+		setupPending = !item.Enabled
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取二次验证状态失败"})
+		return
+	}
+
+	var remaining int64
+	if enabled {
+		remaining, err = h.twoFactorRepo.CountUnusedRecoveryCodes(session.username)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取二次验证状态失败"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"username":                 session.username,
+			"enabled":                  enabled,
+			"required":                 h.cfg.TwoFactorRequired,
+			"setup_pending":            setupPending,
+			"verification_pending":     session.pending,
+			"recovery_codes_remaining": remaining,
+		},
+	})
+}
+
+// SetupTwoFactor 生成待绑定的 TOTP 密钥和 otpauth URI
+// @route POST /api/v1/auth/2fa/setup
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	session, ok := h.resolveTwoFactorSession(c)
+	if !ok {
+		return
+	}
+
+	item, err := h.twoFactorRepo.BeginSetup(session.username)
+	if err != nil {
+		writeTwoFactorError(c, err, "初始化二次验证失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"secret":      item.Secret,
+			"otpauth_uri": auth.TOTPURI(auth.TOTPIssuer, session.username, item.Secret),
+			"qrcode_url":  "/api/v1/auth/2fa/qrcode",
+		},
+	})
+}
+
+// TwoFactorQRCode 以 PNG 返回待绑定密钥的 otpauth 二维码；已启用后不再提供
+// @route GET /api/v1/auth/2fa/qrcode
+func (h *AuthHandler) TwoFactorQRCode(c *gin.Context) {
+	session, ok := h.resolveTwoFactorSession(c)
+	if !ok {
+		return
+	}
+
+	item, err := h.twoFactorRepo.GetByUsername(session.username)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "请先初始化二次验证"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成二维码失败"})
+		return
+	}
+	if item.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "二次验证已启用"})
+		return
+	}
+
+	code, err := qr.Encode(auth.TOTPURI(auth.TOTPIssuer, session.username, item.Secret), qr.M, qr.Auto)
+	if err == nil {
+		code, err = barcode.Scale(code, twoFactorQRCodeSize, twoFactorQRCodeSize)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成二维码失败"})
+		return
+	}
+
+	c.Header("Content-Type", "image/png")
+	c.Header("Cache-Control", "no-store")
+	if err := png.Encode(c.Writer, code); err != nil {
+		c.Status(http.StatusInternalServerError)
+	}
+}
+
+// EnableTwoFactor 校验首个动态码并启用二次验证，返回仅展示一次的恢复码
+// @route POST /api/v1/auth/2fa/enable
+// Body: {"code": "123456"}
+func (h *AuthHandler) EnableTwoFactor(c *gin.Context) {
+	session, ok := h.resolveTwoFactorSession(c)
+	if !ok {
+		return
+	}
+
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请输入验证码"})
+		return
+	}
+
+	codes, err := h.twoFactorRepo.Enable(session.username, req.Code, time.Now())
+	if err != nil {
+		writeTwoFactorError(c, err, "启用二次验证失败")
+		return
+	}
+
+	// 启用后未经二次验证签发的会话全部失效，当前会话刚通过校验，换发新 token 保持登录
+	if !h.completeLogin(c, session.username) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "二次验证已启用",
+		"data": gin.H{
+			"auth_enabled":   true,
+			"username":       session.username,
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// VerifyTwoFactor 登录第二步：校验动态码或恢复码后签发登录 Cookie
+// @route POST /api/v1/auth/2fa/verify
+// Body: {"code": "123456"} 或 {"recovery_code": "abcd-efgh-ijkl"}
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	session, ok := h.resolveTwoFactorSession(c)
+	if !ok {
+		return
+	}
+	if !session.pending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "当前已登录"})
+		return
+	}
+
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请输入验证码或恢复码"})
+		return
+	}
+
+	if err := h.twoFactorRepo.Verify(session.username, req.Code, req.RecoveryCode, time.Now()); err != nil {
+		// 锁定后作废等待会话，需重新输入密码登录
+		if errors.Is(err, repository.ErrTwoFactorLocked) {
+			clearPendingCookie(c, h.cfg)
+		}
+		writeTwoFactorError(c, err, "二次验证失败")
+		return
+	}
+
+	if !h.completeLogin(c, session.username) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"auth_enabled": true,
+			"username":     session.username,
+		},
+	})
+}
+
+// DisableTwoFactor 关闭二次验证；策略强制要求时不可关闭
+// @route POST /api/v1/auth/2fa/disable
+// Body: {"code": "123456"} 或 {"recovery_code": "abcd-efgh-ijkl"}
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	session, ok := h.resolveTwoFactorSession(c)
+	if !ok {
+		return
+	}
+	if session.pending {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录或登录已过期"})
+		return
+	}
+	if h.cfg.TwoFactorRequired {
+		c.JSON(http.StatusForbidden, gin.H{"error": "管理员已要求启用二次验证，不可关闭"})
+		return
+	}
+
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请输入验证码或恢复码"})
+		return
+	}
+
+	if err := h.twoFactorRepo.Disable(session.username, req.Code, req.RecoveryCode, time.Now()); err != nil {
+		writeTwoFactorError(c, err, "关闭二次验证失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "二次验证已关闭"})
+}
+
+// RegenerateRecoveryCodes 校验动态码后重新生成恢复码
+// @route POST /api/v1/auth/2fa/recovery-codes
+// Body: {"code": "123456"}
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	session, ok := h.resolveTwoFactorSession(c)
+	if !ok {
+		return
+	}
+	if session.pending {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录或登录已过期"})
+		return
+	}
+
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请输入验证码"})
+		return
+	}
+
+	codes, err := h.twoFactorRepo.RegenerateRecoveryCodes(session.username, req.Code, time.Now())
+	if err != nil {
+		writeTwoFactorError(c, err, "生成恢复码失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"recovery_codes": codes}})
+}
+
+func writeTwoFactorError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrTwoFactorInvalidCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "验证码无效或已使用"})
+	case errors.Is(err, repository.ErrTwoFactorLocked):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrTwoFactorNotSetup):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请先初始化二次验证"})
+	case errors.Is(err, repository.ErrTwoFactorAlreadyActive):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "二次验证已启用"})
+	case errors.Is(err, repository.ErrTwoFactorNotEnabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "二次验证未启用"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
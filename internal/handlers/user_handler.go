@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Rehtt/hamster-bin/internal/config"
+	"github.com/Rehtt/hamster-bin/internal/middleware"
+	"github.com/Rehtt/hamster-bin/internal/repository"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const maxUsernameLength = 100
+
+type UserHandler struct {
+	cfg  *config.Config
+	repo *repository.UserRepository
+}
+
+func NewUserHandler(cfg *config.Config, db *gorm.DB) *UserHandler {
+	return &UserHandler{cfg: cfg, repo: repository.NewUserRepository(db)}
+}
+
+// requireAdmin 账号管理只对实例管理员开放；鉴权关闭时没有登录，账号无意义
+func (h *UserHandler) requireAdmin(c *gin.Context) bool {
+	if !h.cfg.IsAuthEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "鉴权未启用"})
+		return false
+	}
+	if !middleware.IsInstanceAdmin(h.cfg, c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "仅管理员可管理账号"})
+		return false
+	}
+	return true
+}
+
+func writeUserError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrUserDuplicate),
+		errors.Is(err, repository.ErrPasswordTooShort),
+		errors.Is(err, repository.ErrLastWorkspaceOwner):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// GetAll 获取全部账号（仅实例管理员）
+// @route GET /api/v1/users
+func (h *UserHandler) GetAll(c *gin.Context) {
+	if !h.requireAdmin(c) {
+		return
+	}
+	users, err := h.repo.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取账号失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": users})
+}
+
+// Create 创建账号（仅实例管理员），之后通过工作区成员接口分配角色
+// @route POST /api/v1/users
+// Body: {"username": "alice", "password": "..."}
+func (h *UserHandler) Create(c *gin.Context) {
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	var req struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户名和密码不能为空"})
+		return
+	}
+	username := strings.TrimSpace(req.Username)
+	if username == "" || strings.Contains(username, "/") || utf8.RuneCountInString(username) > maxUsernameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户名"})
+		return
+	}
+	if username == h.cfg.AdminUsername {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户名与管理员相同"})
+		return
+	}
+
+	user, err := h.repo.Create(username, req.Password)
+	if err != nil {
+		writeUserError(c, err, "创建账号失败")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": user})
+}
+
+// SetPassword 重置账号密码（仅实例管理员）
+// @route PUT /api/v1/users/:username/password
+// Body: {"password": "..."}
+func (h *UserHandler) SetPassword(c *gin.Context) {
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "密码不能为空"})
+		return
+	}
+
+	if err := h.repo.SetPassword(c.Param("username"), req.Password); err != nil {
+		writeUserError(c, err, "重置密码失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "密码已重置"})
+}
+
+// Delete 删除账号及其工作区成员身份（仅实例管理员）
+// @route DELETE /api/v1/users/:username
+func (h *UserHandler) Delete(c *gin.Context) {
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	if err := h.repo.Delete(c.Param("username")); err != nil {
+		writeUserError(c, err, "删除账号失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// ChangeOwnPassword 当前账号修改自己的密码；管理员密码由环境变量配置，不能在此修改
+// @route POST /api/v1/auth/password
+// Body: {"old_password": "...", "new_password": "..."}
+func (h *UserHandler) ChangeOwnPassword(c *gin.Context) {
+	if !h.cfg.IsAuthEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "鉴权未启用"})
+		return
+	}
+	if middleware.IsInstanceAdmin(h.cfg, c) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "管理员密码请通过 ADMIN_PASSWORD 修改"})
+		return
+	}
+
+	var req struct {
+		OldPassword string `json:"old_password" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请输入原密码和新密码"})
+		return
+	}
+
+	username := middleware.CurrentUsername(c)
+	if _, err := h.repo.Authenticate(username, req.OldPassword); err != nil {
+		if errors.Is(err, repository.ErrInvalidCredentials) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "原密码错误"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "修改密码失败"})
+		return
+	}
+	if err := h.repo.SetPassword(username, req.NewPassword); err != nil {
+		writeUserError(c, err, "修改密码失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "密码已修改"})
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Rehtt/hamster-bin/internal/config"
+	"github.com/Rehtt/hamster-bin/internal/middleware"
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/Rehtt/hamster-bin/internal/repository"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WorkspaceHandler struct {
+	cfg           *config.Config
+	repo          *repository.WorkspaceRepository
+	userRepo      *repository.UserRepository
+	componentRepo *repository.ComponentRepository
+}
+
+func NewWorkspaceHandler(cfg *config.Config, db *gorm.DB) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		cfg:           cfg,
+		repo:          repository.NewWorkspaceRepository(db),
+		userRepo:      repository.NewUserRepository(db),
+		componentRepo: repository.NewComponentRepository(db),
+	}
+}
+
+func parseWorkspaceID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的工作区 ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// requireRole 校验当前用户在工作区中至少具备 required 角色，失败时写入响应并返回 false
+func (h *WorkspaceHandler) requireRole(c *gin.Context, workspaceID uint, required string) bool {
+	if _, err := h.repo.GetByID(workspaceID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "工作区不存在"})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取工作区失败"})
+		return false
+	}
+
+	role, err := middleware.ResolveWorkspaceRole(h.cfg, h.repo, c, workspaceID)
+	if err != nil || !repository.WorkspaceRoleAllows(role, required) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权执行该工作区操作"})
+		return false
+	}
+	return true
+}
+
+func writeWorkspaceError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrWorkspaceNameDuplicate),
+		errors.Is(err, repository.ErrWorkspaceNotEmpty),
+		errors.Is(err, repository.ErrDefaultWorkspace),
+		errors.Is(err, repository.ErrInvalidWorkspaceRole),
+		errors.Is(err, repository.ErrLastWorkspaceOwner),
+		errors.Is(err, repository.ErrSameWorkspace),
+		errors.Is(err, repository.ErrWorkspaceMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "记录不存在"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// GetAll 获取当前用户可访问的工作区（含角色）
+// @route GET /api/v1/workspaces
+func (h *WorkspaceHandler) GetAll(c *gin.Context) {
+	workspaces, err := h.repo.ListAccessible(middleware.CurrentUsername(c), middleware.IsInstanceAdmin(h.cfg, c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取工作区失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": workspaces})
+}
+
+// Create 创建工作区（仅实例管理员），创建者自动成为 owner
+// @route POST /api/v1/workspaces
+func (h *WorkspaceHandler) Create(c *gin.Context) {
+	if !middleware.IsInstanceAdmin(h.cfg, c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "仅管理员可创建工作区"})
+		return
+	}
+
+	var req struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "工作区名称不能为空"})
+		return
+	}
+
+	workspace := models.Workspace{Name: req.Name, Description: req.Description}
+	if err := h.repo.Create(&workspace, middleware.CurrentUsername(c)); err != nil {
+		writeWorkspaceError(c, err, "创建工作区失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": workspace})
+}
+
+// Update 修改工作区名称与描述（owner）
+// @route PUT /api/v1/workspaces/:id
+func (h *WorkspaceHandler) Update(c *gin.Context) {
+	id, ok := parseWorkspaceID(c)
+	if !ok || !h.requireRole(c, id, repository.WorkspaceRoleOwner) {
+		return
+	}
+
+	var req struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "工作区名称不能为空"})
+		return
+	}
+
+	workspace := models.Workspace{ID: id, Name: req.Name, Description: req.Description}
+	if err := h.repo.Update(&workspace); err != nil {
+		writeWorkspaceError(c, err, "更新工作区失败")
+		return
+	}
+
+	updated, err := h.repo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取更新后工作区失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": updated})
+}
+
+// Delete 删除空工作区（owner）；默认工作区不可删除
+// @route DELETE /api/v1/workspaces/:id
+func (h *WorkspaceHandler) Delete(c *gin.Context) {
+	id, ok := parseWorkspaceID(c)
+	if !ok || !h.requireRole(c, id, repository.WorkspaceRoleOwner) {
+		return
+	}
+
+	if err := h.repo.Delete(id); err != nil {
+		writeWorkspaceError(c, err, "删除工作区失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// GetMembers 获取工作区成员
+// @route GET /api/v1/workspaces/:id/members
+func (h *WorkspaceHandler) GetMembers(c *gin.Context) {
+	id, ok := parseWorkspaceID(c)
+	if !ok || !h.requireRole(c, id, repository.WorkspaceRoleViewer) {
+		return
+	}
+
+	members, err := h.repo.ListMembers(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": members})
+}
+
+// SetMember 添加成员或修改成员角色（owner）；鉴权启用时成员须为已创建的账号
+// @route PUT /api/v1/workspaces/:id/members/:username
+// Body: {"role": "editor"}
+func (h *WorkspaceHandler) SetMember(c *gin.Context) {
+	id, ok := parseWorkspaceID(c)
+	if !ok || !h.requireRole(c, id, repository.WorkspaceRoleOwner) {
+		return
+	}
+
+	username := strings.TrimSpace(c.Param("username"))
+	if username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户名不能为空"})
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+
+	if h.cfg.IsAuthEnabled() && username != h.cfg.AdminUsername {
+		exists, err := h.userRepo.Exists(username)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存成员失败"})
+			return
+		}
+		if !exists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": repository.ErrUserNotFound.Error()})
+			return
+		}
+	}
+
+	member, err := h.repo.SetMember(id, username, strings.TrimSpace(req.Role))
+	if err != nil {
+		writeWorkspaceError(c, err, "保存成员失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": member})
+}
+
+// RemoveMember 移除工作区成员（owner）
+// @route DELETE /api/v1/workspaces/:id/members/:username
+func (h *WorkspaceHandler) RemoveMember(c *gin.Context) {
+	id, ok := parseWorkspaceID(c)
+	if !ok || !h.requireRole(c, id, repository.WorkspaceRoleOwner) {
+		return
+	}
+
+	if err := h.repo.RemoveMember(id, c.Param("username")); err != nil {
+		writeWorkspaceError(c, err, "移除成员失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "移除成功"})
+}
+
+// MoveComponents 将元件移动到其他工作区，需在来源与目标工作区均具备 editor 权限
+// @route POST /api/v1/workspaces/move-components
+// Body: {"component_ids": [1, 2], "from_workspace_id": 1, "to_workspace_id": 2, "category_id": 5}
+func (h *WorkspaceHandler) MoveComponents(c *gin.Context) {
+	var req struct {
+		ComponentIDs    []uint `json:"component_ids" binding:"required,min=1"`
+		FromWorkspaceID uint   `json:"from_workspace_id" binding:"required"`
+		ToWorkspaceID   uint   `json:"to_workspace_id" binding:"required"`
+		CategoryID      *uint  `json:"category_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+
+	seen := make(map[uint]struct{}, len(req.ComponentIDs))
+	for _, id := range req.ComponentIDs {
+		if _, ok := seen[id]; ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "存在重复的元件 ID"})
+			return
+		}
+		seen[id] = struct{}{}
+	}
+
+	if !h.requireRole(c, req.FromWorkspaceID, repository.WorkspaceRoleEditor) ||
+		!h.requireRole(c, req.ToWorkspaceID, repository.WorkspaceRoleEditor) {
+		return
+	}
+
+	moved, err := h.componentRepo.ForWorkspace(req.FromWorkspaceID).MoveToWorkspace(repository.MoveComponentsParams{
+		ComponentIDs:      req.ComponentIDs,
+		TargetWorkspaceID: req.ToWorkspaceID,
+		TargetCategoryID:  req.CategoryID,
+	})
+	if err != nil {
+		writeWorkspaceError(c, err, "移动元件失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": moved, "message": "移动成功"})
+}
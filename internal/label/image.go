@@ -0,0 +1,124 @@
+package label
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// 条码图片格式
+const (
+	ImagePNG = "png"
+	ImageSVG = "svg"
+)
+
+const (
+	// DefaultImageSize 条码图片默认宽度（像素）
+	DefaultImageSize = 256
+	// MaxImageSize 条码图片最大宽度（像素）
+	MaxImageSize = 2048
+)
+
+// ImageContentType 返回条码图片格式对应的 Content-Type
+func ImageContentType(format string) string {
+	if format == ImageSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// codeImage 条码图片的模块布局：矩阵四周留静区，一维码按宽度的四分之一拉高
+type codeImage struct {
+	matrix         [][]bool
+	quietX, quietY int
+	barHeight      int
+}
+
+func newCodeImage(symbology, content string) (*codeImage, error) {
+	matrix, err := encodeMatrix(symbology, content)
+	if err != nil {
+		return nil, err
+	}
+	img := &codeImage{matrix: matrix, barHeight: 1}
+	switch symbology {
+	case SymbologyQR:
+		img.quietX, img.quietY = 4, 4
+	case SymbologyDataMatrix:
+		img.quietX, img.quietY = 1, 1
+	case SymbologyCode128:
+		img.quietX, img.quietY = 10, 2
+		img.barHeight = max(len(matrix[0])/4, 10)
+	}
+	return img, nil
+}
+
+func (c *codeImage) cols() int { return len(c.matrix[0]) + 2*c.quietX }
+func (c *codeImage) rows() int { return len(c.matrix)*c.barHeight + 2*c.quietY }
+
+func (c *codeImage) dark(col, row int) bool {
+	col, row = col-c.quietX, row-c.quietY
+	if col < 0 || row < 0 || col >= len(c.matrix[0]) || row >= len(c.matrix)*c.barHeight {
+		return false
+	}
+	return c.matrix[row/c.barHeight][col]
+}
+
+// WriteCodeImage 将 content 编码为条码（SymbologyQR、SymbologyDataMatrix 或 SymbologyCode128）并以 PNG 或 SVG 写入 w。
+// size 为期望宽度（像素），按整数倍放大模块，实际宽度不超过 size（模块数更多时每个模块 1 像素）
+func WriteCodeImage(w io.Writer, format, symbology, content string, size int) error {
+	switch symbology {
+	case SymbologyQR, SymbologyDataMatrix, SymbologyCode128:
+	default:
+		return fmt.Errorf("%w: 条码类型 %s", ErrUnsupportedFormat, symbology)
+	}
+	if format != ImagePNG && format != ImageSVG {
+		return fmt.Errorf("%w: 图片格式 %s", ErrUnsupportedFormat, format)
+	}
+	code, err := newCodeImage(symbology, content)
+	if err != nil {
+		return err
+	}
+	scale := max(min(size, MaxImageSize)/code.cols(), 1)
+	if format == ImageSVG {
+		return writeSVG(w, code, scale)
+	}
+
+	img := image.NewGray(image.Rect(0, 0, code.cols()*scale, code.rows()*scale))
+	for y := range img.Rect.Dy() {
+		for x := range img.Rect.Dx() {
+			if code.dark(x/scale, y/scale) {
+				img.SetGray(x, y, color.Gray{})
+			} else {
+				img.SetGray(x, y, color.Gray{Y: 0xff})
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// writeSVG 以模块为单位的 viewBox 输出，同一行连续的深色模块合并为一个矩形路径
+func writeSVG(w io.Writer, code *codeImage, scale int) error {
+	bw := bufio.NewWriter(w)
+	cols, rows := code.cols(), code.rows()
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		cols*scale, rows*scale, cols, rows)
+	fmt.Fprintf(bw, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, cols, rows)
+	for row := range rows {
+		for col := 0; col < cols; col++ {
+			if !code.dark(col, row) {
+				continue
+			}
+			end := col
+			for end+1 < cols && code.dark(end+1, row) {
+				end++
+			}
+			fmt.Fprintf(bw, "M%d %dh%dv1h-%dz", col, row, end-col+1, end-col+1)
+			col = end
+		}
+	}
+	bw.WriteString(`"/></svg>`)
+	return bw.Flush()
+}
@@ -0,0 +1,344 @@
+// Package label 将元件与存放位置渲染为可打印的标签：整页或单张 PDF、斑马 ZPL 与 TSC TSPL 指令
+package label
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/Rehtt/hamster-bin/internal/config"
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/Rehtt/hamster-bin/internal/parser"
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/datamatrix"
+	"github.com/boombuler/barcode/qr"
+)
+
+// 输出格式
+const (
+	FormatPDF  = "pdf"
+	FormatZPL  = "zpl"
+	FormatTSPL = "tspl"
+)
+
+const (
+	// DefaultDPI 热敏打印机常见分辨率
+	DefaultDPI = 203
+	// MaxCopies 每个标签的最大打印份数
+	MaxCopies = 100
+)
+
+var (
+	// ErrTemplateNotFound 模板不存在
+	ErrTemplateNotFound = errors.New("标签模板不存在")
+	// ErrUnsupportedFormat 输出格式不支持，或整页模板使用了打印机指令格式
+	ErrUnsupportedFormat = errors.New("不支持的标签输出格式")
+)
+
+// Label 单个标签的内容：Title 为首行大字，Lines 为其下的文本行，Code 为二维码内容（为空时不印二维码）
+type Label struct {
+	Title string
+	Lines []string
+	Code  string
+}
+
+// ComponentCode 元件标签二维码的内容：以系统编号生成的自有二维码（见 parser.ComponentPayload），没有编号时为空
+func ComponentCode(component *models.Component) string {
+	if component.ComponentNumber == nil || *component.ComponentNumber == "" {
+		return ""
+	}
+	return parser.ComponentPayload(*component.ComponentNumber)
+}
+
+// LocationCode 存放位置标签二维码的内容
+func LocationCode(location string) string {
+	return parser.LocationPayload(location)
+}
+
+// ComponentLabel 按模板字段生成元件标签，标题为系统编号（没有编号时为名称）；为空的字段跳过，整行为空时不输出该行
+func ComponentLabel(component *models.Component, tpl Template) Label {
+	label := Label{Title: component.Name, Code: ComponentCode(component)}
+	if component.ComponentNumber != nil && *component.ComponentNumber != "" {
+		label.Title = *component.ComponentNumber
+	}
+	for _, line := range tpl.Fields {
+		var parts []string
+		for field := range strings.SplitSeq(line, "+") {
+			if value := strings.TrimSpace(componentField(component, strings.TrimSpace(field))); value != "" {
+				parts = append(parts, value)
+			}
+		}
+		if len(parts) > 0 {
+			label.Lines = append(label.Lines, strings.Join(parts, " "))
+		}
+	}
+	return label
+}
+
+func componentField(component *models.Component, field string) string {
+	switch field {
+	case "name":
+		return component.Name
+	case "model":
+		return component.Model
+	case "manufacturer":
+		return component.Manufacturer
+	case "value":
+		return component.Value
+	case "package":
+		return component.Package
+	case "location":
+		return component.Location
+	case "category":
+		if component.Category != nil {
+			return component.Category.Name
+		}
+	case "supplier_part_number":
+		return component.SupplierPartNumber
+	}
+	return ""
+}
+
+// LocationLabel 生成存放位置标签：标题为位置，count 大于 0 时附一行元件数
+func LocationLabel(location string, count int) Label {
+	label := Label{Title: location, Code: LocationCode(location)}
+	if count > 0 {
+		label.Lines = []string{fmt.Sprintf("%d 种元件", count)}
+	}
+	return label
+}
+
+// RenderOptions 渲染参数
+type RenderOptions struct {
+	// DPI 打印机分辨率，用于 ZPL/TSPL 的毫米到点换算，不大于 0 时使用 DefaultDPI
+	DPI int
+	// Copies 每个标签的份数，不大于 0 时为 1
+	Copies int
+	// Skip 整页模板第一页跳过的标签位数，便于继续使用已撕掉部分标签的纸
+	Skip int
+}
+
+func (o RenderOptions) normalized() RenderOptions {
+	if o.DPI <= 0 {
+		o.DPI = DefaultDPI
+	}
+	o.Copies = min(max(o.Copies, 1), MaxCopies)
+	o.Skip = max(o.Skip, 0)
+	return o
+}
+
+// Service 标签模板与渲染
+type Service struct {
+	templates []Template
+	zplFont   string
+	tsplFont  string
+}
+
+// NewService 加载内置模板及 LABEL_TEMPLATES_FILE 中的自定义模板
+func NewService(cfg *config.Config) (*Service, error) {
+	templates, err := LoadTemplates(cfg.LabelTemplatesFile)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{
+		templates: templates,
+		zplFont:   strings.TrimSpace(cfg.LabelZPLFont),
+		tsplFont:  strings.TrimSpace(cfg.LabelTSPLFont),
+	}, nil
+}
+
+// Templates 返回全部模板
+func (s *Service) Templates() []Template {
+	templates := make([]Template, len(s.templates))
+	for i, tpl := range s.templates {
+		templates[i] = tpl.clone()
+	}
+	return templates
+}
+
+// Template 按名称查找模板
+func (s *Service) Template(name string) (Template, error) {
+	i := slices.IndexFunc(s.templates, func(t Template) bool { return t.Name == name })
+	if i < 0 {
+		return Template{}, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return s.templates[i].clone(), nil
+}
+
+// ContentType 返回输出格式对应的 Content-Type
+func ContentType(format string) string {
+	if format == FormatPDF {
+		return "application/pdf"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// CheckFormat 校验模板能否以指定格式输出：整页模板只能输出 PDF
+func CheckFormat(tpl Template, format string) error {
+	switch format {
+	case FormatPDF:
+		return nil
+	case FormatZPL, FormatTSPL:
+		if tpl.Sheet != nil {
+			return fmt.Errorf("%w: 整页模板 %s 只能输出 %s", ErrUnsupportedFormat, tpl.Name, FormatPDF)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+// Render 将标签按模板以指定格式写入 w
+func (s *Service) Render(w io.Writer, format string, tpl Template, labels []Label, opts RenderOptions) error {
+	if err := CheckFormat(tpl, format); err != nil {
+		return err
+	}
+	opts = opts.normalized()
+	layouts := make([]layout, len(labels))
+	for i, label := range labels {
+		l, err := newLayout(tpl, label)
+		if err != nil {
+			return err
+		}
+		layouts[i] = l
+	}
+	switch format {
+	case FormatZPL:
+		return renderZPL(w, tpl, layouts, opts, s.zplFont)
+	case FormatTSPL:
+		return renderTSPL(w, tpl, layouts, opts, s.tsplFont)
+	}
+	return renderPDF(w, tpl, layouts, opts)
+}
+
+// textItem 一行文本，坐标与字号单位为毫米，Y 为文字顶端（以标签左上角为原点）
+type textItem struct {
+	X, Y, Size float64
+	Text       string
+}
+
+// layout 单个标签的排版结果，坐标以标签左上角为原点、单位为毫米
+type layout struct {
+	label Label
+	// Code 二维码模块矩阵，true 为深色，没有二维码时为空
+	Code         [][]bool
+	CodeX, CodeY float64
+	CodeSide     float64
+	Texts        []textItem
+}
+
+const (
+	// codeTextGapMM 二维码与文字之间的间距
+	codeTextGapMM = 1.5
+	// titleScale 标题行相对普通行的字号倍数
+	titleScale = 1.3
+	// maxLineSizeMM、maxTitleSizeMM 字号上限，避免大标签上文字过大
+	maxLineSizeMM  = 3.5
+	maxTitleSizeMM = 5.0
+	ellipsis       = "..."
+)
+
+// newLayout 二维码放在内容区左侧（边长取内容区高度，不超过内容区宽度的 45%），文字在右侧自上而下排列：
+// 标题按宽度缩小字号完整显示，其余行超出宽度时截断
+func newLayout(tpl Template, label Label) (layout, error) {
+	l := layout{label: label}
+	x, y := tpl.PaddingMM, tpl.PaddingMM
+	width, height := tpl.WidthMM-2*tpl.PaddingMM, tpl.HeightMM-2*tpl.PaddingMM
+
+	textX := x
+	if label.Code != "" {
+		matrix, err := encodeMatrix(tpl.Symbology, label.Code)
+		if err != nil {
+			return l, err
+		}
+		l.Code = matrix
+		l.CodeSide = min(height, width*0.45)
+		l.CodeX = x
+		l.CodeY = y + (height-l.CodeSide)/2
+		textX = x + l.CodeSide + codeTextGapMM
+	}
+	textWidth := tpl.WidthMM - tpl.PaddingMM - textX
+	if textWidth <= 0 {
+		return l, nil
+	}
+
+	rowHeight := height / (titleScale + float64(len(label.Lines)) + 0.2)
+	lineSize := min(rowHeight*0.85, maxLineSizeMM)
+	titleSize := min(rowHeight*titleScale*0.85, maxTitleSizeMM)
+	if w := textWidthEm(label.Title); w > 0 {
+		titleSize = min(titleSize, textWidth/w)
+	}
+	cursor := y
+	if label.Title != "" {
+		l.Texts = append(l.Texts, textItem{X: textX, Y: cursor, Size: titleSize, Text: label.Title})
+	}
+	cursor += rowHeight * titleScale
+	for _, line := range label.Lines {
+		l.Texts = append(l.Texts, textItem{X: textX, Y: cursor, Size: lineSize, Text: truncate(line, textWidth/lineSize)})
+		cursor += rowHeight
+	}
+	return l, nil
+}
+
+// encodeMatrix 生成条码的模块矩阵（不含静区），一维码只有一行
+func encodeMatrix(symbology, content string) ([][]bool, error) {
+	var code barcode.Barcode
+	var err error
+	switch symbology {
+	case SymbologyDataMatrix:
+		code, err = datamatrix.Encode(content)
+	case SymbologyCode128:
+		code, err = code128.Encode(content)
+	default:
+		code, err = qr.Encode(content, qr.M, qr.Auto)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("生成条码失败: %w", err)
+	}
+	bounds := code.Bounds()
+	matrix := make([][]bool, bounds.Dy())
+	for row := range matrix {
+		matrix[row] = make([]bool, bounds.Dx())
+		for col := range matrix[row] {
+			r, _, _, _ := code.At(bounds.Min.X+col, bounds.Min.Y+row).RGBA()
+			matrix[row][col] = r < 0x8000
+		}
+	}
+	return matrix, nil
+}
+
+// runeWidthEm 字符宽度（以字号为单位）：ASCII 为半角，其余按全角计
+func runeWidthEm(r rune) float64 {
+	if r < 0x80 {
+		return 0.5
+	}
+	return 1
+}
+
+func textWidthEm(text string) float64 {
+	var width float64
+	for _, r := range text {
+		width += runeWidthEm(r)
+	}
+	return width
+}
+
+// truncate 将文本截断到 maxEm 宽度以内，截断时以省略号结尾
+func truncate(text string, maxEm float64) string {
+	if textWidthEm(text) <= maxEm {
+		return text
+	}
+	limit := maxEm - textWidthEm(ellipsis)
+	var b strings.Builder
+	var width float64
+	for _, r := range text {
+		width += runeWidthEm(r)
+		if width > limit {
+			break
+		}
+		b.WriteRune(r)
+	}
+	return b.String() + ellipsis
+}
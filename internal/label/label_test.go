@@ -0,0 +1,142 @@
+package label
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Rehtt/hamster-bin/internal/config"
+	"github.com/Rehtt/hamster-bin/internal/models"
+)
+
+func testComponent() *models.Component {
+	number := "HB-000042"
+	return &models.Component{
+		ComponentNumber: &number,
+		Name:            "贴片电阻 10kΩ ±1% 非常非常非常非常非常长的名称",
+		Value:           "10k",
+		Package:         "0603",
+		Location:        "A1-03",
+	}
+}
+
+func TestComponentLabel(t *testing.T) {
+	tpl := Template{Fields: []string{"name", "value+package", "manufacturer", "location"}}
+	label := ComponentLabel(testComponent(), tpl)
+	if label.Title != "HB-000042" || label.Code != "HB1:C:HB-000042" {
+		t.Errorf("title/code = %q/%q", label.Title, label.Code)
+	}
+	// 空字段所在行不输出，多个字段以空格拼接
+	if len(label.Lines) != 3 || label.Lines[1] != "10k 0603" || label.Lines[2] != "A1-03" {
+		t.Errorf("lines = %q", label.Lines)
+	}
+	if got := truncate("ABCDEFGHIJ", 3); got != "ABC..." {
+		t.Errorf("truncate = %q", got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	service, err := NewService(&config.Config{LabelTSPLFont: "TSS24.BF2"})
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	thermal, err := service.Template("40x30")
+	if err != nil {
+		t.Fatalf("Template: %v", err)
+	}
+	labels := []Label{ComponentLabel(testComponent(), thermal), LocationLabel(`A1"03`, 2)}
+
+	var zpl bytes.Buffer
+	if err := service.Render(&zpl, FormatZPL, thermal, labels, RenderOptions{Copies: 2}); err != nil {
+		t.Fatalf("Render zpl: %v", err)
+	}
+	for _, want := range []string{"^XA\n^CI28\n^PW320\n^LL240\n^PQ2", "^BQN,2,", "^FDMA,HB1:C:HB-000042^FS", "^FDHB-000042^FS", "^FD10k 0603^FS"} {
+		if !strings.Contains(zpl.String(), want) {
+			t.Errorf("zpl missing %q:\n%s", want, zpl.String())
+		}
+	}
+	if strings.Count(zpl.String(), "^XZ") != 2 {
+		t.Errorf("zpl labels = %d", strings.Count(zpl.String(), "^XZ"))
+	}
+
+	var tspl bytes.Buffer
+	if err := service.Render(&tspl, FormatTSPL, thermal, labels, RenderOptions{DPI: 300}); err != nil {
+		t.Fatalf("Render tspl: %v", err)
+	}
+	for _, want := range []string{"SIZE 40 mm,30 mm\nGAP 2 mm,0 mm", `,"HB1:C:HB-000042"`, `"HB1:L:A1\["]03"`, `"TSS24.BF2"`, "PRINT 1,1"} {
+		if !strings.Contains(tspl.String(), want) {
+			t.Errorf("tspl missing %q:\n%s", want, tspl.String())
+		}
+	}
+
+	sheet, err := service.Template("a4-3x8")
+	if err != nil {
+		t.Fatalf("Template: %v", err)
+	}
+	if err := service.Render(&bytes.Buffer{}, FormatZPL, sheet, labels, RenderOptions{}); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("sheet as zpl: %v", err)
+	}
+	var pdf bytes.Buffer
+	// 跳过 23 个位置后第一页只剩 1 个，份数为 2 时共 4 个标签占两页
+	if err := service.Render(&pdf, FormatPDF, sheet, labels, RenderOptions{Copies: 2, Skip: 23}); err != nil {
+		t.Fatalf("Render pdf: %v", err)
+	}
+	out := pdf.String()
+	if !strings.HasPrefix(out, "%PDF-1.4") || !strings.HasSuffix(out, "%%EOF\n") || !strings.Contains(out, "/Count 2") ||
+		!strings.Contains(out, "/MediaBox [0 0 595.28 841.89]") || !strings.Contains(out, "/STSong-Light") {
+		t.Errorf("unexpected pdf:\n%s", out)
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "templates.json")
+	custom := `[{"name":"40x30","width_mm":40,"height_mm":20,"padding_mm":1,"fields":["name"]},
+		{"name":"bin","title":"料盒","width_mm":80,"height_mm":50,"symbology":"datamatrix","fields":["name","category"]}]`
+	if err := os.WriteFile(path, []byte(custom), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	templates, err := LoadTemplates(path)
+	if err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+	if len(templates) != len(defaultTemplates)+1 || templates[0].HeightMM != 20 || templates[0].Symbology != SymbologyQR {
+		t.Errorf("templates = %+v", templates)
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"name":"bad","width_mm":40,"height_mm":30,"fields":["price"]}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTemplates(path); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
+
+func TestWriteCodeImage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCodeImage(&buf, ImagePNG, SymbologyQR, "HB1:C:HB-000042", 200); err != nil {
+		t.Fatalf("png: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("decode png: %v", err)
+	}
+	// 版本 1 二维码 21 个模块加两侧各 4 个静区模块：200 / 29 = 6 倍
+	if got := img.Bounds().Dx(); got != 29*6 {
+		t.Errorf("png width = %d", got)
+	}
+
+	buf.Reset()
+	if err := WriteCodeImage(&buf, ImageSVG, SymbologyCode128, "HB1:C:HB-000042", 0); err != nil {
+		t.Fatalf("svg: %v", err)
+	}
+	if out := buf.String(); !strings.HasPrefix(out, "<svg ") || !strings.Contains(out, `shape-rendering="crispEdges"`) || !strings.Contains(out, "h-") {
+		t.Errorf("unexpected svg: %s", out)
+	}
+	if err := WriteCodeImage(&bytes.Buffer{}, ImagePNG, SymbologyCode128, "位置", 0); err == nil {
+		t.Error("code128 should reject non-ASCII content")
+	}
+}
@@ -0,0 +1,192 @@
+package label
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// mmToPt 毫米换算为 PDF 点（1/72 英寸）
+func mmToPt(mm float64) float64 {
+	return mm * 72 / 25.4
+}
+
+// pdfNum 格式化 PDF 数值，保留两位小数并去掉多余的 0
+func pdfNum(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// pdfHexText 将文本编码为 UTF-16BE 十六进制串，配合 UniGB-UCS2-H 编码使用；BMP 之外的字符替换为 ?
+func pdfHexText(text string) string {
+	var b strings.Builder
+	b.WriteByte('<')
+	for _, r := range text {
+		if r > 0xFFFF || utf16.IsSurrogate(r) {
+			r = '?'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+// pdfWriter 最小化的 PDF 写入器：按顺序写对象并记录偏移，最后输出交叉引用表
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+// object 写入编号为 len(offsets)+1 的对象并返回编号
+func (p *pdfWriter) object(body string) int {
+	p.offsets = append(p.offsets, p.buf.Len())
+	id := len(p.offsets)
+	fmt.Fprintf(&p.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+	return id
+}
+
+// reserve 预留对象编号，之后用 fill 写入（各页面需要先引用页面树的编号）
+func (p *pdfWriter) reserve() int {
+	p.offsets = append(p.offsets, -1)
+	return len(p.offsets)
+}
+
+func (p *pdfWriter) fill(id int, body string) {
+	p.offsets[id-1] = p.buf.Len()
+	fmt.Fprintf(&p.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+func (p *pdfWriter) stream(content []byte) (int, error) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(content); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	p.offsets = append(p.offsets, p.buf.Len())
+	id := len(p.offsets)
+	fmt.Fprintf(&p.buf, "%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", id, compressed.Len())
+	p.buf.Write(compressed.Bytes())
+	p.buf.WriteString("\nendstream\nendobj\n")
+	return id, nil
+}
+
+func (p *pdfWriter) finish(w io.Writer, root int) error {
+	xref := p.buf.Len()
+	fmt.Fprintf(&p.buf, "xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1)
+	for _, offset := range p.offsets {
+		fmt.Fprintf(&p.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&p.buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, root, xref)
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
+
+// pdfFont 写入宋体（STSong-Light）字体对象。该字体为 PDF 阅读器内置的中文字体，无需嵌入；
+// ASCII 字符按半角宽度排版，与 runeWidthEm 一致
+func (p *pdfWriter) pdfFont() int {
+	descriptor := p.object("<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] " +
+		"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>")
+	cidFont := p.object(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor %d 0 R "+
+		"/DW 1000 /W [1 95 500 814 939 500] >>", descriptor))
+	return p.object(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light-UniGB-UCS2-H "+
+		"/Encoding /UniGB-UCS2-H /DescendantFonts [%d 0 R] >>", cidFont))
+}
+
+// drawLabel 将标签绘制到页面内容中，(left, top) 为标签左上角在页面上的位置，pageHeight 用于换算 PDF 自下而上的坐标
+func drawLabel(b *strings.Builder, l layout, left, top, pageHeight float64) {
+	y := func(mm float64) float64 { return mmToPt(pageHeight - top - mm) }
+	if len(l.Code) > 0 {
+		module := l.CodeSide / float64(len(l.Code))
+		for row, cells := range l.Code {
+			// 合并同一行连续的深色模块，减少矩形数量
+			for col := 0; col < len(cells); col++ {
+				if !cells[col] {
+					continue
+				}
+				end := col
+				for end+1 < len(cells) && cells[end+1] {
+					end++
+				}
+				fmt.Fprintf(b, "%s %s %s %s re\n",
+					pdfNum(mmToPt(left+l.CodeX+float64(col)*module)), pdfNum(y(l.CodeY+float64(row+1)*module)),
+					pdfNum(mmToPt(float64(end-col+1)*module)), pdfNum(mmToPt(module)))
+				col = end
+			}
+		}
+		b.WriteString("f\n")
+	}
+	for _, text := range l.Texts {
+		// 基线位于文字顶端下方一个上升高度处
+		fmt.Fprintf(b, "BT /F1 %s Tf %s %s Td %s Tj ET\n",
+			pdfNum(mmToPt(text.Size)), pdfNum(mmToPt(left+text.X)), pdfNum(y(text.Y+text.Size*0.88)), pdfHexText(text.Text))
+	}
+}
+
+// renderPDF 整页模板按行优先排满每页；单张模板每页一个标签，页面大小即标签大小
+func renderPDF(w io.Writer, tpl Template, layouts []layout, opts RenderOptions) error {
+	pageWidth, pageHeight := tpl.WidthMM, tpl.HeightMM
+	if sheet := tpl.Sheet; sheet != nil {
+		pageWidth, pageHeight = sheet.WidthMM, sheet.HeightMM
+	}
+
+	slot := 0
+	if tpl.Sheet != nil {
+		slot = opts.Skip % (tpl.Sheet.Columns * tpl.Sheet.Rows)
+	}
+	var page *strings.Builder
+	var contents []*strings.Builder
+	for _, l := range layouts {
+		for range opts.Copies {
+			left, top := 0.0, 0.0
+			if sheet := tpl.Sheet; sheet != nil {
+				perPage := sheet.Columns * sheet.Rows
+				if page == nil || slot == perPage {
+					if page != nil {
+						slot = 0
+					}
+					page = &strings.Builder{}
+					contents = append(contents, page)
+				}
+				col, row := slot%sheet.Columns, slot/sheet.Columns
+				left = sheet.MarginLeftMM + float64(col)*(tpl.WidthMM+sheet.GapXMM)
+				top = sheet.MarginTopMM + float64(row)*(tpl.HeightMM+sheet.GapYMM)
+				slot++
+			} else {
+				page = &strings.Builder{}
+				contents = append(contents, page)
+			}
+			page.WriteString("0 g\n")
+			drawLabel(page, l, left, top, pageHeight)
+		}
+	}
+	if len(contents) == 0 {
+		contents = append(contents, &strings.Builder{})
+	}
+
+	var p pdfWriter
+	p.buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+	font := p.pdfFont()
+	pagesID := p.reserve()
+	kids := make([]string, 0, len(contents))
+	for _, content := range contents {
+		stream, err := p.stream([]byte(content.String()))
+		if err != nil {
+			return err
+		}
+		id := p.object(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] "+
+			"/Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesID, pdfNum(mmToPt(pageWidth)), pdfNum(mmToPt(pageHeight)), font, stream))
+		kids = append(kids, fmt.Sprintf("%d 0 R", id))
+	}
+	p.fill(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	root := p.object(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+	return p.finish(w, root)
+}
@@ -0,0 +1,151 @@
+package label
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// 条码类型；标签模板只支持二维的 QR 与 DataMatrix，Code128 一维码只用于单独生成条码图片
+const (
+	SymbologyQR         = "qr"
+	SymbologyDataMatrix = "datamatrix"
+	SymbologyCode128    = "code128"
+)
+
+// Sheet 整页标签纸（如 A4 不干胶）的排版，标签按行优先从左上角开始排列
+type Sheet struct {
+	WidthMM      float64 `json:"width_mm"`
+	HeightMM     float64 `json:"height_mm"`
+	Columns      int     `json:"columns"`
+	Rows         int     `json:"rows"`
+	MarginLeftMM float64 `json:"margin_left_mm"`
+	MarginTopMM  float64 `json:"margin_top_mm"`
+	GapXMM       float64 `json:"gap_x_mm"`
+	GapYMM       float64 `json:"gap_y_mm"`
+}
+
+// Template 标签模板。条码固定在左侧（边长取内容区高度，不超过内容区宽度的 45%），右侧依次为标题与 Fields 文本行
+type Template struct {
+	Name     string  `json:"name"`
+	Title    string  `json:"title"`
+	WidthMM  float64 `json:"width_mm"`
+	HeightMM float64 `json:"height_mm"`
+	// PaddingMM 标签内边距
+	PaddingMM float64 `json:"padding_mm"`
+	// GapMM 热敏标签之间的间隙（TSPL 的 GAP），整页模板忽略
+	GapMM     float64 `json:"gap_mm"`
+	Symbology string  `json:"symbology"`
+	// Fields 元件标签标题（系统编号）之下的文本行，每行为一个字段或用 + 连接的多个字段（以空格拼接），见 FieldNames
+	Fields []string `json:"fields"`
+	// Sheet 非空时为整页标签纸，只能输出 PDF
+	Sheet *Sheet `json:"sheet,omitempty"`
+}
+
+// FieldNames 元件标签可用的文本字段
+var FieldNames = []string{"name", "model", "manufacturer", "value", "package", "location", "category", "supplier_part_number"}
+
+// defaultTemplates 内置模板：常见热敏标签尺寸与 A4 不干胶标签纸
+var defaultTemplates = []Template{
+	{Name: "40x30", Title: "热敏 40×30mm", WidthMM: 40, HeightMM: 30, PaddingMM: 2, GapMM: 2, Symbology: SymbologyQR,
+		Fields: []string{"name", "value+package", "location"}},
+	{Name: "50x25", Title: "热敏 50×25mm", WidthMM: 50, HeightMM: 25, PaddingMM: 1.5, GapMM: 2, Symbology: SymbologyQR,
+		Fields: []string{"name", "value+package", "location"}},
+	{Name: "60x40", Title: "热敏 60×40mm", WidthMM: 60, HeightMM: 40, PaddingMM: 2, GapMM: 2, Symbology: SymbologyQR,
+		Fields: []string{"name", "value+package", "manufacturer", "location"}},
+	{Name: "a4-3x8", Title: "A4 3×8（70×37mm）", WidthMM: 70, HeightMM: 37, PaddingMM: 3, Symbology: SymbologyQR,
+		Fields: []string{"name", "value+package", "manufacturer", "location"},
+		Sheet:  &Sheet{WidthMM: 210, HeightMM: 297, Columns: 3, Rows: 8, MarginTopMM: 0.5}},
+	{Name: "a4-2x7", Title: "A4 2×7（99.1×38.1mm）", WidthMM: 99.1, HeightMM: 38.1, PaddingMM: 3, Symbology: SymbologyQR,
+		Fields: []string{"name", "value+package", "manufacturer", "location"},
+		Sheet:  &Sheet{WidthMM: 210, HeightMM: 297, Columns: 2, Rows: 7, MarginLeftMM: 4.65, MarginTopMM: 15.15, GapXMM: 2.5}},
+}
+
+// DefaultTemplates 返回内置模板的副本
+func DefaultTemplates() []Template {
+	templates := make([]Template, len(defaultTemplates))
+	for i, tpl := range defaultTemplates {
+		templates[i] = tpl.clone()
+	}
+	return templates
+}
+
+func (t Template) clone() Template {
+	t.Fields = slices.Clone(t.Fields)
+	if t.Sheet != nil {
+		sheet := *t.Sheet
+		t.Sheet = &sheet
+	}
+	return t
+}
+
+// Validate 校验模板尺寸、条码类型、字段与整页排版
+func (t Template) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("模板名称不能为空")
+	}
+	if t.WidthMM <= 0 || t.HeightMM <= 0 || t.PaddingMM < 0 || t.GapMM < 0 {
+		return fmt.Errorf("模板 %s：尺寸必须为正数", t.Name)
+	}
+	if t.PaddingMM*2 >= t.HeightMM || t.PaddingMM*2 >= t.WidthMM {
+		return fmt.Errorf("模板 %s：内边距过大", t.Name)
+	}
+	if t.Symbology != SymbologyQR && t.Symbology != SymbologyDataMatrix {
+		return fmt.Errorf("模板 %s：条码类型仅支持 %s 或 %s", t.Name, SymbologyQR, SymbologyDataMatrix)
+	}
+	for _, line := range t.Fields {
+		for field := range strings.SplitSeq(line, "+") {
+			if !slices.Contains(FieldNames, strings.TrimSpace(field)) {
+				return fmt.Errorf("模板 %s：不支持的字段 %q，可用字段：%s", t.Name, field, strings.Join(FieldNames, ", "))
+			}
+		}
+	}
+	if sheet := t.Sheet; sheet != nil {
+		if sheet.Columns <= 0 || sheet.Rows <= 0 || sheet.MarginLeftMM < 0 || sheet.MarginTopMM < 0 || sheet.GapXMM < 0 || sheet.GapYMM < 0 {
+			return fmt.Errorf("模板 %s：整页排版参数无效", t.Name)
+		}
+		width := sheet.MarginLeftMM + float64(sheet.Columns)*t.WidthMM + float64(sheet.Columns-1)*sheet.GapXMM
+		height := sheet.MarginTopMM + float64(sheet.Rows)*t.HeightMM + float64(sheet.Rows-1)*sheet.GapYMM
+		// 允许 0.5mm 的舍入误差
+		if width > sheet.WidthMM+0.5 || height > sheet.HeightMM+0.5 {
+			return fmt.Errorf("模板 %s：标签超出纸张范围", t.Name)
+		}
+	}
+	return nil
+}
+
+// LoadTemplates 返回内置模板，并合并 path 指定的 JSON 模板数组（同名覆盖内置模板，其余追加）；path 为空时只返回内置模板
+func LoadTemplates(path string) ([]Template, error) {
+	templates := DefaultTemplates()
+	if strings.TrimSpace(path) == "" {
+		return templates, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取标签模板文件失败: %w", err)
+	}
+	var custom []Template
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("解析标签模板文件失败: %w", err)
+	}
+	for _, tpl := range custom {
+		tpl.Name = strings.TrimSpace(tpl.Name)
+		if tpl.Symbology == "" {
+			tpl.Symbology = SymbologyQR
+		}
+		if tpl.Title == "" {
+			tpl.Title = tpl.Name
+		}
+		if err := tpl.Validate(); err != nil {
+			return nil, err
+		}
+		if i := slices.IndexFunc(templates, func(t Template) bool { return t.Name == tpl.Name }); i >= 0 {
+			templates[i] = tpl
+		} else {
+			templates = append(templates, tpl)
+		}
+	}
+	return templates, nil
+}
@@ -0,0 +1,87 @@
+package label
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// dots 毫米换算为打印机点数
+func dots(mm float64, dpi int) int {
+	return int(math.Round(mm * float64(dpi) / 25.4))
+}
+
+// moduleDots 二维码每个模块的点数，使二维码尽量铺满预留区域
+func moduleDots(l layout, dpi int) int {
+	return min(max(dots(l.CodeSide, dpi)/len(l.Code), 1), 10)
+}
+
+// zplEscaper 配合 ^FH 转义字段中的 ZPL 控制字符
+var zplEscaper = strings.NewReplacer("_", "_5F", "^", "_5E", "~", "_7E")
+
+// renderZPL 每个标签一个 ^XA…^XZ 格式，份数用 ^PQ。文字默认用内置可缩放字体 0（只含西文），
+// font 为打印机上的字体文件（如 E:SIMSUN.TTF）时以 ^A@ 调用，用于打印中文
+func renderZPL(w io.Writer, tpl Template, layouts []layout, opts RenderOptions, font string) error {
+	bw := bufio.NewWriter(w)
+	for _, l := range layouts {
+		fmt.Fprintf(bw, "^XA\n^CI28\n^PW%d\n^LL%d\n^PQ%d\n", dots(tpl.WidthMM, opts.DPI), dots(tpl.HeightMM, opts.DPI), opts.Copies)
+		if len(l.Code) > 0 {
+			x, y := dots(l.CodeX, opts.DPI), dots(l.CodeY, opts.DPI)
+			module := moduleDots(l, opts.DPI)
+			if tpl.Symbology == SymbologyDataMatrix {
+				fmt.Fprintf(bw, "^FO%d,%d^BXN,%d,200^FH^FD%s^FS\n", x, y, module, zplEscaper.Replace(l.label.Code))
+			} else {
+				fmt.Fprintf(bw, "^FO%d,%d^BQN,2,%d^FH^FDMA,%s^FS\n", x, y, module, zplEscaper.Replace(l.label.Code))
+			}
+		}
+		for _, text := range l.Texts {
+			height := dots(text.Size, opts.DPI)
+			fontCommand := fmt.Sprintf("^A0N,%d,%d", height, height)
+			if font != "" && font != "0" {
+				fontCommand = fmt.Sprintf("^A@N,%d,%d,%s", height, height, font)
+			}
+			fmt.Fprintf(bw, "^FO%d,%d%s^FH^FD%s^FS\n", dots(text.X, opts.DPI), dots(text.Y, opts.DPI), fontCommand, zplEscaper.Replace(text.Text))
+		}
+		bw.WriteString("^XZ\n")
+	}
+	return bw.Flush()
+}
+
+// tsplFontDots TSPL 位图字体的字高（点），TSS24.BF2 等中文字体均为 24 点，文字按倍数放大
+const tsplFontDots = 24
+
+// tsplQuote 转义 TSPL 字符串中的双引号
+func tsplQuote(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, `\["]`) + `"`
+}
+
+// renderTSPL 先输出纸张尺寸与间隙，再为每个标签输出 CLS…PRINT，份数用 PRINT 的第二个参数
+func renderTSPL(w io.Writer, tpl Template, layouts []layout, opts RenderOptions, font string) error {
+	if font == "" {
+		font = "TSS24.BF2"
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "SIZE %s mm,%s mm\nGAP %s mm,0 mm\nDIRECTION 1\nCODEPAGE UTF-8\n",
+		pdfNum(tpl.WidthMM), pdfNum(tpl.HeightMM), pdfNum(tpl.GapMM))
+	for _, l := range layouts {
+		bw.WriteString("CLS\n")
+		if len(l.Code) > 0 {
+			x, y := dots(l.CodeX, opts.DPI), dots(l.CodeY, opts.DPI)
+			if tpl.Symbology == SymbologyDataMatrix {
+				side := dots(l.CodeSide, opts.DPI)
+				fmt.Fprintf(bw, "DMATRIX %d,%d,%d,%d,%s\n", x, y, side, side, tsplQuote(l.label.Code))
+			} else {
+				fmt.Fprintf(bw, "QRCODE %d,%d,M,%d,A,0,%s\n", x, y, moduleDots(l, opts.DPI), tsplQuote(l.label.Code))
+			}
+		}
+		for _, text := range l.Texts {
+			scale := min(max(int(math.Round(float64(dots(text.Size, opts.DPI))/tsplFontDots)), 1), 10)
+			fmt.Fprintf(bw, "TEXT %d,%d,%s,0,%d,%d,%s\n",
+				dots(text.X, opts.DPI), dots(text.Y, opts.DPI), tsplQuote(font), scale, scale, tsplQuote(text.Text))
+		}
+		fmt.Fprintf(bw, "PRINT 1,%d\n", opts.Copies)
+	}
+	return bw.Flush()
+}
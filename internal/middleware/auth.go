@@ -1,32 +1,59 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/Rehtt/hamster-bin/internal/auth"
 	"github.com/Rehtt/hamster-bin/internal/config"
+	"github.com/Rehtt/hamster-bin/internal/repository"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 const usernameContextKey = "username"
 
+// CurrentUsername 返回鉴权中间件写入的当前操作人；鉴权关闭或未登录时返回空字符串
+func CurrentUsername(c *gin.Context) string {
+	return c.GetString(usernameContextKey)
+}
+
+// ParseSession 解析登录 Cookie 并按二次验证状态校验 token；未登录或 token 已失效时返回 auth.ErrInvalidToken
+func ParseSession(c *gin.Context, cfg *config.Config, twoFactorRepo *repository.TwoFactorRepository) (*auth.Claims, error) {
+	token, err := c.Cookie(auth.CookieName)
+	if err != nil || token == "" {
+		return nil, auth.ErrInvalidToken
+	}
+	claims, err := auth.ParseToken(token, cfg.JWTSecret)
+	if err != nil {
+		return nil, err
+	}
+	valid, err := twoFactorRepo.SessionTokenValid(claims.Username, claims.Verified, cfg.TwoFactorRequired)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, auth.ErrInvalidToken
+	}
+	return claims, nil
+}
+
 // AuthMiddleware 校验 JWT Cookie；鉴权关闭时直接放行
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+func AuthMiddleware(cfg *config.Config, db *gorm.DB) gin.HandlerFunc {
+	twoFactorRepo := repository.NewTwoFactorRepository(db)
 	return func(c *gin.Context) {
 		if !cfg.IsAuthEnabled() {
 			c.Next()
 			return
 		}
 
-		token, err := c.Cookie(auth.CookieName)
-		if err != nil || token == "" {
+		claims, err := ParseSession(c, cfg, twoFactorRepo)
+		if errors.Is(err, auth.ErrInvalidToken) {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "未登录或登录已过期"})
 			return
 		}
-
-		claims, err := auth.ParseToken(token, cfg.JWTSecret)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "未登录或登录已过期"})
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "校验登录状态失败"})
 			return
 		}
 
@@ -7,7 +7,10 @@ import (
 
 	"github.com/Rehtt/hamster-bin/internal/auth"
 	"github.com/Rehtt/hamster-bin/internal/config"
+	"github.com/Rehtt/hamster-bin/internal/models"
 	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
 )
 
 func init() {
@@ -23,13 +26,25 @@ func testAuthConfig() *config.Config {
 	}
 }
 
+func testAuthDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.TwoFactorAuth{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
 func TestAuthMiddlewareDisabled(t *testing.T) {
 	cfg := testAuthConfig()
 	cfg.AdminUsername = ""
 	cfg.AdminPassword = ""
 
 	r := gin.New()
-	r.GET("/protected", AuthMiddleware(cfg), func(c *gin.Context) {
+	r.GET("/protected", AuthMiddleware(cfg, testAuthDB(t)), func(c *gin.Context) {
 		c.Status(http.StatusOK)
 	})
 
@@ -45,7 +60,7 @@ func TestAuthMiddlewareDisabled(t *testing.T) {
 func TestAuthMiddlewareMissingCookie(t *testing.T) {
 	cfg := testAuthConfig()
 	r := gin.New()
-	r.GET("/protected", AuthMiddleware(cfg), func(c *gin.Context) {
+	r.GET("/protected", AuthMiddleware(cfg, testAuthDB(t)), func(c *gin.Context) {
 		c.Status(http.StatusOK)
 	})
 
@@ -66,7 +81,7 @@ func TestAuthMiddlewareValidCookie(t *testing.T) {
 	}
 
 	r := gin.New()
-	r.GET("/protected", AuthMiddleware(cfg), func(c *gin.Context) {
+	r.GET("/protected", AuthMiddleware(cfg, testAuthDB(t)), func(c *gin.Context) {
 		c.Status(http.StatusOK)
 	})
 
@@ -83,7 +98,7 @@ func TestAuthMiddlewareValidCookie(t *testing.T) {
 func TestAuthMiddlewareInvalidCookie(t *testing.T) {
 	cfg := testAuthConfig()
 	r := gin.New()
-	r.GET("/protected", AuthMiddleware(cfg), func(c *gin.Context) {
+	r.GET("/protected", AuthMiddleware(cfg, testAuthDB(t)), func(c *gin.Context) {
 		c.Status(http.StatusOK)
 	})
 
@@ -96,3 +111,50 @@ func TestAuthMiddlewareInvalidCookie(t *testing.T) {
 		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
 	}
 }
+
+func TestAuthMiddlewareTwoFactor(t *testing.T) {
+	cfg := testAuthConfig()
+	db := testAuthDB(t)
+	if err := db.Create(&models.TwoFactorAuth{Username: "admin", Secret: "SECRET", Enabled: true}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.GET("/protected", AuthMiddleware(cfg, db), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	request := func(token string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+		req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: token})
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 启用二次验证前签发的会话失效
+	legacy, err := auth.IssueToken("admin", cfg.JWTSecret, cfg.JWTExpireHours)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := request(legacy); code != http.StatusUnauthorized {
+		t.Fatalf("legacy token status = %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	verified, err := auth.IssueVerifiedToken("admin", cfg.JWTSecret, cfg.JWTExpireHours)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := request(verified); code != http.StatusOK {
+		t.Fatalf("verified token status = %d, want %d", code, http.StatusOK)
+	}
+
+	// 策略强制二次验证时，未绑定的账号也不能使用旧会话
+	cfg.TwoFactorRequired = true
+	other, err := auth.IssueToken("alice", cfg.JWTSecret, cfg.JWTExpireHours)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := request(other); code != http.StatusUnauthorized {
+		t.Fatalf("unverified token under policy status = %d, want %d", code, http.StatusUnauthorized)
+	}
+}
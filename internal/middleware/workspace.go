@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Rehtt/hamster-bin/internal/config"
+	"github.com/Rehtt/hamster-bin/internal/repository"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// WorkspaceHeader 请求头方式选择工作区
+	WorkspaceHeader = "X-Workspace-ID"
+	// WorkspaceCookieName 前端记住当前工作区的 Cookie，图片与导出等直接链接也会携带
+	WorkspaceCookieName = "hamster_workspace"
+
+	workspaceIDContextKey   = "workspace_id"
+	workspaceRoleContextKey = "workspace_role"
+)
+
+var errNotWorkspaceMember = errors.New("无权访问该工作区")
+
+// CurrentWorkspaceID 返回工作区中间件解析出的工作区；未经过中间件时返回默认工作区
+func CurrentWorkspaceID(c *gin.Context) uint {
+	if id, ok := c.Get(workspaceIDContextKey); ok {
+		return id.(uint)
+	}
+	return repository.DefaultWorkspaceID
+}
+
+// CurrentWorkspaceRole 返回当前用户在所选工作区中的角色
+func CurrentWorkspaceRole(c *gin.Context) string {
+	return c.GetString(workspaceRoleContextKey)
+}
+
+// IsInstanceAdmin 鉴权关闭或当前用户为 ADMIN_USERNAME 时视为实例管理员，拥有所有工作区的 owner 权限
+func IsInstanceAdmin(cfg *config.Config, c *gin.Context) bool {
+	return !cfg.IsAuthEnabled() || CurrentUsername(c) == cfg.AdminUsername
+}
+
+// ResolveWorkspaceRole 计算当前用户在指定工作区中的角色；非成员返回错误
+func ResolveWorkspaceRole(cfg *config.Config, repo *repository.WorkspaceRepository, c *gin.Context, workspaceID uint) (string, error) {
+	if IsInstanceAdmin(cfg, c) {
+		return repository.WorkspaceRoleOwner, nil
+	}
+	role, err := repo.GetRole(workspaceID, CurrentUsername(c))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", errNotWorkspaceMember
+	}
+	return role, err
+}
+
+func requestedWorkspaceID(c *gin.Context) (uint, bool, error) {
+	raw := strings.TrimSpace(c.GetHeader(WorkspaceHeader))
+	if raw == "" {
+		raw = strings.TrimSpace(c.Query("workspace_id"))
+	}
+	if raw == "" {
+		raw, _ = c.Cookie(WorkspaceCookieName)
+		raw = strings.TrimSpace(raw)
+	}
+	if raw == "" {
+		return 0, false, nil
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil || id == 0 {
+		return 0, true, errors.New("无效的工作区 ID")
+	}
+	return uint(id), true, nil
+}
+
+// WorkspaceMiddleware 解析当前工作区（请求头 X-Workspace-ID > query workspace_id > Cookie hamster_workspace），
+// 校验成员身份，viewer 角色只允许只读请求。未指定时使用默认工作区，非管理员则使用其第一个可访问的工作区。
+func WorkspaceMiddleware(cfg *config.Config, db *gorm.DB) gin.HandlerFunc {
+	return workspaceMiddleware(cfg, db, repository.WorkspaceRoleEditor)
+}
+
+// WorkspaceMemberMiddleware 同 WorkspaceMiddleware，但 viewer 也可发起写请求，由 handler 按角色限制可写的范围
+// （如只读成员只能管理自己的私有保存搜索）
+func WorkspaceMemberMiddleware(cfg *config.Config, db *gorm.DB) gin.HandlerFunc {
+	return workspaceMiddleware(cfg, db, repository.WorkspaceRoleViewer)
+}
+
+// workspaceMiddleware writeRole 为发起非只读请求所需的最低角色
+func workspaceMiddleware(cfg *config.Config, db *gorm.DB, writeRole string) gin.HandlerFunc {
+	repo := repository.NewWorkspaceRepository(db)
+	return func(c *gin.Context) {
+		workspaceID, specified, err := requestedWorkspaceID(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !specified {
+			workspaceID = repository.DefaultWorkspaceID
+			if !IsInstanceAdmin(cfg, c) {
+				accessible, err := repo.ListAccessible(CurrentUsername(c), false)
+				if err != nil {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "获取工作区失败"})
+					return
+				}
+				if len(accessible) == 0 {
+					c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "当前用户未加入任何工作区"})
+					return
+				}
+				workspaceID = accessible[0].ID
+			}
+		}
+
+		if _, err := repo.GetByID(workspaceID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "工作区不存在"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "获取工作区失败"})
+			return
+		}
+
+		role, err := ResolveWorkspaceRole(cfg, repo, c, workspaceID)
+		if err != nil {
+			if errors.Is(err, errNotWorkspaceMember) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "获取工作区角色失败"})
+			return
+		}
+
+		readOnly := c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+		if !readOnly && !repository.WorkspaceRoleAllows(role, writeRole) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "当前工作区角色为只读"})
+			return
+		}
+
+		c.Set(workspaceIDContextKey, workspaceID)
+		c.Set(workspaceRoleContextKey, role)
+		c.Next()
+	}
+}
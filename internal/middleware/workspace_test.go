@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/Rehtt/hamster-bin/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestWorkspaceMiddlewareRoles(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Workspace{}, &models.WorkspaceMember{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	repo := repository.NewWorkspaceRepository(db)
+	if err := repo.EnsureDefault(); err != nil {
+		t.Fatalf("EnsureDefault: %v", err)
+	}
+	club := models.Workspace{Name: "机器人社"}
+	if err := repo.Create(&club, "admin"); err != nil {
+		t.Fatalf("create workspace: %v", err)
+	}
+	if _, err := repo.SetMember(club.ID, "viewer", repository.WorkspaceRoleViewer); err != nil {
+		t.Fatalf("SetMember: %v", err)
+	}
+
+	cfg := testAuthConfig()
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(usernameContextKey, c.GetHeader("X-Test-User"))
+	}, WorkspaceMiddleware(cfg, db))
+	handler := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"workspace_id": CurrentWorkspaceID(c), "role": CurrentWorkspaceRole(c)})
+	}
+	r.GET("/items", handler)
+	r.POST("/items", handler)
+
+	tests := []struct {
+		name      string
+		method    string
+		user      string
+		workspace string
+		want      int
+	}{
+		{"viewer defaults to own workspace", http.MethodGet, "viewer", "", http.StatusOK},
+		{"viewer cannot write", http.MethodPost, "viewer", "", http.StatusForbidden},
+		{"non-member is rejected", http.MethodGet, "viewer", "1", http.StatusForbidden},
+		{"admin can access every workspace", http.MethodPost, "admin", "1", http.StatusOK},
+		{"unknown workspace", http.MethodGet, "admin", "99", http.StatusNotFound},
+		{"invalid workspace id", http.MethodGet, "admin", "abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, "/items", nil)
+			req.Header.Set("X-Test-User", tt.user)
+			if tt.workspace != "" {
+				req.Header.Set(WorkspaceHeader, tt.workspace)
+			}
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+	// WorkspaceMemberMiddleware 允许 viewer 写入，由 handler 自行限制
+	member := gin.New()
+	member.Use(func(c *gin.Context) {
+		c.Set(usernameContextKey, c.GetHeader("X-Test-User"))
+	}, WorkspaceMemberMiddleware(cfg, db))
+	member.POST("/items", handler)
+	for user, want := range map[string]int{"viewer": http.StatusOK, "stranger": http.StatusForbidden} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/items", nil)
+		req.Header.Set("X-Test-User", user)
+		req.Header.Set(WorkspaceHeader, strconv.FormatUint(uint64(club.ID), 10))
+		member.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Fatalf("member middleware %s status = %d, want %d, body = %s", user, w.Code, want, w.Body.String())
+		}
+	}
+}
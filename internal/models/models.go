@@ -2,53 +2,107 @@ package models
 
 import (
 	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/searchkey"
+	"gorm.io/gorm"
 )
 
+// Workspace 工作区表，每个工作区拥有独立的分类、供应商、元件与编号序列
+type Workspace struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"not null;uniqueIndex;size:100" json:"name"`
+	Description string    `gorm:"size:500" json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// User 登录账号表，由实例管理员创建；ADMIN_USERNAME 对应的管理员只由环境变量配置，不在此表中
+type User struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Username     string    `gorm:"not null;uniqueIndex;size:100" json:"username"`
+	PasswordHash string    `gorm:"not null;size:100" json:"-"` // bcrypt 哈希
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// WorkspaceMember 工作区成员表
+type WorkspaceMember struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	WorkspaceID uint      `gorm:"not null;uniqueIndex:idx_workspace_members_workspace_user" json:"workspace_id"`
+	Username    string    `gorm:"not null;size:100;uniqueIndex:idx_workspace_members_workspace_user;index" json:"username"`
+	Role        string    `gorm:"not null;size:20" json:"role"` // owner / editor / viewer
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
 // Category 分类表
 type Category struct {
-	ID       uint   `gorm:"primaryKey" json:"id"`
-	Name     string `gorm:"not null;size:100" json:"name"`
-	ParentID *uint  `json:"parent_id,omitempty"` // 父分类ID，支持树形结构
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	WorkspaceID uint   `gorm:"not null;default:1;index" json:"workspace_id"`
+	Name        string `gorm:"not null;size:100" json:"name"`
+	ParentID    *uint  `json:"parent_id,omitempty"` // 父分类ID，支持树形结构
 }
 
 // Supplier 供应商表
 type Supplier struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `gorm:"not null;uniqueIndex;size:100" json:"name"` // 供应商名称
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                 uint      `gorm:"primaryKey" json:"id"`
+	WorkspaceID        uint      `gorm:"not null;default:1;uniqueIndex:idx_suppliers_workspace_name" json:"workspace_id"`
+	Name               string    `gorm:"not null;uniqueIndex:idx_suppliers_workspace_name;size:100" json:"name"` // 供应商名称（工作区内唯一）
+	ContactName        string    `gorm:"size:100" json:"contact_name"`                                           // 联系人
+	Phone              string    `gorm:"size:50" json:"phone"`                                                   // 联系电话
+	Email              string    `gorm:"size:100" json:"email"`                                                  // 联系邮箱
+	Website            string    `gorm:"size:255" json:"website"`                                                // 官网
+	Notes              string    `gorm:"type:text" json:"notes"`                                                 // 备注
+	ProductURLTemplate string    `gorm:"size:500" json:"product_url_template"`                                   // 商品链接模板，{sku} 替换为供应商料号
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
 }
 
 // Component 元件表
 type Component struct {
 	ID                 uint      `gorm:"primaryKey" json:"id"`
+	WorkspaceID        uint      `gorm:"not null;default:1;uniqueIndex:idx_components_workspace_number" json:"workspace_id"`
 	CategoryID         uint      `gorm:"not null;index" json:"category_id"`
 	Category           *Category `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
-	ComponentNumber    *string   `gorm:"uniqueIndex;size:50" json:"component_number,omitempty"` // 系统管理的元件编号
-	Name               string    `gorm:"not null;size:200" json:"name"`                         // 元件名称/型号
-	Model              string    `gorm:"size:100" json:"model,omitempty"`                       // 厂家型号
-	Manufacturer       string    `gorm:"size:100" json:"manufacturer,omitempty"`                // 制造商
-	Value              string    `gorm:"size:100" json:"value,omitempty"`                       // 参数值(如: 10k, 100nF)
-	Package            string    `gorm:"size:50" json:"package,omitempty"`                      // 封装形式
-	SupplierID         *uint     `gorm:"index" json:"supplier_id,omitempty"`                    // 供应商ID
-	Supplier           *Supplier `gorm:"foreignKey:SupplierID" json:"supplier,omitempty"`       // 供应商
-	SupplierPartNumber string    `gorm:"size:100" json:"supplier_part_number,omitempty"`        // 供应商料号
-	Description        string    `gorm:"type:text" json:"description,omitempty"`                // 描述
-	StockQuantity      int       `gorm:"default:0" json:"stock_quantity"`                       // 库存数量
-	UnitPriceMicro     int64     `gorm:"default:0" json:"unit_price_micro,omitempty"`           // 参考单价（微元，1元=1,000,000）
-	Location           string    `gorm:"size:100" json:"location,omitempty"`                    // 存放位置
+	ComponentNumber    *string   `gorm:"uniqueIndex:idx_components_workspace_number;size:50" json:"component_number,omitempty"` // 系统管理的元件编号（工作区内唯一）
+	Name               string    `gorm:"not null;size:200" json:"name"`                                                         // 元件名称/型号
+	Model              string    `gorm:"size:100" json:"model,omitempty"`                                                       // 厂家型号
+	Manufacturer       string    `gorm:"size:100" json:"manufacturer,omitempty"`                                                // 制造商
+	Value              string    `gorm:"size:100" json:"value,omitempty"`                                                       // 参数值(如: 10k, 100nF)
+	Package            string    `gorm:"size:50" json:"package,omitempty"`                                                      // 封装形式
+	SupplierID         *uint     `gorm:"index" json:"supplier_id,omitempty"`                                                    // 供应商ID
+	Supplier           *Supplier `gorm:"foreignKey:SupplierID" json:"supplier,omitempty"`                                       // 供应商
+	SupplierPartNumber string    `gorm:"size:100" json:"supplier_part_number,omitempty"`                                        // 供应商料号
+	Description        string    `gorm:"type:text" json:"description,omitempty"`                                                // 描述
+	StockQuantity      int       `gorm:"default:0" json:"stock_quantity"`                                                       // 库存数量
+	UnitPriceMicro     int64     `gorm:"default:0" json:"unit_price_micro,omitempty"`                                           // 参考单价（微元，1元=1,000,000）
+	Location           string    `gorm:"size:100" json:"location,omitempty"`                                                    // 存放位置
 	DatasheetURL       string    `gorm:"size:500" json:"datasheet_url,omitempty"`
 	ImageURL           string    `gorm:"size:500" json:"image_url,omitempty"`
+	SearchKeys         string    `gorm:"type:text" json:"-"`                      // 搜索键（拼音、型号三元组），保存时由 BeforeSave 生成
+	ABCClass           string    `gorm:"size:1;index" json:"abc_class,omitempty"` // ABC 分类（A/B/C），由定时任务按出库消耗计算，未计算时为空
 	CreatedAt          time.Time `json:"created_at"`
 	UpdatedAt          time.Time `json:"updated_at"`
+
+	Tags     []string           `gorm:"-" json:"tags"`               // 标签，读取时由 ComponentTag 填充；创建与更新时非 nil 则整体替换
+	Forecast *ComponentForecast `gorm:"-" json:"forecast,omitempty"` // 消耗预测，列表与导出时按需填充
+}
+
+// ComponentTag 元件标签表，每行为元件的一个标签（同一元件内不区分大小写唯一）
+type ComponentTag struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ComponentID uint      `gorm:"not null;uniqueIndex:idx_component_tags_component_name" json:"component_id"`
+	Name        string    `gorm:"not null;size:50;uniqueIndex:idx_component_tags_component_name;index" json:"name"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 // PreStock 预入库记录表
 type PreStock struct {
 	ID                 uint       `gorm:"primaryKey" json:"id"`
+	WorkspaceID        uint       `gorm:"not null;default:1;uniqueIndex:idx_pre_stocks_workspace_number" json:"workspace_id"`
 	CategoryID         uint       `gorm:"not null;index" json:"category_id"`
 	Category           *Category  `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
-	ComponentNumber    *string    `gorm:"uniqueIndex;size:50" json:"component_number,omitempty"`
+	ComponentNumber    *string    `gorm:"uniqueIndex:idx_pre_stocks_workspace_number;size:50" json:"component_number,omitempty"`
 	Name               string     `gorm:"not null;size:200" json:"name"`
 	Model              string     `gorm:"size:100" json:"model,omitempty"`
 	Manufacturer       string     `gorm:"size:100" json:"manufacturer,omitempty"`
@@ -74,18 +128,101 @@ type PreStock struct {
 // StockLog 库存变更记录表
 type StockLog struct {
 	ID              uint       `gorm:"primaryKey" json:"id"`
+	WorkspaceID     uint       `gorm:"not null;default:1;index" json:"workspace_id"`
 	ComponentID     uint       `gorm:"not null;index" json:"component_id"`
 	Component       *Component `gorm:"foreignKey:ComponentID" json:"component,omitempty"`
 	ChangeAmount    int        `gorm:"not null" json:"change_amount"`                // 正数为入库，负数为出库
 	UnitPriceMicro  int64      `gorm:"default:0" json:"unit_price_micro,omitempty"`  // 分摊单价（微元，1元=1,000,000）
 	TotalPriceCents int64      `gorm:"default:0" json:"total_price_cents,omitempty"` // 录入总价（分）
 	Reason          string     `gorm:"size:500" json:"reason,omitempty"`
+	Operator        string     `gorm:"size:100;index" json:"operator,omitempty"` // 操作人（登录用户名）；鉴权关闭时为空
 	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
 	ReversalOfID    *uint      `gorm:"index" json:"reversal_of_id,omitempty"`
+	MergedFromID    *uint      `json:"merged_from_id,omitempty"` // 合并元件时写入的记录：被合并（已删除）元件的 ID
 	CreatedAt       time.Time  `json:"created_at"`
 }
 
+// ComponentForecast 元件消耗预测，由定时任务按出库记录重新计算（派生数据，不写入备份）
+type ComponentForecast struct {
+	ComponentID         uint       `gorm:"primaryKey;autoIncrement:false" json:"component_id"`
+	WorkspaceID         uint       `gorm:"not null;default:1;index" json:"workspace_id"`
+	WindowDays          int        `gorm:"not null" json:"window_days"`                 // 实际统计的天数（元件创建晚于窗口起点时从创建日起算）
+	OutboundQuantity    int        `gorm:"not null;default:0" json:"outbound_quantity"` // 统计窗口内的出库数量
+	AvgDailyConsumption float64    `gorm:"not null;default:0" json:"avg_daily_consumption"`
+	DaysOfCover         *float64   `json:"days_of_cover"`                              // 当前库存可用天数，无消耗时为空
+	StockoutDate        *time.Time `json:"stockout_date"`                              // 预计断货日期，无消耗时为空
+	ReorderQuantity     int        `gorm:"not null;default:0" json:"reorder_quantity"` // 建议补货数量
+	ComputedAt          time.Time  `json:"computed_at"`
+}
+
+// SavedSearch 保存的元件查询（智能视图），工作区内按创建人区分；共享的对工作区全部成员可见
+type SavedSearch struct {
+	ID          uint              `gorm:"primaryKey" json:"id"`
+	WorkspaceID uint              `gorm:"not null;default:1;uniqueIndex:idx_saved_searches_workspace_owner_name" json:"workspace_id"`
+	Name        string            `gorm:"not null;size:100;uniqueIndex:idx_saved_searches_workspace_owner_name" json:"name"`
+	Owner       string            `gorm:"not null;default:'';size:100;uniqueIndex:idx_saved_searches_workspace_owner_name" json:"owner"` // 创建人（登录用户名）；鉴权关闭时为空
+	Shared      bool              `gorm:"not null;default:false" json:"shared"`
+	Params      SavedSearchParams `gorm:"type:text;serializer:json" json:"params"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// SavedSearchParams 保存的元件查询条件，字段与元件列表 query 参数同名
+type SavedSearchParams struct {
+	CategoryID           *uint  `json:"category_id,omitempty"`
+	IncludeSubcategories bool   `json:"include_subcategories,omitempty"`
+	Keyword              string `json:"keyword,omitempty"`
+	Q                    string `json:"q,omitempty"`
+	ComponentNumber      string `json:"component_number,omitempty"`
+	Name                 string `json:"name,omitempty"`
+	Model                string `json:"model,omitempty"`
+	Manufacturer         string `json:"manufacturer,omitempty"`
+	Value                string `json:"value,omitempty"`
+	Supplier             string `json:"supplier,omitempty"`
+	SupplierPartNumber   string `json:"supplier_part_number,omitempty"`
+	ABCClass             string `json:"abc_class,omitempty"` // ABC 分类，逗号分隔多个为或，如 A,B
+	SortBy               string `json:"sort_by,omitempty"`
+	SortOrder            string `json:"sort_order,omitempty"`
+	// Columns 列表显示与导出的列（取值同导出 columns），为空时使用默认列
+	Columns []string `json:"columns,omitempty"`
+}
+
+// TwoFactorAuth 账号 TOTP 二次验证配置
+type TwoFactorAuth struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	Username       string     `gorm:"not null;uniqueIndex;size:100" json:"username"`
+	Secret         string     `gorm:"not null;size:64" json:"-"`             // Base32 TOTP 密钥
+	Enabled        bool       `gorm:"not null;default:false" json:"enabled"` // 完成绑定校验后才启用
+	LastUsedStep   int64      `gorm:"default:0" json:"-"`                    // 最近一次使用的时间步，防止动态码重放
+	FailedAttempts int        `gorm:"not null;default:0" json:"-"`           // 连续校验失败次数，校验成功后清零
+	LockedAt       *time.Time `json:"-"`                                     // 最近一次因失败过多锁定的时间，此前签发的等待 token 失效
+	EnabledAt      *time.Time `json:"enabled_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TwoFactorRecoveryCode 二次验证恢复码（仅保存哈希，一次性使用）
+type TwoFactorRecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Username  string     `gorm:"not null;index;size:100" json:"username"`
+	CodeHash  string     `gorm:"not null;size:64" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
 // TableName 指定表名
+func (Workspace) TableName() string {
+	return "workspaces"
+}
+
+func (User) TableName() string {
+	return "users"
+}
+
+func (WorkspaceMember) TableName() string {
+	return "workspace_members"
+}
+
 func (Category) TableName() string {
 	return "categories"
 }
@@ -94,6 +231,17 @@ func (Component) TableName() string {
 	return "components"
 }
 
+// BeforeSave 创建或整体保存元件时重新生成搜索键；按列更新（Update/UpdateColumn）不经过此处，
+// 修改名称、型号、供应商料号或描述时需整体保存
+func (c *Component) BeforeSave(tx *gorm.DB) error {
+	c.SearchKeys = searchkey.Build(c.Name, c.Model, c.SupplierPartNumber, c.Description)
+	return nil
+}
+
+func (ComponentTag) TableName() string {
+	return "component_tags"
+}
+
 func (PreStock) TableName() string {
 	return "pre_stocks"
 }
@@ -105,3 +253,15 @@ func (Supplier) TableName() string {
 func (StockLog) TableName() string {
 	return "stock_logs"
 }
+
+func (SavedSearch) TableName() string {
+	return "saved_searches"
+}
+
+func (TwoFactorAuth) TableName() string {
+	return "two_factor_auths"
+}
+
+func (TwoFactorRecoveryCode) TableName() string {
+	return "two_factor_recovery_codes"
+}
@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Hamster Bin 自有二维码内容：HB<版本>:<类型>:<值>，如 HB1:C:HB-000001。
+// 类型 C 为元件（值为系统编号）、L 为存放位置（值为位置原文，可含冒号）、P 为预入库（值为预入库 ID）。
+// 前缀与类型均为大写字母，数字与常见编号字符可以用二维码的字母数字模式编码，码图更小
+const (
+	// HamsterBinPlatform 自有二维码在 QRCodeData.Platform 中的名称
+	HamsterBinPlatform = "HamsterBin"
+	// PayloadVersion 当前生成的二维码内容版本
+	PayloadVersion = 1
+
+	payloadPrefix = "HB"
+)
+
+// 自有二维码指向的对象类型（QRCodeData.Kind）
+const (
+	PayloadComponent = "component"
+	PayloadLocation  = "location"
+	PayloadPreStock  = "pre_stock"
+)
+
+// bareComponentNumber 只含系统编号的内容（如 HB-000001），早期元件标签的二维码只编码系统编号
+var bareComponentNumber = regexp.MustCompile(`^HB-\d+$`)
+
+var payloadKindCodes = map[string]string{
+	"C": PayloadComponent,
+	"L": PayloadLocation,
+	"P": PayloadPreStock,
+}
+
+func formatPayload(kindCode, value string) string {
+	return fmt.Sprintf("%s%d:%s:%s", payloadPrefix, PayloadVersion, kindCode, value)
+}
+
+// ComponentPayload 元件二维码内容，number 为系统编号
+func ComponentPayload(number string) string {
+	return formatPayload("C", number)
+}
+
+// LocationPayload 存放位置二维码内容
+func LocationPayload(location string) string {
+	return formatPayload("L", location)
+}
+
+// PreStockPayload 预入库二维码内容
+func PreStockPayload(id uint) string {
+	return formatPayload("P", strconv.FormatUint(uint64(id), 10))
+}
+
+// parseHamsterBinCode 解析自有二维码；只含系统编号（HB-<数字>）时视为元件，Version 为 0；
+// 不是 HB<数字>: 开头时返回 nil, nil 交给其他格式，版本不支持、类型未知或值为空时返回错误
+func parseHamsterBinCode(code string) (*QRCodeData, error) {
+	code = strings.TrimSpace(code)
+	if bareComponentNumber.MatchString(code) {
+		return &QRCodeData{Code: code, RawData: code, Platform: HamsterBinPlatform, Kind: PayloadComponent}, nil
+	}
+	rest, ok := strings.CutPrefix(code, payloadPrefix)
+	if !ok {
+		return nil, nil
+	}
+	versionText, rest, ok := strings.Cut(rest, ":")
+	version, err := strconv.Atoi(versionText)
+	if !ok || err != nil || version <= 0 {
+		return nil, nil
+	}
+	if version > PayloadVersion {
+		return nil, fmt.Errorf("不支持的 Hamster Bin 二维码版本 %d，请升级程序", version)
+	}
+
+	kindCode, value, _ := strings.Cut(rest, ":")
+	kind, ok := payloadKindCodes[kindCode]
+	if !ok {
+		return nil, fmt.Errorf("未知的 Hamster Bin 二维码类型: %s", kindCode)
+	}
+	if value == "" {
+		return nil, fmt.Errorf("Hamster Bin 二维码内容为空")
+	}
+	if kind == PayloadPreStock {
+		if id, err := strconv.ParseUint(value, 10, 32); err != nil || id == 0 {
+			return nil, fmt.Errorf("无效的预入库 ID: %s", value)
+		}
+	}
+	return &QRCodeData{
+		Code:     value,
+		RawData:  code,
+		Platform: HamsterBinPlatform,
+		Kind:     kind,
+		Version:  version,
+	}, nil
+}
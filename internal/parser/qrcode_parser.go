@@ -13,14 +13,21 @@ type QRCodeData struct {
 	Quantity int    `json:"quantity"` // 数量
 	RawData  string `json:"raw_data"` // 原始二维码数据
 	Platform string `json:"platform"` // 识别的平台
+	// Kind、Version 仅 Hamster Bin 自有二维码有值：指向的对象类型（PayloadComponent 等）与内容版本，Code 为编号、位置或 ID
+	Kind    string `json:"kind,omitempty"`
+	Version int    `json:"version,omitempty"`
 }
 
-// ParseQRCode 解析二维码内容，提取元件编码和数量
+// ParseQRCode 解析二维码内容：Hamster Bin 自有二维码（元件、存放位置、预入库），或立创商城的元件编码和数量
 func ParseQRCode(qrcodeData string) (*QRCodeData, error) {
 	if qrcodeData == "" {
 		return nil, fmt.Errorf("二维码数据为空")
 	}
 
+	if info, err := parseHamsterBinCode(qrcodeData); info != nil || err != nil {
+		return info, err
+	}
+
 	if info := parseLCSCCode(qrcodeData); info != nil {
 		return info, nil
 	}
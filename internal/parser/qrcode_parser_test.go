@@ -0,0 +1,47 @@
+package parser
+
+import "testing"
+
+func TestParseQRCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantErr  bool
+		platform string
+		kind     string
+		code     string
+		quantity int
+	}{
+		{name: "component", data: ComponentPayload("HB-000001"), platform: HamsterBinPlatform, kind: PayloadComponent, code: "HB-000001"},
+		{name: "location with colon", data: LocationPayload("柜A:03"), platform: HamsterBinPlatform, kind: PayloadLocation, code: "柜A:03"},
+		{name: "pre-stock", data: PreStockPayload(42), platform: HamsterBinPlatform, kind: PayloadPreStock, code: "42"},
+		{name: "newer version", data: "HB2:C:HB-000001", wantErr: true},
+		{name: "unknown kind", data: "HB1:X:1", wantErr: true},
+		{name: "empty value", data: "HB1:C:", wantErr: true},
+		{name: "invalid pre-stock id", data: "HB1:P:abc", wantErr: true},
+		{name: "lcsc", data: "{pbn:PICK2309,on:SO2309,pc:C25804,pm:0603WAF1002T5E,qty:100}", platform: "LCSC", code: "C25804", quantity: 100},
+		// 早期元件标签只编码系统编号
+		{name: "bare number", data: " HB-000001 ", platform: HamsterBinPlatform, kind: PayloadComponent, code: "HB-000001"},
+		{name: "bare number with suffix", data: "HB-000001A", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQRCode(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseQRCode(%q) = %+v, want error", tt.data, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseQRCode(%q): %v", tt.data, err)
+			}
+			if got.Platform != tt.platform || got.Kind != tt.kind || got.Code != tt.code || got.Quantity != tt.quantity {
+				t.Errorf("ParseQRCode(%q) = %+v", tt.data, got)
+			}
+		})
+	}
+	if got := ComponentPayload("HB-000001"); got != "HB1:C:HB-000001" {
+		t.Errorf("ComponentPayload = %q", got)
+	}
+}
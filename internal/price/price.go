@@ -11,14 +11,19 @@ func UnitPriceMicro(totalPriceCents int64, quantity int) int64 {
 	return (totalPriceCents*MicroPerCent + int64(quantity)/2) / int64(quantity)
 }
 
-// OutboundTotalCents 将单价（微元）按数量计算出库成本总价（分），四舍五入。
-func OutboundTotalCents(unitPriceMicro int64, quantity int) int64 {
+// TotalCents 将单价（微元）按数量计算总价（分），四舍五入；用于入库记录与库存估值等不区分方向的场景。
+func TotalCents(unitPriceMicro int64, quantity int) int64 {
 	if quantity <= 0 || unitPriceMicro <= 0 {
 		return 0
 	}
 	return (unitPriceMicro*int64(quantity) + MicroPerCent/2) / MicroPerCent
 }
 
+// OutboundTotalCents 将单价（微元）按数量计算出库成本总价（分），四舍五入。
+func OutboundTotalCents(unitPriceMicro int64, quantity int) int64 {
+	return TotalCents(unitPriceMicro, quantity)
+}
+
 // YuanToCents 将元（浮点）四舍五入换算为分。
 func YuanToCents(yuan float64) int64 {
 	if yuan <= 0 {
@@ -59,3 +64,22 @@ func ReverseAverageUnitPriceMicro(curQty int, curUnitMicro int64, inQty int, inT
 	}
 	return remainValue / int64(remainQty)
 }
+
+// MergeUnitPriceMicro 合并两条库存时按有价库存加权计算参考单价（微元）。
+// 一方无库存或无参考单价时不参与加权，直接沿用另一方单价；双方都不参与时优先保留 a 的单价。
+func MergeUnitPriceMicro(qtyA int, unitA int64, qtyB int, unitB int64) int64 {
+	var weightA, weightB int64
+	if qtyA > 0 && unitA > 0 {
+		weightA = int64(qtyA)
+	}
+	if qtyB > 0 && unitB > 0 {
+		weightB = int64(qtyB)
+	}
+	if weightA+weightB == 0 {
+		if unitA > 0 {
+			return unitA
+		}
+		return unitB
+	}
+	return (weightA*unitA + weightB*unitB) / (weightA + weightB)
+}
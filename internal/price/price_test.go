@@ -39,6 +39,9 @@ func TestOutboundTotalCents(t *testing.T) {
 		if got != tt.want {
 			t.Errorf("OutboundTotalCents(%d, %d) = %d, want %d", tt.unitMicro, tt.quantity, got, tt.want)
 		}
+		if got := TotalCents(tt.unitMicro, tt.quantity); got != tt.want {
+			t.Errorf("TotalCents(%d, %d) = %d, want %d", tt.unitMicro, tt.quantity, got, tt.want)
+		}
 	}
 }
 
@@ -172,3 +175,56 @@ func TestReverseAverageUnitPriceMicro(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeUnitPriceMicro(t *testing.T) {
+	tests := []struct {
+		name  string
+		qtyA  int
+		unitA int64
+		qtyB  int
+		unitB int64
+		want  int64
+	}{
+		{
+			name:  "weighted by priced stock",
+			qtyA:  10,
+			unitA: 1000000,
+			qtyB:  30,
+			unitB: 2000000,
+			want:  1750000,
+		},
+		{
+			name:  "unpriced side does not dilute",
+			qtyA:  10,
+			unitA: 0,
+			qtyB:  5,
+			unitB: 1200000,
+			want:  1200000,
+		},
+		{
+			name:  "no stock keeps survivor price",
+			qtyA:  0,
+			unitA: 900000,
+			qtyB:  0,
+			unitB: 1200000,
+			want:  900000,
+		},
+		{
+			name:  "survivor without price falls back to source",
+			qtyA:  0,
+			unitA: 0,
+			qtyB:  0,
+			unitB: 1200000,
+			want:  1200000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeUnitPriceMicro(tt.qtyA, tt.unitA, tt.qtyB, tt.unitB)
+			if got != tt.want {
+				t.Errorf("MergeUnitPriceMicro(%d, %d, %d, %d) = %d, want %d",
+					tt.qtyA, tt.unitA, tt.qtyB, tt.unitB, got, tt.want)
+			}
+		})
+	}
+}
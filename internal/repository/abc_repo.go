@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/Rehtt/hamster-bin/internal/price"
+	"gorm.io/gorm"
+)
+
+const (
+	// ABCMetricValue 按统计期内的出库金额分类
+	ABCMetricValue = "value"
+	// ABCMetricMovements 按统计期内的出库次数分类
+	ABCMetricMovements = "movements"
+
+	// DefaultABCWindowDays 未配置统计天数时使用一年
+	DefaultABCWindowDays = 365
+)
+
+// ABCClasses 全部 ABC 分类，按重要程度从高到低
+var ABCClasses = []string{"A", "B", "C"}
+
+// ABCParams ABC 分类参数
+type ABCParams struct {
+	// Metric 分类依据：ABCMetricValue 或 ABCMetricMovements
+	Metric string `json:"metric"`
+	// WindowDays 统计出库的最近天数，不大于 0 时使用 DefaultABCWindowDays
+	WindowDays int `json:"window_days"`
+	// ThresholdA、ThresholdB 为累计占比（百分比）阈值：按指标降序排列后，之前的累计占比未达到 ThresholdA 的为 A 类，
+	// 未达到 ThresholdB 的为 B 类，其余为 C 类
+	ThresholdA int `json:"threshold_a"`
+	ThresholdB int `json:"threshold_b"`
+}
+
+// Validate 校验分类依据与阈值（0 < A ≤ B ≤ 100）
+func (p ABCParams) Validate() error {
+	if p.Metric != ABCMetricValue && p.Metric != ABCMetricMovements {
+		return fmt.Errorf("分类依据仅支持 %s 或 %s", ABCMetricValue, ABCMetricMovements)
+	}
+	if p.ThresholdA <= 0 || p.ThresholdA > p.ThresholdB || p.ThresholdB > 100 {
+		return fmt.Errorf("阈值应满足 0 < A(%d) ≤ B(%d) ≤ 100", p.ThresholdA, p.ThresholdB)
+	}
+	return nil
+}
+
+// ParseABCClasses 解析逗号分隔的 ABC 分类（不区分大小写），去重后按 A、B、C 顺序返回；空字符串返回 nil
+func ParseABCClasses(raw string) ([]string, error) {
+	var classes []string
+	for part := range strings.SplitSeq(raw, ",") {
+		class := strings.ToUpper(strings.TrimSpace(part))
+		if class == "" {
+			continue
+		}
+		if !slices.Contains(ABCClasses, class) {
+			return nil, fmt.Errorf("abc_class 仅支持 A、B、C: %s", part)
+		}
+		if !slices.Contains(classes, class) {
+			classes = append(classes, class)
+		}
+	}
+	slices.Sort(classes)
+	return classes, nil
+}
+
+func (p ABCParams) windowDays() int {
+	if p.WindowDays <= 0 {
+		return DefaultABCWindowDays
+	}
+	return p.WindowDays
+}
+
+type ABCRepository struct {
+	db          *gorm.DB
+	workspaceID uint
+}
+
+func NewABCRepository(db *gorm.DB) *ABCRepository {
+	return &ABCRepository{db: db, workspaceID: DefaultWorkspaceID}
+}
+
+// ForWorkspace 返回限定在指定工作区内的仓储副本
+func (r *ABCRepository) ForWorkspace(workspaceID uint) *ABCRepository {
+	return &ABCRepository{db: r.db, workspaceID: workspaceID}
+}
+
+// abcUsage 元件在统计期内的出库金额与次数
+type abcUsage struct {
+	ConsumptionCents int64
+	Movements        int64
+}
+
+func (u abcUsage) metric(name string) int64 {
+	if name == ABCMetricMovements {
+		return u.Movements
+	}
+	return u.ConsumptionCents
+}
+
+// usage 统计 now 之前 WindowDays 天内各元件的出库金额与次数，只计未撤销、非冲销的出库记录；
+// 金额口径同 /stats/series：优先取记录总价，为 0 时按记录单价补算
+func (r *ABCRepository) usage(params ABCParams, now time.Time) (map[uint]abcUsage, error) {
+	since := now.AddDate(0, 0, -params.windowDays())
+	var rows []statsLogRow
+	if err := r.db.Table("stock_logs").
+		Select("stock_logs.component_id, stock_logs.change_amount, stock_logs.unit_price_micro, stock_logs.total_price_cents").
+		Where("stock_logs.workspace_id = ?", r.workspaceID).
+		Where("stock_logs.change_amount < 0 AND stock_logs.revoked_at IS NULL AND stock_logs.reversal_of_id IS NULL").
+		Where("stock_logs.created_at >= ? AND stock_logs.created_at < ?", dbTime(since), dbTime(now)).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[uint]abcUsage)
+	for _, row := range rows {
+		usage := result[row.ComponentID]
+		usage.ConsumptionCents += row.amounts().OutboundCostCents
+		usage.Movements++
+		result[row.ComponentID] = usage
+	}
+	return result, nil
+}
+
+// classifyABC 按指标降序（相同时按 ID）排列后依累计占比划分 A/B/C；指标为 0 的元件一律为 C 类
+func classifyABC(ids []uint, metric func(uint) int64, params ABCParams) map[uint]string {
+	sorted := slices.Clone(ids)
+	slices.SortFunc(sorted, func(a, b uint) int {
+		return cmp.Or(cmp.Compare(metric(b), metric(a)), cmp.Compare(a, b))
+	})
+	var total int64
+	for _, id := range sorted {
+		total += metric(id)
+	}
+
+	classes := make(map[uint]string, len(sorted))
+	var cumulative int64
+	for _, id := range sorted {
+		value := metric(id)
+		class := "C"
+		if value > 0 {
+			share := float64(cumulative) * 100 / float64(total)
+			switch {
+			case share < float64(params.ThresholdA):
+				class = "A"
+			case share < float64(params.ThresholdB):
+				class = "B"
+			}
+		}
+		classes[id] = class
+		cumulative += value
+	}
+	return classes
+}
+
+// Classify 重新计算工作区内全部元件的 ABC 分类并写入 components.abc_class，返回各分类的元件数。
+// 只更新分类列，不修改元件的更新时间
+func (r *ABCRepository) Classify(params ABCParams, now time.Time) (map[string]int, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	var ids []uint
+	if err := inWorkspace(r.db.Model(&models.Component{}), "components", r.workspaceID).
+		Pluck("components.id", &ids).Error; err != nil {
+		return nil, err
+	}
+	usage, err := r.usage(params, now)
+	if err != nil {
+		return nil, err
+	}
+	classes := classifyABC(ids, func(id uint) int64 { return usage[id].metric(params.Metric) }, params)
+
+	byClass := make(map[string][]uint, len(ABCClasses))
+	for _, id := range ids {
+		byClass[classes[id]] = append(byClass[classes[id]], id)
+	}
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		for _, class := range ABCClasses {
+			for chunk := range slices.Chunk(byClass[class], 500) {
+				if err := tx.Model(&models.Component{}).Where("id IN ?", chunk).UpdateColumn("abc_class", class).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(ABCClasses))
+	for _, class := range ABCClasses {
+		counts[class] = len(byClass[class])
+	}
+	return counts, nil
+}
+
+// ABCClassSummary 单个 ABC 分类的汇总；Class 为空表示尚未分类的元件
+type ABCClassSummary struct {
+	Class            string `json:"class"`
+	ComponentCount   int64  `json:"component_count"`
+	ConsumptionCents int64  `json:"consumption_cents"`
+	Movements        int64  `json:"movements"`
+	// Share 该分类指标占全部元件的百分比
+	Share           float64 `json:"share"`
+	StockQuantity   int64   `json:"stock_quantity"`
+	StockValueCents int64   `json:"stock_value_cents"`
+}
+
+// ABCSummary ABC 分类统计
+type ABCSummary struct {
+	Params  ABCParams         `json:"params"`
+	Since   time.Time         `json:"since"`
+	Classes []ABCClassSummary `json:"classes"`
+}
+
+// Summary 按元件当前保存的分类汇总元件数、统计期内出库金额与次数、指标占比及当前库存与价值。
+// 固定返回 A、B、C 三项，存在尚未分类的元件时另附 Class 为空的一项
+func (r *ABCRepository) Summary(params ABCParams, now time.Time) (*ABCSummary, error) {
+	params.WindowDays = params.windowDays()
+	var components []struct {
+		ID             uint
+		ABCClass       string
+		StockQuantity  int
+		UnitPriceMicro int64
+	}
+	if err := inWorkspace(r.db.Model(&models.Component{}), "components", r.workspaceID).
+		Select("components.id, COALESCE(components.abc_class, '') AS abc_class, components.stock_quantity, components.unit_price_micro").
+		Scan(&components).Error; err != nil {
+		return nil, err
+	}
+	usage, err := r.usage(params, now)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &ABCSummary{Params: params, Since: now.AddDate(0, 0, -params.WindowDays)}
+	byClass := make(map[string]*ABCClassSummary, len(ABCClasses)+1)
+	for _, class := range ABCClasses {
+		byClass[class] = &ABCClassSummary{Class: class}
+	}
+	var total int64
+	for _, component := range components {
+		class, ok := byClass[component.ABCClass]
+		if !ok {
+			class = &ABCClassSummary{Class: component.ABCClass}
+			byClass[component.ABCClass] = class
+		}
+		used := usage[component.ID]
+		class.ComponentCount++
+		class.ConsumptionCents += used.ConsumptionCents
+		class.Movements += used.Movements
+		class.StockQuantity += int64(component.StockQuantity)
+		if component.StockQuantity > 0 {
+			class.StockValueCents += price.TotalCents(component.UnitPriceMicro, component.StockQuantity)
+		}
+		total += used.metric(params.Metric)
+	}
+
+	for _, class := range append(slices.Clone(ABCClasses), "") {
+		item, ok := byClass[class]
+		if !ok {
+			continue
+		}
+		if total > 0 {
+			metric := abcUsage{ConsumptionCents: item.ConsumptionCents, Movements: item.Movements}.metric(params.Metric)
+			item.Share = float64(metric) * 100 / float64(total)
+		}
+		summary.Classes = append(summary.Classes, *item)
+	}
+	return summary, nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+)
+
+func TestABCClassify(t *testing.T) {
+	db := setupStatsTestDB(t)
+	now := time.Date(2026, 6, 30, 12, 0, 0, 0, time.Local)
+	daysAgo := func(days int) time.Time { return now.AddDate(0, 0, -days) }
+
+	category := models.Category{Name: "芯片"}
+	mustCreate(t, db, &category)
+	names := []string{"MCU", "LDO", "Resistor", "Capacitor", "Idle"}
+	components := make([]models.Component, len(names))
+	for i, name := range names {
+		components[i] = models.Component{CategoryID: category.ID, Name: name, StockQuantity: 100, UnitPriceMicro: 10000}
+		mustCreate(t, db, &components[i])
+	}
+	mcu, ldo, resistor, capacitor, idle := components[0].ID, components[1].ID, components[2].ID, components[3].ID, components[4].ID
+
+	revokedAt := daysAgo(9)
+	revoked := models.StockLog{ComponentID: capacitor, ChangeAmount: -1, TotalPriceCents: 100000, CreatedAt: daysAgo(10), RevokedAt: &revokedAt}
+	for _, log := range []*models.StockLog{
+		// 出库金额：MCU 7000、LDO 2000、Resistor 800（按单价补算）、Capacitor 400
+		{ComponentID: mcu, ChangeAmount: -10, TotalPriceCents: 7000, CreatedAt: daysAgo(30)},
+		{ComponentID: ldo, ChangeAmount: -2, TotalPriceCents: 2000, CreatedAt: daysAgo(60)},
+		{ComponentID: resistor, ChangeAmount: -10, UnitPriceMicro: 200000, CreatedAt: daysAgo(5)},
+		{ComponentID: resistor, ChangeAmount: -20, UnitPriceMicro: 200000, CreatedAt: daysAgo(6)},
+		{ComponentID: resistor, ChangeAmount: -10, UnitPriceMicro: 200000, CreatedAt: daysAgo(7)},
+		{ComponentID: capacitor, ChangeAmount: -4, TotalPriceCents: 400, CreatedAt: daysAgo(100)},
+		{ComponentID: idle, ChangeAmount: -50, TotalPriceCents: 100000, CreatedAt: daysAgo(400)}, // 统计期之外
+		{ComponentID: idle, ChangeAmount: 500, TotalPriceCents: 50000, CreatedAt: daysAgo(3)},    // 入库不计
+		&revoked,
+	} {
+		mustCreate(t, db, log)
+	}
+	mustCreate(t, db, &models.StockLog{ComponentID: capacitor, ChangeAmount: 1, ReversalOfID: &revoked.ID, CreatedAt: revokedAt})
+
+	repo := NewABCRepository(db)
+	classes := func() map[uint]string {
+		t.Helper()
+		var rows []models.Component
+		if err := db.Find(&rows).Error; err != nil {
+			t.Fatalf("find components: %v", err)
+		}
+		result := make(map[uint]string, len(rows))
+		for _, row := range rows {
+			result[row.ID] = row.ABCClass
+		}
+		return result
+	}
+
+	// 累计占比（之前）：MCU 0%、LDO 68.6%、Resistor 88.2%、Capacitor 96.1%
+	params := ABCParams{Metric: ABCMetricValue, WindowDays: 365, ThresholdA: 80, ThresholdB: 95}
+	counts, err := repo.Classify(params, now)
+	if err != nil {
+		t.Fatalf("Classify: %v", err)
+	}
+	if counts["A"] != 2 || counts["B"] != 1 || counts["C"] != 2 {
+		t.Errorf("counts = %v", counts)
+	}
+	got := classes()
+	if got[mcu] != "A" || got[ldo] != "A" || got[resistor] != "B" || got[capacitor] != "C" || got[idle] != "C" {
+		t.Errorf("classes by value = %v", got)
+	}
+
+	// 按出库次数：Resistor 3 次，其余各 1 次；累计占比（之前）：Resistor 0%、MCU 50%、LDO 66.7%、Capacitor 83.3%
+	params = ABCParams{Metric: ABCMetricMovements, WindowDays: 365, ThresholdA: 50, ThresholdB: 80}
+	if _, err := repo.Classify(params, now); err != nil {
+		t.Fatalf("Classify by movements: %v", err)
+	}
+	got = classes()
+	if got[resistor] != "A" || got[mcu] != "B" || got[ldo] != "B" || got[capacitor] != "C" || got[idle] != "C" {
+		t.Errorf("classes by movements = %v", got)
+	}
+
+	summary, err := repo.Summary(params, now)
+	if err != nil {
+		t.Fatalf("Summary: %v", err)
+	}
+	if len(summary.Classes) != 3 || summary.Classes[0].Class != "A" || summary.Classes[0].Movements != 3 ||
+		summary.Classes[0].ConsumptionCents != 800 || summary.Classes[0].Share != 50 || summary.Classes[0].StockValueCents != 100 {
+		t.Errorf("summary = %+v", summary.Classes)
+	}
+	if c := summary.Classes[2]; c.ComponentCount != 2 || c.Movements != 1 || c.ConsumptionCents != 400 || c.StockQuantity != 200 {
+		t.Errorf("class C summary = %+v", c)
+	}
+
+	list, _, err := NewComponentRepository(db).GetAll(ComponentQuery{ABCClasses: []string{"B"}, SortBy: "name", SortOrder: "asc"})
+	if err != nil {
+		t.Fatalf("GetAll abc_class=B: %v", err)
+	}
+	if names := componentNames(list); len(names) != 2 || names[0] != "LDO" || names[1] != "MCU" {
+		t.Errorf("components in class B = %v", names)
+	}
+
+	if _, err := repo.Classify(ABCParams{Metric: "count", ThresholdA: 80, ThresholdB: 95}, now); err == nil {
+		t.Error("Classify with invalid metric should fail")
+	}
+	if parsed, err := ParseABCClasses(" c,a,A "); err != nil || len(parsed) != 2 || parsed[0] != "A" || parsed[1] != "C" {
+		t.Errorf("ParseABCClasses = %v, %v", parsed, err)
+	}
+	if _, err := ParseABCClasses("D"); err == nil {
+		t.Error("ParseABCClasses(D) should fail")
+	}
+}
@@ -1,43 +1,300 @@
 package repository
 
 import (
+	"errors"
+
 	"github.com/Rehtt/hamster-bin/internal/models"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrCategoryCycle       = errors.New("不能将分类移动到自身或其子分类下")
+	ErrCategoryInUse       = errors.New("分类下仍有元件或预入库记录，请指定转移分类")
+	ErrCategoryHasChildren = errors.New("分类下仍有子分类，无法删除")
+	ErrInvalidReassign     = errors.New("转移分类不能是被删除的分类本身")
+)
+
 type CategoryRepository struct {
-	db *gorm.DB
+	db          *gorm.DB
+	workspaceID uint
 }
 
 func NewCategoryRepository(db *gorm.DB) *CategoryRepository {
-	return &CategoryRepository{db: db}
+	return &CategoryRepository{db: db, workspaceID: DefaultWorkspaceID}
+}
+
+// ForWorkspace 返回限定在指定工作区内的仓储
+func (r *CategoryRepository) ForWorkspace(workspaceID uint) *CategoryRepository {
+	return &CategoryRepository{db: r.db, workspaceID: workspaceID}
+}
+
+func (r *CategoryRepository) scoped() *gorm.DB {
+	return inWorkspace(r.db, "categories", r.workspaceID)
+}
+
+func (r *CategoryRepository) validateParent(category *models.Category) error {
+	if category.ParentID == nil {
+		return nil
+	}
+	if err := ensureInWorkspace(r.db, &models.Category{}, *category.ParentID, r.workspaceID); err != nil {
+		return err
+	}
+	if category.ID == 0 {
+		return nil
+	}
+	descendants, err := r.DescendantIDs(category.ID)
+	if err != nil {
+		return err
+	}
+	for _, id := range descendants {
+		if id == *category.ParentID {
+			return ErrCategoryCycle
+		}
+	}
+	return nil
 }
 
 // GetAll 获取所有分类
 func (r *CategoryRepository) GetAll() ([]models.Category, error) {
 	var categories []models.Category
-	err := r.db.Find(&categories).Error
+	err := r.scoped().Find(&categories).Error
 	return categories, err
 }
 
 // GetByID 根据ID获取分类
 func (r *CategoryRepository) GetByID(id uint) (*models.Category, error) {
 	var category models.Category
-	err := r.db.First(&category, id).Error
+	err := r.scoped().First(&category, id).Error
 	return &category, err
 }
 
+// DescendantIDs 返回分类自身及其所有子孙分类的 ID（自身在首位）
+func (r *CategoryRepository) DescendantIDs(id uint) ([]uint, error) {
+	categories, err := r.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return descendantIDs(categories, id), nil
+}
+
+func descendantIDs(categories []models.Category, id uint) []uint {
+	children := make(map[uint][]uint)
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+		}
+	}
+
+	ids := []uint{id}
+	visited := map[uint]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			// 防御历史数据中已存在的环
+			if visited[child] {
+				continue
+			}
+			visited[child] = true
+			ids = append(ids, child)
+		}
+	}
+	return ids
+}
+
+// CategoryNode 分类树节点。component_count / stock_quantity / stock_value_cents 仅统计直接挂在该分类下的元件，
+// total_* 字段包含所有子孙分类
+type CategoryNode struct {
+	models.Category
+	ComponentCount       int64           `json:"component_count"`
+	StockQuantity        int64           `json:"stock_quantity"`
+	StockValueCents      int64           `json:"stock_value_cents"`
+	TotalComponentCount  int64           `json:"total_component_count"`
+	TotalStockQuantity   int64           `json:"total_stock_quantity"`
+	TotalStockValueCents int64           `json:"total_stock_value_cents"`
+	Children             []*CategoryNode `json:"children"`
+
+	valueMicroQty      int64
+	totalValueMicroQty int64
+}
+
+// GetTree 获取分类树及每个节点的元件数量与库存价值。
+// 库存价值口径与仪表盘一致：先累加 stock_quantity×unit_price_micro，最后整除 10000 得到分。
+func (r *CategoryRepository) GetTree() ([]*CategoryNode, error) {
+	var categories []models.Category
+	if err := r.scoped().Order("name ASC, id ASC").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	var aggregates []struct {
+		CategoryID     uint
+		ComponentCount int64
+		StockQuantity  int64
+		ValueMicroQty  int64
+	}
+	if err := inWorkspace(r.db.Table("components"), "components", r.workspaceID).
+		Select("category_id, COUNT(*) AS component_count, COALESCE(SUM(stock_quantity), 0) AS stock_quantity, " +
+			"COALESCE(SUM(CASE WHEN stock_quantity > 0 AND unit_price_micro > 0 THEN stock_quantity * unit_price_micro ELSE 0 END), 0) AS value_micro_qty").
+		Group("category_id").
+		Scan(&aggregates).Error; err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uint]*CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &CategoryNode{Category: category, Children: []*CategoryNode{}}
+	}
+	for _, agg := range aggregates {
+		if node, ok := nodes[agg.CategoryID]; ok {
+			node.ComponentCount = agg.ComponentCount
+			node.StockQuantity = agg.StockQuantity
+			node.valueMicroQty = agg.ValueMicroQty
+		}
+	}
+
+	roots := []*CategoryNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		parent, ok := (*CategoryNode)(nil), false
+		if category.ParentID != nil && *category.ParentID != category.ID {
+			parent, ok = nodes[*category.ParentID]
+		}
+		if ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	// 历史数据中若存在环，环上节点不会出现在根下，此处将其断开挂到根级，保证每个分类都可见
+	visited := make(map[uint]bool, len(nodes))
+	var walk func(node *CategoryNode)
+	walk = func(node *CategoryNode) {
+		visited[node.ID] = true
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	for _, root := range roots {
+		walk(root)
+	}
+	for _, category := range categories {
+		if !visited[category.ID] {
+			node := nodes[category.ID]
+			if parent, ok := nodes[*category.ParentID]; ok {
+				parent.Children = removeCategoryNode(parent.Children, node.ID)
+			}
+			roots = append(roots, node)
+			walk(node)
+		}
+	}
+
+	for _, root := range roots {
+		accumulateCategoryNode(root)
+	}
+	return roots, nil
+}
+
+func removeCategoryNode(nodes []*CategoryNode, id uint) []*CategoryNode {
+	kept := nodes[:0]
+	for _, node := range nodes {
+		if node.ID != id {
+			kept = append(kept, node)
+		}
+	}
+	return kept
+}
+
+func accumulateCategoryNode(node *CategoryNode) {
+	node.StockValueCents = node.valueMicroQty / 10000
+	node.TotalComponentCount = node.ComponentCount
+	node.TotalStockQuantity = node.StockQuantity
+	node.totalValueMicroQty = node.valueMicroQty
+	for _, child := range node.Children {
+		accumulateCategoryNode(child)
+		node.TotalComponentCount += child.TotalComponentCount
+		node.TotalStockQuantity += child.TotalStockQuantity
+		node.totalValueMicroQty += child.totalValueMicroQty
+	}
+	node.TotalStockValueCents = node.totalValueMicroQty / 10000
+}
+
 // Create 创建分类
 func (r *CategoryRepository) Create(category *models.Category) error {
+	category.WorkspaceID = r.workspaceID
+	if err := r.validateParent(category); err != nil {
+		return err
+	}
 	return r.db.Create(category).Error
 }
 
-// Update 更新分类
+// Update 更新分类；父分类不能是自身或子孙分类
 func (r *CategoryRepository) Update(category *models.Category) error {
+	category.WorkspaceID = r.workspaceID
+	if err := r.validateParent(category); err != nil {
+		return err
+	}
 	return r.db.Save(category).Error
 }
 
-// Delete 删除分类
-func (r *CategoryRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Category{}, id).Error
+// Move 将分类（连同其子树）移动到新的父分类下；parentID 为空表示移动到根级
+func (r *CategoryRepository) Move(id uint, parentID *uint) (*models.Category, error) {
+	category, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	category.ParentID = parentID
+	if err := r.validateParent(category); err != nil {
+		return nil, err
+	}
+	if err := r.scoped().Model(&models.Category{}).Where("id = ?", id).
+		Update("parent_id", parentID).Error; err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// Delete 删除分类。仍被元件或预入库引用时，reassignTo 为空则拒绝删除，否则先把引用转移到 reassignTo；
+// 有子分类时拒绝删除。
+func (r *CategoryRepository) Delete(id uint, reassignTo *uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := inWorkspace(tx, "categories", r.workspaceID).First(&models.Category{}, id).Error; err != nil {
+			return err
+		}
+
+		var children int64
+		if err := inWorkspace(tx.Model(&models.Category{}), "categories", r.workspaceID).
+			Where("parent_id = ?", id).Count(&children).Error; err != nil {
+			return err
+		}
+		if children > 0 {
+			return ErrCategoryHasChildren
+		}
+
+		if reassignTo != nil {
+			if *reassignTo == id {
+				return ErrInvalidReassign
+			}
+			if err := ensureInWorkspace(tx, &models.Category{}, *reassignTo, r.workspaceID); err != nil {
+				return err
+			}
+			for _, model := range []any{&models.Component{}, &models.PreStock{}} {
+				if err := tx.Model(model).Where("workspace_id = ? AND category_id = ?", r.workspaceID, id).
+					Update("category_id", *reassignTo).Error; err != nil {
+					return err
+				}
+			}
+		} else {
+			for _, model := range []any{&models.Component{}, &models.PreStock{}} {
+				var count int64
+				if err := tx.Model(model).Where("workspace_id = ? AND category_id = ?", r.workspaceID, id).
+					Count(&count).Error; err != nil {
+					return err
+				}
+				if count > 0 {
+					return ErrCategoryInUse
+				}
+			}
+		}
+
+		return inWorkspace(tx, "categories", r.workspaceID).Delete(&models.Category{}, id).Error
+	})
 }
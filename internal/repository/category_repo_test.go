@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupCategoryTree 构造 电子元件 > 被动元件 > 电阻，以及独立的 工具 分类
+func setupCategoryTree(t *testing.T) (*gorm.DB, map[string]uint) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Category{}, &models.Supplier{}, &models.Component{}, &models.ComponentTag{}, &models.PreStock{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	repo := NewCategoryRepository(db)
+	ids := make(map[string]uint)
+	for _, item := range []struct{ name, parent string }{
+		{"电子元件", ""},
+		{"被动元件", "电子元件"},
+		{"电阻", "被动元件"},
+		{"工具", ""},
+	} {
+		category := models.Category{Name: item.name}
+		if item.parent != "" {
+			parentID := ids[item.parent]
+			category.ParentID = &parentID
+		}
+		if err := repo.Create(&category); err != nil {
+			t.Fatalf("create category %s: %v", item.name, err)
+		}
+		ids[item.name] = category.ID
+	}
+
+	components := []models.Component{
+		{CategoryID: ids["电子元件"], Name: "ESP32", StockQuantity: 2, UnitPriceMicro: 15_000_000},
+		{CategoryID: ids["被动元件"], Name: "磁珠", StockQuantity: 10},
+		{CategoryID: ids["电阻"], Name: "10k", StockQuantity: 100, UnitPriceMicro: 5_000},
+		{CategoryID: ids["工具"], Name: "镊子", StockQuantity: 1, UnitPriceMicro: 8_000_000},
+	}
+	for i := range components {
+		if err := db.Create(&components[i]).Error; err != nil {
+			t.Fatalf("create component: %v", err)
+		}
+	}
+	return db, ids
+}
+
+func TestCategoryTreeAggregatesDescendants(t *testing.T) {
+	db, ids := setupCategoryTree(t)
+
+	tree, err := NewCategoryRepository(db).GetTree()
+	if err != nil {
+		t.Fatalf("GetTree: %v", err)
+	}
+	if len(tree) != 2 {
+		t.Fatalf("roots = %d, want 2", len(tree))
+	}
+
+	var root *CategoryNode
+	for _, node := range tree {
+		if node.ID == ids["电子元件"] {
+			root = node
+		}
+	}
+	if root == nil || len(root.Children) != 1 || len(root.Children[0].Children) != 1 {
+		t.Fatalf("tree shape = %+v, want 电子元件 > 被动元件 > 电阻", tree)
+	}
+	if root.ComponentCount != 1 || root.StockValueCents != 3000 {
+		t.Fatalf("root own = %d/%d, want 1/3000", root.ComponentCount, root.StockValueCents)
+	}
+	if root.TotalComponentCount != 3 || root.TotalStockQuantity != 112 || root.TotalStockValueCents != 3050 {
+		t.Fatalf("root total = %d/%d/%d, want 3/112/3050",
+			root.TotalComponentCount, root.TotalStockQuantity, root.TotalStockValueCents)
+	}
+
+	filtered, total, err := NewComponentRepository(db).GetAll(ComponentQuery{
+		CategoryID:           &root.ID,
+		IncludeSubcategories: true,
+	})
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if total != 3 || len(filtered) != 3 {
+		t.Fatalf("GetAll with subcategories = %d, want 3", total)
+	}
+	if _, total, _ := NewComponentRepository(db).GetAll(ComponentQuery{CategoryID: &root.ID}); total != 1 {
+		t.Fatalf("GetAll without subcategories = %d, want 1", total)
+	}
+}
+
+func TestCategoryMoveRejectsCycles(t *testing.T) {
+	db, ids := setupCategoryTree(t)
+	repo := NewCategoryRepository(db)
+
+	for _, target := range []string{"电子元件", "电阻"} {
+		parentID := ids[target]
+		if _, err := repo.Move(ids["电子元件"], &parentID); !errors.Is(err, ErrCategoryCycle) {
+			t.Fatalf("move under %s error = %v, want ErrCategoryCycle", target, err)
+		}
+	}
+
+	category, err := repo.GetByID(ids["被动元件"])
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	resistorID := ids["电阻"]
+	category.ParentID = &resistorID
+	if err := repo.Update(category); !errors.Is(err, ErrCategoryCycle) {
+		t.Fatalf("Update into descendant error = %v, want ErrCategoryCycle", err)
+	}
+
+	toolsID := ids["工具"]
+	moved, err := repo.Move(ids["被动元件"], &toolsID)
+	if err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if moved.ParentID == nil || *moved.ParentID != toolsID {
+		t.Fatalf("moved parent = %v, want %d", moved.ParentID, toolsID)
+	}
+	descendants, err := repo.DescendantIDs(toolsID)
+	if err != nil {
+		t.Fatalf("DescendantIDs: %v", err)
+	}
+	if len(descendants) != 3 {
+		t.Fatalf("descendants of 工具 = %v, want subtree moved along", descendants)
+	}
+}
+
+func TestCategoryDeleteRefusesOrReassigns(t *testing.T) {
+	db, ids := setupCategoryTree(t)
+	repo := NewCategoryRepository(db)
+
+	if err := repo.Delete(ids["被动元件"], nil); !errors.Is(err, ErrCategoryHasChildren) {
+		t.Fatalf("delete with children error = %v, want ErrCategoryHasChildren", err)
+	}
+	if err := repo.Delete(ids["电阻"], nil); !errors.Is(err, ErrCategoryInUse) {
+		t.Fatalf("delete in use error = %v, want ErrCategoryInUse", err)
+	}
+	resistorID := ids["电阻"]
+	if err := repo.Delete(ids["电阻"], &resistorID); !errors.Is(err, ErrInvalidReassign) {
+		t.Fatalf("reassign to self error = %v, want ErrInvalidReassign", err)
+	}
+
+	toolsID := ids["工具"]
+	if err := repo.Delete(ids["电阻"], &toolsID); err != nil {
+		t.Fatalf("Delete with reassign: %v", err)
+	}
+	var count int64
+	if err := db.Model(&models.Component{}).Where("category_id = ?", toolsID).Count(&count).Error; err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("components in 工具 = %d, want 2 after reassign", count)
+	}
+	if _, err := repo.GetByID(ids["电阻"]); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("deleted category lookup error = %v, want ErrRecordNotFound", err)
+	}
+}
@@ -0,0 +1,585 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+)
+
+// 查询语言（元件列表 q 参数）示例：
+//
+//	pkg:0603 value:10k stock:<100 cat:电阻 -tag:obsolete mfr:"Texas Instruments" price:>0.5 location:A1*
+//	(pkg:0603 OR pkg:0805) -supplier:LCSC STM32
+//
+// 条件之间空格为 AND（也可写 AND），OR 优先级低于 AND，括号分组，- 或 NOT 取反；
+// 不带字段的词按 keyword 全文搜索匹配。文本字段默认包含匹配，值中的 * 为通配符（此时整体匹配），
+// 以 = 开头为精确匹配（不区分大小写取决于数据库 LIKE）；数值字段支持 > >= < <= =、a..b 区间。
+
+const (
+	// maxFilterConditions 单个查询最多的条件数
+	maxFilterConditions = 50
+	// maxFilterDepth 括号与取反的最大嵌套层数
+	maxFilterDepth = 16
+)
+
+// FilterSyntaxError 查询语言解析错误，Pos 为出错位置（从 1 开始的字符序号）
+type FilterSyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *FilterSyntaxError) Error() string {
+	return fmt.Sprintf("查询语法错误（第 %d 个字符）：%s", e.Pos, e.Msg)
+}
+
+type filterFieldKind int
+
+const (
+	filterText filterFieldKind = iota
+	filterInt
+	filterPrice
+	filterCategory
+	filterTag
+)
+
+type filterField struct {
+	name   string
+	column string
+	kind   filterFieldKind
+}
+
+var filterFieldList = []filterField{
+	{"number", "components.component_number", filterText},
+	{"name", "components.name", filterText},
+	{"model", "components.model", filterText},
+	{"mfr", "components.manufacturer", filterText},
+	{"value", "components.value", filterText},
+	{"pkg", "components.package", filterText},
+	{"desc", "components.description", filterText},
+	{"location", "components.location", filterText},
+	{"supplier", "suppliers.name", filterText},
+	{"spn", "components.supplier_part_number", filterText},
+	{"cat", "components.category_id", filterCategory},
+	{"stock", "components.stock_quantity", filterInt},
+	{"price", "components.unit_price_micro", filterPrice},
+	{"abc", "components.abc_class", filterText},
+	{"tag", "component_tags.name", filterTag},
+}
+
+// filterFieldAliases 字段别名到 filterFieldList 中的名称
+var filterFieldAliases = map[string]string{
+	"component_number":     "number",
+	"num":                  "number",
+	"manufacturer":         "mfr",
+	"val":                  "value",
+	"package":              "pkg",
+	"description":          "desc",
+	"loc":                  "location",
+	"supplier_part_number": "spn",
+	"category":             "cat",
+	"qty":                  "stock",
+	"stock_quantity":       "stock",
+	"unit_price":           "price",
+	"tags":                 "tag",
+}
+
+// ComponentFilterFields 返回查询语言支持的字段名（不含别名）
+func ComponentFilterFields() []string {
+	names := make([]string, len(filterFieldList))
+	for i, field := range filterFieldList {
+		names[i] = field.name
+	}
+	return names
+}
+
+func lookupFilterField(name string) (filterField, bool) {
+	name = strings.ToLower(name)
+	if alias, ok := filterFieldAliases[name]; ok {
+		name = alias
+	}
+	for _, field := range filterFieldList {
+		if field.name == name {
+			return field, true
+		}
+	}
+	return filterField{}, false
+}
+
+// ComponentFilter 解析后的查询语言条件树
+type ComponentFilter struct {
+	root filterNode
+}
+
+type filterNode interface{}
+
+type filterAnd []filterNode
+
+type filterOr []filterNode
+
+type filterNot struct{ node filterNode }
+
+// filterTerm 单个条件；field 为 nil 时为全文搜索词
+type filterTerm struct {
+	field *filterField
+	// op 文本字段为 contains、exact、glob；数值字段为比较运算符或 between
+	op     string
+	text   string
+	number [2]int64
+}
+
+type filterTokenKind int
+
+const (
+	tokenTerm filterTokenKind = iota
+	tokenLParen
+	tokenRParen
+	tokenOr
+	tokenAnd
+	tokenNot
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	pos  int
+	// raw 条件原文（字段与值，值中引号已去除），quoted 标记值是否带引号，colon 为字段分隔符在原文中的位置（无字段时为 -1）
+	raw    string
+	quoted bool
+	colon  int
+	// valuePos 值在查询中的位置
+	valuePos int
+}
+
+// ParseComponentFilter 解析查询语言；q 为空白时返回 nil
+func ParseComponentFilter(q string) (*ComponentFilter, error) {
+	tokens, err := lexFilter(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	p := &filterParser{tokens: tokens, end: utf8.RuneCountInString(q) + 1}
+	root, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if tok, ok := p.peek(); ok {
+		if tok.kind == tokenRParen {
+			return nil, &FilterSyntaxError{Pos: tok.pos, Msg: "多余的右括号"}
+		}
+		return nil, &FilterSyntaxError{Pos: tok.pos, Msg: "无法识别的内容"}
+	}
+	return &ComponentFilter{root: root}, nil
+}
+
+func lexFilter(q string) ([]filterToken, error) {
+	runes := []rune(q)
+	var tokens []filterToken
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(':
+			tokens = append(tokens, filterToken{kind: tokenLParen, pos: pos})
+			i++
+			continue
+		case r == ')':
+			tokens = append(tokens, filterToken{kind: tokenRParen, pos: pos})
+			i++
+			continue
+		case r == '|':
+			tokens = append(tokens, filterToken{kind: tokenOr, pos: pos})
+			i++
+			continue
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			tokens = append(tokens, filterToken{kind: tokenNot, pos: pos})
+			i++
+			continue
+		}
+
+		tok := filterToken{kind: tokenTerm, pos: pos, colon: -1, valuePos: pos}
+		var raw []rune
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+			switch {
+			case runes[i] == '"':
+				start := i
+				i++
+				for i < len(runes) && runes[i] != '"' {
+					if runes[i] == '\\' && i+1 < len(runes) {
+						i++
+					}
+					raw = append(raw, runes[i])
+					i++
+				}
+				if i >= len(runes) {
+					return nil, &FilterSyntaxError{Pos: start + 1, Msg: "引号未闭合"}
+				}
+				tok.quoted = true
+				i++
+			case runes[i] == ':' && tok.colon < 0 && !tok.quoted:
+				tok.colon = len(raw)
+				tok.valuePos = i + 2
+				raw = append(raw, ':')
+				i++
+			default:
+				raw = append(raw, runes[i])
+				i++
+			}
+		}
+		tok.raw = string(raw)
+		if tok.colon < 0 && !tok.quoted {
+			switch tok.raw {
+			case "OR":
+				tok.kind = tokenOr
+			case "AND":
+				tok.kind = tokenAnd
+			case "NOT":
+				tok.kind = tokenNot
+			}
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	next   int
+	end    int
+	terms  int
+}
+
+func (p *filterParser) peek() (filterToken, bool) {
+	if p.next >= len(p.tokens) {
+		return filterToken{}, false
+	}
+	return p.tokens[p.next], true
+}
+
+// posOrEnd 返回下一个 token 的位置，已到结尾时返回查询末尾
+func (p *filterParser) posOrEnd() int {
+	if tok, ok := p.peek(); ok {
+		return tok.pos
+	}
+	return p.end
+}
+
+func (p *filterParser) parseOr(depth int) (filterNode, error) {
+	first, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	nodes := filterOr{first}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != tokenOr {
+			break
+		}
+		p.next++
+		node, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return first, nil
+	}
+	return nodes, nil
+}
+
+func (p *filterParser) parseAnd(depth int) (filterNode, error) {
+	var nodes filterAnd
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind == tokenOr || tok.kind == tokenRParen {
+			break
+		}
+		if tok.kind == tokenAnd {
+			p.next++
+			if next, ok := p.peek(); !ok || next.kind == tokenOr || next.kind == tokenRParen || next.kind == tokenAnd {
+				return nil, &FilterSyntaxError{Pos: tok.pos, Msg: "AND 后缺少条件"}
+			}
+			continue
+		}
+		node, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	switch len(nodes) {
+	case 0:
+		tok, ok := p.peek()
+		if ok && tok.kind == tokenOr {
+			return nil, &FilterSyntaxError{Pos: tok.pos, Msg: "OR 两侧都需要条件"}
+		}
+		return nil, &FilterSyntaxError{Pos: p.posOrEnd(), Msg: "缺少条件"}
+	case 1:
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *filterParser) parseUnary(depth int) (filterNode, error) {
+	tok := p.tokens[p.next]
+	if depth >= maxFilterDepth {
+		return nil, &FilterSyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("嵌套超过 %d 层", maxFilterDepth)}
+	}
+	switch tok.kind {
+	case tokenNot:
+		p.next++
+		next, ok := p.peek()
+		if !ok || next.kind == tokenOr || next.kind == tokenRParen || next.kind == tokenAnd {
+			return nil, &FilterSyntaxError{Pos: tok.pos, Msg: "取反后缺少条件"}
+		}
+		node, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{node: node}, nil
+	case tokenLParen:
+		p.next++
+		node, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if next, ok := p.peek(); !ok || next.kind != tokenRParen {
+			return nil, &FilterSyntaxError{Pos: tok.pos, Msg: "括号未闭合"}
+		}
+		p.next++
+		return node, nil
+	}
+	p.next++
+	p.terms++
+	if p.terms > maxFilterConditions {
+		return nil, &FilterSyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("条件超过 %d 个", maxFilterConditions)}
+	}
+	return parseFilterTerm(tok)
+}
+
+func parseFilterTerm(tok filterToken) (filterNode, error) {
+	if tok.colon < 0 {
+		if tok.raw == "" {
+			return nil, &FilterSyntaxError{Pos: tok.pos, Msg: "搜索词为空"}
+		}
+		return filterTerm{op: "contains", text: tok.raw}, nil
+	}
+
+	name := tok.raw[:tok.colon]
+	value := tok.raw[tok.colon+1:]
+	if name == "" {
+		return nil, &FilterSyntaxError{Pos: tok.pos, Msg: "冒号前缺少字段名"}
+	}
+	field, ok := lookupFilterField(name)
+	if !ok {
+		return nil, &FilterSyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("未知字段 %s，可用字段：%s", name, strings.Join(ComponentFilterFields(), "、"))}
+	}
+	if value == "" && !tok.quoted {
+		return nil, &FilterSyntaxError{Pos: tok.valuePos, Msg: fmt.Sprintf("字段 %s 缺少值", name)}
+	}
+
+	term := filterTerm{field: &field}
+	switch field.kind {
+	case filterInt, filterPrice:
+		if err := parseFilterNumber(&term, value, tok.valuePos); err != nil {
+			return nil, err
+		}
+	default:
+		term.op, term.text = "contains", value
+		if !tok.quoted {
+			if rest, ok := strings.CutPrefix(value, "="); ok {
+				term.op, term.text = "exact", rest
+			} else if strings.Contains(value, "*") {
+				term.op = "glob"
+			}
+			if term.text == "" {
+				return nil, &FilterSyntaxError{Pos: tok.valuePos, Msg: fmt.Sprintf("字段 %s 缺少值", name)}
+			}
+		}
+	}
+	return term, nil
+}
+
+// parseFilterNumber 解析数值条件：>、>=、<、<=、=（可省略）或 a..b 区间；价格单位为元
+func parseFilterNumber(term *filterTerm, value string, pos int) error {
+	parse := func(s string, offset int) (int64, error) {
+		s = strings.TrimSpace(s)
+		if term.field.kind == filterPrice {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) || math.Abs(v) > 1e9 {
+				return 0, &FilterSyntaxError{Pos: pos + offset, Msg: fmt.Sprintf("价格 %q 不是有效数字（单位：元）", s)}
+			}
+			return int64(math.Round(v * 1e6)), nil
+		}
+		v, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return 0, &FilterSyntaxError{Pos: pos + offset, Msg: fmt.Sprintf("%s %q 不是有效整数", term.field.name, s)}
+		}
+		return v, nil
+	}
+
+	if low, high, ok := strings.Cut(value, ".."); ok {
+		var err error
+		if term.number[0], err = parse(low, 0); err != nil {
+			return err
+		}
+		if term.number[1], err = parse(high, utf8.RuneCountInString(low)+2); err != nil {
+			return err
+		}
+		if term.number[0] > term.number[1] {
+			return &FilterSyntaxError{Pos: pos, Msg: "区间下限大于上限"}
+		}
+		term.op = "between"
+		return nil
+	}
+
+	term.op = "="
+	offset := 0
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if rest, ok := strings.CutPrefix(value, op); ok {
+			term.op, value, offset = op, rest, len(op)
+			break
+		}
+	}
+	var err error
+	term.number[0], err = parse(value, offset)
+	return err
+}
+
+// componentFilterBuilder 构建条件树的 SQL；分类条件需查询当前工作区的分类树
+type componentFilterBuilder struct {
+	repo       *ComponentRepository
+	engine     string
+	categories []models.Category
+}
+
+func (r *ComponentRepository) filterCondition(filter *ComponentFilter) (string, []any, error) {
+	b := &componentFilterBuilder{repo: r, engine: r.SearchEngine()}
+	return b.build(filter.root)
+}
+
+func (b *componentFilterBuilder) build(node filterNode) (string, []any, error) {
+	switch n := node.(type) {
+	case filterAnd:
+		return b.join(n, " AND ")
+	case filterOr:
+		return b.join(n, " OR ")
+	case filterNot:
+		sql, args, err := b.build(n.node)
+		if err != nil {
+			return "", nil, err
+		}
+		// 以子查询取反，避免 LEFT JOIN 的空供应商等 NULL 值使 NOT 条件整体为 NULL
+		return "components.id NOT IN (SELECT components.id FROM components LEFT JOIN suppliers ON suppliers.id = components.supplier_id WHERE " + sql + ")", args, nil
+	case filterTerm:
+		return b.term(n)
+	}
+	return "", nil, fmt.Errorf("未知的查询节点 %T", node)
+}
+
+func (b *componentFilterBuilder) join(nodes []filterNode, sep string) (string, []any, error) {
+	parts := make([]string, len(nodes))
+	var args []any
+	for i, node := range nodes {
+		sql, nodeArgs, err := b.build(node)
+		if err != nil {
+			return "", nil, err
+		}
+		parts[i] = "(" + sql + ")"
+		args = append(args, nodeArgs...)
+	}
+	return strings.Join(parts, sep), args, nil
+}
+
+func (b *componentFilterBuilder) term(t filterTerm) (string, []any, error) {
+	if t.field == nil {
+		sql, args := keywordCondition(b.engine, t.text)
+		return sql, args, nil
+	}
+	switch t.field.kind {
+	case filterInt, filterPrice:
+		if t.op == "between" {
+			return t.field.column + " BETWEEN ? AND ?", []any{t.number[0], t.number[1]}, nil
+		}
+		return t.field.column + " " + t.op + " ?", []any{t.number[0]}, nil
+	case filterCategory:
+		ids, err := b.categoryIDs(t)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(ids) == 0 {
+			return "1 = 0", nil, nil
+		}
+		return "components.category_id IN ?", []any{ids}, nil
+	case filterTag:
+		// 任一标签匹配即命中
+		return "components.id IN (SELECT component_tags.component_id FROM component_tags WHERE component_tags.name LIKE ? ESCAPE '!')", []any{likeFilterPattern(t)}, nil
+	}
+	return "COALESCE(" + t.field.column + ", '') LIKE ? ESCAPE '!'", []any{likeFilterPattern(t)}, nil
+}
+
+// categoryIDs 返回名称匹配的分类及其全部子孙分类
+func (b *componentFilterBuilder) categoryIDs(t filterTerm) ([]uint, error) {
+	if b.categories == nil {
+		var err error
+		if b.categories, err = NewCategoryRepository(b.repo.db).ForWorkspace(b.repo.workspaceID).GetAll(); err != nil {
+			return nil, err
+		}
+	}
+	match := textMatcher(t)
+	seen := make(map[uint]bool)
+	var ids []uint
+	for _, category := range b.categories {
+		if seen[category.ID] || !match(category.Name) {
+			continue
+		}
+		for _, id := range descendantIDs(b.categories, category.ID) {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// likeFilterPattern 生成 ESCAPE '!' 的 LIKE 模式
+func likeFilterPattern(t filterTerm) string {
+	escaped := likeEscaper.Replace(t.text)
+	switch t.op {
+	case "exact":
+		return escaped
+	case "glob":
+		return strings.ReplaceAll(escaped, "*", "%")
+	}
+	return "%" + escaped + "%"
+}
+
+// textMatcher 在 Go 中按与 LIKE 相同的语义匹配（不区分大小写）
+func textMatcher(t filterTerm) func(string) bool {
+	switch t.op {
+	case "exact":
+		return func(s string) bool { return strings.EqualFold(s, t.text) }
+	case "glob":
+		parts := strings.Split(t.text, "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		re := regexp.MustCompile("(?is)^" + strings.Join(parts, ".*") + "$")
+		return re.MatchString
+	}
+	needle := strings.ToLower(t.text)
+	return func(s string) bool { return strings.Contains(strings.ToLower(s), needle) }
+}
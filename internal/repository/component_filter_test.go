@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"gorm.io/gorm"
+)
+
+func seedFilterFixtures(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	resistor := models.Category{Name: "电阻"}
+	mustCreate(t, db, &resistor)
+	smd := models.Category{Name: "贴片电阻", ParentID: &resistor.ID}
+	mustCreate(t, db, &smd)
+	capacitor := models.Category{Name: "电容"}
+	mustCreate(t, db, &capacitor)
+	lcsc := models.Supplier{Name: "LCSC"}
+	mustCreate(t, db, &lcsc)
+
+	for _, component := range []*models.Component{
+		{CategoryID: smd.ID, SupplierID: &lcsc.ID, Name: "R1", Package: "0603", Value: "10k", StockQuantity: 50, UnitPriceMicro: 10_000, Location: "A1-01"},
+		{CategoryID: smd.ID, Name: "R2", Package: "0805", Value: "10k", StockQuantity: 500, UnitPriceMicro: 20_000, Location: "A1-02"},
+		{CategoryID: resistor.ID, Name: "R3", Package: "TH", Value: "4.7k", Manufacturer: "Texas Instruments", StockQuantity: 5, UnitPriceMicro: 800_000, Location: "B2"},
+		{CategoryID: capacitor.ID, SupplierID: &lcsc.ID, Name: "C1", Package: "0603", Value: "100nF", Description: "去耦 10%_off", StockQuantity: 0, Location: "A10"},
+	} {
+		mustCreate(t, db, component)
+	}
+	// R3 停产，C1 为常用料
+	mustCreate(t, db, &models.ComponentTag{ComponentID: 3, Name: "obsolete"})
+	mustCreate(t, db, &models.ComponentTag{ComponentID: 4, Name: "常用"})
+	mustCreate(t, db, &models.ComponentTag{ComponentID: 4, Name: "obsolete-soon"})
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, value any) {
+	t.Helper()
+	if err := db.Create(value).Error; err != nil {
+		t.Fatalf("create %T: %v", value, err)
+	}
+}
+
+func TestComponentFilterQuery(t *testing.T) {
+	db := setupComponentTestDB(t)
+	seedFilterFixtures(t, db)
+	repo := NewComponentRepository(db)
+
+	cases := []struct {
+		q    string
+		want []string
+	}{
+		{"pkg:0603", []string{"C1", "R1"}},
+		{"pkg:0603 value:10k", []string{"R1"}},
+		{"pkg:0603 AND value:10k", []string{"R1"}},
+		{"stock:<100", []string{"C1", "R1", "R3"}},
+		{"stock:>=500", []string{"R2"}},
+		{"stock:50..500", []string{"R1", "R2"}},
+		{"stock:5", []string{"R3"}},
+		{"price:>0.5", []string{"R3"}},
+		{"price:0.01", []string{"R1"}},
+		{"cat:电阻", []string{"R1", "R2", "R3"}},
+		{"cat:=贴片电阻", []string{"R1", "R2"}},
+		{"cat:电阻 -cat:贴片*", []string{"R3"}},
+		{"cat:不存在", nil},
+		{`mfr:"Texas Instruments"`, []string{"R3"}},
+		{"mfr:=texas", nil},
+		{"location:A1*", []string{"C1", "R1", "R2"}},
+		{"location:A1-*", []string{"R1", "R2"}},
+		{"location:=a10", []string{"C1"}},
+		{"pkg:0603 OR pkg:0805", []string{"C1", "R1", "R2"}},
+		{"(pkg:0603 | pkg:0805) -supplier:LCSC", []string{"R2"}},
+		{"NOT supplier:LCSC", []string{"R2", "R3"}},
+		{"-(stock:0 OR price:>0.5)", []string{"R1", "R2"}},
+		{"desc:10%_", []string{"C1"}},
+		{"desc:1_%", nil},
+		{"10k -pkg:0805", []string{"R1"}},
+		{`"4.7k"`, []string{"R3"}},
+		{"tag:obsolete", []string{"C1", "R3"}},
+		{"tag:=obsolete", []string{"R3"}},
+		{"cat:电阻 -tag:=obsolete", []string{"R1", "R2"}},
+		{"tags:常用 OR tag:=OBSOLETE", []string{"C1", "R3"}},
+		{"-tag:*", []string{"R1", "R2"}},
+	}
+	for _, tc := range cases {
+		filter, err := ParseComponentFilter(tc.q)
+		if err != nil {
+			t.Fatalf("ParseComponentFilter(%q): %v", tc.q, err)
+		}
+		items, total, err := repo.GetAll(ComponentQuery{Filter: filter})
+		if err != nil {
+			t.Fatalf("GetAll(%q): %v", tc.q, err)
+		}
+		got := componentNames(items)
+		slices.Sort(got)
+		if int(total) != len(tc.want) || strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("q=%q got %v (total %d), want %v", tc.q, got, total, tc.want)
+		}
+	}
+}
+
+func TestParseComponentFilterErrors(t *testing.T) {
+	cases := []struct {
+		q   string
+		pos int
+		msg string
+	}{
+		{"color:red", 1, "未知字段 color"},
+		{`pkg:0603 mfr:"Texas`, 14, "引号未闭合"},
+		{"stock:<abc", 8, "不是有效整数"},
+		{"stock:abc", 7, "不是有效整数"},
+		{"price:1..x", 10, "不是有效数字"},
+		{"stock:9..1", 7, "下限大于上限"},
+		{"(pkg:0603 OR pkg:0805", 1, "括号未闭合"},
+		{"pkg:0603)", 9, "多余的右括号"},
+		{"pkg:0603 OR", 12, "缺少条件"},
+		{"OR pkg:0603", 1, "OR 两侧都需要条件"},
+		{"pkg:", 5, "缺少值"},
+		{":0603", 1, "缺少字段名"},
+		{"pkg:0603 NOT", 10, "取反后缺少条件"},
+		{"()", 2, "缺少条件"},
+		{strings.Repeat("(", 20) + "x" + strings.Repeat(")", 20), 17, "嵌套超过"},
+	}
+	for _, tc := range cases {
+		_, err := ParseComponentFilter(tc.q)
+		var syntaxErr *FilterSyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Fatalf("ParseComponentFilter(%q) err = %v, want FilterSyntaxError", tc.q, err)
+		}
+		if syntaxErr.Pos != tc.pos || !strings.Contains(syntaxErr.Msg, tc.msg) {
+			t.Errorf("ParseComponentFilter(%q) = %d %q, want %d %q", tc.q, syntaxErr.Pos, syntaxErr.Msg, tc.pos, tc.msg)
+		}
+	}
+
+	if filter, err := ParseComponentFilter("  "); filter != nil || err != nil {
+		t.Fatalf("blank query = %v, %v", filter, err)
+	}
+}
+
+func TestComponentFilterKeywordUsesFullTextIndex(t *testing.T) {
+	repo := NewComponentRepository(setupSearchTestDB(t))
+	for q, want := range map[string]string{
+		"yageo -电容":          "贴片电阻",
+		"tpdz OR mokuai":     "ESP32 模块,贴片电阻",
+		"-(0603 OR espress)": "",
+	} {
+		filter, err := ParseComponentFilter(q)
+		if err != nil {
+			t.Fatalf("ParseComponentFilter(%q): %v", q, err)
+		}
+		items, _, err := repo.GetAll(ComponentQuery{Filter: filter})
+		if err != nil {
+			t.Fatalf("GetAll(%q): %v", q, err)
+		}
+		got := componentNames(items)
+		slices.Sort(got)
+		if strings.Join(got, ",") != want {
+			t.Errorf("q=%q got %v, want %s", q, got, want)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/Rehtt/hamster-bin/internal/price"
+	"gorm.io/gorm"
+)
+
+// 导入行处理结果
+const (
+	ImportActionCreate = "create"
+	ImportActionUpdate = "update"
+	ImportActionError  = "error"
+)
+
+// ComponentImportColumns 可导入的列，与导出列同名（不含 created_at / updated_at）
+var ComponentImportColumns = []string{
+	"component_number", "name", "model", "manufacturer", "value", "package", "description",
+	"category", "stock_quantity", "unit_price", "location", "supplier", "supplier_part_number", "datasheet_url",
+}
+
+var (
+	ErrImportValidationFailed = errors.New("导入数据校验失败，未写入任何数据")
+	errImportDryRun           = errors.New("dry run")
+)
+
+// ComponentImportRow 一行导入数据；Values 以导入列名为键，Line 为文件中的行号（从 1 开始，含表头）
+type ComponentImportRow struct {
+	Line   int
+	Values map[string]string
+}
+
+// ComponentImportRowResult 单行校验/导入结果
+type ComponentImportRowResult struct {
+	Line            int      `json:"line"`
+	Action          string   `json:"action"`
+	ComponentID     uint     `json:"component_id,omitempty"`
+	ComponentNumber string   `json:"component_number,omitempty"`
+	Name            string   `json:"name,omitempty"`
+	Errors          []string `json:"errors,omitempty"`
+	Warnings        []string `json:"warnings,omitempty"`
+}
+
+// ComponentImportReport 导入报告
+type ComponentImportReport struct {
+	DryRun            bool                       `json:"dry_run"`
+	Total             int                        `json:"total"`
+	Created           int                        `json:"created"`
+	Updated           int                        `json:"updated"`
+	Failed            int                        `json:"failed"`
+	CreatedCategories []string                   `json:"created_categories"`
+	CreatedSuppliers  []string                   `json:"created_suppliers"`
+	Rows              []ComponentImportRowResult `json:"rows"`
+}
+
+// Import 按行创建或更新元件：component_number 匹配到已有元件时更新，否则创建（编号为空时自动生成）。
+// 分类与供应商按名称匹配，不存在时创建。整批在单事务中执行：任一行校验失败则全部回滚并返回
+// ErrImportValidationFailed；dryRun 时完整执行后回滚，报告与实际导入一致。
+func (r *ComponentRepository) Import(rows []ComponentImportRow, dryRun bool, operator string) (*ComponentImportReport, error) {
+	report := &ComponentImportReport{
+		DryRun:            dryRun,
+		Total:             len(rows),
+		CreatedCategories: []string{},
+		CreatedSuppliers:  []string{},
+		Rows:              make([]ComponentImportRowResult, 0, len(rows)),
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		importer := &componentImporter{
+			repo:       NewComponentRepository(tx).ForWorkspace(r.workspaceID),
+			tx:         tx,
+			operator:   operator,
+			report:     report,
+			categories: make(map[string]uint),
+			suppliers:  make(map[string]uint),
+			numbers:    make(map[string]int),
+		}
+		for _, row := range rows {
+			result := importer.importRow(row)
+			switch result.Action {
+			case ImportActionCreate:
+				report.Created++
+			case ImportActionUpdate:
+				report.Updated++
+			default:
+				report.Failed++
+			}
+			report.Rows = append(report.Rows, result)
+		}
+
+		if report.Failed > 0 {
+			return ErrImportValidationFailed
+		}
+		if dryRun {
+			return errImportDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errImportDryRun) {
+		return report, nil
+	}
+	if errors.Is(err, ErrImportValidationFailed) {
+		return report, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+type componentImporter struct {
+	repo       *ComponentRepository
+	tx         *gorm.DB
+	operator   string
+	report     *ComponentImportReport
+	categories map[string]uint
+	suppliers  map[string]uint
+	// numbers 文件内已出现的元件编号及其行号，用于检测重复
+	numbers map[string]int
+}
+
+// importRow 校验并写入单行；数据库错误同样记录为该行错误，由调用方整体回滚
+func (im *componentImporter) importRow(row ComponentImportRow) ComponentImportRowResult {
+	result := ComponentImportRowResult{Line: row.Line, Name: strings.TrimSpace(row.Values["name"])}
+	fail := func(format string, args ...any) ComponentImportRowResult {
+		result.Action = ImportActionError
+		result.Errors = append(result.Errors, fmt.Sprintf(format, args...))
+		return result
+	}
+
+	value := func(column string) (string, bool) {
+		v, ok := row.Values[column]
+		v = strings.TrimSpace(v)
+		return v, ok && v != ""
+	}
+
+	stock, hasStock, err := parseImportQuantity(row.Values["stock_quantity"])
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	unitPrice, hasPrice, err := parseImportUnitPrice(row.Values["unit_price"])
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+
+	var existing *models.Component
+	number, hasNumber := value("component_number")
+	if hasNumber {
+		if line, ok := im.numbers[number]; ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("元件编号 %s 与第 %d 行重复", number, line))
+		} else {
+			im.numbers[number] = row.Line
+		}
+		var found models.Component
+		err := im.repo.scoped().Where("component_number = ?", number).First(&found).Error
+		switch {
+		case err == nil:
+			existing = &found
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return fail("查询元件编号失败: %v", err)
+		}
+	}
+
+	if existing == nil {
+		if result.Name == "" {
+			result.Errors = append(result.Errors, "新建元件时名称不能为空")
+		}
+		if _, ok := value("category"); !ok {
+			result.Errors = append(result.Errors, "新建元件时分类不能为空")
+		}
+	}
+	if len(result.Errors) > 0 {
+		result.Action = ImportActionError
+		return result
+	}
+
+	component := models.Component{}
+	if existing != nil {
+		component = *existing
+	}
+	assign := func(column string, dst *string) {
+		if v, ok := value(column); ok {
+			*dst = v
+		}
+	}
+	assign("name", &component.Name)
+	assign("model", &component.Model)
+	assign("manufacturer", &component.Manufacturer)
+	assign("value", &component.Value)
+	assign("package", &component.Package)
+	assign("description", &component.Description)
+	assign("location", &component.Location)
+	assign("supplier_part_number", &component.SupplierPartNumber)
+	assign("datasheet_url", &component.DatasheetURL)
+
+	if name, ok := value("category"); ok {
+		id, err := im.resolveCategory(name)
+		if err != nil {
+			return fail("处理分类失败: %v", err)
+		}
+		component.CategoryID = id
+	}
+	if name, ok := value("supplier"); ok {
+		id, err := im.resolveSupplier(name)
+		if err != nil {
+			return fail("处理供应商失败: %v", err)
+		}
+		component.SupplierID = &id
+	}
+
+	if existing == nil {
+		return im.createComponent(result, component, number, stock, hasStock, unitPrice, hasPrice)
+	}
+	return im.updateComponent(result, component, stock, hasStock, unitPrice, hasPrice)
+}
+
+func (im *componentImporter) createComponent(result ComponentImportRowResult, component models.Component, number string, stock int, hasStock bool, unitPrice int64, hasPrice bool) ComponentImportRowResult {
+	result.Action = ImportActionError
+	if number != "" {
+		component.ComponentNumber = &number
+	}
+	if err := im.repo.AssignComponentNumberForCreate(&component); err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+	if hasStock {
+		component.StockQuantity = stock
+	}
+	if hasPrice {
+		component.UnitPriceMicro = unitPrice
+	}
+	if err := im.repo.Create(&component); err != nil {
+		result.Errors = append(result.Errors, "创建元件失败: "+err.Error())
+		return result
+	}
+
+	if component.StockQuantity > 0 {
+		log := models.StockLog{
+			WorkspaceID:     component.WorkspaceID,
+			ComponentID:     component.ID,
+			ChangeAmount:    component.StockQuantity,
+			UnitPriceMicro:  component.UnitPriceMicro,
+			TotalPriceCents: price.TotalCents(component.UnitPriceMicro, component.StockQuantity),
+			Reason:          "导入初始库存",
+			Operator:        im.operator,
+		}
+		if err := im.tx.Create(&log).Error; err != nil {
+			result.Errors = append(result.Errors, "创建库存记录失败: "+err.Error())
+			return result
+		}
+	}
+
+	result.Action = ImportActionCreate
+	result.ComponentID = component.ID
+	if component.ComponentNumber != nil {
+		result.ComponentNumber = *component.ComponentNumber
+	}
+	return result
+}
+
+func (im *componentImporter) updateComponent(result ComponentImportRowResult, component models.Component, stock int, hasStock bool, unitPrice int64, hasPrice bool) ComponentImportRowResult {
+	result.Action = ImportActionError
+	if result.Name == "" {
+		result.Name = component.Name
+	}
+	if component.ComponentNumber != nil {
+		result.ComponentNumber = *component.ComponentNumber
+	}
+
+	if hasPrice && unitPrice != component.UnitPriceMicro {
+		if component.UnitPriceMicro == 0 {
+			component.UnitPriceMicro = unitPrice
+		} else {
+			result.Warnings = append(result.Warnings, "已有参考单价，忽略导入的单价")
+		}
+	}
+
+	component.Category = nil
+	component.Supplier = nil
+	if err := im.repo.Update(&component); err != nil {
+		result.Errors = append(result.Errors, "更新元件失败: "+err.Error())
+		return result
+	}
+
+	if hasStock && stock != component.StockQuantity {
+		_, err := applyStockChangeTx(im.tx, im.repo.workspaceID, StockChangeParams{
+			ComponentID: component.ID,
+			Amount:      stock - component.StockQuantity,
+			Reason:      "导入调整库存",
+			Operator:    im.operator,
+		})
+		if err != nil {
+			result.Errors = append(result.Errors, "调整库存失败: "+err.Error())
+			return result
+		}
+	}
+
+	result.Action = ImportActionUpdate
+	result.ComponentID = component.ID
+	return result
+}
+
+func (im *componentImporter) resolveCategory(name string) (uint, error) {
+	if id, ok := im.categories[name]; ok {
+		return id, nil
+	}
+	var category models.Category
+	err := inWorkspace(im.tx, "categories", im.repo.workspaceID).
+		Where("name = ?", name).
+		Order("id ASC").
+		First(&category).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		category = models.Category{Name: name}
+		err = NewCategoryRepository(im.tx).ForWorkspace(im.repo.workspaceID).Create(&category)
+		if err == nil {
+			im.report.CreatedCategories = append(im.report.CreatedCategories, name)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	im.categories[name] = category.ID
+	return category.ID, nil
+}
+
+func (im *componentImporter) resolveSupplier(name string) (uint, error) {
+	if id, ok := im.suppliers[name]; ok {
+		return id, nil
+	}
+	suppliers := NewSupplierRepository(im.tx).ForWorkspace(im.repo.workspaceID)
+	supplier, err := suppliers.FindByName(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		supplier, err = suppliers.FirstOrCreateByName(name)
+		if err == nil {
+			im.report.CreatedSuppliers = append(im.report.CreatedSuppliers, name)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	im.suppliers[name] = supplier.ID
+	return supplier.ID, nil
+}
+
+func parseImportQuantity(raw string) (int, bool, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, false, nil
+	}
+	// 表格软件常把整数存成 10.0
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil || f < 0 || f != math.Trunc(f) || f > math.MaxInt32 {
+		return 0, false, fmt.Errorf("库存数量无效: %s", raw)
+	}
+	return int(f), true, nil
+}
+
+func parseImportUnitPrice(raw string) (int64, bool, error) {
+	raw = strings.TrimSpace(raw)
+	raw = strings.TrimLeft(raw, "¥￥")
+	if raw == "" {
+		return 0, false, nil
+	}
+	yuan, err := strconv.ParseFloat(raw, 64)
+	if err != nil || yuan < 0 {
+		return 0, false, fmt.Errorf("参考单价无效: %s", raw)
+	}
+	return int64(math.Round(yuan * 1e6)), true, nil
+}
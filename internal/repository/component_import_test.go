@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+)
+
+func TestComponentImport(t *testing.T) {
+	db := setupComponentTestDB(t)
+	if err := db.AutoMigrate(&models.PreStock{}, &models.StockLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	category := models.Category{Name: "电阻"}
+	if err := db.Create(&category).Error; err != nil {
+		t.Fatalf("create category: %v", err)
+	}
+	existing := models.Component{CategoryID: category.ID, ComponentNumber: strPtr("HB-000001"), Name: "10k 0603",
+		StockQuantity: 10, UnitPriceMicro: 20000}
+	if err := db.Create(&existing).Error; err != nil {
+		t.Fatalf("create component: %v", err)
+	}
+
+	repo := NewComponentRepository(db)
+	rows := []ComponentImportRow{
+		{Line: 2, Values: map[string]string{"component_number": "HB-000001", "stock_quantity": "25.0", "unit_price": "0.05", "location": "A1"}},
+		{Line: 3, Values: map[string]string{"name": "100nF 0603", "category": "电容", "supplier": "嘉立创", "stock_quantity": "100", "unit_price": "¥0.01"}},
+		{Line: 4, Values: map[string]string{"component_number": "OLD-7", "name": "LED", "category": "电容"}},
+	}
+
+	report, err := repo.Import(rows, true, "alice")
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if report.Created != 2 || report.Updated != 1 || report.Failed != 0 {
+		t.Fatalf("dry run report = %+v", report)
+	}
+	if len(report.CreatedCategories) != 1 || len(report.CreatedSuppliers) != 1 {
+		t.Fatalf("dry run created refs = %v %v", report.CreatedCategories, report.CreatedSuppliers)
+	}
+	var count int64
+	db.Model(&models.Component{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("dry run wrote components: %d", count)
+	}
+	db.Model(&models.Category{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("dry run wrote categories: %d", count)
+	}
+
+	report, err = repo.Import(rows, false, "alice")
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if report.Rows[1].ComponentNumber != "HB-000002" || report.Rows[2].ComponentNumber != "OLD-7" {
+		t.Fatalf("numbers = %q %q", report.Rows[1].ComponentNumber, report.Rows[2].ComponentNumber)
+	}
+	if len(report.Rows[0].Warnings) != 1 {
+		t.Fatalf("expected unit price warning, got %v", report.Rows[0].Warnings)
+	}
+
+	updated, _ := repo.GetByID(existing.ID)
+	if updated.StockQuantity != 25 || updated.Location != "A1" || updated.Name != "10k 0603" || updated.UnitPriceMicro != 20000 {
+		t.Fatalf("updated = %+v", updated)
+	}
+	created, _ := repo.GetByID(report.Rows[1].ComponentID)
+	if created.StockQuantity != 100 || created.UnitPriceMicro != 10000 || created.Supplier == nil || created.Category.Name != "电容" {
+		t.Fatalf("created = %+v", created)
+	}
+
+	var logs []models.StockLog
+	db.Order("id ASC").Find(&logs)
+	if len(logs) != 2 || logs[0].ChangeAmount != 15 || logs[1].ChangeAmount != 100 || logs[1].TotalPriceCents != 100 {
+		t.Fatalf("logs = %+v", logs)
+	}
+}
+
+func TestComponentImportRejectsInvalidRows(t *testing.T) {
+	db := setupComponentTestDB(t)
+	if err := db.AutoMigrate(&models.PreStock{}, &models.StockLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	rows := []ComponentImportRow{
+		{Line: 2, Values: map[string]string{"name": "ok", "category": "电阻"}},
+		{Line: 3, Values: map[string]string{"name": "no category"}},
+		{Line: 4, Values: map[string]string{"component_number": "X-1", "name": "a", "category": "电阻", "stock_quantity": "1.5"}},
+		{Line: 5, Values: map[string]string{"component_number": "X-1", "name": "b", "category": "电阻"}},
+	}
+	report, err := NewComponentRepository(db).Import(rows, false, "")
+	if !errors.Is(err, ErrImportValidationFailed) {
+		t.Fatalf("err = %v, want ErrImportValidationFailed", err)
+	}
+	if report.Failed != 3 || report.Rows[0].Action != ImportActionCreate {
+		t.Fatalf("report = %+v", report)
+	}
+
+	var count int64
+	db.Model(&models.Component{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("failed import wrote %d components", count)
+	}
+}
@@ -0,0 +1,328 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/Rehtt/hamster-bin/internal/price"
+	"gorm.io/gorm"
+)
+
+// 重复元件判定依据
+const (
+	DuplicateBySupplierPartNumber = "supplier_part_number"
+	DuplicateByModelManufacturer  = "model_manufacturer"
+	DuplicateByValuePackage       = "value_package"
+)
+
+// DuplicateCriteria 全部判定依据，按可信度从高到低排列
+var DuplicateCriteria = []string{
+	DuplicateBySupplierPartNumber,
+	DuplicateByModelManufacturer,
+	DuplicateByValuePackage,
+}
+
+var (
+	ErrInvalidDuplicateCriteria = errors.New("无效的重复判定依据")
+	ErrInvalidComponentMerge    = errors.New("合并目标不能同时是被合并的元件")
+)
+
+// IsValidDuplicateCriteria 判断重复判定依据是否受支持
+func IsValidDuplicateCriteria(by string) bool {
+	return slices.Contains(DuplicateCriteria, by)
+}
+
+// DuplicateGroup 按同一依据判定为重复的一组元件
+type DuplicateGroup struct {
+	By         string             `json:"by"`
+	Key        string             `json:"key"`
+	Components []models.Component `json:"components"`
+}
+
+var valuePrefixes = map[string]float64{
+	"p": 1e-12, "n": 1e-9, "u": 1e-6, "m": 1e-3,
+	"": 1, "r": 1, "k": 1e3, "meg": 1e6, "g": 1e9,
+}
+
+// NormalizeComponentValue 归一化参数值，使 100nF / 0.1uF、4k7 / 4.7kΩ、1M / 1MΩ 得到相同结果。
+// 只换算倍率前缀，单位 F、H 保留在结果中（10uF 与 10uH 不同）；欧姆常被省略，与不带单位的数值视为相同。
+// 无法识别为数值的参数按去空白小写处理。
+func NormalizeComponentValue(value string) string {
+	v := strings.TrimSpace(value)
+	v = strings.NewReplacer("µ", "u", "μ", "u", "Ω", "", "ω", "", " ", "").Replace(v)
+	if v == "" {
+		return ""
+	}
+	// 大写 M 代表兆，与毫（m）区分后再统一小写
+	if !strings.Contains(strings.ToLower(v), "meg") {
+		v = strings.ReplaceAll(v, "M", "meg")
+	}
+	v = strings.ToLower(v)
+	original := v
+	v = strings.TrimSuffix(v, "ohms")
+	v = strings.TrimSuffix(v, "ohm")
+	unit := ""
+	for _, suffix := range []string{"f", "h"} {
+		if trimmed, found := strings.CutSuffix(v, suffix); found {
+			v, unit = trimmed, suffix
+			break
+		}
+	}
+
+	number, prefix, ok := splitValuePrefix(v)
+	if !ok {
+		return original
+	}
+	multiplier, known := valuePrefixes[prefix]
+	if !known {
+		return original
+	}
+	return strconv.FormatFloat(number*multiplier, 'g', 6, 64) + unit
+}
+
+// splitValuePrefix 拆分数值与倍率前缀，支持 4k7、4R7 这类倍率字母作小数点的写法
+func splitValuePrefix(v string) (float64, string, bool) {
+	for _, prefix := range []string{"meg", "p", "n", "u", "m", "r", "k", "g"} {
+		before, after, found := strings.Cut(v, prefix)
+		if !found || before == "" {
+			continue
+		}
+		if after != "" {
+			if _, err := strconv.Atoi(after); err != nil {
+				continue
+			}
+			before += "." + after
+		}
+		number, err := strconv.ParseFloat(before, 64)
+		if err != nil {
+			continue
+		}
+		return number, prefix, true
+	}
+	number, err := strconv.ParseFloat(v, 64)
+	return number, "", err == nil
+}
+
+func normalizeDuplicateText(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), ""))
+}
+
+// duplicateKey 返回元件在指定依据下的分组键；相关字段为空时返回空字符串，表示不参与分组
+func duplicateKey(component *models.Component, by string) string {
+	switch by {
+	case DuplicateBySupplierPartNumber:
+		return normalizeDuplicateText(component.SupplierPartNumber)
+	case DuplicateByModelManufacturer:
+		model := normalizeDuplicateText(component.Model)
+		if model == "" {
+			return ""
+		}
+		return model + "|" + normalizeDuplicateText(component.Manufacturer)
+	case DuplicateByValuePackage:
+		value := NormalizeComponentValue(component.Value)
+		pkg := normalizeDuplicateText(component.Package)
+		if value == "" || pkg == "" {
+			return ""
+		}
+		return value + "|" + pkg
+	}
+	return ""
+}
+
+// FindDuplicates 查找当前工作区内的疑似重复元件。by 为空时使用全部依据；
+// 成员完全相同的分组只保留可信度最高的依据。
+func (r *ComponentRepository) FindDuplicates(by []string) ([]DuplicateGroup, error) {
+	if len(by) == 0 {
+		by = DuplicateCriteria
+	}
+	for _, criteria := range by {
+		if !IsValidDuplicateCriteria(criteria) {
+			return nil, ErrInvalidDuplicateCriteria
+		}
+	}
+
+	var components []models.Component
+	if err := r.scoped().Preload("Category").Preload("Supplier").Order("id ASC").Find(&components).Error; err != nil {
+		return nil, err
+	}
+
+	groups := []DuplicateGroup{}
+	seenMembers := make(map[string]bool)
+	for _, criteria := range DuplicateCriteria {
+		if !slices.Contains(by, criteria) {
+			continue
+		}
+		byKey := make(map[string][]models.Component)
+		for _, component := range components {
+			if key := duplicateKey(&component, criteria); key != "" {
+				byKey[key] = append(byKey[key], component)
+			}
+		}
+
+		keys := make([]string, 0, len(byKey))
+		for key, members := range byKey {
+			if len(members) > 1 {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			members := byKey[key]
+			ids := make([]string, len(members))
+			for i, member := range members {
+				ids[i] = strconv.FormatUint(uint64(member.ID), 10)
+			}
+			signature := strings.Join(ids, ",")
+			if seenMembers[signature] {
+				continue
+			}
+			seenMembers[signature] = true
+			groups = append(groups, DuplicateGroup{By: criteria, Key: key, Components: members})
+		}
+	}
+	return groups, nil
+}
+
+// FindLikelyDuplicates 查找与给定元件在任一依据下相同的已有元件（排除其自身）
+func (r *ComponentRepository) FindLikelyDuplicates(component *models.Component) ([]models.Component, error) {
+	keys := make(map[string]string, len(DuplicateCriteria))
+	for _, criteria := range DuplicateCriteria {
+		if key := duplicateKey(component, criteria); key != "" {
+			keys[criteria] = key
+		}
+	}
+	if len(keys) == 0 {
+		return []models.Component{}, nil
+	}
+
+	var candidates []models.Component
+	if err := r.scoped().Where("id <> ?", component.ID).Order("id ASC").Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	matches := []models.Component{}
+	for _, candidate := range candidates {
+		for criteria, key := range keys {
+			if duplicateKey(&candidate, criteria) == key {
+				matches = append(matches, candidate)
+				break
+			}
+		}
+	}
+	return matches, nil
+}
+
+// MergeComponents 将 sourceIDs 合并到 targetID：库存数量相加并按有价库存加权重算参考单价，
+// 库存记录与预入库关联改指向目标元件，目标为空的字段用来源补全，标签取并集，保留目标的元件编号。
+// 每个被合并的元件会在目标上写入一条 change_amount=0、merged_from_id 指向来源的合并记录，然后删除来源。
+func (r *ComponentRepository) MergeComponents(targetID uint, sourceIDs []uint, operator string) (*models.Component, error) {
+	for _, id := range sourceIDs {
+		if id == targetID {
+			return nil, ErrInvalidComponentMerge
+		}
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var target models.Component
+		if err := inWorkspace(tx, "components", r.workspaceID).First(&target, targetID).Error; err != nil {
+			return err
+		}
+
+		var sources []models.Component
+		if err := inWorkspace(tx, "components", r.workspaceID).
+			Where("id IN ?", sourceIDs).
+			Order("id ASC").
+			Find(&sources).Error; err != nil {
+			return err
+		}
+		if len(sources) != len(sourceIDs) {
+			return gorm.ErrRecordNotFound
+		}
+
+		// 标签取目标与来源的并集（不受单个元件的标签数上限约束，避免合并失败）
+		tags, err := tagsByComponent(tx, append([]uint{targetID}, sourceIDs...))
+		if err != nil {
+			return err
+		}
+		merged := tags[targetID]
+		for _, source := range sources {
+			merged = append(merged, tags[source.ID]...)
+		}
+		if err := tx.Where("component_id IN ?", sourceIDs).Delete(&models.ComponentTag{}).Error; err != nil {
+			return err
+		}
+		if err := replaceTagsTx(tx, targetID, dedupeTags(merged)); err != nil {
+			return err
+		}
+
+		for _, source := range sources {
+			target.UnitPriceMicro = price.MergeUnitPriceMicro(
+				target.StockQuantity, target.UnitPriceMicro,
+				source.StockQuantity, source.UnitPriceMicro,
+			)
+			target.StockQuantity += source.StockQuantity
+			mergeEmptyComponentFields(&target, &source)
+
+			if err := tx.Model(&models.StockLog{}).Where("component_id = ?", source.ID).
+				Update("component_id", target.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.PreStock{}).Where("component_id = ?", source.ID).
+				Update("component_id", target.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&models.Component{}, source.ID).Error; err != nil {
+				return err
+			}
+
+			sourceID := source.ID
+			history := models.StockLog{
+				WorkspaceID:  r.workspaceID,
+				ComponentID:  target.ID,
+				Reason:       mergeReason(&source),
+				Operator:     operator,
+				MergedFromID: &sourceID,
+			}
+			if err := tx.Create(&history).Error; err != nil {
+				return err
+			}
+		}
+
+		target.Category = nil
+		target.Supplier = nil
+		return tx.Save(&target).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(targetID)
+}
+
+func mergeReason(source *models.Component) string {
+	label := source.Name
+	if source.ComponentNumber != nil && *source.ComponentNumber != "" {
+		label = *source.ComponentNumber + " " + label
+	}
+	return fmt.Sprintf("合并元件 %s（转入库存 %d）", label, source.StockQuantity)
+}
+
+func mergeEmptyComponentFields(target, source *models.Component) {
+	fillEmpty(&target.Model, source.Model)
+	fillEmpty(&target.Manufacturer, source.Manufacturer)
+	fillEmpty(&target.Value, source.Value)
+	fillEmpty(&target.Package, source.Package)
+	fillEmpty(&target.SupplierPartNumber, source.SupplierPartNumber)
+	fillEmpty(&target.Description, source.Description)
+	fillEmpty(&target.Location, source.Location)
+	fillEmpty(&target.DatasheetURL, source.DatasheetURL)
+	fillEmpty(&target.ImageURL, source.ImageURL)
+	if target.SupplierID == nil {
+		target.SupplierID = source.SupplierID
+	}
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestNormalizeComponentValue(t *testing.T) {
+	same := [][]string{
+		{"100nF", "0.1uF", "0.1 µF", "100 nf"},
+		{"4k7", "4.7k", "4.7kΩ", "4.7K ohm"},
+		{"1M", "1MΩ", "1meg", "1000k"},
+		{"4R7", "4.7", "4.7Ω"},
+	}
+	for _, group := range same {
+		want := NormalizeComponentValue(group[0])
+		for _, value := range group[1:] {
+			if got := NormalizeComponentValue(value); got != want {
+				t.Fatalf("NormalizeComponentValue(%q) = %q, want %q (same as %q)", value, got, want, group[0])
+			}
+		}
+	}
+	if NormalizeComponentValue("1m") == NormalizeComponentValue("1M") {
+		t.Fatalf("milli and mega must not collide")
+	}
+	// 单位不同的参数不能归为同一值
+	for _, pair := range [][2]string{{"10uF", "10uH"}, {"100nF", "100n"}, {"1mH", "1m"}} {
+		if NormalizeComponentValue(pair[0]) == NormalizeComponentValue(pair[1]) {
+			t.Fatalf("%q and %q must not collide", pair[0], pair[1])
+		}
+	}
+	if got := NormalizeComponentValue("100nF X7R"); got != "100nfx7r" {
+		t.Fatalf("unparseable value = %q, want lowercase without spaces", got)
+	}
+}
+
+func TestFindDuplicatesAndMergeComponents(t *testing.T) {
+	db := setupComponentTestDB(t)
+	if err := db.AutoMigrate(&models.PreStock{}, &models.StockLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	category := models.Category{Name: "电容"}
+	if err := db.Create(&category).Error; err != nil {
+		t.Fatalf("create category: %v", err)
+	}
+
+	components := []models.Component{
+		{CategoryID: category.ID, ComponentNumber: strPtr("HB-000001"), Name: "100nF 0603", Value: "100nF", Package: "0603",
+			SupplierPartNumber: "C14663", StockQuantity: 10, UnitPriceMicro: 1000000},
+		{CategoryID: category.ID, ComponentNumber: strPtr("HB-000002"), Name: "贴片电容 0.1uF", Value: "0.1uF", Package: "0603",
+			SupplierPartNumber: "c14663", StockQuantity: 30, UnitPriceMicro: 2000000, Location: "A1"},
+		{CategoryID: category.ID, ComponentNumber: strPtr("HB-000003"), Name: "104 电容", Value: "100 nF", Package: "0603",
+			StockQuantity: 5},
+		{CategoryID: category.ID, ComponentNumber: strPtr("HB-000004"), Name: "10uF", Value: "10uF", Package: "0805"},
+	}
+	for i := range components {
+		if err := db.Create(&components[i]).Error; err != nil {
+			t.Fatalf("create component: %v", err)
+		}
+	}
+	if err := db.Create(&models.StockLog{ComponentID: components[1].ID, ChangeAmount: 30, Reason: "入库"}).Error; err != nil {
+		t.Fatalf("create log: %v", err)
+	}
+	if err := db.Create(&models.PreStock{CategoryID: category.ID, ComponentID: &components[2].ID, Name: "104 电容", Status: PreStockStatusConfirmed}).Error; err != nil {
+		t.Fatalf("create pre-stock: %v", err)
+	}
+
+	repo := NewComponentRepository(db)
+	groups, err := repo.FindDuplicates(nil)
+	if err != nil {
+		t.Fatalf("FindDuplicates: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("groups = %+v, want part-number group and value+package group", groups)
+	}
+	if groups[0].By != DuplicateBySupplierPartNumber || len(groups[0].Components) != 2 {
+		t.Fatalf("first group = %+v, want 2 components by supplier_part_number", groups[0])
+	}
+	if groups[1].By != DuplicateByValuePackage || len(groups[1].Components) != 3 {
+		t.Fatalf("second group = %+v, want 3 components by value_package", groups[1])
+	}
+	if _, err := repo.FindDuplicates([]string{"name"}); !errors.Is(err, ErrInvalidDuplicateCriteria) {
+		t.Fatalf("invalid criteria error = %v, want ErrInvalidDuplicateCriteria", err)
+	}
+
+	likely, err := repo.FindLikelyDuplicates(&models.Component{SupplierPartNumber: "C14663"})
+	if err != nil || len(likely) != 2 {
+		t.Fatalf("FindLikelyDuplicates = %d/%v, want 2", len(likely), err)
+	}
+
+	if _, err := repo.MergeComponents(components[0].ID, []uint{components[0].ID}, "alice"); !errors.Is(err, ErrInvalidComponentMerge) {
+		t.Fatalf("merge into self error = %v, want ErrInvalidComponentMerge", err)
+	}
+	merged, err := repo.MergeComponents(components[0].ID, []uint{components[1].ID, components[2].ID}, "alice")
+	if err != nil {
+		t.Fatalf("MergeComponents: %v", err)
+	}
+	if merged.StockQuantity != 45 || merged.UnitPriceMicro != 1750000 {
+		t.Fatalf("merged stock/price = %d/%d, want 45/1750000", merged.StockQuantity, merged.UnitPriceMicro)
+	}
+	if *merged.ComponentNumber != "HB-000001" || merged.Location != "A1" {
+		t.Fatalf("merged = %s/%q, want surviving number and filled location", *merged.ComponentNumber, merged.Location)
+	}
+
+	var logs []models.StockLog
+	if err := db.Where("component_id = ?", merged.ID).Order("id ASC").Find(&logs).Error; err != nil {
+		t.Fatalf("find logs: %v", err)
+	}
+	if len(logs) != 3 || logs[0].ChangeAmount != 30 {
+		t.Fatalf("logs = %+v, want moved inbound log plus two merge records", logs)
+	}
+	if logs[1].MergedFromID == nil || *logs[1].MergedFromID != components[1].ID || logs[1].Operator != "alice" || logs[1].ChangeAmount != 0 {
+		t.Fatalf("merge record = %+v", logs[1])
+	}
+	if _, _, err := NewStockLogRepository(db).RevokeStockLog(logs[1].ID, "alice"); !errors.Is(err, ErrCannotRevokeMerge) {
+		t.Fatalf("revoke merge record error = %v, want ErrCannotRevokeMerge", err)
+	}
+
+	var preStock models.PreStock
+	if err := db.First(&preStock).Error; err != nil {
+		t.Fatalf("find pre-stock: %v", err)
+	}
+	if preStock.ComponentID == nil || *preStock.ComponentID != merged.ID {
+		t.Fatalf("pre-stock component = %v, want %d", preStock.ComponentID, merged.ID)
+	}
+	if _, err := repo.GetByID(components[1].ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("merged source lookup error = %v, want ErrRecordNotFound", err)
+	}
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"gorm.io/gorm"
+)
+
+// MoveComponentsParams 元件跨工作区移动参数
+type MoveComponentsParams struct {
+	ComponentIDs      []uint
+	TargetWorkspaceID uint
+	// TargetCategoryID 目标工作区中的分类；为空时按原分类名称匹配，不存在则自动创建
+	TargetCategoryID *uint
+}
+
+// MoveToWorkspace 将当前工作区的元件连同库存记录与关联的预入库记录移动到目标工作区。
+// 供应商按名称在目标工作区匹配或创建；编号在目标工作区冲突时重新分配 HB 编号。
+func (r *ComponentRepository) MoveToWorkspace(params MoveComponentsParams) ([]models.Component, error) {
+	if params.TargetWorkspaceID == r.workspaceID {
+		return nil, ErrSameWorkspace
+	}
+
+	var moved []models.Component
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.Workspace{}, params.TargetWorkspaceID).Error; err != nil {
+			return err
+		}
+		if params.TargetCategoryID != nil {
+			if err := ensureInWorkspace(tx, &models.Category{}, *params.TargetCategoryID, params.TargetWorkspaceID); err != nil {
+				return err
+			}
+		}
+
+		var components []models.Component
+		if err := tx.Where("workspace_id = ? AND id IN ?", r.workspaceID, params.ComponentIDs).
+			Order("id ASC").
+			Find(&components).Error; err != nil {
+			return err
+		}
+		if len(components) != len(params.ComponentIDs) {
+			return gorm.ErrRecordNotFound
+		}
+
+		target := NewComponentRepository(tx).ForWorkspace(params.TargetWorkspaceID)
+		categoryMap := make(map[uint]uint)
+		supplierMap := make(map[uint]uint)
+
+		for _, component := range components {
+			categoryID, err := r.mapCategoryInTx(tx, component.CategoryID, params, categoryMap)
+			if err != nil {
+				return err
+			}
+
+			var supplierID *uint
+			if component.SupplierID != nil {
+				id, err := r.mapSupplierInTx(tx, *component.SupplierID, params.TargetWorkspaceID, supplierMap)
+				if err != nil {
+					return err
+				}
+				supplierID = &id
+			}
+
+			number := component.ComponentNumber
+			if number != nil {
+				taken, err := isComponentNumberTakenInTx(tx, params.TargetWorkspaceID, *number, 0, 0)
+				if err != nil {
+					return err
+				}
+				if taken {
+					number = nil
+				}
+			}
+			if number == nil {
+				next, err := target.generateNextInTx(tx)
+				if err != nil {
+					return err
+				}
+				number = &next
+			}
+
+			if err := tx.Model(&models.Component{}).Where("id = ?", component.ID).Updates(map[string]any{
+				"workspace_id":     params.TargetWorkspaceID,
+				"category_id":      categoryID,
+				"supplier_id":      supplierID,
+				"component_number": number,
+			}).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.StockLog{}).Where("component_id = ?", component.ID).
+				Update("workspace_id", params.TargetWorkspaceID).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.PreStock{}).Where("component_id = ?", component.ID).Updates(map[string]any{
+				"workspace_id":     params.TargetWorkspaceID,
+				"category_id":      categoryID,
+				"supplier_id":      supplierID,
+				"component_number": number,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Preload("Category").Preload("Supplier").
+			Where("id IN ?", params.ComponentIDs).
+			Order("id ASC").
+			Find(&moved).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+func (r *ComponentRepository) mapCategoryInTx(tx *gorm.DB, sourceID uint, params MoveComponentsParams, cache map[uint]uint) (uint, error) {
+	if params.TargetCategoryID != nil {
+		return *params.TargetCategoryID, nil
+	}
+	if id, ok := cache[sourceID]; ok {
+		return id, nil
+	}
+
+	var source models.Category
+	if err := tx.Where("workspace_id = ?", r.workspaceID).First(&source, sourceID).Error; err != nil {
+		return 0, err
+	}
+	target := models.Category{WorkspaceID: params.TargetWorkspaceID, Name: strings.TrimSpace(source.Name)}
+	if err := tx.Where("workspace_id = ? AND name = ?", target.WorkspaceID, target.Name).
+		Order("id ASC").
+		FirstOrCreate(&target).Error; err != nil {
+		return 0, err
+	}
+	cache[sourceID] = target.ID
+	return target.ID, nil
+}
+
+func (r *ComponentRepository) mapSupplierInTx(tx *gorm.DB, sourceID uint, targetWorkspaceID uint, cache map[uint]uint) (uint, error) {
+	if id, ok := cache[sourceID]; ok {
+		return id, nil
+	}
+
+	var source models.Supplier
+	if err := tx.Where("workspace_id = ?", r.workspaceID).First(&source, sourceID).Error; err != nil {
+		return 0, err
+	}
+	target := models.Supplier{WorkspaceID: targetWorkspaceID, Name: source.Name}
+	if err := tx.Where("workspace_id = ? AND name = ?", target.WorkspaceID, target.Name).
+		FirstOrCreate(&target).Error; err != nil {
+		return 0, err
+	}
+	cache[sourceID] = target.ID
+	return target.ID, nil
+}
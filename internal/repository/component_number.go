@@ -33,7 +33,7 @@ func formatHBComponentNumber(seq int) string {
 func (r *ComponentRepository) getMaxHBSequence(tx *gorm.DB) (int, error) {
 	var numbers []string
 	err := tx.Model(&models.Component{}).
-		Where("component_number LIKE ?", componentNumberPrefix+"%").
+		Where("workspace_id = ? AND component_number LIKE ?", r.workspaceID, componentNumberPrefix+"%").
 		Pluck("component_number", &numbers).Error
 	if err != nil {
 		return 0, err
@@ -41,7 +41,7 @@ func (r *ComponentRepository) getMaxHBSequence(tx *gorm.DB) (int, error) {
 
 	var preStockNumbers []string
 	err = tx.Model(&models.PreStock{}).
-		Where("component_number LIKE ?", componentNumberPrefix+"%").
+		Where("workspace_id = ? AND component_number LIKE ?", r.workspaceID, componentNumberPrefix+"%").
 		Pluck("component_number", &preStockNumbers).Error
 	if err != nil {
 		return 0, err
@@ -69,10 +69,10 @@ func (r *ComponentRepository) generateNextInTx(tx *gorm.DB) (string, error) {
 	return formatHBComponentNumber(max + 1), nil
 }
 
-// IsComponentNumberTaken 检查编号是否已被其他元件使用。
+// IsComponentNumberTaken 检查编号是否已被当前工作区的其他元件使用。
 func (r *ComponentRepository) IsComponentNumberTaken(number string, excludeID uint) (bool, error) {
 	var count int64
-	db := r.db.Model(&models.Component{}).Where("component_number = ?", number)
+	db := r.db.Model(&models.Component{}).Where("workspace_id = ? AND component_number = ?", r.workspaceID, number)
 	if excludeID > 0 {
 		db = db.Where("id != ?", excludeID)
 	}
@@ -80,9 +80,10 @@ func (r *ComponentRepository) IsComponentNumberTaken(number string, excludeID ui
 	return count > 0, err
 }
 
-func isComponentNumberTakenInTx(tx *gorm.DB, number string, excludeComponentID uint, excludePreStockID uint) (bool, error) {
+// isComponentNumberTakenInTx 检查编号在工作区内是否已被元件或预入库记录占用（两表共用一个编号序列）
+func isComponentNumberTakenInTx(tx *gorm.DB, workspaceID uint, number string, excludeComponentID uint, excludePreStockID uint) (bool, error) {
 	var componentCount int64
-	componentDB := tx.Model(&models.Component{}).Where("component_number = ?", number)
+	componentDB := tx.Model(&models.Component{}).Where("workspace_id = ? AND component_number = ?", workspaceID, number)
 	if excludeComponentID > 0 {
 		componentDB = componentDB.Where("id != ?", excludeComponentID)
 	}
@@ -94,7 +95,7 @@ func isComponentNumberTakenInTx(tx *gorm.DB, number string, excludeComponentID u
 	}
 
 	var preStockCount int64
-	preStockDB := tx.Model(&models.PreStock{}).Where("component_number = ?", number)
+	preStockDB := tx.Model(&models.PreStock{}).Where("workspace_id = ? AND component_number = ?", workspaceID, number)
 	if excludePreStockID > 0 {
 		preStockDB = preStockDB.Where("id != ?", excludePreStockID)
 	}
@@ -108,7 +109,7 @@ func isComponentNumberTakenInTx(tx *gorm.DB, number string, excludeComponentID u
 func (r *ComponentRepository) AssignComponentNumberForCreate(component *models.Component) error {
 	component.ComponentNumber = NormalizeComponentNumber(component.ComponentNumber)
 	if component.ComponentNumber != nil {
-		taken, err := isComponentNumberTakenInTx(r.db, *component.ComponentNumber, 0, 0)
+		taken, err := isComponentNumberTakenInTx(r.db, r.workspaceID, *component.ComponentNumber, 0, 0)
 		if err != nil {
 			return err
 		}
@@ -146,7 +147,7 @@ func (r *ComponentRepository) ValidateComponentNumberForUpdate(component *models
 		})
 	}
 
-	taken, err := isComponentNumberTakenInTx(r.db, *normalized, component.ID, 0)
+	taken, err := isComponentNumberTakenInTx(r.db, r.workspaceID, *normalized, component.ID, 0)
 	if err != nil {
 		return err
 	}
@@ -157,12 +158,13 @@ func (r *ComponentRepository) ValidateComponentNumberForUpdate(component *models
 	return nil
 }
 
-// GenerateMissingComponentNumbers 为所有未编号元件按 id 顺序批量生成 HB-xxxxxx 编号。
+// GenerateMissingComponentNumbers 为当前工作区所有未编号元件按 id 顺序批量生成 HB-xxxxxx 编号。
 func (r *ComponentRepository) GenerateMissingComponentNumbers() (int64, error) {
 	var updated int64
 	err := r.db.Transaction(func(tx *gorm.DB) error {
 		var components []models.Component
-		if err := tx.Where("component_number IS NULL OR component_number = ''").
+		if err := tx.Where("workspace_id = ?", r.workspaceID).
+			Where("component_number IS NULL OR component_number = ''").
 			Order("id ASC").
 			Find(&components).Error; err != nil {
 			return err
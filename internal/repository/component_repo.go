@@ -15,28 +15,62 @@ var (
 )
 
 type ComponentRepository struct {
-	db *gorm.DB
+	db          *gorm.DB
+	workspaceID uint
 }
 
 func NewComponentRepository(db *gorm.DB) *ComponentRepository {
-	return &ComponentRepository{db: db}
+	return &ComponentRepository{db: db, workspaceID: DefaultWorkspaceID}
+}
+
+// ForWorkspace 返回限定在指定工作区内的仓储
+func (r *ComponentRepository) ForWorkspace(workspaceID uint) *ComponentRepository {
+	return &ComponentRepository{db: r.db, workspaceID: workspaceID}
+}
+
+func (r *ComponentRepository) scoped() *gorm.DB {
+	return inWorkspace(r.db, "components", r.workspaceID)
+}
+
+// validateReferences 校验元件引用的分类与供应商属于当前工作区
+func (r *ComponentRepository) validateReferences(component *models.Component) error {
+	if component.CategoryID != 0 {
+		if err := ensureInWorkspace(r.db, &models.Category{}, component.CategoryID, r.workspaceID); err != nil {
+			return err
+		}
+	}
+	if component.SupplierID != nil {
+		return ensureInWorkspace(r.db, &models.Supplier{}, *component.SupplierID, r.workspaceID)
+	}
+	return nil
 }
 
 // Query 查询参数
 type ComponentQuery struct {
-	CategoryID         *uint
-	Keyword            string
-	ComponentNumber    string
-	Name               string
-	Model              string
-	Manufacturer       string
-	Value              string
-	SupplierName       string
-	SupplierPartNumber string
-	Page               int
-	PageSize           int
-	SortBy             string
-	SortOrder          string
+	CategoryID *uint
+	// IncludeSubcategories 为 true 时 CategoryID 筛选同时包含其所有子孙分类
+	IncludeSubcategories bool
+	Keyword              string
+	ComponentNumber      string
+	Name                 string
+	Model                string
+	Manufacturer         string
+	Value                string
+	SupplierName         string
+	SupplierPartNumber   string
+	// ABCClasses 非空时只返回这些 ABC 分类的元件
+	ABCClasses []string
+	// IDs 非空时只返回这些元件（如批量打印选中的元件）
+	IDs []uint
+	// Filter 查询语言（q 参数）解析结果，与其他条件 AND
+	Filter    *ComponentFilter
+	Page      int
+	PageSize  int
+	SortBy    string
+	SortOrder string
+
+	// fuzzy 容错匹配结果：关键词 → 元件 ID → 编辑距离，由 Search 在精确匹配无结果时填充
+	fuzzy map[string]map[uint]int
 }
 
 // ComponentSortColumns 允许排序的 API 字段名到 SQL 列映射
@@ -57,11 +91,20 @@ var ComponentSortColumns = map[string]string{
 	"datasheet_url":        "components.datasheet_url",
 	"created_at":           "components.created_at",
 	"updated_at":           "components.updated_at",
+	"abc_class":            "COALESCE(components.abc_class, '')",
+	// 消耗预测字段，尚未计算或无消耗的元件可用天数视为无限长
+	"avg_daily_consumption": "COALESCE(component_forecasts.avg_daily_consumption, 0)",
+	"days_of_cover":         "COALESCE(component_forecasts.days_of_cover, 1e9)",
+	"stockout_date":         "COALESCE(component_forecasts.days_of_cover, 1e9)",
+	"reorder_quantity":      "COALESCE(component_forecasts.reorder_quantity, 0)",
 }
 
+// ComponentSortRelevance 按关键词相关度排序，未指定排序且有关键词时默认使用
+const ComponentSortRelevance = "relevance"
+
 func IsValidComponentSortBy(sortBy string) bool {
 	_, ok := ComponentSortColumns[sortBy]
-	return ok
+	return ok || sortBy == ComponentSortRelevance
 }
 
 func applyColumnLikeTokens(db *gorm.DB, column, raw string) *gorm.DB {
@@ -71,28 +114,40 @@ func applyColumnLikeTokens(db *gorm.DB, column, raw string) *gorm.DB {
 	return db
 }
 
-func applyKeywordTokens(db *gorm.DB, keyword string) *gorm.DB {
-	for token := range strings.FieldsSeq(keyword) {
-		pattern := "%" + token + "%"
-		db = db.Where(
-			"components.name LIKE ? OR components.component_number LIKE ? OR components.model LIKE ? OR components.manufacturer LIKE ? OR components.value LIKE ? OR components.supplier_part_number LIKE ? OR components.description LIKE ? OR suppliers.name LIKE ?",
-			pattern, pattern, pattern, pattern, pattern, pattern, pattern, pattern,
-		)
-	}
-	return db
+// keywordLikeCondition 单个词逐列 LIKE；search_keys（拼音与型号三元组）均为小写，按小写匹配
+func keywordLikeCondition(token string) (string, []any) {
+	pattern := "%" + token + "%"
+	return "components.name LIKE ? OR components.component_number LIKE ? OR components.model LIKE ? OR components.manufacturer LIKE ? OR components.value LIKE ? OR components.supplier_part_number LIKE ? OR components.description LIKE ? OR components.search_keys LIKE ? OR suppliers.name LIKE ?",
+		[]any{pattern, pattern, pattern, pattern, pattern, pattern, pattern, strings.ToLower(pattern), pattern}
 }
 
 func needsSupplierJoin(query ComponentQuery) bool {
-	return query.SupplierName != "" || query.Keyword != "" || query.SortBy == "supplier"
+	return query.SupplierName != "" || query.Keyword != "" || query.Filter != nil || query.SortBy == "supplier"
 }
 
 func needsCategoryJoin(query ComponentQuery) bool {
 	return query.SortBy == "category"
 }
 
-func applyComponentSort(db *gorm.DB, query ComponentQuery) *gorm.DB {
+func needsForecastJoin(query ComponentQuery) bool {
+	switch query.SortBy {
+	case "avg_daily_consumption", "days_of_cover", "stockout_date", "reorder_quantity":
+		return true
+	}
+	return false
+}
+
+func applyComponentSort(db *gorm.DB, engine string, query ComponentQuery) *gorm.DB {
 	sortBy := strings.TrimSpace(query.SortBy)
-	if sortBy == "" {
+	if query.Keyword != "" && (sortBy == "" || sortBy == ComponentSortRelevance) {
+		if order := fuzzyOrder(query.fuzzy); order != nil {
+			return db.Order(*order).Order(ComponentSortColumns["updated_at"] + " DESC")
+		}
+		if order := relevanceOrder(engine, query.Keyword); order != nil {
+			return db.Order(*order).Order(ComponentSortColumns["updated_at"] + " DESC")
+		}
+	}
+	if sortBy == "" || sortBy == ComponentSortRelevance {
 		sortBy = "updated_at"
 	}
 
@@ -109,16 +164,21 @@ func applyComponentSort(db *gorm.DB, query ComponentQuery) *gorm.DB {
 	return db.Order(column + " " + order)
 }
 
-// GetAll 获取所有元件（支持分页和搜索）
-func (r *ComponentRepository) GetAll(query ComponentQuery) ([]models.Component, int64, error) {
-	var components []models.Component
-	var total int64
-
-	db := r.db.Model(&models.Component{}).Preload("Category").Preload("Supplier")
+// filtered 按查询条件构建元件查询（不含分页、排序与预加载）
+func (r *ComponentRepository) filtered(query ComponentQuery) (*gorm.DB, error) {
+	db := inWorkspace(r.db.Model(&models.Component{}), "components", r.workspaceID)
 
 	// 分类筛选
 	if query.CategoryID != nil {
-		db = db.Where("category_id = ?", *query.CategoryID)
+		if query.IncludeSubcategories {
+			ids, err := NewCategoryRepository(r.db).ForWorkspace(r.workspaceID).DescendantIDs(*query.CategoryID)
+			if err != nil {
+				return nil, err
+			}
+			db = db.Where("components.category_id IN ?", ids)
+		} else {
+			db = db.Where("components.category_id = ?", *query.CategoryID)
+		}
 	}
 
 	if needsSupplierJoin(query) {
@@ -127,6 +187,9 @@ func (r *ComponentRepository) GetAll(query ComponentQuery) ([]models.Component,
 	if needsCategoryJoin(query) {
 		db = db.Joins("LEFT JOIN categories ON categories.id = components.category_id")
 	}
+	if needsForecastJoin(query) {
+		db = db.Joins("LEFT JOIN component_forecasts ON component_forecasts.component_id = components.id")
+	}
 
 	db = applyColumnLikeTokens(db, "components.component_number", query.ComponentNumber)
 	db = applyColumnLikeTokens(db, "components.name", query.Name)
@@ -135,10 +198,41 @@ func (r *ComponentRepository) GetAll(query ComponentQuery) ([]models.Component,
 	db = applyColumnLikeTokens(db, "components.value", query.Value)
 	db = applyColumnLikeTokens(db, "suppliers.name", query.SupplierName)
 	db = applyColumnLikeTokens(db, "components.supplier_part_number", query.SupplierPartNumber)
+	if len(query.ABCClasses) > 0 {
+		db = db.Where("components.abc_class IN ?", query.ABCClasses)
+	}
+	if len(query.IDs) > 0 {
+		db = db.Where("components.id IN ?", query.IDs)
+	}
 
 	if query.Keyword != "" {
-		db = applyKeywordTokens(db, query.Keyword)
+		db = applyKeywordSearch(db, r.SearchEngine(), query.Keyword, query.fuzzy)
+	}
+	if query.Filter != nil {
+		sql, args, err := r.filterCondition(query.Filter)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(sql, args...)
+	}
+	return db, nil
+}
+
+// SearchEngine 返回关键词搜索使用的实现（全文索引或 LIKE）
+func (r *ComponentRepository) SearchEngine() string {
+	return SearchEngine(r.db)
+}
+
+// GetAll 获取所有元件（支持分页和搜索）
+func (r *ComponentRepository) GetAll(query ComponentQuery) ([]models.Component, int64, error) {
+	var components []models.Component
+	var total int64
+
+	db, err := r.filtered(query)
+	if err != nil {
+		return nil, 0, err
 	}
+	db = db.Preload("Category").Preload("Supplier")
 
 	// 计算总数
 	db.Count(&total)
@@ -149,35 +243,126 @@ func (r *ComponentRepository) GetAll(query ComponentQuery) ([]models.Component,
 		db = db.Offset(offset).Limit(query.PageSize)
 	}
 
-	err := applyComponentSort(db, query).Find(&components).Error
-	return components, total, err
+	if err := applyComponentSort(db, r.SearchEngine(), query).Find(&components).Error; err != nil {
+		return nil, 0, err
+	}
+	return components, total, attachTags(r.db, components)
+}
+
+// Summarize 返回符合查询条件的元件数与库存合计（忽略分页与排序）
+func (r *ComponentRepository) Summarize(query ComponentQuery) (count, totalStock int64, err error) {
+	db, err := r.filtered(query)
+	if err != nil {
+		return 0, 0, err
+	}
+	var result struct {
+		Count int64
+		Total int64
+	}
+	err = db.Select("COUNT(*) AS count, COALESCE(SUM(components.stock_quantity), 0) AS total").Scan(&result).Error
+	return result.Count, result.Total, err
+}
+
+// Each 按查询条件与排序逐行遍历元件（忽略分页），分类与供应商预先加载后填充，用于流式导出
+func (r *ComponentRepository) Each(query ComponentQuery, fn func(*models.Component) error) error {
+	db, err := r.filtered(query)
+	if err != nil {
+		return err
+	}
+	db = applyComponentSort(db.Select("components.*"), r.SearchEngine(), query)
+
+	categories, err := loadByID(r.db, "categories", r.workspaceID, func(c *models.Category) uint { return c.ID })
+	if err != nil {
+		return err
+	}
+	suppliers, err := loadByID(r.db, "suppliers", r.workspaceID, func(s *models.Supplier) uint { return s.ID })
+	if err != nil {
+		return err
+	}
+	return eachRow(db, func(component *models.Component) error {
+		component.Category = categories[component.CategoryID]
+		if component.SupplierID != nil {
+			component.Supplier = suppliers[*component.SupplierID]
+		}
+		return fn(component)
+	})
 }
 
 // GetByID 根据ID获取元件
 func (r *ComponentRepository) GetByID(id uint) (*models.Component, error) {
 	var component models.Component
-	err := r.db.Preload("Category").Preload("Supplier").First(&component, id).Error
-	return &component, err
+	if err := r.scoped().Preload("Category").Preload("Supplier").First(&component, id).Error; err != nil {
+		return &component, err
+	}
+	return &component, attachComponentTags(r.db, &component)
+}
+
+// GetByNumber 根据系统编号获取元件
+func (r *ComponentRepository) GetByNumber(number string) (*models.Component, error) {
+	var component models.Component
+	if err := r.scoped().Preload("Category").Preload("Supplier").Where("component_number = ?", number).First(&component).Error; err != nil {
+		return &component, err
+	}
+	return &component, attachComponentTags(r.db, &component)
 }
 
-// Create 创建元件
+// Create 创建元件，Tags 非 nil 时一并写入标签
 func (r *ComponentRepository) Create(component *models.Component) error {
-	return r.db.Create(component).Error
+	component.WorkspaceID = r.workspaceID
+	if err := r.validateReferences(component); err != nil {
+		return err
+	}
+	return r.saveWithTags(component, true)
 }
 
-// Update 更新元件
+// Update 更新元件，Tags 非 nil 时整体替换标签（调用方需先通过 GetByID 确认元件属于当前工作区）
 func (r *ComponentRepository) Update(component *models.Component) error {
-	return r.db.Save(component).Error
+	component.WorkspaceID = r.workspaceID
+	if err := r.validateReferences(component); err != nil {
+		return err
+	}
+	return r.saveWithTags(component, false)
 }
 
-// Delete 删除元件
+// saveWithTags 在事务中写入元件并替换标签；create 为 false 时整体保存已有元件
+func (r *ComponentRepository) saveWithTags(component *models.Component, create bool) error {
+	var tags []string
+	if component.Tags != nil {
+		var err error
+		if tags, err = NormalizeTags(component.Tags); err != nil {
+			return err
+		}
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		save := tx.Save
+		if create {
+			save = tx.Create
+		}
+		if err := save(component).Error; err != nil {
+			return err
+		}
+		if component.Tags == nil {
+			return nil
+		}
+		component.Tags = tags
+		return replaceTagsTx(tx, component.ID, tags)
+	})
+}
+
+// Delete 删除元件及其标签
 func (r *ComponentRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Component{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := inWorkspace(tx, "components", r.workspaceID).Delete(&models.Component{}, id)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Where("component_id = ?", id).Delete(&models.ComponentTag{}).Error
+	})
 }
 
 // UpdateStock 更新库存数量
 func (r *ComponentRepository) UpdateStock(id uint, amount int) error {
-	return r.db.Model(&models.Component{}).Where("id = ?", id).
+	return r.scoped().Model(&models.Component{}).Where("id = ?", id).
 		UpdateColumn("stock_quantity", gorm.Expr("stock_quantity + ?", amount)).Error
 }
 
@@ -188,12 +373,13 @@ type StockChangeParams struct {
 	Reason          string
 	UnitPriceMicro  int64
 	TotalPriceCents int64
+	Operator        string
 }
 
-func applyStockChangeTx(tx *gorm.DB, params StockChangeParams) (*models.Component, error) {
+func applyStockChangeTx(tx *gorm.DB, workspaceID uint, params StockChangeParams) (*models.Component, error) {
 	var updated models.Component
 	var component models.Component
-	if err := tx.First(&component, params.ComponentID).Error; err != nil {
+	if err := tx.Where("workspace_id = ?", workspaceID).First(&component, params.ComponentID).Error; err != nil {
 		return nil, err
 	}
 
@@ -230,11 +416,13 @@ func applyStockChangeTx(tx *gorm.DB, params StockChangeParams) (*models.Componen
 	}
 
 	log := models.StockLog{
+		WorkspaceID:     component.WorkspaceID,
 		ComponentID:     params.ComponentID,
 		ChangeAmount:    params.Amount,
 		UnitPriceMicro:  logUnitPrice,
 		TotalPriceCents: logTotalPrice,
 		Reason:          params.Reason,
+		Operator:        params.Operator,
 	}
 	if err := tx.Create(&log).Error; err != nil {
 		return nil, err
@@ -251,7 +439,7 @@ func (r *ComponentRepository) ApplyStockChange(params StockChangeParams) (*model
 	var updated *models.Component
 	err := r.db.Transaction(func(tx *gorm.DB) error {
 		var err error
-		updated, err = applyStockChangeTx(tx, params)
+		updated, err = applyStockChangeTx(tx, r.workspaceID, params)
 		return err
 	})
 	if err != nil {
@@ -276,14 +464,14 @@ type BatchStockOutFailure struct {
 }
 
 // BatchApplyStockOut 在单事务中批量出库；任一校验失败则整批回滚
-func (r *ComponentRepository) BatchApplyStockOut(items []BatchStockOutItem, reason string) ([]models.Component, []BatchStockOutFailure, error) {
+func (r *ComponentRepository) BatchApplyStockOut(items []BatchStockOutItem, reason, operator string) ([]models.Component, []BatchStockOutFailure, error) {
 	var updated []models.Component
 	var failures []BatchStockOutFailure
 
 	err := r.db.Transaction(func(tx *gorm.DB) error {
 		for _, item := range items {
 			var component models.Component
-			if err := tx.First(&component, item.ComponentID).Error; err != nil {
+			if err := tx.Where("workspace_id = ?", r.workspaceID).First(&component, item.ComponentID).Error; err != nil {
 				if errors.Is(err, gorm.ErrRecordNotFound) {
 					failures = append(failures, BatchStockOutFailure{
 						ComponentID: item.ComponentID,
@@ -320,10 +508,11 @@ func (r *ComponentRepository) BatchApplyStockOut(items []BatchStockOutItem, reas
 
 		updated = make([]models.Component, 0, len(items))
 		for _, item := range items {
-			component, err := applyStockChangeTx(tx, StockChangeParams{
+			component, err := applyStockChangeTx(tx, r.workspaceID, StockChangeParams{
 				ComponentID: item.ComponentID,
 				Amount:      -item.Quantity,
 				Reason:      reason,
+				Operator:    operator,
 			})
 			if err != nil {
 				return err
@@ -346,14 +535,26 @@ func (r *ComponentRepository) BatchUpdateLocation(ids []uint, location string) (
 	if len(ids) == 0 {
 		return 0, nil
 	}
-	result := r.db.Model(&models.Component{}).Where("id IN ?", ids).Update("location", location)
+	result := r.scoped().Model(&models.Component{}).Where("id IN ?", ids).Update("location", location)
+	return result.RowsAffected, result.Error
+}
+
+// BatchUpdateCategory 批量移动元件到指定分类，分类须属于当前工作区
+func (r *ComponentRepository) BatchUpdateCategory(ids []uint, categoryID uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := ensureInWorkspace(r.db, &models.Category{}, categoryID, r.workspaceID); err != nil {
+		return 0, err
+	}
+	result := r.scoped().Model(&models.Component{}).Where("id IN ?", ids).Update("category_id", categoryID)
 	return result.RowsAffected, result.Error
 }
 
 // GetDistinctPackages 获取历史封装列表（去重、非空、按名称排序）
 func (r *ComponentRepository) GetDistinctPackages() ([]string, error) {
 	var packages []string
-	err := r.db.Model(&models.Component{}).
+	err := r.scoped().Model(&models.Component{}).
 		Where("package <> ''").
 		Distinct("package").
 		Order("package ASC").
@@ -364,7 +565,7 @@ func (r *ComponentRepository) GetDistinctPackages() ([]string, error) {
 // GetDistinctLocations 获取历史位置列表（去重、非空、按名称排序）
 func (r *ComponentRepository) GetDistinctLocations() ([]string, error) {
 	var locations []string
-	err := r.db.Model(&models.Component{}).
+	err := r.scoped().Model(&models.Component{}).
 		Where("location <> ''").
 		Distinct("location").
 		Order("location ASC").
@@ -372,10 +573,32 @@ func (r *ComponentRepository) GetDistinctLocations() ([]string, error) {
 	return locations, err
 }
 
+// LocationCount 存放位置及其中的元件数
+type LocationCount struct {
+	Location string `json:"location"`
+	Count    int    `json:"count"`
+}
+
+// CountByLocation 统计各存放位置的元件数（按位置排序）；prefix 非空时只统计以此开头的位置，locations 非空时只统计这些位置
+func (r *ComponentRepository) CountByLocation(prefix string, locations []string) ([]LocationCount, error) {
+	db := r.scoped().Model(&models.Component{}).
+		Select("location, COUNT(*) AS count").
+		Where("location <> ''")
+	if prefix != "" {
+		db = db.Where("location LIKE ? ESCAPE '!'", likeEscaper.Replace(prefix)+"%")
+	}
+	if len(locations) > 0 {
+		db = db.Where("location IN ?", locations)
+	}
+	var counts []LocationCount
+	err := db.Group("location").Order("location ASC").Scan(&counts).Error
+	return counts, err
+}
+
 // GetDistinctManufacturers 获取历史制造商列表（去重、非空、按名称排序）
 func (r *ComponentRepository) GetDistinctManufacturers() ([]string, error) {
 	var manufacturers []string
-	err := r.db.Model(&models.Component{}).
+	err := r.scoped().Model(&models.Component{}).
 		Where("manufacturer <> ''").
 		Distinct("manufacturer").
 		Order("manufacturer ASC").
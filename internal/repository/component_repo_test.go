@@ -15,7 +15,7 @@ func setupComponentTestDB(t *testing.T) *gorm.DB {
 	if err != nil {
 		t.Fatalf("open db: %v", err)
 	}
-	if err := db.AutoMigrate(&models.Category{}, &models.Supplier{}, &models.Component{}); err != nil {
+	if err := db.AutoMigrate(&models.Category{}, &models.Supplier{}, &models.Component{}, &models.ComponentTag{}); err != nil {
 		t.Fatalf("migrate: %v", err)
 	}
 	return db
@@ -296,7 +296,7 @@ func TestComponentRepositoryBatchApplyStockOutSuccess(t *testing.T) {
 	updated, failures, err := repo.BatchApplyStockOut([]BatchStockOutItem{
 		{ComponentID: resistor.ID, Quantity: 10},
 		{ComponentID: capacitor.ID, Quantity: 5},
-	}, "项目装配")
+	}, "项目装配", "admin")
 	if err != nil {
 		t.Fatalf("BatchApplyStockOut: %v", err)
 	}
@@ -324,11 +324,11 @@ func TestComponentRepositoryBatchApplyStockOutSuccess(t *testing.T) {
 	}
 
 	var logCount int64
-	if err := db.Model(&models.StockLog{}).Count(&logCount).Error; err != nil {
+	if err := db.Model(&models.StockLog{}).Where("operator = ?", "admin").Count(&logCount).Error; err != nil {
 		t.Fatalf("count logs: %v", err)
 	}
 	if logCount != 2 {
-		t.Fatalf("logCount = %d, want 2", logCount)
+		t.Fatalf("logCount = %d, want 2 logs recorded for admin", logCount)
 	}
 }
 
@@ -342,7 +342,7 @@ func TestComponentRepositoryBatchApplyStockOutRollback(t *testing.T) {
 	_, failures, err := repo.BatchApplyStockOut([]BatchStockOutItem{
 		{ComponentID: resistor.ID, Quantity: 10},
 		{ComponentID: esp32.ID, Quantity: 10},
-	}, "项目装配")
+	}, "项目装配", "admin")
 	if !errors.Is(err, ErrBatchStockOutFailed) {
 		t.Fatalf("err = %v, want ErrBatchStockOutFailed", err)
 	}
@@ -372,3 +372,23 @@ func TestComponentRepositoryBatchApplyStockOutRollback(t *testing.T) {
 		t.Fatalf("logCount = %d, want 0 (rollback)", logCount)
 	}
 }
+
+func TestComponentRepositoryCountByLocationPrefix(t *testing.T) {
+	db := setupComponentTestDB(t)
+	for _, location := range []string{"A1-01", "A1-02", "A1_03", "B2"} {
+		if err := db.Create(&models.Component{CategoryID: 1, Name: location, Location: location}).Error; err != nil {
+			t.Fatalf("create component: %v", err)
+		}
+	}
+	repo := NewComponentRepository(db)
+
+	counts, err := repo.CountByLocation("A1", nil)
+	if err != nil || len(counts) != 3 {
+		t.Fatalf("prefix A1 = %+v, %v", counts, err)
+	}
+	// 前缀中的 LIKE 通配符按字面匹配
+	counts, err = repo.CountByLocation("A1_", nil)
+	if err != nil || len(counts) != 1 || counts[0].Location != "A1_03" {
+		t.Fatalf("prefix A1_ = %+v, %v", counts, err)
+	}
+}
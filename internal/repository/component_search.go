@@ -0,0 +1,419 @@
+package repository
+
+import (
+	"html"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/Rehtt/hamster-bin/internal/searchindex"
+	"github.com/Rehtt/hamster-bin/internal/searchkey"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 关键词搜索实现，由数据库中是否存在全文索引决定
+const (
+	SearchEngineFTS5     = "fts5"     // SQLite FTS5（trigram 分词，子串匹配）
+	SearchEngineTSVector = "tsvector" // PostgreSQL tsvector + GIN（按词前缀匹配）
+	SearchEngineFullText = "fulltext" // MySQL FULLTEXT（ngram 分词）
+	SearchEngineLike     = "like"     // 无全文索引时逐列 LIKE
+)
+
+// searchEngines 按数据库（Dialector，事务与会话共用）缓存检测结果；索引在迁移中创建，启动后不再变化
+var searchEngines sync.Map
+
+// SearchEngine 返回当前数据库的关键词搜索实现
+func SearchEngine(db *gorm.DB) string {
+	if engine, ok := searchEngines.Load(db.Dialector); ok {
+		return engine.(string)
+	}
+	engine := detectSearchEngine(db)
+	searchEngines.Store(db.Dialector, engine)
+	return engine
+}
+
+func detectSearchEngine(db *gorm.DB) string {
+	migrator := db.Migrator()
+	switch db.Dialector.Name() {
+	case "sqlite":
+		if migrator.HasTable(searchindex.Table) {
+			return SearchEngineFTS5
+		}
+	case "postgres":
+		if migrator.HasColumn(&models.Component{}, searchindex.VectorColumn) {
+			return SearchEngineTSVector
+		}
+	case "mysql":
+		if migrator.HasIndex(&models.Component{}, searchindex.FullTextIndex) {
+			return SearchEngineFullText
+		}
+	}
+	return SearchEngineLike
+}
+
+// indexable 判断单个关键词能否走全文索引：trigram 至少 3 个字符，ngram 至少 2 个字符；
+// tsvector 的 simple 分词不切分中文，含汉字的词仍用 LIKE
+func indexable(engine, token string) bool {
+	switch engine {
+	case SearchEngineFTS5:
+		return utf8.RuneCountInString(token) >= 3
+	case SearchEngineFullText:
+		return utf8.RuneCountInString(token) >= 2
+	case SearchEngineTSVector:
+		return len(tsWords(token)) > 0 && !strings.ContainsFunc(token, func(r rune) bool { return unicode.Is(unicode.Han, r) })
+	}
+	return false
+}
+
+// tsWords 将关键词按非字母数字拆分为 tsquery 词
+func tsWords(token string) []string {
+	return strings.FieldsFunc(strings.ToLower(token), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// ftsPhrase 返回 FTS5 短语查询
+func ftsPhrase(token string) string {
+	return `"` + strings.ReplaceAll(token, `"`, `""`) + `"`
+}
+
+// tsPrefixQuery 返回按词前缀匹配的 tsquery，多个词之间为 AND
+func tsPrefixQuery(token string) string {
+	words := tsWords(token)
+	for i, word := range words {
+		words[i] = "'" + strings.ReplaceAll(word, "'", "''") + "':*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// fullTextPhrase 返回 MySQL 布尔模式的短语查询
+func fullTextPhrase(token string) string {
+	return `"` + strings.ReplaceAll(token, `"`, " ") + `"`
+}
+
+func fullTextMatch() string {
+	columns := make([]string, len(searchindex.Columns))
+	for i, column := range searchindex.Columns {
+		columns[i] = "components." + column
+	}
+	return "MATCH(" + strings.Join(columns, ", ") + ") AGAINST (? IN BOOLEAN MODE)"
+}
+
+const supplierNameCondition = "components.supplier_id IN (SELECT id FROM suppliers WHERE suppliers.name LIKE ?)"
+
+// applyKeywordSearch 每个关键词都必须命中（AND）；fuzzy 中的词改为限定容错匹配到的元件
+func applyKeywordSearch(db *gorm.DB, engine, keyword string, fuzzy map[string]map[uint]int) *gorm.DB {
+	for token := range strings.FieldsSeq(keyword) {
+		if matches, ok := fuzzy[token]; ok {
+			db = db.Where("components.id IN ?", slices.Collect(maps.Keys(matches)))
+			continue
+		}
+		sql, args := keywordCondition(engine, token)
+		db = db.Where(sql, args...)
+	}
+	return db
+}
+
+// keywordCondition 返回单个关键词的匹配条件：可走索引的词查全文索引或供应商名称，其余词逐列 LIKE
+func keywordCondition(engine, token string) (string, []any) {
+	if !indexable(engine, token) {
+		return keywordLikeCondition(token)
+	}
+	pattern := "%" + token + "%"
+	switch engine {
+	case SearchEngineFTS5:
+		return "components.id IN (SELECT rowid FROM " + searchindex.Table + " WHERE " + searchindex.Table + " MATCH ?) OR " + supplierNameCondition, []any{ftsPhrase(token), pattern}
+	case SearchEngineTSVector:
+		return "components." + searchindex.VectorColumn + " @@ to_tsquery('simple', ?) OR " + supplierNameCondition, []any{tsPrefixQuery(token), pattern}
+	case SearchEngineFullText:
+		return "components.id IN (SELECT id FROM components WHERE " + fullTextMatch() + ") OR " + supplierNameCondition, []any{fullTextPhrase(token), pattern}
+	}
+	return keywordLikeCondition(token)
+}
+
+// relevanceOrder 返回按相关度排序的表达式（任一可走索引的词命中即参与打分）；无法打分时返回 nil
+func relevanceOrder(engine, keyword string) *clause.OrderBy {
+	var terms []string
+	for token := range strings.FieldsSeq(keyword) {
+		if !indexable(engine, token) {
+			continue
+		}
+		switch engine {
+		case SearchEngineFTS5:
+			terms = append(terms, ftsPhrase(token))
+		case SearchEngineTSVector:
+			terms = append(terms, "("+tsPrefixQuery(token)+")")
+		case SearchEngineFullText:
+			terms = append(terms, fullTextPhrase(token))
+		}
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	switch engine {
+	case SearchEngineFTS5:
+		return relevanceOrderExpr(engine, strings.Join(terms, " OR "))
+	case SearchEngineTSVector:
+		return relevanceOrderExpr(engine, strings.Join(terms, " | "))
+	}
+	return relevanceOrderExpr(engine, strings.Join(terms, " "))
+}
+
+// relevanceOrderExpr 返回按全文索引查询 query 的得分排序的表达式
+func relevanceOrderExpr(engine, query string) *clause.OrderBy {
+	var expr clause.Expr
+	switch engine {
+	case SearchEngineFTS5:
+		// bm25 越小越相关；未命中索引（仅供应商名称匹配）的排在最后
+		expr = clause.Expr{
+			SQL:  "COALESCE((SELECT bm25(" + searchindex.Table + ") FROM " + searchindex.Table + " WHERE " + searchindex.Table + " MATCH ? AND " + searchindex.Table + ".rowid = components.id), 0) ASC",
+			Vars: []any{query},
+		}
+	case SearchEngineTSVector:
+		expr = clause.Expr{
+			SQL:  "ts_rank_cd(components." + searchindex.VectorColumn + ", to_tsquery('simple', ?)) DESC",
+			Vars: []any{query},
+		}
+	case SearchEngineFullText:
+		expr = clause.Expr{SQL: fullTextMatch() + " DESC", Vars: []any{query}}
+	}
+	return &clause.OrderBy{Expression: expr}
+}
+
+// fuzzyCandidateLimit 每个关键词容错匹配时最多取回并计算编辑距离的候选元件数
+const fuzzyCandidateLimit = 200
+
+// Search 按关键词搜索元件：先按 GetAll 精确匹配；无结果时，形似型号的关键词（见 searchkey.IsPartNumber）
+// 改为容错匹配——按型号三元组取回候选，再与型号、供应商料号及名称中的型号词计算子串编辑距离，
+// 不超过 searchkey.MaxDistance 即命中，按距离排序。fuzzy 表示结果来自容错匹配。
+func (r *ComponentRepository) Search(query ComponentQuery) (components []models.Component, total int64, fuzzy bool, err error) {
+	components, total, err = r.GetAll(query)
+	if err != nil || total > 0 || strings.TrimSpace(query.Keyword) == "" {
+		return components, total, false, err
+	}
+	matches := make(map[string]map[uint]int)
+	for token := range strings.FieldsSeq(query.Keyword) {
+		if !searchkey.IsPartNumber(token) {
+			continue
+		}
+		if matches[token], err = r.fuzzyMatches(token); err != nil {
+			return nil, 0, false, err
+		}
+	}
+	if len(matches) == 0 {
+		return components, total, false, nil
+	}
+	query.fuzzy = matches
+	components, total, err = r.GetAll(query)
+	return components, total, total > 0, err
+}
+
+// fuzzyMatches 返回与关键词容错匹配的元件 ID 及编辑距离
+func (r *ComponentRepository) fuzzyMatches(token string) (map[uint]int, error) {
+	where, order := anyTrigramCondition(r.SearchEngine(), searchkey.Trigrams(token))
+	var candidates []models.Component
+	err := inWorkspace(r.db.Model(&models.Component{}), "components", r.workspaceID).
+		Select("components.id", "components.name", "components.model", "components.supplier_part_number").
+		Where(where).Order(order).Limit(fuzzyCandidateLimit).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	maxDistance := searchkey.MaxDistance(token)
+	matches := make(map[uint]int)
+	for _, candidate := range candidates {
+		best := maxDistance + 1
+		for _, text := range append([]string{candidate.Model, candidate.SupplierPartNumber}, searchkey.PartNumberWords(candidate.Name)...) {
+			if text != "" {
+				best = min(best, searchkey.SubstringDistance(token, text))
+			}
+		}
+		if best <= maxDistance {
+			matches[candidate.ID] = best
+		}
+	}
+	return matches, nil
+}
+
+// anyTrigramCondition 返回命中任一三元组的条件及按命中程度降序的排序表达式
+func anyTrigramCondition(engine string, grams []string) (clause.Expr, clause.OrderBy) {
+	switch engine {
+	case SearchEngineFTS5:
+		terms := make([]string, len(grams))
+		for i, gram := range grams {
+			terms[i] = ftsPhrase(gram)
+		}
+		query := strings.Join(terms, " OR ")
+		return clause.Expr{SQL: "components.id IN (SELECT rowid FROM " + searchindex.Table + " WHERE " + searchindex.Table + " MATCH ?)", Vars: []any{query}},
+			*relevanceOrderExpr(engine, query)
+	case SearchEngineTSVector:
+		terms := make([]string, len(grams))
+		for i, gram := range grams {
+			terms[i] = "'" + strings.ReplaceAll(gram, "'", "''") + "'"
+		}
+		query := strings.Join(terms, " | ")
+		return clause.Expr{SQL: "components." + searchindex.VectorColumn + " @@ to_tsquery('simple', ?)", Vars: []any{query}},
+			*relevanceOrderExpr(engine, query)
+	case SearchEngineFullText:
+		query := strings.Join(grams, " ")
+		return clause.Expr{SQL: fullTextMatch(), Vars: []any{query}}, *relevanceOrderExpr(engine, query)
+	}
+	conditions := make([]string, len(grams))
+	vars := make([]any, len(grams))
+	for i, gram := range grams {
+		conditions[i] = "components.search_keys LIKE ?"
+		vars[i] = "%" + gram + "%"
+	}
+	hits := make([]string, len(grams))
+	for i := range grams {
+		hits[i] = "(CASE WHEN components.search_keys LIKE ? THEN 1 ELSE 0 END)"
+	}
+	return clause.Expr{SQL: strings.Join(conditions, " OR "), Vars: vars},
+		clause.OrderBy{Expression: clause.Expr{SQL: strings.Join(hits, " + ") + " DESC", Vars: vars}}
+}
+
+// fuzzyOrder 按容错匹配的编辑距离（多个词时求和）升序排序；未使用容错匹配时返回 nil
+func fuzzyOrder(fuzzy map[string]map[uint]int) *clause.OrderBy {
+	if len(fuzzy) == 0 {
+		return nil
+	}
+	distances := make(map[uint]int)
+	for _, matches := range fuzzy {
+		for id, distance := range matches {
+			distances[id] += distance
+		}
+	}
+	if len(distances) == 0 {
+		return nil
+	}
+	var sql strings.Builder
+	vars := make([]any, 0, len(distances)*2)
+	sql.WriteString("CASE components.id")
+	for _, id := range slices.Sorted(maps.Keys(distances)) {
+		sql.WriteString(" WHEN ? THEN ?")
+		vars = append(vars, id, distances[id])
+	}
+	sql.WriteString(" END ASC")
+	return &clause.OrderBy{Expression: clause.Expr{SQL: sql.String(), Vars: vars}}
+}
+
+// SearchHighlight 命中关键词的字段片段，Snippet 已做 HTML 转义，命中部分以 <mark> 包裹
+type SearchHighlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+}
+
+// ComponentSearchResult 关键词搜索结果：元件及命中片段
+type ComponentSearchResult struct {
+	models.Component
+	Highlights []SearchHighlight `json:"highlights"`
+}
+
+const (
+	// snippetRunes 片段最大长度，超出时以首个命中位置为中心截取
+	snippetRunes   = 80
+	snippetContext = 30
+)
+
+// HighlightComponent 返回元件中命中关键词的字段片段，按搜索字段顺序排列
+func HighlightComponent(component *models.Component, keyword string) []SearchHighlight {
+	var tokens [][]rune
+	for token := range strings.FieldsSeq(keyword) {
+		tokens = append(tokens, lowerRunes(token))
+	}
+	fields := []struct{ name, text string }{
+		{"name", component.Name},
+		{"component_number", stringValue(component.ComponentNumber)},
+		{"model", component.Model},
+		{"manufacturer", component.Manufacturer},
+		{"value", component.Value},
+		{"supplier_part_number", component.SupplierPartNumber},
+		{"description", component.Description},
+	}
+	if component.Supplier != nil {
+		fields = append(fields, struct{ name, text string }{"supplier", component.Supplier.Name})
+	}
+
+	highlights := []SearchHighlight{}
+	for _, field := range fields {
+		if snippet, ok := highlightText(field.text, tokens); ok {
+			highlights = append(highlights, SearchHighlight{Field: field.name, Snippet: snippet})
+		}
+	}
+	return highlights
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func lowerRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// highlightText 不区分大小写标记全部命中位置，返回转义后的片段
+func highlightText(text string, tokens [][]rune) (string, bool) {
+	runes := []rune(text)
+	lower := lowerRunes(text)
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, token := range tokens {
+		if len(token) == 0 {
+			continue
+		}
+		for i := 0; i+len(token) <= len(lower); i++ {
+			if string(lower[i:i+len(token)]) != string(token) {
+				continue
+			}
+			for j := i; j < i+len(token); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	start, end := 0, len(runes)
+	if len(runes) > snippetRunes {
+		start = max(0, first-snippetContext)
+		end = min(len(runes), start+snippetRunes)
+	}
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			b.WriteString("<mark>" + segment + "</mark>")
+		} else {
+			b.WriteString(segment)
+		}
+		i = j
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
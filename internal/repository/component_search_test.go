@@ -0,0 +1,278 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/Rehtt/hamster-bin/internal/searchindex"
+	"gorm.io/gorm"
+)
+
+func setupSearchTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := setupComponentTestDB(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("db: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	seedComponentFixtures(t, db)
+	// 索引在已有数据之后创建，验证 rebuild 填充
+	if err := searchindex.Ensure(db); err != nil {
+		t.Fatalf("searchindex.Ensure: %v", err)
+	}
+	if err := searchindex.Ensure(db); err != nil {
+		t.Fatalf("searchindex.Ensure again: %v", err)
+	}
+	return db
+}
+
+func TestComponentFullTextSearch(t *testing.T) {
+	db := setupSearchTestDB(t)
+	repo := NewComponentRepository(db)
+	if engine := repo.SearchEngine(); engine != SearchEngineFTS5 {
+		t.Fatalf("engine = %s, want fts5", engine)
+	}
+
+	cases := []struct {
+		keyword string
+		want    []string
+	}{
+		{"0603", []string{"贴片电阻", "贴片电容"}},
+		{"rc0603", []string{"贴片电阻"}},
+		{"去耦电容", []string{"贴片电容"}},
+		// 少于 3 个字符的词走 LIKE
+		{"10k", []string{"贴片电阻"}},
+		{"模块", []string{"ESP32 模块"}},
+		// 供应商名称不在索引中，仍可匹配
+		{"嘉立创", []string{"贴片电阻"}},
+		{"YAGEO 100nF", []string{"贴片电容"}},
+		{"不存在的型号", nil},
+	}
+	for _, tc := range cases {
+		items, total, err := repo.GetAll(ComponentQuery{Keyword: tc.keyword})
+		if err != nil {
+			t.Fatalf("GetAll(%q): %v", tc.keyword, err)
+		}
+		got := componentNames(items)
+		if int(total) != len(tc.want) || len(got) != len(tc.want) {
+			t.Fatalf("GetAll(%q) = %v (total %d), want %v", tc.keyword, got, total, tc.want)
+		}
+		for _, name := range tc.want {
+			if componentByName(items, name).Name == "" {
+				t.Fatalf("GetAll(%q) = %v, want %v", tc.keyword, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestComponentFullTextSearchRanksByRelevance(t *testing.T) {
+	db := setupSearchTestDB(t)
+	repo := NewComponentRepository(db)
+	// 描述中多次提到 ESP32 的元件排在名称只提一次的元件之前
+	mustCreateComponent(t, db, &models.Component{CategoryID: 1, Name: "开发板", Description: "ESP32 ESP32 ESP32 核心板"})
+
+	items, _, err := repo.GetAll(ComponentQuery{Keyword: "esp32"})
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if got := componentNames(items); len(got) != 2 || got[0] != "开发板" {
+		t.Fatalf("relevance order = %v", got)
+	}
+
+	// 显式指定排序字段时不按相关度
+	items, _, err = repo.GetAll(ComponentQuery{Keyword: "esp32", SortBy: "name", SortOrder: "desc"})
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if got := componentNames(items); len(got) != 2 || got[0] != "开发板" || got[1] != "ESP32 模块" {
+		t.Fatalf("name order = %v", got)
+	}
+}
+
+func TestComponentFullTextSearchFollowsWrites(t *testing.T) {
+	db := setupSearchTestDB(t)
+	repo := NewComponentRepository(db)
+
+	component := models.Component{CategoryID: 1, Name: "稳压芯片", Model: "AMS1117-3.3"}
+	mustCreateComponent(t, db, &component)
+	assertKeywordNames(t, repo, "ams1117", "稳压芯片")
+
+	component.Model = "LM7805"
+	if err := repo.Update(&component); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	assertKeywordNames(t, repo, "ams1117")
+	assertKeywordNames(t, repo, "lm7805", "稳压芯片")
+
+	// 只改库存不影响索引
+	if err := repo.UpdateStock(component.ID, 5); err != nil {
+		t.Fatalf("UpdateStock: %v", err)
+	}
+	assertKeywordNames(t, repo, "lm7805", "稳压芯片")
+
+	if err := repo.Delete(component.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	assertKeywordNames(t, repo, "lm7805")
+}
+
+func TestComponentKeywordSearchWithoutIndex(t *testing.T) {
+	db := setupComponentTestDB(t)
+	seedComponentFixtures(t, db)
+	repo := NewComponentRepository(db)
+	if engine := repo.SearchEngine(); engine != SearchEngineLike {
+		t.Fatalf("engine = %s, want like", engine)
+	}
+	assertKeywordNames(t, repo, "去耦电容", "贴片电容")
+
+	items, _, err := repo.GetAll(ComponentQuery{Keyword: "0603", SortBy: ComponentSortRelevance})
+	if err != nil || len(items) != 2 {
+		t.Fatalf("relevance without index = %v, %v", componentNames(items), err)
+	}
+}
+
+func TestHighlightComponent(t *testing.T) {
+	component := models.Component{
+		Name:        "贴片电阻 <0603>",
+		Model:       "RC0603FR-0710KL",
+		Description: "这是一段很长的描述，" + strings.Repeat("填充", 40) + "包含 0603 封装" + strings.Repeat("尾部", 40),
+		Supplier:    &models.Supplier{Name: "嘉立创"},
+	}
+	highlights := HighlightComponent(&component, "0603 嘉立")
+	want := map[string]string{
+		"name":     "贴片电阻 &lt;<mark>0603</mark>&gt;",
+		"model":    "RC<mark>0603</mark>FR-0710KL",
+		"supplier": "<mark>嘉立</mark>创",
+	}
+	if len(highlights) != 4 {
+		t.Fatalf("highlights = %+v", highlights)
+	}
+	for _, h := range highlights {
+		if expected, ok := want[h.Field]; ok && h.Snippet != expected {
+			t.Fatalf("%s snippet = %q, want %q", h.Field, h.Snippet, expected)
+		}
+		if h.Field == "description" {
+			runes := []rune(h.Snippet)
+			if runes[0] != '…' || runes[len(runes)-1] != '…' || len(runes) > snippetRunes+2+len("<mark></mark>") {
+				t.Fatalf("description snippet not trimmed: %q", h.Snippet)
+			}
+		}
+	}
+}
+
+func mustCreateComponent(t *testing.T, db *gorm.DB, component *models.Component) {
+	t.Helper()
+	if err := db.Create(component).Error; err != nil {
+		t.Fatalf("create component: %v", err)
+	}
+}
+
+func assertKeywordNames(t *testing.T, repo *ComponentRepository, keyword string, want ...string) {
+	t.Helper()
+	items, _, err := repo.GetAll(ComponentQuery{Keyword: keyword})
+	if err != nil {
+		t.Fatalf("GetAll(%q): %v", keyword, err)
+	}
+	got := componentNames(items)
+	if len(got) != len(want) {
+		t.Fatalf("GetAll(%q) = %v, want %v", keyword, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("GetAll(%q) = %v, want %v", keyword, got, want)
+		}
+	}
+}
+
+func TestComponentPinyinSearch(t *testing.T) {
+	for name, setup := range map[string]func(*testing.T) *gorm.DB{
+		"fts5": setupSearchTestDB,
+		"like": func(t *testing.T) *gorm.DB {
+			db := setupComponentTestDB(t)
+			seedComponentFixtures(t, db)
+			return db
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			repo := NewComponentRepository(setup(t))
+			assertKeywordNames(t, repo, "tpdz", "贴片电阻")
+			assertKeywordNames(t, repo, "dianrong", "贴片电容")
+			assertKeywordNames(t, repo, "mokuai", "ESP32 模块")
+		})
+	}
+}
+
+func TestComponentSearchKeysFollowWrites(t *testing.T) {
+	db := setupSearchTestDB(t)
+	repo := NewComponentRepository(db)
+	component := componentByName(mustGetAll(t, repo), "贴片电阻")
+
+	// 按列更新其他字段不会清空搜索键
+	if _, err := repo.BatchUpdateLocation([]uint{component.ID}, "A1-01"); err != nil {
+		t.Fatalf("BatchUpdateLocation: %v", err)
+	}
+	assertKeywordNames(t, repo, "tpdz", "贴片电阻")
+
+	component.Name = "钽电容"
+	if err := repo.Update(&component); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	assertKeywordNames(t, repo, "tpdz")
+	assertKeywordNames(t, repo, "tdr", "钽电容")
+}
+
+func TestComponentFuzzyModelSearch(t *testing.T) {
+	for name, setup := range map[string]func(*testing.T) *gorm.DB{
+		"fts5": setupSearchTestDB,
+		"like": func(t *testing.T) *gorm.DB {
+			db := setupComponentTestDB(t)
+			seedComponentFixtures(t, db)
+			return db
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			db := setup(t)
+			repo := NewComponentRepository(db)
+			mustCreateComponent(t, db, &models.Component{CategoryID: 1, Name: "单片机", Model: "STM32F103C8T6"})
+			mustCreateComponent(t, db, &models.Component{CategoryID: 1, Name: "单片机", Model: "STM32F030F4P6"})
+
+			cases := []struct {
+				keyword string
+				want    []string
+				fuzzy   bool
+			}{
+				{"STM32F10C8", []string{"STM32F103C8T6"}, true},
+				{"CC0603KRX7R9B104", []string{"CC0603KRX7R9BB104"}, true},
+				{"单片机 STM32F10C8", []string{"STM32F103C8T6"}, true},
+				{"单片机 stm32f03", []string{"STM32F030F4P6"}, false},
+				{"STM32F103", []string{"STM32F103C8T6"}, false},
+				{"XYZ99999", nil, false},
+				{"电感", nil, false},
+			}
+			for _, tc := range cases {
+				items, total, fuzzy, err := repo.Search(ComponentQuery{Keyword: tc.keyword})
+				if err != nil {
+					t.Fatalf("Search(%q): %v", tc.keyword, err)
+				}
+				var models []string
+				for _, item := range items {
+					models = append(models, item.Model)
+				}
+				if fuzzy != tc.fuzzy || int(total) != len(tc.want) || strings.Join(models, ",") != strings.Join(tc.want, ",") {
+					t.Fatalf("Search(%q) = %v (total %d, fuzzy %v), want %v (fuzzy %v)", tc.keyword, models, total, fuzzy, tc.want, tc.fuzzy)
+				}
+			}
+		})
+	}
+}
+
+func mustGetAll(t *testing.T, repo *ComponentRepository) []models.Component {
+	t.Helper()
+	items, _, err := repo.GetAll(ComponentQuery{})
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	return items
+}
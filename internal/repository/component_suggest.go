@@ -0,0 +1,298 @@
+package repository
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Rehtt/hamster-bin/internal/searchkey"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultSuggestionLimit 输入提示默认返回的条数
+	DefaultSuggestionLimit = 10
+	// MaxSuggestionLimit 输入提示单次最多返回的条数
+	MaxSuggestionLimit = 50
+	// suggestionCacheTTL 缓存的最长有效期；本进程内的写入提交后即失效缓存，
+	// 该期限兜底其他进程（共用 MySQL/PostgreSQL 的多实例）的写入与超出 suggestionSettleWindow 的长事务
+	suggestionCacheTTL = time.Minute
+	// suggestionSettleWindow 显式事务内写入后的观察期。GORM 没有提交回调，事务内的写入只能在提交前失效缓存，
+	// 期间读到的仍是提交前的数据，因此观察期内加载的取值只缓存到观察期结束；写入后超过该时长才提交的事务，
+	// 其改动最多要等 suggestionCacheTTL 才反映到提示中
+	suggestionSettleWindow = 10 * time.Second
+	// minFuzzySuggestionLength 规范化后至少这么多字符才做容错匹配，避免短前缀匹配出大量无关值
+	minFuzzySuggestionLength = 4
+)
+
+var ErrUnknownSuggestionField = errors.New("不支持的提示字段")
+
+// suggestionSources 各提示字段的取值来源：元件列直接分组计数，供应商与分类按名称统计引用的元件数（含未被引用的）
+var suggestionSources = map[string]func(db *gorm.DB, workspaceID uint) *gorm.DB{
+	"package":      componentColumnSource("package"),
+	"location":     componentColumnSource("location"),
+	"manufacturer": componentColumnSource("manufacturer"),
+	"value":        componentColumnSource("value"),
+	"model":        componentColumnSource("model"),
+	"supplier": func(db *gorm.DB, workspaceID uint) *gorm.DB {
+		return inWorkspace(db.Table("suppliers"), "suppliers", workspaceID).
+			Select("suppliers.name AS value, COUNT(components.id) AS count").
+			Joins("LEFT JOIN components ON components.supplier_id = suppliers.id").
+			Group("suppliers.name")
+	},
+	"category": func(db *gorm.DB, workspaceID uint) *gorm.DB {
+		return inWorkspace(db.Table("categories"), "categories", workspaceID).
+			Select("categories.name AS value, COUNT(components.id) AS count").
+			Joins("LEFT JOIN components ON components.category_id = categories.id").
+			Group("categories.name")
+	},
+}
+
+func componentColumnSource(column string) func(db *gorm.DB, workspaceID uint) *gorm.DB {
+	return func(db *gorm.DB, workspaceID uint) *gorm.DB {
+		return inWorkspace(db.Table("components"), "components", workspaceID).
+			Select(column + " AS value, COUNT(*) AS count").
+			Where(column + " <> ''").
+			Group(column)
+	}
+}
+
+// SuggestionFields 返回支持输入提示的字段名（按名称排序）
+func SuggestionFields() []string {
+	fields := make([]string, 0, len(suggestionSources))
+	for field := range suggestionSources {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+// Suggestion 输入提示的一项：取值、使用该值的元件数，以及是否为容错匹配
+type Suggestion struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+	Fuzzy bool   `json:"fuzzy"`
+}
+
+// Suggest 返回字段 field 中与 prefix 匹配的前 limit 个取值。匹配优先级：整值前缀（忽略大小写与分隔符）、
+// 词或拼音前缀（"dz" 匹配「电阻」）、包含，最后是型号容错匹配；同级按使用次数降序、再按取值排序。
+// prefix 为空时按使用次数返回最常用的取值。各字段的取值与计数按工作区缓存，元件、分类或供应商写入后失效
+func (r *ComponentRepository) Suggest(field, prefix string, limit int) ([]Suggestion, error) {
+	if _, ok := suggestionSources[field]; !ok {
+		return nil, ErrUnknownSuggestionField
+	}
+	if limit <= 0 {
+		limit = DefaultSuggestionLimit
+	}
+	limit = min(limit, MaxSuggestionLimit)
+
+	values, err := suggestionCache.load(r.db, r.workspaceID, field)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix = strings.TrimSpace(prefix)
+	type ranked struct {
+		Suggestion
+		rank int
+	}
+	var matches []ranked
+	for _, value := range values {
+		rank, ok := value.match(prefix)
+		if !ok {
+			continue
+		}
+		matches = append(matches, ranked{
+			Suggestion: Suggestion{Value: value.value, Count: value.count, Fuzzy: rank == suggestionRankFuzzy},
+			rank:       rank,
+		})
+	}
+	slices.SortFunc(matches, func(a, b ranked) int {
+		return cmp.Or(cmp.Compare(a.rank, b.rank), cmp.Compare(b.Count, a.Count), strings.Compare(a.Value, b.Value))
+	})
+
+	suggestions := make([]Suggestion, 0, min(limit, len(matches)))
+	for _, match := range matches[:min(limit, len(matches))] {
+		suggestions = append(suggestions, match.Suggestion)
+	}
+	return suggestions, nil
+}
+
+const (
+	suggestionRankPrefix = iota
+	suggestionRankWord
+	suggestionRankContains
+	suggestionRankFuzzy
+)
+
+// suggestionValue 缓存的取值，附带预先计算的匹配键
+type suggestionValue struct {
+	value      string
+	count      int64
+	lower      string
+	normalized string
+	// words 按空白与分隔符拆出的词，以及汉字片段的全拼与首字母
+	words []string
+}
+
+func newSuggestionValue(value string, count int64) suggestionValue {
+	lower := strings.ToLower(value)
+	words := strings.FieldsFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return suggestionValue{
+		value:      value,
+		count:      count,
+		lower:      lower,
+		normalized: searchkey.Normalize(value),
+		words:      append(words, searchkey.PinyinKeys(value)...),
+	}
+}
+
+// match 返回取值与 prefix 的匹配级别
+func (v suggestionValue) match(prefix string) (int, bool) {
+	if prefix == "" {
+		return suggestionRankPrefix, true
+	}
+	lower := strings.ToLower(prefix)
+	normalized := searchkey.Normalize(prefix)
+	switch {
+	case strings.HasPrefix(v.lower, lower), normalized != "" && strings.HasPrefix(v.normalized, normalized):
+		return suggestionRankPrefix, true
+	case slices.ContainsFunc(v.words, func(word string) bool { return strings.HasPrefix(word, lower) }):
+		return suggestionRankWord, true
+	case strings.Contains(v.lower, lower), normalized != "" && strings.Contains(v.normalized, normalized):
+		return suggestionRankContains, true
+	case len([]rune(normalized)) >= minFuzzySuggestionLength &&
+		searchkey.SubstringDistance(normalized, v.normalized) <= searchkey.MaxDistance(normalized):
+		return suggestionRankFuzzy, true
+	}
+	return 0, false
+}
+
+type suggestionCacheKey struct {
+	dialector   gorm.Dialector
+	workspaceID uint
+	field       string
+}
+
+type suggestionCacheEntry struct {
+	values    []suggestionValue
+	expiresAt time.Time
+}
+
+// suggestionCacheStore 按数据库（Dialector）、工作区与字段缓存提示取值
+type suggestionCacheStore struct {
+	mu      sync.Mutex
+	entries map[suggestionCacheKey]suggestionCacheEntry
+	// generation 每次失效递增；加载期间发生失效时不写入缓存，避免缓存失效前读到的旧值
+	generation uint64
+	// settleUntil 各数据库显式事务写入后的观察期截止时间
+	settleUntil map[gorm.Dialector]time.Time
+}
+
+var suggestionCache = &suggestionCacheStore{
+	entries:     map[suggestionCacheKey]suggestionCacheEntry{},
+	settleUntil: map[gorm.Dialector]time.Time{},
+}
+
+func (s *suggestionCacheStore) load(db *gorm.DB, workspaceID uint, field string) ([]suggestionValue, error) {
+	key := suggestionCacheKey{dialector: db.Dialector, workspaceID: workspaceID, field: field}
+	s.mu.Lock()
+	entry, ok := s.entries[key]
+	generation := s.generation
+	s.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.values, nil
+	}
+
+	var rows []struct {
+		Value string
+		Count int64
+	}
+	if err := suggestionSources[field](db.Session(&gorm.Session{NewDB: true}), workspaceID).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	values := make([]suggestionValue, 0, len(rows))
+	for _, row := range rows {
+		if strings.TrimSpace(row.Value) == "" {
+			continue
+		}
+		values = append(values, newSuggestionValue(row.Value, row.Count))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generation == generation {
+		now := time.Now()
+		expiresAt := now.Add(suggestionCacheTTL)
+		if until := s.settleUntil[key.dialector]; now.Before(until) {
+			expiresAt = until
+		}
+		s.entries[key] = suggestionCacheEntry{values: values, expiresAt: expiresAt}
+	}
+	return values, nil
+}
+
+// invalidate 清除该数据库下全部工作区的缓存；settle 为 true 表示写入尚未提交，开启观察期
+func (s *suggestionCacheStore) invalidate(dialector gorm.Dialector, settle bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+	if settle {
+		s.settleUntil[dialector] = time.Now().Add(suggestionSettleWindow)
+	}
+	for key := range s.entries {
+		if key.dialector == dialector {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// suggestionTables 写入后需要失效提示缓存的表
+var suggestionTables = []string{"components", "suppliers", "categories"}
+
+// RegisterSuggestionCacheInvalidation 在 db 上注册写入回调：创建、更新、删除上述表或执行涉及它们的原生 SQL 后清除提示缓存。
+// 单条写入的回调排在 GORM 默认事务提交之后；显式事务内的写入在提交前失效并开启观察期（见 suggestionSettleWindow）。
+// 在打开数据库连接后调用一次
+func RegisterSuggestionCacheInvalidation(db *gorm.DB) error {
+	invalidate := func(tx *gorm.DB) {
+		if tx.Error != nil || tx.Statement == nil {
+			return
+		}
+		table := tx.Statement.Table
+		if table == "" && tx.Statement.Schema != nil {
+			table = tx.Statement.Schema.Table
+		}
+		if slices.Contains(suggestionTables, table) {
+			suggestionCache.invalidate(tx.Dialector, inTransaction(tx))
+		}
+	}
+	invalidateRaw := func(tx *gorm.DB) {
+		if tx.Error != nil || tx.Statement == nil {
+			return
+		}
+		sql := strings.ToLower(tx.Statement.SQL.String())
+		if slices.ContainsFunc(suggestionTables, func(table string) bool { return strings.Contains(sql, table) }) {
+			suggestionCache.invalidate(tx.Dialector, inTransaction(tx))
+		}
+	}
+	const name = "repository:invalidate_suggestions"
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().After("gorm:commit_or_rollback_transaction").Register(name, invalidate),
+		callbacks.Update().After("gorm:commit_or_rollback_transaction").Register(name, invalidate),
+		callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register(name, invalidate),
+		callbacks.Raw().After("gorm:raw").Register(name, invalidateRaw),
+	)
+}
+
+// inTransaction 判断写入是否仍处于未提交的显式事务中（默认事务此时已提交，连接已恢复为连接池）
+func inTransaction(tx *gorm.DB) bool {
+	_, ok := tx.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestComponentSuggest(t *testing.T) {
+	db := setupComponentTestDB(t)
+	if err := RegisterSuggestionCacheInvalidation(db); err != nil {
+		t.Fatalf("RegisterSuggestionCacheInvalidation: %v", err)
+	}
+	seedFilterFixtures(t, db)
+	var smd models.Category
+	if err := db.Where("name = ?", "贴片电阻").First(&smd).Error; err != nil {
+		t.Fatalf("load category: %v", err)
+	}
+	mustCreate(t, db, &models.Component{CategoryID: smd.ID, Name: "MCU", Model: "STM32F103C8T6", Package: "LQFP-48"})
+	repo := NewComponentRepository(db)
+
+	cases := []struct {
+		field, prefix string
+		limit         int
+		want          string
+	}{
+		{"package", "", 0, "[0603×2 0805×1 LQFP-48×1 TH×1]"},
+		{"package", "", 2, "[0603×2 0805×1]"},
+		{"package", "06", 0, "[0603×2]"},
+		{"package", "lqfp48", 0, "[LQFP-48×1]"},
+		{"package", "48", 0, "[LQFP-48×1]"},
+		{"location", "a1", 0, "[A1-01×1 A1-02×1 A10×1]"},
+		{"manufacturer", "instr", 0, "[Texas Instruments×1]"},
+		{"supplier", "lc", 0, "[LCSC×2]"},
+		{"category", "电", 0, "[电容×1 电阻×1 贴片电阻×3]"},
+		{"category", "dz", 0, "[电阻×1]"},
+		{"category", "tp", 0, "[贴片电阻×3]"},
+		{"model", "STM32F10C8", 0, "[STM32F103C8T6×1~]"},
+		{"value", "xyz", 0, "[]"},
+	}
+	for _, tc := range cases {
+		suggestions, err := repo.Suggest(tc.field, tc.prefix, tc.limit)
+		if err != nil {
+			t.Fatalf("Suggest(%s, %q): %v", tc.field, tc.prefix, err)
+		}
+		if got := formatSuggestions(suggestions); got != tc.want {
+			t.Errorf("Suggest(%s, %q, %d) = %s, want %s", tc.field, tc.prefix, tc.limit, got, tc.want)
+		}
+	}
+
+	if _, err := repo.Suggest("tag", "", 0); !errors.Is(err, ErrUnknownSuggestionField) {
+		t.Fatalf("unknown field err = %v", err)
+	}
+	if suggestions, err := repo.ForWorkspace(2).Suggest("package", "", 0); err != nil || len(suggestions) != 0 {
+		t.Fatalf("other workspace = %v, %v", suggestions, err)
+	}
+}
+
+func TestComponentSuggestCacheInvalidation(t *testing.T) {
+	db := setupComponentTestDB(t)
+	if err := RegisterSuggestionCacheInvalidation(db); err != nil {
+		t.Fatalf("RegisterSuggestionCacheInvalidation: %v", err)
+	}
+	seedFilterFixtures(t, db)
+	repo := NewComponentRepository(db)
+	suggest := func(field, prefix string) string {
+		t.Helper()
+		suggestions, err := repo.Suggest(field, prefix, 0)
+		if err != nil {
+			t.Fatalf("Suggest: %v", err)
+		}
+		return formatSuggestions(suggestions)
+	}
+
+	if got := suggest("package", "0"); got != "[0603×2 0805×1]" {
+		t.Fatalf("initial = %s", got)
+	}
+	var r1 models.Component
+	if err := db.Where("name = ?", "R1").First(&r1).Error; err != nil {
+		t.Fatalf("load R1: %v", err)
+	}
+	mustCreate(t, db, &models.Component{CategoryID: r1.CategoryID, Name: "R4", Package: "0402"})
+	if got := suggest("package", "0"); got != "[0603×2 0402×1 0805×1]" {
+		t.Errorf("after create = %s", got)
+	}
+	if err := db.Model(&r1).Update("package", "0805").Error; err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got := suggest("package", "0"); got != "[0805×2 0402×1 0603×1]" {
+		t.Errorf("after update = %s", got)
+	}
+	if err := db.Exec("UPDATE components SET package = '1206' WHERE name = 'R4'").Error; err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	if got := suggest("package", "0"); got != "[0805×2 0603×1 1206×1]" {
+		t.Errorf("after raw update = %s", got)
+	}
+	if err := db.Model(&models.Supplier{}).Where("name = ?", "LCSC").Update("name", "立创").Error; err != nil {
+		t.Fatalf("rename supplier: %v", err)
+	}
+	if got := suggest("supplier", "lc"); got != "[立创×2]" {
+		t.Errorf("after supplier rename = %s", got)
+	}
+}
+
+func TestComponentSuggestCacheSettleWindow(t *testing.T) {
+	db := setupComponentTestDB(t)
+	if err := RegisterSuggestionCacheInvalidation(db); err != nil {
+		t.Fatalf("RegisterSuggestionCacheInvalidation: %v", err)
+	}
+	seedFilterFixtures(t, db)
+	key := suggestionCacheKey{dialector: db.Dialector, workspaceID: DefaultWorkspaceID, field: "package"}
+	expiresAt := func() time.Time {
+		suggestionCache.mu.Lock()
+		defer suggestionCache.mu.Unlock()
+		return suggestionCache.entries[key].expiresAt
+	}
+
+	// 默认事务的写入在提交后失效，重新加载的取值按完整有效期缓存
+	if _, err := NewComponentRepository(db).Suggest("package", "", 0); err != nil {
+		t.Fatalf("Suggest: %v", err)
+	}
+	if got := expiresAt(); got.Before(time.Now().Add(suggestionSettleWindow)) {
+		t.Fatalf("expiresAt = %v, want full TTL", got)
+	}
+
+	// 显式事务内的写入在提交前失效，观察期内加载的取值只缓存到观察期结束
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.Component{CategoryID: 1, Name: "R9", Package: "0201"}).Error; err != nil {
+			return err
+		}
+		_, err := NewComponentRepository(tx).Suggest("package", "", 0)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("transaction: %v", err)
+	}
+	if got := expiresAt(); got.IsZero() || got.After(time.Now().Add(suggestionSettleWindow)) {
+		t.Fatalf("expiresAt = %v, want within settle window", got)
+	}
+}
+
+func formatSuggestions(suggestions []Suggestion) string {
+	out := "["
+	for i, suggestion := range suggestions {
+		if i > 0 {
+			out += " "
+		}
+		out += fmt.Sprintf("%s×%d", suggestion.Value, suggestion.Count)
+		if suggestion.Fuzzy {
+			out += "~"
+		}
+	}
+	return out + "]"
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxTagLength 单个标签的最大字符数
+	maxTagLength = 50
+	// maxComponentTags 单个元件最多的标签数
+	maxComponentTags = 20
+)
+
+// ErrInvalidTag 标签为空、过长或数量超限
+var ErrInvalidTag = errors.New("标签无效")
+
+// NormalizeTags 去除首尾空白并按不区分大小写去重（保留首次出现的写法），校验长度与数量
+func NormalizeTags(tags []string) ([]string, error) {
+	trimmed := make([]string, len(tags))
+	for i, tag := range tags {
+		trimmed[i] = strings.TrimSpace(tag)
+		if trimmed[i] == "" {
+			return nil, fmt.Errorf("%w：标签不能为空", ErrInvalidTag)
+		}
+		if utf8.RuneCountInString(trimmed[i]) > maxTagLength {
+			return nil, fmt.Errorf("%w：标签 %q 超过 %d 个字符", ErrInvalidTag, trimmed[i], maxTagLength)
+		}
+	}
+	result := dedupeTags(trimmed)
+	if len(result) > maxComponentTags {
+		return nil, fmt.Errorf("%w：每个元件最多 %d 个标签", ErrInvalidTag, maxComponentTags)
+	}
+	return result, nil
+}
+
+// dedupeTags 按不区分大小写去重，保留首次出现的写法
+func dedupeTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		key := strings.ToLower(tag)
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// replaceTagsTx 将元件的标签整体替换为 tags（需已经过 NormalizeTags）
+func replaceTagsTx(tx *gorm.DB, componentID uint, tags []string) error {
+	if err := tx.Where("component_id = ?", componentID).Delete(&models.ComponentTag{}).Error; err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	rows := make([]models.ComponentTag, len(tags))
+	for i, tag := range tags {
+		rows[i] = models.ComponentTag{ComponentID: componentID, Name: tag}
+	}
+	return tx.Create(&rows).Error
+}
+
+// tagsByComponent 返回各元件的标签（按写入顺序）
+func tagsByComponent(db *gorm.DB, componentIDs []uint) (map[uint][]string, error) {
+	result := make(map[uint][]string, len(componentIDs))
+	if len(componentIDs) == 0 {
+		return result, nil
+	}
+	var rows []models.ComponentTag
+	if err := db.Where("component_id IN ?", componentIDs).Order("id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.ComponentID] = append(result[row.ComponentID], row.Name)
+	}
+	return result, nil
+}
+
+// attachTags 为元件填充标签，没有标签时为空切片
+func attachTags(db *gorm.DB, components []models.Component) error {
+	ids := make([]uint, len(components))
+	for i := range components {
+		ids[i] = components[i].ID
+	}
+	tags, err := tagsByComponent(db, ids)
+	if err != nil {
+		return err
+	}
+	for i := range components {
+		components[i].Tags = tags[components[i].ID]
+		if components[i].Tags == nil {
+			components[i].Tags = []string{}
+		}
+	}
+	return nil
+}
+
+// TagCount 标签及使用它的元件数
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// GetTags 返回当前工作区使用中的标签（按名称排序，大小写不同的写法分别统计）
+func (r *ComponentRepository) GetTags() ([]TagCount, error) {
+	var tags []TagCount
+	err := r.db.Model(&models.ComponentTag{}).
+		Select("component_tags.name AS name, COUNT(*) AS count").
+		Joins("JOIN components ON components.id = component_tags.component_id").
+		Where("components.workspace_id = ?", r.workspaceID).
+		Group("component_tags.name").
+		Order("component_tags.name ASC").
+		Scan(&tags).Error
+	return tags, err
+}
+
+// attachComponentTags 为单个元件填充标签
+func attachComponentTags(db *gorm.DB, component *models.Component) error {
+	tags, err := tagsByComponent(db, []uint{component.ID})
+	if err != nil {
+		return err
+	}
+	component.Tags = tags[component.ID]
+	if component.Tags == nil {
+		component.Tags = []string{}
+	}
+	return nil
+}
+
+// BatchUpdateTags 为当前工作区内的元件批量添加与移除标签（移除按不区分大小写匹配，先移除后添加，
+// 已有的标签不重复添加），返回涉及的元件数；添加后超过单个元件的标签数上限时整体回滚并返回 ErrInvalidTag
+func (r *ComponentRepository) BatchUpdateTags(ids []uint, add, remove []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	add, err := NormalizeTags(add)
+	if err != nil {
+		return 0, err
+	}
+	remove, err = NormalizeTags(remove)
+	if err != nil {
+		return 0, err
+	}
+
+	var updated int64
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		var componentIDs []uint
+		if err := inWorkspace(tx, "components", r.workspaceID).Model(&models.Component{}).
+			Where("id IN ?", ids).Pluck("id", &componentIDs).Error; err != nil {
+			return err
+		}
+		if len(componentIDs) == 0 {
+			return nil
+		}
+		updated = int64(len(componentIDs))
+
+		if len(remove) > 0 {
+			lowered := make([]string, len(remove))
+			for i, tag := range remove {
+				lowered[i] = strings.ToLower(tag)
+			}
+			if err := tx.Where("component_id IN ? AND LOWER(name) IN ?", componentIDs, lowered).
+				Delete(&models.ComponentTag{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(add) == 0 {
+			return nil
+		}
+
+		existing, err := tagsByComponent(tx, componentIDs)
+		if err != nil {
+			return err
+		}
+		var rows []models.ComponentTag
+		for _, id := range componentIDs {
+			has := make(map[string]bool, len(existing[id]))
+			for _, tag := range existing[id] {
+				has[strings.ToLower(tag)] = true
+			}
+			count := len(existing[id])
+			for _, tag := range add {
+				if has[strings.ToLower(tag)] {
+					continue
+				}
+				rows = append(rows, models.ComponentTag{ComponentID: id, Name: tag})
+				count++
+			}
+			if count > maxComponentTags {
+				return fmt.Errorf("%w：每个元件最多 %d 个标签", ErrInvalidTag, maxComponentTags)
+			}
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&rows, 500).Error
+	})
+	return updated, err
+}
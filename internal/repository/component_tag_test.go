@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{" obsolete ", "常用", "Obsolete", "常用"})
+	if err != nil || !slices.Equal(tags, []string{"obsolete", "常用"}) {
+		t.Fatalf("NormalizeTags = %v, %v", tags, err)
+	}
+	for _, invalid := range [][]string{{" "}, {strings.Repeat("长", maxTagLength+1)}} {
+		if _, err := NormalizeTags(invalid); !errors.Is(err, ErrInvalidTag) {
+			t.Fatalf("NormalizeTags(%q) err = %v, want ErrInvalidTag", invalid, err)
+		}
+	}
+	many := make([]string, maxComponentTags+1)
+	for i := range many {
+		many[i] = strings.Repeat("t", i+1)
+	}
+	if _, err := NormalizeTags(many); !errors.Is(err, ErrInvalidTag) {
+		t.Fatalf("too many tags err = %v, want ErrInvalidTag", err)
+	}
+}
+
+func TestComponentRepositoryTags(t *testing.T) {
+	db := setupComponentTestDB(t)
+	if err := db.AutoMigrate(&models.PreStock{}, &models.StockLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	category := models.Category{Name: "芯片"}
+	mustCreate(t, db, &category)
+	repo := NewComponentRepository(db)
+
+	first := models.Component{CategoryID: category.ID, Name: "NE555", Tags: []string{"obsolete", " 常用 ", "OBSOLETE"}}
+	if err := repo.Create(&first); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	loaded, err := repo.GetByID(first.ID)
+	if err != nil || !slices.Equal(loaded.Tags, []string{"obsolete", "常用"}) {
+		t.Fatalf("tags after create = %v, err %v", loaded.Tags, err)
+	}
+
+	// Tags 为 nil 时保留原有标签
+	loaded.Tags = nil
+	loaded.Category = nil
+	if err := repo.Update(loaded); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if loaded, _ = repo.GetByID(first.ID); len(loaded.Tags) != 2 {
+		t.Fatalf("tags after update without tags = %v", loaded.Tags)
+	}
+	loaded.Tags = []string{"常用"}
+	loaded.Category = nil
+	if err := repo.Update(loaded); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if loaded, _ = repo.GetByID(first.ID); !slices.Equal(loaded.Tags, []string{"常用"}) {
+		t.Fatalf("tags after replace = %v", loaded.Tags)
+	}
+
+	second := models.Component{CategoryID: category.ID, Name: "LM358", Tags: []string{"常用", "停产"}}
+	if err := repo.Create(&second); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := repo.Create(&models.Component{CategoryID: category.ID, Name: "非法", Tags: []string{""}}); !errors.Is(err, ErrInvalidTag) {
+		t.Fatalf("create with empty tag err = %v, want ErrInvalidTag", err)
+	}
+	// 其他工作区的标签不计入
+	mustCreate(t, db, &models.Component{WorkspaceID: 2, CategoryID: category.ID, Name: "其他"})
+	mustCreate(t, db, &models.ComponentTag{ComponentID: 4, Name: "常用"})
+
+	counts, err := repo.GetTags()
+	if err != nil || len(counts) != 2 || counts[0] != (TagCount{Name: "停产", Count: 1}) || counts[1] != (TagCount{Name: "常用", Count: 2}) {
+		t.Fatalf("GetTags = %+v, err %v", counts, err)
+	}
+
+	merged, err := repo.MergeComponents(first.ID, []uint{second.ID}, "alice")
+	if err != nil || !slices.Equal(merged.Tags, []string{"常用", "停产"}) {
+		t.Fatalf("merged tags = %v, err %v", merged.Tags, err)
+	}
+
+	if err := repo.Delete(first.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	var remaining int64
+	db.Model(&models.ComponentTag{}).Where("component_id IN ?", []uint{first.ID, second.ID}).Count(&remaining)
+	if remaining != 0 {
+		t.Fatalf("tags left after delete = %d", remaining)
+	}
+}
+
+func TestComponentRepositoryBatchUpdateTags(t *testing.T) {
+	db := setupComponentTestDB(t)
+	category := models.Category{Name: "芯片"}
+	mustCreate(t, db, &category)
+	repo := NewComponentRepository(db)
+
+	first := models.Component{CategoryID: category.ID, Name: "NE555", Tags: []string{"常用", "Obsolete"}}
+	second := models.Component{CategoryID: category.ID, Name: "LM358"}
+	other := models.Component{WorkspaceID: 2, CategoryID: category.ID, Name: "其他工作区"}
+	for _, component := range []*models.Component{&first, &second} {
+		if err := repo.Create(component); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	mustCreate(t, db, &other)
+
+	updated, err := repo.BatchUpdateTags([]uint{first.ID, second.ID, other.ID}, []string{"obsolete", "呆滞"}, []string{"常用"})
+	if err != nil || updated != 2 {
+		t.Fatalf("BatchUpdateTags = %d, %v", updated, err)
+	}
+	tags, err := tagsByComponent(db, []uint{first.ID, second.ID, other.ID})
+	if err != nil {
+		t.Fatalf("tagsByComponent: %v", err)
+	}
+	if !slices.Equal(tags[first.ID], []string{"Obsolete", "呆滞"}) || !slices.Equal(tags[second.ID], []string{"obsolete", "呆滞"}) || len(tags[other.ID]) != 0 {
+		t.Fatalf("tags after batch = %v", tags)
+	}
+
+	// 超过上限时整体回滚
+	many := make([]string, maxComponentTags-1)
+	for i := range many {
+		many[i] = strings.Repeat("t", i+1)
+	}
+	if _, err := repo.BatchUpdateTags([]uint{second.ID, first.ID}, many, nil); !errors.Is(err, ErrInvalidTag) {
+		t.Fatalf("over limit err = %v, want ErrInvalidTag", err)
+	}
+	if tags, _ = tagsByComponent(db, []uint{first.ID, second.ID}); len(tags[first.ID]) != 2 || len(tags[second.ID]) != 2 {
+		t.Fatalf("tags after rollback = %v", tags)
+	}
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// eachRow 以游标方式逐行扫描查询结果，避免一次性加载到内存
+func eachRow[T any](db *gorm.DB, fn func(*T) error) error {
+	rows, err := db.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item T
+		if err := db.ScanRows(rows, &item); err != nil {
+			return err
+		}
+		if err := fn(&item); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// loadByID 在打开游标前一次性加载工作区内的关联记录（分类、供应商等小表），
+// 遍历期间不再发起查询，避免占用游标所在连接
+func loadByID[T any](db *gorm.DB, table string, workspaceID uint, id func(*T) uint) (map[uint]*T, error) {
+	var items []T
+	if err := inWorkspace(db, table, workspaceID).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[uint]*T, len(items))
+	for i := range items {
+		result[id(&items[i])] = &items[i]
+	}
+	return result, nil
+}
+
+// applyTimeRange 按时间列筛选 [from, to)，零值表示不限
+func applyTimeRange(db *gorm.DB, column string, from, to time.Time) *gorm.DB {
+	if !from.IsZero() {
+		db = db.Where(column+" >= ?", from)
+	}
+	if !to.IsZero() {
+		db = db.Where(column+" < ?", to)
+	}
+	return db
+}
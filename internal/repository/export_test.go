@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+)
+
+func TestComponentEachKeepsSortAndRelations(t *testing.T) {
+	db := setupComponentTestDB(t)
+	category := models.Category{Name: "电阻"}
+	supplier := models.Supplier{Name: "嘉立创"}
+	if err := db.Create(&category).Error; err != nil {
+		t.Fatalf("create category: %v", err)
+	}
+	if err := db.Create(&supplier).Error; err != nil {
+		t.Fatalf("create supplier: %v", err)
+	}
+	components := []models.Component{
+		{CategoryID: category.ID, Name: "B", SupplierID: &supplier.ID},
+		{CategoryID: category.ID, Name: "A"},
+		{CategoryID: category.ID, Name: "C"},
+	}
+	if err := db.Create(&components).Error; err != nil {
+		t.Fatalf("create components: %v", err)
+	}
+
+	var names []string
+	err := NewComponentRepository(db).Each(ComponentQuery{SortBy: "name", SortOrder: "asc"}, func(component *models.Component) error {
+		names = append(names, component.Name)
+		if component.Category == nil || component.Category.Name != "电阻" {
+			t.Fatalf("component %s category = %+v", component.Name, component.Category)
+		}
+		if component.Name == "B" && (component.Supplier == nil || component.Supplier.Name != "嘉立创") {
+			t.Fatalf("component B supplier = %+v", component.Supplier)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Each: %v", err)
+	}
+	if len(names) != 3 || names[0] != "A" || names[2] != "C" {
+		t.Fatalf("names = %v, want sorted A B C", names)
+	}
+}
+
+func TestStockLogEachFiltersByTimeRange(t *testing.T) {
+	db := setupStatsTestDB(t)
+	category := models.Category{Name: "电容"}
+	if err := db.Create(&category).Error; err != nil {
+		t.Fatalf("create category: %v", err)
+	}
+	component := models.Component{CategoryID: category.ID, ComponentNumber: strPtr("HB-000001"), Name: "电容A"}
+	if err := db.Create(&component).Error; err != nil {
+		t.Fatalf("create component: %v", err)
+	}
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	logs := []models.StockLog{
+		{ComponentID: component.ID, ChangeAmount: 5, CreatedAt: base.AddDate(0, 0, -1)},
+		{ComponentID: component.ID, ChangeAmount: 3, CreatedAt: base},
+		{ComponentID: component.ID, ChangeAmount: -1, CreatedAt: base.AddDate(0, 0, 1)},
+		{ComponentID: component.ID + 100, ChangeAmount: 2, CreatedAt: base.Add(time.Hour)},
+	}
+	if err := db.Create(&logs).Error; err != nil {
+		t.Fatalf("create logs: %v", err)
+	}
+
+	var rows []StockLogExportRow
+	query := StockLogQuery{From: base.Add(-time.Hour), To: base.AddDate(0, 0, 1)}
+	err := NewStockLogRepository(db).Each(query, func(row *StockLogExportRow) error {
+		rows = append(rows, *row)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Each: %v", err)
+	}
+	if len(rows) != 2 || rows[0].ChangeAmount != 3 || rows[1].ChangeAmount != 2 {
+		t.Fatalf("rows = %+v, want logs within range in time order", rows)
+	}
+	if rows[0].ComponentNumber != "HB-000001" || rows[0].ComponentName != "电容A" {
+		t.Fatalf("row component = %q %q", rows[0].ComponentNumber, rows[0].ComponentName)
+	}
+	if rows[1].ComponentName != "" {
+		t.Fatalf("deleted component name = %q, want empty", rows[1].ComponentName)
+	}
+}
@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"math"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultForecastWindowDays 未配置统计窗口时使用的天数
+const DefaultForecastWindowDays = 90
+
+// ForecastParams 消耗预测参数
+type ForecastParams struct {
+	// WindowDays 统计出库的最近天数，不大于 0 时使用 DefaultForecastWindowDays
+	WindowDays int
+	// LeadTimeDays 下单到货所需天数（如下次立创下单到收货）
+	LeadTimeDays int
+	// TargetDays 到货后希望库存还能覆盖的天数；建议补货量按 LeadTimeDays+TargetDays 的消耗减去当前库存计算
+	TargetDays int
+}
+
+type ForecastRepository struct {
+	db          *gorm.DB
+	workspaceID uint
+}
+
+func NewForecastRepository(db *gorm.DB) *ForecastRepository {
+	return &ForecastRepository{db: db, workspaceID: DefaultWorkspaceID}
+}
+
+// ForWorkspace 返回限定在指定工作区内的仓储副本
+func (r *ForecastRepository) ForWorkspace(workspaceID uint) *ForecastRepository {
+	return &ForecastRepository{db: r.db, workspaceID: workspaceID}
+}
+
+// Recompute 重新计算工作区内全部元件的消耗预测并整体替换旧结果。日均消耗 = 窗口内出库数量 / 窗口天数，
+// 出库不含已撤销记录与冲销流水；元件创建晚于窗口起点时从创建时起算（至少 1 天）。
+// 可用天数 = 当前库存 / 日均消耗，预计断货日期 = now + 可用天数；建议补货量 = ceil(日均消耗 × (到货天数 + 目标覆盖天数)) − 当前库存，不小于 0
+func (r *ForecastRepository) Recompute(params ForecastParams, now time.Time) (int, error) {
+	if params.WindowDays <= 0 {
+		params.WindowDays = DefaultForecastWindowDays
+	}
+	windowStart := now.AddDate(0, 0, -params.WindowDays)
+
+	var components []struct {
+		ID            uint
+		StockQuantity int
+		CreatedAt     time.Time
+	}
+	if err := inWorkspace(r.db.Model(&models.Component{}), "components", r.workspaceID).
+		Select("components.id, components.stock_quantity, components.created_at").
+		Scan(&components).Error; err != nil {
+		return 0, err
+	}
+
+	var outbound []struct {
+		ComponentID uint
+		Quantity    int
+	}
+	if err := r.db.Table("stock_logs").
+		Select("stock_logs.component_id, SUM(-stock_logs.change_amount) AS quantity").
+		Where("stock_logs.workspace_id = ?", r.workspaceID).
+		Where("stock_logs.change_amount < 0 AND stock_logs.revoked_at IS NULL AND stock_logs.reversal_of_id IS NULL").
+		Where("stock_logs.created_at >= ?", dbTime(windowStart)).
+		Group("stock_logs.component_id").
+		Scan(&outbound).Error; err != nil {
+		return 0, err
+	}
+	consumed := make(map[uint]int, len(outbound))
+	for _, row := range outbound {
+		consumed[row.ComponentID] = row.Quantity
+	}
+
+	forecasts := make([]models.ComponentForecast, 0, len(components))
+	for _, component := range components {
+		forecasts = append(forecasts, computeForecast(r.workspaceID, component.ID, component.StockQuantity,
+			consumed[component.ID], component.CreatedAt, params, now))
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// 元件移动到其他工作区后旧预测仍记在原工作区，按元件一并清除
+		current := tx.Model(&models.Component{}).Select("id").Where("workspace_id = ?", r.workspaceID)
+		if err := tx.Where("workspace_id = ? OR component_id IN (?)", r.workspaceID, current).Delete(&models.ComponentForecast{}).Error; err != nil {
+			return err
+		}
+		if len(forecasts) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(forecasts, 500).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(forecasts), nil
+}
+
+func computeForecast(workspaceID, componentID uint, stock, outbound int, createdAt time.Time, params ForecastParams, now time.Time) models.ComponentForecast {
+	windowDays := float64(params.WindowDays)
+	if createdAt.After(now.AddDate(0, 0, -params.WindowDays)) {
+		windowDays = max(1, now.Sub(createdAt).Hours()/24)
+	}
+	forecast := models.ComponentForecast{
+		ComponentID:         componentID,
+		WorkspaceID:         workspaceID,
+		WindowDays:          int(math.Ceil(windowDays)),
+		OutboundQuantity:    outbound,
+		AvgDailyConsumption: float64(outbound) / windowDays,
+		ComputedAt:          now,
+	}
+	if forecast.AvgDailyConsumption <= 0 {
+		return forecast
+	}
+
+	days := math.Max(0, float64(stock)) / forecast.AvgDailyConsumption
+	stockout := now.Add(time.Duration(days * 24 * float64(time.Hour)))
+	forecast.DaysOfCover = &days
+	forecast.StockoutDate = &stockout
+	needed := math.Ceil(forecast.AvgDailyConsumption * float64(params.LeadTimeDays+params.TargetDays))
+	forecast.ReorderQuantity = max(0, int(needed)-stock)
+	return forecast
+}
+
+// ForecastQuery 消耗预测列表参数
+type ForecastQuery struct {
+	// WithinDays 非空时只返回可用天数不超过该值（将在该天数内断货）的元件
+	WithinDays *float64
+	// ReorderOnly 只返回建议补货量大于 0 的元件
+	ReorderOnly bool
+	SortBy      string
+	SortOrder   string
+	Page        int
+	PageSize    int
+}
+
+// ForecastSortColumns 预测列表允许排序的字段；无消耗（可用天数为空）的元件按可用天数排序时视为无限长
+var ForecastSortColumns = map[string]string{
+	"days_of_cover":         "COALESCE(component_forecasts.days_of_cover, 1e9)",
+	"stockout_date":         "COALESCE(component_forecasts.days_of_cover, 1e9)",
+	"avg_daily_consumption": "component_forecasts.avg_daily_consumption",
+	"reorder_quantity":      "component_forecasts.reorder_quantity",
+	"stock_quantity":        "components.stock_quantity",
+	"name":                  "components.name",
+}
+
+// ComponentForecastItem 预测列表的一项，附带元件基本信息
+type ComponentForecastItem struct {
+	models.ComponentForecast
+	ComponentNumber    string `json:"component_number"`
+	Name               string `json:"name"`
+	StockQuantity      int    `json:"stock_quantity"`
+	SupplierName       string `json:"supplier_name"`
+	SupplierPartNumber string `json:"supplier_part_number"`
+}
+
+// List 分页返回工作区内的消耗预测，默认按可用天数升序
+func (r *ForecastRepository) List(query ForecastQuery) ([]ComponentForecastItem, int64, error) {
+	db := r.db.Table("component_forecasts").
+		Joins("JOIN components ON components.id = component_forecasts.component_id").
+		Joins("LEFT JOIN suppliers ON suppliers.id = components.supplier_id").
+		Where("components.workspace_id = ?", r.workspaceID)
+	if query.WithinDays != nil {
+		db = db.Where("component_forecasts.days_of_cover <= ?", *query.WithinDays)
+	}
+	if query.ReorderOnly {
+		db = db.Where("component_forecasts.reorder_quantity > 0")
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := ForecastSortColumns[query.SortBy]
+	if !ok {
+		column = ForecastSortColumns["days_of_cover"]
+	}
+	order := "ASC"
+	if query.SortOrder == "desc" {
+		order = "DESC"
+	}
+	db = db.Select("component_forecasts.*, COALESCE(components.component_number, '') AS component_number, components.name, " +
+		"components.stock_quantity, COALESCE(suppliers.name, '') AS supplier_name, components.supplier_part_number").
+		Order(column + " " + order).
+		Order("components.id ASC")
+	if query.Page > 0 && query.PageSize > 0 {
+		db = db.Offset((query.Page - 1) * query.PageSize).Limit(query.PageSize)
+	}
+
+	items := []ComponentForecastItem{}
+	err := db.Scan(&items).Error
+	return items, total, err
+}
+
+// ByComponentIDs 返回指定元件的消耗预测，尚未计算的元件不在结果中
+func (r *ForecastRepository) ByComponentIDs(ids []uint) (map[uint]*models.ComponentForecast, error) {
+	result := make(map[uint]*models.ComponentForecast, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+	var forecasts []models.ComponentForecast
+	if err := r.db.Where("component_id IN ?", ids).Find(&forecasts).Error; err != nil {
+		return nil, err
+	}
+	for i := range forecasts {
+		result[forecasts[i].ComponentID] = &forecasts[i]
+	}
+	return result, nil
+}
+
+// All 返回工作区内全部元件的消耗预测，用于导出时填充
+func (r *ForecastRepository) All() (map[uint]*models.ComponentForecast, error) {
+	var forecasts []models.ComponentForecast
+	if err := r.db.Where("workspace_id = ?", r.workspaceID).Find(&forecasts).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[uint]*models.ComponentForecast, len(forecasts))
+	for i := range forecasts {
+		result[forecasts[i].ComponentID] = &forecasts[i]
+	}
+	return result, nil
+}
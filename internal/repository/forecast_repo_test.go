@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+)
+
+func TestForecastRecompute(t *testing.T) {
+	db := setupStatsTestDB(t)
+	if err := db.AutoMigrate(&models.Supplier{}, &models.ComponentForecast{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	daysAgo := func(days float64) time.Time {
+		return now.Add(-time.Duration(days * 24 * float64(time.Hour)))
+	}
+
+	category := models.Category{Name: "电阻"}
+	mustCreate(t, db, &category)
+	steady := models.Component{CategoryID: category.ID, Name: "Steady", StockQuantity: 30, CreatedAt: daysAgo(200)}
+	// 创建不足一个窗口的元件从创建时起算
+	fresh := models.Component{CategoryID: category.ID, Name: "Fresh", StockQuantity: 10, CreatedAt: daysAgo(9.5)}
+	idle := models.Component{CategoryID: category.ID, Name: "Idle", StockQuantity: 5, CreatedAt: daysAgo(200)}
+	mustCreate(t, db, &steady)
+	mustCreate(t, db, &fresh)
+	mustCreate(t, db, &idle)
+
+	revokedAt := daysAgo(4)
+	revoked := models.StockLog{ComponentID: steady.ID, ChangeAmount: -100, CreatedAt: daysAgo(5), RevokedAt: &revokedAt}
+	for _, log := range []*models.StockLog{
+		{ComponentID: steady.ID, ChangeAmount: -45, CreatedAt: daysAgo(10)},
+		{ComponentID: steady.ID, ChangeAmount: -30, CreatedAt: daysAgo(100)}, // 窗口之外
+		{ComponentID: steady.ID, ChangeAmount: 200, CreatedAt: daysAgo(20)},
+		&revoked,
+		{ComponentID: fresh.ID, ChangeAmount: -19, CreatedAt: daysAgo(3)},
+		{ComponentID: idle.ID, ChangeAmount: 5, CreatedAt: daysAgo(30)},
+	} {
+		mustCreate(t, db, log)
+	}
+	mustCreate(t, db, &models.StockLog{ComponentID: steady.ID, ChangeAmount: 100, ReversalOfID: &revoked.ID, CreatedAt: revokedAt})
+
+	repo := NewForecastRepository(db)
+	params := ForecastParams{WindowDays: 90, LeadTimeDays: 14, TargetDays: 60}
+	// 重复计算应整体替换旧结果
+	for range 2 {
+		count, err := repo.Recompute(params, now)
+		if err != nil || count != 3 {
+			t.Fatalf("Recompute() = %d, %v", count, err)
+		}
+	}
+
+	forecasts, err := repo.ByComponentIDs([]uint{steady.ID, fresh.ID, idle.ID})
+	if err != nil {
+		t.Fatalf("ByComponentIDs: %v", err)
+	}
+	approx := func(got *float64, want float64) bool {
+		return got != nil && math.Abs(*got-want) < 1e-6
+	}
+
+	// 45 / 90 = 0.5/天，30 / 0.5 = 60 天，ceil(0.5 × 74) - 30 = 7
+	got := forecasts[steady.ID]
+	if got == nil || got.OutboundQuantity != 45 || got.AvgDailyConsumption != 0.5 || !approx(got.DaysOfCover, 60) || got.ReorderQuantity != 7 {
+		t.Fatalf("steady forecast = %+v", got)
+	}
+	if got.StockoutDate == nil || !got.StockoutDate.Equal(now.AddDate(0, 0, 60)) {
+		t.Errorf("steady stockout = %v", got.StockoutDate)
+	}
+
+	// 19 / 9.5 = 2/天，10 / 2 = 5 天，ceil(2 × 74) - 10 = 138
+	got = forecasts[fresh.ID]
+	if got == nil || got.WindowDays != 10 || got.AvgDailyConsumption != 2 || !approx(got.DaysOfCover, 5) || got.ReorderQuantity != 138 {
+		t.Fatalf("fresh forecast = %+v", got)
+	}
+
+	got = forecasts[idle.ID]
+	if got == nil || got.AvgDailyConsumption != 0 || got.DaysOfCover != nil || got.StockoutDate != nil || got.ReorderQuantity != 0 {
+		t.Fatalf("idle forecast = %+v", got)
+	}
+
+	items, total, err := repo.List(ForecastQuery{})
+	if err != nil || total != 3 {
+		t.Fatalf("List() total = %d, %v", total, err)
+	}
+	if items[0].Name != "Fresh" || items[1].Name != "Steady" || items[2].Name != "Idle" {
+		t.Errorf("List() order = %s, %s, %s", items[0].Name, items[1].Name, items[2].Name)
+	}
+	within := 30.0
+	if items, total, err := repo.List(ForecastQuery{WithinDays: &within}); err != nil || total != 1 || items[0].Name != "Fresh" {
+		t.Errorf("List(within 30) = %+v, %d, %v", items, total, err)
+	}
+
+	components, _, err := NewComponentRepository(db).GetAll(ComponentQuery{SortBy: "days_of_cover", SortOrder: "asc"})
+	if err != nil {
+		t.Fatalf("GetAll sorted by days_of_cover: %v", err)
+	}
+	if names := componentNames(components); len(names) != 3 || names[0] != "Fresh" || names[1] != "Steady" || names[2] != "Idle" {
+		t.Errorf("components sorted by days_of_cover = %v", names)
+	}
+}
@@ -20,11 +20,34 @@ var (
 )
 
 type PreStockRepository struct {
-	db *gorm.DB
+	db          *gorm.DB
+	workspaceID uint
 }
 
 func NewPreStockRepository(db *gorm.DB) *PreStockRepository {
-	return &PreStockRepository{db: db}
+	return &PreStockRepository{db: db, workspaceID: DefaultWorkspaceID}
+}
+
+// ForWorkspace 返回限定在指定工作区内的仓储
+func (r *PreStockRepository) ForWorkspace(workspaceID uint) *PreStockRepository {
+	return &PreStockRepository{db: r.db, workspaceID: workspaceID}
+}
+
+func (r *PreStockRepository) scoped() *gorm.DB {
+	return inWorkspace(r.db, "pre_stocks", r.workspaceID)
+}
+
+// validateReferencesInTx 校验预入库引用的分类与供应商属于当前工作区
+func (r *PreStockRepository) validateReferencesInTx(tx *gorm.DB, preStock *models.PreStock) error {
+	if preStock.CategoryID != 0 {
+		if err := ensureInWorkspace(tx, &models.Category{}, preStock.CategoryID, r.workspaceID); err != nil {
+			return err
+		}
+	}
+	if preStock.SupplierID != nil {
+		return ensureInWorkspace(tx, &models.Supplier{}, *preStock.SupplierID, r.workspaceID)
+	}
+	return nil
 }
 
 type PreStockQuery struct {
@@ -48,7 +71,7 @@ func (r *PreStockRepository) GetAll(query PreStockQuery) ([]models.PreStock, int
 	var items []models.PreStock
 	var total int64
 
-	db := r.db.Model(&models.PreStock{}).Preload("Category").Preload("Supplier").Preload("Component")
+	db := r.scoped().Model(&models.PreStock{}).Preload("Category").Preload("Supplier").Preload("Component")
 	if query.Status != "" && query.Status != "all" {
 		db = db.Where("status = ?", query.Status)
 	}
@@ -64,16 +87,48 @@ func (r *PreStockRepository) GetAll(query PreStockQuery) ([]models.PreStock, int
 	return items, total, err
 }
 
+// PreStockExportQuery 预入库导出条件：Status 为空或 all 表示全部，时间范围为 [From, To)
+type PreStockExportQuery struct {
+	Status string
+	From   time.Time
+	To     time.Time
+}
+
+// Each 按创建时间先后逐行遍历预入库记录，分类与供应商预先加载后填充，用于流式导出
+func (r *PreStockRepository) Each(query PreStockExportQuery, fn func(*models.PreStock) error) error {
+	db := r.scoped().Model(&models.PreStock{})
+	if query.Status != "" && query.Status != "all" {
+		db = db.Where("status = ?", query.Status)
+	}
+	db = applyTimeRange(db, "created_at", query.From, query.To)
+
+	categories, err := loadByID(r.db, "categories", r.workspaceID, func(c *models.Category) uint { return c.ID })
+	if err != nil {
+		return err
+	}
+	suppliers, err := loadByID(r.db, "suppliers", r.workspaceID, func(s *models.Supplier) uint { return s.ID })
+	if err != nil {
+		return err
+	}
+	return eachRow(db.Order("created_at ASC, id ASC"), func(item *models.PreStock) error {
+		item.Category = categories[item.CategoryID]
+		if item.SupplierID != nil {
+			item.Supplier = suppliers[*item.SupplierID]
+		}
+		return fn(item)
+	})
+}
+
 func (r *PreStockRepository) GetByID(id uint) (*models.PreStock, error) {
 	var item models.PreStock
-	err := r.db.Preload("Category").Preload("Supplier").Preload("Component").First(&item, id).Error
+	err := r.scoped().Preload("Category").Preload("Supplier").Preload("Component").First(&item, id).Error
 	return &item, err
 }
 
 func (r *PreStockRepository) assignNumberInTx(tx *gorm.DB, preStock *models.PreStock) error {
 	preStock.ComponentNumber = NormalizeComponentNumber(preStock.ComponentNumber)
 	if preStock.ComponentNumber != nil {
-		taken, err := isComponentNumberTakenInTx(tx, *preStock.ComponentNumber, 0, preStock.ID)
+		taken, err := isComponentNumberTakenInTx(tx, r.workspaceID, *preStock.ComponentNumber, 0, preStock.ID)
 		if err != nil {
 			return err
 		}
@@ -83,7 +138,7 @@ func (r *PreStockRepository) assignNumberInTx(tx *gorm.DB, preStock *models.PreS
 		return nil
 	}
 
-	componentRepo := NewComponentRepository(tx)
+	componentRepo := NewComponentRepository(tx).ForWorkspace(r.workspaceID)
 	number, err := componentRepo.generateNextInTx(tx)
 	if err != nil {
 		return err
@@ -98,7 +153,11 @@ func (r *PreStockRepository) Create(preStock *models.PreStock) error {
 		return ErrInvalidPreStockStatus
 	}
 
+	preStock.WorkspaceID = r.workspaceID
 	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := r.validateReferencesInTx(tx, preStock); err != nil {
+			return err
+		}
 		if err := r.assignNumberInTx(tx, preStock); err != nil {
 			return err
 		}
@@ -113,12 +172,15 @@ func (r *PreStockRepository) Update(preStock *models.PreStock) error {
 
 	return r.db.Transaction(func(tx *gorm.DB) error {
 		var existing models.PreStock
-		if err := tx.First(&existing, preStock.ID).Error; err != nil {
+		if err := inWorkspace(tx, "pre_stocks", r.workspaceID).First(&existing, preStock.ID).Error; err != nil {
 			return err
 		}
 		if existing.Status != PreStockStatusPending {
 			return ErrPreStockAlreadyConfirmed
 		}
+		if err := r.validateReferencesInTx(tx, preStock); err != nil {
+			return err
+		}
 		if err := r.assignNumberInTx(tx, preStock); err != nil {
 			return err
 		}
@@ -147,7 +209,7 @@ func (r *PreStockRepository) Update(preStock *models.PreStock) error {
 func (r *PreStockRepository) Delete(id uint) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
 		var existing models.PreStock
-		if err := tx.First(&existing, id).Error; err != nil {
+		if err := inWorkspace(tx, "pre_stocks", r.workspaceID).First(&existing, id).Error; err != nil {
 			return err
 		}
 		if existing.Status != PreStockStatusPending {
@@ -157,12 +219,13 @@ func (r *PreStockRepository) Delete(id uint) error {
 	})
 }
 
-func (r *PreStockRepository) Confirm(id uint) (*models.PreStock, error) {
+// Confirm 确认预入库并转为正式元件，入库流水记录确认操作人
+func (r *PreStockRepository) Confirm(id uint, operator string) (*models.PreStock, error) {
 	var confirmed models.PreStock
 
 	err := r.db.Transaction(func(tx *gorm.DB) error {
 		var preStock models.PreStock
-		if err := tx.First(&preStock, id).Error; err != nil {
+		if err := inWorkspace(tx, "pre_stocks", r.workspaceID).First(&preStock, id).Error; err != nil {
 			return err
 		}
 		if preStock.Status != PreStockStatusPending {
@@ -170,14 +233,14 @@ func (r *PreStockRepository) Confirm(id uint) (*models.PreStock, error) {
 		}
 		preStock.ComponentNumber = NormalizeComponentNumber(preStock.ComponentNumber)
 		if preStock.ComponentNumber == nil {
-			componentRepo := NewComponentRepository(tx)
+			componentRepo := NewComponentRepository(tx).ForWorkspace(r.workspaceID)
 			number, err := componentRepo.generateNextInTx(tx)
 			if err != nil {
 				return err
 			}
 			preStock.ComponentNumber = &number
 		}
-		taken, err := isComponentNumberTakenInTx(tx, *preStock.ComponentNumber, 0, preStock.ID)
+		taken, err := isComponentNumberTakenInTx(tx, r.workspaceID, *preStock.ComponentNumber, 0, preStock.ID)
 		if err != nil {
 			return err
 		}
@@ -191,6 +254,7 @@ func (r *PreStockRepository) Confirm(id uint) (*models.PreStock, error) {
 		}
 
 		component := models.Component{
+			WorkspaceID:        preStock.WorkspaceID,
 			CategoryID:         preStock.CategoryID,
 			ComponentNumber:    preStock.ComponentNumber,
 			Name:               preStock.Name,
@@ -213,11 +277,13 @@ func (r *PreStockRepository) Confirm(id uint) (*models.PreStock, error) {
 
 		if preStock.ExpectedQuantity > 0 {
 			log := models.StockLog{
+				WorkspaceID:     preStock.WorkspaceID,
 				ComponentID:     component.ID,
 				ChangeAmount:    preStock.ExpectedQuantity,
 				UnitPriceMicro:  unitPriceMicro,
 				TotalPriceCents: preStock.TotalPriceCents,
 				Reason:          "预入库确认",
+				Operator:        operator,
 			}
 			if err := tx.Create(&log).Error; err != nil {
 				return err
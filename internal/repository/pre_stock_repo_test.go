@@ -15,7 +15,7 @@ func setupPreStockTestDB(t *testing.T) *gorm.DB {
 	if err != nil {
 		t.Fatalf("open db: %v", err)
 	}
-	if err := db.AutoMigrate(&models.Category{}, &models.Supplier{}, &models.Component{}, &models.PreStock{}, &models.StockLog{}); err != nil {
+	if err := db.AutoMigrate(&models.Category{}, &models.Supplier{}, &models.Component{}, &models.ComponentTag{}, &models.PreStock{}, &models.StockLog{}); err != nil {
 		t.Fatalf("migrate: %v", err)
 	}
 	return db
@@ -114,7 +114,7 @@ func TestPreStockConfirmCreatesComponentAndStockLog(t *testing.T) {
 		t.Fatalf("Create: %v", err)
 	}
 
-	confirmed, err := repo.Confirm(item.ID)
+	confirmed, err := repo.Confirm(item.ID, "admin")
 	if err != nil {
 		t.Fatalf("Confirm: %v", err)
 	}
@@ -146,7 +146,7 @@ func TestPreStockConfirmCreatesComponentAndStockLog(t *testing.T) {
 	if err := db.Where("component_id = ?", component.ID).First(&log).Error; err != nil {
 		t.Fatalf("load stock log: %v", err)
 	}
-	if log.ChangeAmount != 50 || log.TotalPriceCents != 1234 || log.Reason != "预入库确认" {
+	if log.ChangeAmount != 50 || log.TotalPriceCents != 1234 || log.Reason != "预入库确认" || log.Operator != "admin" {
 		t.Fatalf("unexpected stock log: %+v", log)
 	}
 }
@@ -163,11 +163,11 @@ func TestPreStockConfirmedRecordCannotBeMutated(t *testing.T) {
 	if err := repo.Create(&item); err != nil {
 		t.Fatalf("Create: %v", err)
 	}
-	if _, err := repo.Confirm(item.ID); err != nil {
+	if _, err := repo.Confirm(item.ID, "admin"); err != nil {
 		t.Fatalf("Confirm: %v", err)
 	}
 
-	if _, err := repo.Confirm(item.ID); !errors.Is(err, ErrPreStockAlreadyConfirmed) {
+	if _, err := repo.Confirm(item.ID, "admin"); !errors.Is(err, ErrPreStockAlreadyConfirmed) {
 		t.Fatalf("Confirm again error = %v, want ErrPreStockAlreadyConfirmed", err)
 	}
 
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSavedSearchNameRequired  = errors.New("保存搜索的名称不能为空")
+	ErrSavedSearchNameDuplicate = errors.New("已存在同名的保存搜索")
+	ErrSavedSearchForbidden     = errors.New("只有创建人或工作区所有者可以修改共享的保存搜索")
+)
+
+// SavedSearchRepository 保存搜索仓储。私有搜索只对创建人可见，共享搜索对工作区全部成员可见
+type SavedSearchRepository struct {
+	db          *gorm.DB
+	workspaceID uint
+}
+
+func NewSavedSearchRepository(db *gorm.DB) *SavedSearchRepository {
+	return &SavedSearchRepository{db: db, workspaceID: DefaultWorkspaceID}
+}
+
+// ForWorkspace 返回限定在指定工作区内的仓储
+func (r *SavedSearchRepository) ForWorkspace(workspaceID uint) *SavedSearchRepository {
+	return &SavedSearchRepository{db: r.db, workspaceID: workspaceID}
+}
+
+// visibleTo 限定为 username 可见的保存搜索
+func (r *SavedSearchRepository) visibleTo(username string) *gorm.DB {
+	return inWorkspace(r.db, "saved_searches", r.workspaceID).Where("shared = ? OR owner = ?", true, username)
+}
+
+// GetAll 返回用户可见的保存搜索，共享的在前，同组按名称排序
+func (r *SavedSearchRepository) GetAll(username string) ([]models.SavedSearch, error) {
+	var searches []models.SavedSearch
+	err := r.visibleTo(username).Order("shared DESC").Order("name ASC").Order("id ASC").Find(&searches).Error
+	return searches, err
+}
+
+// GetByID 返回用户可见的保存搜索；不可见时返回 gorm.ErrRecordNotFound
+func (r *SavedSearchRepository) GetByID(id uint, username string) (*models.SavedSearch, error) {
+	var search models.SavedSearch
+	err := r.visibleTo(username).First(&search, id).Error
+	return &search, err
+}
+
+// Create 以 search.Owner 为创建人保存搜索
+func (r *SavedSearchRepository) Create(search *models.SavedSearch) error {
+	search.ID = 0
+	search.WorkspaceID = r.workspaceID
+	if err := r.checkName(search); err != nil {
+		return err
+	}
+	return r.db.Create(search).Error
+}
+
+// Update 修改名称、共享状态与查询条件，创建人不变；manager 为 true 时（工作区所有者）可修改他人的共享搜索
+func (r *SavedSearchRepository) Update(id uint, username string, manager bool, change *models.SavedSearch) (*models.SavedSearch, error) {
+	search, err := r.editable(id, username, manager)
+	if err != nil {
+		return nil, err
+	}
+	search.Name = change.Name
+	search.Shared = change.Shared
+	search.Params = change.Params
+	if err := r.checkName(search); err != nil {
+		return nil, err
+	}
+	if err := r.db.Save(search).Error; err != nil {
+		return nil, err
+	}
+	return search, nil
+}
+
+// Delete 删除保存搜索，权限同 Update
+func (r *SavedSearchRepository) Delete(id uint, username string, manager bool) error {
+	search, err := r.editable(id, username, manager)
+	if err != nil {
+		return err
+	}
+	return r.db.Delete(search).Error
+}
+
+func (r *SavedSearchRepository) editable(id uint, username string, manager bool) (*models.SavedSearch, error) {
+	search, err := r.GetByID(id, username)
+	if err != nil {
+		return nil, err
+	}
+	if search.Owner != username && !manager {
+		return nil, ErrSavedSearchForbidden
+	}
+	return search, nil
+}
+
+// checkName 名称在工作区内同一创建人下唯一
+func (r *SavedSearchRepository) checkName(search *models.SavedSearch) error {
+	search.Name = strings.TrimSpace(search.Name)
+	if search.Name == "" {
+		return ErrSavedSearchNameRequired
+	}
+	var count int64
+	if err := inWorkspace(r.db.Model(&models.SavedSearch{}), "saved_searches", r.workspaceID).
+		Where("owner = ? AND name = ? AND id <> ?", search.Owner, search.Name, search.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrSavedSearchNameDuplicate
+	}
+	return nil
+}
+
+// SavedSearchQuery 把保存的查询条件转换为元件查询（不含分页）；q 语法错误时返回 *FilterSyntaxError
+func SavedSearchQuery(params models.SavedSearchParams) (ComponentQuery, error) {
+	filter, err := ParseComponentFilter(params.Q)
+	if err != nil {
+		return ComponentQuery{}, err
+	}
+	abcClasses, err := ParseABCClasses(params.ABCClass)
+	if err != nil {
+		return ComponentQuery{}, err
+	}
+	return ComponentQuery{
+		CategoryID:           params.CategoryID,
+		IncludeSubcategories: params.IncludeSubcategories,
+		Keyword:              params.Keyword,
+		ComponentNumber:      params.ComponentNumber,
+		Name:                 params.Name,
+		Model:                params.Model,
+		Manufacturer:         params.Manufacturer,
+		Value:                params.Value,
+		SupplierName:         params.Supplier,
+		SupplierPartNumber:   params.SupplierPartNumber,
+		ABCClasses:           abcClasses,
+		SortBy:               strings.TrimSpace(params.SortBy),
+		SortOrder:            strings.TrimSpace(params.SortOrder),
+		Filter:               filter,
+	}, nil
+}
+
+// SavedSearchCount 保存搜索当前命中的元件数与库存合计
+type SavedSearchCount struct {
+	ID             uint   `json:"id"`
+	Name           string `json:"name"`
+	Shared         bool   `json:"shared"`
+	ComponentCount int64  `json:"component_count"`
+	TotalStock     int64  `json:"total_stock"`
+	// Error 保存的条件已无法解析（如查询语言规则变化）时的原因，此时不统计命中数
+	Error string `json:"error,omitempty"`
+}
+
+// Counts 统计用户可见的每个保存搜索当前命中的元件数与库存合计；条件无法解析的搜索跳过统计并标记 Error
+func (r *SavedSearchRepository) Counts(username string) ([]SavedSearchCount, error) {
+	searches, err := r.GetAll(username)
+	if err != nil {
+		return nil, err
+	}
+	components := NewComponentRepository(r.db).ForWorkspace(r.workspaceID)
+	counts := make([]SavedSearchCount, 0, len(searches))
+	for _, search := range searches {
+		count := SavedSearchCount{ID: search.ID, Name: search.Name, Shared: search.Shared}
+		query, err := SavedSearchQuery(search.Params)
+		if err != nil {
+			count.Error = err.Error()
+			counts = append(counts, count)
+			continue
+		}
+		if count.ComponentCount, count.TotalStock, err = components.Summarize(query); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, nil
+}
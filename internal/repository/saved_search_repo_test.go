@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupSavedSearchTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := setupComponentTestDB(t)
+	if err := db.AutoMigrate(&models.SavedSearch{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	seedFilterFixtures(t, db)
+	return db
+}
+
+func TestSavedSearchVisibilityAndPermissions(t *testing.T) {
+	repo := NewSavedSearchRepository(setupSavedSearchTestDB(t))
+
+	private := models.SavedSearch{Name: "我的 0603", Owner: "alice", Params: models.SavedSearchParams{Q: "pkg:0603"}}
+	if err := repo.Create(&private); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	shared := models.SavedSearch{Name: "低库存", Owner: "alice", Shared: true, Params: models.SavedSearchParams{Q: "stock:<100"}}
+	if err := repo.Create(&shared); err != nil {
+		t.Fatalf("Create shared: %v", err)
+	}
+	// 同名只在同一创建人下冲突
+	if err := repo.Create(&models.SavedSearch{Name: " 低库存 ", Owner: "alice"}); !errors.Is(err, ErrSavedSearchNameDuplicate) {
+		t.Fatalf("duplicate name err = %v", err)
+	}
+	if err := repo.Create(&models.SavedSearch{Name: "低库存", Owner: "bob"}); err != nil {
+		t.Fatalf("same name for another owner: %v", err)
+	}
+
+	bobSees, err := repo.GetAll("bob")
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if len(bobSees) != 2 || bobSees[0].ID != shared.ID {
+		t.Fatalf("bob sees %+v, want shared search first and his own", bobSees)
+	}
+	if _, err := repo.GetByID(private.ID, "bob"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("private search visible to bob: %v", err)
+	}
+	if _, err := repo.ForWorkspace(2).GetByID(shared.ID, "alice"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("search visible in another workspace: %v", err)
+	}
+
+	change := models.SavedSearch{Name: "低库存 <50", Shared: true, Params: models.SavedSearchParams{Q: "stock:<50"}}
+	if _, err := repo.Update(shared.ID, "bob", false, &change); !errors.Is(err, ErrSavedSearchForbidden) {
+		t.Fatalf("bob update err = %v", err)
+	}
+	updated, err := repo.Update(shared.ID, "bob", true, &change)
+	if err != nil || updated.Owner != "alice" || updated.Params.Q != "stock:<50" {
+		t.Fatalf("manager update = %+v, %v", updated, err)
+	}
+	if err := repo.Delete(shared.ID, "bob", false); !errors.Is(err, ErrSavedSearchForbidden) {
+		t.Fatalf("bob delete err = %v", err)
+	}
+	if err := repo.Delete(shared.ID, "alice", false); err != nil {
+		t.Fatalf("owner delete: %v", err)
+	}
+}
+
+func TestSavedSearchCounts(t *testing.T) {
+	db := setupSavedSearchTestDB(t)
+	repo := NewSavedSearchRepository(db)
+	for _, search := range []*models.SavedSearch{
+		{Name: "0603", Shared: true, Params: models.SavedSearchParams{Q: "pkg:0603"}},
+		{Name: "A1 柜低库存", Owner: "alice", Params: models.SavedSearchParams{Q: "location:A1* stock:<100", SortBy: "stock_quantity"}},
+		{Name: "10k", Owner: "alice", Params: models.SavedSearchParams{Value: "10k", Supplier: "LCSC"}},
+	} {
+		if err := repo.Create(search); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	// 写入后规则变化导致无法解析的条件（直接写库绕过校验）
+	mustCreate(t, db, &models.SavedSearch{WorkspaceID: DefaultWorkspaceID, Name: "旧语法", Owner: "alice", Params: models.SavedSearchParams{Q: "color:red"}})
+
+	counts, err := repo.Counts("alice")
+	if err != nil {
+		t.Fatalf("Counts: %v", err)
+	}
+	got := map[string][2]int64{}
+	for _, count := range counts {
+		if count.Name == "旧语法" {
+			if count.Error == "" || count.ComponentCount != 0 {
+				t.Errorf("invalid search count = %+v, want Error set", count)
+			}
+			continue
+		}
+		if count.Error != "" {
+			t.Errorf("%s unexpected error %q", count.Name, count.Error)
+		}
+		got[count.Name] = [2]int64{count.ComponentCount, count.TotalStock}
+	}
+	want := map[string][2]int64{"0603": {2, 50}, "A1 柜低库存": {2, 50}, "10k": {1, 50}}
+	if len(got) != len(want) {
+		t.Fatalf("counts = %+v", counts)
+	}
+	for name, w := range want {
+		if got[name] != w {
+			t.Errorf("%s = %v, want %v", name, got[name], w)
+		}
+	}
+
+	if counts, err := repo.Counts("bob"); err != nil || len(counts) != 1 {
+		t.Fatalf("bob counts = %+v, %v", counts, err)
+	}
+}
@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/price"
+)
+
+const (
+	// DefaultDeadStockDays 默认统计最近多少天没有出库
+	DefaultDeadStockDays = 180
+	// MaxDeadStockDays 最大统计天数
+	MaxDeadStockDays = 3650
+)
+
+// DeadStockQuery 呆滞料查询参数：列出 Now 之前 Days 天内出库数量不超过 MaxOutbound 的有库存元件，
+// MaxOutbound 为 0 时即为完全没有出库的呆滞料，大于 0 时包含出库很少的慢动料
+type DeadStockQuery struct {
+	Days        int
+	MaxOutbound int
+	Now         time.Time
+	CategoryIDs []uint
+	// Location 非空时只统计存放位置以此开头的元件
+	Location string
+}
+
+// DeadStockItem 单个呆滞元件及其占用金额与最后变动
+type DeadStockItem struct {
+	ComponentID     uint   `json:"component_id"`
+	ComponentNumber string `json:"component_number"`
+	Name            string `json:"name"`
+	CategoryID      uint   `json:"category_id"`
+	CategoryName    string `json:"category_name"`
+	Location        string `json:"location"`
+	StockQuantity   int    `json:"stock_quantity"`
+	UnitPriceMicro  int64  `json:"unit_price_micro"`
+	// ValueCents 占用金额 = 库存数量 × 参考单价
+	ValueCents int64 `json:"value_cents"`
+	// OutboundQuantity 统计期内的出库数量
+	OutboundQuantity int        `json:"outbound_quantity"`
+	LastOutboundAt   *time.Time `json:"last_outbound_at"`
+	// LastMovementAt 等为最后一条有效库存变动（入库或出库），没有记录时为空
+	LastMovementAt     *time.Time `json:"last_movement_at"`
+	LastMovementChange int        `json:"last_movement_change"`
+	LastMovementReason string     `json:"last_movement_reason"`
+}
+
+// LocationValuation 单个存放位置的库存合计，Location 为空表示未设置位置
+type LocationValuation struct {
+	Location string `json:"location"`
+	ValuationTotals
+}
+
+// DeadStockReport 呆滞料报表
+type DeadStockReport struct {
+	Days        int                 `json:"days"`
+	MaxOutbound int                 `json:"max_outbound"`
+	Since       time.Time           `json:"since"`
+	Totals      ValuationTotals     `json:"totals"`
+	Categories  []CategoryValuation `json:"categories"`
+	Locations   []LocationValuation `json:"locations"`
+	Items       []DeadStockItem     `json:"items"`
+}
+
+// DeadStockItems 返回呆滞元件，按占用金额降序。统计期起点之后才创建的元件不计入（还没有足够的时间判断）；
+// 出库只计未撤销、非冲销的记录
+func (r *StatsRepository) DeadStockItems(query DeadStockQuery) ([]DeadStockItem, error) {
+	since := dbTime(query.Now.AddDate(0, 0, -query.Days))
+
+	var components []struct {
+		ID              uint
+		ComponentNumber string
+		Name            string
+		CategoryID      uint
+		CategoryName    string
+		Location        string
+		StockQuantity   int
+		UnitPriceMicro  int64
+	}
+	db := inWorkspace(r.db.Table("components"), "components", r.workspaceID).
+		Select("components.id, COALESCE(components.component_number, '') AS component_number, components.name, components.category_id, "+
+			"COALESCE(categories.name, '') AS category_name, components.location, components.stock_quantity, components.unit_price_micro").
+		Joins("LEFT JOIN categories ON categories.id = components.category_id").
+		Where("components.stock_quantity > 0 AND components.created_at < ?", since)
+	if len(query.CategoryIDs) > 0 {
+		db = db.Where("components.category_id IN ?", query.CategoryIDs)
+	}
+	if location := strings.TrimSpace(query.Location); location != "" {
+		db = db.Where("components.location LIKE ? ESCAPE '!'", likeEscaper.Replace(location)+"%")
+	}
+	if err := db.Scan(&components).Error; err != nil {
+		return nil, err
+	}
+
+	var outbound []struct {
+		ComponentID uint
+		Quantity    int
+	}
+	if err := r.effectiveLogs().
+		Select("stock_logs.component_id, SUM(-stock_logs.change_amount) AS quantity").
+		Where("stock_logs.change_amount < 0 AND stock_logs.created_at >= ?", since).
+		Group("stock_logs.component_id").
+		Scan(&outbound).Error; err != nil {
+		return nil, err
+	}
+	outboundByID := make(map[uint]int, len(outbound))
+	for _, row := range outbound {
+		outboundByID[row.ComponentID] = row.Quantity
+	}
+
+	lastOutbound, err := r.latestLogs("stock_logs.change_amount < 0")
+	if err != nil {
+		return nil, err
+	}
+	lastMovement, err := r.latestLogs("stock_logs.change_amount <> 0")
+	if err != nil {
+		return nil, err
+	}
+
+	items := []DeadStockItem{}
+	for _, component := range components {
+		quantity := outboundByID[component.ID]
+		if quantity > query.MaxOutbound {
+			continue
+		}
+		item := DeadStockItem{
+			ComponentID:      component.ID,
+			ComponentNumber:  component.ComponentNumber,
+			Name:             component.Name,
+			CategoryID:       component.CategoryID,
+			CategoryName:     component.CategoryName,
+			Location:         component.Location,
+			StockQuantity:    component.StockQuantity,
+			UnitPriceMicro:   component.UnitPriceMicro,
+			ValueCents:       price.TotalCents(component.UnitPriceMicro, component.StockQuantity),
+			OutboundQuantity: quantity,
+		}
+		if log, ok := lastOutbound[component.ID]; ok {
+			item.LastOutboundAt = &log.CreatedAt
+		}
+		if movement, ok := lastMovement[component.ID]; ok {
+			item.LastMovementAt = &movement.CreatedAt
+			item.LastMovementChange = movement.ChangeAmount
+			item.LastMovementReason = movement.Reason
+		}
+		items = append(items, item)
+	}
+	slices.SortFunc(items, func(a, b DeadStockItem) int {
+		return cmp.Or(cmp.Compare(b.ValueCents, a.ValueCents), cmp.Compare(a.ComponentID, b.ComponentID))
+	})
+	return items, nil
+}
+
+// latestLog 元件最后一条符合条件的库存记录
+type latestLog struct {
+	ComponentID  uint
+	ChangeAmount int
+	Reason       string
+	CreatedAt    time.Time
+}
+
+// latestLogs 返回各元件最后一条符合条件的有效库存记录；库存记录按时间顺序写入，取 ID 最大的一条
+func (r *StatsRepository) latestLogs(condition string) (map[uint]*latestLog, error) {
+	var logs []latestLog
+	latest := r.effectiveLogs().Select("MAX(stock_logs.id)").Where(condition).Group("stock_logs.component_id")
+	if err := r.db.Table("stock_logs").
+		Select("stock_logs.component_id, stock_logs.change_amount, stock_logs.reason, stock_logs.created_at").
+		Where("stock_logs.id IN (?)", latest).
+		Scan(&logs).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[uint]*latestLog, len(logs))
+	for i := range logs {
+		result[logs[i].ComponentID] = &logs[i]
+	}
+	return result, nil
+}
+
+// GetDeadStock 返回呆滞料明细及按分类、按存放位置的合计（均按占用金额降序），口径见 DeadStockItems
+func (r *StatsRepository) GetDeadStock(query DeadStockQuery) (*DeadStockReport, error) {
+	items, err := r.DeadStockItems(query)
+	if err != nil {
+		return nil, err
+	}
+	report := &DeadStockReport{
+		Days:        query.Days,
+		MaxOutbound: query.MaxOutbound,
+		Since:       query.Now.AddDate(0, 0, -query.Days),
+		Items:       items,
+	}
+
+	categories := map[uint]*CategoryValuation{}
+	locations := map[string]*LocationValuation{}
+	for _, item := range items {
+		category, ok := categories[item.CategoryID]
+		if !ok {
+			category = &CategoryValuation{CategoryID: item.CategoryID, CategoryName: item.CategoryName}
+			categories[item.CategoryID] = category
+		}
+		location, ok := locations[item.Location]
+		if !ok {
+			location = &LocationValuation{Location: item.Location}
+			locations[item.Location] = location
+		}
+		for _, totals := range []*ValuationTotals{&report.Totals, &category.ValuationTotals, &location.ValuationTotals} {
+			totals.ComponentCount++
+			totals.Quantity += int64(item.StockQuantity)
+			totals.ValueCents += item.ValueCents
+		}
+	}
+
+	report.Categories = make([]CategoryValuation, 0, len(categories))
+	for _, category := range categories {
+		report.Categories = append(report.Categories, *category)
+	}
+	slices.SortFunc(report.Categories, func(a, b CategoryValuation) int {
+		return cmp.Or(cmp.Compare(b.ValueCents, a.ValueCents), cmp.Compare(a.CategoryID, b.CategoryID))
+	})
+	report.Locations = make([]LocationValuation, 0, len(locations))
+	for _, location := range locations {
+		report.Locations = append(report.Locations, *location)
+	}
+	slices.SortFunc(report.Locations, func(a, b LocationValuation) int {
+		return cmp.Or(cmp.Compare(b.ValueCents, a.ValueCents), cmp.Compare(a.Location, b.Location))
+	})
+	return report, nil
+}
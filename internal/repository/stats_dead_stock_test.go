@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+)
+
+func TestStatsDeadStock(t *testing.T) {
+	db := setupStatsTestDB(t)
+	now := time.Date(2026, 6, 30, 12, 0, 0, 0, time.Local)
+	daysAgo := func(days int) time.Time { return now.AddDate(0, 0, -days) }
+
+	resistor := models.Category{Name: "电阻"}
+	module := models.Category{Name: "模块"}
+	mustCreate(t, db, &resistor)
+	mustCreate(t, db, &module)
+	idle := models.Component{CategoryID: module.ID, Name: "Idle", Location: "A1-01", StockQuantity: 10, UnitPriceMicro: 5000000, CreatedAt: daysAgo(400)}
+	slow := models.Component{CategoryID: resistor.ID, Name: "Slow", Location: "A1-02", StockQuantity: 1000, UnitPriceMicro: 1000, CreatedAt: daysAgo(400)}
+	busy := models.Component{CategoryID: resistor.ID, Name: "Busy", Location: "B2", StockQuantity: 100, UnitPriceMicro: 1000, CreatedAt: daysAgo(400)}
+	revokedOnly := models.Component{CategoryID: resistor.ID, Name: "RevokedOnly", Location: "B2", StockQuantity: 50, UnitPriceMicro: 2000000, CreatedAt: daysAgo(400)}
+	fresh := models.Component{CategoryID: resistor.ID, Name: "Fresh", StockQuantity: 5, CreatedAt: daysAgo(10)}
+	empty := models.Component{CategoryID: resistor.ID, Name: "Empty", StockQuantity: 0, CreatedAt: daysAgo(400)}
+	for _, component := range []*models.Component{&idle, &slow, &busy, &revokedOnly, &fresh, &empty} {
+		mustCreate(t, db, component)
+	}
+
+	revokedAt := daysAgo(19)
+	revoked := models.StockLog{ComponentID: revokedOnly.ID, ChangeAmount: -5, Reason: "误操作", CreatedAt: daysAgo(20), RevokedAt: &revokedAt}
+	for _, log := range []*models.StockLog{
+		{ComponentID: idle.ID, ChangeAmount: 12, Reason: "项目X采购", CreatedAt: daysAgo(300)},
+		{ComponentID: idle.ID, ChangeAmount: -2, Reason: "项目X", CreatedAt: daysAgo(250)},
+		{ComponentID: slow.ID, ChangeAmount: -3, Reason: "维修", CreatedAt: daysAgo(30)},
+		{ComponentID: busy.ID, ChangeAmount: -50, Reason: "量产", CreatedAt: daysAgo(5)},
+		{ComponentID: revokedOnly.ID, ChangeAmount: 55, Reason: "入库", CreatedAt: daysAgo(200)},
+		&revoked,
+	} {
+		mustCreate(t, db, log)
+	}
+	mustCreate(t, db, &models.StockLog{ComponentID: revokedOnly.ID, ChangeAmount: 5, ReversalOfID: &revoked.ID, CreatedAt: revokedAt})
+
+	repo := NewStatsRepository(db)
+	report, err := repo.GetDeadStock(DeadStockQuery{Days: 180, Now: now})
+	if err != nil {
+		t.Fatalf("GetDeadStock: %v", err)
+	}
+	// 撤销的出库不算出库；新建与无库存的元件不计入；按占用金额降序
+	if len(report.Items) != 2 || report.Items[0].Name != "RevokedOnly" || report.Items[1].Name != "Idle" {
+		t.Fatalf("items = %+v", report.Items)
+	}
+	if got := report.Items[1]; got.ValueCents != 5000 || got.LastOutboundAt == nil || !got.LastOutboundAt.Equal(daysAgo(250)) ||
+		got.LastMovementAt == nil || got.LastMovementChange != -2 || got.LastMovementReason != "项目X" {
+		t.Errorf("idle item = %+v", got)
+	}
+	if got := report.Items[0]; got.ValueCents != 10000 || got.LastOutboundAt != nil || got.LastMovementChange != 55 || got.LastMovementReason != "入库" {
+		t.Errorf("revoked-only item = %+v", got)
+	}
+	if report.Totals.ComponentCount != 2 || report.Totals.Quantity != 60 || report.Totals.ValueCents != 15000 {
+		t.Errorf("totals = %+v", report.Totals)
+	}
+	if len(report.Categories) != 2 || report.Categories[0].CategoryName != "电阻" || report.Categories[0].ValueCents != 10000 {
+		t.Errorf("categories = %+v", report.Categories)
+	}
+	if len(report.Locations) != 2 || report.Locations[0].Location != "B2" || report.Locations[1].Location != "A1-01" {
+		t.Errorf("locations = %+v", report.Locations)
+	}
+
+	// 慢动料：允许少量出库
+	items, err := repo.DeadStockItems(DeadStockQuery{Days: 180, MaxOutbound: 3, Now: now, Location: "A1"})
+	if err != nil {
+		t.Fatalf("DeadStockItems: %v", err)
+	}
+	if len(items) != 2 || items[0].Name != "Idle" || items[1].Name != "Slow" || items[1].OutboundQuantity != 3 {
+		t.Errorf("slow movers in A1 = %+v", items)
+	}
+	// 位置前缀中的 LIKE 通配符按字面匹配
+	if items, err := repo.DeadStockItems(DeadStockQuery{Days: 180, MaxOutbound: 3, Now: now, Location: "A1_"}); err != nil || len(items) != 0 {
+		t.Errorf("location A1_ = %+v, %v", items, err)
+	}
+	if items, err := repo.DeadStockItems(DeadStockQuery{Days: 180, Now: now, CategoryIDs: []uint{module.ID}}); err != nil || len(items) != 1 {
+		t.Errorf("category filter = %+v, %v", items, err)
+	}
+}
@@ -9,6 +9,12 @@ import (
 
 var ErrInvalidStatsRange = errors.New("无效的统计时间范围")
 
+// dbTime 将时间边界换算为与 created_at 比较的值。
+// 写入时间按服务器时区保存，SQLite 以文本比较时间，因此边界换算到服务器时区
+func dbTime(t time.Time) time.Time {
+	return t.In(time.Local)
+}
+
 const (
 	StatsRangeMonth   = "month"
 	StatsRangeQuarter = "quarter"
@@ -27,14 +33,35 @@ type DashboardStats struct {
 	InboundQuantity     int64      `json:"inbound_quantity"`
 	OutboundQuantity    int64      `json:"outbound_quantity"`
 	InboundCostCents    int64      `json:"inbound_cost_cents"`
+	// OperatorConsumption 按操作人汇总的出库消耗，按出库金额降序
+	OperatorConsumption []OperatorConsumption `json:"operator_consumption"`
+	// SavedSearches 当前用户可见的保存搜索命中数，由 handler 按用户填充
+	SavedSearches []SavedSearchCount `json:"saved_searches"`
+}
+
+// OperatorConsumption 单个操作人在统计范围内的出库消耗
+type OperatorConsumption struct {
+	Operator          string `json:"operator"`
+	OutboundQuantity  int64  `json:"outbound_quantity"`
+	OutboundCostCents int64  `json:"outbound_cost_cents"`
 }
 
 type StatsRepository struct {
-	db *gorm.DB
+	db          *gorm.DB
+	workspaceID uint
 }
 
 func NewStatsRepository(db *gorm.DB) *StatsRepository {
-	return &StatsRepository{db: db}
+	return &StatsRepository{db: db, workspaceID: DefaultWorkspaceID}
+}
+
+// ForWorkspace 返回限定在指定工作区内的仓储
+func (r *StatsRepository) ForWorkspace(workspaceID uint) *StatsRepository {
+	return &StatsRepository{db: r.db, workspaceID: workspaceID}
+}
+
+func (r *StatsRepository) table(name string) *gorm.DB {
+	return r.db.Table(name).Where("workspace_id = ?", r.workspaceID)
 }
 
 func (r *StatsRepository) GetDashboardStats(rangeKey string) (*DashboardStats, error) {
@@ -49,11 +76,11 @@ func (r *StatsRepository) GetDashboardStats(rangeKey string) (*DashboardStats, e
 		RangeEnd:   rangeEnd,
 	}
 
-	if err := r.db.Table("components").Count(&stats.ComponentCount).Error; err != nil {
+	if err := r.table("components").Count(&stats.ComponentCount).Error; err != nil {
 		return nil, err
 	}
 
-	if err := r.db.Table("categories").Count(&stats.CategoryCount).Error; err != nil {
+	if err := r.table("categories").Count(&stats.CategoryCount).Error; err != nil {
 		return nil, err
 	}
 
@@ -61,7 +88,7 @@ func (r *StatsRepository) GetDashboardStats(rangeKey string) (*DashboardStats, e
 		Total int64
 	}
 	var stockSum sumResult
-	if err := r.db.Table("components").
+	if err := r.table("components").
 		Select("COALESCE(SUM(stock_quantity), 0) AS total").
 		Scan(&stockSum).Error; err != nil {
 		return nil, err
@@ -69,7 +96,7 @@ func (r *StatsRepository) GetDashboardStats(rangeKey string) (*DashboardStats, e
 	stats.TotalStock = stockSum.Total
 
 	var valueSum sumResult
-	if err := r.db.Table("components").
+	if err := r.table("components").
 		Select("COALESCE(SUM(stock_quantity * unit_price_micro), 0) / 10000 AS total").
 		Where("stock_quantity > 0 AND unit_price_micro > 0").
 		Scan(&valueSum).Error; err != nil {
@@ -77,7 +104,7 @@ func (r *StatsRepository) GetDashboardStats(rangeKey string) (*DashboardStats, e
 	}
 	stats.InventoryValueCents = valueSum.Total
 
-	logQuery := r.db.Table("stock_logs").
+	logQuery := r.table("stock_logs").
 		Where("revoked_at IS NULL AND reversal_of_id IS NULL AND change_amount != 0")
 	if rangeStart != nil {
 		logQuery = logQuery.Where("created_at >= ? AND created_at <= ?", *rangeStart, *rangeEnd)
@@ -110,6 +137,16 @@ func (r *StatsRepository) GetDashboardStats(rangeKey string) (*DashboardStats, e
 	}
 	stats.InboundCostCents = inboundCost.Total
 
+	stats.OperatorConsumption = []OperatorConsumption{}
+	if err := logQuery.Session(&gorm.Session{}).
+		Where("change_amount < 0").
+		Select("operator, COALESCE(SUM(ABS(change_amount)), 0) AS outbound_quantity, COALESCE(SUM(total_price_cents), 0) AS outbound_cost_cents").
+		Group("operator").
+		Order("outbound_cost_cents DESC, outbound_quantity DESC, operator").
+		Scan(&stats.OperatorConsumption).Error; err != nil {
+		return nil, err
+	}
+
 	return stats, nil
 }
 
@@ -15,7 +15,7 @@ func setupStatsTestDB(t *testing.T) *gorm.DB {
 	if err != nil {
 		t.Fatalf("open db: %v", err)
 	}
-	if err := db.AutoMigrate(&models.Category{}, &models.Component{}, &models.StockLog{}); err != nil {
+	if err := db.AutoMigrate(&models.Category{}, &models.Component{}, &models.ComponentTag{}, &models.StockLog{}); err != nil {
 		t.Fatalf("migrate: %v", err)
 	}
 	return db
@@ -64,6 +64,7 @@ func seedStatsFixtures(t *testing.T, db *gorm.DB) (uint, uint) {
 			ChangeAmount:    -2,
 			TotalPriceCents: 200,
 			Reason:          "本月出库",
+			Operator:        "alice",
 			CreatedAt:       now,
 		},
 		{
@@ -150,6 +151,12 @@ func TestStatsRepositoryGetDashboardStatsAll(t *testing.T) {
 	if stats.RangeStart != nil {
 		t.Fatalf("range_start should be nil for all")
 	}
+	if len(stats.OperatorConsumption) != 1 {
+		t.Fatalf("operator_consumption = %+v, want 1 entry", stats.OperatorConsumption)
+	}
+	if got := stats.OperatorConsumption[0]; got.Operator != "alice" || got.OutboundQuantity != 2 || got.OutboundCostCents != 200 {
+		t.Fatalf("operator_consumption[0] = %+v, want alice/2/200", got)
+	}
 }
 
 func TestStatsRepositoryGetDashboardStatsMonth(t *testing.T) {
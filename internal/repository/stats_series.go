@@ -0,0 +1,305 @@
+package repository
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/price"
+)
+
+// 时间序列统计的桶大小
+const (
+	StatsBucketDay   = "day"
+	StatsBucketWeek  = "week"
+	StatsBucketMonth = "month"
+)
+
+// 时间序列统计的分组维度；project 为出入库原因（批量出库时填写的项目/用途），目前没有独立的项目实体
+const (
+	StatsGroupCategory = "category"
+	StatsGroupSupplier = "supplier"
+	StatsGroupLocation = "location"
+	StatsGroupProject  = "project"
+)
+
+const (
+	// MaxStatsBuckets 单次请求最多返回的时间桶数
+	MaxStatsBuckets = 1000
+	// DefaultStatsTopN 默认返回的消耗最多元件数
+	DefaultStatsTopN = 10
+	// MaxStatsTopN 最多返回的消耗最多元件数
+	MaxStatsTopN = 100
+)
+
+var ErrTooManyStatsBuckets = errors.New("时间范围内的统计桶过多，请缩小范围或增大桶大小")
+
+var statsGroupColumns = map[string]string{
+	StatsGroupCategory: "COALESCE(categories.name, '')",
+	StatsGroupSupplier: "COALESCE(suppliers.name, '')",
+	StatsGroupLocation: "COALESCE(components.location, '')",
+	StatsGroupProject:  "COALESCE(stock_logs.reason, '')",
+}
+
+// IsStatsBucket 判断是否为支持的桶大小
+func IsStatsBucket(bucket string) bool {
+	return bucket == StatsBucketDay || bucket == StatsBucketWeek || bucket == StatsBucketMonth
+}
+
+// IsStatsGroup 判断是否为支持的分组维度
+func IsStatsGroup(group string) bool {
+	_, ok := statsGroupColumns[group]
+	return ok
+}
+
+// StatsSeriesQuery 时间序列统计参数：时间范围为 [From, To)，桶按 Location 时区的自然日/周（周一开始）/月划分
+type StatsSeriesQuery struct {
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+	Bucket   string
+	// GroupBy 为空时不分组
+	GroupBy string
+	TopN    int
+}
+
+// StatsAmounts 入库、出库的数量与金额
+type StatsAmounts struct {
+	InboundQuantity   int64 `json:"inbound_quantity"`
+	OutboundQuantity  int64 `json:"outbound_quantity"`
+	InboundCostCents  int64 `json:"inbound_cost_cents"`
+	OutboundCostCents int64 `json:"outbound_cost_cents"`
+}
+
+// StatsPoint 单个时间桶的统计，Start 为桶在请求时区的起点
+type StatsPoint struct {
+	Start time.Time `json:"start"`
+	StatsAmounts
+}
+
+// StatsGroupSeries 单个分组的合计与时间序列；Key 为空表示未设置（无分类、无供应商、无位置或无原因）
+type StatsGroupSeries struct {
+	Key    string       `json:"key"`
+	Totals StatsAmounts `json:"totals"`
+	Series []StatsPoint `json:"series"`
+}
+
+// ConsumedComponent 统计范围内出库最多的元件
+type ConsumedComponent struct {
+	ComponentID       uint   `json:"component_id"`
+	ComponentNumber   string `json:"component_number"`
+	Name              string `json:"name"`
+	OutboundQuantity  int64  `json:"outbound_quantity"`
+	OutboundCostCents int64  `json:"outbound_cost_cents"`
+}
+
+// StatsSeries 时间序列统计结果
+type StatsSeries struct {
+	From        time.Time           `json:"from"`
+	To          time.Time           `json:"to"`
+	Timezone    string              `json:"timezone"`
+	Bucket      string              `json:"bucket"`
+	GroupBy     string              `json:"group_by,omitempty"`
+	Totals      StatsAmounts        `json:"totals"`
+	Series      []StatsPoint        `json:"series"`
+	Groups      []StatsGroupSeries  `json:"groups,omitempty"`
+	TopConsumed []ConsumedComponent `json:"top_consumed"`
+}
+
+// statsLogRow 参与统计的库存记录
+type statsLogRow struct {
+	CreatedAt       time.Time
+	ComponentID     uint
+	ChangeAmount    int
+	UnitPriceMicro  int64
+	TotalPriceCents int64
+	GroupKey        string
+	ComponentNumber string
+	ComponentName   string
+}
+
+// amounts 返回单条记录的入库/出库数量与金额；出库未记录成本但有单价时按 price.OutboundTotalCents 补算
+func (row statsLogRow) amounts() StatsAmounts {
+	if row.ChangeAmount > 0 {
+		return StatsAmounts{InboundQuantity: int64(row.ChangeAmount), InboundCostCents: row.TotalPriceCents}
+	}
+	quantity := -row.ChangeAmount
+	cost := row.TotalPriceCents
+	if cost == 0 && row.UnitPriceMicro > 0 {
+		cost = price.OutboundTotalCents(row.UnitPriceMicro, quantity)
+	}
+	return StatsAmounts{OutboundQuantity: int64(quantity), OutboundCostCents: cost}
+}
+
+func (a *StatsAmounts) add(b StatsAmounts) {
+	a.InboundQuantity += b.InboundQuantity
+	a.OutboundQuantity += b.OutboundQuantity
+	a.InboundCostCents += b.InboundCostCents
+	a.OutboundCostCents += b.OutboundCostCents
+}
+
+// GetSeries 按时间桶统计入库/出库数量与金额，可按分类、供应商、位置或项目分组，并列出出库最多的元件。
+// 口径与 GetDashboardStats 相同：排除已撤销记录、冲销流水与变动为 0 的记录
+func (r *StatsRepository) GetSeries(query StatsSeriesQuery) (*StatsSeries, error) {
+	loc := query.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	from, to := query.From.In(loc), query.To.In(loc)
+	starts, err := statsBucketStarts(from, to, query.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	topN := query.TopN
+	if topN <= 0 {
+		topN = DefaultStatsTopN
+	}
+	topN = min(topN, MaxStatsTopN)
+
+	result := &StatsSeries{
+		From:     from,
+		To:       to,
+		Timezone: loc.String(),
+		Bucket:   query.Bucket,
+		GroupBy:  query.GroupBy,
+		Series:   newStatsPoints(starts),
+	}
+
+	groupColumn := "''"
+	if column, ok := statsGroupColumns[query.GroupBy]; ok {
+		groupColumn = column
+	}
+	db := r.db.Table("stock_logs").
+		Select("stock_logs.created_at, stock_logs.component_id, stock_logs.change_amount, stock_logs.unit_price_micro, stock_logs.total_price_cents, "+
+			groupColumn+" AS group_key, COALESCE(components.component_number, '') AS component_number, COALESCE(components.name, '') AS component_name").
+		Joins("LEFT JOIN components ON components.id = stock_logs.component_id").
+		Joins("LEFT JOIN categories ON categories.id = components.category_id").
+		Joins("LEFT JOIN suppliers ON suppliers.id = components.supplier_id").
+		Where("stock_logs.workspace_id = ?", r.workspaceID).
+		Where("stock_logs.revoked_at IS NULL AND stock_logs.reversal_of_id IS NULL AND stock_logs.change_amount <> 0").
+		Where("stock_logs.created_at >= ? AND stock_logs.created_at < ?", dbTime(from), dbTime(to))
+
+	groups := map[string]*StatsGroupSeries{}
+	consumed := map[uint]*ConsumedComponent{}
+	err = eachRow(db, func(row *statsLogRow) error {
+		index := statsBucketIndex(starts, row.CreatedAt.In(loc))
+		if index < 0 {
+			return nil
+		}
+		amounts := row.amounts()
+		result.Totals.add(amounts)
+		result.Series[index].add(amounts)
+
+		if query.GroupBy != "" {
+			group, ok := groups[row.GroupKey]
+			if !ok {
+				group = &StatsGroupSeries{Key: row.GroupKey, Series: newStatsPoints(starts)}
+				groups[row.GroupKey] = group
+			}
+			group.Totals.add(amounts)
+			group.Series[index].add(amounts)
+		}
+
+		if amounts.OutboundQuantity > 0 {
+			component, ok := consumed[row.ComponentID]
+			if !ok {
+				component = &ConsumedComponent{ComponentID: row.ComponentID, ComponentNumber: row.ComponentNumber, Name: row.ComponentName}
+				consumed[row.ComponentID] = component
+			}
+			component.OutboundQuantity += amounts.OutboundQuantity
+			component.OutboundCostCents += amounts.OutboundCostCents
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if query.GroupBy != "" {
+		result.Groups = make([]StatsGroupSeries, 0, len(groups))
+		for _, group := range groups {
+			result.Groups = append(result.Groups, *group)
+		}
+		slices.SortFunc(result.Groups, func(a, b StatsGroupSeries) int {
+			return cmp.Or(
+				cmp.Compare(b.Totals.OutboundCostCents, a.Totals.OutboundCostCents),
+				cmp.Compare(b.Totals.OutboundQuantity, a.Totals.OutboundQuantity),
+				cmp.Compare(b.Totals.InboundCostCents, a.Totals.InboundCostCents),
+				cmp.Compare(a.Key, b.Key),
+			)
+		})
+	}
+
+	result.TopConsumed = make([]ConsumedComponent, 0, len(consumed))
+	for _, component := range consumed {
+		result.TopConsumed = append(result.TopConsumed, *component)
+	}
+	slices.SortFunc(result.TopConsumed, func(a, b ConsumedComponent) int {
+		return cmp.Or(
+			cmp.Compare(b.OutboundQuantity, a.OutboundQuantity),
+			cmp.Compare(b.OutboundCostCents, a.OutboundCostCents),
+			cmp.Compare(a.ComponentID, b.ComponentID),
+		)
+	})
+	result.TopConsumed = result.TopConsumed[:min(topN, len(result.TopConsumed))]
+	return result, nil
+}
+
+// statsBucketStarts 返回 [from, to) 内各桶的起点；第一个桶从 from 所在的自然日/周/月开始
+func statsBucketStarts(from, to time.Time, bucket string) ([]time.Time, error) {
+	if !from.Before(to) {
+		return nil, ErrInvalidStatsRange
+	}
+	start := truncateToBucket(from, bucket)
+	var starts []time.Time
+	for t := start; t.Before(to); t = nextBucket(t, bucket) {
+		if len(starts) == MaxStatsBuckets {
+			return nil, ErrTooManyStatsBuckets
+		}
+		starts = append(starts, t)
+	}
+	return starts, nil
+}
+
+func truncateToBucket(t time.Time, bucket string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch bucket {
+	case StatsBucketWeek:
+		// 周一为一周的开始
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case StatsBucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return day
+	}
+}
+
+// nextBucket 按日历推进，跨越夏令时切换时仍落在当地零点
+func nextBucket(t time.Time, bucket string) time.Time {
+	switch bucket {
+	case StatsBucketWeek:
+		return t.AddDate(0, 0, 7)
+	case StatsBucketMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// statsBucketIndex 返回 t 所在桶的下标，早于第一个桶时返回 -1
+func statsBucketIndex(starts []time.Time, t time.Time) int {
+	i, found := slices.BinarySearchFunc(starts, t, func(start, target time.Time) int { return start.Compare(target) })
+	if found {
+		return i
+	}
+	return i - 1
+}
+
+func newStatsPoints(starts []time.Time) []StatsPoint {
+	points := make([]StatsPoint, len(starts))
+	for i, start := range starts {
+		points[i].Start = start
+	}
+	return points
+}
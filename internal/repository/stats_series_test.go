@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+)
+
+func TestStatsSeries(t *testing.T) {
+	db := setupStatsTestDB(t)
+	if err := db.AutoMigrate(&models.Supplier{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+
+	resistor := models.Category{Name: "电阻"}
+	capacitor := models.Category{Name: "电容"}
+	mustCreate(t, db, &resistor)
+	mustCreate(t, db, &capacitor)
+	supplier := models.Supplier{Name: "LCSC"}
+	mustCreate(t, db, &supplier)
+	r1 := models.Component{CategoryID: resistor.ID, Name: "R1", ComponentNumber: strPtr("R-1"), SupplierID: &supplier.ID, Location: "A1"}
+	c1 := models.Component{CategoryID: capacitor.ID, Name: "C1", ComponentNumber: strPtr("C-1"), Location: "B2"}
+	other := models.Component{WorkspaceID: 2, CategoryID: resistor.ID, Name: "R9"}
+	mustCreate(t, db, &r1)
+	mustCreate(t, db, &c1)
+	mustCreate(t, db, &other)
+
+	at := func(value string) time.Time {
+		t.Helper()
+		parsed, err := time.ParseInLocation(time.DateTime, value, shanghai)
+		if err != nil {
+			t.Fatalf("parse %s: %v", value, err)
+		}
+		return parsed.In(time.Local)
+	}
+	revokedAt := at("2026-03-02 10:00:00")
+	logs := []models.StockLog{
+		{ComponentID: r1.ID, ChangeAmount: 100, TotalPriceCents: 1000, Reason: "采购", CreatedAt: at("2026-03-02 09:00:00")},
+		// 按 UTC 属于 3 月 2 日，按上海时区属于 3 月 3 日
+		{ComponentID: r1.ID, ChangeAmount: -10, UnitPriceMicro: 100000, Reason: "项目A", CreatedAt: at("2026-03-03 01:00:00")},
+		{ComponentID: c1.ID, ChangeAmount: -4, TotalPriceCents: 80, Reason: "项目B", CreatedAt: at("2026-03-09 12:00:00")},
+		{ComponentID: r1.ID, ChangeAmount: -5, TotalPriceCents: 50, Reason: "项目A", CreatedAt: at("2026-03-10 12:00:00")},
+		{ComponentID: r1.ID, ChangeAmount: -50, TotalPriceCents: 500, Reason: "撤销", CreatedAt: at("2026-03-04 12:00:00"), RevokedAt: &revokedAt},
+		{ComponentID: r1.ID, ChangeAmount: 0, TotalPriceCents: 999, Reason: "调价", CreatedAt: at("2026-03-04 12:00:00")},
+		{ComponentID: r1.ID, ChangeAmount: -1, TotalPriceCents: 10, Reason: "范围外", CreatedAt: at("2026-03-11 00:00:00")},
+		{WorkspaceID: 2, ComponentID: other.ID, ChangeAmount: -7, Reason: "其他工作区", CreatedAt: at("2026-03-05 12:00:00")},
+	}
+	for i := range logs {
+		mustCreate(t, db, &logs[i])
+	}
+
+	repo := NewStatsRepository(db)
+	query := StatsSeriesQuery{
+		From:     at("2026-03-02 00:00:00"),
+		To:       at("2026-03-11 00:00:00"),
+		Location: shanghai,
+		Bucket:   StatsBucketDay,
+		GroupBy:  StatsGroupProject,
+	}
+	series, err := repo.GetSeries(query)
+	if err != nil {
+		t.Fatalf("GetSeries: %v", err)
+	}
+	if got := formatStatsAmounts(series.Totals); got != "in 100/1000 out 19/230" {
+		t.Errorf("totals = %s", got)
+	}
+	if len(series.Series) != 9 || !series.Series[0].Start.Equal(query.From) {
+		t.Fatalf("series = %d buckets starting %v", len(series.Series), series.Series[0].Start)
+	}
+	if got := formatStatsAmounts(series.Series[1].StatsAmounts); got != "in 0/0 out 10/100" {
+		t.Errorf("2026-03-03 = %s", got)
+	}
+	var groups []string
+	for _, group := range series.Groups {
+		groups = append(groups, group.Key+" "+formatStatsAmounts(group.Totals))
+	}
+	if got := fmt.Sprint(groups); got != "[项目A in 0/0 out 15/150 项目B in 0/0 out 4/80 采购 in 100/1000 out 0/0]" {
+		t.Errorf("groups = %s", got)
+	}
+	if len(series.TopConsumed) != 2 || series.TopConsumed[0].ComponentNumber != "R-1" ||
+		series.TopConsumed[0].OutboundQuantity != 15 || series.TopConsumed[1].Name != "C1" {
+		t.Errorf("top consumed = %+v", series.TopConsumed)
+	}
+
+	query.Bucket, query.GroupBy, query.TopN = StatsBucketWeek, StatsGroupCategory, 1
+	series, err = repo.GetSeries(query)
+	if err != nil {
+		t.Fatalf("GetSeries week: %v", err)
+	}
+	// 2026-03-02 为周一
+	if len(series.Series) != 2 || formatStatsAmounts(series.Series[1].StatsAmounts) != "in 0/0 out 9/130" {
+		t.Errorf("weekly series = %+v", series.Series)
+	}
+	if len(series.Groups) != 2 || series.Groups[0].Key != "电阻" || len(series.TopConsumed) != 1 {
+		t.Errorf("weekly groups = %+v, top = %+v", series.Groups, series.TopConsumed)
+	}
+
+	query.Bucket, query.GroupBy = StatsBucketMonth, StatsGroupSupplier
+	series, err = repo.GetSeries(query)
+	if err != nil {
+		t.Fatalf("GetSeries month: %v", err)
+	}
+	if len(series.Series) != 1 || len(series.Groups) != 2 || series.Groups[1].Key != "" {
+		t.Errorf("monthly = %+v", series.Groups)
+	}
+
+	query.Bucket = StatsBucketDay
+	query.To = query.From.AddDate(0, 0, MaxStatsBuckets+1)
+	if _, err := repo.GetSeries(query); !errors.Is(err, ErrTooManyStatsBuckets) {
+		t.Errorf("too many buckets err = %v", err)
+	}
+	query.To = query.From
+	if _, err := repo.GetSeries(query); !errors.Is(err, ErrInvalidStatsRange) {
+		t.Errorf("empty range err = %v", err)
+	}
+}
+
+func formatStatsAmounts(amounts StatsAmounts) string {
+	return fmt.Sprintf("in %d/%d out %d/%d", amounts.InboundQuantity, amounts.InboundCostCents, amounts.OutboundQuantity, amounts.OutboundCostCents)
+}
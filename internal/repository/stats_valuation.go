@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/Rehtt/hamster-bin/internal/price"
+	"gorm.io/gorm"
+)
+
+// ValuationQuery 历史库存估值参数：统计 At 之前（不含）的库存，CategoryIDs 非空时只统计这些分类下的元件
+type ValuationQuery struct {
+	At          time.Time
+	CategoryIDs []uint
+}
+
+// ComponentValuation 单个元件在某一时刻的库存数量与价值
+type ComponentValuation struct {
+	ComponentID     uint   `json:"component_id"`
+	ComponentNumber string `json:"component_number"`
+	Name            string `json:"name"`
+	CategoryID      uint   `json:"category_id"`
+	CategoryName    string `json:"category_name"`
+	Quantity        int    `json:"quantity"`
+	UnitPriceMicro  int64  `json:"unit_price_micro"`
+	ValueCents      int64  `json:"value_cents"`
+	// PriceEstimated 为 true 表示无法从库存记录还原当时的单价，使用了当前参考单价
+	PriceEstimated bool `json:"price_estimated"`
+}
+
+// ValuationTotals 库存估值合计，只统计当时有库存的元件
+type ValuationTotals struct {
+	ComponentCount int   `json:"component_count"`
+	Quantity       int64 `json:"quantity"`
+	ValueCents     int64 `json:"value_cents"`
+}
+
+// CategoryValuation 单个分类的库存估值合计
+type CategoryValuation struct {
+	CategoryID   uint   `json:"category_id"`
+	CategoryName string `json:"category_name"`
+	ValuationTotals
+}
+
+// InventoryValuation 某一时刻的库存估值
+type InventoryValuation struct {
+	At         time.Time           `json:"at"`
+	Totals     ValuationTotals     `json:"totals"`
+	Categories []CategoryValuation `json:"categories"`
+}
+
+// StockHistoryPoint 单个时间桶结束时的库存数量与价值
+type StockHistoryPoint struct {
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	Quantity       int       `json:"quantity"`
+	UnitPriceMicro int64     `json:"unit_price_micro"`
+	ValueCents     int64     `json:"value_cents"`
+}
+
+// valuationLog 参与重放的库存记录
+type valuationLog struct {
+	ComponentID     uint
+	ChangeAmount    int
+	UnitPriceMicro  int64
+	TotalPriceCents int64
+	MergedFromID    *uint
+	CreatedAt       time.Time
+}
+
+// stockReplay 按时间顺序重放库存记录得到的数量与加权平均单价，计算方式与入库、补录价格时更新参考单价一致
+type stockReplay struct {
+	quantity       int
+	unitPriceMicro int64
+}
+
+func (s *stockReplay) apply(log *valuationLog) {
+	switch {
+	case log.ChangeAmount > 0 && log.TotalPriceCents > 0:
+		if unitPrice := price.WeightedAverageUnitPriceMicro(s.quantity, s.unitPriceMicro, log.ChangeAmount, log.TotalPriceCents); unitPrice > 0 {
+			s.unitPriceMicro = unitPrice
+		}
+	case log.ChangeAmount == 0 && log.MergedFromID == nil && log.TotalPriceCents > 0 && log.UnitPriceMicro > 0:
+		// 补录价格记录只保存了分摊单价与总价，按两者反推采购数量
+		purchased := int((log.TotalPriceCents*price.MicroPerCent + log.UnitPriceMicro/2) / log.UnitPriceMicro)
+		if unitPrice := price.WeightedAverageUnitPriceMicro(s.quantity, s.unitPriceMicro, purchased, log.TotalPriceCents); unitPrice > 0 {
+			s.unitPriceMicro = unitPrice
+		}
+	}
+	s.quantity += log.ChangeAmount
+}
+
+// valuation 返回重放状态对应的单价与价值；重放不出单价时使用当前参考单价并标记为估算
+func (s *stockReplay) valuation(currentUnitPriceMicro int64) (int64, int64, bool) {
+	unitPrice, estimated := s.unitPriceMicro, false
+	if unitPrice == 0 && currentUnitPriceMicro > 0 {
+		unitPrice, estimated = currentUnitPriceMicro, true
+	}
+	return unitPrice, price.TotalCents(unitPrice, s.quantity), estimated
+}
+
+// effectiveLogs 参与重放的库存记录：已撤销的记录及其冲销流水视为从未发生
+func (r *StatsRepository) effectiveLogs() *gorm.DB {
+	return r.db.Table("stock_logs").
+		Where("stock_logs.workspace_id = ?", r.workspaceID).
+		Where("stock_logs.revoked_at IS NULL AND stock_logs.reversal_of_id IS NULL")
+}
+
+// openingQuantities 返回各元件在第一条有效记录之前的库存：当前库存减去全部有效变动
+func (r *StatsRepository) openingQuantities(db *gorm.DB) (map[uint]int, error) {
+	var rows []struct {
+		ComponentID uint
+		Total       int
+	}
+	if err := db.Select("stock_logs.component_id, SUM(stock_logs.change_amount) AS total").
+		Group("stock_logs.component_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	totals := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		totals[row.ComponentID] = row.Total
+	}
+	return totals, nil
+}
+
+// EachValuation 以当前库存为准倒推、再按时间重放库存记录，得到各元件在 query.At 时的数量、加权平均单价与价值，
+// 按元件 ID 顺序逐个回调。只包含当时已创建的现存元件（已删除元件的记录无法还原），当时库存为 0 的元件也会回调
+func (r *StatsRepository) EachValuation(query ValuationQuery, fn func(*ComponentValuation) error) error {
+	at := dbTime(query.At)
+
+	var components []struct {
+		ID              uint
+		ComponentNumber string
+		Name            string
+		CategoryID      uint
+		CategoryName    string
+		StockQuantity   int
+		UnitPriceMicro  int64
+	}
+	db := inWorkspace(r.db.Table("components"), "components", r.workspaceID).
+		Select("components.id, COALESCE(components.component_number, '') AS component_number, components.name, "+
+			"components.category_id, COALESCE(categories.name, '') AS category_name, components.stock_quantity, components.unit_price_micro").
+		Joins("LEFT JOIN categories ON categories.id = components.category_id").
+		Where("components.created_at < ?", at).
+		Order("components.id ASC")
+	if len(query.CategoryIDs) > 0 {
+		db = db.Where("components.category_id IN ?", query.CategoryIDs)
+	}
+	if err := db.Scan(&components).Error; err != nil {
+		return err
+	}
+
+	totals, err := r.openingQuantities(r.effectiveLogs())
+	if err != nil {
+		return err
+	}
+	replays := make(map[uint]*stockReplay, len(components))
+	for _, component := range components {
+		replays[component.ID] = &stockReplay{quantity: component.StockQuantity - totals[component.ID]}
+	}
+
+	logs := r.effectiveLogs().
+		Select("stock_logs.component_id, stock_logs.change_amount, stock_logs.unit_price_micro, stock_logs.total_price_cents, stock_logs.merged_from_id, stock_logs.created_at").
+		Where("stock_logs.created_at < ?", at).
+		Order("stock_logs.created_at ASC, stock_logs.id ASC")
+	err = eachRow(logs, func(log *valuationLog) error {
+		if replay, ok := replays[log.ComponentID]; ok {
+			replay.apply(log)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, component := range components {
+		replay := replays[component.ID]
+		unitPrice, value, estimated := replay.valuation(component.UnitPriceMicro)
+		err := fn(&ComponentValuation{
+			ComponentID:     component.ID,
+			ComponentNumber: component.ComponentNumber,
+			Name:            component.Name,
+			CategoryID:      component.CategoryID,
+			CategoryName:    component.CategoryName,
+			Quantity:        replay.quantity,
+			UnitPriceMicro:  unitPrice,
+			ValueCents:      value,
+			PriceEstimated:  estimated,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetValuation 返回 query.At 时的库存估值合计与按分类的合计（按价值降序），口径见 EachValuation
+func (r *StatsRepository) GetValuation(query ValuationQuery) (*InventoryValuation, error) {
+	result := &InventoryValuation{At: query.At, Categories: []CategoryValuation{}}
+	categories := map[uint]*CategoryValuation{}
+	err := r.EachValuation(query, func(item *ComponentValuation) error {
+		if item.Quantity == 0 {
+			return nil
+		}
+		category, ok := categories[item.CategoryID]
+		if !ok {
+			category = &CategoryValuation{CategoryID: item.CategoryID, CategoryName: item.CategoryName}
+			categories[item.CategoryID] = category
+		}
+		for _, totals := range []*ValuationTotals{&result.Totals, &category.ValuationTotals} {
+			totals.ComponentCount++
+			totals.Quantity += int64(item.Quantity)
+			totals.ValueCents += item.ValueCents
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, category := range categories {
+		result.Categories = append(result.Categories, *category)
+	}
+	slices.SortFunc(result.Categories, func(a, b CategoryValuation) int {
+		return cmp.Or(cmp.Compare(b.ValueCents, a.ValueCents), cmp.Compare(a.CategoryID, b.CategoryID))
+	})
+	return result, nil
+}
+
+// GetComponentStockHistory 返回单个元件在 [From, To) 内每个时间桶结束时的库存数量与价值，重放口径见 EachValuation。
+// 只使用 query 的 From、To、Location 与 Bucket
+func (r *StatsRepository) GetComponentStockHistory(componentID uint, query StatsSeriesQuery) ([]StockHistoryPoint, error) {
+	loc := query.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	from, to := query.From.In(loc), query.To.In(loc)
+	starts, err := statsBucketStarts(from, to, query.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	var component models.Component
+	if err := inWorkspace(r.db, "components", r.workspaceID).First(&component, componentID).Error; err != nil {
+		return nil, err
+	}
+	totals, err := r.openingQuantities(r.effectiveLogs().Where("stock_logs.component_id = ?", componentID))
+	if err != nil {
+		return nil, err
+	}
+	var logs []valuationLog
+	if err := r.effectiveLogs().
+		Select("stock_logs.component_id, stock_logs.change_amount, stock_logs.unit_price_micro, stock_logs.total_price_cents, stock_logs.merged_from_id, stock_logs.created_at").
+		Where("stock_logs.component_id = ?", componentID).
+		Where("stock_logs.created_at < ?", dbTime(to)).
+		Order("stock_logs.created_at ASC, stock_logs.id ASC").
+		Scan(&logs).Error; err != nil {
+		return nil, err
+	}
+
+	replay := &stockReplay{quantity: component.StockQuantity - totals[componentID]}
+	points := make([]StockHistoryPoint, len(starts))
+	next := 0
+	for i, start := range starts {
+		end := to
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		for ; next < len(logs) && logs[next].CreatedAt.Before(end); next++ {
+			replay.apply(&logs[next])
+		}
+		unitPrice, value, _ := replay.valuation(component.UnitPriceMicro)
+		points[i] = StockHistoryPoint{Start: start, End: end, Quantity: replay.quantity, UnitPriceMicro: unitPrice, ValueCents: value}
+	}
+	return points, nil
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+)
+
+func TestStatsValuationAsOf(t *testing.T) {
+	db := setupStatsTestDB(t)
+	day := func(value string) time.Time {
+		t.Helper()
+		parsed, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+		if err != nil {
+			t.Fatalf("parse %s: %v", value, err)
+		}
+		return parsed.Add(12 * time.Hour)
+	}
+
+	resistor := models.Category{Name: "电阻"}
+	module := models.Category{Name: "模块"}
+	mustCreate(t, db, &resistor)
+	mustCreate(t, db, &module)
+	// A 的当前库存 100 = 100 - 30 + 50 + 10（一月撤销）- 10（冲销）- 20
+	a := models.Component{CategoryID: resistor.ID, Name: "A", ComponentNumber: strPtr("HB-A"), StockQuantity: 100, UnitPriceMicro: 141666, CreatedAt: day("2025-12-01")}
+	// B 没有库存记录，只能使用当前参考单价
+	b := models.Component{CategoryID: module.ID, Name: "B", StockQuantity: 5, UnitPriceMicro: 2000000, CreatedAt: day("2025-11-01")}
+	late := models.Component{CategoryID: resistor.ID, Name: "Late", StockQuantity: 7, UnitPriceMicro: 1000000, CreatedAt: day("2026-01-05")}
+	mustCreate(t, db, &a)
+	mustCreate(t, db, &b)
+	mustCreate(t, db, &late)
+
+	revokedAt := day("2026-01-05")
+	revoked := models.StockLog{ComponentID: a.ID, ChangeAmount: 10, TotalPriceCents: 500, CreatedAt: day("2025-12-25"), RevokedAt: &revokedAt}
+	logs := []*models.StockLog{
+		{ComponentID: a.ID, ChangeAmount: 100, UnitPriceMicro: 100000, TotalPriceCents: 1000, CreatedAt: day("2025-12-01")},
+		{ComponentID: a.ID, ChangeAmount: -30, TotalPriceCents: 300, CreatedAt: day("2025-12-10")},
+		{ComponentID: a.ID, ChangeAmount: 50, UnitPriceMicro: 200000, TotalPriceCents: 1000, CreatedAt: day("2025-12-20")},
+		&revoked,
+		{ComponentID: a.ID, ChangeAmount: -20, TotalPriceCents: 283, CreatedAt: day("2026-01-10")},
+	}
+	for _, log := range logs {
+		mustCreate(t, db, log)
+	}
+	mustCreate(t, db, &models.StockLog{ComponentID: a.ID, ChangeAmount: -10, TotalPriceCents: 500, ReversalOfID: &revoked.ID, CreatedAt: revokedAt})
+
+	repo := NewStatsRepository(db)
+	valuationAt := func(at string, categoryIDs ...uint) map[string]ComponentValuation {
+		t.Helper()
+		items := map[string]ComponentValuation{}
+		err := repo.EachValuation(ValuationQuery{At: day(at), CategoryIDs: categoryIDs}, func(item *ComponentValuation) error {
+			items[item.Name] = *item
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("EachValuation(%s): %v", at, err)
+		}
+		return items
+	}
+
+	items := valuationAt("2025-12-15")
+	if got := items["A"]; got.Quantity != 70 || got.UnitPriceMicro != 100000 || got.ValueCents != 700 || got.PriceEstimated {
+		t.Errorf("A at 12-15 = %+v", got)
+	}
+	if got := items["B"]; got.Quantity != 5 || got.ValueCents != 1000 || !got.PriceEstimated {
+		t.Errorf("B at 12-15 = %+v", got)
+	}
+	if _, ok := items["Late"]; ok {
+		t.Errorf("component created later should be excluded")
+	}
+
+	// 12-25 的入库在一月被撤销，视为从未发生：(70×0.1 + 10) / 120
+	items = valuationAt("2025-12-31")
+	if got := items["A"]; got.Quantity != 120 || got.UnitPriceMicro != 141666 || got.ValueCents != 1700 {
+		t.Errorf("A at 12-31 = %+v", got)
+	}
+	if items := valuationAt("2025-12-31", module.ID); len(items) != 1 || items["B"].Quantity != 5 {
+		t.Errorf("category filter = %+v", items)
+	}
+
+	valuation, err := repo.GetValuation(ValuationQuery{At: day("2025-12-31")})
+	if err != nil {
+		t.Fatalf("GetValuation: %v", err)
+	}
+	if valuation.Totals != (ValuationTotals{ComponentCount: 2, Quantity: 125, ValueCents: 2700}) {
+		t.Errorf("totals = %+v", valuation.Totals)
+	}
+	if len(valuation.Categories) != 2 || valuation.Categories[0].CategoryName != "电阻" || valuation.Categories[0].ValueCents != 1700 {
+		t.Errorf("categories = %+v", valuation.Categories)
+	}
+
+	history, err := repo.GetComponentStockHistory(a.ID, StatsSeriesQuery{
+		From:   day("2025-11-01").Add(-12 * time.Hour),
+		To:     day("2026-02-01").Add(-12 * time.Hour),
+		Bucket: StatsBucketMonth,
+	})
+	if err != nil {
+		t.Fatalf("GetComponentStockHistory: %v", err)
+	}
+	var quantities []int
+	var values []int64
+	for _, point := range history {
+		quantities = append(quantities, point.Quantity)
+		values = append(values, point.ValueCents)
+	}
+	if len(history) != 3 || quantities[0] != 0 || quantities[1] != 120 || quantities[2] != 100 || values[1] != 1700 || values[2] != 1417 {
+		t.Errorf("history quantities = %v, values = %v", quantities, values)
+	}
+	if _, err := repo.ForWorkspace(2).GetComponentStockHistory(a.ID, StatsSeriesQuery{From: day("2025-12-01"), To: day("2025-12-02"), Bucket: StatsBucketDay}); err == nil {
+		t.Errorf("other workspace should not find component")
+	}
+}
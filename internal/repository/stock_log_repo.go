@@ -1,8 +1,11 @@
 package repository
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Rehtt/hamster-bin/internal/models"
@@ -13,60 +16,303 @@ import (
 var (
 	ErrAlreadyRevoked       = errors.New("记录已撤销")
 	ErrCannotRevokeReversal = errors.New("冲销记录不可撤销")
+	ErrCannotRevokeMerge    = errors.New("合并记录不可撤销")
 )
 
 type StockLogRepository struct {
-	db *gorm.DB
+	db          *gorm.DB
+	workspaceID uint
 }
 
 func NewStockLogRepository(db *gorm.DB) *StockLogRepository {
-	return &StockLogRepository{db: db}
+	return &StockLogRepository{db: db, workspaceID: DefaultWorkspaceID}
+}
+
+// ForWorkspace 返回限定在指定工作区内的仓储
+func (r *StockLogRepository) ForWorkspace(workspaceID uint) *StockLogRepository {
+	return &StockLogRepository{db: r.db, workspaceID: workspaceID}
+}
+
+func (r *StockLogRepository) scoped() *gorm.DB {
+	return inWorkspace(r.db, "stock_logs", r.workspaceID)
 }
 
 // Create 创建库存记录
 func (r *StockLogRepository) Create(log *models.StockLog) error {
+	log.WorkspaceID = r.workspaceID
+	if err := ensureInWorkspace(r.db, &models.Component{}, log.ComponentID, r.workspaceID); err != nil {
+		return err
+	}
 	return r.db.Create(log).Error
 }
 
-// GetByComponentID 获取指定元件的库存记录
-func (r *StockLogRepository) GetByComponentID(componentID uint, limit int) ([]models.StockLog, error) {
+// GetByComponentID 获取指定元件的库存记录（含变动后结存）
+func (r *StockLogRepository) GetByComponentID(componentID uint, limit int) ([]StockLogEntry, error) {
 	var logs []models.StockLog
-	query := r.db.Where("component_id = ?", componentID).
-		Order("created_at DESC")
+	query := r.scoped().Where("component_id = ?", componentID).
+		Order("created_at DESC").Order("id DESC")
 
 	if limit > 0 {
 		query = query.Limit(limit)
 	}
 
-	err := query.Find(&logs).Error
-	return logs, err
+	if err := query.Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return r.withBalances(logs)
+}
+
+// 库存记录方向筛选：入库（变动为正）、出库（变动为负）、调整（变动为 0，如补录价格与合并记录）
+const (
+	StockLogDirectionIn     = "in"
+	StockLogDirectionOut    = "out"
+	StockLogDirectionAdjust = "adjust"
+)
+
+// 库存记录状态筛选：正常（未撤销的普通记录）、已撤销、撤销冲销流水、合并记录
+const (
+	StockLogStatusNormal   = "normal"
+	StockLogStatusRevoked  = "revoked"
+	StockLogStatusReversal = "reversal"
+	StockLogStatusMerged   = "merged"
+)
+
+var stockLogStatusConditions = map[string]string{
+	StockLogStatusNormal:   "(stock_logs.revoked_at IS NULL AND stock_logs.reversal_of_id IS NULL AND stock_logs.merged_from_id IS NULL)",
+	StockLogStatusRevoked:  "stock_logs.revoked_at IS NOT NULL",
+	StockLogStatusReversal: "stock_logs.reversal_of_id IS NOT NULL",
+	StockLogStatusMerged:   "stock_logs.merged_from_id IS NOT NULL",
+}
+
+// IsStockLogDirection 判断是否为支持的方向筛选值
+func IsStockLogDirection(direction string) bool {
+	return direction == StockLogDirectionIn || direction == StockLogDirectionOut || direction == StockLogDirectionAdjust
+}
+
+// IsStockLogStatus 判断是否为支持的状态筛选值
+func IsStockLogStatus(status string) bool {
+	_, ok := stockLogStatusConditions[status]
+	return ok
 }
 
-// GetAll 获取所有库存记录（分页）
-func (r *StockLogRepository) GetAll(page, pageSize int) ([]models.StockLog, int64, error) {
+var ErrInvalidStockLogCursor = errors.New("无效的分页游标")
+
+// StockLogCursor 游标分页位置：按 (created_at, id) 倒序排列时上一页最后一条记录
+type StockLogCursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// Encode 编码为不透明的游标字符串
+func (c StockLogCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.CreatedAt.Format(time.RFC3339Nano) + "|" + strconv.FormatUint(uint64(c.ID), 10)))
+}
+
+// ParseStockLogCursor 解析 Encode 生成的游标
+func ParseStockLogCursor(raw string) (StockLogCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return StockLogCursor{}, ErrInvalidStockLogCursor
+	}
+	createdAt, id, ok := strings.Cut(string(decoded), "|")
+	if !ok {
+		return StockLogCursor{}, ErrInvalidStockLogCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return StockLogCursor{}, ErrInvalidStockLogCursor
+	}
+	n, err := strconv.ParseUint(id, 10, 32)
+	if err != nil || n == 0 {
+		return StockLogCursor{}, ErrInvalidStockLogCursor
+	}
+	return StockLogCursor{CreatedAt: t, ID: uint(n)}, nil
+}
+
+// StockLogQuery 库存记录查询参数；时间范围为 [From, To)，零值表示不限
+type StockLogQuery struct {
+	Operator    string
+	ComponentID uint
+	// CategoryID 按元件分类筛选，包含全部子孙分类
+	CategoryID *uint
+	From       time.Time
+	To         time.Time
+	Direction  string
+	// Reason 原因包含匹配（不区分大小写取决于数据库排序规则）
+	Reason string
+	// Statuses 多个状态之间为或
+	Statuses []string
+	// MinAmount / MaxAmount 变动数量绝对值的闭区间
+	MinAmount *int
+	MaxAmount *int
+	Page      int
+	PageSize  int
+	// Cursor 非 nil 时按游标分页（忽略 Page），从该位置之后取 PageSize 条，零值表示从最新一条开始；
+	// 新写入的记录排在最前，不会导致后续页重复或遗漏
+	Cursor *StockLogCursor
+}
+
+// StockLogEntry 库存记录及该元件在此次变动后的结存；元件已删除时结存为 nil
+type StockLogEntry struct {
+	models.StockLog
+	BalanceAfter *int `json:"balance_after"`
+}
+
+// StockLogList 库存记录查询结果；NextCursor 为空表示没有更多记录（仅游标分页时返回）
+type StockLogList struct {
+	Items      []StockLogEntry
+	Total      int64
+	NextCursor string
+}
+
+// GetAll 按条件查询库存记录，按时间倒序（同一时间按 ID 倒序），支持页码或游标分页
+func (r *StockLogRepository) GetAll(query StockLogQuery) (StockLogList, error) {
+	db, err := r.filtered(query)
+	if err != nil {
+		return StockLogList{}, err
+	}
+
+	var list StockLogList
+	if err := db.Session(&gorm.Session{}).Count(&list.Total).Error; err != nil {
+		return StockLogList{}, err
+	}
+
+	switch {
+	case query.Cursor != nil:
+		if query.Cursor.ID > 0 {
+			db = db.Where("stock_logs.created_at < ? OR (stock_logs.created_at = ? AND stock_logs.id < ?)",
+				query.Cursor.CreatedAt, query.Cursor.CreatedAt, query.Cursor.ID)
+		}
+		if query.PageSize > 0 {
+			db = db.Limit(query.PageSize + 1)
+		}
+	case query.Page > 0 && query.PageSize > 0:
+		db = db.Offset((query.Page - 1) * query.PageSize).Limit(query.PageSize)
+	}
+
 	var logs []models.StockLog
-	var total int64
+	if err := db.Preload("Component").Order("stock_logs.created_at DESC").Order("stock_logs.id DESC").Find(&logs).Error; err != nil {
+		return StockLogList{}, err
+	}
+	if query.Cursor != nil && query.PageSize > 0 && len(logs) > query.PageSize {
+		logs = logs[:query.PageSize]
+		last := logs[len(logs)-1]
+		list.NextCursor = StockLogCursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
 
-	db := r.db.Model(&models.StockLog{}).Preload("Component")
+	if list.Items, err = r.withBalances(logs); err != nil {
+		return StockLogList{}, err
+	}
+	return list, nil
+}
 
-	db.Count(&total)
+func (r *StockLogRepository) filtered(query StockLogQuery) (*gorm.DB, error) {
+	db := r.scoped().Model(&models.StockLog{})
+	if query.Operator != "" {
+		db = db.Where("stock_logs.operator = ?", query.Operator)
+	}
+	if query.ComponentID > 0 {
+		db = db.Where("stock_logs.component_id = ?", query.ComponentID)
+	}
+	if query.CategoryID != nil {
+		ids, err := NewCategoryRepository(r.db).ForWorkspace(r.workspaceID).DescendantIDs(*query.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("stock_logs.component_id IN (?)", r.db.Model(&models.Component{}).Select("id").Where("category_id IN ?", ids))
+	}
+	db = applyTimeRange(db, "stock_logs.created_at", query.From, query.To)
+	switch query.Direction {
+	case StockLogDirectionIn:
+		db = db.Where("stock_logs.change_amount > 0")
+	case StockLogDirectionOut:
+		db = db.Where("stock_logs.change_amount < 0")
+	case StockLogDirectionAdjust:
+		db = db.Where("stock_logs.change_amount = 0")
+	}
+	if reason := strings.TrimSpace(query.Reason); reason != "" {
+		db = db.Where("stock_logs.reason LIKE ? ESCAPE '!'", "%"+likeEscaper.Replace(reason)+"%")
+	}
+	if len(query.Statuses) > 0 {
+		conditions := make([]string, 0, len(query.Statuses))
+		for _, status := range query.Statuses {
+			if condition, ok := stockLogStatusConditions[status]; ok {
+				conditions = append(conditions, condition)
+			}
+		}
+		if len(conditions) > 0 {
+			db = db.Where(strings.Join(conditions, " OR "))
+		}
+	}
+	if query.MinAmount != nil {
+		db = db.Where("ABS(stock_logs.change_amount) >= ?", *query.MinAmount)
+	}
+	if query.MaxAmount != nil {
+		db = db.Where("ABS(stock_logs.change_amount) <= ?", *query.MaxAmount)
+	}
+	return db, nil
+}
 
-	if page > 0 && pageSize > 0 {
-		offset := (page - 1) * pageSize
-		db = db.Offset(offset).Limit(pageSize)
+// withBalances 计算每条记录变动后的结存：元件当前库存减去该元件在此记录之后（按 created_at、id 排序）的全部变动。
+// 以当前库存为准倒推，最新一条的结存总是等于当前库存
+func (r *StockLogRepository) withBalances(logs []models.StockLog) ([]StockLogEntry, error) {
+	entries := make([]StockLogEntry, len(logs))
+	if len(logs) == 0 {
+		return entries, nil
+	}
+	ids := make([]uint, len(logs))
+	for i, log := range logs {
+		ids[i] = log.ID
+	}
+
+	var rows []struct {
+		ID      uint
+		Balance int
+	}
+	err := r.db.Table("stock_logs").
+		Select(`stock_logs.id AS id, components.stock_quantity - COALESCE((
+			SELECT SUM(later.change_amount) FROM stock_logs later
+			WHERE later.component_id = stock_logs.component_id
+			AND (later.created_at > stock_logs.created_at OR (later.created_at = stock_logs.created_at AND later.id > stock_logs.id))
+		), 0) AS balance`).
+		Joins("JOIN components ON components.id = stock_logs.component_id").
+		Where("stock_logs.id IN ?", ids).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	balances := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		balances[row.ID] = row.Balance
 	}
 
-	err := db.Order("created_at DESC").Find(&logs).Error
-	return logs, total, err
+	for i, log := range logs {
+		entries[i].StockLog = log
+		if balance, ok := balances[log.ID]; ok {
+			entries[i].BalanceAfter = &balance
+		}
+	}
+	return entries, nil
 }
 
-// RevokeStockLog 撤销库存记录：标记原记录并写入反向冲销流水
-func (r *StockLogRepository) RevokeStockLog(id uint) (*models.StockLog, *models.StockLog, error) {
+// GetDistinctOperators 获取出现过的操作人列表（去重、非空、按名称排序）
+func (r *StockLogRepository) GetDistinctOperators() ([]string, error) {
+	operators := []string{}
+	err := r.scoped().Model(&models.StockLog{}).
+		Where("operator <> ''").
+		Distinct("operator").
+		Order("operator ASC").
+		Pluck("operator", &operators).Error
+	return operators, err
+}
+
+// RevokeStockLog 撤销库存记录：标记原记录并写入反向冲销流水，冲销流水记录撤销操作人
+func (r *StockLogRepository) RevokeStockLog(id uint, operator string) (*models.StockLog, *models.StockLog, error) {
 	var original models.StockLog
 	var reversal models.StockLog
 
 	err := r.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.First(&original, id).Error; err != nil {
+		if err := inWorkspace(tx, "stock_logs", r.workspaceID).First(&original, id).Error; err != nil {
 			return err
 		}
 		if original.RevokedAt != nil {
@@ -75,6 +321,9 @@ func (r *StockLogRepository) RevokeStockLog(id uint) (*models.StockLog, *models.
 		if original.ReversalOfID != nil {
 			return ErrCannotRevokeReversal
 		}
+		if original.MergedFromID != nil {
+			return ErrCannotRevokeMerge
+		}
 
 		var component models.Component
 		if err := tx.First(&component, original.ComponentID).Error; err != nil {
@@ -114,11 +363,13 @@ func (r *StockLogRepository) RevokeStockLog(id uint) (*models.StockLog, *models.
 		}
 
 		reversal = models.StockLog{
+			WorkspaceID:     original.WorkspaceID,
 			ComponentID:     original.ComponentID,
 			ChangeAmount:    reverseAmount,
 			UnitPriceMicro:  original.UnitPriceMicro,
 			TotalPriceCents: original.TotalPriceCents,
 			Reason:          reason,
+			Operator:        operator,
 			ReversalOfID:    &original.ID,
 		}
 		if err := tx.Create(&reversal).Error; err != nil {
@@ -132,3 +383,22 @@ func (r *StockLogRepository) RevokeStockLog(id uint) (*models.StockLog, *models.
 	}
 	return &original, &reversal, nil
 }
+
+// StockLogExportRow 导出用的库存记录，附带元件编号与名称（元件已被合并删除时为空）
+type StockLogExportRow struct {
+	models.StockLog
+	ComponentNumber string
+	ComponentName   string
+}
+
+// Each 按时间先后逐行遍历符合条件的库存记录（忽略分页），用于流式导出
+func (r *StockLogRepository) Each(query StockLogQuery, fn func(*StockLogExportRow) error) error {
+	db, err := r.filtered(query)
+	if err != nil {
+		return err
+	}
+	db = db.Select("stock_logs.*, COALESCE(components.component_number, '') AS component_number, COALESCE(components.name, '') AS component_name").
+		Joins("LEFT JOIN components ON components.id = stock_logs.component_id")
+
+	return eachRow(db.Order("stock_logs.created_at ASC, stock_logs.id ASC"), fn)
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+)
+
+func TestStockLogRepositoryOperatorFilterAndRevoke(t *testing.T) {
+	db := setupStatsTestDB(t)
+	category := models.Category{Name: "电容"}
+	if err := db.Create(&category).Error; err != nil {
+		t.Fatalf("create category: %v", err)
+	}
+	component := models.Component{CategoryID: category.ID, Name: "电容A", StockQuantity: 10}
+	if err := db.Create(&component).Error; err != nil {
+		t.Fatalf("create component: %v", err)
+	}
+	logs := []models.StockLog{
+		{ComponentID: component.ID, ChangeAmount: 5, Reason: "入库", Operator: "alice"},
+		{ComponentID: component.ID, ChangeAmount: -1, Reason: "出库", Operator: "bob"},
+	}
+	if err := db.Create(&logs).Error; err != nil {
+		t.Fatalf("create logs: %v", err)
+	}
+
+	repo := NewStockLogRepository(db)
+	filtered, err := repo.GetAll(StockLogQuery{Operator: "alice", Page: 1, PageSize: 20})
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if filtered.Total != 1 || len(filtered.Items) != 1 || filtered.Items[0].ID != logs[0].ID {
+		t.Fatalf("GetAll(operator=alice) = %d/%+v, want only log %d", filtered.Total, filtered.Items, logs[0].ID)
+	}
+
+	_, reversal, err := repo.RevokeStockLog(logs[0].ID, "bob")
+	if err != nil {
+		t.Fatalf("RevokeStockLog: %v", err)
+	}
+	if reversal.Operator != "bob" {
+		t.Fatalf("reversal operator = %q, want bob", reversal.Operator)
+	}
+
+	operators, err := repo.GetDistinctOperators()
+	if err != nil {
+		t.Fatalf("GetDistinctOperators: %v", err)
+	}
+	if len(operators) != 2 || operators[0] != "alice" || operators[1] != "bob" {
+		t.Fatalf("operators = %v, want [alice bob]", operators)
+	}
+}
+
+func TestStockLogQueryFiltersCursorAndBalance(t *testing.T) {
+	db := setupStatsTestDB(t)
+	resistor := models.Category{Name: "电阻"}
+	mustCreate(t, db, &resistor)
+	smd := models.Category{Name: "贴片电阻", ParentID: &resistor.ID}
+	mustCreate(t, db, &smd)
+	capacitor := models.Category{Name: "电容"}
+	mustCreate(t, db, &capacitor)
+	r1 := models.Component{CategoryID: smd.ID, Name: "R1", StockQuantity: 12}
+	mustCreate(t, db, &r1)
+	c1 := models.Component{CategoryID: capacitor.ID, Name: "C1", StockQuantity: 3}
+	mustCreate(t, db, &c1)
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+	logs := []models.StockLog{
+		{ComponentID: r1.ID, ChangeAmount: 10, Reason: "采购入库", CreatedAt: base.Add(-4 * time.Hour)},
+		{ComponentID: r1.ID, ChangeAmount: -3, Reason: "项目A", CreatedAt: base.Add(-3 * time.Hour)},
+		{ComponentID: c1.ID, ChangeAmount: 5, Reason: "采购", CreatedAt: base.Add(-3 * time.Hour)},
+		{ComponentID: r1.ID, ChangeAmount: 0, Reason: "补录价格", CreatedAt: base.Add(-2 * time.Hour)},
+		{ComponentID: r1.ID, ChangeAmount: 5, Reason: "盘盈", CreatedAt: base.Add(-time.Hour)},
+		{ComponentID: c1.ID, ChangeAmount: -2, Reason: "项目A 调试", CreatedAt: base},
+	}
+	for i := range logs {
+		mustCreate(t, db, &logs[i])
+	}
+	repo := NewStockLogRepository(db)
+	ids := func(query StockLogQuery) []int {
+		t.Helper()
+		list, err := repo.GetAll(query)
+		if err != nil {
+			t.Fatalf("GetAll(%+v): %v", query, err)
+		}
+		var got []int
+		for _, item := range list.Items {
+			got = append(got, slices.IndexFunc(logs, func(log models.StockLog) bool { return log.ID == item.ID })+1)
+		}
+		return got
+	}
+
+	all, err := repo.GetAll(StockLogQuery{})
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	wantBalances := map[uint]int{logs[0].ID: 10, logs[1].ID: 7, logs[2].ID: 5, logs[3].ID: 7, logs[4].ID: 12, logs[5].ID: 3}
+	for _, item := range all.Items {
+		if item.BalanceAfter == nil || *item.BalanceAfter != wantBalances[item.ID] {
+			t.Errorf("log %d balance = %v, want %d", item.ID, item.BalanceAfter, wantBalances[item.ID])
+		}
+	}
+
+	minAmount, maxAmount := 3, 5
+	cases := []struct {
+		name  string
+		query StockLogQuery
+		want  []int
+	}{
+		{"category with descendants", StockLogQuery{CategoryID: &resistor.ID}, []int{5, 4, 2, 1}},
+		{"direction in", StockLogQuery{Direction: StockLogDirectionIn}, []int{5, 3, 1}},
+		{"direction out", StockLogQuery{Direction: StockLogDirectionOut}, []int{6, 2}},
+		{"direction adjust", StockLogQuery{Direction: StockLogDirectionAdjust}, []int{4}},
+		{"reason", StockLogQuery{Reason: "项目A"}, []int{6, 2}},
+		{"amount range", StockLogQuery{MinAmount: &minAmount, MaxAmount: &maxAmount}, []int{5, 3, 2}},
+		{"time range", StockLogQuery{From: base.Add(-3 * time.Hour), To: base}, []int{5, 4, 3, 2}},
+		{"component", StockLogQuery{ComponentID: c1.ID}, []int{6, 3}},
+	}
+	for _, tc := range cases {
+		if got := ids(tc.query); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	// 游标分页：翻页期间写入的新记录不影响后续页
+	var pages [][]int
+	cursor := &StockLogCursor{}
+	for cursor != nil {
+		list, err := repo.GetAll(StockLogQuery{PageSize: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("GetAll cursor: %v", err)
+		}
+		if len(pages) == 0 {
+			mustCreate(t, db, &models.StockLog{ComponentID: r1.ID, ChangeAmount: 1, CreatedAt: base.Add(time.Hour)})
+		}
+		var page []int
+		for _, item := range list.Items {
+			page = append(page, slices.IndexFunc(logs, func(log models.StockLog) bool { return log.ID == item.ID })+1)
+		}
+		pages = append(pages, page)
+		cursor = nil
+		if list.NextCursor != "" {
+			next, err := ParseStockLogCursor(list.NextCursor)
+			if err != nil {
+				t.Fatalf("ParseStockLogCursor: %v", err)
+			}
+			cursor = &next
+		}
+	}
+	if fmt.Sprint(pages) != "[[6 5] [4 3] [2 1]]" {
+		t.Fatalf("cursor pages = %v", pages)
+	}
+	if _, err := ParseStockLogCursor("bm90LWEtY3Vyc29y"); !errors.Is(err, ErrInvalidStockLogCursor) {
+		t.Fatalf("invalid cursor err = %v", err)
+	}
+
+	if _, _, err := repo.RevokeStockLog(logs[4].ID, ""); err != nil {
+		t.Fatalf("RevokeStockLog: %v", err)
+	}
+	for status, want := range map[string]int{StockLogStatusRevoked: 1, StockLogStatusReversal: 1, StockLogStatusNormal: 6} {
+		list, err := repo.GetAll(StockLogQuery{Statuses: []string{status}})
+		if err != nil || list.Total != int64(want) {
+			t.Errorf("status %s total = %d, %v, want %d", status, list.Total, err, want)
+		}
+	}
+}
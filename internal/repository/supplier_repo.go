@@ -1,55 +1,284 @@
 package repository
 
 import (
+	"errors"
+	"net/url"
 	"strings"
 
 	"github.com/Rehtt/hamster-bin/internal/models"
 	"gorm.io/gorm"
 )
 
+// ProductURLPlaceholder 商品链接模板中代表供应商料号的占位符
+const ProductURLPlaceholder = "{sku}"
+
+var (
+	ErrSupplierNameDuplicate     = errors.New("供应商名称已存在")
+	ErrSupplierInUse             = errors.New("供应商仍被元件或预入库记录引用，请指定转移供应商")
+	ErrInvalidSupplierReassign   = errors.New("转移供应商不能是被删除的供应商本身")
+	ErrInvalidSupplierMerge      = errors.New("合并目标不能同时是被合并的供应商")
+	ErrInvalidProductURLTemplate = errors.New("商品链接模板需以 http:// 或 https:// 开头，且包含一个 {sku} 占位符")
+)
+
 type SupplierRepository struct {
-	db *gorm.DB
+	db          *gorm.DB
+	workspaceID uint
 }
 
 func NewSupplierRepository(db *gorm.DB) *SupplierRepository {
-	return &SupplierRepository{db: db}
+	return &SupplierRepository{db: db, workspaceID: DefaultWorkspaceID}
+}
+
+// ForWorkspace 返回限定在指定工作区内的仓储
+func (r *SupplierRepository) ForWorkspace(workspaceID uint) *SupplierRepository {
+	return &SupplierRepository{db: r.db, workspaceID: workspaceID}
+}
+
+func (r *SupplierRepository) scoped() *gorm.DB {
+	return inWorkspace(r.db, "suppliers", r.workspaceID)
+}
+
+// ValidateProductURLTemplate 校验商品链接模板；空模板视为未配置
+func ValidateProductURLTemplate(template string) error {
+	template = strings.TrimSpace(template)
+	if template == "" {
+		return nil
+	}
+	if strings.Count(template, ProductURLPlaceholder) != 1 {
+		return ErrInvalidProductURLTemplate
+	}
+	parsed, err := url.Parse(strings.Replace(template, ProductURLPlaceholder, "sku", 1))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return ErrInvalidProductURLTemplate
+	}
+	return nil
+}
+
+// MatchProductURL 按商品链接模板反向提取供应商料号。比较时忽略协议差异，
+// 模板本身不含 query 或 fragment 时也忽略链接中的 query 与 fragment（常见的推广追踪参数）。
+func MatchProductURL(template, rawURL string) (string, bool) {
+	template = strings.TrimSpace(template)
+	rawURL = strings.TrimSpace(rawURL)
+	if ValidateProductURLTemplate(template) != nil || template == "" {
+		return "", false
+	}
+
+	template = stripURLScheme(template)
+	rawURL = stripURLScheme(rawURL)
+	if !strings.ContainsAny(template, "?#") {
+		if i := strings.IndexAny(rawURL, "?#"); i >= 0 {
+			rawURL = rawURL[:i]
+		}
+	}
+
+	prefix, suffix, _ := strings.Cut(template, ProductURLPlaceholder)
+	if len(rawURL) <= len(prefix)+len(suffix) ||
+		!strings.EqualFold(rawURL[:len(prefix)], prefix) ||
+		!strings.HasSuffix(rawURL, suffix) {
+		return "", false
+	}
+
+	sku := rawURL[len(prefix) : len(rawURL)-len(suffix)]
+	if strings.ContainsAny(sku, "/?#") {
+		return "", false
+	}
+	if unescaped, err := url.PathUnescape(sku); err == nil {
+		sku = unescaped
+	}
+	sku = strings.TrimSpace(sku)
+	return sku, sku != ""
+}
+
+func stripURLScheme(value string) string {
+	if i := strings.Index(value, "://"); i >= 0 {
+		return value[i+3:]
+	}
+	return value
+}
+
+func normalizeSupplier(supplier *models.Supplier) error {
+	supplier.Name = strings.TrimSpace(supplier.Name)
+	supplier.ContactName = strings.TrimSpace(supplier.ContactName)
+	supplier.Phone = strings.TrimSpace(supplier.Phone)
+	supplier.Email = strings.TrimSpace(supplier.Email)
+	supplier.Website = strings.TrimSpace(supplier.Website)
+	supplier.ProductURLTemplate = strings.TrimSpace(supplier.ProductURLTemplate)
+	return ValidateProductURLTemplate(supplier.ProductURLTemplate)
 }
 
 // GetAll 获取所有供应商
 func (r *SupplierRepository) GetAll() ([]models.Supplier, error) {
 	var suppliers []models.Supplier
-	err := r.db.Order("name ASC").Find(&suppliers).Error
+	err := r.scoped().Order("name ASC").Find(&suppliers).Error
 	return suppliers, err
 }
 
 // GetByID 根据ID获取供应商
 func (r *SupplierRepository) GetByID(id uint) (*models.Supplier, error) {
 	var supplier models.Supplier
-	err := r.db.First(&supplier, id).Error
+	err := r.scoped().First(&supplier, id).Error
 	return &supplier, err
 }
 
 // FindByName 根据名称获取供应商
 func (r *SupplierRepository) FindByName(name string) (*models.Supplier, error) {
 	var supplier models.Supplier
-	err := r.db.Where("name = ?", strings.TrimSpace(name)).First(&supplier).Error
+	err := r.scoped().Where("name = ?", strings.TrimSpace(name)).First(&supplier).Error
 	return &supplier, err
 }
 
 // FirstOrCreateByName 按名称获取或创建供应商
 func (r *SupplierRepository) FirstOrCreateByName(name string) (*models.Supplier, error) {
-	supplier := models.Supplier{Name: strings.TrimSpace(name)}
-	err := r.db.Where("name = ?", supplier.Name).FirstOrCreate(&supplier).Error
-	return &supplier, err
+	return r.FirstOrCreate(&models.Supplier{Name: name})
+}
+
+// FirstOrCreate 按名称获取供应商，不存在时用传入的联系方式等字段创建；已存在时原样返回已有记录
+func (r *SupplierRepository) FirstOrCreate(supplier *models.Supplier) (*models.Supplier, error) {
+	if err := normalizeSupplier(supplier); err != nil {
+		return nil, err
+	}
+	supplier.ID = 0
+	supplier.WorkspaceID = r.workspaceID
+	saved := models.Supplier{WorkspaceID: r.workspaceID, Name: supplier.Name}
+	err := r.db.Where("workspace_id = ? AND name = ?", saved.WorkspaceID, saved.Name).
+		Attrs(*supplier).
+		FirstOrCreate(&saved).Error
+	return &saved, err
+}
+
+// FindByProductURL 在当前工作区内查找商品链接模板能匹配 rawURL 的供应商，返回供应商与提取出的料号
+func (r *SupplierRepository) FindByProductURL(rawURL string) (*models.Supplier, string, error) {
+	var suppliers []models.Supplier
+	if err := r.scoped().Where("product_url_template <> ''").Order("id ASC").Find(&suppliers).Error; err != nil {
+		return nil, "", err
+	}
+	for i := range suppliers {
+		if sku, ok := MatchProductURL(suppliers[i].ProductURLTemplate, rawURL); ok {
+			return &suppliers[i], sku, nil
+		}
+	}
+	return nil, "", gorm.ErrRecordNotFound
 }
 
-// Update 更新供应商
+// Update 更新供应商；名称在工作区内不可与其他供应商重复
 func (r *SupplierRepository) Update(supplier *models.Supplier) error {
-	supplier.Name = strings.TrimSpace(supplier.Name)
+	if err := normalizeSupplier(supplier); err != nil {
+		return err
+	}
+	supplier.WorkspaceID = r.workspaceID
+
+	var count int64
+	if err := r.scoped().Model(&models.Supplier{}).
+		Where("name = ? AND id <> ?", supplier.Name, supplier.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrSupplierNameDuplicate
+	}
 	return r.db.Save(supplier).Error
 }
 
-// Delete 删除供应商
-func (r *SupplierRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Supplier{}, id).Error
+// reassignInTx 把元件与预入库中引用 fromIDs 的供应商改为 toID
+func (r *SupplierRepository) reassignInTx(tx *gorm.DB, fromIDs []uint, toID uint) error {
+	for _, model := range []any{&models.Component{}, &models.PreStock{}} {
+		if err := tx.Model(model).Where("workspace_id = ? AND supplier_id IN ?", r.workspaceID, fromIDs).
+			Update("supplier_id", toID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete 删除供应商。仍被元件或预入库引用时，reassignTo 为空则拒绝删除，否则先把引用转移到 reassignTo
+func (r *SupplierRepository) Delete(id uint, reassignTo *uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := inWorkspace(tx, "suppliers", r.workspaceID).First(&models.Supplier{}, id).Error; err != nil {
+			return err
+		}
+
+		if reassignTo != nil {
+			if *reassignTo == id {
+				return ErrInvalidSupplierReassign
+			}
+			if err := ensureInWorkspace(tx, &models.Supplier{}, *reassignTo, r.workspaceID); err != nil {
+				return err
+			}
+			if err := r.reassignInTx(tx, []uint{id}, *reassignTo); err != nil {
+				return err
+			}
+		} else {
+			for _, model := range []any{&models.Component{}, &models.PreStock{}} {
+				var count int64
+				if err := tx.Model(model).Where("workspace_id = ? AND supplier_id = ?", r.workspaceID, id).
+					Count(&count).Error; err != nil {
+					return err
+				}
+				if count > 0 {
+					return ErrSupplierInUse
+				}
+			}
+		}
+
+		return inWorkspace(tx, "suppliers", r.workspaceID).Delete(&models.Supplier{}, id).Error
+	})
+}
+
+// Merge 将 sourceIDs 合并到 targetID：转移全部引用后删除来源供应商。
+// 目标供应商的联系人、电话、邮箱、官网、链接模板为空时，依次用来源供应商的值补全；备注按行追加。
+func (r *SupplierRepository) Merge(sourceIDs []uint, targetID uint) (*models.Supplier, error) {
+	var target models.Supplier
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range sourceIDs {
+			if id == targetID {
+				return ErrInvalidSupplierMerge
+			}
+		}
+		if err := inWorkspace(tx, "suppliers", r.workspaceID).First(&target, targetID).Error; err != nil {
+			return err
+		}
+
+		var sources []models.Supplier
+		if err := inWorkspace(tx, "suppliers", r.workspaceID).
+			Where("id IN ?", sourceIDs).
+			Order("id ASC").
+			Find(&sources).Error; err != nil {
+			return err
+		}
+		if len(sources) != len(sourceIDs) {
+			return gorm.ErrRecordNotFound
+		}
+
+		for _, source := range sources {
+			fillEmpty(&target.ContactName, source.ContactName)
+			fillEmpty(&target.Phone, source.Phone)
+			fillEmpty(&target.Email, source.Email)
+			fillEmpty(&target.Website, source.Website)
+			fillEmpty(&target.ProductURLTemplate, source.ProductURLTemplate)
+			if notes := strings.TrimSpace(source.Notes); notes != "" && !strings.Contains(target.Notes, notes) {
+				if target.Notes != "" {
+					target.Notes += "\n"
+				}
+				target.Notes += notes
+			}
+		}
+
+		if err := r.reassignInTx(tx, sourceIDs, targetID); err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Supplier{}, sourceIDs).Error; err != nil {
+			return err
+		}
+		return tx.Save(&target).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &target, nil
+}
+
+func fillEmpty(dst *string, value string) {
+	if strings.TrimSpace(*dst) == "" {
+		*dst = value
+	}
 }
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSupplierTestDB(t *testing.T) (*gorm.DB, models.Category) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Category{}, &models.Supplier{}, &models.Component{}, &models.ComponentTag{}, &models.PreStock{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	category := models.Category{Name: "电阻"}
+	if err := db.Create(&category).Error; err != nil {
+		t.Fatalf("create category: %v", err)
+	}
+	return db, category
+}
+
+func TestProductURLTemplate(t *testing.T) {
+	const template = "https://item.szlcsc.com/{sku}.html"
+
+	tests := []struct {
+		url    string
+		want   string
+		wantOK bool
+	}{
+		{"https://item.szlcsc.com/C2040.html", "C2040", true},
+		{"http://item.szlcsc.com/C2040.html?spm=sc.gb.xh#detail", "C2040", true},
+		{"https://item.szlcsc.com/a/C2040.html", "", false},
+		{"https://item.szlcsc.com/.html", "", false},
+		{"https://www.taobao.com/C2040.html", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := MatchProductURL(template, tt.url)
+		if got != tt.want || ok != tt.wantOK {
+			t.Fatalf("MatchProductURL(%q) = %q/%v, want %q/%v", tt.url, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	for _, invalid := range []string{"item.szlcsc.com/{sku}", "https://item.szlcsc.com/", "https://x.com/{sku}/{sku}"} {
+		if err := ValidateProductURLTemplate(invalid); !errors.Is(err, ErrInvalidProductURLTemplate) {
+			t.Fatalf("ValidateProductURLTemplate(%q) = %v, want ErrInvalidProductURLTemplate", invalid, err)
+		}
+	}
+}
+
+func TestSupplierUpdateDeleteAndMerge(t *testing.T) {
+	db, category := setupSupplierTestDB(t)
+	repo := NewSupplierRepository(db)
+
+	lcsc, err := repo.FirstOrCreate(&models.Supplier{Name: "立创", ProductURLTemplate: "https://item.szlcsc.com/{sku}.html"})
+	if err != nil {
+		t.Fatalf("create 立创: %v", err)
+	}
+	alias, err := repo.FirstOrCreate(&models.Supplier{Name: "LCSC", Phone: "0755-00000000", Notes: "海外站"})
+	if err != nil {
+		t.Fatalf("create LCSC: %v", err)
+	}
+	taobao, err := repo.FirstOrCreateByName("淘宝")
+	if err != nil {
+		t.Fatalf("create 淘宝: %v", err)
+	}
+
+	renamed := *alias
+	renamed.Name = "立创"
+	if err := repo.Update(&renamed); !errors.Is(err, ErrSupplierNameDuplicate) {
+		t.Fatalf("rename to existing error = %v, want ErrSupplierNameDuplicate", err)
+	}
+
+	if err := db.Create(&models.Component{CategoryID: category.ID, SupplierID: &alias.ID, Name: "10k"}).Error; err != nil {
+		t.Fatalf("create component: %v", err)
+	}
+	if err := db.Create(&models.PreStock{CategoryID: category.ID, SupplierID: &taobao.ID, Name: "1k", Status: PreStockStatusPending}).Error; err != nil {
+		t.Fatalf("create pre-stock: %v", err)
+	}
+
+	if err := repo.Delete(taobao.ID, nil); !errors.Is(err, ErrSupplierInUse) {
+		t.Fatalf("delete in use error = %v, want ErrSupplierInUse", err)
+	}
+	if err := repo.Delete(taobao.ID, &alias.ID); err != nil {
+		t.Fatalf("delete with reassign: %v", err)
+	}
+
+	if _, err := repo.Merge([]uint{lcsc.ID}, lcsc.ID); !errors.Is(err, ErrInvalidSupplierMerge) {
+		t.Fatalf("merge into self error = %v, want ErrInvalidSupplierMerge", err)
+	}
+	merged, err := repo.Merge([]uint{alias.ID}, lcsc.ID)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if merged.Phone != "0755-00000000" || merged.Notes != "海外站" {
+		t.Fatalf("merged = %+v, want empty fields filled from source", merged)
+	}
+
+	for _, model := range []any{&models.Component{}, &models.PreStock{}} {
+		var count int64
+		if err := db.Model(model).Where("supplier_id = ?", lcsc.ID).Count(&count).Error; err != nil {
+			t.Fatalf("count: %v", err)
+		}
+		if count != 1 {
+			t.Fatalf("%T referencing merged supplier = %d, want 1", model, count)
+		}
+	}
+	if _, err := repo.GetByID(alias.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("merged source lookup error = %v, want ErrRecordNotFound", err)
+	}
+
+	found, sku, err := repo.FindByProductURL("https://item.szlcsc.com/C2040.html")
+	if err != nil || found.ID != lcsc.ID || sku != "C2040" {
+		t.Fatalf("FindByProductURL = %+v/%q/%v, want 立创/C2040", found, sku, err)
+	}
+}
@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/auth"
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTwoFactorNotSetup      = errors.New("未初始化二次验证")
+	ErrTwoFactorAlreadyActive = errors.New("二次验证已启用")
+	ErrTwoFactorNotEnabled    = errors.New("二次验证未启用")
+	ErrTwoFactorInvalidCode   = errors.New("验证码无效")
+	ErrTwoFactorLocked        = errors.New("验证失败次数过多，请稍后再试")
+)
+
+const (
+	// TwoFactorMaxFailures 连续校验失败达到该次数后锁定（动态码与恢复码合计）
+	TwoFactorMaxFailures = 5
+	// TwoFactorLockDuration 首次锁定时长；之后未成功校验又失败满次数时，锁定时长逐次翻倍
+	TwoFactorLockDuration = 5 * time.Minute
+	// TwoFactorMaxLockDuration 锁定时长上限
+	TwoFactorMaxLockDuration = 24 * time.Hour
+)
+
+// twoFactorLocked 判断账号当前是否处于锁定期
+func twoFactorLocked(item *models.TwoFactorAuth, now time.Time) bool {
+	if item.LockedAt == nil || item.FailedAttempts < TwoFactorMaxFailures {
+		return false
+	}
+	duration := TwoFactorLockDuration
+	for i := 1; i < item.FailedAttempts/TwoFactorMaxFailures && duration < TwoFactorMaxLockDuration; i++ {
+		duration *= 2
+	}
+	return now.Before(item.LockedAt.Add(min(duration, TwoFactorMaxLockDuration)))
+}
+
+type TwoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// GetByUsername 获取账号的二次验证配置；未配置时返回 gorm.ErrRecordNotFound
+func (r *TwoFactorRepository) GetByUsername(username string) (*models.TwoFactorAuth, error) {
+	var item models.TwoFactorAuth
+	err := r.db.Where("username = ?", username).First(&item).Error
+	return &item, err
+}
+
+// IsEnabled 账号是否已启用二次验证
+func (r *TwoFactorRepository) IsEnabled(username string) (bool, error) {
+	item, err := r.GetByUsername(username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return item.Enabled, nil
+}
+
+// BeginSetup 生成新的待确认密钥；已启用时拒绝覆盖
+func (r *TwoFactorRepository) BeginSetup(username string) (*models.TwoFactorAuth, error) {
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	var item models.TwoFactorAuth
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("username = ?", username).First(&item).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			item = models.TwoFactorAuth{Username: username, Secret: secret}
+			return tx.Create(&item).Error
+		}
+		if err != nil {
+			return err
+		}
+		if item.Enabled {
+			return ErrTwoFactorAlreadyActive
+		}
+		item.Secret = secret
+		item.LastUsedStep = 0
+		return tx.Model(&item).Updates(map[string]any{
+			"secret":         secret,
+			"last_used_step": 0,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Enable 校验首个动态码后启用二次验证，并生成一组新的恢复码
+func (r *TwoFactorRepository) Enable(username, code string, now time.Time) ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		var item models.TwoFactorAuth
+		if err := tx.Where("username = ?", username).First(&item).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTwoFactorNotSetup
+			}
+			return err
+		}
+		if item.Enabled {
+			return ErrTwoFactorAlreadyActive
+		}
+		step, ok := auth.ValidateTOTP(item.Secret, code, now, item.LastUsedStep)
+		if !ok {
+			return ErrTwoFactorInvalidCode
+		}
+		if err := tx.Model(&item).Updates(map[string]any{
+			"enabled":        true,
+			"enabled_at":     now,
+			"last_used_step": step,
+		}).Error; err != nil {
+			return err
+		}
+		return replaceRecoveryCodesTx(tx, username, codes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// SessionTokenValid 判断登录 token 是否仍然有效：账号已启用或策略要求二次验证时，只接受通过二次验证后签发的 token，
+// 因此启用二次验证前签发的旧会话随之失效
+func (r *TwoFactorRepository) SessionTokenValid(username string, verified, required bool) (bool, error) {
+	if verified {
+		return true, nil
+	}
+	if required {
+		return false, nil
+	}
+	enabled, err := r.IsEnabled(username)
+	return !enabled, err
+}
+
+// PendingTokenValid 判断二次验证等待 token 是否仍然有效：锁定时作废此前签发的全部等待 token
+func (r *TwoFactorRepository) PendingTokenValid(username string, issuedAt time.Time) (bool, error) {
+	item, err := r.GetByUsername(username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return item.LockedAt == nil || issuedAt.After(*item.LockedAt), nil
+}
+
+// verify 在事务中校验动态码或恢复码，成功后执行 then；校验码错误时在事务外累计失败次数，
+// 失败次数达到上限时返回 ErrTwoFactorLocked
+func (r *TwoFactorRepository) verify(username, code, recoveryCode string, now time.Time, then func(tx *gorm.DB) error) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := verifyTx(tx, username, code, recoveryCode, now); err != nil {
+			return err
+		}
+		if then == nil {
+			return nil
+		}
+		return then(tx)
+	})
+	if !errors.Is(err, ErrTwoFactorInvalidCode) {
+		return err
+	}
+	locked, recordErr := r.recordFailure(username, now)
+	if recordErr != nil {
+		return recordErr
+	}
+	if locked {
+		return ErrTwoFactorLocked
+	}
+	return err
+}
+
+// recordFailure 累计一次校验失败，每满 TwoFactorMaxFailures 次记录锁定时间
+func (r *TwoFactorRepository) recordFailure(username string, now time.Time) (bool, error) {
+	locked := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.TwoFactorAuth{}).Where("username = ?", username).
+			UpdateColumn("failed_attempts", gorm.Expr("failed_attempts + 1")).Error; err != nil {
+			return err
+		}
+		var item models.TwoFactorAuth
+		if err := tx.Where("username = ?", username).First(&item).Error; err != nil {
+			return err
+		}
+		if item.FailedAttempts%TwoFactorMaxFailures != 0 {
+			return nil
+		}
+		locked = true
+		return tx.Model(&item).UpdateColumn("locked_at", now).Error
+	})
+	return locked, err
+}
+
+// Verify 校验动态码或恢复码（二选一）；恢复码校验成功后立即作废
+func (r *TwoFactorRepository) Verify(username, code, recoveryCode string, now time.Time) error {
+	return r.verify(username, code, recoveryCode, now, nil)
+}
+
+// Disable 校验通过后关闭二次验证并清除恢复码
+func (r *TwoFactorRepository) Disable(username, code, recoveryCode string, now time.Time) error {
+	return r.verify(username, code, recoveryCode, now, func(tx *gorm.DB) error {
+		if err := tx.Where("username = ?", username).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("username = ?", username).Delete(&models.TwoFactorAuth{}).Error
+	})
+}
+
+// RegenerateRecoveryCodes 校验动态码后重新生成恢复码，旧恢复码全部作废
+func (r *TwoFactorRepository) RegenerateRecoveryCodes(username, code string, now time.Time) ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = r.verify(username, code, "", now, func(tx *gorm.DB) error {
+		return replaceRecoveryCodesTx(tx, username, codes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CountUnusedRecoveryCodes 统计剩余可用恢复码数量
+func (r *TwoFactorRepository) CountUnusedRecoveryCodes(username string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.TwoFactorRecoveryCode{}).
+		Where("username = ? AND used_at IS NULL", username).
+		Count(&count).Error
+	return count, err
+}
+
+func verifyTx(tx *gorm.DB, username, code, recoveryCode string, now time.Time) error {
+	var item models.TwoFactorAuth
+	if err := tx.Where("username = ?", username).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTwoFactorNotEnabled
+		}
+		return err
+	}
+	if !item.Enabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if twoFactorLocked(&item, now) {
+		return ErrTwoFactorLocked
+	}
+
+	if code != "" {
+		step, ok := auth.ValidateTOTP(item.Secret, code, now, item.LastUsedStep)
+		if !ok {
+			return ErrTwoFactorInvalidCode
+		}
+		return tx.Model(&item).Updates(map[string]any{
+			"last_used_step":  step,
+			"failed_attempts": 0,
+		}).Error
+	}
+
+	if recoveryCode == "" {
+		return ErrTwoFactorInvalidCode
+	}
+	result := tx.Model(&models.TwoFactorRecoveryCode{}).
+		Where("username = ? AND code_hash = ? AND used_at IS NULL", username, auth.HashRecoveryCode(recoveryCode)).
+		Update("used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTwoFactorInvalidCode
+	}
+	return tx.Model(&item).Update("failed_attempts", 0).Error
+}
+
+func replaceRecoveryCodesTx(tx *gorm.DB, username string, codes []string) error {
+	if err := tx.Where("username = ?", username).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+		return err
+	}
+	records := make([]models.TwoFactorRecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		records = append(records, models.TwoFactorRecoveryCode{
+			Username: username,
+			CodeHash: auth.HashRecoveryCode(code),
+		})
+	}
+	return tx.Create(&records).Error
+}
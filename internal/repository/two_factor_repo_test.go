@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rehtt/hamster-bin/internal/auth"
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTwoFactorLockoutBackoff(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.TwoFactorAuth{}, &models.TwoFactorRecoveryCode{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	repo := NewTwoFactorRepository(db)
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	item, err := repo.BeginSetup("alice")
+	if err != nil {
+		t.Fatalf("BeginSetup: %v", err)
+	}
+	codeAt := func(now time.Time) string {
+		code, err := auth.TOTPCode(item.Secret, auth.TOTPStep(now))
+		if err != nil {
+			t.Fatalf("TOTPCode: %v", err)
+		}
+		return code
+	}
+	if _, err := repo.Enable("alice", codeAt(start), start); err != nil {
+		t.Fatalf("Enable: %v", err)
+	}
+
+	fail := func(now time.Time) error {
+		return repo.Verify("alice", "", "wrong-code", now)
+	}
+	for i := 1; i < TwoFactorMaxFailures; i++ {
+		if err := fail(start); !errors.Is(err, ErrTwoFactorInvalidCode) {
+			t.Fatalf("attempt %d err = %v", i, err)
+		}
+	}
+	if err := fail(start); !errors.Is(err, ErrTwoFactorLocked) {
+		t.Fatalf("limit err = %v, want ErrTwoFactorLocked", err)
+	}
+	if valid, err := repo.PendingTokenValid("alice", start.Add(-time.Minute)); err != nil || valid {
+		t.Fatalf("pending token issued before lock valid = %v, %v", valid, err)
+	}
+
+	// 锁定期内正确的动态码同样被拒绝，且不再累计失败次数
+	inLock := start.Add(TwoFactorLockDuration - time.Minute)
+	if err := repo.Verify("alice", codeAt(inLock), "", inLock); !errors.Is(err, ErrTwoFactorLocked) {
+		t.Fatalf("verify during lock err = %v", err)
+	}
+
+	// 锁定结束后再次失败满次数，锁定时长翻倍
+	second := start.Add(TwoFactorLockDuration)
+	for i := 1; i < TwoFactorMaxFailures; i++ {
+		if err := fail(second); !errors.Is(err, ErrTwoFactorInvalidCode) {
+			t.Fatalf("second round attempt %d err = %v", i, err)
+		}
+	}
+	if err := fail(second); !errors.Is(err, ErrTwoFactorLocked) {
+		t.Fatalf("second limit err = %v", err)
+	}
+	afterBase := second.Add(TwoFactorLockDuration + time.Minute)
+	if err := repo.Verify("alice", codeAt(afterBase), "", afterBase); !errors.Is(err, ErrTwoFactorLocked) {
+		t.Fatalf("second lock should last twice as long, err = %v", err)
+	}
+
+	// 锁定结束后校验成功，失败次数清零
+	unlocked := second.Add(2 * TwoFactorLockDuration)
+	if err := repo.Verify("alice", codeAt(unlocked), "", unlocked); err != nil {
+		t.Fatalf("verify after lock: %v", err)
+	}
+	stored, err := repo.GetByUsername("alice")
+	if err != nil || stored.FailedAttempts != 0 {
+		t.Fatalf("failed attempts = %d, %v", stored.FailedAttempts, err)
+	}
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/Rehtt/hamster-bin/internal/auth"
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUserNotFound       = errors.New("用户不存在")
+	ErrUserDuplicate      = errors.New("用户名已存在")
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	ErrPasswordTooShort   = errors.New("密码长度不能少于 8 位")
+)
+
+// dummyPasswordHash 用户不存在时也执行一次 bcrypt 比较，避免通过响应时间枚举用户名
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := auth.HashPassword("hamster-bin-dummy-password")
+	return hash
+})
+
+type UserRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// List 获取全部账号
+func (r *UserRepository) List() ([]models.User, error) {
+	users := []models.User{}
+	err := r.db.Order("username ASC").Find(&users).Error
+	return users, err
+}
+
+// Exists 账号是否存在
+func (r *UserRepository) Exists(username string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where("username = ?", username).Count(&count).Error
+	return count > 0, err
+}
+
+// Create 创建账号
+func (r *UserRepository) Create(username, password string) (*models.User, error) {
+	username = strings.TrimSpace(username)
+	if len(password) < auth.MinPasswordLength {
+		return nil, ErrPasswordTooShort
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := models.User{Username: username, PasswordHash: hash}
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrUserDuplicate
+		}
+		return tx.Create(&user).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SetPassword 重置账号密码
+func (r *UserRepository) SetPassword(username, password string) error {
+	if len(password) < auth.MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	result := r.db.Model(&models.User{}).Where("username = ?", username).Update("password_hash", hash)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Authenticate 校验账号密码，用户不存在与密码错误均返回 ErrInvalidCredentials
+func (r *UserRepository) Authenticate(username, password string) (*models.User, error) {
+	var user models.User
+	err := r.db.Where("username = ?", username).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		auth.CheckPassword(dummyPasswordHash(), password)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !auth.CheckPassword(user.PasswordHash, password) {
+		return nil, ErrInvalidCredentials
+	}
+	return &user, nil
+}
+
+// Delete 删除账号及其工作区成员身份与二次验证配置；账号是某个工作区唯一的 owner 时拒绝删除
+func (r *UserRepository) Delete(username string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Where("username = ?", username).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+
+		var owned []uint
+		if err := tx.Model(&models.WorkspaceMember{}).
+			Where("username = ? AND role = ?", username, WorkspaceRoleOwner).
+			Pluck("workspace_id", &owned).Error; err != nil {
+			return err
+		}
+		for _, workspaceID := range owned {
+			last, err := isLastOwnerInTx(tx, workspaceID, username)
+			if err != nil {
+				return err
+			}
+			if last {
+				return ErrLastWorkspaceOwner
+			}
+		}
+
+		for _, model := range []any{&models.WorkspaceMember{}, &models.TwoFactorRecoveryCode{}, &models.TwoFactorAuth{}} {
+			if err := tx.Where("username = ?", username).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&user).Error
+	})
+}
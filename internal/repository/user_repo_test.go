@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+)
+
+func TestUserRepositoryLifecycle(t *testing.T) {
+	db, second := setupWorkspaceTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.TwoFactorAuth{}, &models.TwoFactorRecoveryCode{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	repo := NewUserRepository(db)
+
+	if _, err := repo.Create("bob", "short"); !errors.Is(err, ErrPasswordTooShort) {
+		t.Fatalf("Create short password err = %v", err)
+	}
+	user, err := repo.Create(" bob ", "bob-password")
+	if err != nil || user.Username != "bob" || user.PasswordHash == "bob-password" {
+		t.Fatalf("Create = %+v, %v", user, err)
+	}
+	if _, err := repo.Create("bob", "another-password"); !errors.Is(err, ErrUserDuplicate) {
+		t.Fatalf("Create duplicate err = %v", err)
+	}
+
+	if _, err := repo.Authenticate("bob", "bob-password"); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	for _, tc := range [][2]string{{"bob", "wrong-password"}, {"nobody", "bob-password"}} {
+		if _, err := repo.Authenticate(tc[0], tc[1]); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Authenticate(%s) err = %v", tc[0], err)
+		}
+	}
+	if err := repo.SetPassword("bob", "new-password"); err != nil {
+		t.Fatalf("SetPassword: %v", err)
+	}
+	if _, err := repo.Authenticate("bob", "new-password"); err != nil {
+		t.Fatalf("Authenticate with new password: %v", err)
+	}
+	if err := repo.SetPassword("nobody", "new-password"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("SetPassword missing user err = %v", err)
+	}
+
+	// 唯一的 owner 不能删除；有其他 owner 后删除账号同时移除成员身份
+	workspaces := NewWorkspaceRepository(db)
+	if _, err := workspaces.SetMember(second.ID, "bob", WorkspaceRoleOwner); err != nil {
+		t.Fatalf("SetMember: %v", err)
+	}
+	if err := workspaces.RemoveMember(second.ID, "alice"); err != nil {
+		t.Fatalf("RemoveMember: %v", err)
+	}
+	if err := repo.Delete("bob"); !errors.Is(err, ErrLastWorkspaceOwner) {
+		t.Fatalf("Delete last owner err = %v", err)
+	}
+	if _, err := workspaces.SetMember(second.ID, "alice", WorkspaceRoleOwner); err != nil {
+		t.Fatalf("SetMember: %v", err)
+	}
+	if err := repo.Delete("bob"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := workspaces.GetRole(second.ID, "bob"); err == nil {
+		t.Fatal("membership should be removed with the user")
+	}
+	if err := repo.Delete("bob"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("Delete again err = %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultWorkspaceID 默认工作区 ID；旧数据与未指定工作区的请求均归属于此
+	DefaultWorkspaceID   uint = 1
+	DefaultWorkspaceName      = "默认工作区"
+)
+
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleEditor = "editor"
+	WorkspaceRoleViewer = "viewer"
+)
+
+var (
+	ErrWorkspaceMismatch      = errors.New("关联数据不属于当前工作区")
+	ErrWorkspaceNameDuplicate = errors.New("工作区名称已存在")
+	ErrWorkspaceNotEmpty      = errors.New("工作区仍有数据，无法删除")
+	ErrDefaultWorkspace       = errors.New("默认工作区不可删除")
+	ErrInvalidWorkspaceRole   = errors.New("无效的工作区角色")
+	ErrLastWorkspaceOwner     = errors.New("工作区至少需要保留一名所有者")
+	ErrSameWorkspace          = errors.New("目标工作区与来源工作区相同")
+)
+
+var workspaceRoleRank = map[string]int{
+	WorkspaceRoleViewer: 1,
+	WorkspaceRoleEditor: 2,
+	WorkspaceRoleOwner:  3,
+}
+
+// IsValidWorkspaceRole 校验角色取值
+func IsValidWorkspaceRole(role string) bool {
+	_, ok := workspaceRoleRank[role]
+	return ok
+}
+
+// WorkspaceRoleAllows 判断 role 是否具备 required 及以上权限
+func WorkspaceRoleAllows(role, required string) bool {
+	return workspaceRoleRank[role] >= workspaceRoleRank[required] && workspaceRoleRank[required] > 0
+}
+
+// inWorkspace 为查询追加工作区条件，table 用于多表 JOIN 时消除歧义
+func inWorkspace(db *gorm.DB, table string, workspaceID uint) *gorm.DB {
+	return db.Where(table+".workspace_id = ?", workspaceID)
+}
+
+// ensureInWorkspace 校验被引用的记录属于指定工作区
+func ensureInWorkspace(tx *gorm.DB, model any, id uint, workspaceID uint) error {
+	var count int64
+	if err := tx.Model(model).Where("id = ? AND workspace_id = ?", id, workspaceID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrWorkspaceMismatch
+	}
+	return nil
+}
+
+// WorkspaceWithRole 带当前用户角色的工作区
+type WorkspaceWithRole struct {
+	models.Workspace
+	Role string `json:"role"`
+}
+
+type WorkspaceRepository struct {
+	db *gorm.DB
+}
+
+func NewWorkspaceRepository(db *gorm.DB) *WorkspaceRepository {
+	return &WorkspaceRepository{db: db}
+}
+
+// EnsureDefault 确保默认工作区存在，启动迁移时调用
+func (r *WorkspaceRepository) EnsureDefault() error {
+	var count int64
+	if err := r.db.Model(&models.Workspace{}).Where("id = ?", DefaultWorkspaceID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	// 不显式指定主键：空表的自增序列首个值即为 1，避免 PostgreSQL 序列与显式 ID 冲突
+	workspace := models.Workspace{Name: DefaultWorkspaceName}
+	if err := r.db.Create(&workspace).Error; err != nil {
+		return err
+	}
+	if workspace.ID != DefaultWorkspaceID {
+		return fmt.Errorf("默认工作区 ID 应为 %d，实际为 %d", DefaultWorkspaceID, workspace.ID)
+	}
+	return nil
+}
+
+// IDsWithComponents 返回存在元件的工作区，定时任务（消耗预测、ABC 分类）逐个重新计算
+func (r *WorkspaceRepository) IDsWithComponents() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Component{}).Distinct("workspace_id").Order("workspace_id ASC").Pluck("workspace_id", &ids).Error
+	return ids, err
+}
+
+// ListAccessible 列出用户可访问的工作区；all 为 true 时（实例管理员）返回全部并视为 owner
+func (r *WorkspaceRepository) ListAccessible(username string, all bool) ([]WorkspaceWithRole, error) {
+	result := []WorkspaceWithRole{}
+	if all {
+		var workspaces []models.Workspace
+		if err := r.db.Order("id ASC").Find(&workspaces).Error; err != nil {
+			return nil, err
+		}
+		for _, workspace := range workspaces {
+			result = append(result, WorkspaceWithRole{Workspace: workspace, Role: WorkspaceRoleOwner})
+		}
+		return result, nil
+	}
+
+	var members []models.WorkspaceMember
+	if err := r.db.Where("username = ?", username).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return result, nil
+	}
+	roles := make(map[uint]string, len(members))
+	ids := make([]uint, 0, len(members))
+	for _, member := range members {
+		roles[member.WorkspaceID] = member.Role
+		ids = append(ids, member.WorkspaceID)
+	}
+
+	var workspaces []models.Workspace
+	if err := r.db.Where("id IN ?", ids).Order("id ASC").Find(&workspaces).Error; err != nil {
+		return nil, err
+	}
+	for _, workspace := range workspaces {
+		result = append(result, WorkspaceWithRole{Workspace: workspace, Role: roles[workspace.ID]})
+	}
+	return result, nil
+}
+
+// GetByID 根据ID获取工作区
+func (r *WorkspaceRepository) GetByID(id uint) (*models.Workspace, error) {
+	var workspace models.Workspace
+	err := r.db.First(&workspace, id).Error
+	return &workspace, err
+}
+
+// GetRole 获取用户在工作区中的角色；非成员返回 gorm.ErrRecordNotFound
+func (r *WorkspaceRepository) GetRole(workspaceID uint, username string) (string, error) {
+	var member models.WorkspaceMember
+	if err := r.db.Where("workspace_id = ? AND username = ?", workspaceID, username).First(&member).Error; err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+func (r *WorkspaceRepository) isNameTaken(tx *gorm.DB, name string, excludeID uint) (bool, error) {
+	var count int64
+	db := tx.Model(&models.Workspace{}).Where("name = ?", name)
+	if excludeID > 0 {
+		db = db.Where("id != ?", excludeID)
+	}
+	err := db.Count(&count).Error
+	return count > 0, err
+}
+
+// Create 创建工作区，owner 非空时同时写入所有者成员
+func (r *WorkspaceRepository) Create(workspace *models.Workspace, owner string) error {
+	workspace.Name = strings.TrimSpace(workspace.Name)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		taken, err := r.isNameTaken(tx, workspace.Name, 0)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrWorkspaceNameDuplicate
+		}
+		if err := tx.Create(workspace).Error; err != nil {
+			return err
+		}
+		if owner == "" {
+			return nil
+		}
+		return tx.Create(&models.WorkspaceMember{
+			WorkspaceID: workspace.ID,
+			Username:    owner,
+			Role:        WorkspaceRoleOwner,
+		}).Error
+	})
+}
+
+// Update 更新工作区名称与描述
+func (r *WorkspaceRepository) Update(workspace *models.Workspace) error {
+	workspace.Name = strings.TrimSpace(workspace.Name)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		taken, err := r.isNameTaken(tx, workspace.Name, workspace.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrWorkspaceNameDuplicate
+		}
+		return tx.Model(&models.Workspace{}).Where("id = ?", workspace.ID).Updates(map[string]any{
+			"name":        workspace.Name,
+			"description": workspace.Description,
+		}).Error
+	})
+}
+
+// Delete 删除空工作区及其成员与保存搜索；默认工作区与仍有数据的工作区不可删除
+func (r *WorkspaceRepository) Delete(id uint) error {
+	if id == DefaultWorkspaceID {
+		return ErrDefaultWorkspace
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.Workspace{}, id).Error; err != nil {
+			return err
+		}
+		for _, model := range []any{&models.Component{}, &models.PreStock{}, &models.Category{}, &models.Supplier{}, &models.StockLog{}} {
+			var count int64
+			if err := tx.Model(model).Where("workspace_id = ?", id).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrWorkspaceNotEmpty
+			}
+		}
+		for _, model := range []any{&models.SavedSearch{}, &models.WorkspaceMember{}} {
+			if err := tx.Where("workspace_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.Workspace{}, id).Error
+	})
+}
+
+// ListMembers 获取工作区成员
+func (r *WorkspaceRepository) ListMembers(workspaceID uint) ([]models.WorkspaceMember, error) {
+	members := []models.WorkspaceMember{}
+	err := r.db.Where("workspace_id = ?", workspaceID).Order("username ASC").Find(&members).Error
+	return members, err
+}
+
+func isLastOwnerInTx(tx *gorm.DB, workspaceID uint, username string) (bool, error) {
+	var owners []string
+	if err := tx.Model(&models.WorkspaceMember{}).
+		Where("workspace_id = ? AND role = ?", workspaceID, WorkspaceRoleOwner).
+		Pluck("username", &owners).Error; err != nil {
+		return false, err
+	}
+	return len(owners) == 1 && owners[0] == username, nil
+}
+
+// SetMember 添加成员或修改成员角色
+func (r *WorkspaceRepository) SetMember(workspaceID uint, username, role string) (*models.WorkspaceMember, error) {
+	username = strings.TrimSpace(username)
+	if !IsValidWorkspaceRole(role) {
+		return nil, ErrInvalidWorkspaceRole
+	}
+
+	var member models.WorkspaceMember
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.Workspace{}, workspaceID).Error; err != nil {
+			return err
+		}
+		err := tx.Where("workspace_id = ? AND username = ?", workspaceID, username).First(&member).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			member = models.WorkspaceMember{WorkspaceID: workspaceID, Username: username, Role: role}
+			return tx.Create(&member).Error
+		}
+		if err != nil {
+			return err
+		}
+		if member.Role == WorkspaceRoleOwner && role != WorkspaceRoleOwner {
+			last, err := isLastOwnerInTx(tx, workspaceID, username)
+			if err != nil {
+				return err
+			}
+			if last {
+				return ErrLastWorkspaceOwner
+			}
+		}
+		member.Role = role
+		return tx.Model(&member).Update("role", role).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// RemoveMember 移除工作区成员
+func (r *WorkspaceRepository) RemoveMember(workspaceID uint, username string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var member models.WorkspaceMember
+		if err := tx.Where("workspace_id = ? AND username = ?", workspaceID, username).First(&member).Error; err != nil {
+			return err
+		}
+		if member.Role == WorkspaceRoleOwner {
+			last, err := isLastOwnerInTx(tx, workspaceID, username)
+			if err != nil {
+				return err
+			}
+			if last {
+				return ErrLastWorkspaceOwner
+			}
+		}
+		return tx.Delete(&member).Error
+	})
+}
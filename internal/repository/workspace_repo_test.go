@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupWorkspaceTestDB(t *testing.T) (*gorm.DB, models.Workspace) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Workspace{}, &models.WorkspaceMember{}, &models.Category{}, &models.Supplier{}, &models.Component{}, &models.ComponentTag{}, &models.PreStock{}, &models.StockLog{}, &models.SavedSearch{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	repo := NewWorkspaceRepository(db)
+	if err := repo.EnsureDefault(); err != nil {
+		t.Fatalf("EnsureDefault: %v", err)
+	}
+	second := models.Workspace{Name: "音频社"}
+	if err := repo.Create(&second, "alice"); err != nil {
+		t.Fatalf("create workspace: %v", err)
+	}
+	return db, second
+}
+
+func TestWorkspaceScopesDataAndNumbering(t *testing.T) {
+	db, second := setupWorkspaceTestDB(t)
+
+	defaultCategory := models.Category{Name: "电阻"}
+	if err := NewCategoryRepository(db).Create(&defaultCategory); err != nil {
+		t.Fatalf("create default category: %v", err)
+	}
+	secondCategories := NewCategoryRepository(db).ForWorkspace(second.ID)
+	secondCategory := models.Category{Name: "电阻"}
+	if err := secondCategories.Create(&secondCategory); err != nil {
+		t.Fatalf("create second category: %v", err)
+	}
+
+	defaultRepo := NewComponentRepository(db)
+	secondRepo := NewComponentRepository(db).ForWorkspace(second.ID)
+	var createdIDs []uint
+	for _, tc := range []struct {
+		repo     *ComponentRepository
+		category uint
+	}{{defaultRepo, defaultCategory.ID}, {secondRepo, secondCategory.ID}} {
+		component := models.Component{CategoryID: tc.category, Name: "10k"}
+		if err := tc.repo.AssignComponentNumberForCreate(&component); err != nil {
+			t.Fatalf("AssignComponentNumberForCreate: %v", err)
+		}
+		if err := tc.repo.Create(&component); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if *component.ComponentNumber != "HB-000001" {
+			t.Fatalf("component_number = %s, want HB-000001 in each workspace", *component.ComponentNumber)
+		}
+		createdIDs = append(createdIDs, component.ID)
+	}
+
+	components, total, err := secondRepo.GetAll(ComponentQuery{})
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if total != 1 || len(components) != 1 || components[0].WorkspaceID != second.ID {
+		t.Fatalf("GetAll in second workspace = %d/%+v, want only its own component", total, components)
+	}
+
+	if _, err := secondRepo.GetByID(createdIDs[0]); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("GetByID across workspace error = %v, want ErrRecordNotFound", err)
+	}
+
+	crossRef := models.Component{CategoryID: defaultCategory.ID, Name: "越界"}
+	if err := secondRepo.Create(&crossRef); !errors.Is(err, ErrWorkspaceMismatch) {
+		t.Fatalf("Create with foreign category error = %v, want ErrWorkspaceMismatch", err)
+	}
+}
+
+func TestMoveComponentsToWorkspace(t *testing.T) {
+	db, second := setupWorkspaceTestDB(t)
+
+	category := models.Category{Name: "电容"}
+	if err := db.Create(&category).Error; err != nil {
+		t.Fatalf("create category: %v", err)
+	}
+	supplier := models.Supplier{Name: "嘉立创"}
+	if err := db.Create(&supplier).Error; err != nil {
+		t.Fatalf("create supplier: %v", err)
+	}
+	targetCategory := models.Category{WorkspaceID: second.ID, Name: "其他"}
+	if err := db.Create(&targetCategory).Error; err != nil {
+		t.Fatalf("create target category: %v", err)
+	}
+	if err := db.Create(&models.Component{
+		WorkspaceID:     second.ID,
+		CategoryID:      targetCategory.ID,
+		ComponentNumber: strPtr("HB-000001"),
+		Name:            "目标已有元件",
+	}).Error; err != nil {
+		t.Fatalf("create target component: %v", err)
+	}
+
+	component := models.Component{
+		CategoryID:      category.ID,
+		SupplierID:      &supplier.ID,
+		ComponentNumber: strPtr("HB-000001"),
+		Name:            "100nF",
+		StockQuantity:   5,
+	}
+	if err := db.Create(&component).Error; err != nil {
+		t.Fatalf("create component: %v", err)
+	}
+	if err := db.Create(&models.StockLog{ComponentID: component.ID, ChangeAmount: 5, Reason: "入库"}).Error; err != nil {
+		t.Fatalf("create log: %v", err)
+	}
+
+	moved, err := NewComponentRepository(db).MoveToWorkspace(MoveComponentsParams{
+		ComponentIDs:      []uint{component.ID},
+		TargetWorkspaceID: second.ID,
+	})
+	if err != nil {
+		t.Fatalf("MoveToWorkspace: %v", err)
+	}
+	if len(moved) != 1 {
+		t.Fatalf("moved = %+v, want 1 component", moved)
+	}
+	got := moved[0]
+	if got.WorkspaceID != second.ID || *got.ComponentNumber != "HB-000002" {
+		t.Fatalf("moved component = workspace %d number %s, want %d/HB-000002", got.WorkspaceID, *got.ComponentNumber, second.ID)
+	}
+	if got.Category == nil || got.Category.WorkspaceID != second.ID || got.Category.Name != "电容" {
+		t.Fatalf("moved category = %+v, want 电容 recreated in target workspace", got.Category)
+	}
+	if got.Supplier == nil || got.Supplier.WorkspaceID != second.ID || got.Supplier.ID == supplier.ID {
+		t.Fatalf("moved supplier = %+v, want new supplier in target workspace", got.Supplier)
+	}
+
+	logs, err := NewStockLogRepository(db).ForWorkspace(second.ID).GetByComponentID(component.ID, 0)
+	if err != nil {
+		t.Fatalf("GetByComponentID: %v", err)
+	}
+	if len(logs) != 1 {
+		t.Fatalf("logs in target workspace = %d, want 1", len(logs))
+	}
+
+	if _, err := NewComponentRepository(db).MoveToWorkspace(MoveComponentsParams{
+		ComponentIDs:      []uint{component.ID},
+		TargetWorkspaceID: second.ID,
+	}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("move already-moved component error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestWorkspaceMembersKeepLastOwner(t *testing.T) {
+	db, second := setupWorkspaceTestDB(t)
+	repo := NewWorkspaceRepository(db)
+
+	if _, err := repo.SetMember(second.ID, "alice", WorkspaceRoleViewer); !errors.Is(err, ErrLastWorkspaceOwner) {
+		t.Fatalf("demote last owner error = %v, want ErrLastWorkspaceOwner", err)
+	}
+	if _, err := repo.SetMember(second.ID, "bob", "admin"); !errors.Is(err, ErrInvalidWorkspaceRole) {
+		t.Fatalf("invalid role error = %v, want ErrInvalidWorkspaceRole", err)
+	}
+	if _, err := repo.SetMember(second.ID, "bob", WorkspaceRoleViewer); err != nil {
+		t.Fatalf("SetMember: %v", err)
+	}
+
+	accessible, err := repo.ListAccessible("bob", false)
+	if err != nil {
+		t.Fatalf("ListAccessible: %v", err)
+	}
+	if len(accessible) != 1 || accessible[0].ID != second.ID || accessible[0].Role != WorkspaceRoleViewer {
+		t.Fatalf("accessible = %+v, want second workspace as viewer", accessible)
+	}
+
+	if err := repo.Delete(DefaultWorkspaceID); !errors.Is(err, ErrDefaultWorkspace) {
+		t.Fatalf("delete default error = %v, want ErrDefaultWorkspace", err)
+	}
+	// 保存搜索不算数据，随工作区一并删除
+	if err := db.Create(&models.SavedSearch{WorkspaceID: second.ID, Name: "低库存", Owner: "bob"}).Error; err != nil {
+		t.Fatalf("create saved search: %v", err)
+	}
+	if err := repo.Delete(second.ID); err != nil {
+		t.Fatalf("delete empty workspace: %v", err)
+	}
+	var saved int64
+	db.Model(&models.SavedSearch{}).Where("workspace_id = ?", second.ID).Count(&saved)
+	if saved != 0 {
+		t.Fatalf("saved searches left after delete = %d", saved)
+	}
+}
@@ -6,8 +6,12 @@ import (
 	"strings"
 
 	hamsterbin "github.com/Rehtt/hamster-bin"
+	"github.com/Rehtt/hamster-bin/internal/abc"
+	"github.com/Rehtt/hamster-bin/internal/backup"
 	"github.com/Rehtt/hamster-bin/internal/config"
+	"github.com/Rehtt/hamster-bin/internal/forecast"
 	"github.com/Rehtt/hamster-bin/internal/handlers"
+	"github.com/Rehtt/hamster-bin/internal/label"
 	"github.com/Rehtt/hamster-bin/internal/middleware"
 	"github.com/Rehtt/hamster-bin/internal/parser"
 
@@ -21,7 +25,7 @@ var allowedOrigins = map[string]struct{}{
 }
 
 // Setup 设置路由
-func Setup(db *gorm.DB, parserManager *parser.ParserManager, cfg *config.Config) *gin.Engine {
+func Setup(db *gorm.DB, parserManager *parser.ParserManager, cfg *config.Config, scheduler *backup.Scheduler, forecastJob *forecast.Job, abcJob *abc.Job, labels *label.Service) *gin.Engine {
 	// 设置为发布模式（生产环境）
 	// gin.SetMode(gin.ReleaseMode)
 
@@ -37,9 +41,18 @@ func Setup(db *gorm.DB, parserManager *parser.ParserManager, cfg *config.Config)
 	preStockHandler := handlers.NewPreStockHandler(db)
 	stockLogHandler := handlers.NewStockLogHandler(db)
 	statsHandler := handlers.NewStatsHandler(db)
-	parserHandler := handlers.NewParserHandler(parserManager)
-	authHandler := handlers.NewAuthHandler(cfg)
-	authMiddleware := middleware.AuthMiddleware(cfg)
+	savedSearchHandler := handlers.NewSavedSearchHandler(db)
+	forecastHandler := handlers.NewForecastHandler(db, forecastJob)
+	abcHandler := handlers.NewABCHandler(db, abcJob)
+	labelHandler := handlers.NewLabelHandler(db, labels)
+	parserHandler := handlers.NewParserHandler(parserManager, db)
+	authHandler := handlers.NewAuthHandler(cfg, db)
+	workspaceHandler := handlers.NewWorkspaceHandler(cfg, db)
+	userHandler := handlers.NewUserHandler(cfg, db)
+	backupHandler := handlers.NewBackupHandler(cfg, db, scheduler)
+	authMiddleware := middleware.AuthMiddleware(cfg, db)
+	workspaceMiddleware := middleware.WorkspaceMiddleware(cfg, db)
+	workspaceMemberMiddleware := middleware.WorkspaceMemberMiddleware(cfg, db)
 
 	// API 路由组
 	v1 := r.Group("/api/v1")
@@ -49,38 +62,94 @@ func Setup(db *gorm.DB, parserManager *parser.ParserManager, cfg *config.Config)
 			authGroup.POST("/login", authHandler.Login)
 			authGroup.POST("/logout", authHandler.Logout)
 			authGroup.GET("/me", authHandler.Me)
+
+			// TOTP 二次验证（同时接受已登录 Cookie 与登录第二步的等待 Cookie）
+			authGroup.GET("/2fa", authHandler.TwoFactorStatus)
+			authGroup.POST("/2fa/setup", authHandler.SetupTwoFactor)
+			authGroup.GET("/2fa/qrcode", authHandler.TwoFactorQRCode)
+			authGroup.POST("/2fa/enable", authHandler.EnableTwoFactor)
+			authGroup.POST("/2fa/verify", authHandler.VerifyTwoFactor)
+			authGroup.POST("/2fa/disable", authHandler.DisableTwoFactor)
+			authGroup.POST("/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
 		}
 
 		protected := v1.Group("")
 		protected.Use(authMiddleware)
 		{
+			// 账号管理（仅实例管理员）与修改自己的密码
+			protected.POST("/auth/password", userHandler.ChangeOwnPassword)
+			users := protected.Group("/users")
+			{
+				users.GET("", userHandler.GetAll)
+				users.POST("", userHandler.Create)
+				users.PUT("/:username/password", userHandler.SetPassword)
+				users.DELETE("/:username", userHandler.Delete)
+			}
+
+			// 工作区管理（不依赖当前所选工作区，按路径中的工作区 ID 校验角色）
+			workspaces := protected.Group("/workspaces")
+			{
+				workspaces.GET("", workspaceHandler.GetAll)
+				workspaces.POST("", workspaceHandler.Create)
+				workspaces.POST("/move-components", workspaceHandler.MoveComponents)
+				workspaces.PUT("/:id", workspaceHandler.Update)
+				workspaces.DELETE("/:id", workspaceHandler.Delete)
+				workspaces.GET("/:id/members", workspaceHandler.GetMembers)
+				workspaces.PUT("/:id/members/:username", workspaceHandler.SetMember)
+				workspaces.DELETE("/:id/members/:username", workspaceHandler.RemoveMember)
+			}
+
+			// 整库备份与恢复（仅实例管理员，包含全部工作区）
+			protected.GET("/backup", backupHandler.Download)
+			protected.POST("/backup/restore", backupHandler.Restore)
+			protected.GET("/backup/schedule", backupHandler.GetSchedule)
+			protected.POST("/backup/run", backupHandler.RunNow)
+			protected.POST("/backup/maintenance", backupHandler.RunMaintenance)
+			protected.GET("/backup/files/:name", backupHandler.DownloadFile)
+
+			// 以下接口均限定在当前所选工作区内
+			scoped := protected.Group("")
+			scoped.Use(workspaceMiddleware)
+
 			// 分类管理
-			categories := protected.Group("/categories")
+			categories := scoped.Group("/categories")
 			{
 				categories.GET("", categoryHandler.GetAll)
+				categories.GET("/tree", categoryHandler.GetTree)
 				categories.GET("/:id", categoryHandler.GetByID)
 				categories.POST("", categoryHandler.Create)
 				categories.PUT("/:id", categoryHandler.Update)
+				categories.PUT("/:id/move", categoryHandler.Move)
 				categories.DELETE("/:id", categoryHandler.Delete)
 			}
 
 			// 供应商管理
-			suppliers := protected.Group("/suppliers")
+			suppliers := scoped.Group("/suppliers")
 			{
 				suppliers.GET("", supplierHandler.GetAll)
 				suppliers.GET("/:id", supplierHandler.GetByID)
 				suppliers.POST("", supplierHandler.Create)
+				suppliers.POST("/merge", supplierHandler.Merge)
+				suppliers.PUT("/:id", supplierHandler.Update)
+				suppliers.DELETE("/:id", supplierHandler.Delete)
 			}
 
 			// 元件管理
-			components := protected.Group("/components")
+			components := scoped.Group("/components")
 			{
 				components.GET("", componentHandler.GetAll)
 				components.GET("/options", componentHandler.GetOptions)
-				components.GET("/export", componentHandler.ExportCSV)
+				components.GET("/tags", componentHandler.GetTags)
+				components.GET("/suggest/:field", componentHandler.Suggest)
+				components.GET("/export", componentHandler.Export)
+				components.POST("/import", componentHandler.ImportComponents)
 				components.PATCH("/batch-location", componentHandler.BatchUpdateLocation)
+				components.PATCH("/batch-category", componentHandler.BatchUpdateCategory)
+				components.PATCH("/batch-tags", componentHandler.BatchUpdateTags)
 				components.POST("/batch-stock-out", componentHandler.BatchStockOut)
 				components.PATCH("/generate-numbers", componentHandler.GenerateMissingNumbers)
+				components.GET("/duplicates", componentHandler.GetDuplicates)
+				components.POST("/merge", componentHandler.Merge)
 				components.GET("/:id", componentHandler.GetByID)
 				components.POST("", componentHandler.Create)
 				components.PUT("/:id", componentHandler.Update)
@@ -90,21 +159,27 @@ func Setup(db *gorm.DB, parserManager *parser.ParserManager, cfg *config.Config)
 				components.POST("/:id/stock", componentHandler.UpdateStock)
 				components.POST("/:id/backfill-price", componentHandler.BackfillPrice)
 				components.GET("/:id/logs", componentHandler.GetStockLogs)
+				components.GET("/:id/stock-history", statsHandler.GetComponentStockHistory)
 
 				// 图片处理
 				components.POST("/:id/image", componentHandler.UploadImage)
 				components.GET("/:id/image", componentHandler.GetImage)
+				components.GET("/:id/qrcode", labelHandler.ComponentCodeImage)
 
-				// 平台解析
+				// 平台解析（只读，GET 供只读成员扫码查找）
+				components.GET("/parse", parserHandler.ParseComponent)
 				components.POST("/parse", parserHandler.ParseComponent)
+				components.GET("/parse-qrcode", parserHandler.ParseQRCode)
 				components.POST("/parse-qrcode", parserHandler.ParseQRCode)
 			}
 
 			// 预入库
-			preStocks := protected.Group("/pre-stocks")
+			preStocks := scoped.Group("/pre-stocks")
 			{
 				preStocks.GET("", preStockHandler.GetAll)
+				preStocks.GET("/export", preStockHandler.Export)
 				preStocks.GET("/:id", preStockHandler.GetByID)
+				preStocks.GET("/:id/qrcode", labelHandler.PreStockCodeImage)
 				preStocks.POST("", preStockHandler.Create)
 				preStocks.PUT("/:id", preStockHandler.Update)
 				preStocks.DELETE("/:id", preStockHandler.Delete)
@@ -112,13 +187,42 @@ func Setup(db *gorm.DB, parserManager *parser.ParserManager, cfg *config.Config)
 			}
 
 			// 库存记录
-			stockLogs := protected.Group("/stock-logs")
+			stockLogs := scoped.Group("/stock-logs")
 			{
 				stockLogs.GET("", stockLogHandler.GetAll)
+				stockLogs.GET("/operators", stockLogHandler.GetOperators)
+				stockLogs.GET("/export", stockLogHandler.Export)
 				stockLogs.POST("/:id/revoke", stockLogHandler.Revoke)
 			}
 
-			protected.GET("/stats", statsHandler.GetDashboard)
+			// 保存搜索（智能视图）：只读成员也可管理自己的私有搜索，由 handler 校验
+			savedSearches := protected.Group("/saved-searches", workspaceMemberMiddleware)
+			{
+				savedSearches.GET("", savedSearchHandler.GetAll)
+				savedSearches.GET("/:id", savedSearchHandler.GetByID)
+				savedSearches.POST("", savedSearchHandler.Create)
+				savedSearches.PUT("/:id", savedSearchHandler.Update)
+				savedSearches.DELETE("/:id", savedSearchHandler.Delete)
+			}
+
+			// 消耗预测
+			scoped.GET("/forecasts", forecastHandler.GetAll)
+			scoped.POST("/forecasts/recompute", forecastHandler.Recompute)
+
+			scoped.GET("/stats", statsHandler.GetDashboard)
+			scoped.GET("/stats/series", statsHandler.GetSeries)
+			scoped.GET("/stats/valuation", statsHandler.GetValuation)
+			scoped.GET("/stats/valuation/export", statsHandler.ExportValuation)
+			scoped.GET("/stats/dead-stock", statsHandler.GetDeadStock)
+			scoped.GET("/stats/dead-stock/export", statsHandler.ExportDeadStock)
+			scoped.GET("/stats/abc", abcHandler.GetSummary)
+			scoped.POST("/stats/abc/recompute", abcHandler.Recompute)
+
+			// 标签打印
+			scoped.GET("/labels/templates", labelHandler.GetTemplates)
+			scoped.GET("/labels/components", labelHandler.PrintComponents)
+			scoped.GET("/labels/locations", labelHandler.PrintLocations)
+			scoped.GET("/locations/qrcode", labelHandler.LocationCodeImage)
 
 			// 平台支持
 			protected.GET("/platforms", parserHandler.GetSupportedPlatforms)
@@ -159,7 +263,7 @@ func corsMiddleware() gin.HandlerFunc {
 		}
 
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+middleware.WorkspaceHeader)
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
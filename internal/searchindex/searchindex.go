@@ -0,0 +1,121 @@
+// Package searchindex 维护元件全文索引的表结构（FTS5 外部内容表、tsvector 生成列、FULLTEXT 索引），
+// 由数据库迁移创建，仓储层据此判断关键词搜索的实现。
+package searchindex
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Rehtt/hamster-bin/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// Table SQLite FTS5 外部内容表，rowid 为元件 ID
+	Table = "component_search"
+	// VectorColumn PostgreSQL 元件表上的 tsvector 生成列
+	VectorColumn = "search_vector"
+	// FullTextIndex MySQL 元件表上的 FULLTEXT 索引
+	FullTextIndex = "idx_components_fulltext"
+)
+
+// Columns 全文索引覆盖的元件列，search_keys 为预计算的拼音与型号三元组；
+// 供应商名称在其他表中，始终按 LIKE 匹配
+var Columns = []string{"name", "component_number", "model", "manufacturer", "value", "supplier_part_number", "description", "search_keys"}
+
+// Ensure 创建元件全文索引（由数据库迁移调用），可重复执行：
+// SQLite 为 FTS5 外部内容表（trigram 分词）及同步触发器，PostgreSQL 为 tsvector 生成列与 GIN 索引，
+// MySQL 为 ngram 分词的 FULLTEXT 索引。SQLite 上重建 components 表（如 AlterColumn）会丢失触发器，
+// 此类迁移之后需再次调用。只索引表中已存在的列（早期迁移执行时尚无 search_keys）。
+func Ensure(tx *gorm.DB) error {
+	var columns []string
+	for _, column := range Columns {
+		if tx.Migrator().HasColumn(&models.Component{}, column) {
+			columns = append(columns, column)
+		}
+	}
+	switch tx.Dialector.Name() {
+	case "sqlite":
+		return ensureSQLiteSearchIndex(tx, columns)
+	case "postgres":
+		if tx.Migrator().HasColumn(&models.Component{}, VectorColumn) {
+			return nil
+		}
+		parts := make([]string, len(columns))
+		for i, column := range columns {
+			parts[i] = "coalesce(" + column + ", '')"
+		}
+		if err := tx.Exec(fmt.Sprintf(
+			"ALTER TABLE components ADD COLUMN %s tsvector GENERATED ALWAYS AS (to_tsvector('simple', %s)) STORED",
+			VectorColumn, strings.Join(parts, " || ' ' || "),
+		)).Error; err != nil {
+			return err
+		}
+		return tx.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_components_search_vector ON components USING GIN (%s)", VectorColumn)).Error
+	case "mysql":
+		if tx.Migrator().HasIndex(&models.Component{}, FullTextIndex) {
+			return nil
+		}
+		return tx.Exec(fmt.Sprintf("ALTER TABLE components ADD FULLTEXT INDEX %s (%s) WITH PARSER ngram",
+			FullTextIndex, strings.Join(columns, ", "))).Error
+	}
+	return nil
+}
+
+// Rebuild 删除并按当前列重新创建元件全文索引，用于索引列变化后的迁移
+func Rebuild(tx *gorm.DB) error {
+	var statements []string
+	switch tx.Dialector.Name() {
+	case "sqlite":
+		statements = []string{
+			"DROP TRIGGER IF EXISTS components_search_ai",
+			"DROP TRIGGER IF EXISTS components_search_ad",
+			"DROP TRIGGER IF EXISTS components_search_au",
+			"DROP TABLE IF EXISTS " + Table,
+		}
+	case "postgres":
+		statements = []string{
+			"DROP INDEX IF EXISTS idx_components_search_vector",
+			"ALTER TABLE components DROP COLUMN IF EXISTS " + VectorColumn,
+		}
+	case "mysql":
+		if tx.Migrator().HasIndex(&models.Component{}, FullTextIndex) {
+			statements = []string{"ALTER TABLE components DROP INDEX " + FullTextIndex}
+		}
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return Ensure(tx)
+}
+
+func ensureSQLiteSearchIndex(tx *gorm.DB, columns []string) error {
+	table := Table
+	list := strings.Join(columns, ", ")
+	values := func(prefix string) string {
+		parts := make([]string, len(columns))
+		for i, column := range columns {
+			parts[i] = prefix + column
+		}
+		return strings.Join(parts, ", ")
+	}
+	insertNew := fmt.Sprintf("INSERT INTO %s(rowid, %s) VALUES (new.id, %s);", table, list, values("new."))
+	deleteOld := fmt.Sprintf("INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.id, %s);", table, table, list, values("old."))
+
+	statements := []string{
+		fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(%s, content='components', content_rowid='id', tokenize='trigram')", table, list),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS components_search_ai AFTER INSERT ON components BEGIN %s END", insertNew),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS components_search_ad AFTER DELETE ON components BEGIN %s END", deleteOld),
+		// 库存变动只改 stock_quantity，不触发重建索引
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS components_search_au AFTER UPDATE OF %s ON components BEGIN %s %s END", list, deleteOld, insertNew),
+		fmt.Sprintf("INSERT INTO %s(%s) VALUES ('rebuild')", table, table),
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,410 @@
+package searchkey
+
+// pinyinTable GB2312 全部 6763 个汉字按读音分组，依据 Unicode CLDR 拼音排序整理；
+// 多音字取元件名称中的常用读音（如 地 di、长 chang、伺 si、弹 tan），ü 写作 v
+var pinyinTable = []struct {
+	syllable string
+	chars    string
+}{
+	{"a", "阿锕嗄啊"},
+	{"ai", "哎哀唉埃挨嗳锿捱皑癌矮蔼霭艾爱砹隘嗌嫒碍暧瑷"},
+	{"an", "安桉氨庵谙鹌鞍俺埯铵揞犴岸按案胺暗黯"},
+	{"ang", "肮昂盎"},
+	{"ao", "凹敖嗷廒遨熬獒翱聱螯鳌鏖拗袄媪岙坳傲奥骜懊澳鏊"},
+	{"ba", "八扒岜芭疤捌粑拔茇菝跋魃把钯靶坝爸耙鲅霸灞巴叭吧笆罢"},
+	{"bai", "掰擘白百佰柏捭摆败拜稗"},
+	{"ban", "扳班般颁斑搬瘢癍阪坂板版钣舨办半伴拌绊瓣扮"},
+	{"bang", "邦帮梆浜绑榜膀蚌傍棒谤蒡磅镑"},
+	{"bao", "勹包孢苞胞煲龅褒雹宝饱保鸨堡葆褓报抱豹趵鲍暴爆"},
+	{"bei", "陂卑杯悲碑鹎北贝孛狈邶备背钡倍悖被惫焙辈碚蓓褙鞴鐾呗"},
+	{"ben", "奔贲锛本苯畚坌笨"},
+	{"beng", "崩嘣甭绷泵迸甏蹦"},
+	{"bi", "逼荸鼻匕比吡妣彼秕俾笔舭鄙币必毕闭庇畀哔毖荜陛毙狴铋婢庳敝萆弼愎筚滗痹蓖裨跸弊碧箅蔽壁嬖篦薜避濞臂髀璧襞"},
+	{"bian", "边砭笾编煸蝙鳊鞭贬扁窆匾碥褊卞弁忭汴苄变便缏遍辨辩辫"},
+	{"biao", "灬杓标飑髟彪骠膘瘭镖飙飚镳表婊裱鳔"},
+	{"bie", "憋鳖别蹩瘪"},
+	{"bin", "玢宾彬傧斌滨缤槟镔濒豳摈殡膑髌鬓"},
+	{"bing", "冫冰兵丙邴秉柄炳饼摒禀并病"},
+	{"bo", "拨波玻剥钵饽菠播伯驳帛勃亳钹铂脖舶博渤鹁搏箔踣礴跛簸檗薄"},
+	{"bu", "卜啵膊逋晡醭卟补哺捕不布步怖钚埔部钸埠瓿簿"},
+	{"ca", "嚓擦礤"},
+	{"cai", "猜才材财裁采彩睬踩菜蔡"},
+	{"can", "参骖餐残蚕惭惨黪灿掺孱粲璨"},
+	{"cang", "仓伧沧苍舱藏"},
+	{"cao", "操糙曹嘈漕槽艚螬草艹"},
+	{"ce", "册侧厕恻测策"},
+	{"cen", "岑涔"},
+	{"ceng", "噌层曾蹭"},
+	{"cha", "叉杈插馇锸查茬茶搽猹槎察碴檫衩镲汊岔诧姹差"},
+	{"chai", "拆钗侪柴豺虿瘥"},
+	{"chan", "觇搀婵谗禅馋缠蝉廛潺澶镡蟾躔产谄铲阐蒇骣冁忏颤羼"},
+	{"chang", "伥昌娼猖菖阊鲳肠苌尝偿常徜嫦厂场昶惝氅怅畅倡鬯唱敞长"},
+	{"chao", "抄怊钞焯超晁巢朝嘲潮吵炒耖"},
+	{"che", "车砗扯屮彻坼掣撤澈"},
+	{"chen", "抻郴琛嗔尘臣忱沉辰陈宸谌碜衬龀趁榇谶晨"},
+	{"cheng", "柽称蛏撑瞠丞成呈承枨诚城乘埕晟铖惩程裎塍酲澄橙逞骋秤"},
+	{"chi", "吃哧蚩鸱眵笞嗤媸痴螭魑弛池驰迟坻茌持墀踟篪尺侈齿耻褫彳叱斥赤饬炽翅敕啻傺瘛"},
+	{"chong", "充冲忡茺舂憧艟虫崇宠铳"},
+	{"chou", "抽瘳仇俦帱惆绸畴愁稠筹踌雠丑瞅臭酬"},
+	{"chu", "出初樗刍除厨滁锄蜍雏橱躇蹰杵础储楮褚亍处怵绌畜搐触憷黜矗楚"},
+	{"chuai", "揣搋啜嘬膪踹"},
+	{"chuan", "巛川氚穿传舡船遄椽舛喘串钏"},
+	{"chuang", "疮窗床幢闯创怆"},
+	{"chui", "吹炊垂陲捶棰椎槌锤"},
+	{"chun", "春椿蝽纯唇莼淳醇蠢鹑"},
+	{"chuo", "踔戳辶绰辍龊"},
+	{"ci", "呲疵词祠茈茨瓷慈辞磁雌鹚糍此次刺赐"},
+	{"cong", "匆囱苁枞葱骢璁聪从丛淙琮"},
+	{"cou", "凑腠辏"},
+	{"cu", "粗徂殂促猝酢蔟醋簇蹙蹴"},
+	{"cuan", "汆撺镩蹿窜篡爨"},
+	{"cui", "崔催摧榱璀脆啐悴淬萃毳瘁粹翠"},
+	{"cun", "村皴存忖寸"},
+	{"cuo", "搓磋撮蹉嵯痤矬鹾脞厝挫措锉错"},
+	{"da", "哒耷嗒搭褡达妲怛沓笪答靼鞑打大瘩"},
+	{"dai", "呆呔歹逮傣代岱甙绐迨骀带待怠殆玳贷埭袋戴黛"},
+	{"dan", "丹单担眈耽郸聃殚瘅箪儋胆疸掸赕旦但诞啖惮淡萏蛋氮澹"},
+	{"dang", "当裆挡党谠凼宕砀荡档菪铛"},
+	{"dao", "刀刂叨忉氘导岛捣祷蹈到倒悼焘盗道稻纛"},
+	{"de", "锝德的得"},
+	{"deng", "灯登噔簦蹬等戥邓凳嶝瞪磴镫"},
+	{"di", "氐低羝堤滴镝狄籴迪敌涤荻笛觌嘀嫡翟诋邸底抵柢砥骶弟帝娣递第谛棣睇缔蒂碲地"},
+	{"dia", "嗲"},
+	{"dian", "甸掂滇颠巅癫典点碘踮电佃阽坫店垫玷钿惦淀奠殿靛癜簟"},
+	{"diao", "刁叼凋貂碉雕鲷吊钓掉铞铫"},
+	{"die", "爹跌迭垤瓞谍喋堞揲耋叠牒碟蝶蹀鲽"},
+	{"ding", "丁仃叮玎疔盯钉耵酊顶鼎订定啶铤腚碇锭"},
+	{"diu", "丢铥"},
+	{"dong", "东冬咚岽氡鸫董懂动冻侗垌峒恫栋洞胨胴硐"},
+	{"dou", "都兜蔸篼抖陡蚪斗豆逗痘窦"},
+	{"du", "嘟督毒独读渎椟牍犊碡黩髑笃堵赌睹芏妒杜肚度渡镀蠹"},
+	{"duan", "端短段断缎椴煅锻簖"},
+	{"dui", "堆队对兑怼碓憝镦"},
+	{"dun", "吨敦墩礅蹲盹趸囤沌炖盾砘钝顿遁"},
+	{"duo", "多咄哆掇裰夺铎踱哚垛缍躲剁柁堕舵惰跺朵"},
+	{"e", "婀屙讹俄娥峨莪锇鹅蛾额厄呃扼苊轭垩恶饿谔鄂阏愕萼遏腭锷鹗颚噩鳄"},
+	{"ei", "诶"},
+	{"en", "恩蒽摁"},
+	{"er", "儿而鸸鲕尔耳迩洱饵珥铒二佴贰"},
+	{"fa", "发乏伐垡罚阀砝筏法珐"},
+	{"fan", "帆番幡蕃翻藩凡矾钒烦樊燔繁蹯蘩反返犯泛饭范贩畈梵"},
+	{"fang", "匚方邡芳枋钫防妨房肪鲂仿访彷纺舫放坊"},
+	{"fei", "飞妃非啡绯菲扉蜚霏鲱肥淝腓匪诽悱斐榧翡篚吠芾废沸狒肺费痱镄"},
+	{"fen", "分吩纷芬氛酚坟汾棼焚鼢粉份奋忿偾愤粪鲼瀵"},
+	{"feng", "丰风沣枫封疯砜峰烽葑锋蜂酆冯逢讽唪凤奉俸缝"},
+	{"fou", "缶否"},
+	{"fu", "呋肤趺麸稃跗孵敷弗伏凫佛孚扶芙怫拂服绂绋苻俘氟祓罘茯郛浮砩莩蚨匐桴涪符艴菔幅福蜉辐幞蝠黻呒抚府拊斧俯釜辅腑滏腐黼阝父讣付妇负附阜驸复赴副富赋缚腹鲋赙蝮鳆覆馥夫甫咐袱傅"},
+	{"ga", "旮呷嘎钆尜噶尕尬"},
+	{"gai", "该陔垓赅改丐钙盖溉戤概"},
+	{"gan", "甘杆肝坩泔矸苷柑竿疳酐尴秆赶敢感澉橄擀干旰绀淦赣"},
+	{"gang", "冈刚杠纲肛缸钢罡岗港筻戆"},
+	{"gao", "皋羔高槔睾膏篙糕杲搞缟槁稿镐藁告诰郜锆"},
+	{"ge", "戈仡圪纥疙咯哥胳袼鸽割搁歌阁革格鬲葛隔嗝塥搿膈镉骼哿舸个各虼硌铬"},
+	{"gei", "给"},
+	{"gen", "根跟哏艮亘茛"},
+	{"geng", "庚耕赓羹哽埂绠耿梗鲠更"},
+	{"gong", "工弓公功攻供肱宫恭躬龚觥廾巩汞拱珙共贡蚣"},
+	{"gou", "勾佝沟钩缑篝鞲岣狗苟枸笱构诟购垢够媾彀遘觏"},
+	{"gu", "估呱姑孤沽轱鸪菰蛄觚辜酤箍古汩诂谷股牯骨罟钴蛊鹄毂鼓嘏鹘臌瞽固故顾崮梏牿雇痼锢鲴咕菇"},
+	{"gua", "瓜刮胍栝鸹聒剐寡卦诖挂褂"},
+	{"guai", "乖掴拐怪"},
+	{"guan", "关观官冠倌棺鳏莞馆管贯惯掼涫盥灌鹳罐"},
+	{"guang", "光咣桄胱广犷逛"},
+	{"gui", "归圭妫龟规皈闺硅瑰鲑宄轨庋匦诡癸鬼晷簋刽刿柜贵桂桧跪鳜"},
+	{"gun", "丨衮绲辊滚磙鲧棍"},
+	{"guo", "呙埚郭崞锅蝈国帼虢馘果猓椁蜾裹过"},
+	{"ha", "哈铪蛤"},
+	{"hai", "嗨还孩骸海胲醢亥骇害氦"},
+	{"han", "顸蚶酣憨鼾邗含邯函晗涵焓寒韩罕喊阚汉汗旱悍捍焊菡颔撖憾撼翰瀚"},
+	{"hang", "夯杭绗珩航颃沆"},
+	{"hao", "蒿嚆薅蚝毫嗥貉豪嚎壕濠好郝号昊浩耗皓颢灏"},
+	{"he", "诃喝嗬禾合何劾和河曷阂核盍荷涸盒菏蚵颌阖翮贺褐赫鹤壑呵"},
+	{"hei", "黑嘿"},
+	{"hen", "痕很狠恨"},
+	{"heng", "亨哼恒桁横衡蘅"},
+	{"hong", "轰哄訇烘薨弘红宏闳泓洪荭虹鸿蕻黉讧"},
+	{"hou", "侯喉猴瘊篌糇骺吼后厚後逅堠鲎候"},
+	{"hu", "虍呼忽烀轷唿惚滹囫弧狐胡壶斛湖猢葫煳瑚鹕槲蝴醐觳虎浒琥互户冱护沪岵怙戽祜笏扈瓠鹱乎唬糊"},
+	{"hua", "花哗华骅铧滑猾化划画话桦"},
+	{"huai", "怀徊淮槐踝坏"},
+	{"huan", "獾环郇洹桓萑锾圜寰缳鬟缓幻奂宦唤换浣涣患焕逭痪豢漶鲩擐欢"},
+	{"huang", "肓荒慌皇凰隍黄徨惶湟遑煌潢璜篁蝗癀磺簧蟥鳇恍谎幌晃"},
+	{"hui", "灰诙咴恢挥虺晖珲辉麾徽隳回洄茴蛔悔毁卉汇会讳哕浍绘荟诲恚烩贿彗晦秽喙惠缋慧蕙蟪"},
+	{"hun", "昏荤婚阍浑馄魂诨混溷"},
+	{"huo", "耠锪劐豁攉活火伙钬夥或货砉获祸惑霍镬嚯藿蠖"},
+	{"ji", "丌讥击叽饥乩圾机玑肌芨矶鸡咭迹剞唧姬屐积笄基绩嵇犄缉赍畸跻箕畿稽齑墼激羁及吉岌汲级即极亟佶诘急笈疾脊戢棘殛集嫉楫蒺瘠蕺藉籍几己虮挤掎戟嵴麂彐计记伎纪妓忌技芰际剂季哜既洎济荠继觊偈寂寄悸祭蓟暨跽霁鲚稷鲫冀髻骥辑"},
+	{"jia", "加夹伽佳迦枷浃珈家痂笳袈葭跏嘉镓郏荚恝戛袷铗蛱颊甲岬胛贾钾假瘕价驾架嫁稼"},
+	{"jian", "戋奸尖坚歼间肩艰兼监笺菅湔犍缄搛煎缣蒹鲣鹣鞯囝拣枧俭柬茧捡笕减剪检趼睑硷裥锏简谫戬碱翦謇蹇见件建饯剑牮荐贱健涧舰渐谏楗毽溅腱践鉴键僭箭踺"},
+	{"jiang", "江姜将茳浆豇僵缰礓疆讲奖桨蒋耩降洚绛酱犟糨匠"},
+	{"jiao", "艽交郊姣娇浇茭骄胶椒焦蛟跤僬鲛蕉礁鹪角佼侥挢狡绞饺皎矫脚铰搅湫剿敫徼缴叫峤轿较教窖酵噍醮"},
+	{"jie", "阶疖皆接秸喈嗟揭街卩孑节讦劫杰拮洁结桀婕捷颉睫截碣竭鲒羯解介戒芥届界疥诫借蚧骱姐"},
+	{"jin", "巾今斤钅金津矜衿筋襟仅尽卺紧堇谨锦廑馑槿瑾劲妗近进荩晋浸烬赆禁缙靳觐噤"},
+	{"jing", "京泾经茎荆惊旌菁晶腈粳兢精鲸井阱刭肼颈景儆憬警净弪径迳胫痉竞婧竟敬靓靖境獍静镜睛"},
+	{"jiong", "冂扃炅迥炯窘"},
+	{"jiu", "纠究鸠赳阄啾揪鬏九久灸玖韭酒旧臼咎疚柩桕厩救就舅僦鹫"},
+	{"ju", "居拘狙苴驹疽掬菹椐琚趄锔裾雎鞠鞫局桔菊橘咀沮举莒榉榘龃踽巨句讵拒苣具炬钜俱倨剧惧据距犋飓锯窭聚屦踞遽醵矩"},
+	{"juan", "娟捐涓鹃镌蠲卷锩倦桊狷绢隽眷鄄"},
+	{"jue", "噘撅孓决诀抉珏绝觉倔崛掘桷觖厥劂谲獗蕨噱橛爵镢蹶嚼矍爝攫"},
+	{"jun", "军君均钧皲菌麇俊郡峻捃浚骏竣"},
+	{"ka", "咔咖喀卡佧胩"},
+	{"kai", "开揩锎凯剀垲恺铠慨蒈楷锴忾"},
+	{"kan", "刊勘龛堪戡坎侃砍莰槛看瞰"},
+	{"kang", "闶康慷糠扛亢伉抗炕钪"},
+	{"kao", "尻考拷栲烤铐犒靠"},
+	{"ke", "苛柯珂科轲疴棵颏嗑稞窠颗瞌磕蝌髁壳可坷岢渴克刻客恪课氪骒缂溘锞咳钶"},
+	{"ken", "肯垦恳啃龈裉"},
+	{"keng", "吭坑铿"},
+	{"kong", "空倥崆箜孔恐控"},
+	{"kou", "抠芤眍口叩扣寇筘蔻"},
+	{"ku", "刳枯哭堀窟骷苦库绔喾裤酷"},
+	{"kua", "夸侉垮挎胯跨"},
+	{"kuai", "蒯块快侩郐哙狯脍筷"},
+	{"kuan", "宽髋款"},
+	{"kuang", "匡诓哐框筐狂诳夼邝圹纩况旷矿贶眶"},
+	{"kui", "亏岿悝盔窥奎逵隗馗喹揆葵暌魁睽蝰夔跬匮喟愦愧溃蒉馈篑聩傀"},
+	{"kun", "坤昆琨锟髡醌鲲悃捆阃困"},
+	{"kuo", "扩括蛞阔廓"},
+	{"la", "垃拉邋旯剌砬喇腊瘌蜡辣啦"},
+	{"lai", "来崃徕涞莱铼赉睐赖濑癞籁"},
+	{"lan", "兰岚拦栏婪阑蓝谰澜褴斓篮镧览揽缆榄漤罱懒烂滥"},
+	{"lang", "啷郎狼阆廊琅榔稂锒螂朗浪莨蒗"},
+	{"lao", "捞劳牢唠崂痨铹醪老佬姥栳铑潦涝烙耢酪"},
+	{"le", "肋仂乐叻泐鳓了勒"},
+	{"lei", "雷嫘缧擂檑镭羸耒诔垒磊蕾儡泪类累酹嘞"},
+	{"leng", "塄棱楞冷愣"},
+	{"li", "厘离骊梨犁喱鹂漓缡蓠蜊嫠璃鲡黎篱罹藜黧蠡礼里俚娌逦理锂鲤澧醴鳢力历厉立吏丽利励呖坜沥苈例戾枥疠隶俐俪栎疬荔轹郦栗猁砺砾莅莉唳笠粒粝蛎傈痢詈跞雳溧篥李哩狸"},
+	{"lia", "俩"},
+	{"lian", "奁连帘怜涟莲联裢廉鲢濂臁镰蠊敛琏脸裣蔹练炼恋殓链楝潋"},
+	{"liang", "良凉梁椋粮粱墚踉两魉亮谅辆晾量"},
+	{"liao", "撩辽疗聊僚寥嘹寮獠缭燎鹩钌蓼尥料廖撂镣"},
+	{"lie", "列劣冽洌埒烈捩猎裂趔躐鬣咧"},
+	{"lin", "拎邻林临啉淋琳粼嶙遴辚霖瞵磷鳞麟凛廪懔檩吝赁蔺膦躏"},
+	{"ling", "灵囹泠苓柃玲瓴凌铃陵棂绫羚翎聆菱蛉零龄鲮酃岭领令另呤伶"},
+	{"liu", "溜熘刘浏流留琉硫旒遛馏骝榴瘤镏鎏柳绺锍六鹨"},
+	{"long", "龙咙泷茏栊珑胧砻笼聋隆癃陇垄垅拢窿"},
+	{"lou", "娄偻蒌楼耧蝼髅嵝搂篓陋漏瘘镂喽"},
+	{"lu", "噜撸卢庐芦垆泸炉栌胪轳鸬舻颅鲈卤虏掳鲁橹镥陆录赂辂渌逯鹿禄碌路漉戮辘潞璐簏鹭麓露氇"},
+	{"lv", "驴闾榈吕侣捋旅稆铝屡缕膂褛履律虑率绿氯滤"},
+	{"luan", "娈孪峦挛栾鸾脔滦銮卵乱"},
+	{"lve", "锊掠略"},
+	{"lun", "抡仑伦囵沦纶轮论"},
+	{"luo", "罗猡脶萝逻椤锣箩骡镙螺倮裸瘰蠃泺洛络荦骆珞落摞漯雒"},
+	{"ma", "妈嬷麻马玛码蚂犸杩骂唛吗嘛蟆"},
+	{"mai", "埋霾买荬劢迈麦卖脉"},
+	{"man", "颟蛮谩馒瞒鞔鳗满螨曼墁幔慢漫缦蔓熳镘"},
+	{"mang", "邙忙芒氓盲茫硭莽漭蟒"},
+	{"mao", "猫毛矛牦茅茆旄锚髦蝥蟊卯峁泖昴铆茂冒贸耄袤帽瑁瞀貌懋"},
+	{"me", "么"},
+	{"mei", "没枚玫眉莓梅媒嵋湄猸楣煤酶镅鹛霉每美浼镁妹昧袂媚寐魅"},
+	{"men", "门扪钔闷焖懑们"},
+	{"meng", "虻萌盟蒙甍瞢朦檬礞艨勐猛锰艋蜢懵蠓孟梦"},
+	{"mi", "咪眯弥祢迷猕谜醚糜縻麋靡蘼米芈弭敉脒冖糸汨宓泌觅秘密幂谧嘧蜜"},
+	{"mian", "宀眠绵棉免沔黾勉眄娩冕渑湎缅腼面"},
+	{"miao", "喵苗描瞄鹋杪眇秒淼渺缈藐邈妙庙"},
+	{"mie", "乜咩灭蔑篾蠛"},
+	{"min", "民岷苠珉缗皿闵抿泯闽悯敏愍鳘"},
+	{"ming", "名明鸣茗冥铭溟暝瞑螟酩命"},
+	{"miu", "谬"},
+	{"mo", "摸谟嫫馍摹模膜麽摩磨蘑魔抹末殁沫茉陌秣莫寞漠蓦貊瘼镆墨默貘耱"},
+	{"mou", "哞牟侔眸谋蛑缪鍪某"},
+	{"mu", "毪母亩牡坶姆木仫目沐牧苜钼募墓幕睦慕暮穆拇"},
+	{"n", "嗯"},
+	{"na", "拿镎哪那纳肭娜衲钠捺"},
+	{"nai", "乃奶艿氖奈柰耐萘鼐"},
+	{"nan", "囡男南难喃楠赧腩蝻"},
+	{"nang", "囔囊馕曩攮"},
+	{"nao", "孬呶挠硇铙猱蛲垴恼脑瑙闹淖"},
+	{"ne", "疒讷呐呢"},
+	{"nei", "馁内"},
+	{"nen", "恁嫩"},
+	{"neng", "能"},
+	{"ni", "妮尼坭怩泥倪铌猊霓鲵你拟旎伲昵逆匿溺睨腻"},
+	{"nian", "拈蔫年鲇鲶黏捻辇辗撵碾廿念埝"},
+	{"niang", "酿娘"},
+	{"niao", "鸟茑袅嬲尿脲"},
+	{"nie", "捏陧涅聂臬啮嗫镊镍颞蹑孽蘖"},
+	{"nin", "您"},
+	{"ning", "宁咛拧狞柠聍甯凝佞泞"},
+	{"niu", "妞牛忸扭狃纽钮"},
+	{"nong", "农侬哝浓脓弄"},
+	{"nou", "耨"},
+	{"nu", "奴孥驽努弩胬怒"},
+	{"nv", "女钕恧衄"},
+	{"nuan", "暖"},
+	{"nve", "疟虐"},
+	{"nuo", "挪傩诺喏搦锘懦糯"},
+	{"o", "喔噢哦"},
+	{"ou", "讴沤欧殴瓯鸥呕偶耦藕怄"},
+	{"pa", "趴啪葩杷爬琶筢帕怕"},
+	{"pai", "拍俳徘排牌哌派湃蒎"},
+	{"pan", "潘攀爿盘磐蹒蟠判拚泮叛盼畔袢襻"},
+	{"pang", "乓滂庞逄旁螃耪胖"},
+	{"pao", "抛脬刨咆庖狍袍匏跑泡炮疱"},
+	{"pei", "呸胚醅陪培赔锫裴沛佩帔旆配辔霈"},
+	{"pen", "喷盆湓"},
+	{"peng", "怦抨砰烹嘭澎朋堋彭棚硼蓬鹏膨蟛捧碰篷"},
+	{"pi", "丕批纰邳坯披砒铍劈噼霹皮芘枇毗疲蚍郫陴啤埤琵脾罴蜱貔鼙匹庀疋仳圮痞擗癖屁淠媲睥辟僻甓譬"},
+	{"pian", "偏犏篇翩骈胼蹁谝片骗"},
+	{"piao", "剽缥飘螵嫖瓢殍瞟票嘌漂氕撇瞥"},
+	{"pie", "丿苤"},
+	{"pin", "姘拼贫嫔频颦品榀牝聘"},
+	{"ping", "乒俜娉平评凭坪苹屏枰瓶萍鲆"},
+	{"po", "钋坡泊颇婆鄱皤叵钷笸迫珀破粕魄泼"},
+	{"pou", "剖掊裒"},
+	{"pu", "仆攴扑噗匍莆脯菩葡蒲璞濮镤朴圃浦普溥谱氆镨蹼铺瀑曝"},
+	{"qi", "七沏妻柒凄栖桤萋期欺嘁漆槭蹊亓祁齐圻岐芪其奇歧祈俟耆脐颀崎淇畦萁骐骑棋琦琪祺蛴旗綦蜞蕲鳍麒乞企屺岂芑启杞起绮綮气讫汔迄弃汽泣契砌葺碛器憩戚"},
+	{"qia", "掐葜恰洽髂"},
+	{"qian", "千仟阡扦芊迁佥岍钎牵悭铅谦愆签骞搴褰前钤虔钱钳掮箝潜黔凵浅肷遣谴缱欠芡茜倩堑嵌椠慊歉乾"},
+	{"qiang", "呛羌戕戗枪跄腔蜣锖锵镪丬强墙嫱蔷樯抢羟襁炝"},
+	{"qiao", "悄硗跷劁敲锹橇缲乔侨荞桥谯憔鞒樵瞧巧愀俏诮峭窍翘撬鞘"},
+	{"qie", "且切妾怯郄窃挈惬箧锲茄"},
+	{"qin", "亲侵钦衾芩芹秦琴禽勤嗪溱噙擒檎螓锓寝吣沁揿"},
+	{"qing", "青氢轻倾卿圊清蜻鲭情晴氰擎檠黥苘顷请庆箐磬罄謦"},
+	{"qiong", "芎邛穷穹茕筇琼蛩跫銎"},
+	{"qiu", "丘邱秋蚯楸鳅囚犰求虬泅俅酋逑球赇巯遒裘蝤鼽糗"},
+	{"qu", "区曲岖诎驱屈祛蛆躯蛐趋麴黢劬朐鸲渠蕖磲璩瞿蘧氍癯衢蠼取娶龋去阒觑趣"},
+	{"quan", "悛圈全权诠泉荃拳辁痊铨筌蜷醛鬈颧犬畎绻劝券犭"},
+	{"que", "缺阙瘸却悫雀确阕榷鹊炔"},
+	{"qun", "逡裙群"},
+	{"ran", "蚺然髯燃冉苒染"},
+	{"rang", "禳瓤穰嚷壤攘让"},
+	{"rao", "娆荛饶桡扰绕"},
+	{"re", "惹热"},
+	{"ren", "人亻仁壬忍荏稔刃认仞任纫妊轫韧饪衽葚"},
+	{"reng", "扔仍"},
+	{"ri", "日"},
+	{"rong", "茸戎肜狨绒荣容嵘溶蓉榕熔蝾融冗"},
+	{"rou", "柔揉糅蹂鞣肉"},
+	{"ru", "如茹铷儒嚅孺濡薷襦蠕颥汝乳辱入洳溽缛蓐褥"},
+	{"ruan", "阮朊软"},
+	{"rui", "蕤蕊芮枘蚋锐瑞睿"},
+	{"run", "闰润"},
+	{"ruo", "若偌弱箬"},
+	{"sa", "仨挲撒洒卅飒脎萨"},
+	{"sai", "塞腮噻鳃赛"},
+	{"san", "三叁毵伞糁馓霰散"},
+	{"sang", "桑嗓搡磉颡丧"},
+	{"sao", "搔骚缫臊鳋扫嫂埽瘙"},
+	{"se", "色涩啬铯瑟穑"},
+	{"sen", "森"},
+	{"seng", "僧"},
+	{"sha", "杀沙纱刹砂莎铩痧煞裟鲨傻唼啥歃霎"},
+	{"shai", "筛酾晒"},
+	{"shan", "山彡删杉芟姗苫衫钐埏珊舢跚煽潸膻闪陕讪汕疝剡扇善骟鄯缮嬗擅膳赡蟮鳝"},
+	{"shang", "伤殇商觞墒熵垧晌赏上尚绱裳"},
+	{"shao", "捎烧梢稍筲艄蛸勺芍苕韶少劭邵绍哨潲"},
+	{"she", "奢猞赊畲舌佘蛇舍厍设社射涉赦慑摄滠歙麝"},
+	{"shen", "申伸身呻绅诜娠砷莘深什甚神审哂矧谂婶渖肾胂渗慎椹蜃沈"},
+	{"sheng", "升生声牲笙甥绳省眚圣胜盛剩嵊"},
+	{"shi", "尸失师虱诗施狮湿蓍鲺十饣石时实炻蚀食埘莳鲥史矢豕使始驶屎士氏礻世仕市示似式事侍势视试饰室恃拭是柿贳适舐轼逝铈豉弑谥释嗜筮誓噬螫识拾匙"},
+	{"shou", "收手守首艏寿受狩兽售授绶瘦扌"},
+	{"shu", "书殳抒纾叔枢姝倏殊梳淑菽疏舒摅毹输蔬秫孰赎塾熟属暑黍署蜀鼠薯曙术戍束沭述树竖恕庶数腧墅漱澍"},
+	{"shua", "刷唰耍"},
+	{"shuai", "衰摔甩帅蟀"},
+	{"shuan", "闩拴栓涮"},
+	{"shuang", "双霜孀爽"},
+	{"shui", "谁水税睡氵"},
+	{"shun", "吮顺舜瞬"},
+	{"shuo", "说妁烁朔铄硕搠蒴槊"},
+	{"si", "厶纟丝司私咝思鸶斯缌蛳厮锶嘶撕澌死巳四寺汜兕姒祀泗饲驷笥耜嗣肆伺"},
+	{"song", "忪松凇崧淞菘嵩怂悚耸竦讼宋诵送颂"},
+	{"sou", "嗖搜溲馊飕锼艘螋叟嗾瞍擞薮嗽"},
+	{"su", "苏酥稣俗夙肃涑素速宿粟谡嗉塑愫溯僳蔌觫簌诉"},
+	{"suan", "狻酸蒜算"},
+	{"sui", "攵虽荽眭睢濉绥隋随髓岁祟谇遂碎隧燧穗邃"},
+	{"sun", "孙狲荪飧损笋隼榫"},
+	{"suo", "唆娑桫梭睃嗍羧蓑缩所唢索琐锁嗦"},
+	{"ta", "他它她趿铊塌溻塔獭鳎挞闼遢榻踏蹋"},
+	{"tai", "胎台邰抬苔炱跆鲐薹太汰态肽钛泰酞"},
+	{"tan", "坍贪摊滩瘫坛昙谈郯覃痰锬谭潭檀忐坦袒钽毯叹炭探碳弹"},
+	{"tang", "汤铴耥羰镗饧唐堂棠塘搪溏瑭樘膛糖螗螳醣帑倘淌傥躺烫趟"},
+	{"tao", "涛绦掏滔韬饕洮逃桃陶啕淘萄鼗讨套"},
+	{"te", "忑忒特铽慝"},
+	{"teng", "疼腾誊滕藤"},
+	{"ti", "剔梯锑踢荑绨啼提缇鹈题蹄醍体剃倜悌涕逖惕替裼嚏屉"},
+	{"tian", "天添田恬畋甜填阗忝殄腆舔掭"},
+	{"tiao", "佻挑祧条迢笤龆蜩髫鲦窕眺粜跳调"},
+	{"tie", "帖贴萜铁餮"},
+	{"ting", "厅汀听町烃廷亭庭莛停婷葶蜓霆挺梃艇"},
+	{"tong", "通嗵仝同佟彤茼桐砼铜童酮僮潼瞳统捅桶筒恸痛"},
+	{"tou", "偷亠头投骰钭透"},
+	{"tu", "凸秃突图徒荼途屠菟酴土吐钍兔堍涂"},
+	{"tuan", "湍团抟疃彖"},
+	{"tui", "推颓腿退煺蜕褪"},
+	{"tun", "吞暾屯饨豚臀氽"},
+	{"tuo", "乇托拖脱驮佗陀坨沱沲砣鸵跎酡橐鼍妥庹椭柝唾箨驼拓"},
+	{"wa", "挖洼娲蛙娃瓦佤袜腽哇"},
+	{"wai", "歪崴外"},
+	{"wan", "弯剜湾蜿豌丸纨芄完玩顽烷宛挽婉惋晚绾脘菀琬皖畹碗万腕"},
+	{"wang", "汪亡王网往罔惘辋魍妄忘旺望枉"},
+	{"wei", "危威偎萎逶隈葳微煨薇巍囗韦圩围帏沩违闱桅涠唯帷惟维嵬潍伟伪尾纬苇委炜玮洧娓诿猥痿艉韪鲔卫为未位味畏胃軎尉谓喂渭蔚慰魏猬"},
+	{"wen", "温瘟文纹玟闻蚊阌雯刎吻紊稳问汶璺"},
+	{"weng", "翁嗡蓊瓮蕹"},
+	{"wo", "挝倭涡莴窝蜗我沃肟卧幄握渥硪斡龌"},
+	{"wu", "乌圬污邬呜巫屋诬钨无毋吴吾芜唔浯梧蜈鼯五午仵妩庑忤怃武侮捂牾鹉舞兀勿戊阢坞杌芴迕物误悟晤焐婺痦骛雾寤鹜鋈务伍"},
+	{"xi", "夕兮吸汐希昔析穸郗唏奚浠牺悉惜欷淅烯硒菥晰犀稀粞翕舾溪皙锡僖熄熙蜥嘻嬉膝樨熹羲螅蟋醯曦鼷习席袭觋媳隰檄洗玺徙铣喜葸屣蓰禧戏系饩矽细阋舄隙禊西息"},
+	{"xia", "虾瞎匣侠狎峡柙狭硖遐暇瑕辖霞黠下吓夏罅厦"},
+	{"xian", "先纤氙祆籼莶掀跹酰锨鲜暹闲弦贤咸涎娴舷衔痫鹇嫌冼显险猃蚬筅跣藓燹县岘苋现线限宪陷馅羡献腺仙"},
+	{"xiang", "乡芗相香厢湘缃葙箱襄骧镶详庠祥翔享响饷飨想鲞向巷项象像橡蟓"},
+	{"xiao", "枭哓枵骁哮宵消绡逍萧硝销潇箫霄魈嚣崤淆小晓筱孝肖效校笑啸"},
+	{"xie", "些楔歇蝎协邪胁挟偕斜谐携勰撷缬鞋写泄泻绁卸屑械亵渫谢榍榭廨懈獬薤邂燮瀣蟹躞"},
+	{"xin", "心忻芯辛昕欣锌新歆薪馨鑫囟信衅忄"},
+	{"xing", "星惺猩腥刑行邢形陉型荥硎醒擤兴杏姓幸性荇悻"},
+	{"xiong", "凶兄匈汹胸雄熊"},
+	{"xiu", "休修咻庥羞鸺貅馐髹朽秀岫绣袖锈嗅溴"},
+	{"xu", "吁戌盱胥须顼虚嘘墟需徐许诩栩糈醑旭序叙恤洫勖绪续酗婿溆絮煦蓄蓿"},
+	{"xuan", "轩宣谖喧揎萱暄煊儇玄痃悬旋漩璇选癣泫炫绚眩铉渲楦碹镟"},
+	{"xue", "削靴薛穴学泶踅雪鳕血谑"},
+	{"xun", "勋埙熏窨獯薰曛醺寻旬巡驯询峋恂洵浔荀荨循鲟讯汛迅徇逊殉巽蕈训"},
+	{"ya", "丫压吖押垭鸦桠鸭牙伢岈芽琊蚜崖涯睚衙哑痖雅亚讶迓娅砑氩揠呀"},
+	{"yan", "恹烟胭崦淹焉菸阉湮腌鄢嫣讠延严妍芫言岩沿炎研盐阎筵蜒颜檐兖奄俨衍偃厣掩眼郾琰罨演魇鼹厌闫咽彦砚唁宴晏艳验谚堰焰焱雁滟酽谳餍燕赝"},
+	{"yang", "央泱殃秧鸯鞅扬羊阳杨炀佯疡徉洋烊蛘仰养氧痒怏恙样漾"},
+	{"yao", "幺夭吆妖腰邀爻尧肴姚轺珧窑谣徭摇遥瑶繇鳐杳咬窈舀崾药要钥鹞曜耀"},
+	{"ye", "掖椰噎耶揶铘也冶野业叶曳页邺夜晔烨液谒腋靥爷"},
+	{"yi", "一伊衣医依咿猗铱壹揖欹漪噫黟仪圯夷沂诒怡迤饴咦姨贻眙胰痍移遗颐疑嶷彝乙已以钇矣苡舣蚁倚酏椅旖义亿弋刈忆艺议亦屹异佚呓役抑译邑佾峄怿易绎诣驿奕弈疫羿轶悒挹益谊埸翊翌逸意溢缢肄裔瘗蜴毅熠镒劓殪薏翳翼臆癔镱懿衤宜"},
+	{"yin", "因阴姻洇茵荫音殷氤铟喑堙吟垠狺寅淫银鄞夤霪廴尹引吲饮蚓隐瘾印茚胤"},
+	{"ying", "应英莺婴瑛嘤撄缨罂樱璎鹦膺鹰迎茔盈荧莹萤营萦楹滢蓥潆嬴赢瀛郢颍颖影瘿映硬媵蝇"},
+	{"yo", "哟唷"},
+	{"yong", "佣拥痈邕庸雍墉慵壅镛臃鳙饔喁永甬咏泳俑勇涌恿蛹踊用"},
+	{"you", "优忧攸呦幽悠尢尤由犹邮油疣莜莸铀蚰游鱿猷蝣有卣酉莠铕牖黝又右幼佑侑囿宥柚诱蚴釉鼬友"},
+	{"yu", "纡迂淤瘀于余妤欤於盂臾鱼俞禺竽舁娱狳谀馀渔萸隅雩嵛愉揄渝腴逾愚榆瑜虞觎窬舆蝓与予伛宇屿羽雨俣禹语圄圉庾瘐窳龉肀玉驭聿芋妪饫育郁昱狱峪浴钰预域欲谕阈喻寓御裕遇鹆愈煜蓣誉毓蜮豫燠鹬鬻"},
+	{"yuan", "鸢冤眢鸳渊箢元员园沅垣爰原圆袁援缘鼋塬源猿辕橼螈远苑怨院垸媛掾瑗愿"},
+	{"yue", "曰约月刖岳悦钺阅跃粤越樾龠瀹"},
+	{"yun", "晕氲云匀纭芸昀郧耘筠允狁陨殒孕运郓恽酝愠韫韵熨蕴"},
+	{"za", "匝咂拶杂砸咋"},
+	{"zai", "灾甾哉栽宰崽再在载"},
+	{"zan", "糌簪咱昝攒趱暂赞錾瓒"},
+	{"zang", "赃臧驵奘脏葬"},
+	{"zao", "遭糟凿早枣蚤澡藻灶皂唣造噪燥躁"},
+	{"ze", "则择泽责迮啧帻笮舴箦赜仄昃"},
+	{"zei", "贼"},
+	{"zen", "怎谮"},
+	{"zeng", "增憎缯罾锃甑赠"},
+	{"zha", "扎吒哳喳揸渣楂齄札闸铡眨砟乍诈咤柞栅炸痄蚱榨轧"},
+	{"zhai", "斋摘宅窄债砦寨瘵"},
+	{"zhan", "沾毡旃粘詹谵瞻斩展盏崭搌占战栈站绽湛蘸"},
+	{"zhang", "张章鄣嫜彰漳獐樟璋蟑仉涨掌丈仗帐杖胀账障嶂幛瘴"},
+	{"zhao", "钊招昭啁爪找沼召兆诏赵笊棹照罩肇"},
+	{"zhe", "蜇遮折哲辄蛰谪摺磔辙者锗赭褶这柘浙鹧着蔗"},
+	{"zhen", "贞针侦浈珍胗桢真砧祯斟甄蓁榛箴臻诊枕轸畛疹缜稹圳阵鸩振朕赈镇震"},
+	{"zheng", "争征怔诤峥挣狰钲睁铮筝蒸徵拯整正证郑帧政症"},
+	{"zhi", "之支卮汁芝吱枝知织肢栀祗胝脂蜘执侄直值埴职植殖絷跖摭踯夂止只旨址纸芷祉咫指枳轵趾黹酯至志忮豸制帙帜治炙质郅峙栉陟挚桎秩致贽轾掷痔窒鸷彘智滞痣蛭骘稚置雉膣觯踬"},
+	{"zhong", "中忠终盅钟舯衷锺螽肿种冢踵仲众重"},
+	{"zhou", "州舟诌周洲粥妯轴肘纣咒宙绉昼胄荮皱酎骤籀帚"},
+	{"zhu", "朱侏诛邾洙茱株珠诸猪铢蛛槠潴橥竹竺烛逐舳瘃躅丶主拄渚煮嘱麈瞩伫住助苎杼注贮驻柱炷祝疰蛀筑铸箸翥著"},
+	{"zhua", "抓"},
+	{"zhuai", "拽"},
+	{"zhuan", "专砖颛转啭赚撰篆馔"},
+	{"zhuang", "妆庄桩装壮状撞"},
+	{"zhui", "隹追骓锥坠惴缒赘缀"},
+	{"zhun", "肫窀谆准"},
+	{"zhuo", "卓拙倬捉桌涿灼茁斫浊浞诼酌啄禚擢濯镯"},
+	{"zi", "孜兹咨姿赀资淄缁谘孳嵫滋粢辎觜訾趑锱龇髭鲻仔姊秭籽耔笫梓紫滓字自恣渍眦子"},
+	{"zong", "宗综棕腙踪鬃总偬纵粽"},
+	{"zou", "邹驺诹陬鄹鲰走奏揍楱"},
+	{"zu", "租足卒族镞诅阻组俎祖"},
+	{"zuan", "钻躜缵纂攥"},
+	{"zui", "嘴最罪蕞醉"},
+	{"zun", "尊遵樽鳟撙"},
+	{"zuo", "昨琢左佐作坐阼怍祚胙唑座做"},
+}
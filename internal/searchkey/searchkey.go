@@ -0,0 +1,157 @@
+// Package searchkey 生成元件搜索用的预计算键：汉字的全拼与拼音首字母、型号的三元组，
+// 以及型号容错匹配使用的编辑距离。
+package searchkey
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+var pinyinIndex = sync.OnceValue(func() map[rune]string {
+	index := make(map[rune]string, 6800)
+	for _, entry := range pinyinTable {
+		for _, r := range entry.chars {
+			index[r] = entry.syllable
+		}
+	}
+	return index
+})
+
+// Pinyin 返回汉字的拼音（不带声调），不在 GB2312 范围内的字返回 false
+func Pinyin(r rune) (string, bool) {
+	syllable, ok := pinyinIndex()[r]
+	return syllable, ok
+}
+
+// Normalize 转小写并只保留字母与数字，用于型号比较（"RC0603FR-07" → "rc0603fr07"）
+func Normalize(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// IsPartNumber 判断关键词是否形似型号：规范化后至少 5 个字符，且同时含字母与数字
+func IsPartNumber(s string) bool {
+	normalized := Normalize(s)
+	if len([]rune(normalized)) < 5 {
+		return false
+	}
+	return strings.ContainsFunc(normalized, unicode.IsLetter) && strings.ContainsFunc(normalized, unicode.IsDigit)
+}
+
+// MaxDistance 返回型号容错匹配允许的编辑距离：8 个字符及以上 2，其余 1
+func MaxDistance(query string) int {
+	if len([]rune(Normalize(query))) >= 8 {
+		return 2
+	}
+	return 1
+}
+
+// Trigrams 返回规范化后的三元组（去重，按出现顺序），不足 3 个字符时返回规范化结果本身
+func Trigrams(s string) []string {
+	runes := []rune(Normalize(s))
+	if len(runes) == 0 {
+		return nil
+	}
+	if len(runes) < 3 {
+		return []string{string(runes)}
+	}
+	var grams []string
+	for i := 0; i+3 <= len(runes); i++ {
+		gram := string(runes[i : i+3])
+		if !slices.Contains(grams, gram) {
+			grams = append(grams, gram)
+		}
+	}
+	return grams
+}
+
+// SubstringDistance 返回 query 与 text 中最相近子串的编辑距离（均先规范化），
+// 用于在较长的型号中查找带错字的片段，如 "STM32F10C8" 与 "STM32F103C8T6" 距离为 1
+func SubstringDistance(query, text string) int {
+	q, t := []rune(Normalize(query)), []rune(Normalize(text))
+	// prev[j]：query 前 i 个字符匹配到以 text[j-1] 结尾的子串的最小代价，起点不计代价
+	prev := make([]int, len(t)+1)
+	curr := make([]int, len(t)+1)
+	for i := 1; i <= len(q); i++ {
+		curr[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if q[i-1] == t[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j-1]+cost, prev[j]+1, curr[j-1]+1)
+		}
+		prev, curr = curr, prev
+	}
+	if len(q) == 0 {
+		return 0
+	}
+	return slices.Min(prev)
+}
+
+// PartNumberWords 返回文本中形似型号的词（按空白与中文拆分），如 "ESP32 模块" → ["ESP32"]
+func PartNumberWords(s string) []string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.Is(unicode.Han, r) || r == ',' || r == '，' || r == '/'
+	})
+	return slices.DeleteFunc(words, func(word string) bool { return !IsPartNumber(word) })
+}
+
+// PinyinKeys 返回文本中含汉字片段的全拼与首字母：按非字母数字拆分片段，片段内字母数字转小写保留，
+// 如 "贴片电阻 ESP32模块" → ["tiepiandianzu", "tpdz", "esp32mokuai", "esp32mk"]
+func PinyinKeys(s string) []string {
+	var keys []string
+	segments := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, segment := range segments {
+		var full, initials strings.Builder
+		hasHan := false
+		for _, r := range segment {
+			if syllable, ok := Pinyin(r); ok {
+				hasHan = true
+				full.WriteString(syllable)
+				initials.WriteByte(syllable[0])
+				continue
+			}
+			if r < unicode.MaxASCII {
+				lower := unicode.ToLower(r)
+				full.WriteRune(lower)
+				initials.WriteRune(lower)
+			}
+		}
+		if hasHan {
+			keys = append(keys, full.String(), initials.String())
+		}
+	}
+	return keys
+}
+
+// Build 生成元件的搜索键：名称与描述的全拼、拼音首字母，型号、供应商料号与名称中型号词的三元组，空格分隔
+func Build(name, model, supplierPartNumber, description string) string {
+	var keys []string
+	add := func(key string) {
+		if key != "" && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range PinyinKeys(name) {
+		add(key)
+	}
+	for _, key := range PinyinKeys(description) {
+		add(key)
+	}
+	for _, text := range append([]string{model, supplierPartNumber}, PartNumberWords(name)...) {
+		for _, gram := range Trigrams(text) {
+			add(gram)
+		}
+	}
+	return strings.Join(keys, " ")
+}
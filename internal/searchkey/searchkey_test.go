@@ -0,0 +1,70 @@
+package searchkey
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPinyinKeys(t *testing.T) {
+	cases := []struct {
+		text string
+		want []string
+	}{
+		{"贴片电阻", []string{"tiepiandianzu", "tpdz"}},
+		{"钽电容", []string{"tandianrong", "tdr"}},
+		{"ESP32模块", []string{"esp32mokuai", "esp32mk"}},
+		{"伺服电机 / 绿色LED", []string{"sifudianji", "sfdj", "lvseled", "lsled"}},
+		{"RC0603FR-0710KL", nil},
+	}
+	for _, tc := range cases {
+		got := PinyinKeys(tc.text)
+		if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+			t.Fatalf("PinyinKeys(%q) = %v, want %v", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestPinyinTableCoversGB2312(t *testing.T) {
+	seen := make(map[rune]bool)
+	for _, entry := range pinyinTable {
+		for _, r := range entry.chars {
+			if seen[r] {
+				t.Fatalf("%c listed twice", r)
+			}
+			seen[r] = true
+		}
+	}
+	if len(seen) != 6763 {
+		t.Fatalf("table has %d characters, want 6763", len(seen))
+	}
+}
+
+func TestSubstringDistance(t *testing.T) {
+	cases := []struct {
+		query, text string
+		want        int
+	}{
+		{"STM32F10C8", "STM32F103C8T6", 1},
+		{"stm32f103", "STM32F103C8T6", 0},
+		{"RC0603FR0710K", "RC0603FR-0710KL", 0},
+		{"AMS1171", "AMS1117-3.3", 1},
+		{"ESP32", "LM7805", 5},
+	}
+	for _, tc := range cases {
+		if got := SubstringDistance(tc.query, tc.text); got != tc.want {
+			t.Fatalf("SubstringDistance(%q, %q) = %d, want %d", tc.query, tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	keys := Build("ESP32 模块", "ESP32-WROOM", "C82899", "无线模组")
+	for _, want := range []string{"mokuai", "mk", "wuxianmozu", "wxmz", "esp", "p32", "2wr", "c82", "899"} {
+		if !strings.Contains(" "+keys+" ", " "+want+" ") {
+			t.Fatalf("Build() = %q, missing %q", keys, want)
+		}
+	}
+	if IsPartNumber("10k") || IsPartNumber("模块") || !IsPartNumber("STM32F10C8") {
+		t.Fatalf("IsPartNumber misclassified")
+	}
+}
@@ -10,7 +10,10 @@ const Dashboard = lazy(() => import('./pages/Dashboard'));
 const Components = lazy(() => import('./pages/Components'));
 const PreStocks = lazy(() => import('./pages/PreStocks'));
 const Categories = lazy(() => import('./pages/Categories'));
+const Suppliers = lazy(() => import('./pages/Suppliers'));
 const StockLogs = lazy(() => import('./pages/StockLogs'));
+const DeadStock = lazy(() => import('./pages/DeadStock'));
+const Backup = lazy(() => import('./pages/Backup'));
 
 function PageLoader() {
   return (
@@ -37,7 +40,10 @@ function App() {
                       <Route path="/components" element={<Components />} />
                       <Route path="/pre-stocks" element={<PreStocks />} />
                       <Route path="/categories" element={<Categories />} />
+                      <Route path="/suppliers" element={<Suppliers />} />
                       <Route path="/logs" element={<StockLogs />} />
+                      <Route path="/dead-stock" element={<DeadStock />} />
+                      <Route path="/backup" element={<Backup />} />
                     </Routes>
                   </Layout>
                 </ProtectedRoute>
@@ -16,6 +16,7 @@ client.interceptors.response.use(
       status === 401 &&
       !url.includes('/auth/me') &&
       !url.includes('/auth/login') &&
+      !url.includes('/auth/2fa') &&
       window.location.pathname !== '/login'
     ) {
       window.location.href = '/login';
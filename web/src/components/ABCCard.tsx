@@ -0,0 +1,119 @@
+import { useCallback, useEffect, useState } from 'react';
+import { Layers, Loader2, RefreshCw } from 'lucide-react';
+import { Link } from 'react-router-dom';
+import { toast } from 'react-hot-toast';
+import { Card, CardContent, CardHeader, CardTitle } from './ui/Card';
+import { Button } from './ui/Button';
+import client from '../api/client';
+import { type ABCSummary } from '../types';
+import { formatCents } from '../utils/price';
+
+const CLASS_STYLES: Record<string, string> = {
+  A: 'bg-red-100 text-red-700',
+  B: 'bg-yellow-100 text-yellow-800',
+  C: 'bg-muted text-muted-foreground',
+};
+
+export function ABCCard() {
+  const [summary, setSummary] = useState<ABCSummary | null>(null);
+  const [loading, setLoading] = useState(true);
+  const [recomputing, setRecomputing] = useState(false);
+
+  const fetchSummary = useCallback(async () => {
+    setLoading(true);
+    try {
+      const res = await client.get<{ data: ABCSummary }>('/stats/abc');
+      setSummary(res.data.data);
+    } catch (error) {
+      const err = error as { response?: { data?: { error?: string } } };
+      toast.error(err.response?.data?.error || '获取 ABC 分类统计失败');
+      setSummary(null);
+    } finally {
+      setLoading(false);
+    }
+  }, []);
+
+  useEffect(() => {
+    fetchSummary();
+  }, [fetchSummary]);
+
+  const handleRecompute = async () => {
+    setRecomputing(true);
+    try {
+      await client.post('/stats/abc/recompute');
+      toast.success('已重新分类');
+      await fetchSummary();
+    } catch (error) {
+      const err = error as { response?: { data?: { error?: string } } };
+      toast.error(err.response?.data?.error || '计算 ABC 分类失败');
+    } finally {
+      setRecomputing(false);
+    }
+  };
+
+  const params = summary?.params;
+
+  return (
+    <Card>
+      <CardHeader className="flex flex-row items-center justify-between space-y-0 pb-2">
+        <CardTitle className="text-sm font-medium">ABC 分类</CardTitle>
+        <Layers className="h-4 w-4 text-muted-foreground" />
+      </CardHeader>
+      <CardContent className="space-y-4">
+        <div className="flex flex-wrap items-center justify-between gap-2">
+          {params && (
+            <div className="text-xs text-muted-foreground">
+              按最近 {params.window_days} 天{params.metric === 'movements' ? '出库次数' : '出库金额'}累计占比：
+              前 {params.threshold_a}% 为 A 类，{params.threshold_a}%–{params.threshold_b}% 为 B 类，其余为 C 类
+            </div>
+          )}
+          <Button size="sm" variant="outline" onClick={handleRecompute} disabled={recomputing}>
+            {recomputing ? <Loader2 className="mr-2 h-4 w-4 animate-spin" /> : <RefreshCw className="mr-2 h-4 w-4" />}
+            重新分类
+          </Button>
+        </div>
+
+        {loading && <div className="text-sm text-muted-foreground">加载中...</div>}
+        {!loading && summary && (
+          <div className="overflow-x-auto">
+            <table className="w-full text-sm">
+              <thead>
+                <tr className="border-b text-muted-foreground">
+                  <th className="py-2 text-left font-medium">分类</th>
+                  <th className="py-2 text-right font-medium">元件数</th>
+                  <th className="py-2 text-right font-medium">出库金额</th>
+                  <th className="py-2 text-right font-medium">出库次数</th>
+                  <th className="py-2 text-right font-medium">占比</th>
+                  <th className="py-2 text-right font-medium">库存价值</th>
+                </tr>
+              </thead>
+              <tbody>
+                {summary.classes.map(item => (
+                  <tr key={item.class || 'none'} className="border-b last:border-0">
+                    <td className="py-2">
+                      {item.class ? (
+                        <Link to={`/components?abc_class=${item.class}`} className="inline-flex items-center gap-2 hover:underline">
+                          <span className={`inline-flex h-6 w-6 items-center justify-center rounded text-xs font-semibold ${CLASS_STYLES[item.class]}`}>
+                            {item.class}
+                          </span>
+                          查看元件
+                        </Link>
+                      ) : (
+                        <span className="text-muted-foreground">未分类</span>
+                      )}
+                    </td>
+                    <td className="py-2 text-right">{item.component_count}</td>
+                    <td className="py-2 text-right">{formatCents(item.consumption_cents)}</td>
+                    <td className="py-2 text-right">{item.movements}</td>
+                    <td className="py-2 text-right">{item.share.toFixed(1)}%</td>
+                    <td className="py-2 text-right">{formatCents(item.stock_value_cents)}</td>
+                  </tr>
+                ))}
+              </tbody>
+            </table>
+          </div>
+        )}
+      </CardContent>
+    </Card>
+  );
+}
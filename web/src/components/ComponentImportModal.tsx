@@ -0,0 +1,189 @@
+import { useState } from 'react';
+import { Loader2 } from 'lucide-react';
+import { toast } from 'react-hot-toast';
+import client from '../api/client';
+import { type ComponentImportColumn, type ComponentImportReport } from '../types';
+import { Button } from './ui/Button';
+import { Input } from './ui/Input';
+import { Label } from './ui/Label';
+import { Modal } from './ui/Modal';
+import { cn } from '../utils/cn';
+
+type ComponentImportModalProps = {
+  isOpen: boolean;
+  onClose: () => void;
+  onSuccess: () => void;
+};
+
+const IMPORT_COLUMN_LABELS: Record<string, string> = {
+  component_number: '系统编号',
+  name: '名称',
+  model: '厂家型号',
+  manufacturer: '制造商',
+  value: '参数',
+  package: '封装',
+  description: '描述',
+  category: '分类',
+  stock_quantity: '库存数量',
+  unit_price: '参考单价',
+  location: '存放位置',
+  supplier: '供应商',
+  supplier_part_number: '供应商料号',
+  datasheet_url: '数据手册',
+};
+
+const ACTION_LABELS = {
+  create: { text: '新建', className: 'text-green-600' },
+  update: { text: '更新', className: 'text-blue-600' },
+  error: { text: '失败', className: 'text-destructive' },
+};
+
+const selectClassName = 'h-8 w-full rounded-md border border-input bg-background px-2 text-sm';
+
+type ImportResponse = { data?: ComponentImportReport; columns?: ComponentImportColumn[]; error?: string };
+
+export function ComponentImportModal({ isOpen, onClose, onSuccess }: ComponentImportModalProps) {
+  const [file, setFile] = useState<File | null>(null);
+  const [columns, setColumns] = useState<ComponentImportColumn[]>([]);
+  const [report, setReport] = useState<ComponentImportReport | null>(null);
+  const [submitting, setSubmitting] = useState<'check' | 'import' | null>(null);
+
+  const reset = () => {
+    setFile(null);
+    setColumns([]);
+    setReport(null);
+  };
+
+  const handleClose = () => {
+    reset();
+    onClose();
+  };
+
+  const submit = async (dryRun: boolean) => {
+    if (!file) return toast.error('请选择 CSV 或 XLSX 文件');
+
+    const formData = new FormData();
+    formData.append('file', file);
+    formData.append('dry_run', String(dryRun));
+    if (columns.length > 0) {
+      formData.append('mapping', JSON.stringify(Object.fromEntries(columns.map(item => [item.header, item.column]))));
+    }
+
+    setSubmitting(dryRun ? 'check' : 'import');
+    try {
+      const res = await client.post('/components/import', formData, {
+        headers: { 'Content-Type': 'multipart/form-data' },
+      });
+      const body: ImportResponse = res.data;
+      setColumns(body.columns || []);
+      setReport(body.data || null);
+      if (!dryRun) {
+        toast.success(`导入完成：新建 ${body.data?.created ?? 0} 个，更新 ${body.data?.updated ?? 0} 个`);
+        onSuccess();
+        handleClose();
+      }
+    } catch (error) {
+      const err = error as { response?: { data?: ImportResponse } };
+      const body = err.response?.data;
+      if (body?.columns) setColumns(body.columns);
+      if (body?.data) setReport(body.data);
+      toast.error(body?.error || '导入失败');
+    } finally {
+      setSubmitting(null);
+    }
+  };
+
+  const updateColumn = (header: string, column: string) => {
+    setColumns(prev => prev.map(item => (item.header === header ? { ...item, column } : item)));
+    setReport(null);
+  };
+
+  const canImport = report !== null && report.dry_run && report.failed === 0 && report.total > 0;
+
+  return (
+    <Modal
+      isOpen={isOpen}
+      onClose={handleClose}
+      title="导入元件"
+      className="max-w-3xl"
+      footer={
+        <>
+          <Button variant="outline" onClick={handleClose}>取消</Button>
+          <Button variant="outline" onClick={() => submit(true)} disabled={!file || submitting !== null}>
+            {submitting === 'check' && <Loader2 className="mr-2 h-4 w-4 animate-spin" />}
+            校验
+          </Button>
+          <Button onClick={() => submit(false)} disabled={!canImport || submitting !== null}>
+            {submitting === 'import' && <Loader2 className="mr-2 h-4 w-4 animate-spin" />}
+            导入
+          </Button>
+        </>
+      }
+    >
+      <div className="max-h-[65vh] space-y-4 overflow-auto text-sm">
+        <div className="space-y-2">
+          <Label htmlFor="import-file">CSV / XLSX 文件</Label>
+          <Input
+            id="import-file"
+            type="file"
+            accept=".csv,.xlsx"
+            onChange={e => {
+              reset();
+              setFile(e.target.files?.[0] ?? null);
+            }}
+          />
+          <p className="text-xs text-muted-foreground">
+            表头按导出列名自动识别；系统编号匹配已有元件时更新（空单元格保留原值），否则新建。分类和供应商按名称匹配，不存在时自动创建。先校验，全部通过后才能导入。
+          </p>
+        </div>
+
+        {columns.length > 0 && (
+          <div className="space-y-2">
+            <Label>列映射</Label>
+            <div className="grid grid-cols-1 gap-2 md:grid-cols-2">
+              {columns.map(item => (
+                <div key={item.header} className="flex items-center gap-2">
+                  <span className="w-28 shrink-0 truncate" title={item.header}>{item.header || '（空表头）'}</span>
+                  <select className={selectClassName} value={item.column} onChange={e => updateColumn(item.header, e.target.value)}>
+                    <option value="">忽略</option>
+                    {Object.entries(IMPORT_COLUMN_LABELS).map(([key, label]) => (
+                      <option key={key} value={key}>{label}</option>
+                    ))}
+                  </select>
+                </div>
+              ))}
+            </div>
+          </div>
+        )}
+
+        {report && (
+          <div className="space-y-2">
+            <div>
+              共 {report.total} 行：新建 {report.created}，更新 {report.updated}，
+              <span className={cn(report.failed > 0 && 'text-destructive')}>失败 {report.failed}</span>
+              {report.created_categories.length > 0 && <span>；将新建分类 {report.created_categories.join('、')}</span>}
+              {report.created_suppliers.length > 0 && <span>；将新建供应商 {report.created_suppliers.join('、')}</span>}
+            </div>
+            <div className="divide-y rounded-md border">
+              {report.rows.map(row => (
+                <div key={row.line} className="flex gap-3 px-3 py-2">
+                  <span className="w-14 shrink-0 text-muted-foreground">第 {row.line} 行</span>
+                  <span className={cn('w-10 shrink-0', ACTION_LABELS[row.action].className)}>{ACTION_LABELS[row.action].text}</span>
+                  <div className="min-w-0 flex-1">
+                    <div className="truncate">{[row.component_number, row.name].filter(Boolean).join(' · ')}</div>
+                    {row.errors?.map(message => (
+                      <div key={message} className="text-xs text-destructive">{message}</div>
+                    ))}
+                    {row.warnings?.map(message => (
+                      <div key={message} className="text-xs text-amber-600">{message}</div>
+                    ))}
+                  </div>
+                </div>
+              ))}
+            </div>
+          </div>
+        )}
+      </div>
+    </Modal>
+  );
+}
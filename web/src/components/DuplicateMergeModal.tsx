@@ -0,0 +1,121 @@
+import { useCallback, useEffect, useState } from 'react';
+import { Loader2 } from 'lucide-react';
+import { toast } from 'react-hot-toast';
+import client from '../api/client';
+import { type Component, type DuplicateCriteria, type DuplicateGroup } from '../types';
+import { Button } from './ui/Button';
+import { Modal } from './ui/Modal';
+import { formatMicro } from '../utils/price';
+
+type DuplicateMergeModalProps = {
+  isOpen: boolean;
+  onClose: () => void;
+  onSuccess: () => void;
+};
+
+const CRITERIA_LABELS: Record<DuplicateCriteria, string> = {
+  supplier_part_number: '供应商料号相同',
+  model_manufacturer: '厂家型号与制造商相同',
+  value_package: '参数值与封装相同',
+};
+
+function groupKey(group: DuplicateGroup): string {
+  return `${group.by}:${group.key}`;
+}
+
+function componentLabel(component: Component): string {
+  return [component.component_number, component.name, component.model].filter(Boolean).join(' · ');
+}
+
+export function DuplicateMergeModal({ isOpen, onClose, onSuccess }: DuplicateMergeModalProps) {
+  const [groups, setGroups] = useState<DuplicateGroup[]>([]);
+  const [loading, setLoading] = useState(false);
+  const [targets, setTargets] = useState<Record<string, number>>({});
+  const [mergingKey, setMergingKey] = useState<string | null>(null);
+
+  const loadGroups = useCallback(async () => {
+    setLoading(true);
+    try {
+      const res = await client.get('/components/duplicates');
+      const data: DuplicateGroup[] = res.data.data || [];
+      setGroups(data);
+      setTargets(Object.fromEntries(data.map(group => [groupKey(group), group.components[0].id])));
+    } catch {
+      toast.error('查找重复元件失败');
+    } finally {
+      setLoading(false);
+    }
+  }, []);
+
+  useEffect(() => {
+    if (isOpen) void loadGroups();
+  }, [isOpen, loadGroups]);
+
+  const handleMerge = async (group: DuplicateGroup) => {
+    const key = groupKey(group);
+    const targetId = targets[key];
+    const sourceIds = group.components.map(component => component.id).filter(id => id !== targetId);
+    if (!confirm(`确定将 ${sourceIds.length} 个元件合并到所选元件吗？被合并的元件将被删除。`)) return;
+
+    setMergingKey(key);
+    try {
+      await client.post('/components/merge', { target_id: targetId, source_ids: sourceIds });
+      toast.success('合并成功');
+      onSuccess();
+      await loadGroups();
+    } catch (error) {
+      const err = error as { response?: { data?: { error?: string } } };
+      toast.error(err.response?.data?.error || '合并失败');
+    } finally {
+      setMergingKey(null);
+    }
+  };
+
+  return (
+    <Modal isOpen={isOpen} onClose={onClose} title="查找重复元件" className="max-w-3xl">
+      <div className="max-h-[60vh] space-y-4 overflow-auto text-sm">
+        {loading ? (
+          <div className="flex items-center justify-center py-8 text-muted-foreground">
+            <Loader2 className="mr-2 h-4 w-4 animate-spin" /> 查找中...
+          </div>
+        ) : groups.length === 0 ? (
+          <div className="py-8 text-center text-muted-foreground">未发现疑似重复的元件</div>
+        ) : (
+          groups.map(group => {
+            const key = groupKey(group);
+            return (
+              <div key={key} className="space-y-2 rounded-lg border p-3">
+                <div className="flex items-center justify-between gap-2">
+                  <span className="font-medium">{CRITERIA_LABELS[group.by]}</span>
+                  <Button size="sm" onClick={() => handleMerge(group)} disabled={mergingKey !== null}>
+                    {mergingKey === key && <Loader2 className="mr-2 h-4 w-4 animate-spin" />}
+                    合并到所选
+                  </Button>
+                </div>
+                {group.components.map(component => (
+                  <label key={component.id} className="flex items-center gap-2">
+                    <input
+                      type="radio"
+                      name={key}
+                      checked={targets[key] === component.id}
+                      onChange={() => setTargets(prev => ({ ...prev, [key]: component.id }))}
+                      className="h-4 w-4"
+                    />
+                    <span className="min-w-0 flex-1 truncate">{componentLabel(component)}</span>
+                    <span className="shrink-0 text-muted-foreground">
+                      库存 {component.stock_quantity}
+                      {component.unit_price_micro ? ` · ¥${formatMicro(component.unit_price_micro)}` : ''}
+                    </span>
+                  </label>
+                ))}
+              </div>
+            );
+          })
+        )}
+      </div>
+      <p className="mt-3 text-xs text-muted-foreground">
+        合并后保留所选元件的编号，库存相加并按加权平均重算参考单价，库存记录与预入库关联转移到保留的元件。
+      </p>
+    </Modal>
+  );
+}
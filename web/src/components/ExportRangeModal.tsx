@@ -0,0 +1,94 @@
+import { useState } from 'react';
+import { Download, Loader2 } from 'lucide-react';
+import { toast } from 'react-hot-toast';
+import { Button } from './ui/Button';
+import { Input } from './ui/Input';
+import { Label } from './ui/Label';
+import { Modal } from './ui/Modal';
+import { downloadExport, EXPORT_FORMAT_OPTIONS, type ExportFormat } from '../utils/download';
+
+type ExportRangeModalProps = {
+  isOpen: boolean;
+  onClose: () => void;
+  title: string;
+  path: string;
+  fallbackFilename: string;
+  description?: string;
+  // 页面当前筛选条件，随导出一起提交
+  params?: Record<string, string | undefined>;
+};
+
+const selectClassName = 'h-9 w-full rounded-md border border-input bg-background px-2 text-sm';
+
+export function ExportRangeModal({ isOpen, onClose, title, path, fallbackFilename, description, params }: ExportRangeModalProps) {
+  const [format, setFormat] = useState<ExportFormat>('csv');
+  const [from, setFrom] = useState('');
+  const [to, setTo] = useState('');
+  const [exporting, setExporting] = useState(false);
+
+  const handleExport = async () => {
+    if (from && to && from > to) return toast.error('开始日期不能晚于结束日期');
+
+    const search = new URLSearchParams({ format });
+    if (from) search.set('from', from);
+    if (to) search.set('to', to);
+    Object.entries(params ?? {}).forEach(([key, value]) => {
+      if (value) search.set(key, value);
+    });
+
+    setExporting(true);
+    try {
+      await downloadExport(path, search, `${fallbackFilename}.${format}`);
+      toast.success('导出成功');
+      onClose();
+    } catch (error) {
+      toast.error(error instanceof Error ? error.message : '导出失败');
+    } finally {
+      setExporting(false);
+    }
+  };
+
+  return (
+    <Modal
+      isOpen={isOpen}
+      onClose={() => !exporting && onClose()}
+      title={title}
+      footer={
+        <>
+          <Button variant="outline" onClick={onClose} disabled={exporting}>取消</Button>
+          <Button onClick={handleExport} disabled={exporting}>
+            {exporting ? <Loader2 className="mr-2 h-4 w-4 animate-spin" /> : <Download className="mr-2 h-4 w-4" />}
+            确认导出
+          </Button>
+        </>
+      }
+    >
+      <div className="space-y-4 text-sm">
+        {description && <p className="text-muted-foreground">{description}</p>}
+        <div className="grid grid-cols-2 gap-4">
+          <div className="space-y-2">
+            <Label htmlFor="export-from">开始日期</Label>
+            <Input id="export-from" type="date" value={from} onChange={e => setFrom(e.target.value)} />
+          </div>
+          <div className="space-y-2">
+            <Label htmlFor="export-to">结束日期（含当天）</Label>
+            <Input id="export-to" type="date" value={to} onChange={e => setTo(e.target.value)} />
+          </div>
+        </div>
+        <div className="space-y-2">
+          <Label htmlFor="export-range-format">文件格式</Label>
+          <select
+            id="export-range-format"
+            className={selectClassName}
+            value={format}
+            onChange={e => setFormat(e.target.value as ExportFormat)}
+          >
+            {EXPORT_FORMAT_OPTIONS.map(option => (
+              <option key={option.value} value={option.value}>{option.label}</option>
+            ))}
+          </select>
+        </div>
+      </div>
+    </Modal>
+  );
+}
@@ -0,0 +1,116 @@
+import { useCallback, useEffect, useState } from 'react';
+import { Loader2, RefreshCw, Timer } from 'lucide-react';
+import { toast } from 'react-hot-toast';
+import { Card, CardContent, CardHeader, CardTitle } from './ui/Card';
+import { Button } from './ui/Button';
+import client from '../api/client';
+import { type ComponentForecastItem, type ForecastParams } from '../types';
+
+const selectClass = 'h-9 rounded-md border border-input bg-background px-2 text-sm';
+
+const withinOptions = [7, 14, 30, 60, 90];
+
+export function ForecastCard() {
+  const [withinDays, setWithinDays] = useState(30);
+  const [items, setItems] = useState<ComponentForecastItem[]>([]);
+  const [total, setTotal] = useState(0);
+  const [params, setParams] = useState<ForecastParams | null>(null);
+  const [loading, setLoading] = useState(true);
+  const [recomputing, setRecomputing] = useState(false);
+
+  const fetchForecasts = useCallback(async () => {
+    setLoading(true);
+    try {
+      const res = await client.get<{ data: ComponentForecastItem[]; params: ForecastParams; pagination: { total: number } }>(
+        '/forecasts',
+        { params: { within_days: withinDays, page_size: 10 } },
+      );
+      setItems(res.data.data);
+      setTotal(res.data.pagination.total);
+      setParams(res.data.params);
+    } catch (error) {
+      const err = error as { response?: { data?: { error?: string } } };
+      toast.error(err.response?.data?.error || '获取消耗预测失败');
+      setItems([]);
+      setTotal(0);
+    } finally {
+      setLoading(false);
+    }
+  }, [withinDays]);
+
+  useEffect(() => {
+    fetchForecasts();
+  }, [fetchForecasts]);
+
+  const handleRecompute = async () => {
+    setRecomputing(true);
+    try {
+      await client.post('/forecasts/recompute');
+      toast.success('已重新计算');
+      await fetchForecasts();
+    } catch (error) {
+      const err = error as { response?: { data?: { error?: string } } };
+      toast.error(err.response?.data?.error || '计算消耗预测失败');
+    } finally {
+      setRecomputing(false);
+    }
+  };
+
+  return (
+    <Card>
+      <CardHeader className="flex flex-row items-center justify-between space-y-0 pb-2">
+        <CardTitle className="text-sm font-medium">即将断货</CardTitle>
+        <Timer className="h-4 w-4 text-muted-foreground" />
+      </CardHeader>
+      <CardContent className="space-y-4">
+        <div className="flex flex-wrap items-center gap-2">
+          <span className="text-sm text-muted-foreground">预计</span>
+          <select className={selectClass} value={withinDays} onChange={e => setWithinDays(Number(e.target.value))}>
+            {withinOptions.map(days => (
+              <option key={days} value={days}>{days} 天内</option>
+            ))}
+          </select>
+          <span className="text-sm text-muted-foreground">断货</span>
+          <Button size="sm" variant="outline" onClick={handleRecompute} disabled={recomputing}>
+            {recomputing ? <Loader2 className="mr-2 h-4 w-4 animate-spin" /> : <RefreshCw className="mr-2 h-4 w-4" />}
+            重新计算
+          </Button>
+        </div>
+        {params && (
+          <div className="text-xs text-muted-foreground">
+            按最近 {params.window_days} 天出库计算日均消耗；建议补货量覆盖到货 {params.lead_time_days} 天 + {params.target_days} 天
+          </div>
+        )}
+
+        {loading && <div className="text-sm text-muted-foreground">加载中...</div>}
+        {!loading && (
+          <div className="divide-y text-sm">
+            {items.map(item => (
+              <div key={item.component_id} className="flex items-center justify-between gap-2 py-1.5">
+                <span className="truncate">
+                  {item.name}
+                  {item.component_number && (
+                    <span className="ml-1 text-xs text-muted-foreground">{item.component_number}</span>
+                  )}
+                </span>
+                <span className="shrink-0 text-muted-foreground">
+                  库存 {item.stock_quantity} · 日均 {item.avg_daily_consumption.toFixed(2)} ·{' '}
+                  <span className="text-red-600">{item.days_of_cover != null ? `${item.days_of_cover.toFixed(1)} 天` : '-'}</span>
+                  {item.reorder_quantity > 0 && <> · 建议补 {item.reorder_quantity}</>}
+                </span>
+              </div>
+            ))}
+            {items.length === 0 && (
+              <div className="py-1.5 text-muted-foreground">{withinDays} 天内没有预计断货的元件</div>
+            )}
+            {total > items.length && (
+              <div className="py-1.5 text-xs text-muted-foreground">
+                共 {total} 种，可在元件列表按“可用天数”排序查看全部
+              </div>
+            )}
+          </div>
+        )}
+      </CardContent>
+    </Card>
+  );
+}
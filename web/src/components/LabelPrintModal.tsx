@@ -0,0 +1,203 @@
+import { useEffect, useState } from 'react';
+import { Loader2, Printer } from 'lucide-react';
+import { toast } from 'react-hot-toast';
+import client from '../api/client';
+import { type LabelFormat, type LabelTemplate } from '../types';
+import { Button } from './ui/Button';
+import { Input } from './ui/Input';
+import { Label } from './ui/Label';
+import { Modal } from './ui/Modal';
+import { downloadExport } from '../utils/download';
+
+const selectClass = 'h-9 w-full rounded-md border border-input bg-background px-2 text-sm';
+
+const FORMAT_OPTIONS: { value: LabelFormat; label: string }[] = [
+  { value: 'pdf', label: 'PDF（普通打印机）' },
+  { value: 'zpl', label: 'ZPL（斑马热敏打印机）' },
+  { value: 'tspl', label: 'TSPL（TSC/佳博等热敏打印机）' },
+];
+
+const DPI_OPTIONS = [203, 300, 600];
+
+type LabelPrintModalProps = {
+  isOpen: boolean;
+  onClose: () => void;
+  // components 打印元件标签：componentIds 非空时打印这些元件，否则按 filterParams（元件列表的筛选条件）打印全部匹配的元件
+  // locations 打印存放位置标签：initialLocations 为预填的位置
+  kind: 'components' | 'locations';
+  componentIds?: number[];
+  filterParams?: URLSearchParams;
+  initialLocations?: string[];
+};
+
+export function LabelPrintModal({
+  isOpen,
+  onClose,
+  kind,
+  componentIds = [],
+  filterParams,
+  initialLocations = [],
+}: LabelPrintModalProps) {
+  const [templates, setTemplates] = useState<LabelTemplate[]>([]);
+  const [templateName, setTemplateName] = useState('');
+  const [format, setFormat] = useState<LabelFormat>('pdf');
+  const [copies, setCopies] = useState('1');
+  const [dpi, setDpi] = useState(203);
+  const [skip, setSkip] = useState('0');
+  const [locations, setLocations] = useState('');
+  const [prefix, setPrefix] = useState('');
+  const [isPrinting, setIsPrinting] = useState(false);
+
+  useEffect(() => {
+    if (!isOpen) return;
+    setLocations(initialLocations.join('\n'));
+    setPrefix('');
+    if (templates.length > 0) return;
+    client.get('/labels/templates')
+      .then(res => {
+        const items: LabelTemplate[] = res.data.data || [];
+        setTemplates(items);
+        setTemplateName(current => current || items[0]?.name || '');
+      })
+      .catch(() => toast.error('获取标签模板失败'));
+  }, [isOpen]); // eslint-disable-line react-hooks/exhaustive-deps
+
+  const template = templates.find(item => item.name === templateName);
+  const isSheet = Boolean(template?.sheet);
+
+  useEffect(() => {
+    // 整页模板只能输出 PDF
+    if (isSheet && format !== 'pdf') setFormat('pdf');
+  }, [isSheet, format]);
+
+  const handlePrint = async () => {
+    if (!template) {
+      toast.error('请选择标签模板');
+      return;
+    }
+    const params = new URLSearchParams();
+    if (kind === 'components') {
+      if (componentIds.length > 0) {
+        params.set('ids', componentIds.join(','));
+      } else if (filterParams) {
+        filterParams.forEach((value, key) => params.set(key, value));
+      }
+    } else {
+      const list = locations.split(/[\n,，]/).map(item => item.trim()).filter(Boolean);
+      if (list.length > 0) params.set('locations', list.join(','));
+      else if (prefix.trim()) params.set('prefix', prefix.trim());
+    }
+    params.set('template', template.name);
+    params.set('format', format);
+    params.set('copies', String(Math.max(1, Number(copies) || 1)));
+    if (format !== 'pdf') params.set('dpi', String(dpi));
+    if (isSheet && Number(skip) > 0) params.set('skip', String(Number(skip)));
+
+    setIsPrinting(true);
+    try {
+      await downloadExport(`/labels/${kind}`, params, `labels-${template.name}.${format}`);
+      toast.success('标签已生成');
+      onClose();
+    } catch (error) {
+      toast.error(error instanceof Error ? error.message : '生成标签失败');
+    } finally {
+      setIsPrinting(false);
+    }
+  };
+
+  const scope = kind === 'components'
+    ? (componentIds.length > 0 ? `将打印已选择的 ${componentIds.length} 个元件` : '将打印当前筛选条件下的全部元件')
+    : '每行或用逗号分隔一个位置；留空时按前缀打印已使用的位置，前缀也为空时打印全部位置';
+
+  return (
+    <Modal
+      isOpen={isOpen}
+      onClose={onClose}
+      title={kind === 'components' ? '打印元件标签' : '打印位置标签'}
+      footer={
+        <>
+          <Button variant="outline" onClick={onClose}>取消</Button>
+          <Button onClick={handlePrint} disabled={isPrinting || !template}>
+            {isPrinting ? <Loader2 className="mr-2 h-4 w-4 animate-spin" /> : <Printer className="mr-2 h-4 w-4" />}
+            生成标签
+          </Button>
+        </>
+      }
+    >
+      <div className="space-y-4">
+        <p className="text-sm text-muted-foreground">{scope}</p>
+        {kind === 'locations' && (
+          <>
+            <div className="space-y-2">
+              <Label htmlFor="label-locations">存放位置</Label>
+              <textarea
+                id="label-locations"
+                className="min-h-[96px] w-full rounded-md border border-input bg-background px-3 py-2 text-sm"
+                value={locations}
+                onChange={e => setLocations(e.target.value)}
+                placeholder={'A1-01\nA1-02'}
+              />
+            </div>
+            <div className="space-y-2">
+              <Label htmlFor="label-prefix">位置前缀</Label>
+              <Input id="label-prefix" value={prefix} onChange={e => setPrefix(e.target.value)} placeholder="如 A1" disabled={locations.trim() !== ''} />
+            </div>
+          </>
+        )}
+        <div className="grid grid-cols-2 gap-4">
+          <div className="space-y-2">
+            <Label htmlFor="label-template">模板</Label>
+            <select id="label-template" className={selectClass} value={templateName} onChange={e => setTemplateName(e.target.value)}>
+              {templates.map(item => (
+                <option key={item.name} value={item.name}>{item.title}</option>
+              ))}
+            </select>
+          </div>
+          <div className="space-y-2">
+            <Label htmlFor="label-format">格式</Label>
+            <select id="label-format" className={selectClass} value={format} onChange={e => setFormat(e.target.value as LabelFormat)}>
+              {FORMAT_OPTIONS.map(option => (
+                <option key={option.value} value={option.value} disabled={isSheet && option.value !== 'pdf'}>{option.label}</option>
+              ))}
+            </select>
+          </div>
+          <div className="space-y-2">
+            <Label htmlFor="label-copies">每个标签份数</Label>
+            <Input id="label-copies" type="number" min={1} max={100} value={copies} onChange={e => setCopies(e.target.value)} />
+          </div>
+          {format !== 'pdf' && (
+            <div className="space-y-2">
+              <Label htmlFor="label-dpi">打印机分辨率</Label>
+              <select id="label-dpi" className={selectClass} value={dpi} onChange={e => setDpi(Number(e.target.value))}>
+                {DPI_OPTIONS.map(value => (
+                  <option key={value} value={value}>{value} dpi</option>
+                ))}
+              </select>
+            </div>
+          )}
+          {isSheet && template?.sheet && (
+            <div className="space-y-2">
+              <Label htmlFor="label-skip">跳过前几个位置</Label>
+              <Input
+                id="label-skip"
+                type="number"
+                min={0}
+                max={template.sheet.columns * template.sheet.rows - 1}
+                value={skip}
+                onChange={e => setSkip(e.target.value)}
+              />
+            </div>
+          )}
+        </div>
+        {template && (
+          <p className="text-xs text-muted-foreground">
+            标签 {template.width_mm}×{template.height_mm}mm
+            {template.sheet && `，每页 ${template.sheet.columns}×${template.sheet.rows} 个`}
+            ，{template.symbology === 'datamatrix' ? 'DataMatrix' : '二维码'}
+            {kind === 'components' && '内容为元件系统编号，没有编号的元件需先自动编号'}
+          </p>
+        )}
+      </div>
+    </Modal>
+  );
+}
@@ -1,8 +1,9 @@
 import { useState } from 'react';
 import { Link, useLocation, useNavigate } from 'react-router-dom';
-import { LayoutDashboard, Package, ClipboardList, FolderTree, History, Menu, X, ChevronLeft, ChevronRight, LogOut } from 'lucide-react';
+import { LayoutDashboard, Package, ClipboardList, FolderTree, Truck, History, Archive, DatabaseBackup, Menu, X, ChevronLeft, ChevronRight, LogOut } from 'lucide-react';
 import { cn } from '../utils/cn';
 import { useAuth } from '../context/useAuth';
+import WorkspaceSelector from './WorkspaceSelector';
 
 const SIDEBAR_COLLAPSED_KEY = 'hamster-sidebar-collapsed';
 
@@ -11,7 +12,10 @@ const navItems = [
   { name: '元件管理', href: '/components', icon: Package },
   { name: '预入库', href: '/pre-stocks', icon: ClipboardList },
   { name: '分类管理', href: '/categories', icon: FolderTree },
+  { name: '供应商', href: '/suppliers', icon: Truck },
   { name: '库存记录', href: '/logs', icon: History },
+  { name: '呆滞料', href: '/dead-stock', icon: Archive },
+  { name: '数据备份', href: '/backup', icon: DatabaseBackup },
 ];
 
 export default function Layout({ children }: { children: React.ReactNode }) {
@@ -68,6 +72,9 @@ export default function Layout({ children }: { children: React.ReactNode }) {
             <X className="h-6 w-6" />
           </button>
         </div>
+        <div className={cn("px-4 pt-4", isCollapsed && "md:hidden")}>
+          <WorkspaceSelector />
+        </div>
         <nav className="flex-1 p-4 space-y-2">
           {navItems.map((item) => {
             const Icon = item.icon;
@@ -0,0 +1,164 @@
+import { useCallback, useEffect, useState } from 'react';
+import { Bookmark, Trash2 } from 'lucide-react';
+import { toast } from 'react-hot-toast';
+import client from '../api/client';
+import { type SavedSearch, type SavedSearchParams } from '../types';
+import { useAuth } from '../context/useAuth';
+import { Button } from './ui/Button';
+import { Input } from './ui/Input';
+import { Label } from './ui/Label';
+import { Modal } from './ui/Modal';
+
+type SavedSearchBarProps = {
+  /** 当前页面上的筛选、排序与显示列，保存时提交 */
+  getCurrentParams: () => SavedSearchParams;
+  onApply: (search: SavedSearch) => void;
+  /** 初始选中的保存搜索（如仪表盘跳转带上的 saved_search_id） */
+  initialId?: number;
+};
+
+export function SavedSearchBar({ getCurrentParams, onApply, initialId }: SavedSearchBarProps) {
+  const { username } = useAuth();
+  const [searches, setSearches] = useState<SavedSearch[]>([]);
+  const [activeId, setActiveId] = useState<number | null>(null);
+  const [isSaveOpen, setIsSaveOpen] = useState(false);
+  const [name, setName] = useState('');
+  const [shared, setShared] = useState(false);
+  const [saving, setSaving] = useState(false);
+
+  const loadSearches = useCallback(async () => {
+    try {
+      const res = await client.get('/saved-searches');
+      const data: SavedSearch[] = res.data.data || [];
+      setSearches(data);
+      return data;
+    } catch {
+      toast.error('加载保存的搜索失败');
+      return [];
+    }
+  }, []);
+
+  useEffect(() => {
+    void loadSearches().then(data => {
+      const initial = initialId ? data.find(search => search.id === initialId) : undefined;
+      if (initial) {
+        setActiveId(initial.id);
+        onApply(initial);
+      }
+    });
+  }, []); // eslint-disable-line react-hooks/exhaustive-deps
+
+  const active = searches.find(search => search.id === activeId);
+  const canEdit = active ? active.owner === (username ?? '') : false;
+
+  const handleSelect = (value: string) => {
+    const search = searches.find(item => String(item.id) === value);
+    setActiveId(search ? search.id : null);
+    if (search) onApply(search);
+  };
+
+  const openSave = () => {
+    setName(canEdit && active ? active.name : '');
+    setShared(canEdit && active ? active.shared : false);
+    setIsSaveOpen(true);
+  };
+
+  const handleSave = async (overwrite: boolean) => {
+    if (!name.trim()) {
+      toast.error('请输入名称');
+      return;
+    }
+    setSaving(true);
+    try {
+      const body = { name: name.trim(), shared, params: getCurrentParams() };
+      const res = overwrite && active
+        ? await client.put(`/saved-searches/${active.id}`, body)
+        : await client.post('/saved-searches', body);
+      const saved: SavedSearch = res.data.data;
+      await loadSearches();
+      setActiveId(saved.id);
+      setIsSaveOpen(false);
+      toast.success('搜索已保存');
+    } catch (error) {
+      const err = error as { response?: { data?: { error?: string } } };
+      toast.error(err.response?.data?.error || '保存搜索失败');
+    } finally {
+      setSaving(false);
+    }
+  };
+
+  const handleDelete = async () => {
+    if (!active || !confirm(`确定删除保存的搜索「${active.name}」吗？`)) return;
+    try {
+      await client.delete(`/saved-searches/${active.id}`);
+      setActiveId(null);
+      await loadSearches();
+      toast.success('删除成功');
+    } catch (error) {
+      const err = error as { response?: { data?: { error?: string } } };
+      toast.error(err.response?.data?.error || '删除失败');
+    }
+  };
+
+  return (
+    <div className="flex flex-wrap items-center gap-2">
+      <select
+        aria-label="保存的搜索"
+        className="h-9 min-w-40 rounded-md border border-input bg-background px-2 text-sm"
+        value={activeId ?? ''}
+        onChange={e => handleSelect(e.target.value)}
+      >
+        <option value="">保存的搜索…</option>
+        {searches.map(search => (
+          <option key={search.id} value={search.id}>
+            {search.name}
+            {search.shared ? `（共享${search.owner && search.owner !== username ? ` · ${search.owner}` : ''}）` : ''}
+          </option>
+        ))}
+      </select>
+      <Button size="sm" variant="outline" onClick={openSave}>
+        <Bookmark className="h-4 w-4 mr-1" />
+        保存当前搜索
+      </Button>
+      {active && (
+        <Button size="sm" variant="ghost" onClick={handleDelete} title="删除保存的搜索">
+          <Trash2 className="h-4 w-4" />
+        </Button>
+      )}
+
+      <Modal
+        isOpen={isSaveOpen}
+        onClose={() => !saving && setIsSaveOpen(false)}
+        title="保存当前搜索"
+        footer={
+          <>
+            <Button variant="outline" onClick={() => setIsSaveOpen(false)} disabled={saving}>取消</Button>
+            {canEdit && active && (
+              <Button variant="outline" onClick={() => handleSave(true)} disabled={saving}>覆盖「{active.name}」</Button>
+            )}
+            <Button onClick={() => handleSave(false)} disabled={saving}>另存为新搜索</Button>
+          </>
+        }
+      >
+        <div className="space-y-4">
+          <p className="text-sm text-muted-foreground">
+            保存当前的筛选条件、高级查询、排序与显示列，之后可一键套用、按此导出，并在仪表盘查看命中数量。
+          </p>
+          <div className="space-y-1">
+            <Label htmlFor="saved-search-name">名称</Label>
+            <Input
+              id="saved-search-name"
+              placeholder="如：B 柜 0402 电容低库存"
+              value={name}
+              onChange={e => setName(e.target.value)}
+            />
+          </div>
+          <label className="flex items-center gap-2 text-sm">
+            <input type="checkbox" checked={shared} onChange={e => setShared(e.target.checked)} />
+            共享给工作区全部成员
+          </label>
+        </div>
+      </Modal>
+    </div>
+  );
+}
@@ -0,0 +1,171 @@
+import { useEffect, useState } from 'react';
+import { BarChart3 } from 'lucide-react';
+import { Card, CardContent, CardHeader, CardTitle } from './ui/Card';
+import { Input } from './ui/Input';
+import client from '../api/client';
+import { type StatsBucket, type StatsGroupBy, type StatsPoint, type StatsSeries } from '../types';
+import { formatCents } from '../utils/price';
+
+const selectClass = 'h-9 rounded-md border border-input bg-background px-2 text-sm';
+
+const bucketOptions: { value: StatsBucket; label: string }[] = [
+  { value: 'day', label: '按日' },
+  { value: 'week', label: '按周' },
+  { value: 'month', label: '按月' },
+];
+
+const groupOptions: { value: StatsGroupBy | ''; label: string }[] = [
+  { value: '', label: '不分组' },
+  { value: 'category', label: '按分类' },
+  { value: 'supplier', label: '按供应商' },
+  { value: 'location', label: '按位置' },
+  { value: 'project', label: '按项目（出入库原因）' },
+];
+
+const emptyGroupKey: Record<StatsGroupBy, string> = {
+  category: '未分类',
+  supplier: '无供应商',
+  location: '无位置',
+  project: '未填写原因',
+};
+
+function formatDate(date: Date): string {
+  const pad = (n: number) => String(n).padStart(2, '0');
+  return `${date.getFullYear()}-${pad(date.getMonth() + 1)}-${pad(date.getDate())}`;
+}
+
+/** 桶起点为带时区偏移的 RFC3339，取日期部分即为所选时区的日期 */
+function bucketLabel(point: StatsPoint, bucket: StatsBucket): string {
+  const date = point.start.slice(0, 10);
+  return bucket === 'month' ? date.slice(0, 7) : date;
+}
+
+export function StatsSeriesCard() {
+  const [from, setFrom] = useState(() => formatDate(new Date(Date.now() - 29 * 24 * 3600 * 1000)));
+  const [to, setTo] = useState(() => formatDate(new Date()));
+  const [bucket, setBucket] = useState<StatsBucket>('day');
+  const [groupBy, setGroupBy] = useState<StatsGroupBy | ''>('');
+  const [series, setSeries] = useState<StatsSeries | null>(null);
+  const [error, setError] = useState('');
+  const [loading, setLoading] = useState(true);
+
+  useEffect(() => {
+    const fetchSeries = async () => {
+      setLoading(true);
+      setError('');
+      try {
+        const params: Record<string, string> = {
+          from,
+          to,
+          bucket,
+          tz: Intl.DateTimeFormat().resolvedOptions().timeZone,
+        };
+        if (groupBy) params.group_by = groupBy;
+        const res = await client.get<{ data: StatsSeries }>('/stats/series', { params });
+        setSeries(res.data.data);
+      } catch (error) {
+        const err = error as { response?: { data?: { error?: string } } };
+        setError(err.response?.data?.error || '获取统计数据失败');
+        setSeries(null);
+      } finally {
+        setLoading(false);
+      }
+    };
+
+    fetchSeries();
+  }, [from, to, bucket, groupBy]);
+
+  const maxQuantity = Math.max(
+    1,
+    ...(series?.series ?? []).map(point => Math.max(point.inbound_quantity, point.outbound_quantity)),
+  );
+
+  return (
+    <Card>
+      <CardHeader className="flex flex-row items-center justify-between space-y-0 pb-2">
+        <CardTitle className="text-sm font-medium">出入库趋势</CardTitle>
+        <BarChart3 className="h-4 w-4 text-muted-foreground" />
+      </CardHeader>
+      <CardContent className="space-y-4">
+        <div className="flex flex-wrap items-center gap-2">
+          <Input type="date" className="w-40" value={from} onChange={e => setFrom(e.target.value)} />
+          <span className="text-muted-foreground">-</span>
+          <Input type="date" className="w-40" value={to} onChange={e => setTo(e.target.value)} />
+          <select className={selectClass} value={bucket} onChange={e => setBucket(e.target.value as StatsBucket)}>
+            {bucketOptions.map(option => (
+              <option key={option.value} value={option.value}>{option.label}</option>
+            ))}
+          </select>
+          <select className={selectClass} value={groupBy} onChange={e => setGroupBy(e.target.value as StatsGroupBy | '')}>
+            {groupOptions.map(option => (
+              <option key={option.value} value={option.value}>{option.label}</option>
+            ))}
+          </select>
+        </div>
+
+        {error && <div className="text-sm text-destructive">{error}</div>}
+        {loading && <div className="text-sm text-muted-foreground">加载中...</div>}
+
+        {!loading && series && (
+          <>
+            <div className="text-sm text-muted-foreground">
+              入库 {series.totals.inbound_quantity}（{formatCents(series.totals.inbound_cost_cents)}） ·
+              出库 {series.totals.outbound_quantity}（{formatCents(series.totals.outbound_cost_cents)}）
+            </div>
+
+            <div className="max-h-72 overflow-auto space-y-1">
+              {series.series.map(point => (
+                <div key={point.start} className="grid grid-cols-[6rem_1fr] items-center gap-2 text-xs">
+                  <span className="text-muted-foreground">{bucketLabel(point, series.bucket)}</span>
+                  <div className="space-y-0.5" title={`入库 ${point.inbound_quantity}，出库 ${point.outbound_quantity}`}>
+                    <div className="h-1.5 rounded bg-green-500" style={{ width: `${(point.inbound_quantity / maxQuantity) * 100}%` }} />
+                    <div className="h-1.5 rounded bg-orange-500" style={{ width: `${(point.outbound_quantity / maxQuantity) * 100}%` }} />
+                  </div>
+                </div>
+              ))}
+            </div>
+
+            <div className="grid gap-4 md:grid-cols-2">
+              {series.group_by && series.groups && (
+                <div>
+                  <div className="mb-1 text-sm font-medium">分组合计</div>
+                  <div className="divide-y text-sm">
+                    {series.groups.map(group => (
+                      <div key={group.key} className="flex items-center justify-between py-1.5">
+                        <span>{group.key || emptyGroupKey[series.group_by!]}</span>
+                        <span className="text-muted-foreground">
+                          入 {group.totals.inbound_quantity} · 出 {group.totals.outbound_quantity} · {formatCents(group.totals.outbound_cost_cents)}
+                        </span>
+                      </div>
+                    ))}
+                  </div>
+                </div>
+              )}
+              <div>
+                <div className="mb-1 text-sm font-medium">消耗最多的元件</div>
+                <div className="divide-y text-sm">
+                  {series.top_consumed.map(component => (
+                    <div key={component.component_id} className="flex items-center justify-between py-1.5">
+                      <span className="truncate">
+                        {component.name}
+                        {component.component_number && (
+                          <span className="ml-1 text-xs text-muted-foreground">{component.component_number}</span>
+                        )}
+                      </span>
+                      <span className="shrink-0 text-muted-foreground">
+                        {component.outbound_quantity} · {formatCents(component.outbound_cost_cents)}
+                      </span>
+                    </div>
+                  ))}
+                  {series.top_consumed.length === 0 && (
+                    <div className="py-1.5 text-muted-foreground">暂无出库记录</div>
+                  )}
+                </div>
+              </div>
+            </div>
+          </>
+        )}
+      </CardContent>
+    </Card>
+  );
+}
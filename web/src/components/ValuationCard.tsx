@@ -0,0 +1,108 @@
+import { useEffect, useState } from 'react';
+import { Download, History, Loader2 } from 'lucide-react';
+import { toast } from 'react-hot-toast';
+import { Card, CardContent, CardHeader, CardTitle } from './ui/Card';
+import { Button } from './ui/Button';
+import { Input } from './ui/Input';
+import client from '../api/client';
+import { type InventoryValuation } from '../types';
+import { formatCents } from '../utils/price';
+import { downloadExport, EXPORT_FORMAT_OPTIONS, type ExportFormat } from '../utils/download';
+
+const selectClass = 'h-9 rounded-md border border-input bg-background px-2 text-sm';
+
+// 默认去年最后一天，便于年终盘点
+function defaultDate(): string {
+  return `${new Date().getFullYear() - 1}-12-31`;
+}
+
+export function ValuationCard() {
+  const [at, setAt] = useState(defaultDate);
+  const [format, setFormat] = useState<ExportFormat>('xlsx');
+  const [valuation, setValuation] = useState<InventoryValuation | null>(null);
+  const [loading, setLoading] = useState(true);
+  const [exporting, setExporting] = useState(false);
+
+  const tz = Intl.DateTimeFormat().resolvedOptions().timeZone;
+
+  useEffect(() => {
+    if (!at) return;
+    const fetchValuation = async () => {
+      setLoading(true);
+      try {
+        const res = await client.get<{ data: InventoryValuation }>('/stats/valuation', { params: { at, tz } });
+        setValuation(res.data.data);
+      } catch (error) {
+        const err = error as { response?: { data?: { error?: string } } };
+        toast.error(err.response?.data?.error || '获取库存估值失败');
+        setValuation(null);
+      } finally {
+        setLoading(false);
+      }
+    };
+
+    fetchValuation();
+  }, [at, tz]);
+
+  const handleExport = async () => {
+    setExporting(true);
+    try {
+      await downloadExport('/stats/valuation/export', new URLSearchParams({ at, tz, format }), `valuation.${format}`);
+      toast.success('导出成功');
+    } catch (error) {
+      toast.error(error instanceof Error ? error.message : '导出失败');
+    } finally {
+      setExporting(false);
+    }
+  };
+
+  return (
+    <Card>
+      <CardHeader className="flex flex-row items-center justify-between space-y-0 pb-2">
+        <CardTitle className="text-sm font-medium">历史库存估值</CardTitle>
+        <History className="h-4 w-4 text-muted-foreground" />
+      </CardHeader>
+      <CardContent className="space-y-4">
+        <div className="flex flex-wrap items-center gap-2">
+          <span className="text-sm text-muted-foreground">截至</span>
+          <Input type="date" className="w-40" value={at} onChange={e => setAt(e.target.value)} />
+          <span className="text-sm text-muted-foreground">当天结束</span>
+          <select className={selectClass} value={format} onChange={e => setFormat(e.target.value as ExportFormat)}>
+            {EXPORT_FORMAT_OPTIONS.map(option => (
+              <option key={option.value} value={option.value}>{option.label}</option>
+            ))}
+          </select>
+          <Button size="sm" variant="outline" onClick={handleExport} disabled={exporting || !at}>
+            {exporting ? <Loader2 className="mr-2 h-4 w-4 animate-spin" /> : <Download className="mr-2 h-4 w-4" />}
+            导出明细
+          </Button>
+        </div>
+
+        {loading && <div className="text-sm text-muted-foreground">加载中...</div>}
+        {!loading && valuation && (
+          <>
+            <div className="text-sm">
+              <span className="text-2xl font-bold">{formatCents(valuation.totals.value_cents)}</span>
+              <span className="ml-2 text-muted-foreground">
+                {valuation.totals.component_count} 种 · 库存 {valuation.totals.quantity}
+              </span>
+            </div>
+            <div className="divide-y text-sm">
+              {valuation.categories.map(category => (
+                <div key={category.category_id} className="flex items-center justify-between py-1.5">
+                  <span>{category.category_name || '未分类'}</span>
+                  <span className="text-muted-foreground">
+                    {category.component_count} 种 · 库存 {category.quantity} · {formatCents(category.value_cents)}
+                  </span>
+                </div>
+              ))}
+              {valuation.categories.length === 0 && (
+                <div className="py-1.5 text-muted-foreground">当时没有库存</div>
+              )}
+            </div>
+          </>
+        )}
+      </CardContent>
+    </Card>
+  );
+}
@@ -0,0 +1,60 @@
+import { useEffect, useState } from 'react';
+import client from '../api/client';
+import type { Workspace } from '../types';
+
+const WORKSPACE_COOKIE = 'hamster_workspace';
+
+function readWorkspaceCookie(): number | null {
+  const match = document.cookie.match(new RegExp(`(?:^|; )${WORKSPACE_COOKIE}=(\\d+)`));
+  return match ? Number(match[1]) : null;
+}
+
+function writeWorkspaceCookie(id: number) {
+  document.cookie = `${WORKSPACE_COOKIE}=${id}; path=/; max-age=${60 * 60 * 24 * 365}; samesite=lax`;
+}
+
+export default function WorkspaceSelector() {
+  const [workspaces, setWorkspaces] = useState<Workspace[]>([]);
+  const [current, setCurrent] = useState<number | null>(() => readWorkspaceCookie());
+
+  useEffect(() => {
+    client
+      .get<{ data: Workspace[] }>('/workspaces')
+      .then((res) => {
+        const list = res.data.data || [];
+        setWorkspaces(list);
+        if (list.length > 0 && !list.some((item) => item.id === current)) {
+          writeWorkspaceCookie(list[0].id);
+          setCurrent(list[0].id);
+        }
+      })
+      .catch(console.error);
+  }, []); // eslint-disable-line react-hooks/exhaustive-deps
+
+  if (workspaces.length <= 1) {
+    return null;
+  }
+
+  const handleChange = (id: number) => {
+    writeWorkspaceCookie(id);
+    setCurrent(id);
+    // 所有页面数据均按工作区隔离，切换后整页刷新最稳妥
+    window.location.reload();
+  };
+
+  return (
+    <select
+      className="h-9 w-full rounded-md border border-input bg-background px-2 text-sm"
+      value={current ?? ''}
+      onChange={(e) => handleChange(Number(e.target.value))}
+      aria-label="当前工作区"
+    >
+      {workspaces.map((item) => (
+        <option key={item.id} value={item.id}>
+          {item.name}
+          {item.role === 'viewer' ? '（只读）' : ''}
+        </option>
+      ))}
+    </select>
+  );
+}
@@ -0,0 +1,31 @@
+import { type Suggestion } from '../../types';
+
+type SuggestionListProps = {
+  suggestions: Suggestion[];
+  onSelect: (value: string) => void;
+  /** 无结果时的提示，如「无匹配位置」 */
+  emptyText: string;
+};
+
+export function SuggestionList({ suggestions, onSelect, emptyText }: SuggestionListProps) {
+  return (
+    <div className="absolute z-50 w-full mt-1 bg-popover border rounded-md shadow-lg max-h-60 overflow-auto">
+      {suggestions.map(suggestion => (
+        <div
+          key={suggestion.value}
+          className="flex items-center justify-between gap-2 px-3 py-2 text-sm cursor-pointer hover:bg-accent hover:text-accent-foreground"
+          onClick={() => onSelect(suggestion.value)}
+        >
+          <span className="truncate">
+            {suggestion.value}
+            {suggestion.fuzzy && <span className="ml-1 text-xs text-muted-foreground">（近似）</span>}
+          </span>
+          <span className="shrink-0 text-xs text-muted-foreground">{suggestion.count}</span>
+        </div>
+      ))}
+      {suggestions.length === 0 && (
+        <div className="px-3 py-2 text-sm text-muted-foreground">{emptyText}</div>
+      )}
+    </div>
+  );
+}
@@ -52,6 +52,15 @@ export function AuthProvider({ children }: { children: ReactNode }) {
       username: inputUsername,
       password,
     });
+    // 需要二次验证时由登录页继续完成第二步，此时尚未登录
+    if (!res.data.data.two_factor_required) {
+      applyAuthState(res.data.data);
+    }
+    return res.data.data;
+  }, [applyAuthState]);
+
+  const verifyTwoFactor = useCallback(async (payload: { code?: string; recovery_code?: string }) => {
+    const res = await client.post<{ data: AuthMeData }>('/auth/2fa/verify', payload);
     applyAuthState(res.data.data);
   }, [applyAuthState]);
 
@@ -69,10 +78,11 @@ export function AuthProvider({ children }: { children: ReactNode }) {
       username,
       loading,
       login,
+      verifyTwoFactor,
       logout,
       refresh,
     }),
-    [authEnabled, isAuthenticated, username, loading, login, logout, refresh]
+    [authEnabled, isAuthenticated, username, loading, login, verifyTwoFactor, logout, refresh]
   );
 
   return <AuthContext.Provider value={value}>{children}</AuthContext.Provider>;
@@ -3,6 +3,8 @@ import { createContext } from 'react';
 export interface AuthMeData {
   auth_enabled: boolean;
   username?: string;
+  two_factor_required?: boolean;
+  enrollment_required?: boolean;
 }
 
 export interface AuthContextValue {
@@ -10,7 +12,8 @@ export interface AuthContextValue {
   isAuthenticated: boolean;
   username: string | null;
   loading: boolean;
-  login: (username: string, password: string) => Promise<void>;
+  login: (username: string, password: string) => Promise<AuthMeData>;
+  verifyTwoFactor: (payload: { code?: string; recovery_code?: string }) => Promise<void>;
   logout: () => Promise<void>;
   refresh: () => Promise<void>;
 }
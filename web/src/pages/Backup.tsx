@@ -0,0 +1,276 @@
+import { useCallback, useEffect, useState } from 'react';
+import { Download, Loader2, Play, Wrench } from 'lucide-react';
+import { toast } from 'react-hot-toast';
+import client from '../api/client';
+import { Card, CardContent, CardHeader, CardTitle } from '../components/ui/Card';
+import { Button } from '../components/ui/Button';
+import { Input } from '../components/ui/Input';
+import { Label } from '../components/ui/Label';
+import { PageHeader } from '../components/ui/PageHeader';
+import { type BackupRunStatus, type BackupScheduleStatus, type RestoreMode, type RestoreReport } from '../types';
+import { downloadExport } from '../utils/download';
+
+const MODE_OPTIONS: { value: RestoreMode; label: string; hint: string }[] = [
+  { value: 'merge', label: '合并', hint: '保留现有数据，备份中的记录重新分配 ID 后追加；同名工作区、分类、供应商和相同编号的元件视为已存在并跳过。' },
+  { value: 'replace', label: '覆盖', hint: '清空当前全部工作区的数据与图片，按备份原样恢复。此操作不可撤销。' },
+];
+
+function formatBytes(bytes: number) {
+  if (bytes < 1024) return `${bytes} B`;
+  if (bytes < 1024 * 1024) return `${(bytes / 1024).toFixed(1)} KB`;
+  return `${(bytes / 1024 / 1024).toFixed(1)} MB`;
+}
+
+function formatTime(value: string | null | undefined) {
+  return value ? new Date(value).toLocaleString() : '-';
+}
+
+function describeRun(run: BackupRunStatus | null) {
+  if (!run) return '尚未执行';
+  const at = formatTime(run.finished_at);
+  if (run.error) return `${at} 失败：${run.error}`;
+  if (run.maintenance) {
+    const m = run.maintenance;
+    return `${at} 完成${m.vacuumed ? '，已执行 VACUUM' : ''}，回收 ${m.freed_pages} 页，WAL 写回 ${m.wal_pages} 页`;
+  }
+  if (run.file) {
+    return `${at} 完成：${run.file}（${formatBytes(run.size ?? 0)}）${run.uploaded ? '，已上传' : ''}，清理 ${run.pruned ?? 0} 份`;
+  }
+  return `${at} 完成`;
+}
+
+export default function Backup() {
+  const [file, setFile] = useState<File | null>(null);
+  const [mode, setMode] = useState<RestoreMode>('merge');
+  const [report, setReport] = useState<RestoreReport | null>(null);
+  const [submitting, setSubmitting] = useState<'check' | 'restore' | null>(null);
+  const [downloading, setDownloading] = useState(false);
+  const [schedule, setSchedule] = useState<BackupScheduleStatus | null>(null);
+  const [running, setRunning] = useState<'backup' | 'maintenance' | null>(null);
+
+  const fetchSchedule = useCallback(async () => {
+    try {
+      const res = await client.get<{ data: BackupScheduleStatus }>('/backup/schedule');
+      setSchedule(res.data.data);
+    } catch {
+      setSchedule(null);
+    }
+  }, []);
+
+  useEffect(() => {
+    fetchSchedule();
+  }, [fetchSchedule]);
+
+  const runTask = async (task: 'backup' | 'maintenance') => {
+    setRunning(task);
+    try {
+      await client.post(task === 'backup' ? '/backup/run' : '/backup/maintenance');
+      toast.success(task === 'backup' ? '备份完成' : '数据库维护完成');
+    } catch (error) {
+      const err = error as { response?: { data?: { error?: string } } };
+      toast.error(err.response?.data?.error || '执行失败');
+    } finally {
+      setRunning(null);
+      fetchSchedule();
+    }
+  };
+
+  const downloadFile = async (name: string) => {
+    try {
+      await downloadExport(`/backup/files/${encodeURIComponent(name)}`, new URLSearchParams(), name);
+    } catch (error) {
+      toast.error(error instanceof Error ? error.message : '下载备份失败');
+    }
+  };
+
+  const handleDownload = async () => {
+    setDownloading(true);
+    try {
+      await downloadExport('/backup', new URLSearchParams(), 'hamster-bin-backup.zip');
+    } catch (error) {
+      toast.error(error instanceof Error ? error.message : '下载备份失败');
+    } finally {
+      setDownloading(false);
+    }
+  };
+
+  const submit = async (dryRun: boolean) => {
+    if (!file) return toast.error('请选择备份文件');
+    if (!dryRun && mode === 'replace' && !window.confirm('覆盖恢复将清空当前全部数据与图片，确定继续？')) return;
+
+    const formData = new FormData();
+    formData.append('file', file);
+    formData.append('mode', mode);
+    formData.append('dry_run', String(dryRun));
+
+    setSubmitting(dryRun ? 'check' : 'restore');
+    try {
+      const res = await client.post<{ data: RestoreReport }>('/backup/restore', formData, {
+        headers: { 'Content-Type': 'multipart/form-data' },
+      });
+      setReport(res.data.data);
+      toast.success(dryRun ? '备份文件校验通过' : '恢复完成');
+    } catch (error) {
+      const err = error as { response?: { data?: { error?: string } } };
+      setReport(null);
+      toast.error(err.response?.data?.error || '恢复失败');
+    } finally {
+      setSubmitting(null);
+    }
+  };
+
+  return (
+    <div className="space-y-6">
+      <PageHeader
+        title="数据备份"
+        actions={
+          <Button onClick={handleDownload} disabled={downloading}>
+            {downloading ? <Loader2 className="mr-2 h-4 w-4 animate-spin" /> : <Download className="mr-2 h-4 w-4" />}
+            下载完整备份
+          </Button>
+        }
+      />
+      <p className="text-sm text-muted-foreground">
+        备份包含全部工作区的数据、图片与二次验证密钥，请妥善保管。仅管理员可下载和恢复。
+      </p>
+
+      {schedule && (
+        <Card>
+          <CardHeader>
+            <CardTitle>定时备份</CardTitle>
+          </CardHeader>
+          <CardContent className="space-y-4 text-sm">
+            <div className="grid gap-2 sm:grid-cols-[8rem_1fr]">
+              <span className="text-muted-foreground">备份计划</span>
+              <span>
+                {schedule.schedule ? <span className="font-mono">{schedule.schedule}</span> : '未启用（BACKUP_SCHEDULE）'}
+                {schedule.next_run && `，下次 ${formatTime(schedule.next_run)}`}
+              </span>
+              <span className="text-muted-foreground">最近备份</span>
+              <span>{describeRun(schedule.last_run)}</span>
+              <span className="text-muted-foreground">保留策略</span>
+              <span>
+                {`每天 ${schedule.retention.daily} 份 / 每周 ${schedule.retention.weekly} 份 / 每月 ${schedule.retention.monthly} 份，目录 `}
+                <span className="font-mono">{schedule.dir}</span>
+                {schedule.s3_enabled && '，同步到对象存储'}
+              </span>
+              <span className="text-muted-foreground">数据库维护</span>
+              <span>
+                {schedule.maintenance_schedule ? <span className="font-mono">{schedule.maintenance_schedule}</span> : '未启用'}
+                {schedule.next_maintenance && `，下次 ${formatTime(schedule.next_maintenance)}`}
+              </span>
+              <span className="text-muted-foreground">最近维护</span>
+              <span>{describeRun(schedule.last_maintenance)}</span>
+            </div>
+            <div className="flex gap-2">
+              <Button variant="outline" onClick={() => runTask('backup')} disabled={running !== null}>
+                {running === 'backup' ? <Loader2 className="mr-2 h-4 w-4 animate-spin" /> : <Play className="mr-2 h-4 w-4" />}
+                立即备份
+              </Button>
+              <Button variant="outline" onClick={() => runTask('maintenance')} disabled={running !== null}>
+                {running === 'maintenance' ? <Loader2 className="mr-2 h-4 w-4 animate-spin" /> : <Wrench className="mr-2 h-4 w-4" />}
+                数据库维护
+              </Button>
+            </div>
+            {schedule.files.length > 0 && (
+              <div className="divide-y rounded-md border">
+                {schedule.files.map(item => (
+                  <div key={item.name} className="flex items-center gap-3 px-3 py-2">
+                    <span className="flex-1 font-mono text-xs">{item.name}</span>
+                    <span className="w-20 shrink-0 text-muted-foreground">{formatBytes(item.size)}</span>
+                    <Button variant="ghost" size="sm" onClick={() => downloadFile(item.name)}>
+                      <Download className="h-4 w-4" />
+                    </Button>
+                  </div>
+                ))}
+              </div>
+            )}
+          </CardContent>
+        </Card>
+      )}
+
+      <Card>
+        <CardHeader>
+          <CardTitle>从备份恢复</CardTitle>
+        </CardHeader>
+        <CardContent className="space-y-4 text-sm">
+          <div className="space-y-2">
+            <Label htmlFor="backup-file">备份文件（.zip）</Label>
+            <Input
+              id="backup-file"
+              type="file"
+              accept=".zip"
+              onChange={e => {
+                setReport(null);
+                setFile(e.target.files?.[0] ?? null);
+              }}
+            />
+          </div>
+          <div className="space-y-2">
+            <Label>恢复方式</Label>
+            {MODE_OPTIONS.map(option => (
+              <label key={option.value} className="flex items-start gap-2">
+                <input
+                  type="radio"
+                  name="restore-mode"
+                  className="mt-1"
+                  checked={mode === option.value}
+                  onChange={() => {
+                    setMode(option.value);
+                    setReport(null);
+                  }}
+                />
+                <span>
+                  <span className="font-medium">{option.label}</span>
+                  <span className="block text-xs text-muted-foreground">{option.hint}</span>
+                </span>
+              </label>
+            ))}
+          </div>
+          <div className="flex gap-2">
+            <Button variant="outline" onClick={() => submit(true)} disabled={!file || submitting !== null}>
+              {submitting === 'check' && <Loader2 className="mr-2 h-4 w-4 animate-spin" />}
+              校验
+            </Button>
+            <Button
+              variant={mode === 'replace' ? 'destructive' : 'default'}
+              onClick={() => submit(false)}
+              disabled={!file || submitting !== null}
+            >
+              {submitting === 'restore' && <Loader2 className="mr-2 h-4 w-4 animate-spin" />}
+              恢复
+            </Button>
+          </div>
+
+          {report && (
+            <div className="space-y-2">
+              <div>
+                备份于 {new Date(report.manifest.created_at).toLocaleString()}（版本 {report.manifest.app_version}，{report.manifest.db_driver}，
+                表结构 v{report.manifest.schema_version}），图片 {report.manifest.images} 张 / {formatBytes(report.manifest.image_bytes)}
+              </div>
+              <div className="divide-y rounded-md border">
+                {report.manifest.tables.map(table => {
+                  const result = report.tables.find(item => item.name === table.name);
+                  return (
+                    <div key={table.name} className="flex gap-3 px-3 py-2">
+                      <span className="w-56 shrink-0 font-mono text-xs">{table.name}</span>
+                      <span className="w-20 shrink-0">{table.rows} 行</span>
+                      {result && (
+                        <span className="text-muted-foreground">写入 {result.inserted}，跳过 {result.skipped}</span>
+                      )}
+                    </div>
+                  );
+                })}
+              </div>
+              {!report.dry_run && (
+                <div className="text-muted-foreground">
+                  图片恢复 {report.images_restored} 张，跳过 {report.images_skipped} 张
+                </div>
+              )}
+            </div>
+          )}
+        </CardContent>
+      </Card>
+    </div>
+  );
+}
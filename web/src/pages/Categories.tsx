@@ -1,25 +1,53 @@
-import { useEffect, useState } from 'react';
+import { useEffect, useMemo, useState } from 'react';
 import { Plus, Pencil, Trash2 } from 'lucide-react';
 import { toast } from 'react-hot-toast';
 import client from '../api/client';
-import { type Category } from '../types';
+import { type CategoryTreeNode } from '../types';
 import { Button } from '../components/ui/Button';
 import { Input } from '../components/ui/Input';
 import { Modal } from '../components/ui/Modal';
 import { Label } from '../components/ui/Label';
 import { PageHeader } from '../components/ui/PageHeader';
 import { Card, CardContent } from '../components/ui/Card';
+import { formatCents } from '../utils/price';
+
+interface FlatCategory {
+  node: CategoryTreeNode;
+  depth: number;
+}
+
+function flattenTree(nodes: CategoryTreeNode[], depth = 0): FlatCategory[] {
+  return nodes.flatMap(node => [{ node, depth }, ...flattenTree(node.children, depth + 1)]);
+}
+
+// 收集分类自身及子孙分类 ID，用于在父分类下拉中排除会形成环的选项
+function collectSubtreeIds(node: CategoryTreeNode, ids = new Set<number>()): Set<number> {
+  ids.add(node.id);
+  node.children.forEach(child => collectSubtreeIds(child, ids));
+  return ids;
+}
+
+const selectClassName = 'h-9 w-full rounded-md border border-input bg-background px-2 text-sm';
 
 export default function Categories() {
-  const [categories, setCategories] = useState<Category[]>([]);
+  const [tree, setTree] = useState<CategoryTreeNode[]>([]);
   const [isModalOpen, setIsModalOpen] = useState(false);
-  const [editingCategory, setEditingCategory] = useState<Category | null>(null);
-  const [formData, setFormData] = useState({ name: '' });
+  const [editingCategory, setEditingCategory] = useState<CategoryTreeNode | null>(null);
+  const [formData, setFormData] = useState<{ name: string; parent_id: number | null }>({ name: '', parent_id: null });
+  const [deletingCategory, setDeletingCategory] = useState<CategoryTreeNode | null>(null);
+  const [reassignTo, setReassignTo] = useState('');
+
+  const flatCategories = useMemo(() => flattenTree(tree), [tree]);
+
+  const parentOptions = useMemo(() => {
+    const excluded = editingCategory ? collectSubtreeIds(editingCategory) : new Set<number>();
+    return flatCategories.filter(({ node }) => !excluded.has(node.id));
+  }, [flatCategories, editingCategory]);
 
   const fetchCategories = async () => {
     try {
-      const res = await client.get('/categories');
-      setCategories(res.data.data || []);
+      const res = await client.get('/categories/tree');
+      setTree(res.data.data || []);
     } catch {
       toast.error('加载分类失败');
     }
@@ -28,8 +56,8 @@ export default function Categories() {
   useEffect(() => {
     const loadCategories = async () => {
       try {
-        const res = await client.get('/categories');
-        setCategories(res.data.data || []);
+        const res = await client.get('/categories/tree');
+        setTree(res.data.data || []);
       } catch {
         toast.error('加载分类失败');
       }
@@ -40,7 +68,7 @@ export default function Categories() {
 
   const handleSubmit = async () => {
     if (!formData.name) return toast.error('请输入分类名称');
-    
+
     try {
       if (editingCategory) {
         await client.put(`/categories/${editingCategory.id}`, formData);
@@ -51,29 +79,39 @@ export default function Categories() {
       }
       setIsModalOpen(false);
       fetchCategories();
-    } catch {
-      toast.error('操作失败');
+    } catch (error) {
+      const err = error as { response?: { data?: { error?: string } } };
+      toast.error(err.response?.data?.error || '操作失败');
     }
   };
 
-  const handleDelete = async (id: number) => {
-    if (!confirm('确定删除此分类吗？')) return;
+  const openDelete = (category: CategoryTreeNode) => {
+    setDeletingCategory(category);
+    setReassignTo('');
+  };
+
+  const handleDelete = async () => {
+    if (!deletingCategory) return;
     try {
-      await client.delete(`/categories/${id}`);
+      await client.delete(`/categories/${deletingCategory.id}`, {
+        params: reassignTo ? { reassign_to: reassignTo } : undefined,
+      });
       toast.success('删除成功');
+      setDeletingCategory(null);
       fetchCategories();
-    } catch {
-      toast.error('删除失败');
+    } catch (error) {
+      const err = error as { response?: { data?: { error?: string } } };
+      toast.error(err.response?.data?.error || '删除失败');
     }
   };
 
-  const openModal = (category?: Category) => {
+  const openModal = (category?: CategoryTreeNode) => {
     if (category) {
       setEditingCategory(category);
-      setFormData({ name: category.name });
+      setFormData({ name: category.name, parent_id: category.parent_id ?? null });
     } else {
       setEditingCategory(null);
-      setFormData({ name: '' });
+      setFormData({ name: '', parent_id: null });
     }
     setIsModalOpen(true);
   };
@@ -89,23 +127,34 @@ export default function Categories() {
         }
       />
 
-      <div className="grid gap-4 md:grid-cols-2 lg:grid-cols-3">
-        {categories.map((category) => (
-          <Card key={category.id}>
-            <CardContent className="flex justify-between items-center p-6">
-              <span className="font-medium">{category.name}</span>
-              <div className="space-x-2">
-                <Button variant="ghost" size="icon" onClick={() => openModal(category)}>
+      <Card>
+        <CardContent className="divide-y p-0">
+          {flatCategories.length === 0 && (
+            <div className="p-6 text-center text-sm text-muted-foreground">暂无分类</div>
+          )}
+          {flatCategories.map(({ node, depth }) => (
+            <div key={node.id} className="flex items-center justify-between gap-4 px-6 py-3">
+              <div className="min-w-0" style={{ paddingLeft: depth * 20 }}>
+                <div className="font-medium truncate">{node.name}</div>
+                <div className="text-xs text-muted-foreground">
+                  {node.total_component_count} 个元件 · 库存 {node.total_stock_quantity} · 价值 ¥{formatCents(node.total_stock_value_cents)}
+                  {node.children.length > 0 && node.component_count !== node.total_component_count && (
+                    <span>（本级 {node.component_count} 个）</span>
+                  )}
+                </div>
+              </div>
+              <div className="flex shrink-0 space-x-2">
+                <Button variant="ghost" size="icon" onClick={() => openModal(node)}>
                   <Pencil className="h-4 w-4" />
                 </Button>
-                <Button variant="ghost" size="icon" className="text-destructive hover:text-destructive" onClick={() => handleDelete(category.id)}>
+                <Button variant="ghost" size="icon" className="text-destructive hover:text-destructive" onClick={() => openDelete(node)}>
                   <Trash2 className="h-4 w-4" />
                 </Button>
               </div>
-            </CardContent>
-          </Card>
-        ))}
-      </div>
+            </div>
+          ))}
+        </CardContent>
+      </Card>
 
       <Modal
         isOpen={isModalOpen}
@@ -118,16 +167,68 @@ export default function Categories() {
           </>
         }
       >
-        <div className="space-y-2">
-          <Label htmlFor="name">分类名称</Label>
-          <Input
-            id="name"
-            value={formData.name}
-            onChange={(e) => setFormData({ name: e.target.value })}
-            placeholder="例如: 电阻, 电容"
-          />
+        <div className="space-y-4">
+          <div className="space-y-2">
+            <Label htmlFor="name">分类名称</Label>
+            <Input
+              id="name"
+              value={formData.name}
+              onChange={(e) => setFormData({ ...formData, name: e.target.value })}
+              placeholder="例如: 电阻, 电容"
+            />
+          </div>
+          <div className="space-y-2">
+            <Label htmlFor="parent">上级分类</Label>
+            <select
+              id="parent"
+              className={selectClassName}
+              value={formData.parent_id ?? ''}
+              onChange={(e) => setFormData({ ...formData, parent_id: e.target.value ? Number(e.target.value) : null })}
+            >
+              <option value="">无（顶级分类）</option>
+              {parentOptions.map(({ node, depth }) => (
+                <option key={node.id} value={node.id}>{'　'.repeat(depth)}{node.name}</option>
+              ))}
+            </select>
+          </div>
         </div>
       </Modal>
+
+      <Modal
+        isOpen={deletingCategory !== null}
+        onClose={() => setDeletingCategory(null)}
+        title="删除分类"
+        footer={
+          <>
+            <Button variant="outline" onClick={() => setDeletingCategory(null)}>取消</Button>
+            <Button variant="destructive" onClick={handleDelete}>删除</Button>
+          </>
+        }
+      >
+        {deletingCategory && (
+          <div className="space-y-4 text-sm">
+            <p>确定删除分类「{deletingCategory.name}」吗？</p>
+            {deletingCategory.component_count > 0 && (
+              <div className="space-y-2">
+                <Label htmlFor="reassign">该分类下有 {deletingCategory.component_count} 个元件，请选择转移到</Label>
+                <select
+                  id="reassign"
+                  className={selectClassName}
+                  value={reassignTo}
+                  onChange={(e) => setReassignTo(e.target.value)}
+                >
+                  <option value="">请选择分类</option>
+                  {flatCategories
+                    .filter(({ node }) => node.id !== deletingCategory.id)
+                    .map(({ node, depth }) => (
+                      <option key={node.id} value={node.id}>{'　'.repeat(depth)}{node.name}</option>
+                    ))}
+                </select>
+              </div>
+            )}
+          </div>
+        )}
+      </Modal>
     </div>
   );
 }
@@ -1,4 +1,5 @@
 import { lazy, Suspense, useEffect, useState, useRef } from 'react';
+import { useNavigate, useSearchParams } from 'react-router-dom';
 import {
   DndContext,
   closestCenter,
@@ -16,10 +17,10 @@ import {
   verticalListSortingStrategy,
 } from '@dnd-kit/sortable';
 import { CSS } from '@dnd-kit/utilities';
-import { Plus, Minus, Search, Edit, Copy, Trash2, Database, History, QrCode, Camera, Upload, Loader2, Hash, Download, Coins, Columns3, GripVertical, PackageMinus } from 'lucide-react';
+import { Plus, Minus, Search, Edit, Copy, Trash2, Database, History, QrCode, Camera, Upload, Loader2, Hash, Download, Coins, Columns3, GripVertical, PackageMinus, ExternalLink, CopyCheck, FileUp, Printer, MapPin } from 'lucide-react';
 import { toast } from 'react-hot-toast';
 import client from '../api/client';
-import { type Component, type Category, type Supplier, type StockLog, type Pagination, type ComponentOptions } from '../types';
+import { type Component, type Category, type Supplier, type StockLog, type Pagination, type SavedSearch, type SavedSearchParams, type StockHistoryPoint, type ABCClass, type QRScanResult } from '../types';
 import { Button } from '../components/ui/Button';
 import { Input } from '../components/ui/Input';
 import { Modal } from '../components/ui/Modal';
@@ -29,11 +30,20 @@ import { CollapsibleFilterPanel, type CollapsibleFilterPanelHandle } from '../co
 import { QuantityShortcuts } from '../components/ui/QuantityShortcuts';
 import { RowActionsMenu } from '../components/ui/RowActionsMenu';
 import { BatchStockOutModal } from '../components/BatchStockOutModal';
+import { DuplicateMergeModal } from '../components/DuplicateMergeModal';
+import { ComponentImportModal } from '../components/ComponentImportModal';
+import { LabelPrintModal } from '../components/LabelPrintModal';
+import { SavedSearchBar } from '../components/SavedSearchBar';
+import { SuggestionList } from '../components/ui/SuggestionList';
 const QRScanner = lazy(() => import('../components/QRScanner'));
 const CameraCapture = lazy(() => import('../components/CameraCapture'));
 import { yuanToCents, formatCents, formatMicro, calcUnitPriceMicro, calcOutboundCostCents } from '../utils/price';
 import { copyToClipboard } from '../utils/clipboard';
+import { buildProductUrl } from '../utils/supplier';
+import { locationQuery } from '../utils/query';
+import { downloadExport, EXPORT_FORMAT_OPTIONS, type ExportFormat } from '../utils/download';
 import { cn } from '../utils/cn';
+import { useSuggestions } from '../utils/useSuggestions';
 import {
   canRevoke,
   isReversal,
@@ -44,9 +54,12 @@ import {
   formatStockLogChangeAmount,
   stockLogIconLabel,
   isBackfillLog,
+  isMergeLog,
 } from '../utils/stockLog';
 
 type ComponentSearchFilters = {
+  keyword: string;
+  q: string;
   component_number: string;
   name: string;
   model: string;
@@ -54,12 +67,15 @@ type ComponentSearchFilters = {
   value: string;
   supplier: string;
   supplier_part_number: string;
+  abc_class: string;
 };
 
 type ComponentSearchParams = {
   page: number;
   page_size: number;
   category_id?: string;
+  keyword?: string;
+  q?: string;
   component_number?: string;
   name?: string;
   model?: string;
@@ -67,11 +83,14 @@ type ComponentSearchParams = {
   value?: string;
   supplier?: string;
   supplier_part_number?: string;
+  abc_class?: string;
   sort_by?: string;
   sort_order?: string;
 };
 
 const EMPTY_SEARCH_FILTERS: ComponentSearchFilters = {
+  keyword: '',
+  q: '',
   component_number: '',
   name: '',
   model: '',
@@ -79,9 +98,12 @@ const EMPTY_SEARCH_FILTERS: ComponentSearchFilters = {
   value: '',
   supplier: '',
   supplier_part_number: '',
+  abc_class: '',
 };
 
 const SEARCH_FILTER_FIELDS: { key: keyof ComponentSearchFilters; label: string; placeholder: string }[] = [
+  { key: 'keyword', label: '全文搜索', placeholder: '名称、型号、描述、供应商…（空格拆词）' },
+  { key: 'q', label: '高级查询', placeholder: 'pkg:0603 value:10k stock:<100 cat:电阻 -tag:obsolete (mfr:TI OR mfr:ST) location:A1*' },
   { key: 'component_number', label: '编号', placeholder: 'HB-000001' },
   { key: 'name', label: '名称', placeholder: '元件名称' },
   { key: 'model', label: '厂家型号', placeholder: 'RC0603FR-0710KL' },
@@ -90,6 +112,8 @@ const SEARCH_FILTER_FIELDS: { key: keyof ComponentSearchFilters; label: string;
 ];
 
 const SEARCH_PARAM_KEYS: (keyof ComponentSearchFilters)[] = [
+  'keyword',
+  'q',
   'component_number',
   'name',
   'model',
@@ -97,10 +121,36 @@ const SEARCH_PARAM_KEYS: (keyof ComponentSearchFilters)[] = [
   'value',
   'supplier',
   'supplier_part_number',
+  'abc_class',
 ];
 
 const PAGE_SIZE_OPTIONS = [10, 20, 50, 100];
 
+const ABC_CLASS_STYLES: Record<ABCClass, string> = {
+  A: 'bg-red-100 text-red-700',
+  B: 'bg-yellow-100 text-yellow-800',
+  C: 'bg-muted text-muted-foreground',
+};
+
+const ABC_FILTER_OPTIONS = [
+  { value: '', label: '全部' },
+  { value: 'A', label: 'A 类' },
+  { value: 'B', label: 'B 类' },
+  { value: 'C', label: 'C 类' },
+  { value: 'A,B', label: 'A + B 类' },
+];
+
+const HIGHLIGHT_FIELD_LABELS: Record<string, string> = {
+  name: '名称',
+  component_number: '编号',
+  model: '型号',
+  manufacturer: '制造商',
+  value: '参数',
+  supplier_part_number: '料号',
+  description: '描述',
+  supplier: '供应商',
+};
+
 type ExportColumnKey =
   | 'component_number'
   | 'name'
@@ -117,7 +167,12 @@ type ExportColumnKey =
   | 'supplier_part_number'
   | 'datasheet_url'
   | 'created_at'
-  | 'updated_at';
+  | 'updated_at'
+  | 'avg_daily_consumption'
+  | 'days_of_cover'
+  | 'stockout_date'
+  | 'reorder_quantity'
+  | 'abc_class';
 
 type ComponentSortOrder = 'asc' | 'desc';
 
@@ -160,6 +215,11 @@ const EXPORT_COLUMNS: ExportColumnConfig[] = [
   { key: 'datasheet_url', defaultHeader: '数据手册', defaultSelected: false, defaultTableSelected: true },
   { key: 'created_at', defaultHeader: '创建时间', defaultSelected: false, defaultTableSelected: false },
   { key: 'updated_at', defaultHeader: '更新时间', defaultSelected: false, defaultTableSelected: false },
+  { key: 'avg_daily_consumption', defaultHeader: '日均消耗', defaultSelected: false, defaultTableSelected: false },
+  { key: 'days_of_cover', defaultHeader: '可用天数', defaultSelected: false, defaultTableSelected: false },
+  { key: 'stockout_date', defaultHeader: '预计断货日期', defaultSelected: false, defaultTableSelected: false },
+  { key: 'reorder_quantity', defaultHeader: '建议补货数量', defaultSelected: false, defaultTableSelected: false },
+  { key: 'abc_class', defaultHeader: 'ABC分类', defaultSelected: false, defaultTableSelected: false },
 ];
 
 const VALID_COLUMN_KEYS = new Set<ExportColumnKey>(EXPORT_COLUMNS.map(column => column.key));
@@ -443,7 +503,7 @@ function ColumnSettingsPanel({
   );
 }
 
-const renderCopyableCell = (value: string | null | undefined, className?: string) => {
+const renderCopyableCell = (value: string | null | undefined, className?: string, href?: string) => {
   const text = value?.trim();
   if (!text) {
     return <td className={cn('p-4 align-middle', className)}>-</td>;
@@ -462,20 +522,39 @@ const renderCopyableCell = (value: string | null | undefined, className?: string
       >
         {text}
       </button>
+      {href && (
+        <a
+          href={href}
+          target="_blank"
+          rel="noopener noreferrer"
+          title="打开供应商商品页"
+          className="ml-1 inline-flex align-middle text-muted-foreground hover:text-primary"
+        >
+          <ExternalLink className="h-3.5 w-3.5" />
+        </a>
+      )}
     </td>
   );
 };
 
 export default function Components() {
+  const [urlParams] = useSearchParams();
+  const navigate = useNavigate();
+  const initialSavedSearchId = Number(urlParams.get('saved_search_id')) || undefined;
   const [components, setComponents] = useState<Component[]>([]);
   const [categories, setCategories] = useState<Category[]>([]);
   const [suppliers, setSuppliers] = useState<Supplier[]>([]);
   const [pagination, setPagination] = useState<Pagination>({ page: 1, page_size: 20, total: 0, total_page: 0 });
   const [loading, setLoading] = useState(false);
-  const [searchFilters, setSearchFilters] = useState<ComponentSearchFilters>(EMPTY_SEARCH_FILTERS);
+  const [fuzzySearch, setFuzzySearch] = useState(false);
+  // 仪表盘 ABC 分类卡片通过 ?abc_class= 跳转、其他页面扫到元件或位置二维码通过 ?q= 跳转时预先套用该筛选
+  const [searchFilters, setSearchFilters] = useState<ComponentSearchFilters>(() => ({
+    ...EMPTY_SEARCH_FILTERS,
+    q: urlParams.get('q') ?? '',
+    abc_class: urlParams.get('abc_class') ?? '',
+  }));
   const [selectedCategory, setSelectedCategory] = useState<string>('');
   const [categorySearchInput, setCategorySearchInput] = useState('');
-  const [manufacturerOptions, setManufacturerOptions] = useState<string[]>([]);
   const [showSearchManufacturerDropdown, setShowSearchManufacturerDropdown] = useState(false);
   const [showSearchSupplierDropdown, setShowSearchSupplierDropdown] = useState(false);
   const [showSearchCategoryDropdown, setShowSearchCategoryDropdown] = useState(false);
@@ -485,12 +564,16 @@ export default function Components() {
   const [batchLocation, setBatchLocation] = useState('');
   const [isBatchUpdating, setIsBatchUpdating] = useState(false);
   const [isBatchStockOutOpen, setIsBatchStockOutOpen] = useState(false);
+  const [isDuplicatesOpen, setIsDuplicatesOpen] = useState(false);
+  const [isImportOpen, setIsImportOpen] = useState(false);
+  const [labelPrint, setLabelPrint] = useState<{ kind: 'components' | 'locations'; componentIds: number[] } | null>(null);
   const [batchStockOutSeed, setBatchStockOutSeed] = useState<Component[]>([]);
   const [isGeneratingNumbers, setIsGeneratingNumbers] = useState(false);
   const [isExportOpen, setIsExportOpen] = useState(false);
   const [exportColumns, setExportColumns] = useState<ColumnState[]>(() => readStoredExportColumns());
   const [exportColumnsDraft, setExportColumnsDraft] = useState<ColumnState[]>(() => readStoredExportColumns());
   const [isExporting, setIsExporting] = useState(false);
+  const [exportFormat, setExportFormat] = useState<ExportFormat>('csv');
   const [tableColumns, setTableColumns] = useState<ColumnState[]>(() => readStoredTableColumns());
   const [tableColumnsDraft, setTableColumnsDraft] = useState<ColumnState[]>(() => readStoredTableColumns());
   const [isColumnSettingsOpen, setIsColumnSettingsOpen] = useState(false);
@@ -516,8 +599,6 @@ export default function Components() {
   const [showCategoryDropdown, setShowCategoryDropdown] = useState(false);
   const [supplierInput, setSupplierInput] = useState('');
   const [showSupplierDropdown, setShowSupplierDropdown] = useState(false);
-  const [packageOptions, setPackageOptions] = useState<string[]>([]);
-  const [locationOptions, setLocationOptions] = useState<string[]>([]);
   const [showPackageDropdown, setShowPackageDropdown] = useState(false);
   const [showLocationDropdown, setShowLocationDropdown] = useState(false);
   const [showBatchLocationDropdown, setShowBatchLocationDropdown] = useState(false);
@@ -529,6 +610,8 @@ export default function Components() {
   const [previewUrl, setPreviewUrl] = useState<string>('');
   const [showImageMenu, setShowImageMenu] = useState(false);
   const [formTotalPriceYuan, setFormTotalPriceYuan] = useState('');
+  // 标签输入框原文，逗号分隔，提交时拆分
+  const [formTagsText, setFormTagsText] = useState('');
 
 
   // Stock State
@@ -538,6 +621,7 @@ export default function Components() {
   
   // Logs State
   const [componentLogs, setComponentLogs] = useState<StockLog[]>([]);
+  const [componentHistory, setComponentHistory] = useState<StockHistoryPoint[]>([]);
   const [revokingLogId, setRevokingLogId] = useState<number | null>(null);
 
   // Platform Import
@@ -625,13 +709,20 @@ export default function Components() {
         const value = filters[key].trim();
         if (value) params[key] = value;
       }
+      // 全文搜索且未改过排序时按相关度排序
+      if (params.keyword && nextSortBy === DEFAULT_SORT_BY && nextSortOrder === DEFAULT_SORT_ORDER) {
+        params.sort_by = 'relevance';
+        delete params.sort_order;
+      }
 
       const res = await client.get('/components', { params });
       setComponents(res.data.data || []);
+      setFuzzySearch(Boolean(res.data.search?.fuzzy));
       setPagination(res.data.pagination || { page: 1, page_size: 20, total: 0, total_page: 0 });
       setSelectedIds([]);
-    } catch {
-      toast.error('加载元件失败');
+    } catch (error) {
+      const err = error as { response?: { data?: { error?: string } } };
+      toast.error(err.response?.data?.error || '加载元件失败');
     } finally {
       if (showLoading) setLoading(false);
     }
@@ -655,22 +746,9 @@ export default function Components() {
     }
   };
 
-  const fetchComponentOptions = async () => {
-    try {
-      const res = await client.get('/components/options');
-      const options = res.data.data as ComponentOptions;
-      setPackageOptions(options.packages || []);
-      setLocationOptions(options.locations || []);
-      setManufacturerOptions(options.manufacturers || []);
-    } catch {
-      toast.error('加载历史选项失败');
-    }
-  };
-
   useEffect(() => {
     fetchCategories();
     fetchSuppliers();
-    fetchComponentOptions();
     fetchComponents();
   }, []); // eslint-disable-line react-hooks/exhaustive-deps
 
@@ -690,6 +768,56 @@ export default function Components() {
     fetchComponents(1, pagination.page_size, EMPTY_SEARCH_FILTERS, '', '', sortBy, sortOrder);
   };
 
+  // getSavedSearchParams 收集当前的筛选、排序与显示列，用于保存搜索
+  const getSavedSearchParams = (): SavedSearchParams => {
+    const params: SavedSearchParams = {
+      sort_by: sortBy,
+      sort_order: sortOrder,
+      columns: tableColumns.filter(column => column.selected).map(column => column.key),
+    };
+    const resolvedCategoryId = resolveCategoryId(categorySearchInput, selectedCategory);
+    if (resolvedCategoryId) params.category_id = Number(resolvedCategoryId);
+    for (const key of SEARCH_PARAM_KEYS) {
+      const value = searchFilters[key].trim();
+      if (value) params[key] = value;
+    }
+    return params;
+  };
+
+  // applySavedSearch 套用保存的搜索：替换筛选与排序，按保存的列调整表格显示（不写入本地列设置）
+  const applySavedSearch = (search: SavedSearch) => {
+    const saved = search.params;
+    const filters = { ...EMPTY_SEARCH_FILTERS };
+    for (const key of SEARCH_PARAM_KEYS) {
+      filters[key] = saved[key] ?? '';
+    }
+    const categoryId = saved.category_id ? String(saved.category_id) : '';
+    const categoryName = categories.find(c => String(c.id) === categoryId)?.name ?? '';
+    const nextSortBy = saved.sort_by && VALID_COLUMN_KEYS.has(saved.sort_by as ExportColumnKey)
+      ? (saved.sort_by as ExportColumnKey)
+      : sortBy;
+    const nextSortOrder: ComponentSortOrder = saved.sort_order === 'asc' || saved.sort_order === 'desc'
+      ? saved.sort_order
+      : sortOrder;
+
+    setSearchFilters(filters);
+    setSelectedCategory(categoryId);
+    setCategorySearchInput(categoryName);
+    setSortBy(nextSortBy);
+    setSortOrder(nextSortOrder);
+    if (saved.columns && saved.columns.length > 0) {
+      const keys = saved.columns.filter(key => VALID_COLUMN_KEYS.has(key as ExportColumnKey)) as ExportColumnKey[];
+      if (keys.length > 0) {
+        setTableColumns(prev => [
+          ...keys.flatMap(key => prev.filter(column => column.key === key).map(column => ({ ...column, selected: true }))),
+          ...prev.filter(column => !keys.includes(column.key)).map(column => ({ ...column, selected: false })),
+        ]);
+      }
+    }
+    setPagination(prev => ({ ...prev, page: 1 }));
+    fetchComponents(1, pagination.page_size, filters, categoryName, categoryId, nextSortBy, nextSortOrder);
+  };
+
   const handleSortByChange = (value: ExportColumnKey) => {
     setSortBy(value);
     persistComponentSort(value, sortOrder);
@@ -726,9 +854,10 @@ export default function Components() {
     setSelectedCategory(matched ? String(matched.id) : '');
   };
 
-  const filteredManufacturerOptions = manufacturerOptions.filter(option =>
-    option.toLowerCase().includes(searchFilters.manufacturer.toLowerCase())
-  );
+  const manufacturerSuggestions = useSuggestions('manufacturer', searchFilters.manufacturer, showSearchManufacturerDropdown);
+  const packageSuggestions = useSuggestions('package', formData.package || '', showPackageDropdown);
+  const locationSuggestions = useSuggestions('location', formData.location || '', showLocationDropdown);
+  const batchLocationSuggestions = useSuggestions('location', batchLocation, showBatchLocationDropdown);
 
   const filteredSupplierOptions = suppliers.filter(s =>
     s.name.toLowerCase().includes(searchFilters.supplier.toLowerCase())
@@ -791,7 +920,6 @@ export default function Components() {
       setIsBatchLocationOpen(false);
       setBatchLocation('');
       fetchComponents(pagination.page, pagination.page_size);
-      fetchComponentOptions();
     } catch {
       toast.error('批量更新位置失败');
     } finally {
@@ -904,8 +1032,27 @@ export default function Components() {
     switch (key) {
       case 'component_number':
         return renderCopyableCell(component.component_number, 'font-mono text-xs');
-      case 'name':
-        return <td className="p-4 align-middle font-medium">{component.name}</td>;
+      case 'name': {
+        const nameHighlight = component.highlights?.find(h => h.field === 'name');
+        const otherHighlights = component.highlights?.filter(h => h.field !== 'name') ?? [];
+        return (
+          <td className="p-4 align-middle font-medium [&_mark]:bg-yellow-200 [&_mark]:text-foreground">
+            {nameHighlight ? <span dangerouslySetInnerHTML={{ __html: nameHighlight.snippet }} /> : component.name}
+            {component.tags && component.tags.length > 0 && (
+              <div className="mt-1 flex flex-wrap gap-1">
+                {component.tags.map(tag => (
+                  <span key={tag} className="rounded bg-secondary px-1.5 py-0.5 text-xs font-normal text-secondary-foreground">{tag}</span>
+                ))}
+              </div>
+            )}
+            {otherHighlights.map(h => (
+              <div key={h.field} className="text-xs font-normal text-muted-foreground">
+                {HIGHLIGHT_FIELD_LABELS[h.field] || h.field}：<span dangerouslySetInnerHTML={{ __html: h.snippet }} />
+              </div>
+            ))}
+          </td>
+        );
+      }
       case 'model':
         return renderCopyableCell(component.model);
       case 'manufacturer':
@@ -944,7 +1091,11 @@ export default function Components() {
       case 'supplier':
         return <td className="p-4 align-middle">{component.supplier?.name || '-'}</td>;
       case 'supplier_part_number':
-        return renderCopyableCell(component.supplier_part_number);
+        return renderCopyableCell(
+          component.supplier_part_number,
+          undefined,
+          buildProductUrl(component.supplier?.product_url_template, component.supplier_part_number),
+        );
       case 'datasheet_url':
         return (
           <td className="p-4 align-middle">
@@ -965,12 +1116,52 @@ export default function Components() {
             {component.updated_at ? new Date(component.updated_at).toLocaleString() : '-'}
           </td>
         );
+      case 'avg_daily_consumption':
+        return (
+          <td className="p-4 align-middle">
+            {component.forecast ? component.forecast.avg_daily_consumption.toFixed(2) : '-'}
+          </td>
+        );
+      case 'days_of_cover': {
+        const days = component.forecast?.days_of_cover;
+        return (
+          <td className="p-4 align-middle">
+            {days != null ? (
+              <span className={days < 14 ? 'text-red-600 font-medium' : days < 30 ? 'text-yellow-700' : undefined}>
+                {days.toFixed(1)}
+              </span>
+            ) : '-'}
+          </td>
+        );
+      }
+      case 'stockout_date':
+        return (
+          <td className="p-4 align-middle whitespace-nowrap">
+            {component.forecast?.stockout_date ? new Date(component.forecast.stockout_date).toLocaleDateString() : '-'}
+          </td>
+        );
+      case 'reorder_quantity':
+        return (
+          <td className="p-4 align-middle">
+            {component.forecast?.reorder_quantity ? component.forecast.reorder_quantity : '-'}
+          </td>
+        );
+      case 'abc_class':
+        return (
+          <td className="p-4 align-middle">
+            {component.abc_class ? (
+              <span className={`inline-flex h-6 w-6 items-center justify-center rounded text-xs font-semibold ${ABC_CLASS_STYLES[component.abc_class]}`}>
+                {component.abc_class}
+              </span>
+            ) : '-'}
+          </td>
+        );
       default:
         return <td className="p-4 align-middle">-</td>;
     }
   };
 
-  const handleExportCSV = async () => {
+  const handleExport = async () => {
     const selectedColumns = exportColumnsDraft.filter(column => column.selected);
     if (selectedColumns.length === 0) {
       toast.error('请至少选择一列');
@@ -986,30 +1177,8 @@ export default function Components() {
         selectedColumns.map(column => getColumnHeader(column)).join(','),
       );
 
-      const response = await fetch(`/api/v1/components/export?${params.toString()}`, {
-        credentials: 'include',
-      });
-      if (!response.ok) {
-        const data = await response.json().catch(() => null) as { error?: string } | null;
-        throw new Error(data?.error || '导出失败');
-      }
-
-      const blob = await response.blob();
-      const disposition = response.headers.get('Content-Disposition');
-      let filename = 'components.csv';
-      if (disposition) {
-        const match = disposition.match(/filename="?([^"]+)"?/);
-        if (match?.[1]) filename = match[1];
-      }
-
-      const url = URL.createObjectURL(blob);
-      const link = document.createElement('a');
-      link.href = url;
-      link.download = filename;
-      document.body.appendChild(link);
-      link.click();
-      document.body.removeChild(link);
-      URL.revokeObjectURL(url);
+      params.set('format', exportFormat);
+      await downloadExport('/components/export', params, `components.${exportFormat}`);
 
       toast.success('导出成功');
       setExportColumns(exportColumnsDraft);
@@ -1059,10 +1228,12 @@ export default function Components() {
       setCategoryInput(component.category?.name || '');
       setSupplierInput(component.supplier?.name || '');
       setFormTotalPriceYuan('');
+      setFormTagsText(component.tags?.join(', ') ?? '');
     } else {
       setEditingComponent(null);
       setFormData({ stock_quantity: 0 });
       setFormTotalPriceYuan('');
+      setFormTagsText('');
       setCategoryInput('');
       setSupplierInput('');
       setPreviewUrl('');
@@ -1097,6 +1268,7 @@ export default function Components() {
     setCategoryInput(component.category?.name || '');
     setSupplierInput(component.supplier?.name || '');
     setFormTotalPriceYuan('');
+    setFormTagsText(component.tags?.join(', ') ?? '');
     setPreviewUrl(component.image_url || '');
     setIsFormOpen(true);
   };
@@ -1171,7 +1343,8 @@ export default function Components() {
         supplier: undefined,
         category: undefined,
         component_number: formData.component_number?.trim() || undefined,
-        stock_quantity: Number(formData.stock_quantity)
+        stock_quantity: Number(formData.stock_quantity),
+        tags: formTagsText.split(/[,，]/).map(tag => tag.trim()).filter(Boolean),
       };
 
       const totalPriceCents = yuanToCents(formTotalPriceYuan);
@@ -1188,6 +1361,11 @@ export default function Components() {
         const res = await client.post('/components', data);
         savedId = res.data.data.id;
         toast.success('添加成功');
+        const duplicates: Component[] = res.data.likely_duplicates || [];
+        if (duplicates.length > 0) {
+          const labels = duplicates.slice(0, 3).map(item => item.component_number || item.name).join('、');
+          toast(`可能与已有元件重复：${labels}${duplicates.length > 3 ? ' 等' : ''}，可通过「查找重复」合并`, { icon: '⚠️', duration: 6000 });
+        }
       }
 
       // Upload Image if selected
@@ -1207,7 +1385,6 @@ export default function Components() {
 
       setIsFormOpen(false);
       fetchComponents(pagination.page, pagination.page_size);
-      fetchComponentOptions();
     } catch (error) {
       console.error(error);
       const err = error as { response?: { data?: { error?: string } } };
@@ -1223,7 +1400,7 @@ export default function Components() {
     }
     setIsImportParsing(true);
     try {
-      const res = await client.post('/components/parse', { code: parseCode, use_llm: useAIParse });
+      const res = await client.get('/components/parse', { params: { code: parseCode, use_llm: useAIParse } });
       const data = res.data.data as ParsedComponentInfo;
       applyParsedComponent(data);
       toast.success('解析成功');
@@ -1303,12 +1480,30 @@ export default function Components() {
   const openLogs = async (component: Component) => {
     setEditingComponent(component);
     setIsLogsOpen(true);
+    setComponentHistory([]);
     try {
       const res = await client.get(`/components/${component.id}/logs`);
       setComponentLogs(res.data.data || []);
     } catch {
       toast.error('加载记录失败');
     }
+    // 近 12 个月每月末的库存，加载失败不影响记录列表
+    const now = new Date();
+    const from = new Date(now.getFullYear(), now.getMonth() - 11, 1);
+    const pad = (n: number) => String(n).padStart(2, '0');
+    try {
+      const res = await client.get<{ data: StockHistoryPoint[] }>(`/components/${component.id}/stock-history`, {
+        params: {
+          from: `${from.getFullYear()}-${pad(from.getMonth() + 1)}-01`,
+          to: `${now.getFullYear()}-${pad(now.getMonth() + 1)}-${pad(now.getDate())}`,
+          bucket: 'month',
+          tz: Intl.DateTimeFormat().resolvedOptions().timeZone,
+        },
+      });
+      setComponentHistory(res.data.data || []);
+    } catch (error) {
+      console.error('Failed to fetch stock history:', error);
+    }
   };
 
   const handleRevokeLog = async (log: StockLog) => {
@@ -1336,12 +1531,55 @@ export default function Components() {
   };
 
   // QR Handlers
+  // 扫到自有二维码：元件直接打开编辑，位置按 loc:= 精确筛选，预入库跳转到预入库页面
+  const handleOwnCodeScan = (scanned: QRScanResult) => {
+    switch (scanned.kind) {
+      case 'component':
+        if (isFormOpen) {
+          toast.error(`已扫描到元件 ${scanned.component.component_number || scanned.component.name}，请先关闭当前表单`);
+          return;
+        }
+        openForm(scanned.component);
+        break;
+      case 'location': {
+        const filters = { ...EMPTY_SEARCH_FILTERS, q: locationQuery(scanned.location) };
+        setSearchFilters(filters);
+        setSelectedCategory('');
+        setCategorySearchInput('');
+        setPagination(prev => ({ ...prev, page: 1 }));
+        fetchComponents(1, pagination.page_size, filters, '', '');
+        toast.success(`位置 ${scanned.location}：${scanned.component_count} 种元件`);
+        break;
+      }
+      case 'pre_stock':
+        navigate(`/pre-stocks?pre_stock_id=${scanned.pre_stock.id}`);
+        break;
+    }
+  };
+
+  const downloadQRCode = async (component: Component) => {
+    try {
+      await downloadExport(
+        `/components/${component.id}/qrcode`,
+        new URLSearchParams({ format: 'svg', download: 'true' }),
+        `${component.component_number || component.id}.svg`,
+      );
+    } catch (error) {
+      toast.error(error instanceof Error ? error.message : '下载二维码失败');
+    }
+  };
+
   const handleScan = async (data: string) => {
     if (isImportParsing) return;
     setIsScannerOpen(false);
     setIsImportParsing(true);
     try {
-      const res = await client.post('/components/parse-qrcode', { qrcode_data: data, use_llm: useAIParse });
+      const res = await client.get('/components/parse-qrcode', { params: { qrcode_data: data, use_llm: useAIParse } });
+      const scanned = res.data.data as QRScanResult | { kind?: undefined };
+      if (scanned.kind) {
+        handleOwnCodeScan(scanned);
+        return;
+      }
       const { component, quantity } = res.data.data as { component: ParsedComponentInfo; quantity: number };
 
       if (isFormOpen) {
@@ -1375,7 +1613,13 @@ export default function Components() {
               <Columns3 className="mr-2 h-4 w-4" /> 列设置
             </Button>
             <Button variant="outline" onClick={openExportModal}>
-              <Download className="mr-2 h-4 w-4" /> 导出 CSV
+              <Download className="mr-2 h-4 w-4" /> 导出
+            </Button>
+            <Button variant="outline" onClick={() => setLabelPrint({ kind: 'components', componentIds: [] })}>
+              <Printer className="mr-2 h-4 w-4" /> 打印标签
+            </Button>
+            <Button variant="outline" onClick={() => setLabelPrint({ kind: 'locations', componentIds: [] })}>
+              <MapPin className="mr-2 h-4 w-4" /> 位置标签
             </Button>
             <Button variant="outline" onClick={handleGenerateNumbers} disabled={isGeneratingNumbers}>
               {isGeneratingNumbers ? (
@@ -1392,6 +1636,12 @@ export default function Components() {
             <Button variant="outline" onClick={() => openBatchStockOut()}>
               <PackageMinus className="mr-2 h-4 w-4" /> 批量出库
             </Button>
+            <Button variant="outline" onClick={() => setIsImportOpen(true)}>
+              <FileUp className="mr-2 h-4 w-4" /> 导入
+            </Button>
+            <Button variant="outline" onClick={() => setIsDuplicatesOpen(true)}>
+              <CopyCheck className="mr-2 h-4 w-4" /> 查找重复
+            </Button>
             <Button onClick={() => openForm()}>
               <Plus className="mr-2 h-4 w-4" /> 添加元件
             </Button>
@@ -1412,9 +1662,11 @@ export default function Components() {
         }
       >
         <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-4 gap-3">
+          {renderSearchField('keyword')}
           {renderSearchField('component_number')}
           {renderSearchField('name')}
           {renderSearchField('model')}
+          <div className="md:col-span-2 lg:col-span-4">{renderSearchField('q')}</div>
           <div className="space-y-1">
             <Label htmlFor="search-manufacturer" className="text-xs text-muted-foreground">制造商</Label>
             <div className="relative">
@@ -1428,25 +1680,14 @@ export default function Components() {
                 onKeyDown={handleSearchKeyDown}
               />
               {showSearchManufacturerDropdown && (
-                <div className="absolute z-50 w-full mt-1 bg-popover border rounded-md shadow-lg max-h-60 overflow-auto">
-                  {filteredManufacturerOptions.map(option => (
-                    <div
-                      key={option}
-                      className="px-3 py-2 text-sm cursor-pointer hover:bg-accent hover:text-accent-foreground"
-                      onClick={() => {
-                        handleSearchFilterChange('manufacturer', option);
-                        setShowSearchManufacturerDropdown(false);
-                      }}
-                    >
-                      {option}
-                    </div>
-                  ))}
-                  {filteredManufacturerOptions.length === 0 && (
-                    <div className="px-3 py-2 text-sm text-muted-foreground">
-                      {searchFilters.manufacturer ? '无匹配制造商' : '无历史制造商'}
-                    </div>
-                  )}
-                </div>
+                <SuggestionList
+                  suggestions={manufacturerSuggestions}
+                  onSelect={value => {
+                    handleSearchFilterChange('manufacturer', value);
+                    setShowSearchManufacturerDropdown(false);
+                  }}
+                  emptyText={searchFilters.manufacturer ? '无匹配制造商' : '无历史制造商'}
+                />
               )}
             </div>
           </div>
@@ -1524,6 +1765,19 @@ export default function Components() {
               )}
             </div>
           </div>
+          <div className="space-y-1">
+            <Label htmlFor="search-abc-class" className="text-xs text-muted-foreground">ABC 分类</Label>
+            <select
+              id="search-abc-class"
+              value={searchFilters.abc_class}
+              onChange={e => handleSearchFilterChange('abc_class', e.target.value)}
+              className="h-9 w-full rounded-md border border-input bg-background px-2 text-sm"
+            >
+              {ABC_FILTER_OPTIONS.map(option => (
+                <option key={option.value} value={option.value}>{option.label}</option>
+              ))}
+            </select>
+          </div>
         </div>
         <div className="flex flex-col sm:flex-row gap-3 sm:items-end">
           <div className="flex flex-col sm:flex-row gap-3 sm:items-end">
@@ -1555,7 +1809,12 @@ export default function Components() {
               </select>
             </div>
           </div>
-          <div className="flex gap-2 sm:ml-auto">
+          <div className="flex flex-wrap gap-2 sm:ml-auto">
+            <SavedSearchBar
+              getCurrentParams={getSavedSearchParams}
+              onApply={applySavedSearch}
+              initialId={initialSavedSearchId}
+            />
             <Button onClick={handleSearch} variant="secondary">
               <Search className="h-4 w-4 mr-2" />搜索
             </Button>
@@ -1574,6 +1833,9 @@ export default function Components() {
             <Button variant="outline" onClick={() => openBatchStockOut(components.filter(c => selectedIds.includes(c.id)))}>
               批量出库
             </Button>
+            <Button variant="outline" onClick={() => setLabelPrint({ kind: 'components', componentIds: selectedIds })}>
+              打印标签
+            </Button>
             <Button onClick={() => setIsBatchLocationOpen(true)}>批量修改位置</Button>
           </div>
         </div>
@@ -1651,6 +1913,9 @@ export default function Components() {
                         { key: 'backfill', icon: Coins, label: '补录价格', onClick: () => openBackfill(component), iconClassName: 'text-amber-600' },
                         { key: 'logs', icon: History, label: '记录', onClick: () => openLogs(component), iconClassName: 'text-gray-500' },
                         { key: 'copy', icon: Copy, label: '复制', onClick: () => openCopyForm(component), iconClassName: 'text-emerald-600' },
+                        ...(component.component_number
+                          ? [{ key: 'qrcode', icon: QrCode, label: '二维码', onClick: () => downloadQRCode(component), iconClassName: 'text-gray-500' }]
+                          : []),
                         { key: 'delete', icon: Trash2, label: '删除', onClick: () => handleDelete(component.id), destructive: true },
                       ]}
                     />
@@ -1666,6 +1931,7 @@ export default function Components() {
       <div className="flex flex-col sm:flex-row justify-between gap-3 items-start sm:items-center">
         <div className="flex items-center gap-3 text-sm text-muted-foreground">
           <span>共 {pagination.total} 条</span>
+          {fuzzySearch && <span className="text-amber-600">未找到精确匹配，已按型号容错匹配</span>}
           <div className="flex items-center gap-2">
             <span>每页</span>
             <select
@@ -1731,27 +1997,14 @@ export default function Components() {
                 autoFocus
               />
               {showBatchLocationDropdown && (
-                <div className="absolute z-50 w-full mt-1 bg-popover border rounded-md shadow-lg max-h-60 overflow-auto">
-                  {locationOptions
-                    .filter(loc => loc.toLowerCase().includes(batchLocation.toLowerCase()))
-                    .map(loc => (
-                      <div
-                        key={loc}
-                        className="px-3 py-2 text-sm cursor-pointer hover:bg-accent hover:text-accent-foreground"
-                        onClick={() => {
-                          setBatchLocation(loc);
-                          setShowBatchLocationDropdown(false);
-                        }}
-                      >
-                        {loc}
-                      </div>
-                    ))}
-                  {locationOptions.filter(loc => loc.toLowerCase().includes(batchLocation.toLowerCase())).length === 0 && (
-                    <div className="px-3 py-2 text-sm text-muted-foreground">
-                      {batchLocation ? '无匹配位置' : '无历史位置'}
-                    </div>
-                  )}
-                </div>
+                <SuggestionList
+                  suggestions={batchLocationSuggestions}
+                  onSelect={value => {
+                    setBatchLocation(value);
+                    setShowBatchLocationDropdown(false);
+                  }}
+                  emptyText={batchLocation ? '无匹配位置' : '无历史位置'}
+                />
               )}
             </div>
           </div>
@@ -1765,6 +2018,29 @@ export default function Components() {
         onSuccess={handleBatchStockOutSuccess}
       />
 
+      <DuplicateMergeModal
+        isOpen={isDuplicatesOpen}
+        onClose={() => setIsDuplicatesOpen(false)}
+        onSuccess={() => fetchComponents(pagination.page, pagination.page_size)}
+      />
+
+      <ComponentImportModal
+        isOpen={isImportOpen}
+        onClose={() => setIsImportOpen(false)}
+        onSuccess={() => fetchComponents(pagination.page, pagination.page_size)}
+      />
+
+      <LabelPrintModal
+        isOpen={labelPrint !== null}
+        onClose={() => setLabelPrint(null)}
+        kind={labelPrint?.kind ?? 'components'}
+        componentIds={labelPrint?.componentIds}
+        filterParams={labelPrint?.kind === 'components' ? buildExportSearchParams() : undefined}
+        initialLocations={labelPrint?.kind === 'locations'
+          ? [...new Set(components.filter(c => selectedIds.includes(c.id) && c.location).map(c => c.location as string))]
+          : undefined}
+      />
+
       {/* Edit/Add Modal */}
       <Modal 
         isOpen={isFormOpen} 
@@ -1903,27 +2179,14 @@ export default function Components() {
                             placeholder="例如 0603"
                         />
                         {showPackageDropdown && (
-                            <div className="absolute z-50 w-full mt-1 bg-popover border rounded-md shadow-lg max-h-60 overflow-auto">
-                                {packageOptions
-                                    .filter(pkg => pkg.toLowerCase().includes((formData.package || '').toLowerCase()))
-                                    .map(pkg => (
-                                    <div
-                                        key={pkg}
-                                        className="px-3 py-2 text-sm cursor-pointer hover:bg-accent hover:text-accent-foreground"
-                                        onClick={() => {
-                                            setFormData({ ...formData, package: pkg });
-                                            setShowPackageDropdown(false);
-                                        }}
-                                    >
-                                        {pkg}
-                                    </div>
-                                ))}
-                                {packageOptions.filter(pkg => pkg.toLowerCase().includes((formData.package || '').toLowerCase())).length === 0 && (
-                                    <div className="px-3 py-2 text-sm text-muted-foreground">
-                                        {(formData.package || '') ? '无匹配封装' : '无历史封装'}
-                                    </div>
-                                )}
-                            </div>
+                            <SuggestionList
+                                suggestions={packageSuggestions}
+                                onSelect={value => {
+                                    setFormData({ ...formData, package: value });
+                                    setShowPackageDropdown(false);
+                                }}
+                                emptyText={(formData.package || '') ? '无匹配封装' : '无历史封装'}
+                            />
                         )}
                     </div>
                 </div>
@@ -2038,27 +2301,14 @@ export default function Components() {
                             placeholder="例如 A1-03"
                         />
                         {showLocationDropdown && (
-                            <div className="absolute z-50 w-full mt-1 bg-popover border rounded-md shadow-lg max-h-60 overflow-auto">
-                                {locationOptions
-                                    .filter(loc => loc.toLowerCase().includes((formData.location || '').toLowerCase()))
-                                    .map(loc => (
-                                    <div
-                                        key={loc}
-                                        className="px-3 py-2 text-sm cursor-pointer hover:bg-accent hover:text-accent-foreground"
-                                        onClick={() => {
-                                            setFormData({ ...formData, location: loc });
-                                            setShowLocationDropdown(false);
-                                        }}
-                                    >
-                                        {loc}
-                                    </div>
-                                ))}
-                                {locationOptions.filter(loc => loc.toLowerCase().includes((formData.location || '').toLowerCase())).length === 0 && (
-                                    <div className="px-3 py-2 text-sm text-muted-foreground">
-                                        {(formData.location || '') ? '无匹配位置' : '无历史位置'}
-                                    </div>
-                                )}
-                            </div>
+                            <SuggestionList
+                                suggestions={locationSuggestions}
+                                onSelect={value => {
+                                    setFormData({ ...formData, location: value });
+                                    setShowLocationDropdown(false);
+                                }}
+                                emptyText={(formData.location || '') ? '无匹配位置' : '无历史位置'}
+                            />
                         )}
                     </div>
                 </div>
@@ -2071,6 +2321,10 @@ export default function Components() {
                     onChange={e => setFormData({...formData, description: e.target.value})}
                 />
             </div>
+            <div className="space-y-2">
+                <Label>标签</Label>
+                <Input value={formTagsText} onChange={e => setFormTagsText(e.target.value)} placeholder="多个标签用逗号分隔，例如 常用, obsolete" />
+            </div>
             <div className="grid grid-cols-1 md:grid-cols-2 gap-4">
                 <div className="space-y-2">
                     <Label>图片</Label>
@@ -2312,6 +2566,24 @@ export default function Components() {
 
       {/* Logs Modal */}
       <Modal isOpen={isLogsOpen} onClose={() => setIsLogsOpen(false)} title="库存记录">
+         {componentHistory.length > 0 && (
+           <div className="mb-4">
+             <div className="mb-1 text-xs text-muted-foreground">近 12 个月月末库存</div>
+             <div className="flex items-end gap-1 h-16">
+               {componentHistory.map(point => {
+                 const max = Math.max(1, ...componentHistory.map(p => p.quantity));
+                 return (
+                   <div
+                     key={point.start}
+                     className="flex-1 rounded-t bg-primary/60"
+                     style={{ height: `${Math.max(0, point.quantity) / max * 100}%` }}
+                     title={`${point.start.slice(0, 7)}：${point.quantity}（${formatCents(point.value_cents)}）`}
+                   />
+                 );
+               })}
+             </div>
+           </div>
+         )}
          <div className="max-h-[60vh] overflow-auto space-y-4">
             {componentLogs.length === 0 ? <div className="text-center text-muted-foreground py-8">暂无记录</div> : 
              componentLogs.map(log => (
@@ -2322,7 +2594,7 @@ export default function Components() {
                          </div>
                          <div className="min-w-0">
                              <div className="flex items-center gap-2 flex-wrap">
-                               <span className="font-medium">{isBackfillLog(log) ? '补录' : Math.abs(log.change_amount)}</span>
+                               <span className="font-medium">{isBackfillLog(log) ? '补录' : isMergeLog(log) ? '合并' : Math.abs(log.change_amount)}</span>
                                {isRevoked(log) && (
                                  <span className="text-xs px-2 py-0.5 rounded-full bg-muted text-muted-foreground">已撤销</span>
                                )}
@@ -2345,6 +2617,9 @@ export default function Components() {
                            )}
                          </div>
                        )}
+                       {log.balance_after != null && (
+                         <div className="text-muted-foreground">结存 {log.balance_after}</div>
+                       )}
                        <div className="text-muted-foreground">{log.reason || '无备注'}</div>
                        {canRevoke(log) && (
                          <Button
@@ -2403,7 +2678,7 @@ export default function Components() {
       <Modal
         isOpen={isExportOpen}
         onClose={() => !isExporting && setIsExportOpen(false)}
-        title="导出 CSV"
+        title="导出元件"
         className="max-w-2xl"
         footer={
           <div className="flex justify-end gap-2">
@@ -2413,7 +2688,7 @@ export default function Components() {
             <Button variant="outline" onClick={resetExportColumns} disabled={isExporting}>
               恢复默认
             </Button>
-            <Button onClick={handleExportCSV} disabled={isExporting}>
+            <Button onClick={handleExport} disabled={isExporting}>
               {isExporting ? (
                 <>
                   <Loader2 className="mr-2 h-4 w-4 animate-spin" />
@@ -2436,6 +2711,21 @@ export default function Components() {
           <p className="text-sm text-muted-foreground">
             导出顺序与当前列表排序一致（{currentSortLabel}，{currentSortOrderLabel}）。
           </p>
+          <div className="flex items-center gap-2 text-sm">
+            <Label htmlFor="export-format" className="shrink-0">文件格式</Label>
+            <select
+              id="export-format"
+              className="h-9 rounded-md border border-input bg-background px-2 text-sm"
+              value={exportFormat}
+              onChange={e => setExportFormat(e.target.value as ExportFormat)}
+              disabled={isExporting}
+            >
+              {EXPORT_FORMAT_OPTIONS.map(option => (
+                <option key={option.value} value={option.value}>{option.label}</option>
+              ))}
+            </select>
+            {exportFormat === 'jsonl' && <span className="text-muted-foreground">JSON Lines 以列名为字段名，不使用自定义表头</span>}
+          </div>
           <ColumnSettingsPanel
             columns={exportColumnsDraft}
             isAllSelected={isAllExportSelected}
@@ -7,10 +7,16 @@ import {
   TrendingDown,
   ArrowDownToLine,
   ArrowUpFromLine,
+  Bookmark,
 } from 'lucide-react';
+import { Link } from 'react-router-dom';
 import { Card, CardContent, CardHeader, CardTitle } from '../components/ui/Card';
 import { Button } from '../components/ui/Button';
 import { PageHeader } from '../components/ui/PageHeader';
+import { StatsSeriesCard } from '../components/StatsSeriesCard';
+import { ValuationCard } from '../components/ValuationCard';
+import { ForecastCard } from '../components/ForecastCard';
+import { ABCCard } from '../components/ABCCard';
 import client from '../api/client';
 import { type DashboardStats, type StatsRange } from '../types';
 import { formatCents } from '../utils/price';
@@ -130,6 +136,46 @@ export default function Dashboard() {
           loading={loading}
         />
       </div>
+
+      <StatsSeriesCard />
+
+      <ValuationCard />
+
+      <ForecastCard />
+
+      <ABCCard />
+
+      {stats.saved_searches && stats.saved_searches.length > 0 && (
+        <Card>
+          <CardHeader className="flex flex-row items-center justify-between space-y-0 pb-2">
+            <CardTitle className="text-sm font-medium">保存的搜索</CardTitle>
+            <Bookmark className="h-4 w-4 text-muted-foreground" />
+          </CardHeader>
+          <CardContent>
+            <div className="divide-y">
+              {stats.saved_searches.map((search) => (
+                <Link
+                  key={search.id}
+                  to={`/components?saved_search_id=${search.id}`}
+                  className="flex items-center justify-between py-2 text-sm hover:text-primary"
+                >
+                  <span>
+                    {search.name}
+                    {search.shared && <span className="ml-1 text-xs text-muted-foreground">(共享)</span>}
+                  </span>
+                  {search.error ? (
+                    <span className="text-destructive" title={search.error}>条件无效，请重新保存</span>
+                  ) : (
+                    <span className="text-muted-foreground">
+                      {search.component_count} 种 · 库存 {search.total_stock}
+                    </span>
+                  )}
+                </Link>
+              ))}
+            </div>
+          </CardContent>
+        </Card>
+      )}
     </div>
   );
 }
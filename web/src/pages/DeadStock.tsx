@@ -0,0 +1,369 @@
+import { useEffect, useState } from 'react';
+import { Download, FolderInput, Loader2, MapPin, Search, Tag } from 'lucide-react';
+import { toast } from 'react-hot-toast';
+import client from '../api/client';
+import { type Category, type DeadStockReport } from '../types';
+import { Button } from '../components/ui/Button';
+import { Input } from '../components/ui/Input';
+import { Label } from '../components/ui/Label';
+import { Modal } from '../components/ui/Modal';
+import { PageHeader } from '../components/ui/PageHeader';
+import { Card, CardContent, CardHeader, CardTitle } from '../components/ui/Card';
+import { formatCents, formatMicro } from '../utils/price';
+import { downloadExport, EXPORT_FORMAT_OPTIONS, type ExportFormat } from '../utils/download';
+
+const selectClass = 'h-9 w-full rounded-md border border-input bg-background px-2 text-sm';
+
+type DeadStockFilters = {
+  days: string;
+  max_outbound: string;
+  category_id: string;
+  location: string;
+};
+
+const defaultFilters: DeadStockFilters = { days: '180', max_outbound: '0', category_id: '', location: '' };
+
+function errorMessage(error: unknown, fallback: string) {
+  const err = error as { response?: { data?: { error?: string } } };
+  return err.response?.data?.error || fallback;
+}
+
+function buildParams(filters: DeadStockFilters): Record<string, string> {
+  const params: Record<string, string> = { days: filters.days, max_outbound: filters.max_outbound };
+  if (filters.category_id) {
+    params.category_id = filters.category_id;
+    params.include_subcategories = 'true';
+  }
+  if (filters.location.trim()) params.location = filters.location.trim();
+  return params;
+}
+
+const ACTION_ERRORS = {
+  location: '批量更新位置失败',
+  category: '批量更新分类失败',
+  tag: '批量添加标签失败',
+};
+
+export default function DeadStock() {
+  const [filters, setFilters] = useState<DeadStockFilters>(defaultFilters);
+  const [report, setReport] = useState<DeadStockReport | null>(null);
+  const [categories, setCategories] = useState<Category[]>([]);
+  const [selectedIds, setSelectedIds] = useState<number[]>([]);
+  const [loading, setLoading] = useState(true);
+  const [format, setFormat] = useState<ExportFormat>('xlsx');
+  const [exporting, setExporting] = useState(false);
+  const [action, setAction] = useState<'location' | 'category' | 'tag' | null>(null);
+  const [targetLocation, setTargetLocation] = useState('');
+  const [targetCategory, setTargetCategory] = useState('');
+  const [targetTag, setTargetTag] = useState('obsolete');
+  const [applying, setApplying] = useState(false);
+
+  const fetchReport = async (nextFilters = filters) => {
+    setLoading(true);
+    try {
+      const res = await client.get<{ data: DeadStockReport }>('/stats/dead-stock', { params: buildParams(nextFilters) });
+      setReport(res.data.data);
+      setSelectedIds([]);
+    } catch (error) {
+      toast.error(errorMessage(error, '获取呆滞料报表失败'));
+      setReport(null);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  useEffect(() => {
+    void fetchReport();
+    client
+      .get<{ data: Category[] }>('/categories')
+      .then(res => setCategories(res.data.data || []))
+      .catch(console.error);
+  }, []); // eslint-disable-line react-hooks/exhaustive-deps
+
+  const items = report?.items ?? [];
+  const allSelected = items.length > 0 && selectedIds.length === items.length;
+
+  const toggleAll = () => {
+    setSelectedIds(allSelected ? [] : items.map(item => item.component_id));
+  };
+
+  const toggleOne = (id: number) => {
+    setSelectedIds(prev => (prev.includes(id) ? prev.filter(item => item !== id) : [...prev, id]));
+  };
+
+  const handleExport = async () => {
+    setExporting(true);
+    try {
+      await downloadExport('/stats/dead-stock/export', new URLSearchParams({ ...buildParams(filters), format }), `dead_stock.${format}`);
+      toast.success('导出成功');
+    } catch (error) {
+      toast.error(error instanceof Error ? error.message : '导出失败');
+    } finally {
+      setExporting(false);
+    }
+  };
+
+  const handleApply = async () => {
+    if (selectedIds.length === 0) return;
+    if (action === 'category' && !targetCategory) {
+      toast.error('请选择目标分类');
+      return;
+    }
+    if (action === 'tag' && !targetTag.trim()) {
+      toast.error('请输入标签');
+      return;
+    }
+    setApplying(true);
+    try {
+      if (action === 'location') {
+        await client.patch('/components/batch-location', { ids: selectedIds, location: targetLocation.trim() });
+        toast.success(`已更新 ${selectedIds.length} 个元件的位置`);
+      } else if (action === 'category') {
+        await client.patch('/components/batch-category', { ids: selectedIds, category_id: Number(targetCategory) });
+        toast.success(`已将 ${selectedIds.length} 个元件移动到新分类`);
+      } else {
+        await client.patch('/components/batch-tags', { ids: selectedIds, add: [targetTag.trim()] });
+        toast.success(`已为 ${selectedIds.length} 个元件添加标签 ${targetTag.trim()}`);
+      }
+      setAction(null);
+      setTargetLocation('');
+      setTargetCategory('');
+      await fetchReport();
+    } catch (error) {
+      toast.error(errorMessage(error, ACTION_ERRORS[action ?? 'location']));
+    } finally {
+      setApplying(false);
+    }
+  };
+
+  return (
+    <div className="space-y-6">
+      <PageHeader
+        title="呆滞料"
+        actions={
+          <>
+            <select className="h-9 rounded-md border border-input bg-background px-2 text-sm" value={format} onChange={e => setFormat(e.target.value as ExportFormat)}>
+              {EXPORT_FORMAT_OPTIONS.map(option => (
+                <option key={option.value} value={option.value}>{option.label}</option>
+              ))}
+            </select>
+            <Button variant="outline" onClick={handleExport} disabled={exporting}>
+              {exporting ? <Loader2 className="mr-2 h-4 w-4 animate-spin" /> : <Download className="mr-2 h-4 w-4" />}
+              导出
+            </Button>
+          </>
+        }
+      />
+
+      <Card>
+        <CardContent className="pt-6">
+          <form
+            className="grid grid-cols-1 gap-3 md:grid-cols-2 lg:grid-cols-5"
+            onSubmit={e => {
+              e.preventDefault();
+              void fetchReport();
+            }}
+          >
+            <div className="space-y-1">
+              <Label htmlFor="dead-days" className="text-xs text-muted-foreground">最近天数</Label>
+              <Input id="dead-days" type="number" min={1} value={filters.days} onChange={e => setFilters(prev => ({ ...prev, days: e.target.value }))} />
+            </div>
+            <div className="space-y-1">
+              <Label htmlFor="dead-max-outbound" className="text-xs text-muted-foreground">出库不超过（0 为完全无出库）</Label>
+              <Input id="dead-max-outbound" type="number" min={0} value={filters.max_outbound} onChange={e => setFilters(prev => ({ ...prev, max_outbound: e.target.value }))} />
+            </div>
+            <div className="space-y-1">
+              <Label htmlFor="dead-category" className="text-xs text-muted-foreground">分类（含子分类）</Label>
+              <select id="dead-category" className={selectClass} value={filters.category_id} onChange={e => setFilters(prev => ({ ...prev, category_id: e.target.value }))}>
+                <option value="">全部分类</option>
+                {categories.map(category => (
+                  <option key={category.id} value={category.id}>{category.name}</option>
+                ))}
+              </select>
+            </div>
+            <div className="space-y-1">
+              <Label htmlFor="dead-location" className="text-xs text-muted-foreground">存放位置前缀</Label>
+              <Input id="dead-location" value={filters.location} placeholder="如 A1" onChange={e => setFilters(prev => ({ ...prev, location: e.target.value }))} />
+            </div>
+            <div className="flex items-end">
+              <Button type="submit" className="w-full">
+                <Search className="mr-2 h-4 w-4" />
+                查询
+              </Button>
+            </div>
+          </form>
+        </CardContent>
+      </Card>
+
+      {loading && <div className="text-sm text-muted-foreground">加载中...</div>}
+
+      {!loading && report && (
+        <>
+          <div className="text-sm">
+            <span className="text-2xl font-bold">{formatCents(report.totals.value_cents)}</span>
+            <span className="ml-2 text-muted-foreground">
+              {report.totals.component_count} 种 · 库存 {report.totals.quantity} · 自 {new Date(report.since).toLocaleDateString()} 起
+              {report.max_outbound > 0 ? `出库不超过 ${report.max_outbound}` : '没有出库'}
+            </span>
+          </div>
+
+          <div className="grid gap-4 md:grid-cols-2">
+            <Card>
+              <CardHeader className="pb-2">
+                <CardTitle className="text-sm font-medium">按分类</CardTitle>
+              </CardHeader>
+              <CardContent className="divide-y text-sm">
+                {report.categories.map(category => (
+                  <div key={category.category_id} className="flex items-center justify-between py-1.5">
+                    <span>{category.category_name || '未分类'}</span>
+                    <span className="text-muted-foreground">
+                      {category.component_count} 种 · {formatCents(category.value_cents)}
+                    </span>
+                  </div>
+                ))}
+                {report.categories.length === 0 && <div className="py-1.5 text-muted-foreground">暂无呆滞料</div>}
+              </CardContent>
+            </Card>
+            <Card>
+              <CardHeader className="pb-2">
+                <CardTitle className="text-sm font-medium">按存放位置</CardTitle>
+              </CardHeader>
+              <CardContent className="divide-y text-sm">
+                {report.locations.map(location => (
+                  <div key={location.location} className="flex items-center justify-between py-1.5">
+                    <span>{location.location || '未设置位置'}</span>
+                    <span className="text-muted-foreground">
+                      {location.component_count} 种 · {formatCents(location.value_cents)}
+                    </span>
+                  </div>
+                ))}
+                {report.locations.length === 0 && <div className="py-1.5 text-muted-foreground">暂无呆滞料</div>}
+              </CardContent>
+            </Card>
+          </div>
+
+          <div className="flex flex-wrap items-center gap-2">
+            <span className="text-sm text-muted-foreground">已选 {selectedIds.length} 项</span>
+            <Button size="sm" variant="outline" disabled={selectedIds.length === 0} onClick={() => setAction('location')}>
+              <MapPin className="mr-2 h-4 w-4" />
+              移动位置
+            </Button>
+            <Button size="sm" variant="outline" disabled={selectedIds.length === 0} onClick={() => setAction('category')}>
+              <FolderInput className="mr-2 h-4 w-4" />
+              移动到分类
+            </Button>
+            <Button size="sm" variant="outline" disabled={selectedIds.length === 0} onClick={() => setAction('tag')}>
+              <Tag className="mr-2 h-4 w-4" />
+              添加标签
+            </Button>
+          </div>
+
+          <div className="rounded-md border overflow-x-auto">
+            <table className="w-full text-sm">
+              <thead className="bg-muted/50">
+                <tr className="border-b">
+                  <th className="h-10 px-4 text-left">
+                    <input type="checkbox" checked={allSelected} onChange={toggleAll} />
+                  </th>
+                  <th className="h-10 px-4 text-left font-medium">元件</th>
+                  <th className="h-10 px-4 text-left font-medium">分类</th>
+                  <th className="h-10 px-4 text-left font-medium">位置</th>
+                  <th className="h-10 px-4 text-right font-medium">库存</th>
+                  <th className="h-10 px-4 text-right font-medium">单价</th>
+                  <th className="h-10 px-4 text-right font-medium">占用金额</th>
+                  <th className="h-10 px-4 text-left font-medium">最后出库</th>
+                  <th className="h-10 px-4 text-left font-medium">最后变动</th>
+                </tr>
+              </thead>
+              <tbody>
+                {items.map(item => (
+                  <tr key={item.component_id} className="border-b hover:bg-muted/50">
+                    <td className="p-4">
+                      <input type="checkbox" checked={selectedIds.includes(item.component_id)} onChange={() => toggleOne(item.component_id)} />
+                    </td>
+                    <td className="p-4">
+                      <div className="font-medium">{item.name}</div>
+                      {item.component_number && <div className="text-xs text-muted-foreground">{item.component_number}</div>}
+                    </td>
+                    <td className="p-4">{item.category_name || '-'}</td>
+                    <td className="p-4">{item.location || '-'}</td>
+                    <td className="p-4 text-right">{item.stock_quantity}</td>
+                    <td className="p-4 text-right">{item.unit_price_micro ? formatMicro(item.unit_price_micro) : '-'}</td>
+                    <td className="p-4 text-right">{formatCents(item.value_cents)}</td>
+                    <td className="p-4 whitespace-nowrap">
+                      {item.last_outbound_at ? new Date(item.last_outbound_at).toLocaleDateString() : '从未出库'}
+                      {item.outbound_quantity > 0 && <div className="text-xs text-muted-foreground">期内出库 {item.outbound_quantity}</div>}
+                    </td>
+                    <td className="p-4">
+                      {item.last_movement_at ? (
+                        <>
+                          <div className="whitespace-nowrap">
+                            {new Date(item.last_movement_at).toLocaleDateString()}
+                            <span className={item.last_movement_change > 0 ? 'ml-1 text-green-600' : 'ml-1 text-orange-600'}>
+                              {item.last_movement_change > 0 ? `+${item.last_movement_change}` : item.last_movement_change}
+                            </span>
+                          </div>
+                          {item.last_movement_reason && <div className="text-xs text-muted-foreground">{item.last_movement_reason}</div>}
+                        </>
+                      ) : '-'}
+                    </td>
+                  </tr>
+                ))}
+                {items.length === 0 && (
+                  <tr>
+                    <td colSpan={9} className="p-4 text-center text-muted-foreground">暂无呆滞料</td>
+                  </tr>
+                )}
+              </tbody>
+            </table>
+          </div>
+        </>
+      )}
+
+      <Modal
+        isOpen={action !== null}
+        onClose={() => setAction(null)}
+        title={
+          action === 'location'
+            ? `移动 ${selectedIds.length} 个元件的位置`
+            : action === 'category'
+              ? `移动 ${selectedIds.length} 个元件到分类`
+              : `为 ${selectedIds.length} 个元件添加标签`
+        }
+        footer={
+          <>
+            <Button variant="outline" onClick={() => setAction(null)}>取消</Button>
+            <Button onClick={handleApply} disabled={applying}>
+              {applying && <Loader2 className="mr-2 h-4 w-4 animate-spin" />}
+              确定
+            </Button>
+          </>
+        }
+      >
+        {action === 'location' ? (
+          <div className="space-y-2">
+            <Label htmlFor="dead-target-location">新位置（留空清除位置）</Label>
+            <Input id="dead-target-location" value={targetLocation} placeholder="如 呆滞料箱-1" onChange={e => setTargetLocation(e.target.value)} />
+          </div>
+        ) : action === 'tag' ? (
+          <div className="space-y-2">
+            <Label htmlFor="dead-target-tag">标签</Label>
+            <Input id="dead-target-tag" value={targetTag} placeholder="如 obsolete" onChange={e => setTargetTag(e.target.value)} />
+            <p className="text-xs text-muted-foreground">之后可在元件管理的高级查询中用 tag:{targetTag.trim() || 'obsolete'} 筛选，或用 -tag: 排除。</p>
+          </div>
+        ) : (
+          <div className="space-y-2">
+            <Label htmlFor="dead-target-category">目标分类</Label>
+            <select id="dead-target-category" className={selectClass} value={targetCategory} onChange={e => setTargetCategory(e.target.value)}>
+              <option value="">请选择分类</option>
+              {categories.map(category => (
+                <option key={category.id} value={category.id}>{category.name}</option>
+              ))}
+            </select>
+            <p className="text-xs text-muted-foreground">可先在分类管理中新建“呆滞料”等分类，用于集中标记这些元件。</p>
+          </div>
+        )}
+      </Modal>
+    </div>
+  );
+}
@@ -1,15 +1,38 @@
 import { useState, type FormEvent } from 'react';
 import { Navigate } from 'react-router-dom';
 import toast from 'react-hot-toast';
+import client from '../api/client';
 import { useAuth } from '../context/useAuth';
 import { Button } from '../components/ui/Button';
 import { Input } from '../components/ui/Input';
 import { Card, CardContent, CardHeader, CardTitle } from '../components/ui/Card';
 
+type LoginStep = 'password' | 'verify' | 'enroll' | 'recovery-codes';
+
+interface TwoFactorSetup {
+  secret: string;
+  otpauth_uri: string;
+  qrcode_url: string;
+}
+
+function errorMessage(error: unknown, fallback: string): string {
+  return typeof error === 'object' &&
+    error !== null &&
+    'response' in error &&
+    typeof (error as { response?: { data?: { error?: string } } }).response?.data?.error === 'string'
+    ? (error as { response?: { data?: { error?: string } } }).response?.data?.error || fallback
+    : fallback;
+}
+
 export default function Login() {
-  const { authEnabled, isAuthenticated, loading, login } = useAuth();
+  const { authEnabled, isAuthenticated, loading, login, verifyTwoFactor, refresh } = useAuth();
   const [username, setUsername] = useState('');
   const [password, setPassword] = useState('');
+  const [step, setStep] = useState<LoginStep>('password');
+  const [code, setCode] = useState('');
+  const [useRecoveryCode, setUseRecoveryCode] = useState(false);
+  const [setup, setSetup] = useState<TwoFactorSetup | null>(null);
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([]);
   const [submitting, setSubmitting] = useState(false);
 
   if (loading) {
@@ -20,7 +43,7 @@ export default function Login() {
     );
   }
 
-  if (!authEnabled || isAuthenticated) {
+  if ((!authEnabled || isAuthenticated) && step !== 'recovery-codes') {
     return <Navigate to="/" replace />;
   }
 
@@ -28,22 +51,59 @@ export default function Login() {
     event.preventDefault();
     setSubmitting(true);
     try {
-      await login(username, password);
+      const data = await login(username, password);
+      if (!data.two_factor_required) {
+        toast.success('登录成功');
+        return;
+      }
+      setCode('');
+      if (data.enrollment_required) {
+        const res = await client.post<{ data: TwoFactorSetup }>('/auth/2fa/setup');
+        setSetup(res.data.data);
+        setStep('enroll');
+      } else {
+        setStep('verify');
+      }
+    } catch (error) {
+      toast.error(errorMessage(error, '登录失败'));
+    } finally {
+      setSubmitting(false);
+    }
+  };
+
+  const handleVerify = async (event: FormEvent) => {
+    event.preventDefault();
+    setSubmitting(true);
+    try {
+      await verifyTwoFactor(useRecoveryCode ? { recovery_code: code } : { code });
       toast.success('登录成功');
     } catch (error) {
-      const message =
-        typeof error === 'object' &&
-        error !== null &&
-        'response' in error &&
-        typeof (error as { response?: { data?: { error?: string } } }).response?.data?.error === 'string'
-          ? (error as { response?: { data?: { error?: string } } }).response?.data?.error
-          : '登录失败';
-      toast.error(message || '登录失败');
+      toast.error(errorMessage(error, '验证失败'));
     } finally {
       setSubmitting(false);
     }
   };
 
+  const handleEnroll = async (event: FormEvent) => {
+    event.preventDefault();
+    setSubmitting(true);
+    try {
+      const res = await client.post<{ data: { recovery_codes: string[] } }>('/auth/2fa/enable', { code });
+      setRecoveryCodes(res.data.data.recovery_codes);
+      setStep('recovery-codes');
+    } catch (error) {
+      toast.error(errorMessage(error, '绑定失败'));
+    } finally {
+      setSubmitting(false);
+    }
+  };
+
+  const handleFinishEnrollment = async () => {
+    await refresh();
+    setStep('password');
+    toast.success('登录成功');
+  };
+
   return (
     <div className="min-h-screen bg-background flex items-center justify-center p-6">
       <Card className="w-full max-w-md">
@@ -54,36 +114,114 @@ export default function Login() {
           <CardTitle>登录库存管理系统</CardTitle>
         </CardHeader>
         <CardContent>
-          <form className="space-y-4" onSubmit={handleSubmit}>
-            <div className="space-y-2">
-              <label htmlFor="username" className="text-sm font-medium">
-                用户名
-              </label>
-              <Input
-                id="username"
-                value={username}
-                onChange={(event) => setUsername(event.target.value)}
-                autoComplete="username"
-                required
-              />
-            </div>
-            <div className="space-y-2">
-              <label htmlFor="password" className="text-sm font-medium">
-                密码
-              </label>
-              <Input
-                id="password"
-                type="password"
-                value={password}
-                onChange={(event) => setPassword(event.target.value)}
-                autoComplete="current-password"
-                required
-              />
+          {step === 'password' && (
+            <form className="space-y-4" onSubmit={handleSubmit}>
+              <div className="space-y-2">
+                <label htmlFor="username" className="text-sm font-medium">
+                  用户名
+                </label>
+                <Input
+                  id="username"
+                  value={username}
+                  onChange={(event) => setUsername(event.target.value)}
+                  autoComplete="username"
+                  required
+                />
+              </div>
+              <div className="space-y-2">
+                <label htmlFor="password" className="text-sm font-medium">
+                  密码
+                </label>
+                <Input
+                  id="password"
+                  type="password"
+                  value={password}
+                  onChange={(event) => setPassword(event.target.value)}
+                  autoComplete="current-password"
+                  required
+                />
+              </div>
+              <Button type="submit" className="w-full" disabled={submitting}>
+                {submitting ? '登录中...' : '登录'}
+              </Button>
+            </form>
+          )}
+
+          {step === 'verify' && (
+            <form className="space-y-4" onSubmit={handleVerify}>
+              <div className="space-y-2">
+                <label htmlFor="two-factor-code" className="text-sm font-medium">
+                  {useRecoveryCode ? '恢复码' : '身份验证器动态码'}
+                </label>
+                <Input
+                  id="two-factor-code"
+                  value={code}
+                  onChange={(event) => setCode(event.target.value)}
+                  autoComplete="one-time-code"
+                  inputMode={useRecoveryCode ? 'text' : 'numeric'}
+                  autoFocus
+                  required
+                />
+              </div>
+              <Button type="submit" className="w-full" disabled={submitting}>
+                {submitting ? '验证中...' : '验证'}
+              </Button>
+              <button
+                type="button"
+                className="w-full text-sm text-muted-foreground hover:text-foreground"
+                onClick={() => {
+                  setUseRecoveryCode((prev) => !prev);
+                  setCode('');
+                }}
+              >
+                {useRecoveryCode ? '使用动态码' : '无法使用身份验证器？使用恢复码'}
+              </button>
+            </form>
+          )}
+
+          {step === 'enroll' && setup && (
+            <form className="space-y-4" onSubmit={handleEnroll}>
+              <p className="text-sm text-muted-foreground">
+                管理员要求启用二次验证。请使用身份验证器 App 扫描二维码，或手动输入密钥后填写动态码。
+              </p>
+              <div className="flex justify-center">
+                <img src={setup.qrcode_url} alt="TOTP 二维码" className="h-48 w-48" />
+              </div>
+              <p className="break-all text-center font-mono text-xs">{setup.secret}</p>
+              <div className="space-y-2">
+                <label htmlFor="enroll-code" className="text-sm font-medium">
+                  动态码
+                </label>
+                <Input
+                  id="enroll-code"
+                  value={code}
+                  onChange={(event) => setCode(event.target.value)}
+                  autoComplete="one-time-code"
+                  inputMode="numeric"
+                  required
+                />
+              </div>
+              <Button type="submit" className="w-full" disabled={submitting}>
+                {submitting ? '绑定中...' : '绑定并登录'}
+              </Button>
+            </form>
+          )}
+
+          {step === 'recovery-codes' && (
+            <div className="space-y-4">
+              <p className="text-sm text-muted-foreground">
+                请妥善保存以下恢复码。每个恢复码只能使用一次，且只会显示这一次。
+              </p>
+              <div className="grid grid-cols-2 gap-2 rounded-md border p-3 font-mono text-sm">
+                {recoveryCodes.map((item) => (
+                  <span key={item}>{item}</span>
+                ))}
+              </div>
+              <Button className="w-full" onClick={() => void handleFinishEnrollment()}>
+                我已保存，继续
+              </Button>
             </div>
-            <Button type="submit" className="w-full" disabled={submitting}>
-              {submitting ? '登录中...' : '登录'}
-            </Button>
-          </form>
+          )}
         </CardContent>
       </Card>
     </div>
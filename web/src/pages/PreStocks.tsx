@@ -1,8 +1,9 @@
 import { lazy, Suspense, useEffect, useMemo, useState } from 'react';
-import { CheckCircle2, Edit, Hash, Loader2, Minus, Plus, QrCode, Search, Trash2 } from 'lucide-react';
+import { useNavigate, useSearchParams } from 'react-router-dom';
+import { CheckCircle2, Download, Edit, Hash, Loader2, Minus, Plus, QrCode, Search, Trash2 } from 'lucide-react';
 import { toast } from 'react-hot-toast';
 import client from '../api/client';
-import { type Category, type ComponentOptions, type Pagination, type PreStock, type PreStockStatus, type Supplier } from '../types';
+import { type Category, type ComponentOptions, type Pagination, type PreStock, type PreStockStatus, type QRScanResult, type Supplier } from '../types';
 import { Button } from '../components/ui/Button';
 import { Input } from '../components/ui/Input';
 import { Label } from '../components/ui/Label';
@@ -11,9 +12,12 @@ import { PageHeader } from '../components/ui/PageHeader';
 import { CollapsibleFilterPanel } from '../components/ui/CollapsibleFilterPanel';
 import { QuantityShortcuts } from '../components/ui/QuantityShortcuts';
 import { RowActionsMenu } from '../components/ui/RowActionsMenu';
+import { ExportRangeModal } from '../components/ExportRangeModal';
 import { calcUnitPriceMicro, formatCents, formatMicro, yuanToCents } from '../utils/price';
 import { copyToClipboard } from '../utils/clipboard';
 import { cn } from '../utils/cn';
+import { downloadExport } from '../utils/download';
+import { componentNumberQuery, locationQuery } from '../utils/query';
 
 const QRScanner = lazy(() => import('../components/QRScanner'));
 
@@ -80,11 +84,14 @@ const getSupplierNameFromPlatform = (platformName?: string) => {
 const statusLabel = (status: PreStockStatus) => status === 'confirmed' ? '已入库' : '待入库';
 
 export default function PreStocks() {
+  const [urlParams, setUrlParams] = useSearchParams();
+  const navigate = useNavigate();
   const [items, setItems] = useState<PreStock[]>([]);
   const [categories, setCategories] = useState<Category[]>([]);
   const [suppliers, setSuppliers] = useState<Supplier[]>([]);
   const [pagination, setPagination] = useState<Pagination>({ page: 1, page_size: 20, total: 0, total_page: 0 });
   const [statusFilter, setStatusFilter] = useState<PreStockStatus | 'all'>('pending');
+  const [isExportOpen, setIsExportOpen] = useState(false);
   const [loading, setLoading] = useState(false);
   const [isFormOpen, setIsFormOpen] = useState(false);
   const [isScannerOpen, setIsScannerOpen] = useState(false);
@@ -174,6 +181,19 @@ export default function PreStocks() {
     // eslint-disable-next-line react-hooks/exhaustive-deps
   }, []);
 
+  // 元件页面扫到预入库二维码时通过 ?pre_stock_id= 跳转，打开该记录
+  useEffect(() => {
+    const id = Number(urlParams.get('pre_stock_id'));
+    if (!id) return;
+    setUrlParams({}, { replace: true });
+    client.get(`/pre-stocks/${id}`)
+      .then(res => openForm(res.data.data as PreStock))
+      .catch((error) => {
+        const err = error as { response?: { data?: { error?: string } } };
+        toast.error(err.response?.data?.error || '加载预入库记录失败');
+      });
+  }, [urlParams]); // eslint-disable-line react-hooks/exhaustive-deps
+
   const openForm = (item?: PreStock) => {
     if (item) {
       setEditingItem(item);
@@ -327,7 +347,7 @@ export default function PreStocks() {
     }
     setIsParsing(true);
     try {
-      const res = await client.post('/components/parse', { code, use_llm: useAIParse });
+      const res = await client.get('/components/parse', { params: { code, use_llm: useAIParse } });
       applyParsedComponent(res.data.data as ParsedComponentInfo);
       toast.success('解析成功');
     } catch (error) {
@@ -338,12 +358,44 @@ export default function PreStocks() {
     }
   };
 
+  const downloadQRCode = async (item: PreStock) => {
+    try {
+      await downloadExport(
+        `/pre-stocks/${item.id}/qrcode`,
+        new URLSearchParams({ format: 'svg', download: 'true' }),
+        `pre-stock-${item.id}.svg`,
+      );
+    } catch (error) {
+      toast.error(error instanceof Error ? error.message : '下载二维码失败');
+    }
+  };
+
+  // 扫到自有二维码：预入库直接打开编辑，元件与位置跳转到元件页面并按编号或位置筛选
+  const handleOwnCodeScan = (scanned: QRScanResult) => {
+    switch (scanned.kind) {
+      case 'pre_stock':
+        openForm(scanned.pre_stock);
+        break;
+      case 'component':
+        navigate(`/components?${new URLSearchParams({ q: componentNumberQuery(scanned.component.component_number || '') })}`);
+        break;
+      case 'location':
+        navigate(`/components?${new URLSearchParams({ q: locationQuery(scanned.location) })}`);
+        break;
+    }
+  };
+
   const handleScan = async (data: string) => {
     if (isParsing) return;
     setIsScannerOpen(false);
     setIsParsing(true);
     try {
-      const res = await client.post('/components/parse-qrcode', { qrcode_data: data, use_llm: useAIParse });
+      const res = await client.get('/components/parse-qrcode', { params: { qrcode_data: data, use_llm: useAIParse } });
+      const scanned = res.data.data as QRScanResult | { kind?: undefined };
+      if (scanned.kind) {
+        handleOwnCodeScan(scanned);
+        return;
+      }
       const parsed = res.data.data as { component: ParsedComponentInfo; quantity: number };
       applyParsedComponent(parsed.component, parsed.quantity);
       setPlatformCode(parsed.component.platform_code || '');
@@ -431,6 +483,9 @@ export default function PreStocks() {
             <Button variant="outline" onClick={() => fetchItems(1, pagination.page_size)} disabled={loading}>
               <Search className="mr-2 h-4 w-4" /> 刷新
             </Button>
+            <Button variant="outline" onClick={() => setIsExportOpen(true)}>
+              <Download className="mr-2 h-4 w-4" /> 导出
+            </Button>
             <Button onClick={() => openForm()}>
               <Plus className="mr-2 h-4 w-4" /> 新建预入库
             </Button>
@@ -546,6 +601,7 @@ export default function PreStocks() {
                             disabled: confirmingId === item.id,
                             iconClassName: 'text-green-600',
                           },
+                          { key: 'qrcode', icon: QrCode, label: '二维码', onClick: () => downloadQRCode(item), iconClassName: 'text-gray-500' },
                           {
                             key: 'delete',
                             icon: Trash2,
@@ -930,6 +986,16 @@ export default function PreStocks() {
           )}
         </div>
       </Modal>
+
+      <ExportRangeModal
+        isOpen={isExportOpen}
+        onClose={() => setIsExportOpen(false)}
+        title="导出预入库"
+        path="/pre-stocks/export"
+        fallbackFilename="pre_stocks"
+        description={`按创建时间导出${STATUS_FILTER_LABELS[statusFilter]}的预入库记录，日期留空表示不限。`}
+        params={{ status: statusFilter }}
+      />
     </div>
   );
 }
@@ -1,10 +1,14 @@
 import { useEffect, useState } from 'react';
-import { History } from 'lucide-react';
+import { Download, History, Search } from 'lucide-react';
 import { toast } from 'react-hot-toast';
 import client from '../api/client';
-import { type StockLog, type Pagination } from '../types';
+import { type StockLog, type Category, type CursorPagination, type StockLogDirection, type StockLogStatus } from '../types';
 import { Card, CardContent } from '../components/ui/Card';
 import { Button } from '../components/ui/Button';
+import { Input } from '../components/ui/Input';
+import { Label } from '../components/ui/Label';
+import { CollapsibleFilterPanel } from '../components/ui/CollapsibleFilterPanel';
+import { ExportRangeModal } from '../components/ExportRangeModal';
 import { formatCents, formatMicro } from '../utils/price';
 import {
   canRevoke,
@@ -18,42 +22,132 @@ import {
 
 const PAGE_SIZE_OPTIONS = [10, 20, 50, 100];
 
+type StockLogFilters = {
+  operator: string;
+  category_id: string;
+  direction: '' | StockLogDirection;
+  status: '' | StockLogStatus;
+  reason: string;
+  from: string;
+  to: string;
+  min_amount: string;
+  max_amount: string;
+};
+
+const EMPTY_FILTERS: StockLogFilters = {
+  operator: '',
+  category_id: '',
+  direction: '',
+  status: '',
+  reason: '',
+  from: '',
+  to: '',
+  min_amount: '',
+  max_amount: '',
+};
+
+const DIRECTION_OPTIONS: { value: StockLogDirection; label: string }[] = [
+  { value: 'in', label: '入库' },
+  { value: 'out', label: '出库' },
+  { value: 'adjust', label: '调整（补录/合并）' },
+];
+
+const STATUS_OPTIONS: { value: StockLogStatus; label: string }[] = [
+  { value: 'normal', label: '正常' },
+  { value: 'revoked', label: '已撤销' },
+  { value: 'reversal', label: '撤销冲销' },
+  { value: 'merged', label: '合并' },
+];
+
+const selectClass = 'h-9 w-full rounded-md border border-input bg-background px-2 text-sm';
+
 export default function StockLogs() {
   const [logs, setLogs] = useState<StockLog[]>([]);
-  const [pagination, setPagination] = useState<Pagination>({ page: 1, page_size: 20, total: 0, total_page: 0 });
+  const [pagination, setPagination] = useState<CursorPagination>({ page_size: 20, total: 0, next_cursor: '' });
+  // cursors[i] 为第 i+1 页的游标，第一页为空字符串；翻页期间新写入的记录不会打乱后续页
+  const [cursors, setCursors] = useState<string[]>(['']);
   const [revokingId, setRevokingId] = useState<number | null>(null);
+  const [operators, setOperators] = useState<string[]>([]);
+  const [categories, setCategories] = useState<Category[]>([]);
+  const [filters, setFilters] = useState<StockLogFilters>(EMPTY_FILTERS);
+  const [isExportOpen, setIsExportOpen] = useState(false);
 
-  const fetchLogs = async (page = 1, pageSize = pagination.page_size) => {
+  const fetchLogs = async (cursor = '', pageSize = pagination.page_size, nextFilters = filters) => {
+    const params: Record<string, string | number> = { cursor, page_size: pageSize };
+    Object.entries(nextFilters).forEach(([key, value]) => {
+      if (value.trim()) params[key] = value.trim();
+    });
     try {
-      const res = await client.get('/stock-logs', { params: { page, page_size: pageSize } });
+      const res = await client.get('/stock-logs', { params });
       setLogs(res.data.data || []);
-      setPagination(res.data.pagination || { page: 1, page_size: 20, total: 0, total_page: 0 });
+      setPagination(res.data.pagination || { page_size: pageSize, total: 0, next_cursor: '' });
+      return true;
     } catch (error) {
-      console.error(error);
+      const err = error as { response?: { data?: { error?: string } } };
+      toast.error(err.response?.data?.error || '加载库存记录失败');
+      return false;
     }
   };
 
   useEffect(() => {
-    void fetchLogs(1, pagination.page_size);
+    void fetchLogs();
+    client
+      .get<{ data: string[] }>('/stock-logs/operators')
+      .then(res => setOperators(res.data.data || []))
+      .catch(console.error);
+    client
+      .get<{ data: Category[] }>('/categories')
+      .then(res => setCategories(res.data.data || []))
+      .catch(console.error);
   }, []); // eslint-disable-line react-hooks/exhaustive-deps
 
+  const reload = (nextFilters = filters, pageSize = pagination.page_size) => {
+    setCursors(['']);
+    void fetchLogs('', pageSize, nextFilters);
+  };
+
+  const updateFilter = <K extends keyof StockLogFilters>(key: K, value: StockLogFilters[K]) => {
+    setFilters(prev => ({ ...prev, [key]: value }));
+  };
+
+  // 下拉类筛选即时生效，文本与数值筛选点击「搜索」后生效
+  const applySelectFilter = <K extends keyof StockLogFilters>(key: K, value: StockLogFilters[K]) => {
+    const next = { ...filters, [key]: value };
+    setFilters(next);
+    reload(next);
+  };
+
+  const handleClearFilters = () => {
+    setFilters(EMPTY_FILTERS);
+    reload(EMPTY_FILTERS);
+  };
+
   const handlePageSizeChange = (pageSize: number) => {
-    setPagination(prev => ({ ...prev, page: 1, page_size: pageSize }));
-    void fetchLogs(1, pageSize);
+    reload(filters, pageSize);
+  };
+
+  const handleNextPage = async () => {
+    const cursor = pagination.next_cursor;
+    if (cursor && await fetchLogs(cursor)) setCursors(prev => [...prev, cursor]);
   };
 
-  const handlePageChange = (page: number) => {
-    setPagination(prev => ({ ...prev, page }));
-    void fetchLogs(page, pagination.page_size);
+  const handlePrevPage = async () => {
+    if (cursors.length <= 1) return;
+    const prev = cursors.slice(0, -1);
+    if (await fetchLogs(prev[prev.length - 1])) setCursors(prev);
   };
 
+  const activeFilterCount = Object.values(filters).filter(value => value.trim() !== '').length;
+  const pageNumber = cursors.length;
+  const totalPage = Math.max(Math.ceil(pagination.total / pagination.page_size), 1);
+
   const handleRevoke = async (log: StockLog) => {
     if (!confirm('确定撤销此记录？库存将回滚。')) return;
     setRevokingId(log.id);
     try {
       await client.post(`/stock-logs/${log.id}/revoke`);
       toast.success('撤销成功');
-      await fetchLogs(pagination.page, pagination.page_size);
+      await fetchLogs(cursors[cursors.length - 1]);
     } catch (err: unknown) {
       const message = (err as { response?: { data?: { error?: string } } })?.response?.data?.error || '撤销失败';
       toast.error(message);
@@ -62,11 +156,85 @@ export default function StockLogs() {
     }
   };
 
-  const totalPage = pagination.total_page ?? (pagination.total > 0 ? Math.ceil(pagination.total / pagination.page_size) : 0);
-
   return (
     <div className="space-y-6">
-      <h2 className="text-3xl font-bold tracking-tight">全局库存记录</h2>
+      <div className="flex items-center justify-between gap-4 flex-wrap">
+        <h2 className="text-3xl font-bold tracking-tight">全局库存记录</h2>
+        <Button variant="outline" onClick={() => setIsExportOpen(true)}>
+          <Download className="mr-2 h-4 w-4" /> 导出
+        </Button>
+      </div>
+
+      <CollapsibleFilterPanel
+        activeCount={activeFilterCount}
+        showClear={activeFilterCount > 0}
+        onClear={handleClearFilters}
+        headerActions={
+          <Button size="sm" variant="secondary" onClick={() => reload()}>
+            <Search className="h-4 w-4 mr-1" />
+            搜索
+          </Button>
+        }
+      >
+        <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-4 gap-3">
+          <div className="space-y-1">
+            <Label htmlFor="log-category" className="text-xs text-muted-foreground">分类（含子分类）</Label>
+            <select id="log-category" className={selectClass} value={filters.category_id} onChange={e => applySelectFilter('category_id', e.target.value)}>
+              <option value="">全部分类</option>
+              {categories.map(category => (
+                <option key={category.id} value={category.id}>{category.name}</option>
+              ))}
+            </select>
+          </div>
+          <div className="space-y-1">
+            <Label htmlFor="log-direction" className="text-xs text-muted-foreground">方向</Label>
+            <select id="log-direction" className={selectClass} value={filters.direction} onChange={e => applySelectFilter('direction', e.target.value as StockLogFilters['direction'])}>
+              <option value="">全部</option>
+              {DIRECTION_OPTIONS.map(option => (
+                <option key={option.value} value={option.value}>{option.label}</option>
+              ))}
+            </select>
+          </div>
+          <div className="space-y-1">
+            <Label htmlFor="log-status" className="text-xs text-muted-foreground">状态</Label>
+            <select id="log-status" className={selectClass} value={filters.status} onChange={e => applySelectFilter('status', e.target.value as StockLogFilters['status'])}>
+              <option value="">全部</option>
+              {STATUS_OPTIONS.map(option => (
+                <option key={option.value} value={option.value}>{option.label}</option>
+              ))}
+            </select>
+          </div>
+          <div className="space-y-1">
+            <Label htmlFor="log-operator" className="text-xs text-muted-foreground">操作人</Label>
+            <select id="log-operator" className={selectClass} value={filters.operator} onChange={e => applySelectFilter('operator', e.target.value)}>
+              <option value="">全部操作人</option>
+              {operators.map(name => (
+                <option key={name} value={name}>{name}</option>
+              ))}
+            </select>
+          </div>
+          <div className="space-y-1">
+            <Label htmlFor="log-reason" className="text-xs text-muted-foreground">原因</Label>
+            <Input id="log-reason" placeholder="项目A" value={filters.reason} onChange={e => updateFilter('reason', e.target.value)} onKeyDown={e => e.key === 'Enter' && reload()} />
+          </div>
+          <div className="space-y-1">
+            <Label className="text-xs text-muted-foreground">日期范围</Label>
+            <div className="flex items-center gap-2">
+              <Input type="date" value={filters.from} onChange={e => updateFilter('from', e.target.value)} />
+              <span className="text-muted-foreground">~</span>
+              <Input type="date" value={filters.to} onChange={e => updateFilter('to', e.target.value)} />
+            </div>
+          </div>
+          <div className="space-y-1">
+            <Label className="text-xs text-muted-foreground">变动数量（绝对值）</Label>
+            <div className="flex items-center gap-2">
+              <Input type="number" min={0} placeholder="最小" value={filters.min_amount} onChange={e => updateFilter('min_amount', e.target.value)} onKeyDown={e => e.key === 'Enter' && reload()} />
+              <span className="text-muted-foreground">~</span>
+              <Input type="number" min={0} placeholder="最大" value={filters.max_amount} onChange={e => updateFilter('max_amount', e.target.value)} onKeyDown={e => e.key === 'Enter' && reload()} />
+            </div>
+          </div>
+        </div>
+      </CollapsibleFilterPanel>
       
       <div className="space-y-4">
         {logs.map(log => (
@@ -88,6 +256,7 @@ export default function StockLogs() {
                             </div>
                             <div className="text-sm text-muted-foreground">
                                 {new Date(log.created_at).toLocaleString()}
+                                {log.operator && <span> · {log.operator}</span>}
                             </div>
                         </div>
                     </div>
@@ -103,6 +272,9 @@ export default function StockLogs() {
                             )}
                           </div>
                         )}
+                        {log.balance_after != null && (
+                          <div className="text-sm text-muted-foreground">结存 {log.balance_after}</div>
+                        )}
                         <div className="text-sm text-muted-foreground">{log.reason || '无备注'}</div>
                         {canRevoke(log) && (
                           <Button
@@ -139,19 +311,29 @@ export default function StockLogs() {
           </div>
         </div>
         <div className="flex gap-2 items-center">
-          <Button 
-            variant="outline" 
-            disabled={pagination.page <= 1} 
-            onClick={() => handlePageChange(pagination.page - 1)}
+          <Button
+            variant="outline"
+            disabled={pageNumber <= 1}
+            onClick={() => void handlePrevPage()}
           >上一页</Button>
-          <span>第 {pagination.page} / {Math.max(totalPage, 1)} 页</span>
-          <Button 
-            variant="outline" 
-            disabled={totalPage === 0 || pagination.page >= totalPage} 
-            onClick={() => handlePageChange(pagination.page + 1)}
+          <span>第 {pageNumber} / {totalPage} 页</span>
+          <Button
+            variant="outline"
+            disabled={!pagination.next_cursor}
+            onClick={() => void handleNextPage()}
           >下一页</Button>
         </div>
       </div>
+
+      <ExportRangeModal
+        isOpen={isExportOpen}
+        onClose={() => setIsExportOpen(false)}
+        title="导出库存记录"
+        path="/stock-logs/export"
+        fallbackFilename="stock_logs"
+        description={`按时间先后导出库存记录${activeFilterCount > 0 ? '（沿用当前筛选条件）' : ''}，日期留空表示不限。`}
+        params={{ ...filters, from: undefined, to: undefined }}
+      />
     </div>
   );
 }
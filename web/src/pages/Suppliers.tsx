@@ -0,0 +1,317 @@
+import { useEffect, useState } from 'react';
+import { Plus, Pencil, Trash2, Merge, ExternalLink } from 'lucide-react';
+import { toast } from 'react-hot-toast';
+import client from '../api/client';
+import { type Supplier } from '../types';
+import { Button } from '../components/ui/Button';
+import { Input } from '../components/ui/Input';
+import { Modal } from '../components/ui/Modal';
+import { Label } from '../components/ui/Label';
+import { PageHeader } from '../components/ui/PageHeader';
+import { Card, CardContent } from '../components/ui/Card';
+
+type SupplierForm = Omit<Supplier, 'id'>;
+
+const emptyForm: SupplierForm = {
+  name: '',
+  contact_name: '',
+  phone: '',
+  email: '',
+  website: '',
+  notes: '',
+  product_url_template: '',
+};
+
+const selectClassName = 'h-9 w-full rounded-md border border-input bg-background px-2 text-sm';
+
+function errorMessage(error: unknown, fallback: string) {
+  const err = error as { response?: { data?: { error?: string } } };
+  return err.response?.data?.error || fallback;
+}
+
+export default function Suppliers() {
+  const [suppliers, setSuppliers] = useState<Supplier[]>([]);
+  const [isModalOpen, setIsModalOpen] = useState(false);
+  const [editingSupplier, setEditingSupplier] = useState<Supplier | null>(null);
+  const [formData, setFormData] = useState<SupplierForm>(emptyForm);
+  const [deletingSupplier, setDeletingSupplier] = useState<Supplier | null>(null);
+  const [reassignTo, setReassignTo] = useState('');
+  const [isMergeOpen, setIsMergeOpen] = useState(false);
+  const [mergeSources, setMergeSources] = useState<number[]>([]);
+  const [mergeTarget, setMergeTarget] = useState('');
+
+  const fetchSuppliers = async () => {
+    try {
+      const res = await client.get('/suppliers');
+      setSuppliers(res.data.data || []);
+    } catch {
+      toast.error('加载供应商失败');
+    }
+  };
+
+  useEffect(() => {
+    const loadSuppliers = async () => {
+      try {
+        const res = await client.get('/suppliers');
+        setSuppliers(res.data.data || []);
+      } catch {
+        toast.error('加载供应商失败');
+      }
+    };
+
+    void loadSuppliers();
+  }, []);
+
+  const openModal = (supplier?: Supplier) => {
+    setEditingSupplier(supplier ?? null);
+    setFormData(supplier ? { ...emptyForm, ...supplier } : emptyForm);
+    setIsModalOpen(true);
+  };
+
+  const handleSubmit = async () => {
+    if (!formData.name.trim()) return toast.error('请输入供应商名称');
+
+    try {
+      if (editingSupplier) {
+        await client.put(`/suppliers/${editingSupplier.id}`, formData);
+        toast.success('更新成功');
+      } else {
+        await client.post('/suppliers', formData);
+        toast.success('添加成功');
+      }
+      setIsModalOpen(false);
+      fetchSuppliers();
+    } catch (error) {
+      toast.error(errorMessage(error, '操作失败'));
+    }
+  };
+
+  const handleDelete = async () => {
+    if (!deletingSupplier) return;
+    try {
+      await client.delete(`/suppliers/${deletingSupplier.id}`, {
+        params: reassignTo ? { reassign_to: reassignTo } : undefined,
+      });
+      toast.success('删除成功');
+      setDeletingSupplier(null);
+      fetchSuppliers();
+    } catch (error) {
+      toast.error(errorMessage(error, '删除失败'));
+    }
+  };
+
+  const openMerge = () => {
+    setMergeSources([]);
+    setMergeTarget('');
+    setIsMergeOpen(true);
+  };
+
+  const toggleMergeSource = (id: number, checked: boolean) => {
+    setMergeSources(prev => (checked ? [...prev, id] : prev.filter(item => item !== id)));
+  };
+
+  const handleMerge = async () => {
+    if (!mergeTarget) return toast.error('请选择保留的供应商');
+    const sourceIds = mergeSources.filter(id => id !== Number(mergeTarget));
+    if (sourceIds.length === 0) return toast.error('请勾选要合并的供应商');
+
+    try {
+      await client.post('/suppliers/merge', { source_ids: sourceIds, target_id: Number(mergeTarget) });
+      toast.success('合并成功');
+      setIsMergeOpen(false);
+      fetchSuppliers();
+    } catch (error) {
+      toast.error(errorMessage(error, '合并失败'));
+    }
+  };
+
+  const renderField = (key: keyof SupplierForm, label: string, placeholder?: string) => (
+    <div className="space-y-2">
+      <Label htmlFor={`supplier-${key}`}>{label}</Label>
+      <Input
+        id={`supplier-${key}`}
+        value={formData[key] || ''}
+        onChange={e => setFormData({ ...formData, [key]: e.target.value })}
+        placeholder={placeholder}
+      />
+    </div>
+  );
+
+  return (
+    <div className="space-y-6">
+      <PageHeader
+        title="供应商管理"
+        actions={
+          <>
+            <Button variant="outline" onClick={openMerge} disabled={suppliers.length < 2}>
+              <Merge className="mr-2 h-4 w-4" /> 合并重复
+            </Button>
+            <Button onClick={() => openModal()}>
+              <Plus className="mr-2 h-4 w-4" /> 添加供应商
+            </Button>
+          </>
+        }
+      />
+
+      <div className="grid gap-4 md:grid-cols-2 lg:grid-cols-3">
+        {suppliers.map(supplier => (
+          <Card key={supplier.id}>
+            <CardContent className="flex justify-between gap-4 p-6">
+              <div className="min-w-0 space-y-1 text-sm">
+                <div className="flex items-center gap-1 font-medium text-base">
+                  <span className="truncate">{supplier.name}</span>
+                  {supplier.website && (
+                    <a href={supplier.website} target="_blank" rel="noopener noreferrer" title="打开官网" className="text-muted-foreground hover:text-primary">
+                      <ExternalLink className="h-3.5 w-3.5" />
+                    </a>
+                  )}
+                </div>
+                {(supplier.contact_name || supplier.phone) && (
+                  <div className="text-muted-foreground">{[supplier.contact_name, supplier.phone].filter(Boolean).join(' · ')}</div>
+                )}
+                {supplier.email && <div className="text-muted-foreground truncate">{supplier.email}</div>}
+                {supplier.product_url_template && (
+                  <div className="text-xs text-muted-foreground truncate" title={supplier.product_url_template}>
+                    链接模板：{supplier.product_url_template}
+                  </div>
+                )}
+              </div>
+              <div className="flex shrink-0 items-start space-x-2">
+                <Button variant="ghost" size="icon" onClick={() => openModal(supplier)}>
+                  <Pencil className="h-4 w-4" />
+                </Button>
+                <Button
+                  variant="ghost"
+                  size="icon"
+                  className="text-destructive hover:text-destructive"
+                  onClick={() => {
+                    setDeletingSupplier(supplier);
+                    setReassignTo('');
+                  }}
+                >
+                  <Trash2 className="h-4 w-4" />
+                </Button>
+              </div>
+            </CardContent>
+          </Card>
+        ))}
+      </div>
+
+      <Modal
+        isOpen={isModalOpen}
+        onClose={() => setIsModalOpen(false)}
+        title={editingSupplier ? '编辑供应商' : '添加供应商'}
+        footer={
+          <>
+            <Button variant="outline" onClick={() => setIsModalOpen(false)}>取消</Button>
+            <Button onClick={handleSubmit}>保存</Button>
+          </>
+        }
+      >
+        <div className="space-y-4">
+          {renderField('name', '供应商名称', '例如: 嘉立创')}
+          <div className="grid grid-cols-1 gap-4 md:grid-cols-2">
+            {renderField('contact_name', '联系人')}
+            {renderField('phone', '电话')}
+            {renderField('email', '邮箱')}
+            {renderField('website', '官网', 'https://')}
+          </div>
+          {renderField('product_url_template', '商品链接模板', 'https://item.szlcsc.com/{sku}.html')}
+          <p className="text-xs text-muted-foreground">
+            {'{sku}'} 会替换为供应商料号，元件列表中的料号将显示商品链接；粘贴匹配模板的商品链接即可自动解析。
+          </p>
+          <div className="space-y-2">
+            <Label htmlFor="supplier-notes">备注</Label>
+            <textarea
+              id="supplier-notes"
+              className="flex min-h-[80px] w-full rounded-md border border-input bg-background px-3 py-2 text-sm"
+              value={formData.notes || ''}
+              onChange={e => setFormData({ ...formData, notes: e.target.value })}
+            />
+          </div>
+        </div>
+      </Modal>
+
+      <Modal
+        isOpen={deletingSupplier !== null}
+        onClose={() => setDeletingSupplier(null)}
+        title="删除供应商"
+        footer={
+          <>
+            <Button variant="outline" onClick={() => setDeletingSupplier(null)}>取消</Button>
+            <Button variant="destructive" onClick={handleDelete}>删除</Button>
+          </>
+        }
+      >
+        {deletingSupplier && (
+          <div className="space-y-4 text-sm">
+            <p>确定删除供应商「{deletingSupplier.name}」吗？</p>
+            <div className="space-y-2">
+              <Label htmlFor="supplier-reassign">仍有元件使用该供应商时，转移到</Label>
+              <select
+                id="supplier-reassign"
+                className={selectClassName}
+                value={reassignTo}
+                onChange={e => setReassignTo(e.target.value)}
+              >
+                <option value="">不转移（有引用时拒绝删除）</option>
+                {suppliers
+                  .filter(item => item.id !== deletingSupplier.id)
+                  .map(item => (
+                    <option key={item.id} value={item.id}>{item.name}</option>
+                  ))}
+              </select>
+            </div>
+          </div>
+        )}
+      </Modal>
+
+      <Modal
+        isOpen={isMergeOpen}
+        onClose={() => setIsMergeOpen(false)}
+        title="合并重复供应商"
+        footer={
+          <>
+            <Button variant="outline" onClick={() => setIsMergeOpen(false)}>取消</Button>
+            <Button onClick={handleMerge}>合并</Button>
+          </>
+        }
+      >
+        <div className="space-y-4 text-sm">
+          <div className="space-y-2">
+            <Label htmlFor="merge-target">保留的供应商</Label>
+            <select
+              id="merge-target"
+              className={selectClassName}
+              value={mergeTarget}
+              onChange={e => setMergeTarget(e.target.value)}
+            >
+              <option value="">请选择</option>
+              {suppliers.map(item => (
+                <option key={item.id} value={item.id}>{item.name}</option>
+              ))}
+            </select>
+          </div>
+          <div className="space-y-2">
+            <Label>合并并删除以下供应商（其元件与预入库转移到保留的供应商）</Label>
+            <div className="max-h-60 space-y-1 overflow-y-auto rounded-md border p-2">
+              {suppliers
+                .filter(item => String(item.id) !== mergeTarget)
+                .map(item => (
+                  <label key={item.id} className="flex items-center gap-2">
+                    <input
+                      type="checkbox"
+                      checked={mergeSources.includes(item.id)}
+                      onChange={e => toggleMergeSource(item.id, e.target.checked)}
+                      className="h-4 w-4 rounded border-input"
+                    />
+                    {item.name}
+                  </label>
+                ))}
+            </div>
+          </div>
+        </div>
+      </Modal>
+    </div>
+  );
+}
@@ -1,15 +1,33 @@
 export interface Category {
   id: number;
   name: string;
+  parent_id?: number | null;
+}
+
+export interface CategoryTreeNode extends Category {
+  component_count: number;
+  stock_quantity: number;
+  stock_value_cents: number;
+  total_component_count: number;
+  total_stock_quantity: number;
+  total_stock_value_cents: number;
+  children: CategoryTreeNode[];
 }
 
 export interface Supplier {
   id: number;
   name: string;
+  contact_name?: string;
+  phone?: string;
+  email?: string;
+  website?: string;
+  notes?: string;
+  product_url_template?: string;
 }
 
 export interface Component {
   id: number;
+  workspace_id?: number;
   category_id: number;
   supplier_id?: number | null;
   component_number?: string | null;
@@ -29,6 +47,101 @@ export interface Component {
   updated_at?: string;
   category?: Category;
   supplier?: Supplier;
+  // 全文搜索（keyword）时返回，snippet 已转义，命中部分以 <mark> 包裹
+  highlights?: SearchHighlight[];
+  // 消耗预测，尚未计算时省略
+  forecast?: ComponentForecast;
+  // ABC 分类，由定时任务按出库消耗计算，尚未计算时省略
+  abc_class?: ABCClass;
+  // 标签，可用 q=tag:xxx 筛选
+  tags?: string[];
+}
+
+export type ABCClass = 'A' | 'B' | 'C';
+
+export interface ABCParams {
+  metric: 'value' | 'movements';
+  window_days: number;
+  threshold_a: number;
+  threshold_b: number;
+}
+
+export interface ABCClassSummary {
+  // 空字符串表示尚未分类
+  class: ABCClass | '';
+  component_count: number;
+  consumption_cents: number;
+  movements: number;
+  share: number;
+  stock_quantity: number;
+  stock_value_cents: number;
+}
+
+export interface ABCSummary {
+  params: ABCParams;
+  since: string;
+  classes: ABCClassSummary[];
+}
+
+export type LabelFormat = 'pdf' | 'zpl' | 'tspl';
+
+export interface LabelSheet {
+  width_mm: number;
+  height_mm: number;
+  columns: number;
+  rows: number;
+  margin_left_mm: number;
+  margin_top_mm: number;
+  gap_x_mm: number;
+  gap_y_mm: number;
+}
+
+export interface LabelTemplate {
+  name: string;
+  title: string;
+  width_mm: number;
+  height_mm: number;
+  padding_mm: number;
+  gap_mm: number;
+  symbology: 'qr' | 'datamatrix';
+  fields: string[];
+  sheet?: LabelSheet;
+}
+
+// 扫描 Hamster Bin 自有二维码（HB1:C:/L:/P:）时 /components/parse-qrcode 返回的对象
+export type QRScanResult =
+  | { kind: 'component'; component: Component }
+  | { kind: 'location'; location: string; component_count: number }
+  | { kind: 'pre_stock'; pre_stock: PreStock };
+
+export interface ComponentForecast {
+  component_id: number;
+  window_days: number;
+  outbound_quantity: number;
+  avg_daily_consumption: number;
+  days_of_cover: number | null;
+  stockout_date: string | null;
+  reorder_quantity: number;
+  computed_at: string;
+}
+
+export interface ComponentForecastItem extends ComponentForecast {
+  component_number: string;
+  name: string;
+  stock_quantity: number;
+  supplier_name: string;
+  supplier_part_number: string;
+}
+
+export interface ForecastParams {
+  window_days: number;
+  lead_time_days: number;
+  target_days: number;
+}
+
+export interface SearchHighlight {
+  field: string;
+  snippet: string;
 }
 
 export interface StockLog {
@@ -40,8 +153,58 @@ export interface StockLog {
   reason: string;
   revoked_at?: string | null;
   reversal_of_id?: number | null;
+  merged_from_id?: number | null;
+  operator?: string;
   created_at: string;
   component?: Component;
+  /** 该元件在此次变动后的结存（由当前库存倒推）；元件已删除时为 null */
+  balance_after?: number | null;
+}
+
+export type StockLogDirection = 'in' | 'out' | 'adjust';
+
+export type StockLogStatus = 'normal' | 'revoked' | 'reversal' | 'merged';
+
+export interface CursorPagination {
+  page_size: number;
+  total: number;
+  next_cursor: string;
+}
+
+export type DuplicateCriteria = 'supplier_part_number' | 'model_manufacturer' | 'value_package';
+
+export interface DuplicateGroup {
+  by: DuplicateCriteria;
+  key: string;
+  components: Component[];
+}
+
+export type ComponentImportAction = 'create' | 'update' | 'error';
+
+export interface ComponentImportRowResult {
+  line: number;
+  action: ComponentImportAction;
+  component_id?: number;
+  component_number?: string;
+  name?: string;
+  errors?: string[];
+  warnings?: string[];
+}
+
+export interface ComponentImportReport {
+  dry_run: boolean;
+  total: number;
+  created: number;
+  updated: number;
+  failed: number;
+  created_categories: string[];
+  created_suppliers: string[];
+  rows: ComponentImportRowResult[];
+}
+
+export interface ComponentImportColumn {
+  header: string;
+  column: string;
 }
 
 export type PreStockStatus = 'pending' | 'confirmed';
@@ -97,6 +260,14 @@ export interface ComponentOptions {
   manufacturers: string[];
 }
 
+export type SuggestionField = 'package' | 'location' | 'manufacturer' | 'value' | 'supplier' | 'category' | 'model';
+
+export interface Suggestion {
+  value: string;
+  count: number;
+  fuzzy: boolean;
+}
+
 export type StatsRange = 'month' | 'quarter' | 'all';
 
 export interface DashboardStats {
@@ -110,6 +281,151 @@ export interface DashboardStats {
   inbound_quantity: number;
   outbound_quantity: number;
   inbound_cost_cents: number;
+  operator_consumption: OperatorConsumption[];
+  saved_searches?: SavedSearchCount[];
+}
+
+export type StatsBucket = 'day' | 'week' | 'month';
+
+export type StatsGroupBy = 'category' | 'supplier' | 'location' | 'project';
+
+export interface StatsAmounts {
+  inbound_quantity: number;
+  outbound_quantity: number;
+  inbound_cost_cents: number;
+  outbound_cost_cents: number;
+}
+
+export interface StatsPoint extends StatsAmounts {
+  start: string;
+}
+
+export interface StatsGroupSeries {
+  /** 空字符串表示未设置（无分类、无供应商、无位置或无原因） */
+  key: string;
+  totals: StatsAmounts;
+  series: StatsPoint[];
+}
+
+export interface ConsumedComponent {
+  component_id: number;
+  component_number: string;
+  name: string;
+  outbound_quantity: number;
+  outbound_cost_cents: number;
+}
+
+export interface StatsSeries {
+  from: string;
+  to: string;
+  timezone: string;
+  bucket: StatsBucket;
+  group_by?: StatsGroupBy;
+  totals: StatsAmounts;
+  series: StatsPoint[];
+  groups?: StatsGroupSeries[];
+  top_consumed: ConsumedComponent[];
+}
+
+export interface ValuationTotals {
+  component_count: number;
+  quantity: number;
+  value_cents: number;
+}
+
+export interface CategoryValuation extends ValuationTotals {
+  category_id: number;
+  category_name: string;
+}
+
+export interface InventoryValuation {
+  at: string;
+  totals: ValuationTotals;
+  categories: CategoryValuation[];
+}
+
+export interface LocationValuation extends ValuationTotals {
+  location: string;
+}
+
+export interface DeadStockItem {
+  component_id: number;
+  component_number: string;
+  name: string;
+  category_id: number;
+  category_name: string;
+  location: string;
+  stock_quantity: number;
+  unit_price_micro: number;
+  value_cents: number;
+  outbound_quantity: number;
+  last_outbound_at: string | null;
+  last_movement_at: string | null;
+  last_movement_change: number;
+  last_movement_reason: string;
+}
+
+export interface DeadStockReport {
+  days: number;
+  max_outbound: number;
+  since: string;
+  totals: ValuationTotals;
+  categories: CategoryValuation[];
+  locations: LocationValuation[];
+  items: DeadStockItem[];
+}
+
+export interface StockHistoryPoint {
+  start: string;
+  end: string;
+  quantity: number;
+  unit_price_micro: number;
+  value_cents: number;
+}
+
+export interface SavedSearchParams {
+  category_id?: number;
+  include_subcategories?: boolean;
+  keyword?: string;
+  q?: string;
+  component_number?: string;
+  name?: string;
+  model?: string;
+  manufacturer?: string;
+  value?: string;
+  supplier?: string;
+  supplier_part_number?: string;
+  abc_class?: string;
+  sort_by?: string;
+  sort_order?: string;
+  columns?: string[];
+}
+
+export interface SavedSearch {
+  id: number;
+  workspace_id: number;
+  name: string;
+  owner: string;
+  shared: boolean;
+  params: SavedSearchParams;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface SavedSearchCount {
+  id: number;
+  name: string;
+  shared: boolean;
+  component_count: number;
+  total_stock: number;
+  // 保存的条件已无法解析时的原因，此时不统计命中数
+  error?: string;
+}
+
+export interface OperatorConsumption {
+  operator: string;
+  outbound_quantity: number;
+  outbound_cost_cents: number;
 }
 
 export interface BatchStockOutFailure {
@@ -125,3 +441,61 @@ export interface BatchStockOutResult {
   total_quantity: number;
   total_cost_cents: number;
 }
+
+export type WorkspaceRole = 'owner' | 'editor' | 'viewer';
+
+export interface Workspace {
+  id: number;
+  name: string;
+  description?: string;
+  role: WorkspaceRole;
+  created_at: string;
+  updated_at: string;
+}
+
+export type RestoreMode = 'replace' | 'merge';
+
+export interface BackupManifest {
+  format: string;
+  format_version: number;
+  schema_version: number;
+  app_version: string;
+  db_driver: string;
+  created_at: string;
+  tables: { name: string; rows: number }[];
+  images: number;
+  image_bytes: number;
+}
+
+export interface RestoreReport {
+  mode: RestoreMode;
+  dry_run: boolean;
+  manifest: BackupManifest;
+  tables: { name: string; inserted: number; skipped: number }[];
+  images_restored: number;
+  images_skipped: number;
+}
+
+export interface BackupRunStatus {
+  started_at: string;
+  finished_at: string;
+  error?: string;
+  file?: string;
+  size?: number;
+  uploaded?: boolean;
+  pruned?: number;
+  maintenance?: { vacuumed: boolean; freed_pages: number; wal_pages: number };
+}
+
+export interface BackupScheduleStatus {
+  schedule: string;
+  next_run: string | null;
+  last_run: BackupRunStatus | null;
+  maintenance_schedule: string;
+  next_maintenance: string | null;
+  last_maintenance: BackupRunStatus | null;
+  retention: { daily: number; weekly: number; monthly: number };
+  dir: string;
+  s3_enabled: boolean;
+  files: { name: string; size: number; created_at: string }[];
+}
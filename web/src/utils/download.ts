@@ -0,0 +1,35 @@
+export type ExportFormat = 'csv' | 'xlsx' | 'jsonl';
+
+export const EXPORT_FORMAT_OPTIONS: { value: ExportFormat; label: string }[] = [
+  { value: 'csv', label: 'CSV' },
+  { value: 'xlsx', label: 'Excel (XLSX)' },
+  { value: 'jsonl', label: 'JSON Lines' },
+];
+
+// 下载导出文件：文件名取自 Content-Disposition，失败时抛出后端返回的错误信息
+export async function downloadExport(path: string, params: URLSearchParams, fallbackFilename: string): Promise<void> {
+  const response = await fetch(`/api/v1${path}?${params.toString()}`, {
+    credentials: 'include',
+  });
+  if (!response.ok) {
+    const data = await response.json().catch(() => null) as { error?: string } | null;
+    throw new Error(data?.error || '导出失败');
+  }
+
+  const blob = await response.blob();
+  const disposition = response.headers.get('Content-Disposition');
+  let filename = fallbackFilename;
+  if (disposition) {
+    const match = disposition.match(/filename="?([^"]+)"?/);
+    if (match?.[1]) filename = match[1];
+  }
+
+  const url = URL.createObjectURL(blob);
+  const link = document.createElement('a');
+  link.href = url;
+  link.download = filename;
+  document.body.appendChild(link);
+  link.click();
+  document.body.removeChild(link);
+  URL.revokeObjectURL(url);
+}
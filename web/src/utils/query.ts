@@ -0,0 +1,11 @@
+// 元件高级查询（q 参数）的构造
+
+// 按存放位置精确筛选；含空白或引号的位置只能用带引号的包含匹配（查询语法不支持转义引号）
+export function locationQuery(location: string): string {
+  return /[\s"]/.test(location) ? `loc:"${location.replace(/"/g, '')}"` : `loc:=${location}`;
+}
+
+// 按系统编号精确筛选
+export function componentNumberQuery(componentNumber: string): string {
+  return `number:=${componentNumber}`;
+}
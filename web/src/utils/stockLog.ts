@@ -8,24 +8,30 @@ export function isReversal(log: StockLog): boolean {
   return !!log.reversal_of_id;
 }
 
+export function isMergeLog(log: StockLog): boolean {
+  return !!log.merged_from_id;
+}
+
 export function canRevoke(log: StockLog): boolean {
-  return !isRevoked(log) && !isReversal(log);
+  return !isRevoked(log) && !isReversal(log) && !isMergeLog(log);
 }
 
 export function isBackfillLog(log: StockLog): boolean {
-  return log.change_amount === 0;
+  return log.change_amount === 0 && !isMergeLog(log);
 }
 
 export function stockLogAmountClass(log: StockLog): string {
   if (isRevoked(log)) return 'text-muted-foreground line-through';
   if (isReversal(log)) return 'text-muted-foreground';
   if (isBackfillLog(log)) return 'text-amber-700';
+  if (isMergeLog(log)) return 'text-blue-700';
   return log.change_amount > 0 ? 'text-green-600' : 'text-red-600';
 }
 
 export function stockLogIconClass(log: StockLog): string {
   if (isRevoked(log) || isReversal(log)) return 'bg-muted text-muted-foreground';
   if (isBackfillLog(log)) return 'bg-amber-100 text-amber-700';
+  if (isMergeLog(log)) return 'bg-blue-100 text-blue-700';
   return log.change_amount > 0 ? 'bg-green-100 text-green-700' : 'bg-red-100 text-red-700';
 }
 
@@ -37,10 +43,12 @@ export function stockLogCardClass(log: StockLog): string {
 
 export function formatStockLogChangeAmount(log: StockLog): string {
   if (isBackfillLog(log)) return '补录';
+  if (isMergeLog(log)) return '合并';
   return `${log.change_amount > 0 ? '+' : ''}${log.change_amount}`;
 }
 
 export function stockLogIconLabel(log: StockLog): string {
   if (isBackfillLog(log)) return '¥';
+  if (isMergeLog(log)) return '⇄';
   return log.change_amount > 0 ? '+' : '-';
 }
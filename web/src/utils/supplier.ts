@@ -0,0 +1,9 @@
+const SKU_PLACEHOLDER = '{sku}';
+
+// 用供应商料号填充商品链接模板，占位符同后端 repository.ProductURLPlaceholder
+export function buildProductUrl(template?: string | null, sku?: string | null): string {
+  const tpl = template?.trim();
+  const code = sku?.trim();
+  if (!tpl || !code || !tpl.includes(SKU_PLACEHOLDER)) return '';
+  return tpl.replace(SKU_PLACEHOLDER, encodeURIComponent(code));
+}
@@ -0,0 +1,32 @@
+import { useEffect, useState } from 'react';
+import client from '../api/client';
+import { type Suggestion, type SuggestionField } from '../types';
+
+const SUGGEST_DEBOUNCE_MS = 150;
+
+/**
+ * 输入提示：enabled 为 true 时按 prefix 防抖请求 GET /components/suggest/:field，
+ * 返回按匹配程度与使用次数排序的取值。
+ */
+export function useSuggestions(field: SuggestionField, prefix: string, enabled: boolean, limit = 10) {
+  const [suggestions, setSuggestions] = useState<Suggestion[]>([]);
+
+  useEffect(() => {
+    if (!enabled) return;
+    let cancelled = false;
+    const timer = setTimeout(async () => {
+      try {
+        const res = await client.get(`/components/suggest/${field}`, { params: { prefix: prefix.trim(), limit } });
+        if (!cancelled) setSuggestions(res.data.data || []);
+      } catch {
+        if (!cancelled) setSuggestions([]);
+      }
+    }, SUGGEST_DEBOUNCE_MS);
+    return () => {
+      cancelled = true;
+      clearTimeout(timer);
+    };
+  }, [field, prefix, enabled, limit]);
+
+  return suggestions;
+}